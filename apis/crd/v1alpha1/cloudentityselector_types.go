@@ -25,6 +25,10 @@ type EntityMatch struct {
 	MatchName string `json:"matchName,omitempty"`
 	// MatchID matches cloud entities' identifier. If not specified, it matches any cloud entities.
 	MatchID string `json:"matchID,omitempty"`
+	// MatchTags matches cloud entities' tags. Cloud entities must carry all tags, a tag with empty value
+	// matches any value of the tag key. If not specified, it matches any cloud entities.
+	// MatchTags is supported in VMMatch only.
+	MatchTags map[string]string `json:"matchTags,omitempty"`
 }
 
 // VirtualMachineSelector specifies VirtualMachine match criteria.
@@ -49,6 +53,9 @@ type CloudEntitySelectorSpec struct {
 	// It is an array, VirtualMachines satisfying any item on VMSelector are selected(ORed).
 	// If any item under VMSelector is not specified, all VirtualMachines are selected.
 	VMSelector []VirtualMachineSelector `json:"vmSelector,omitempty"`
+	// EndpointAddressTypes specifies types of VirtualMachine IP addresses, InternalIP or ExternalIP, used as
+	// ExternalEntity endpoints. If EndpointAddressTypes is not specified, all IP addresses are used.
+	EndpointAddressTypes []AddressType `json:"endpointAddressTypes,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -117,6 +117,37 @@ func (r *CloudEntitySelector) ValidateCreate() error {
 		return err
 	}
 
+	if err := r.validateMatchTags(); err != nil {
+		return err
+	}
+	return r.validateEndpointAddressTypes()
+}
+
+// validateMatchTags makes sure tags are matched on virtual machines only and tag keys are not empty.
+func (r *CloudEntitySelector) validateMatchTags() error {
+	for _, match := range r.Spec.VMSelector {
+		if match.VpcMatch != nil && len(match.VpcMatch.MatchTags) != 0 {
+			return fmt.Errorf("matchTags is not supported in vpcMatch")
+		}
+		for _, vmMatch := range match.VMMatch {
+			for key := range vmMatch.MatchTags {
+				if len(strings.TrimSpace(key)) == 0 {
+					return fmt.Errorf("matchTags key cannot be blank or empty")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateEndpointAddressTypes makes sure only internal and external IP addresses are used as endpoints.
+func (r *CloudEntitySelector) validateEndpointAddressTypes() error {
+	for _, addressType := range r.Spec.EndpointAddressTypes {
+		if addressType != AddressTypeInternalIP && addressType != AddressTypeExternalIP {
+			return fmt.Errorf("unsupported endpointAddressTypes %v, supported types are %v and %v", addressType,
+				AddressTypeInternalIP, AddressTypeExternalIP)
+		}
+	}
 	return nil
 }
 
@@ -151,7 +182,10 @@ func (r *CloudEntitySelector) ValidateUpdate(old runtime.Object) error {
 		return fmt.Errorf("account name update not allowed (old:%v, new:%v)", oldAccName, newAccountName)
 	}
 
-	return nil
+	if err := r.validateMatchTags(); err != nil {
+		return err
+	}
+	return r.validateEndpointAddressTypes()
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
@@ -21,7 +21,7 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
-// +kubebuilder:validation:Enum=Azure;AWS
+// +kubebuilder:validation:Enum=Azure;AWS;GCP
 // CloudProvider specifies a cloud provider.
 type CloudProvider string
 
@@ -30,8 +30,33 @@ const (
 	AzureCloudProvider CloudProvider = "Azure"
 	// AWSCloudProvider specifies AWS.
 	AWSCloudProvider CloudProvider = "AWS"
+	// GCPCloudProvider specifies GCP.
+	GCPCloudProvider CloudProvider = "GCP"
 )
 
+const (
+	// AccountConditionCredentialsValid indicates whether account credentials are accepted by the cloud plugin.
+	AccountConditionCredentialsValid = "CredentialsValid"
+	// AccountConditionInventorySynced indicates whether the last inventory poll of the account succeeded.
+	AccountConditionInventorySynced = "InventorySynced"
+	// AccountConditionSecurityEnforcementReady indicates whether security policies can be enforced on the account.
+	AccountConditionSecurityEnforcementReady = "SecurityEnforcementReady"
+)
+
+// Reasons of CloudProviderAccount conditions.
+const (
+	AccountReasonCredentialsAccepted     = "CredentialsAccepted"
+	AccountReasonCredentialsInvalid      = "CredentialsInvalid"
+	AccountReasonInventoryPending        = "InventoryPending"
+	AccountReasonInventoryPollSucceeded  = "InventoryPollSucceeded"
+	AccountReasonInventoryPollFailed     = "InventoryPollFailed"
+	AccountReasonInventoryInitialized    = "InventoryInitialized"
+	AccountReasonInventoryNotInitialized = "InventoryNotInitialized"
+)
+
+// AllRegions is the Regions value selecting all regions enabled in a cloud provider account.
+const AllRegions = "all"
+
 // CloudProviderAccountSpec defines the desired state of CloudProviderAccount.
 type CloudProviderAccountSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -43,6 +68,11 @@ type CloudProviderAccountSpec struct {
 	AWSConfig *CloudProviderAccountAWSConfig `json:"awsConfig,omitempty"`
 	// Cloud provider account config
 	AzureConfig *CloudProviderAccountAzureConfig `json:"azureConfig,omitempty"`
+	// Cloud provider account config
+	GCPConfig *CloudProviderAccountGCPConfig `json:"gcpConfig,omitempty"`
+	// DryRun, if true, computes cloud security groups of the account without applying them to the cloud.
+	// Intended security groups are reported in status plannedSecurityGroups.
+	DryRun bool `json:"dryRun,omitempty"`
 }
 
 type CloudProviderAccountAWSConfig struct {
@@ -50,24 +80,98 @@ type CloudProviderAccountAWSConfig struct {
 	AccountID string `json:"accountID,omitempty"`
 	// Cloud provider account access key ID
 	AccessKeyID string `json:"accessKeyId,omitempty"`
-	// Cloud provider account access key secret
-	// (TODO Secret needs to be saved using k8 secrets)
+	// Cloud provider account access key secret, prefer SecretRef to avoid storing it in plain text
 	AccessKeySecret string `json:"accessKeySecret,omitempty"`
+	// Reference to the Secret key holding cloud provider account access key secret
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
 	// Cloud provider account region
 	Region string `json:"region,omitempty"`
+	// Cloud provider account regions, in addition to Region. Set to ["all"] for all regions enabled in the account
+	Regions []string `json:"regions,omitempty"`
 	// Cloud provider role arn to be assumed
 	RoleArn string `json:"roleArn,omitempty"`
 	// Cloud provider external id used in assume role
 	ExternalID string `json:"externalId,omitempty"`
+	// URL of the SQS queue receiving EventBridge EC2 events of the account. If set, inventory is updated on events in
+	// between polls
+	EventQueueURL string `json:"eventQueueURL,omitempty"`
+	// SecurityGroupMode specifies how security groups not created by nephe are treated on network interfaces of
+	// VirtualMachines. Defaults to Replace
+	SecurityGroupMode SecurityGroupMode `json:"securityGroupMode,omitempty"`
+	// Security group rules with at least PrefixListThreshold IPv4 or IPv6 CIDRs reference customer-managed prefix
+	// lists of the CIDRs, instead of individual IP ranges. Disabled if 0
+	PrefixListThreshold uint `json:"prefixListThreshold,omitempty"`
+	// RulesPerSecurityGroupQuota is the quota of inbound or outbound rules of an address family per security group of
+	// the account. Defaults to 60 if 0
+	RulesPerSecurityGroupQuota uint `json:"rulesPerSecurityGroupQuota,omitempty"`
+	// SecurityGroupsPerNetworkInterfaceQuota is the quota of security groups per network interface of the account.
+	// Defaults to 5 if 0
+	SecurityGroupsPerNetworkInterfaceQuota uint `json:"securityGroupsPerNetworkInterfaceQuota,omitempty"`
+	// EntriesPerNetworkACLQuota is the quota of inbound or outbound entries of an address family per network ACL of the
+	// account. Defaults to 20 if 0
+	EntriesPerNetworkACLQuota uint `json:"entriesPerNetworkACLQuota,omitempty"`
 }
 
 type CloudProviderAccountAzureConfig struct {
-	SubscriptionID   string `json:"subscriptionId,omitempty"`
-	ClientID         string `json:"clientId,omitempty"`
-	TenantID         string `json:"tenantId,omitempty"`
-	ClientKey        string `json:"clientKey,omitempty"`
-	Region           string `json:"region,omitempty"`
-	IdentityClientID string `json:"identityClientId,omitempty"`
+	SubscriptionID string `json:"subscriptionId,omitempty"`
+	ClientID       string `json:"clientId,omitempty"`
+	TenantID       string `json:"tenantId,omitempty"`
+	// Client key, prefer SecretRef to avoid storing it in plain text
+	ClientKey string `json:"clientKey,omitempty"`
+	Region    string `json:"region,omitempty"`
+	// Regions, in addition to Region. Set to ["all"] for all regions available to the subscription
+	Regions          []string `json:"regions,omitempty"`
+	IdentityClientID string   `json:"identityClientId,omitempty"`
+	// Reference to the Secret key holding client key
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+	// URL of the Storage queue receiving Event Grid resource events of the subscription. If set, inventory is updated on
+	// events in between polls
+	EventQueueURL string `json:"eventQueueURL,omitempty"`
+	// SecurityGroupMode specifies how network security groups not created by nephe are treated on network interfaces
+	// of VirtualMachines. Defaults to Replace. Coexist is not supported, as a network interface has a single network
+	// security group
+	SecurityGroupMode SecurityGroupMode `json:"securityGroupMode,omitempty"`
+}
+
+type CloudProviderAccountGCPConfig struct {
+	// Cloud provider project identifier
+	ProjectID string `json:"projectID,omitempty"`
+	// Cloud provider service account key in JSON format, prefer SecretRef to avoid storing it in plain text
+	ServiceAccountKey string `json:"serviceAccountKey,omitempty"`
+	// Reference to the Secret key holding service account key in JSON format
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+	// Cloud provider service account to be impersonated
+	ServiceAccountEmail string `json:"serviceAccountEmail,omitempty"`
+	// Cloud provider account region
+	Region string `json:"region,omitempty"`
+}
+
+// SecurityGroupMode specifies how security groups not created by nephe are treated on network interfaces.
+// +kubebuilder:validation:Enum=Replace;Coexist;Fail
+type SecurityGroupMode string
+
+const (
+	// SecurityGroupModeReplace replaces security groups not created by nephe with nephe security groups, when a
+	// network interface is attached to nephe security groups. Replaced security groups are recorded on the network
+	// interface, and restored once it is no longer attached to nephe security groups.
+	SecurityGroupModeReplace SecurityGroupMode = "Replace"
+	// SecurityGroupModeCoexist keeps security groups not created by nephe attached to network interfaces, along with
+	// nephe security groups.
+	SecurityGroupModeCoexist SecurityGroupMode = "Coexist"
+	// SecurityGroupModeFail leaves network interfaces with security groups not created by nephe unmodified, and reports
+	// an error for them.
+	SecurityGroupModeFail SecurityGroupMode = "Fail"
+)
+
+// SecretReference references a key of a Secret holding a cloud provider account credential.
+type SecretReference struct {
+	// Name of the Secret
+	Name string `json:"name"`
+	// Namespace of the Secret, defaults to namespace of the CloudProviderAccount. It must be namespace of the
+	// CloudProviderAccount or of nephe controller.
+	Namespace string `json:"namespace,omitempty"`
+	// Key of the credential within the Secret
+	Key string `json:"key"`
 }
 
 // CloudProviderAccountStatus defines the observed state of CloudProviderAccount.
@@ -76,6 +180,66 @@ type CloudProviderAccountStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	// Error is current error, if any, of the CloudProviderAccount.
 	Error string `json:"error,omitempty"`
+	// Conditions of the CloudProviderAccount.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// LastSuccessfulPollTime is the time all cloud services of the account were last polled successfully.
+	LastSuccessfulPollTime *metav1.Time `json:"lastSuccessfulPollTime,omitempty"`
+	// DiscoveredVirtualMachines is the number of virtual machines discovered in the account.
+	DiscoveredVirtualMachines int `json:"discoveredVirtualMachines,omitempty"`
+	// DiscoveredVpcs is the number of VPCs of the discovered virtual machines.
+	DiscoveredVpcs int `json:"discoveredVpcs,omitempty"`
+	// ServiceStats is the inventory poll statistics of each cloud service of the account.
+	ServiceStats []CloudServiceStats `json:"serviceStats,omitempty"`
+	// PlannedSecurityGroups are cloud security groups intended to be configured on the account in dry-run mode.
+	PlannedSecurityGroups []PlannedSecurityGroup `json:"plannedSecurityGroups,omitempty"`
+}
+
+// Operations on a cloud security group.
+const (
+	SecurityGroupOperationCreate        = "Create"
+	SecurityGroupOperationUpdateMembers = "UpdateMembers"
+	SecurityGroupOperationUpdateRules   = "UpdateRules"
+	SecurityGroupOperationDelete        = "Delete"
+)
+
+// PlannedSecurityGroup defines a cloud security group intended to be configured in dry-run mode.
+type PlannedSecurityGroup struct {
+	// Name of the cloud security group
+	Name string `json:"name"`
+	// VpcID is the VPC of the cloud security group
+	VpcID string `json:"vpcId"`
+	// MembershipOnly is true if the cloud security group only tracks members of an address group
+	MembershipOnly bool `json:"membershipOnly,omitempty"`
+	// Operations are the intended operations on the cloud security group since its creation or deletion
+	Operations []string `json:"operations,omitempty"`
+	// Members are the cloud resources intended to be attached to the cloud security group
+	Members []string `json:"members,omitempty"`
+	// IngressRules are the intended ingress rules of the cloud security group
+	IngressRules []string `json:"ingressRules,omitempty"`
+	// EgressRules are the intended egress rules of the cloud security group
+	EgressRules []string `json:"egressRules,omitempty"`
+	// LastOperationTime is the time of the last intended operation
+	LastOperationTime metav1.Time `json:"lastOperationTime,omitempty"`
+}
+
+// CloudServiceStats defines inventory poll statistics of a cloud service.
+type CloudServiceStats struct {
+	// Name of the cloud service
+	Name string `json:"name"`
+	// Type of the cloud service
+	Type string `json:"type,omitempty"`
+	// TotalPollCount is the number of inventory polls performed
+	TotalPollCount uint64 `json:"totalPollCount,omitempty"`
+	// SuccessfulPollCount is the number of inventory polls succeeded
+	SuccessfulPollCount uint64 `json:"successfulPollCount,omitempty"`
+	// LastSuccessfulPollTime is the time of last successful inventory poll
+	LastSuccessfulPollTime *metav1.Time `json:"lastSuccessfulPollTime,omitempty"`
+	// LastPollError is the error of last failed inventory poll
+	LastPollError string `json:"lastPollError,omitempty"`
+	// LastPollErrorTime is the time of last failed inventory poll
+	LastPollErrorTime *metav1.Time `json:"lastPollErrorTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
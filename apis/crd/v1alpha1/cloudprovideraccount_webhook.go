@@ -16,6 +16,7 @@ package v1alpha1
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -27,6 +28,20 @@ import (
 // log is for logging in this package.
 var cloudprovideraccountlog = logf.Log.WithName("cloudprovideraccount-resource")
 
+// AllowInlineCredentials is the cluster wide policy on whether credential secrets can be specified inline
+// in the CloudProviderAccount spec, instead of being referenced from a Secret.
+var AllowInlineCredentials = true
+
+// NepheNamespace is the namespace of nephe controller. A CloudProviderAccount can reference a credential Secret in
+// its own namespace, or in NepheNamespace, so that accounts cannot read Secrets of other namespaces.
+var NepheNamespace string
+
+// IsSecretNamespaceAllowed returns true if a CloudProviderAccount in accountNamespace can reference a Secret in
+// secretNamespace.
+func IsSecretNamespaceAllowed(secretNamespace, accountNamespace string) bool {
+	return secretNamespace == accountNamespace || (len(NepheNamespace) != 0 && secretNamespace == NepheNamespace)
+}
+
 func (r *CloudProviderAccount) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
@@ -52,7 +67,7 @@ func (r *CloudProviderAccount) Default() {
 
 // TODO(user): change verbs to :"verbs=create;update;delete" if you want to enable deletion validation.
 // nolint:lll
-// +kubebuilder:webhook:verbs=create,path=/validate-crd-cloud-antrea-io-v1alpha1-cloudprovideraccount,mutating=false,failurePolicy=fail,groups=crd.cloud.antrea.io,resources=cloudprovideraccounts,versions=v1alpha1,name=vcloudprovideraccount.kb.io,sideEffects=None,admissionReviewVersions=v1;v1beta1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-crd-cloud-antrea-io-v1alpha1-cloudprovideraccount,mutating=false,failurePolicy=fail,groups=crd.cloud.antrea.io,resources=cloudprovideraccounts,versions=v1alpha1,name=vcloudprovideraccount.kb.io,sideEffects=None,admissionReviewVersions=v1;v1beta1
 
 var _ webhook.Validator = &CloudProviderAccount{}
 
@@ -60,6 +75,26 @@ var _ webhook.Validator = &CloudProviderAccount{}
 func (r *CloudProviderAccount) ValidateCreate() error {
 	cloudprovideraccountlog.Info("validate create", "name", r.Name)
 
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (r *CloudProviderAccount) ValidateUpdate(old runtime.Object) error {
+	cloudprovideraccountlog.Info("validate update", "name", r.Name)
+
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
+func (r *CloudProviderAccount) ValidateDelete() error {
+	cloudprovideraccountlog.Info("validate delete", "name", r.Name)
+
+	// TODO(user): fill in your validation logic upon object deletion.
+	return nil
+}
+
+// validate validates the CloudProviderAccount spec on both create and update.
+func (r *CloudProviderAccount) validate() error {
 	cloudProviderType, err := r.GetAccountProviderType()
 	if err != nil {
 		return err
@@ -79,12 +114,16 @@ func (r *CloudProviderAccount) ValidateCreate() error {
 			// empty credentials when role based access is configured
 			awsConfig.AccessKeyID = ""
 			awsConfig.AccessKeySecret = ""
-		} else if len(strings.TrimSpace(awsConfig.AccessKeyID)) == 0 || len(strings.TrimSpace(awsConfig.AccessKeySecret)) == 0 {
+		} else if len(strings.TrimSpace(awsConfig.AccessKeyID)) == 0 ||
+			(len(strings.TrimSpace(awsConfig.AccessKeySecret)) == 0 && awsConfig.SecretRef == nil) {
 			return fmt.Errorf("must specify either credentials or role arn, cannot both be empty")
 		}
 
-		if len(strings.TrimSpace(awsConfig.Region)) == 0 {
-			return fmt.Errorf("region cannot be blank or empty")
+		if err := validateRegions(awsConfig.GetRegions()); err != nil {
+			return err
+		}
+		if err := validateEventQueueURL(awsConfig.EventQueueURL); err != nil {
+			return err
 		}
 	case AzureCloudProvider:
 		azureConfig := r.Spec.AzureConfig
@@ -105,45 +144,147 @@ func (r *CloudProviderAccount) ValidateCreate() error {
 			// empty credentials when role based access is configured
 			azureConfig.ClientID = ""
 			azureConfig.ClientKey = ""
-		} else if len(strings.TrimSpace(azureConfig.ClientID)) == 0 || len(strings.TrimSpace(azureConfig.ClientKey)) == 0 {
+		} else if len(strings.TrimSpace(azureConfig.ClientID)) == 0 ||
+			(len(strings.TrimSpace(azureConfig.ClientKey)) == 0 && azureConfig.SecretRef == nil) {
 			return fmt.Errorf("must specify either credentials or managed identity client id, cannot both be empty")
 		}
 
 		// validate region
-		if len(strings.TrimSpace(azureConfig.Region)) == 0 {
+		if err := validateRegions(azureConfig.GetRegions()); err != nil {
+			return err
+		}
+		if err := validateEventQueueURL(azureConfig.EventQueueURL); err != nil {
+			return err
+		}
+
+		// validate security group mode
+		if azureConfig.SecurityGroupMode == SecurityGroupModeCoexist {
+			return fmt.Errorf("securityGroupMode %v is not supported, a network interface has a single network security group",
+				SecurityGroupModeCoexist)
+		}
+	case GCPCloudProvider:
+		gcpConfig := r.Spec.GCPConfig
+
+		// validate project ID
+		if len(strings.TrimSpace(gcpConfig.ProjectID)) == 0 {
+			return fmt.Errorf("project id cannot be blank or empty")
+		}
+
+		// validate credentials
+		if len(strings.TrimSpace(gcpConfig.ServiceAccountEmail)) != 0 {
+			cloudprovideraccountlog.Info("Service account email configured will be impersonated for cloud-account access")
+			// empty credentials when service account impersonation is configured
+			gcpConfig.ServiceAccountKey = ""
+		} else if len(strings.TrimSpace(gcpConfig.ServiceAccountKey)) == 0 && gcpConfig.SecretRef == nil {
+			return fmt.Errorf("must specify either service account key or service account email, cannot both be empty")
+		}
+
+		// validate region
+		if len(strings.TrimSpace(gcpConfig.Region)) == 0 {
 			return fmt.Errorf("region cannot be blank or empty")
 		}
 	}
 
-	if *r.Spec.PollIntervalInSeconds < 30 {
+	if err := r.validateCredentialSecret(); err != nil {
+		return err
+	}
+
+	if r.Spec.PollIntervalInSeconds != nil && *r.Spec.PollIntervalInSeconds < 30 {
 		return fmt.Errorf("pollIntervalInSeconds should be >= 30. If not specified, defaults to 60")
 	}
 
 	return nil
 }
 
-// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
-func (r *CloudProviderAccount) ValidateUpdate(old runtime.Object) error {
-	cloudprovideraccountlog.Info("validate update", "name", r.Name)
+func (r *CloudProviderAccount) GetAccountProviderType() (CloudProvider, error) {
+	if r.Spec.AWSConfig != nil {
+		return AWSCloudProvider, nil
+	} else if r.Spec.AzureConfig != nil {
+		return AzureCloudProvider, nil
+	} else if r.Spec.GCPConfig != nil {
+		return GCPCloudProvider, nil
+	} else {
+		return "", fmt.Errorf("missing cloud provider config. Please add AWS, Azure or GCP Config")
+	}
+}
 
-	// TODO(user): fill in your validation logic upon object update.
-	return nil
+// GetRegions returns regions of the AWS account, merging Region and Regions.
+func (c *CloudProviderAccountAWSConfig) GetRegions() []string {
+	return mergeRegions(c.Region, c.Regions)
 }
 
-// ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
-func (r *CloudProviderAccount) ValidateDelete() error {
-	cloudprovideraccountlog.Info("validate delete", "name", r.Name)
+// GetRegions returns regions of the Azure account, merging Region and Regions.
+func (c *CloudProviderAccountAzureConfig) GetRegions() []string {
+	return mergeRegions(c.Region, c.Regions)
+}
 
-	// TODO(user): fill in your validation logic upon object deletion.
+// mergeRegions returns unique non-empty regions in order of appearance.
+func mergeRegions(region string, regions []string) []string {
+	var merged []string
+	seen := make(map[string]struct{})
+	for _, r := range append([]string{region}, regions...) {
+		r = strings.TrimSpace(r)
+		if _, ok := seen[r]; ok || len(r) == 0 {
+			continue
+		}
+		seen[r] = struct{}{}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// validateRegions validates at least one region is specified, and AllRegions is not combined with other regions.
+func validateRegions(regions []string) error {
+	if len(regions) == 0 {
+		return fmt.Errorf("region cannot be blank or empty")
+	}
+	for _, region := range regions {
+		if region == AllRegions && len(regions) > 1 {
+			return fmt.Errorf("region %v cannot be combined with other regions", AllRegions)
+		}
+	}
 	return nil
 }
 
-func (r *CloudProviderAccount) GetAccountProviderType() (CloudProvider, error) {
+// validateEventQueueURL validates event queue URL, if specified, is an absolute http(s) URL.
+func validateEventQueueURL(queueURL string) error {
+	if len(queueURL) == 0 {
+		return nil
+	}
+	u, err := url.Parse(queueURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || len(u.Host) == 0 {
+		return fmt.Errorf("invalid event queue URL %v", queueURL)
+	}
+	return nil
+}
+
+// validateCredentialSecret validates credential secret is specified either inline or via secretRef, and that
+// inline credential secret is permitted by AllowInlineCredentials policy.
+func (r *CloudProviderAccount) validateCredentialSecret() error {
+	var inlineSecret string
+	var secretRef *SecretReference
 	if r.Spec.AWSConfig != nil {
-		return AWSCloudProvider, nil
+		inlineSecret, secretRef = r.Spec.AWSConfig.AccessKeySecret, r.Spec.AWSConfig.SecretRef
 	} else if r.Spec.AzureConfig != nil {
-		return AzureCloudProvider, nil
-	} else {
-		return "", fmt.Errorf("missing cloud provider config. Please add AWS or Azure Config")
+		inlineSecret, secretRef = r.Spec.AzureConfig.ClientKey, r.Spec.AzureConfig.SecretRef
+	} else if r.Spec.GCPConfig != nil {
+		inlineSecret, secretRef = r.Spec.GCPConfig.ServiceAccountKey, r.Spec.GCPConfig.SecretRef
 	}
+
+	if secretRef != nil {
+		if len(strings.TrimSpace(secretRef.Name)) == 0 || len(strings.TrimSpace(secretRef.Key)) == 0 {
+			return fmt.Errorf("secretRef name and key cannot be blank or empty")
+		}
+		if len(strings.TrimSpace(inlineSecret)) != 0 {
+			return fmt.Errorf("must specify either inline credential or secretRef, cannot specify both")
+		}
+		if len(secretRef.Namespace) != 0 && !IsSecretNamespaceAllowed(secretRef.Namespace, r.Namespace) {
+			return fmt.Errorf("secretRef namespace %s is not allowed, must be namespace of the account or of nephe controller",
+				secretRef.Namespace)
+		}
+	}
+	if len(strings.TrimSpace(inlineSecret)) != 0 && !AllowInlineCredentials {
+		return fmt.Errorf("inline credential is not allowed by cluster policy, must specify secretRef")
+	}
+	return nil
 }
@@ -41,6 +41,19 @@ type NetworkInterface struct {
 	MAC string `json:"mac,omitempty"`
 	// IP addresses of this NetworkInterface.
 	IPs []IPAddress `json:"ips,omitempty"`
+	// Subnet is the cloud subnet this NetworkInterface belongs to.
+	Subnet string `json:"subnet,omitempty"`
+}
+
+// MaxVirtualMachineStateHistory is the maximum number of state transitions kept in VirtualMachine status.
+const MaxVirtualMachineStateHistory = 10
+
+// VirtualMachineStateTransition records a state of a VirtualMachine and when the state is first observed.
+type VirtualMachineStateTransition struct {
+	// State of the VirtualMachine.
+	State string `json:"state"`
+	// TransitionTime is the time the state is first observed.
+	TransitionTime metav1.Time `json:"transitionTime"`
 }
 
 // VirtualMachineStatus defines the observed state of VirtualMachine
@@ -50,12 +63,29 @@ type VirtualMachineStatus struct {
 	Provider CloudProvider `json:"provider,omitempty"`
 	// VirtualPrivateCloud is the virtual private cloud this VirtualMachine belongs to.
 	VirtualPrivateCloud string `json:"virtualPrivateCloud,omitempty"`
+	// Region is the cloud region this VirtualMachine belongs to.
+	Region string `json:"region,omitempty"`
 	// Tags of this VirtualMachine. A corresponding label is also generated for each tag.
 	Tags map[string]string `json:"tags,omitempty"`
 	// NetworkInterfaces is array of NetworkInterfaces attached to this VirtualMachine.
 	NetworkInterfaces []NetworkInterface `json:"networkInterfaces,omitempty"`
 	// State indicates current state of the VirtualMachine.
 	State string `json:"state,omitempty"`
+	// StateHistory is the most recent state transitions of the VirtualMachine, oldest first, at most
+	// MaxVirtualMachineStateHistory transitions are kept.
+	StateHistory []VirtualMachineStateTransition `json:"stateHistory,omitempty"`
+	// InstanceType is the cloud instance type, or machine size, of this VirtualMachine.
+	InstanceType string `json:"instanceType,omitempty"`
+	// AvailabilityZone is the cloud availability zone this VirtualMachine belongs to.
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	// ImageID is the cloud image this VirtualMachine is launched from.
+	ImageID string `json:"imageID,omitempty"`
+	// OSType is the operating system type of this VirtualMachine, linux or windows.
+	OSType string `json:"osType,omitempty"`
+	// LaunchTime is the time this VirtualMachine was last launched.
+	LaunchTime *metav1.Time `json:"launchTime,omitempty"`
+	// SecurityGroups are cloud security groups attached to this VirtualMachine.
+	SecurityGroups []string `json:"securityGroups,omitempty"`
 }
 
 // +genclient
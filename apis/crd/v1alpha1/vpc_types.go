@@ -0,0 +1,68 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VpcStatus defines the observed state of Vpc.
+// It contains observable parameters.
+type VpcStatus struct {
+	// Provider specifies cloud provider of this Vpc.
+	Provider CloudProvider `json:"provider,omitempty"`
+	// ID is the cloud assigned identifier of this Vpc.
+	ID string `json:"id,omitempty"`
+	// Name is the cloud assigned name of this Vpc.
+	Name string `json:"name,omitempty"`
+	// Region is the cloud region this Vpc belongs to.
+	Region string `json:"region,omitempty"`
+	// CIDRs are address ranges of this Vpc.
+	CIDRs []string `json:"cidrs,omitempty"`
+	// Tags of this Vpc.
+	Tags map[string]string `json:"tags,omitempty"`
+	// Peerings are cloud assigned identifiers of Vpcs peered with this Vpc.
+	Peerings []string `json:"peerings,omitempty"`
+}
+
+// +genclient
+// +kubebuilder:object:root=true
+
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName="vpc"
+// +kubebuilder:printcolumn:name="Cloud-Provider",type=string,JSONPath=`.status.provider`
+// +kubebuilder:printcolumn:name="Region",type=string,JSONPath=`.status.region`
+// +kubebuilder:printcolumn:name="Cloud-Name",type=string,JSONPath=`.status.name`
+// Vpc is the Schema for the vpcs API
+// A Vpc object is created automatically for each virtual private cloud
+// of the account of a CloudEntitySelector.
+type Vpc struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status VpcStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// VpcList contains a list of Vpc.
+type VpcList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Vpc `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Vpc{}, &VpcList{})
+}
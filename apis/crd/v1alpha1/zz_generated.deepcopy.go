@@ -20,6 +20,7 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -91,6 +92,11 @@ func (in *CloudEntitySelectorSpec) DeepCopyInto(out *CloudEntitySelectorSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.EndpointAddressTypes != nil {
+		in, out := &in.EndpointAddressTypes, &out.EndpointAddressTypes
+		*out = make([]AddressType, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudEntitySelectorSpec.
@@ -109,7 +115,7 @@ func (in *CloudProviderAccount) DeepCopyInto(out *CloudProviderAccount) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudProviderAccount.
@@ -133,6 +139,16 @@ func (in *CloudProviderAccount) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CloudProviderAccountAWSConfig) DeepCopyInto(out *CloudProviderAccountAWSConfig) {
 	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.Regions != nil {
+		in, out := &in.Regions, &out.Regions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudProviderAccountAWSConfig.
@@ -148,6 +164,16 @@ func (in *CloudProviderAccountAWSConfig) DeepCopy() *CloudProviderAccountAWSConf
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CloudProviderAccountAzureConfig) DeepCopyInto(out *CloudProviderAccountAzureConfig) {
 	*out = *in
+	if in.Regions != nil {
+		in, out := &in.Regions, &out.Regions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudProviderAccountAzureConfig.
@@ -160,6 +186,26 @@ func (in *CloudProviderAccountAzureConfig) DeepCopy() *CloudProviderAccountAzure
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudProviderAccountGCPConfig) DeepCopyInto(out *CloudProviderAccountGCPConfig) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudProviderAccountGCPConfig.
+func (in *CloudProviderAccountGCPConfig) DeepCopy() *CloudProviderAccountGCPConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudProviderAccountGCPConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CloudProviderAccountList) DeepCopyInto(out *CloudProviderAccountList) {
 	*out = *in
@@ -203,12 +249,17 @@ func (in *CloudProviderAccountSpec) DeepCopyInto(out *CloudProviderAccountSpec)
 	if in.AWSConfig != nil {
 		in, out := &in.AWSConfig, &out.AWSConfig
 		*out = new(CloudProviderAccountAWSConfig)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.AzureConfig != nil {
 		in, out := &in.AzureConfig, &out.AzureConfig
 		*out = new(CloudProviderAccountAzureConfig)
-		**out = **in
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GCPConfig != nil {
+		in, out := &in.GCPConfig, &out.GCPConfig
+		*out = new(CloudProviderAccountGCPConfig)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -225,6 +276,31 @@ func (in *CloudProviderAccountSpec) DeepCopy() *CloudProviderAccountSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CloudProviderAccountStatus) DeepCopyInto(out *CloudProviderAccountStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSuccessfulPollTime != nil {
+		in, out := &in.LastSuccessfulPollTime, &out.LastSuccessfulPollTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ServiceStats != nil {
+		in, out := &in.ServiceStats, &out.ServiceStats
+		*out = make([]CloudServiceStats, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PlannedSecurityGroups != nil {
+		in, out := &in.PlannedSecurityGroups, &out.PlannedSecurityGroups
+		*out = make([]PlannedSecurityGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudProviderAccountStatus.
@@ -237,9 +313,39 @@ func (in *CloudProviderAccountStatus) DeepCopy() *CloudProviderAccountStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudServiceStats) DeepCopyInto(out *CloudServiceStats) {
+	*out = *in
+	if in.LastSuccessfulPollTime != nil {
+		in, out := &in.LastSuccessfulPollTime, &out.LastSuccessfulPollTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastPollErrorTime != nil {
+		in, out := &in.LastPollErrorTime, &out.LastPollErrorTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudServiceStats.
+func (in *CloudServiceStats) DeepCopy() *CloudServiceStats {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudServiceStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EntityMatch) DeepCopyInto(out *EntityMatch) {
 	*out = *in
+	if in.MatchTags != nil {
+		in, out := &in.MatchTags, &out.MatchTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EntityMatch.
@@ -287,6 +393,57 @@ func (in *NetworkInterface) DeepCopy() *NetworkInterface {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlannedSecurityGroup) DeepCopyInto(out *PlannedSecurityGroup) {
+	*out = *in
+	if in.Operations != nil {
+		in, out := &in.Operations, &out.Operations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IngressRules != nil {
+		in, out := &in.IngressRules, &out.IngressRules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EgressRules != nil {
+		in, out := &in.EgressRules, &out.EgressRules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.LastOperationTime.DeepCopyInto(&out.LastOperationTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlannedSecurityGroup.
+func (in *PlannedSecurityGroup) DeepCopy() *PlannedSecurityGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(PlannedSecurityGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachine) DeepCopyInto(out *VirtualMachine) {
 	*out = *in
@@ -351,12 +508,14 @@ func (in *VirtualMachineSelector) DeepCopyInto(out *VirtualMachineSelector) {
 	if in.VpcMatch != nil {
 		in, out := &in.VpcMatch, &out.VpcMatch
 		*out = new(EntityMatch)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.VMMatch != nil {
 		in, out := &in.VMMatch, &out.VMMatch
 		*out = make([]EntityMatch, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -370,6 +529,22 @@ func (in *VirtualMachineSelector) DeepCopy() *VirtualMachineSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineStateTransition) DeepCopyInto(out *VirtualMachineStateTransition) {
+	*out = *in
+	in.TransitionTime.DeepCopyInto(&out.TransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineStateTransition.
+func (in *VirtualMachineStateTransition) DeepCopy() *VirtualMachineStateTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineStateTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachineStatus) DeepCopyInto(out *VirtualMachineStatus) {
 	*out = *in
@@ -387,6 +562,22 @@ func (in *VirtualMachineStatus) DeepCopyInto(out *VirtualMachineStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.StateHistory != nil {
+		in, out := &in.StateHistory, &out.StateHistory
+		*out = make([]VirtualMachineStateTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LaunchTime != nil {
+		in, out := &in.LaunchTime, &out.LaunchTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SecurityGroups != nil {
+		in, out := &in.SecurityGroups, &out.SecurityGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineStatus.
@@ -398,3 +589,93 @@ func (in *VirtualMachineStatus) DeepCopy() *VirtualMachineStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Vpc) DeepCopyInto(out *Vpc) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Vpc.
+func (in *Vpc) DeepCopy() *Vpc {
+	if in == nil {
+		return nil
+	}
+	out := new(Vpc)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Vpc) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VpcList) DeepCopyInto(out *VpcList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Vpc, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VpcList.
+func (in *VpcList) DeepCopy() *VpcList {
+	if in == nil {
+		return nil
+	}
+	out := new(VpcList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VpcList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VpcStatus) DeepCopyInto(out *VpcStatus) {
+	*out = *in
+	if in.CIDRs != nil {
+		in, out := &in.CIDRs, &out.CIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Peerings != nil {
+		in, out := &in.Peerings, &out.Peerings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VpcStatus.
+func (in *VpcStatus) DeepCopy() *VpcStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VpcStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -24,6 +24,8 @@ const (
 	Success    Realization = "SUCCESS"
 	InProgress Realization = "IN-PROGRESS"
 	Failed     Realization = "FAILED"
+	// DryRun indicates a NetworkPolicy is planned but not applied to the cloud, as the account is in dry-run mode.
+	DryRun Realization = "DRY-RUN"
 )
 
 type NetworkPolicyStatus struct {
@@ -19,4 +19,8 @@ const (
 	defaultLeaderElectionFlag = false
 	defaultMetricsAddress     = ":8080"
 	defaultDebugLogFlag       = false
+	defaultAllowInlineCreds   = true
+	defaultEnforcementDryRun  = false
+	// podNamespaceEnv is the environment variable of nephe controller namespace.
+	podNamespaceEnv = "POD_NAMESPACE"
 )
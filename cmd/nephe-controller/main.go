@@ -27,6 +27,7 @@ import (
 	crdv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
 	runtimev1alpha1 "antrea.io/nephe/apis/runtime/v1alpha1"
 	"antrea.io/nephe/pkg/apiserver"
+	cloudprovider "antrea.io/nephe/pkg/cloud-provider"
 	controllers "antrea.io/nephe/pkg/controllers/cloud"
 	"antrea.io/nephe/pkg/logging"
 	// +kubebuilder:scaffold:imports
@@ -51,6 +52,8 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var enableDebugLog bool
+	var allowInlineCredentials bool
+	var enforcementDryRun bool
 
 	flag.StringVar(&metricsAddr, "metrics-addr", defaultMetricsAddress, "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", defaultLeaderElectionFlag,
@@ -58,9 +61,18 @@ func main() {
 			"Enabling this will ensure there is only one active cloud-controller manager.")
 	flag.BoolVar(&enableDebugLog, "enable-debug-log", defaultDebugLogFlag,
 		"Enable debug mode for cloud-controller manager. Enabling this will add debug logs")
+	flag.BoolVar(&allowInlineCredentials, "allow-inline-credentials", defaultAllowInlineCreds,
+		"Allow credential secrets to be specified inline in CloudProviderAccount. "+
+			"Disabling this will require credential secrets to be referenced from Kubernetes Secrets using secretRef.")
+	flag.BoolVar(&enforcementDryRun, "enforcement-dry-run", defaultEnforcementDryRun,
+		"Compute cloud security groups of all accounts without applying them to the cloud. "+
+			"Intended security groups are reported in CloudProviderAccount status.")
 	flag.Parse()
 
 	logging.SetDebugLog(enableDebugLog)
+	crdv1alpha1.AllowInlineCredentials = allowInlineCredentials
+	crdv1alpha1.NepheNamespace = os.Getenv(podNamespaceEnv)
+	cloudprovider.EnforcementDryRun = enforcementDryRun
 	ctrl.SetLogger(logging.GetLogger("setup"))
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
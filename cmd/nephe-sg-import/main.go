@@ -0,0 +1,141 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// nephe-sg-import prints Antrea Groups and Antrea NetworkPolicies equivalent to existing cloud security groups of a
+// CloudProviderAccount, so that they can be reviewed and applied before nephe takes over security of the account.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	crdv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
+	cloudprovider "antrea.io/nephe/pkg/cloud-provider"
+	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/controllers/utils"
+	"antrea.io/nephe/pkg/logging"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = crdv1alpha1.AddToScheme(scheme)
+}
+
+func main() {
+	var namespace string
+	var accountName string
+	var enableDebugLog bool
+
+	flag.StringVar(&namespace, "namespace", "default", "Namespace of the CloudProviderAccount.")
+	flag.StringVar(&accountName, "account", "", "Name of the CloudProviderAccount whose cloud security groups are imported.")
+	flag.StringVar(&crdv1alpha1.NepheNamespace, "nephe-namespace", "",
+		"Namespace of nephe controller, whose credential Secrets CloudProviderAccounts may reference.")
+	flag.BoolVar(&enableDebugLog, "enable-debug-log", false, "Enable debug logs.")
+	flag.Parse()
+
+	logging.SetDebugLog(enableDebugLog)
+	if len(accountName) == 0 {
+		fmt.Fprintln(os.Stderr, "--account must be specified")
+		os.Exit(2)
+	}
+
+	k8sClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create client: %v\n", err)
+		os.Exit(1)
+	}
+	result, err := importSecurityGroups(context.Background(), k8sClient,
+		&types.NamespacedName{Namespace: namespace, Name: accountName})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to import security groups: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", warning)
+	}
+	for _, obj := range result.Objects {
+		out, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal %v %v: %v\n", obj.GetKind(), obj.GetName(), err)
+			os.Exit(1)
+		}
+		fmt.Printf("---\n%s", out)
+	}
+}
+
+// importSecurityGroups adds the account and its CloudEntitySelectors to the cloud plug-in, for an inventory of the
+// account, and imports cloud security groups of the inventory. The account is added without event queue, so that
+// events of the controller are not consumed.
+func importSecurityGroups(ctx context.Context, k8sClient client.Client, accNamespacedName *types.NamespacedName) (
+	*cloudprovider.SecurityGroupImport, error) {
+	account := &crdv1alpha1.CloudProviderAccount{}
+	if err := k8sClient.Get(ctx, *accNamespacedName, account); err != nil {
+		return nil, fmt.Errorf("failed to get CloudProviderAccount %v: %w", *accNamespacedName, err)
+	}
+	if err := utils.ResolveSecretRef(ctx, k8sClient, account); err != nil {
+		return nil, err
+	}
+	if account.Spec.AWSConfig != nil {
+		account.Spec.AWSConfig.EventQueueURL = ""
+	} else if account.Spec.AzureConfig != nil {
+		account.Spec.AzureConfig.EventQueueURL = ""
+	}
+	account.Default()
+
+	selectorList := &crdv1alpha1.CloudEntitySelectorList{}
+	if err := k8sClient.List(ctx, selectorList, client.InNamespace(accNamespacedName.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list CloudEntitySelectors: %w", err)
+	}
+	var selectors []*crdv1alpha1.CloudEntitySelector
+	for i := range selectorList.Items {
+		if selectorList.Items[i].Spec.AccountName == accNamespacedName.Name {
+			selectors = append(selectors, &selectorList.Items[i])
+		}
+	}
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("no CloudEntitySelector of CloudProviderAccount %v, inventory of the account is empty",
+			*accNamespacedName)
+	}
+
+	providerType, err := account.GetAccountProviderType()
+	if err != nil {
+		return nil, err
+	}
+	cloudInterface, err := cloudprovider.GetCloudInterface(cloudcommon.ProviderType(providerType))
+	if err != nil {
+		return nil, err
+	}
+	if err := cloudInterface.AddProviderAccount(account); err != nil {
+		return nil, fmt.Errorf("failed to add CloudProviderAccount %v: %w", *accNamespacedName, err)
+	}
+	defer cloudInterface.RemoveProviderAccount(accNamespacedName)
+	for _, selector := range selectors {
+		if err := cloudInterface.AddAccountResourceSelector(accNamespacedName, selector); err != nil {
+			return nil, fmt.Errorf("failed to add CloudEntitySelector %v: %w", selector.Name, err)
+		}
+	}
+	return cloudprovider.ImportSecurityGroups(cloudInterface, accNamespacedName)
+}
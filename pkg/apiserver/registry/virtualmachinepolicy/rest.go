@@ -16,18 +16,24 @@ package virtualmachinepolicy
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
 	logger "github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metatable "k8s.io/apimachinery/pkg/api/meta/table"
 	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/registry/rest"
 	"k8s.io/client-go/tools/cache"
-	"strings"
 
 	runtimev1alpha1 "antrea.io/nephe/apis/runtime/v1alpha1"
 	"antrea.io/nephe/pkg/controllers/cloud"
@@ -44,9 +50,10 @@ const (
 )
 
 var (
-	_ rest.Scoper = &REST{}
-	_ rest.Getter = &REST{}
-	_ rest.Lister = &REST{}
+	_ rest.Scoper  = &REST{}
+	_ rest.Getter  = &REST{}
+	_ rest.Lister  = &REST{}
+	_ rest.Watcher = &REST{}
 )
 
 // NewREST returns a REST object that will work against API services.
@@ -82,7 +89,7 @@ func (r *REST) Get(ctx context.Context, name string, _ *metav1.GetOptions) (runt
 	return r.convertToVMP(obj.(*cloud.NetworkPolicyStatus)), nil
 }
 
-func (r *REST) List(ctx context.Context, _ *internalversion.ListOptions) (runtime.Object, error) {
+func (r *REST) List(ctx context.Context, options *internalversion.ListOptions) (runtime.Object, error) {
 	ns, _ := request.NamespaceFrom(ctx)
 	var objs []interface{}
 	if ns == "" {
@@ -91,13 +98,49 @@ func (r *REST) List(ctx context.Context, _ *internalversion.ListOptions) (runtim
 		objs, _ = r.vmpIndexer.ByIndex(cloud.NetworkPolicyStatusIndexerByNamespace, ns)
 	}
 	vmpList := &runtimev1alpha1.VirtualMachinePolicyList{}
+	if watcher, ok := r.vmpIndexer.(cloud.NetworkPolicyStatusWatcher); ok {
+		vmpList.ResourceVersion = strconv.FormatUint(watcher.GetResourceVersion(), 10)
+	}
 	for _, obj := range objs {
 		vmp := r.convertToVMP(obj.(*cloud.NetworkPolicyStatus))
+		if !matchesSelectors(vmp, options) {
+			continue
+		}
 		vmpList.Items = append(vmpList.Items, *vmp)
 	}
 	return vmpList, nil
 }
 
+// Watch returns changes of VirtualMachinePolicies matching options after options.ResourceVersion.
+func (r *REST) Watch(ctx context.Context, options *internalversion.ListOptions) (watch.Interface, error) {
+	watcher, ok := r.vmpIndexer.(cloud.NetworkPolicyStatusWatcher)
+	if !ok {
+		return nil, errors.NewMethodNotSupported(runtimev1alpha1.Resource("virtualmachinepolicy"), "watch")
+	}
+	var resourceVersion uint64
+	if options != nil && len(options.ResourceVersion) > 0 {
+		var err error
+		if resourceVersion, err = strconv.ParseUint(options.ResourceVersion, 10, 64); err != nil {
+			return nil, errors.NewBadRequest(fmt.Sprintf("invalid resourceVersion %s", options.ResourceVersion))
+		}
+	}
+	events, stop, err := watcher.Watch(resourceVersion)
+	if err != nil {
+		return nil, err
+	}
+	ns, _ := request.NamespaceFrom(ctx)
+	w := &vmpWatcher{
+		rest:      r,
+		namespace: ns,
+		options:   options,
+		result:    make(chan watch.Event),
+		done:      make(chan struct{}),
+		stop:      stop,
+	}
+	go w.run(events)
+	return w, nil
+}
+
 func (r *REST) NamespaceScoped() bool {
 	return true
 }
@@ -128,14 +171,36 @@ func (r *REST) ConvertToTable(_ context.Context, obj runtime.Object, _ runtime.O
 	return table, err
 }
 
+// matchesSelectors returns true if VirtualMachinePolicy matches label and field selectors of options.
+func matchesSelectors(vmp *runtimev1alpha1.VirtualMachinePolicy, options *internalversion.ListOptions) bool {
+	if options == nil {
+		return true
+	}
+	if options.LabelSelector != nil && !options.LabelSelector.Matches(labels.Set(vmp.Labels)) {
+		return false
+	}
+	if options.FieldSelector != nil && !options.FieldSelector.Matches(fields.Set{
+		"metadata.name":      vmp.Name,
+		"metadata.namespace": vmp.Namespace,
+		"status.realization": string(vmp.Status.Realization),
+	}) {
+		return false
+	}
+	return true
+}
+
 func (r *REST) convertToVMP(internal *cloud.NetworkPolicyStatus) *runtimev1alpha1.VirtualMachinePolicy {
 	i := cloud.InProgress{}
 	failed := false
 	inProgress := false
+	dryRun := false
 	npStatusList := make(map[string]*runtimev1alpha1.NetworkPolicyStatus)
 	for anp, status := range internal.NPStatus {
 		if status == cloud.NetworkPolicyStatusApplied {
 			npStatusList[anp] = &runtimev1alpha1.NetworkPolicyStatus{Realization: runtimev1alpha1.Success, Reason: NoneString}
+		} else if status == cloud.NetworkPolicyStatusDryRun {
+			npStatusList[anp] = &runtimev1alpha1.NetworkPolicyStatus{Realization: runtimev1alpha1.DryRun, Reason: NoneString}
+			dryRun = true
 		} else if strings.Contains(status, i.String()) {
 			npStatusList[anp] = &runtimev1alpha1.NetworkPolicyStatus{Realization: runtimev1alpha1.InProgress, Reason: NoneString}
 			inProgress = true
@@ -149,11 +214,16 @@ func (r *REST) convertToVMP(internal *cloud.NetworkPolicyStatus) *runtimev1alpha
 		realization = runtimev1alpha1.Failed
 	} else if inProgress {
 		realization = runtimev1alpha1.InProgress
+	} else if dryRun {
+		realization = runtimev1alpha1.DryRun
 	}
 
 	vmp := &runtimev1alpha1.VirtualMachinePolicy{}
 	vmp.Namespace = internal.Namespace
 	vmp.Name = internal.Name
+	if internal.ResourceVersion != 0 {
+		vmp.ResourceVersion = strconv.FormatUint(internal.ResourceVersion, 10)
+	}
 	vmp.Status.Realization = realization
 	vmp.Status.NetworkPolicyDetails = npStatusList
 	return vmp
@@ -23,9 +23,12 @@ import (
 	logger "github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/client-go/tools/cache"
 )
@@ -206,5 +209,116 @@ var _ = Describe("Virtualmachinepolicy", func() {
 			Expect(err).Should(BeNil())
 			Expect(actualObj).To(Equal(expectedPoliyList))
 		})
+		It("Should filter List result by field selector", func() {
+			indexer := cloud.NewNetworkPolicyStatusIndexer()
+			_ = indexer.Add(newNetworkPolicyStatus("default", "vm1", "applied"))
+			_ = indexer.Add(newNetworkPolicyStatus("default", "vm2", "error"))
+			rest := NewREST(indexer, l)
+			actualObj, err := rest.List(request.NewDefaultContext(),
+				&internalversion.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.realization", "FAILED")})
+			Expect(err).Should(BeNil())
+			vmpList := actualObj.(*v1alpha1.VirtualMachinePolicyList)
+			Expect(vmpList.ResourceVersion).To(Equal("2"))
+			Expect(vmpList.Items).To(HaveLen(1))
+			Expect(vmpList.Items[0].Name).To(Equal("vm2"))
+			Expect(vmpList.Items[0].ResourceVersion).To(Equal("2"))
+		})
+	})
+	Describe("Test Watch function of Rest", func() {
+		var (
+			indexer cache.Indexer
+			rest    *REST
+		)
+		BeforeEach(func() {
+			indexer = cloud.NewNetworkPolicyStatusIndexer()
+			rest = NewREST(indexer, l)
+		})
+		expectEvent := func(w watch.Interface, eventType watch.EventType, name string, realization v1alpha1.Realization) {
+			var event watch.Event
+			Eventually(w.ResultChan()).Should(Receive(&event))
+			Expect(event.Type).To(Equal(eventType))
+			vmp := event.Object.(*v1alpha1.VirtualMachinePolicy)
+			Expect(vmp.Name).To(Equal(name))
+			Expect(vmp.Status.Realization).To(Equal(realization))
+		}
+
+		It("Should watch existing and changed VirtualMachinePolicies", func() {
+			_ = indexer.Add(newNetworkPolicyStatus("default", "vm1", "applied"))
+			w, err := rest.Watch(request.NewDefaultContext(), &internalversion.ListOptions{})
+			Expect(err).Should(BeNil())
+			defer w.Stop()
+			expectEvent(w, watch.Added, "vm1", v1alpha1.Success)
+
+			obj, _, _ := indexer.GetByKey(types.NamespacedName{Namespace: "default", Name: "vm1"}.String())
+			npStatus := obj.(*cloud.NetworkPolicyStatus)
+			npStatus.NPStatus = map[string]string{"test1": "error"}
+			_ = indexer.Update(npStatus)
+			expectEvent(w, watch.Modified, "vm1", v1alpha1.Failed)
+
+			_ = indexer.Delete(npStatus)
+			expectEvent(w, watch.Deleted, "vm1", v1alpha1.Failed)
+		})
+		It("Should watch changes after resourceVersion", func() {
+			_ = indexer.Add(newNetworkPolicyStatus("default", "vm1", "applied"))
+			obj, err := rest.List(request.NewDefaultContext(), &internalversion.ListOptions{})
+			Expect(err).Should(BeNil())
+			_ = indexer.Add(newNetworkPolicyStatus("default", "vm2", "applied"))
+
+			w, err := rest.Watch(request.NewDefaultContext(),
+				&internalversion.ListOptions{ResourceVersion: obj.(*v1alpha1.VirtualMachinePolicyList).ResourceVersion})
+			Expect(err).Should(BeNil())
+			defer w.Stop()
+			expectEvent(w, watch.Added, "vm2", v1alpha1.Success)
+			Consistently(w.ResultChan()).ShouldNot(Receive())
+		})
+		It("Should watch VirtualMachinePolicies in namespace", func() {
+			w, err := rest.Watch(request.WithNamespace(context.TODO(), "ns1"), &internalversion.ListOptions{})
+			Expect(err).Should(BeNil())
+			defer w.Stop()
+			_ = indexer.Add(newNetworkPolicyStatus("ns2", "vm1", "applied"))
+			_ = indexer.Add(newNetworkPolicyStatus("ns1", "vm2", "applied"))
+			expectEvent(w, watch.Added, "vm2", v1alpha1.Success)
+		})
+		It("Should watch VirtualMachinePolicies entering and leaving field selector", func() {
+			npStatus := newNetworkPolicyStatus("default", "vm1", "applied")
+			_ = indexer.Add(npStatus)
+			w, err := rest.Watch(request.NewDefaultContext(),
+				&internalversion.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.realization", "FAILED")})
+			Expect(err).Should(BeNil())
+			defer w.Stop()
+
+			npStatus.NPStatus = map[string]string{"test1": "error"}
+			_ = indexer.Update(npStatus)
+			expectEvent(w, watch.Added, "vm1", v1alpha1.Failed)
+			npStatus.NPStatus = map[string]string{"test1": "applied"}
+			_ = indexer.Update(npStatus)
+			expectEvent(w, watch.Deleted, "vm1", v1alpha1.Success)
+		})
+		It("Should fail to watch with invalid resourceVersion", func() {
+			_ = indexer.Add(newNetworkPolicyStatus("default", "vm1", "applied"))
+			_, err := rest.Watch(request.NewDefaultContext(), &internalversion.ListOptions{ResourceVersion: "abc"})
+			Expect(errors.IsBadRequest(err)).To(BeTrue())
+			_, err = rest.Watch(request.NewDefaultContext(), &internalversion.ListOptions{ResourceVersion: "10"})
+			Expect(errors.IsBadRequest(err)).To(BeTrue())
+		})
+		It("Should fail to watch with expired resourceVersion", func() {
+			npStatus := newNetworkPolicyStatus("default", "vm1", "applied")
+			for i := 0; i < 1002; i++ {
+				_ = indexer.Update(npStatus)
+			}
+			_, err := rest.Watch(request.NewDefaultContext(), &internalversion.ListOptions{ResourceVersion: "1"})
+			Expect(errors.IsResourceExpired(err)).To(BeTrue())
+		})
+		It("Should not support watch without watchable indexer", func() {
+			_, err := NewREST(virtualMachinePolicyIndexer1, l).Watch(request.NewDefaultContext(), &internalversion.ListOptions{})
+			Expect(errors.IsMethodNotSupported(err)).To(BeTrue())
+		})
 	})
 })
+
+func newNetworkPolicyStatus(namespace, name, status string) *cloud.NetworkPolicyStatus {
+	return &cloud.NetworkPolicyStatus{
+		NamespacedName: types.NamespacedName{Namespace: namespace, Name: name},
+		NPStatus:       map[string]string{"test1": status},
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package virtualmachinepolicy
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"antrea.io/nephe/pkg/controllers/cloud"
+)
+
+// vmpWatcher implements watch.Interface, it converts NetworkPolicyStatus changes to VirtualMachinePolicy events.
+type vmpWatcher struct {
+	rest      *REST
+	namespace string
+	options   *internalversion.ListOptions
+	result    chan watch.Event
+	done      chan struct{}
+	stop      func()
+	stopOnce  sync.Once
+}
+
+var _ watch.Interface = &vmpWatcher{}
+
+func (w *vmpWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+func (w *vmpWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.stop()
+	})
+}
+
+func (w *vmpWatcher) run(events <-chan cloud.NetworkPolicyStatusEvent) {
+	defer close(w.result)
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			watchEvent, ok := w.convertEvent(event)
+			if !ok {
+				continue
+			}
+			select {
+			case w.result <- watchEvent:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// convertEvent converts NetworkPolicyStatus change to VirtualMachinePolicy event, it returns false if the change
+// is not visible to watcher. A VirtualMachinePolicy no longer matching selectors is reported as deleted, and
+// a VirtualMachinePolicy starting to match selectors is reported as added.
+func (w *vmpWatcher) convertEvent(event cloud.NetworkPolicyStatusEvent) (watch.Event, bool) {
+	if len(w.namespace) > 0 && event.Status.Namespace != w.namespace {
+		return watch.Event{}, false
+	}
+	vmp := w.rest.convertToVMP(event.Status)
+	matched := matchesSelectors(vmp, w.options)
+	if event.Type == watch.Deleted {
+		return watch.Event{Type: watch.Deleted, Object: vmp}, matched
+	}
+
+	prevMatched := false
+	if event.Type == watch.Modified && event.PrevStatus != nil {
+		prevMatched = matchesSelectors(w.rest.convertToVMP(event.PrevStatus), w.options)
+	}
+	switch {
+	case matched && prevMatched:
+		return watch.Event{Type: watch.Modified, Object: vmp}, true
+	case matched:
+		return watch.Event{Type: watch.Added, Object: vmp}, true
+	case prevMatched:
+		return watch.Event{Type: watch.Deleted, Object: vmp}, true
+	}
+	return watch.Event{}, false
+}
@@ -16,6 +16,7 @@ package aws
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws/endpoints"
@@ -28,9 +29,17 @@ type awsAccountCredentials struct {
 	accountID       string
 	accessKeyID     string
 	accessKeySecret string
-	region          string
+	region          string   // region of service api clients.
+	regions         []string // all regions of the account.
 	roleArn         string
 	externalID      string
+	eventQueueURL   string
+	// securityGroupMode is how security groups not created by nephe are treated on network interfaces.
+	securityGroupMode v1alpha1.SecurityGroupMode
+	// prefixListThreshold is the number of CIDRs of a rule realized as a prefix list, 0 if disabled.
+	prefixListThreshold int
+	// securityGroupQuotas are quotas of security groups of the account.
+	securityGroupQuotas awsSecurityGroupQuotas
 }
 
 // setAccountCredentials sets account credentials.
@@ -40,21 +49,37 @@ func setAccountCredentials(credentials interface{}) (interface{}, error) {
 		accountID:       strings.TrimSpace(awsConfig.AccountID),
 		accessKeyID:     strings.TrimSpace(awsConfig.AccessKeyID),
 		accessKeySecret: strings.TrimSpace(awsConfig.AccessKeySecret),
-		region:          strings.TrimSpace(awsConfig.Region),
+		regions:         awsConfig.GetRegions(),
 		roleArn:         strings.TrimSpace(awsConfig.RoleArn),
 		externalID:      strings.TrimSpace(awsConfig.ExternalID),
+		eventQueueURL:   strings.TrimSpace(awsConfig.EventQueueURL),
+
+		securityGroupMode:   awsConfig.SecurityGroupMode,
+		prefixListThreshold: int(awsConfig.PrefixListThreshold),
+		securityGroupQuotas: awsSecurityGroupQuotas{
+			rulesPerSecurityGroup:             int(awsConfig.RulesPerSecurityGroupQuota),
+			securityGroupsPerNetworkInterface: int(awsConfig.SecurityGroupsPerNetworkInterfaceQuota),
+			entriesPerNetworkACL:              int(awsConfig.EntriesPerNetworkACLQuota),
+		},
 	}
 
 	// NOTE: currently only AWS standard partition regions supported (aws-cn, aws-us-gov etc are not
 	// supported). As we add support for other partitions, validation needs to be updated
+	if len(accCreds.regions) == 0 {
+		return nil, fmt.Errorf("region cannot be blank or empty")
+	}
+	if len(accCreds.regions) == 1 && accCreds.regions[0] == v1alpha1.AllRegions {
+		return accCreds, nil
+	}
 	regions := endpoints.AwsPartition().Regions()
-	_, found := regions[accCreds.region]
-	if !found {
-		var supportedRegions []string
-		for key := range regions {
-			supportedRegions = append(supportedRegions, key)
+	for _, region := range accCreds.regions {
+		if _, found := regions[region]; !found {
+			var supportedRegions []string
+			for key := range regions {
+				supportedRegions = append(supportedRegions, key)
+			}
+			return nil, fmt.Errorf("%v not in supported regions [%v]", region, supportedRegions)
 		}
-		return nil, fmt.Errorf("%v not in supported regions [%v]", accCreds.region, supportedRegions)
 	}
 
 	return accCreds, nil
@@ -77,35 +102,73 @@ func compareAccountCredentials(accountName string, existing interface{}, new int
 		credsChanged = true
 		awsPluginLogger().Info("account access key secret updated", "account", accountName)
 	}
-	if strings.Compare(existingCreds.region, newCreds.region) != 0 {
+	if strings.Join(existingCreds.regions, ",") != strings.Join(newCreds.regions, ",") {
+		credsChanged = true
+		awsPluginLogger().Info("account regions updated", "account", accountName)
+	}
+	if existingCreds.eventQueueURL != newCreds.eventQueueURL {
 		credsChanged = true
-		awsPluginLogger().Info("account region updated", "account", accountName)
+		awsPluginLogger().Info("account event queue URL updated", "account", accountName)
+	}
+	if existingCreds.securityGroupMode != newCreds.securityGroupMode {
+		credsChanged = true
+		awsPluginLogger().Info("account security group mode updated", "account", accountName)
+	}
+	if existingCreds.prefixListThreshold != newCreds.prefixListThreshold {
+		credsChanged = true
+		awsPluginLogger().Info("account prefix list threshold updated", "account", accountName)
+	}
+	if existingCreds.securityGroupQuotas != newCreds.securityGroupQuotas {
+		credsChanged = true
+		awsPluginLogger().Info("account security group quotas updated", "account", accountName)
 	}
 	return credsChanged
 }
 
-// getVpcAccount returns first found account config to which this vpc id belongs.
-func (c *awsCloud) getVpcAccount(vpcID string) internal.CloudAccountInterface {
+// getVpcAccount returns first found account config to which this vpc id belongs, and ec2 service config of the
+// region of the vpc.
+func (c *awsCloud) getVpcAccount(vpcID string) (internal.CloudAccountInterface, *ec2ServiceConfig) {
 	accCfgs := c.cloudCommon.GetCloudAccounts()
 	if len(accCfgs) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	for _, accCfg := range accCfgs {
-		ec2ServiceCfg, err := accCfg.GetServiceConfigByName(awsComputeServiceNameEC2)
-		if err != nil {
-			awsPluginLogger().Error(err, "get ec2 service config failed", "vpcID", vpcID, "account", accCfg.GetNamespacedName())
+		ec2ServiceCfgs := getEC2ServiceConfigs(accCfg)
+		if len(ec2ServiceCfgs) == 0 {
+			awsPluginLogger().Info("no ec2 service config found for account", "vpcID", vpcID, "account", accCfg.GetNamespacedName())
 			continue
 		}
-		accVpcIDs := ec2ServiceCfg.(*ec2ServiceConfig).getCachedVpcIDs()
-		if len(accVpcIDs) == 0 {
-			awsPluginLogger().Info("no vpc found for account", "vpcID", vpcID, "account", accCfg.GetNamespacedName())
+		for _, ec2ServiceCfg := range ec2ServiceCfgs {
+			accVpcIDs := ec2ServiceCfg.getCachedVpcIDs()
+			if len(accVpcIDs) == 0 {
+				awsPluginLogger().Info("no vpc found for account", "vpcID", vpcID, "account", accCfg.GetNamespacedName(),
+					"region", ec2ServiceCfg.region)
+				continue
+			}
+			if _, found := accVpcIDs[strings.ToLower(vpcID)]; found {
+				return accCfg, ec2ServiceCfg
+			}
+		}
+		awsPluginLogger().Info("vpcID not found in cache", "vpcID", vpcID, "account", accCfg.GetNamespacedName())
+	}
+	return nil, nil
+}
+
+// getEC2ServiceConfigs returns ec2 service configs of all regions of an account, sorted by region.
+func getEC2ServiceConfigs(accCfg internal.CloudAccountInterface) []*ec2ServiceConfig {
+	var ec2ServiceCfgs []*ec2ServiceConfig
+	for name := range accCfg.GetServiceConfigs() {
+		serviceCfg, err := accCfg.GetServiceConfigByName(name)
+		if err != nil {
 			continue
 		}
-		if _, found := accVpcIDs[strings.ToLower(vpcID)]; found {
-			return accCfg
+		if ec2ServiceCfg, ok := serviceCfg.(*ec2ServiceConfig); ok {
+			ec2ServiceCfgs = append(ec2ServiceCfgs, ec2ServiceCfg)
 		}
-		awsPluginLogger().Info("vpcID not found in cache", "vpcID", vpcID, "account", accCfg.GetNamespacedName())
 	}
-	return nil
+	sort.Slice(ec2ServiceCfgs, func(i, j int) bool {
+		return ec2ServiceCfgs[i].region < ec2ServiceCfgs[j].region
+	})
+	return ec2ServiceCfgs
 }
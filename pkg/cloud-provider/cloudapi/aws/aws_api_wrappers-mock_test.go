@@ -23,6 +23,7 @@ import (
 	reflect "reflect"
 
 	ec2 "github.com/aws/aws-sdk-go/service/ec2"
+	sqs "github.com/aws/aws-sdk-go/service/sqs"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -79,6 +80,36 @@ func (mr *MockawsEC2WrapperMockRecorder) authorizeSecurityGroupIngress(input int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "authorizeSecurityGroupIngress", reflect.TypeOf((*MockawsEC2Wrapper)(nil).authorizeSecurityGroupIngress), input)
 }
 
+// createManagedPrefixList mocks base method.
+func (m *MockawsEC2Wrapper) createManagedPrefixList(input *ec2.CreateManagedPrefixListInput) (*ec2.CreateManagedPrefixListOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "createManagedPrefixList", input)
+	ret0, _ := ret[0].(*ec2.CreateManagedPrefixListOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// createManagedPrefixList indicates an expected call of createManagedPrefixList.
+func (mr *MockawsEC2WrapperMockRecorder) createManagedPrefixList(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "createManagedPrefixList", reflect.TypeOf((*MockawsEC2Wrapper)(nil).createManagedPrefixList), input)
+}
+
+// createNetworkACLEntry mocks base method.
+func (m *MockawsEC2Wrapper) createNetworkACLEntry(input *ec2.CreateNetworkAclEntryInput) (*ec2.CreateNetworkAclEntryOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "createNetworkACLEntry", input)
+	ret0, _ := ret[0].(*ec2.CreateNetworkAclEntryOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// createNetworkACLEntry indicates an expected call of createNetworkACLEntry.
+func (mr *MockawsEC2WrapperMockRecorder) createNetworkACLEntry(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "createNetworkACLEntry", reflect.TypeOf((*MockawsEC2Wrapper)(nil).createNetworkACLEntry), input)
+}
+
 // createSecurityGroup mocks base method.
 func (m *MockawsEC2Wrapper) createSecurityGroup(input *ec2.CreateSecurityGroupInput) (*ec2.CreateSecurityGroupOutput, error) {
 	m.ctrl.T.Helper()
@@ -94,6 +125,51 @@ func (mr *MockawsEC2WrapperMockRecorder) createSecurityGroup(input interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "createSecurityGroup", reflect.TypeOf((*MockawsEC2Wrapper)(nil).createSecurityGroup), input)
 }
 
+// createTags mocks base method.
+func (m *MockawsEC2Wrapper) createTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "createTags", input)
+	ret0, _ := ret[0].(*ec2.CreateTagsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// createTags indicates an expected call of createTags.
+func (mr *MockawsEC2WrapperMockRecorder) createTags(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "createTags", reflect.TypeOf((*MockawsEC2Wrapper)(nil).createTags), input)
+}
+
+// deleteManagedPrefixList mocks base method.
+func (m *MockawsEC2Wrapper) deleteManagedPrefixList(input *ec2.DeleteManagedPrefixListInput) (*ec2.DeleteManagedPrefixListOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "deleteManagedPrefixList", input)
+	ret0, _ := ret[0].(*ec2.DeleteManagedPrefixListOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// deleteManagedPrefixList indicates an expected call of deleteManagedPrefixList.
+func (mr *MockawsEC2WrapperMockRecorder) deleteManagedPrefixList(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteManagedPrefixList", reflect.TypeOf((*MockawsEC2Wrapper)(nil).deleteManagedPrefixList), input)
+}
+
+// deleteNetworkACLEntry mocks base method.
+func (m *MockawsEC2Wrapper) deleteNetworkACLEntry(input *ec2.DeleteNetworkAclEntryInput) (*ec2.DeleteNetworkAclEntryOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "deleteNetworkACLEntry", input)
+	ret0, _ := ret[0].(*ec2.DeleteNetworkAclEntryOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// deleteNetworkACLEntry indicates an expected call of deleteNetworkACLEntry.
+func (mr *MockawsEC2WrapperMockRecorder) deleteNetworkACLEntry(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteNetworkACLEntry", reflect.TypeOf((*MockawsEC2Wrapper)(nil).deleteNetworkACLEntry), input)
+}
+
 // deleteSecurityGroup mocks base method.
 func (m *MockawsEC2Wrapper) deleteSecurityGroup(input *ec2.DeleteSecurityGroupInput) (*ec2.DeleteSecurityGroupOutput, error) {
 	m.ctrl.T.Helper()
@@ -109,6 +185,36 @@ func (mr *MockawsEC2WrapperMockRecorder) deleteSecurityGroup(input interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteSecurityGroup", reflect.TypeOf((*MockawsEC2Wrapper)(nil).deleteSecurityGroup), input)
 }
 
+// deleteTags mocks base method.
+func (m *MockawsEC2Wrapper) deleteTags(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "deleteTags", input)
+	ret0, _ := ret[0].(*ec2.DeleteTagsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// deleteTags indicates an expected call of deleteTags.
+func (mr *MockawsEC2WrapperMockRecorder) deleteTags(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteTags", reflect.TypeOf((*MockawsEC2Wrapper)(nil).deleteTags), input)
+}
+
+// describeRegionsWrapper mocks base method.
+func (m *MockawsEC2Wrapper) describeRegionsWrapper(input *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "describeRegionsWrapper", input)
+	ret0, _ := ret[0].(*ec2.DescribeRegionsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// describeRegionsWrapper indicates an expected call of describeRegionsWrapper.
+func (mr *MockawsEC2WrapperMockRecorder) describeRegionsWrapper(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "describeRegionsWrapper", reflect.TypeOf((*MockawsEC2Wrapper)(nil).describeRegionsWrapper), input)
+}
+
 // describeSecurityGroups mocks base method.
 func (m *MockawsEC2Wrapper) describeSecurityGroups(input *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
 	m.ctrl.T.Helper()
@@ -154,6 +260,21 @@ func (mr *MockawsEC2WrapperMockRecorder) describeVpcsWrapper(input interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "describeVpcsWrapper", reflect.TypeOf((*MockawsEC2Wrapper)(nil).describeVpcsWrapper), input)
 }
 
+// modifyManagedPrefixList mocks base method.
+func (m *MockawsEC2Wrapper) modifyManagedPrefixList(input *ec2.ModifyManagedPrefixListInput) (*ec2.ModifyManagedPrefixListOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "modifyManagedPrefixList", input)
+	ret0, _ := ret[0].(*ec2.ModifyManagedPrefixListOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// modifyManagedPrefixList indicates an expected call of modifyManagedPrefixList.
+func (mr *MockawsEC2WrapperMockRecorder) modifyManagedPrefixList(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "modifyManagedPrefixList", reflect.TypeOf((*MockawsEC2Wrapper)(nil).modifyManagedPrefixList), input)
+}
+
 // modifyNetworkInterfaceAttribute mocks base method.
 func (m *MockawsEC2Wrapper) modifyNetworkInterfaceAttribute(input *ec2.ModifyNetworkInterfaceAttributeInput) (*ec2.ModifyNetworkInterfaceAttributeOutput, error) {
 	m.ctrl.T.Helper()
@@ -184,6 +305,36 @@ func (mr *MockawsEC2WrapperMockRecorder) pagedDescribeInstancesWrapper(input int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "pagedDescribeInstancesWrapper", reflect.TypeOf((*MockawsEC2Wrapper)(nil).pagedDescribeInstancesWrapper), input)
 }
 
+// pagedDescribeManagedPrefixLists mocks base method.
+func (m *MockawsEC2Wrapper) pagedDescribeManagedPrefixLists(input *ec2.DescribeManagedPrefixListsInput) ([]*ec2.ManagedPrefixList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "pagedDescribeManagedPrefixLists", input)
+	ret0, _ := ret[0].([]*ec2.ManagedPrefixList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// pagedDescribeManagedPrefixLists indicates an expected call of pagedDescribeManagedPrefixLists.
+func (mr *MockawsEC2WrapperMockRecorder) pagedDescribeManagedPrefixLists(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "pagedDescribeManagedPrefixLists", reflect.TypeOf((*MockawsEC2Wrapper)(nil).pagedDescribeManagedPrefixLists), input)
+}
+
+// pagedDescribeNetworkACLsWrapper mocks base method.
+func (m *MockawsEC2Wrapper) pagedDescribeNetworkACLsWrapper(input *ec2.DescribeNetworkAclsInput) ([]*ec2.NetworkAcl, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "pagedDescribeNetworkACLsWrapper", input)
+	ret0, _ := ret[0].([]*ec2.NetworkAcl)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// pagedDescribeNetworkACLsWrapper indicates an expected call of pagedDescribeNetworkACLsWrapper.
+func (mr *MockawsEC2WrapperMockRecorder) pagedDescribeNetworkACLsWrapper(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "pagedDescribeNetworkACLsWrapper", reflect.TypeOf((*MockawsEC2Wrapper)(nil).pagedDescribeNetworkACLsWrapper), input)
+}
+
 // pagedDescribeNetworkInterfaces mocks base method.
 func (m *MockawsEC2Wrapper) pagedDescribeNetworkInterfaces(input *ec2.DescribeNetworkInterfacesInput) ([]*ec2.NetworkInterface, error) {
 	m.ctrl.T.Helper()
@@ -199,6 +350,21 @@ func (mr *MockawsEC2WrapperMockRecorder) pagedDescribeNetworkInterfaces(input in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "pagedDescribeNetworkInterfaces", reflect.TypeOf((*MockawsEC2Wrapper)(nil).pagedDescribeNetworkInterfaces), input)
 }
 
+// pagedGetManagedPrefixListEntries mocks base method.
+func (m *MockawsEC2Wrapper) pagedGetManagedPrefixListEntries(input *ec2.GetManagedPrefixListEntriesInput) ([]*ec2.PrefixListEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "pagedGetManagedPrefixListEntries", input)
+	ret0, _ := ret[0].([]*ec2.PrefixListEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// pagedGetManagedPrefixListEntries indicates an expected call of pagedGetManagedPrefixListEntries.
+func (mr *MockawsEC2WrapperMockRecorder) pagedGetManagedPrefixListEntries(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "pagedGetManagedPrefixListEntries", reflect.TypeOf((*MockawsEC2Wrapper)(nil).pagedGetManagedPrefixListEntries), input)
+}
+
 // revokeSecurityGroupEgress mocks base method.
 func (m *MockawsEC2Wrapper) revokeSecurityGroupEgress(input *ec2.RevokeSecurityGroupEgressInput) (*ec2.RevokeSecurityGroupEgressOutput, error) {
 	m.ctrl.T.Helper()
@@ -228,3 +394,56 @@ func (mr *MockawsEC2WrapperMockRecorder) revokeSecurityGroupIngress(input interf
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "revokeSecurityGroupIngress", reflect.TypeOf((*MockawsEC2Wrapper)(nil).revokeSecurityGroupIngress), input)
 }
+
+// MockawsSQSWrapper is a mock of awsSQSWrapper interface.
+type MockawsSQSWrapper struct {
+	ctrl     *gomock.Controller
+	recorder *MockawsSQSWrapperMockRecorder
+}
+
+// MockawsSQSWrapperMockRecorder is the mock recorder for MockawsSQSWrapper.
+type MockawsSQSWrapperMockRecorder struct {
+	mock *MockawsSQSWrapper
+}
+
+// NewMockawsSQSWrapper creates a new mock instance.
+func NewMockawsSQSWrapper(ctrl *gomock.Controller) *MockawsSQSWrapper {
+	mock := &MockawsSQSWrapper{ctrl: ctrl}
+	mock.recorder = &MockawsSQSWrapperMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockawsSQSWrapper) EXPECT() *MockawsSQSWrapperMockRecorder {
+	return m.recorder
+}
+
+// deleteMessageBatch mocks base method.
+func (m *MockawsSQSWrapper) deleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "deleteMessageBatch", input)
+	ret0, _ := ret[0].(*sqs.DeleteMessageBatchOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// deleteMessageBatch indicates an expected call of deleteMessageBatch.
+func (mr *MockawsSQSWrapperMockRecorder) deleteMessageBatch(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteMessageBatch", reflect.TypeOf((*MockawsSQSWrapper)(nil).deleteMessageBatch), input)
+}
+
+// receiveMessage mocks base method.
+func (m *MockawsSQSWrapper) receiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "receiveMessage", input)
+	ret0, _ := ret[0].(*sqs.ReceiveMessageOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// receiveMessage indicates an expected call of receiveMessage.
+func (mr *MockawsSQSWrapperMockRecorder) receiveMessage(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "receiveMessage", reflect.TypeOf((*MockawsSQSWrapper)(nil).receiveMessage), input)
+}
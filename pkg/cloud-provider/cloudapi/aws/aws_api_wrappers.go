@@ -19,6 +19,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/sqs"
 )
 
 // awsEC2Wrapper is layer above aws EC2 sdk apis to allow for unit-testing.
@@ -39,11 +40,30 @@ type awsEC2Wrapper interface {
 	revokeSecurityGroupEgress(input *ec2.RevokeSecurityGroupEgressInput) (*ec2.RevokeSecurityGroupEgressOutput, error)
 	revokeSecurityGroupIngress(input *ec2.RevokeSecurityGroupIngressInput) (*ec2.RevokeSecurityGroupIngressOutput, error)
 
+	// network acls
+	pagedDescribeNetworkACLsWrapper(input *ec2.DescribeNetworkAclsInput) ([]*ec2.NetworkAcl, error)
+	createNetworkACLEntry(input *ec2.CreateNetworkAclEntryInput) (*ec2.CreateNetworkAclEntryOutput, error)
+	deleteNetworkACLEntry(input *ec2.DeleteNetworkAclEntryInput) (*ec2.DeleteNetworkAclEntryOutput, error)
+
+	// managed prefix lists
+	createManagedPrefixList(input *ec2.CreateManagedPrefixListInput) (*ec2.CreateManagedPrefixListOutput, error)
+	pagedDescribeManagedPrefixLists(input *ec2.DescribeManagedPrefixListsInput) ([]*ec2.ManagedPrefixList, error)
+	pagedGetManagedPrefixListEntries(input *ec2.GetManagedPrefixListEntriesInput) ([]*ec2.PrefixListEntry, error)
+	modifyManagedPrefixList(input *ec2.ModifyManagedPrefixListInput) (*ec2.ModifyManagedPrefixListOutput, error)
+	deleteManagedPrefixList(input *ec2.DeleteManagedPrefixListInput) (*ec2.DeleteManagedPrefixListOutput, error)
+
+	// tags
+	createTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error)
+	deleteTags(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error)
+
 	// vpcs
 	describeVpcsWrapper(input *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error)
 
 	// peer connections
 	describeVpcPeeringConnectionsWrapper(input *ec2.DescribeVpcPeeringConnectionsInput) (*ec2.DescribeVpcPeeringConnectionsOutput, error)
+
+	// regions
+	describeRegionsWrapper(input *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error)
 }
 type awsEC2WrapperImpl struct {
 	ec2 *ec2.EC2
@@ -132,11 +152,128 @@ func (ec2Wrapper *awsEC2WrapperImpl) revokeSecurityGroupIngress(input *ec2.Revok
 	return ec2Wrapper.ec2.RevokeSecurityGroupIngress(input)
 }
 
+func (ec2Wrapper *awsEC2WrapperImpl) pagedDescribeNetworkACLsWrapper(input *ec2.DescribeNetworkAclsInput) ([]*ec2.NetworkAcl, error) {
+	var networkACLs []*ec2.NetworkAcl
+	var nextToken *string
+	for {
+		response, err := ec2Wrapper.ec2.DescribeNetworkAcls(input)
+		if err != nil {
+			return nil, fmt.Errorf("error describing ec2 network acls: %q", err)
+		}
+
+		networkACLs = append(networkACLs, response.NetworkAcls...)
+
+		nextToken = response.NextToken
+		if aws.StringValue(nextToken) == "" {
+			break
+		}
+		input.NextToken = nextToken
+	}
+	return networkACLs, nil
+}
+
+func (ec2Wrapper *awsEC2WrapperImpl) createNetworkACLEntry(input *ec2.CreateNetworkAclEntryInput) (*ec2.CreateNetworkAclEntryOutput,
+	error) {
+	return ec2Wrapper.ec2.CreateNetworkAclEntry(input)
+}
+
+func (ec2Wrapper *awsEC2WrapperImpl) deleteNetworkACLEntry(input *ec2.DeleteNetworkAclEntryInput) (*ec2.DeleteNetworkAclEntryOutput,
+	error) {
+	return ec2Wrapper.ec2.DeleteNetworkAclEntry(input)
+}
+
+func (ec2Wrapper *awsEC2WrapperImpl) createManagedPrefixList(input *ec2.CreateManagedPrefixListInput) (
+	*ec2.CreateManagedPrefixListOutput, error) {
+	return ec2Wrapper.ec2.CreateManagedPrefixList(input)
+}
+
+func (ec2Wrapper *awsEC2WrapperImpl) pagedDescribeManagedPrefixLists(input *ec2.DescribeManagedPrefixListsInput) (
+	[]*ec2.ManagedPrefixList, error) {
+	var prefixLists []*ec2.ManagedPrefixList
+	var nextToken *string
+	for {
+		response, err := ec2Wrapper.ec2.DescribeManagedPrefixLists(input)
+		if err != nil {
+			return nil, fmt.Errorf("error describing ec2 managed prefix lists: %q", err)
+		}
+
+		prefixLists = append(prefixLists, response.PrefixLists...)
+
+		nextToken = response.NextToken
+		if aws.StringValue(nextToken) == "" {
+			break
+		}
+		input.NextToken = nextToken
+	}
+	return prefixLists, nil
+}
+
+func (ec2Wrapper *awsEC2WrapperImpl) pagedGetManagedPrefixListEntries(input *ec2.GetManagedPrefixListEntriesInput) (
+	[]*ec2.PrefixListEntry, error) {
+	var entries []*ec2.PrefixListEntry
+	var nextToken *string
+	for {
+		response, err := ec2Wrapper.ec2.GetManagedPrefixListEntries(input)
+		if err != nil {
+			return nil, fmt.Errorf("error getting ec2 managed prefix list entries: %q", err)
+		}
+
+		entries = append(entries, response.Entries...)
+
+		nextToken = response.NextToken
+		if aws.StringValue(nextToken) == "" {
+			break
+		}
+		input.NextToken = nextToken
+	}
+	return entries, nil
+}
+
+func (ec2Wrapper *awsEC2WrapperImpl) modifyManagedPrefixList(input *ec2.ModifyManagedPrefixListInput) (
+	*ec2.ModifyManagedPrefixListOutput, error) {
+	return ec2Wrapper.ec2.ModifyManagedPrefixList(input)
+}
+
+func (ec2Wrapper *awsEC2WrapperImpl) deleteManagedPrefixList(input *ec2.DeleteManagedPrefixListInput) (
+	*ec2.DeleteManagedPrefixListOutput, error) {
+	return ec2Wrapper.ec2.DeleteManagedPrefixList(input)
+}
+
+func (ec2Wrapper *awsEC2WrapperImpl) createTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	return ec2Wrapper.ec2.CreateTags(input)
+}
+
+func (ec2Wrapper *awsEC2WrapperImpl) deleteTags(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+	return ec2Wrapper.ec2.DeleteTags(input)
+}
+
 func (ec2Wrapper *awsEC2WrapperImpl) describeVpcsWrapper(input *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
 	return ec2Wrapper.ec2.DescribeVpcs(input)
 }
 
+func (ec2Wrapper *awsEC2WrapperImpl) describeRegionsWrapper(input *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error) {
+	return ec2Wrapper.ec2.DescribeRegions(input)
+}
+
 func (ec2Wrapper *awsEC2WrapperImpl) describeVpcPeeringConnectionsWrapper(input *ec2.DescribeVpcPeeringConnectionsInput) (
 	*ec2.DescribeVpcPeeringConnectionsOutput, error) {
 	return ec2Wrapper.ec2.DescribeVpcPeeringConnections(input)
 }
+
+// awsSQSWrapper is layer above aws SQS sdk apis to allow for unit-testing.
+type awsSQSWrapper interface {
+	receiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error)
+	deleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error)
+}
+
+type awsSQSWrapperImpl struct {
+	sqs *sqs.SQS
+}
+
+func (sqsWrapper *awsSQSWrapperImpl) receiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	return sqsWrapper.sqs.ReceiveMessage(input)
+}
+
+func (sqsWrapper *awsSQSWrapperImpl) deleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+	return sqsWrapper.sqs.DeleteMessageBatch(input)
+}
@@ -72,14 +72,28 @@ func (c *awsCloud) InstancesGivenProviderAccount(accountNamespacedName *types.Na
 	return vmCRDs, err
 }
 
+// VpcsGivenProviderAccount returns Vpc CRD for all virtual private clouds of a given cloud provider account.
+func (c *awsCloud) VpcsGivenProviderAccount(accountNamespacedName *types.NamespacedName) ([]*v1alpha1.Vpc, error) {
+	return c.cloudCommon.GetCloudAccountVpcCRDs(accountNamespacedName)
+}
+
 // IsVirtualPrivateCloudPresent returns true if given ID is managed by the cloud, else false.
 func (c *awsCloud) IsVirtualPrivateCloudPresent(vpcUniqueIdentifier string) bool {
-	if accCfg := c.getVpcAccount(vpcUniqueIdentifier); accCfg == nil {
+	if accCfg, _ := c.getVpcAccount(vpcUniqueIdentifier); accCfg == nil {
 		return false
 	}
 	return true
 }
 
+// GetVpcAccount returns namespaced name of the account managing given ID, nil if not managed by the cloud.
+func (c *awsCloud) GetVpcAccount(vpcUniqueIdentifier string) *types.NamespacedName {
+	accCfg, _ := c.getVpcAccount(vpcUniqueIdentifier)
+	if accCfg == nil {
+		return nil
+	}
+	return accCfg.GetNamespacedName()
+}
+
 // ////////////////////////////////////////////////////////
 // 	AccountMgmtInterface Implementation
 // ////////////////////////////////////////////////////////
@@ -106,3 +120,8 @@ func (c *awsCloud) RemoveAccountResourcesSelector(accNamespacedName *types.Names
 func (c *awsCloud) GetAccountStatus(accNamespacedName *types.NamespacedName) (*cloudv1alpha1.CloudProviderAccountStatus, error) {
 	return c.cloudCommon.GetStatus(accNamespacedName)
 }
+
+// WatchAccountInventory returns a channel notified when account inventory is updated by cloud resource events.
+func (c *awsCloud) WatchAccountInventory(accNamespacedName *types.NamespacedName, stopCh <-chan struct{}) (<-chan struct{}, error) {
+	return c.cloudCommon.WatchInventory(accNamespacedName, stopCh)
+}
@@ -15,6 +15,7 @@
 package aws
 
 import (
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
@@ -32,7 +33,7 @@ func convertToIPPermissionProtocol(protocol *int) *string {
 	return aws.String(strconv.FormatInt(int64(*protocol), 10))
 }
 
-func convertToIPPermissionPort(port *int, protocol *int) (*int64, *int64) {
+func convertToIPPermissionPort(port *int, endPort *int, protocol *int) (*int64, *int64) {
 	if port == nil {
 		// For TCP and UDP, aws expects explicit start and end port numbers (for all ports case)
 		if protocol != nil && (*protocol == 6 || *protocol == 17) {
@@ -41,20 +42,115 @@ func convertToIPPermissionPort(port *int, protocol *int) (*int64, *int64) {
 		return nil, nil
 	}
 	portVal := aws.Int64(int64(*port))
+	if endPort != nil && *endPort > *port {
+		return portVal, aws.Int64(int64(*endPort))
+	}
 	return portVal, portVal
 }
 
+// convertToIPPermissionICMPTypeCode returns ICMP type and code of an ICMP ip permission, carried in its FromPort and
+// ToPort. -1 matches any ICMP type or code.
+func convertToIPPermissionICMPTypeCode(icmpType *int, icmpCode *int) (*int64, *int64) {
+	if icmpType == nil && icmpCode == nil {
+		return nil, nil
+	}
+	typeCode := []int64{-1, -1}
+	for i, v := range []*int{icmpType, icmpCode} {
+		if v != nil {
+			typeCode[i] = int64(*v)
+		}
+	}
+	return aws.Int64(typeCode[0]), aws.Int64(typeCode[1])
+}
+
+// convertFromIPPermissionICMPTypeCode returns ICMP type and code of an ICMP ip permission from its FromPort and ToPort.
+func convertFromIPPermissionICMPTypeCode(fromPort *int64, toPort *int64) (*int, *int) {
+	var icmpType, icmpCode *int
+	if fromPort != nil && *fromPort >= 0 {
+		icmpType = aws.Int(int(*fromPort))
+	}
+	if toPort != nil && *toPort >= 0 {
+		icmpCode = aws.Int(int(*toPort))
+	}
+	return icmpType, icmpCode
+}
+
+// splitICMPv6IPPermission returns ip permissions realizing ipPermission. IPv6 ranges of an ICMP ip permission are
+// moved to an ICMPv6 ip permission of the same ICMP type and code.
+func splitICMPv6IPPermission(ipPermission *ec2.IpPermission) []*ec2.IpPermission {
+	protocol := convertFromIPPermissionProtocol(aws.StringValue(ipPermission.IpProtocol))
+	if protocol == nil || *protocol != securitygroup.ProtocolNameNumMap["icmp"] || len(ipPermission.Ipv6Ranges) == 0 {
+		return []*ec2.IpPermission{ipPermission}
+	}
+	icmpv6IPPermission := &ec2.IpPermission{
+		FromPort:   ipPermission.FromPort,
+		ToPort:     ipPermission.ToPort,
+		IpProtocol: convertToIPPermissionProtocol(securitygroup.GetIPv6Protocol(protocol)),
+		Ipv6Ranges: ipPermission.Ipv6Ranges,
+	}
+	ipPermission.Ipv6Ranges = nil
+	if len(ipPermission.IpRanges) == 0 && len(ipPermission.PrefixListIds) == 0 && len(ipPermission.UserIdGroupPairs) == 0 {
+		return []*ec2.IpPermission{icmpv6IPPermission}
+	}
+	return []*ec2.IpPermission{ipPermission, icmpv6IPPermission}
+}
+
+// mergeICMPv6IPPermissions returns ipPermissions with IPv6 ranges of ICMPv6 ip permissions merged into ICMP ip
+// permissions of the same ICMP type and code, as they realize the same rule.
+func mergeICMPv6IPPermissions(ipPermissions []*ec2.IpPermission) []*ec2.IpPermission {
+	getKey := func(ipPermission *ec2.IpPermission) string {
+		return fmt.Sprintf("%v/%v", aws.Int64Value(ipPermission.FromPort), aws.Int64Value(ipPermission.ToPort))
+	}
+	icmpv6Num := securitygroup.ProtocolNameNumMap["icmpv6"]
+	icmpIPPermissions := make(map[string]*ec2.IpPermission)
+	var icmpv6IPPermissions []*ec2.IpPermission
+	merged := make([]*ec2.IpPermission, 0, len(ipPermissions))
+	for _, ipPermission := range ipPermissions {
+		protocol := convertFromIPPermissionProtocol(aws.StringValue(ipPermission.IpProtocol))
+		if protocol != nil && *protocol == icmpv6Num {
+			icmpv6IPPermissions = append(icmpv6IPPermissions, ipPermission)
+			continue
+		}
+		if securitygroup.IsICMPProtocol(protocol) {
+			ipPermission = copyIPPermission(ipPermission)
+			icmpIPPermissions[getKey(ipPermission)] = ipPermission
+		}
+		merged = append(merged, ipPermission)
+	}
+	for _, icmpv6IPPermission := range icmpv6IPPermissions {
+		if icmpIPPermission, found := icmpIPPermissions[getKey(icmpv6IPPermission)]; found {
+			icmpIPPermission.Ipv6Ranges = append(icmpIPPermission.Ipv6Ranges, icmpv6IPPermission.Ipv6Ranges...)
+			continue
+		}
+		icmpIPPermission := copyIPPermission(icmpv6IPPermission)
+		icmpIPPermission.IpProtocol = convertToIPPermissionProtocol(aws.Int(securitygroup.ProtocolNameNumMap["icmp"]))
+		icmpIPPermissions[getKey(icmpIPPermission)] = icmpIPPermission
+		merged = append(merged, icmpIPPermission)
+	}
+	return merged
+}
+
+// copyIPPermission returns a copy of ipPermission, whose ranges may be modified without modifying ipPermission.
+func copyIPPermission(ipPermission *ec2.IpPermission) *ec2.IpPermission {
+	ipPermissionCopy := *ipPermission
+	ipPermissionCopy.Ipv6Ranges = append([]*ec2.Ipv6Range{}, ipPermission.Ipv6Ranges...)
+	return &ipPermissionCopy
+}
+
 func convertToEc2IpRanges(ips []*net.IPNet, ruleHasGroups bool) []*ec2.IpRange {
 	var ipRanges []*ec2.IpRange
 	if len(ips) == 0 && !ruleHasGroups {
 		ipRange := &ec2.IpRange{
-			CidrIp: aws.String("0.0.0.0/0"),
+			CidrIp: aws.String(awsAnyIPv4Address),
 		}
 		ipRanges = append(ipRanges, ipRange)
 		return ipRanges
 	}
 
 	for _, ip := range ips {
+		if ip.IP.To4() == nil {
+			continue
+		}
 		ipRange := &ec2.IpRange{
 			CidrIp: aws.String(ip.String()),
 		}
@@ -63,7 +159,25 @@ func convertToEc2IpRanges(ips []*net.IPNet, ruleHasGroups bool) []*ec2.IpRange {
 	return ipRanges
 }
 
-func convertFromIPRange(ipRanges []*ec2.IpRange) []*net.IPNet {
+// convertToEc2Ipv6Ranges returns IPv6 ranges of ips, or a range of all IPv6 addresses if a rule has no peers.
+func convertToEc2Ipv6Ranges(ips []*net.IPNet, ruleHasGroups bool) []*ec2.Ipv6Range {
+	var ipv6Ranges []*ec2.Ipv6Range
+	if len(ips) == 0 && !ruleHasGroups {
+		return []*ec2.Ipv6Range{{CidrIpv6: aws.String(awsAnyIPv6Address)}}
+	}
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			continue
+		}
+		ipv6Range := &ec2.Ipv6Range{
+			CidrIpv6: aws.String(ip.String()),
+		}
+		ipv6Ranges = append(ipv6Ranges, ipv6Range)
+	}
+	return ipv6Ranges
+}
+
+func convertFromIPRange(ipRanges []*ec2.IpRange, ipv6Ranges []*ec2.Ipv6Range) []*net.IPNet {
 	var srcIPNets []*net.IPNet
 	for _, ipRange := range ipRanges {
 		_, ipNet, err := net.ParseCIDR(*ipRange.CidrIp)
@@ -72,9 +186,36 @@ func convertFromIPRange(ipRanges []*ec2.IpRange) []*net.IPNet {
 		}
 		srcIPNets = append(srcIPNets, ipNet)
 	}
+	for _, ipv6Range := range ipv6Ranges {
+		_, ipNet, err := net.ParseCIDR(aws.StringValue(ipv6Range.CidrIpv6))
+		if err != nil {
+			continue
+		}
+		srcIPNets = append(srcIPNets, ipNet)
+	}
 	return srcIPNets
 }
 
+// convertFromPrefixListIDs returns ips of prefix lists found in prefixListIDToIPs.
+func convertFromPrefixListIDs(prefixListIDs []*ec2.PrefixListId, prefixListIDToIPs map[string][]*net.IPNet) []*net.IPNet {
+	var ipNets []*net.IPNet
+	for _, prefixListID := range prefixListIDs {
+		ipNets = append(ipNets, prefixListIDToIPs[aws.StringValue(prefixListID.PrefixListId)]...)
+	}
+	return ipNets
+}
+
+// convertFromPrefixListIDsToCloudServices returns cloud services of prefix lists found in prefixListIDToCloudService.
+func convertFromPrefixListIDsToCloudServices(prefixListIDs []*ec2.PrefixListId, prefixListIDToCloudService map[string]string) []string {
+	var cloudServices []string
+	for _, prefixListID := range prefixListIDs {
+		if cloudService, found := prefixListIDToCloudService[aws.StringValue(prefixListID.PrefixListId)]; found {
+			cloudServices = append(cloudServices, cloudService)
+		}
+	}
+	return cloudServices
+}
+
 func convertFromSecurityGroupPair(cloudGroups []*ec2.UserIdGroupPair, managedSGs map[string]*ec2.SecurityGroup,
 	unmanagedSGs map[string]*ec2.SecurityGroup) []*securitygroup.CloudResourceID {
 	var cloudResourceIDs []*securitygroup.CloudResourceID
@@ -105,15 +246,22 @@ func convertFromSecurityGroupPair(cloudGroups []*ec2.UserIdGroupPair, managedSGs
 }
 
 func convertFromIPPermissionToIngressRule(ipPermissions []*ec2.IpPermission, managedSGs map[string]*ec2.SecurityGroup,
-	unmanagedSGs map[string]*ec2.SecurityGroup) []securitygroup.IngressRule {
+	unmanagedSGs map[string]*ec2.SecurityGroup, prefixListIDToIPs map[string][]*net.IPNet,
+	prefixListIDToCloudService map[string]string) []securitygroup.IngressRule {
 	var ingressRules []securitygroup.IngressRule
 	for _, ipPermission := range ipPermissions {
 		var ingressRule securitygroup.IngressRule
 
-		ingressRule.FromSrcIP = convertFromIPRange(ipPermission.IpRanges)
+		ingressRule.FromSrcIP = convertFromIPRange(ipPermission.IpRanges, ipPermission.Ipv6Ranges)
+		ingressRule.FromSrcIP = append(ingressRule.FromSrcIP, convertFromPrefixListIDs(ipPermission.PrefixListIds, prefixListIDToIPs)...)
 		ingressRule.FromSecurityGroups = convertFromSecurityGroupPair(ipPermission.UserIdGroupPairs, managedSGs, unmanagedSGs)
+		ingressRule.FromCloudServices = convertFromPrefixListIDsToCloudServices(ipPermission.PrefixListIds, prefixListIDToCloudService)
 		ingressRule.Protocol = convertFromIPPermissionProtocol(*ipPermission.IpProtocol)
-		ingressRule.FromPort = convertFromIPPermissionPort(ipPermission.FromPort, ipPermission.ToPort)
+		if securitygroup.IsICMPProtocol(ingressRule.Protocol) {
+			ingressRule.ICMPType, ingressRule.ICMPCode = convertFromIPPermissionICMPTypeCode(ipPermission.FromPort, ipPermission.ToPort)
+		} else {
+			ingressRule.FromPort, ingressRule.FromEndPort = convertFromIPPermissionPort(ipPermission.FromPort, ipPermission.ToPort)
+		}
 
 		ingressRules = append(ingressRules, ingressRule)
 	}
@@ -121,44 +269,121 @@ func convertFromIPPermissionToIngressRule(ipPermissions []*ec2.IpPermission, man
 }
 
 func convertFromIPPermissionToEgressRule(ipPermissions []*ec2.IpPermission, managedSGs map[string]*ec2.SecurityGroup,
-	unmanagedSGs map[string]*ec2.SecurityGroup) []securitygroup.EgressRule {
+	unmanagedSGs map[string]*ec2.SecurityGroup, prefixListIDToIPs map[string][]*net.IPNet,
+	prefixListIDToCloudService map[string]string) []securitygroup.EgressRule {
 	var egressRules []securitygroup.EgressRule
 	for _, ipPermission := range ipPermissions {
 		var egressRule securitygroup.EgressRule
 
-		egressRule.ToDstIP = convertFromIPRange(ipPermission.IpRanges)
+		egressRule.ToDstIP = convertFromIPRange(ipPermission.IpRanges, ipPermission.Ipv6Ranges)
+		egressRule.ToDstIP = append(egressRule.ToDstIP, convertFromPrefixListIDs(ipPermission.PrefixListIds, prefixListIDToIPs)...)
 		egressRule.ToSecurityGroups = convertFromSecurityGroupPair(ipPermission.UserIdGroupPairs, managedSGs, unmanagedSGs)
+		egressRule.ToCloudServices = convertFromPrefixListIDsToCloudServices(ipPermission.PrefixListIds, prefixListIDToCloudService)
 		egressRule.Protocol = convertFromIPPermissionProtocol(*ipPermission.IpProtocol)
-		egressRule.ToPort = convertFromIPPermissionPort(ipPermission.FromPort, ipPermission.ToPort)
+		if securitygroup.IsICMPProtocol(egressRule.Protocol) {
+			egressRule.ICMPType, egressRule.ICMPCode = convertFromIPPermissionICMPTypeCode(ipPermission.FromPort, ipPermission.ToPort)
+		} else {
+			egressRule.ToPort, egressRule.ToEndPort = convertFromIPPermissionPort(ipPermission.FromPort, ipPermission.ToPort)
+		}
 
 		egressRules = append(egressRules, egressRule)
 	}
 	return egressRules
 }
 
-func convertFromIPPermissionPort(startPort *int64, endPort *int64) *int {
+// convertFromUnmanagedIPPermissionToIngressRule converts ip permissions of a security group not created by nephe to
+// ingress rules. Peers of ip permissions not supported are returned as descriptions of unsupported rules.
+func convertFromUnmanagedIPPermissionToIngressRule(ipPermissions []*ec2.IpPermission, managedSGs map[string]*ec2.SecurityGroup,
+	unmanagedSGs map[string]*ec2.SecurityGroup) ([]securitygroup.IngressRule, []string) {
+	supportedIPPermissions, unsupportedRules := splitUnsupportedIPPermissions(ipPermissions, "ingress", managedSGs, unmanagedSGs)
+	return convertFromIPPermissionToIngressRule(supportedIPPermissions, managedSGs, unmanagedSGs, nil, nil), unsupportedRules
+}
+
+// convertFromUnmanagedIPPermissionToEgressRule converts ip permissions of a security group not created by nephe to
+// egress rules. Peers of ip permissions not supported are returned as descriptions of unsupported rules.
+func convertFromUnmanagedIPPermissionToEgressRule(ipPermissions []*ec2.IpPermission, managedSGs map[string]*ec2.SecurityGroup,
+	unmanagedSGs map[string]*ec2.SecurityGroup) ([]securitygroup.EgressRule, []string) {
+	supportedIPPermissions, unsupportedRules := splitUnsupportedIPPermissions(ipPermissions, "egress", managedSGs, unmanagedSGs)
+	return convertFromIPPermissionToEgressRule(supportedIPPermissions, managedSGs, unmanagedSGs, nil, nil), unsupportedRules
+}
+
+// splitUnsupportedIPPermissions removes peers not supported, prefix lists and security groups not found in vpcs of
+// the account, from ip permissions. An ip permission left without peers is dropped, so that it does not turn into a
+// rule matching any peer.
+func splitUnsupportedIPPermissions(ipPermissions []*ec2.IpPermission, direction string, managedSGs map[string]*ec2.SecurityGroup,
+	unmanagedSGs map[string]*ec2.SecurityGroup) ([]*ec2.IpPermission, []string) {
+	var supportedIPPermissions []*ec2.IpPermission
+	var unsupportedRules []string
+	for _, ipPermission := range ipPermissions {
+		var unsupportedPeers []string
+		for _, prefixList := range ipPermission.PrefixListIds {
+			unsupportedPeers = append(unsupportedPeers, "prefix list "+aws.StringValue(prefixList.PrefixListId))
+		}
+		var groupPairs []*ec2.UserIdGroupPair
+		for _, groupPair := range ipPermission.UserIdGroupPairs {
+			groupID := aws.StringValue(groupPair.GroupId)
+			_, isManagedSg := managedSGs[groupID]
+			_, isUnmanagedSg := unmanagedSGs[groupID]
+			if !isManagedSg && !isUnmanagedSg {
+				unsupportedPeers = append(unsupportedPeers, "security group "+groupID)
+				continue
+			}
+			groupPairs = append(groupPairs, groupPair)
+		}
+		if len(unsupportedPeers) == 0 {
+			supportedIPPermissions = append(supportedIPPermissions, ipPermission)
+			continue
+		}
+
+		rule := fmt.Sprintf("%v rule protocol %v", direction, aws.StringValue(ipPermission.IpProtocol))
+		if ipPermission.FromPort != nil {
+			rule += fmt.Sprintf(" ports %v-%v", aws.Int64Value(ipPermission.FromPort), aws.Int64Value(ipPermission.ToPort))
+		}
+		unsupportedRules = append(unsupportedRules, fmt.Sprintf("%v: unsupported peers %v", rule,
+			strings.Join(unsupportedPeers, ", ")))
+		if len(groupPairs) == 0 && len(ipPermission.IpRanges) == 0 && len(ipPermission.Ipv6Ranges) == 0 {
+			continue
+		}
+		supportedIPPermission := *ipPermission
+		supportedIPPermission.PrefixListIds = nil
+		supportedIPPermission.UserIdGroupPairs = groupPairs
+		supportedIPPermissions = append(supportedIPPermissions, &supportedIPPermission)
+	}
+	return supportedIPPermissions, unsupportedRules
+}
+
+// convertFromIPPermissionPort returns start and end port of a port range. End port is nil for a single port.
+func convertFromIPPermissionPort(startPort *int64, endPort *int64) (*int, *int) {
 	if startPort == nil {
-		return nil
+		return nil, nil
 	}
 	if endPort == nil {
 		retVal := int(*startPort)
-		return &retVal
+		return &retVal, nil
 	}
 	if *startPort == -1 {
-		return nil
+		return nil, nil
 	}
-	if *startPort == *endPort {
-		retVal := int(*startPort)
-		return &retVal
+	// all (0 - 65535) tcp/udp ports returns nil
+	if *startPort == int64(tcpUDPPortStart) && *endPort == int64(tcpUDPPortEnd) {
+		return nil, nil
 	}
-	// other cases along with all (0 - 65535) tcp/udp ports returns nil
-	return nil
+	retVal := int(*startPort)
+	if *startPort >= *endPort {
+		return &retVal, nil
+	}
+	retEndVal := int(*endPort)
+	return &retVal, &retEndVal
 }
 
 func convertFromIPPermissionProtocol(proto string) *int {
 	if strings.Compare(proto, awsAnyProtocolValue) == 0 {
 		return nil
 	}
+	// protocols other than tcp, udp, icmp and icmpv6 are returned by number.
+	if protoNum, err := strconv.Atoi(proto); err == nil {
+		return &protoNum
+	}
 	protoNum := securitygroup.ProtocolNameNumMap[strings.ToLower(proto)]
 	return &protoNum
 }
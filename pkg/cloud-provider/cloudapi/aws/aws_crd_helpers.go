@@ -15,6 +15,9 @@
 package aws
 
 import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
 	"antrea.io/nephe/apis/crd/v1alpha1"
@@ -24,7 +27,7 @@ import (
 const ResourceNameTagKey = "Name"
 
 // ec2InstanceToVirtualMachineCRD converts ec2 instance to VirtualMachine CRD.
-func ec2InstanceToVirtualMachineCRD(instance *ec2.Instance, namespace string) *v1alpha1.VirtualMachine {
+func ec2InstanceToVirtualMachineCRD(instance *ec2.Instance, namespace string, region string) *v1alpha1.VirtualMachine {
 	tags := make(map[string]string)
 	vmTags := instance.Tags
 	if len(vmTags) > 0 {
@@ -48,8 +51,9 @@ func ec2InstanceToVirtualMachineCRD(instance *ec2.Instance, namespace string) *v
 				}
 				ipAddressCRDs = append(ipAddressCRDs, ipAddressCRD)
 
+				// public IP, or Elastic IP, associated with the private IP.
 				association := ipAddress.Association
-				if association != nil {
+				if association != nil && association.PublicIp != nil {
 					ipAddressCRD := v1alpha1.IPAddress{
 						AddressType: v1alpha1.AddressTypeExternalIP,
 						Address:     *association.PublicIp,
@@ -58,10 +62,20 @@ func ec2InstanceToVirtualMachineCRD(instance *ec2.Instance, namespace string) *v
 				}
 			}
 		}
+		for _, ipv6Address := range nwInf.Ipv6Addresses {
+			if ipv6Address.Ipv6Address == nil {
+				continue
+			}
+			ipAddressCRDs = append(ipAddressCRDs, v1alpha1.IPAddress{
+				AddressType: v1alpha1.AddressTypeInternalIP,
+				Address:     *ipv6Address.Ipv6Address,
+			})
+		}
 		networkInterface := v1alpha1.NetworkInterface{
-			Name: *nwInf.NetworkInterfaceId,
-			MAC:  *nwInf.MacAddress,
-			IPs:  ipAddressCRDs,
+			Name:   *nwInf.NetworkInterfaceId,
+			MAC:    *nwInf.MacAddress,
+			IPs:    ipAddressCRDs,
+			Subnet: aws.StringValue(nwInf.SubnetId),
 		}
 		networkInterfaces = append(networkInterfaces, networkInterface)
 	}
@@ -71,5 +85,60 @@ func ec2InstanceToVirtualMachineCRD(instance *ec2.Instance, namespace string) *v
 	cloudNetwork := *instance.VpcId
 
 	return utils.GenerateVirtualMachineCRD(cloudID, cloudName, cloudID, namespace, cloudNetwork, cloudNetwork,
-		*instance.State.Name, tags, networkInterfaces, providerType)
+		*instance.State.Name, tags, networkInterfaces, ec2InstanceProperties(instance, region), providerType)
+}
+
+// ec2InstanceProperties returns cloud properties of ec2 instance.
+func ec2InstanceProperties(instance *ec2.Instance, region string) *utils.VirtualMachineProperties {
+	properties := &utils.VirtualMachineProperties{
+		Region:       region,
+		InstanceType: aws.StringValue(instance.InstanceType),
+		ImageID:      aws.StringValue(instance.ImageId),
+		LaunchTime:   instance.LaunchTime,
+	}
+	if instance.Placement != nil {
+		properties.AvailabilityZone = aws.StringValue(instance.Placement.AvailabilityZone)
+	}
+	// platform is only set for windows instances.
+	properties.OSType = "linux"
+	if instance.Platform != nil {
+		properties.OSType = *instance.Platform
+	}
+	for _, sg := range instance.SecurityGroups {
+		if sg.GroupId != nil {
+			properties.SecurityGroups = append(properties.SecurityGroups, *sg.GroupId)
+		}
+	}
+	sort.Strings(properties.SecurityGroups)
+	return properties
+}
+
+// ec2VpcToVpcCRD converts ec2 vpc to Vpc CRD.
+func ec2VpcToVpcCRD(vpc *ec2.Vpc, namespace string, region string, peers []string) *v1alpha1.Vpc {
+	tags := make(map[string]string)
+	for _, tag := range vpc.Tags {
+		tags[*tag.Key] = *tag.Value
+	}
+
+	var cidrs []string
+	for _, association := range vpc.CidrBlockAssociationSet {
+		if association.CidrBlock != nil {
+			cidrs = append(cidrs, *association.CidrBlock)
+		}
+	}
+	for _, association := range vpc.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlock != nil {
+			cidrs = append(cidrs, *association.Ipv6CidrBlock)
+		}
+	}
+	if len(cidrs) == 0 && vpc.CidrBlock != nil {
+		cidrs = append(cidrs, *vpc.CidrBlock)
+	}
+	sort.Strings(cidrs)
+	sort.Strings(peers)
+
+	cloudName := tags[ResourceNameTagKey]
+	cloudID := *vpc.VpcId
+
+	return utils.GenerateVpcCRD(cloudID, cloudName, cloudID, namespace, region, cidrs, tags, peers, providerType)
 }
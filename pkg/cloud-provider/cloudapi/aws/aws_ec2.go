@@ -17,6 +17,8 @@ package aws
 import (
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -34,6 +36,8 @@ import (
 
 type ec2ServiceConfig struct {
 	accountName    string
+	name           internal.CloudServiceName
+	region         string
 	apiClient      awsEC2Wrapper
 	resourcesCache *internal.CloudServiceResourcesCache
 	inventoryStats *internal.CloudServiceStats
@@ -43,6 +47,12 @@ type ec2ServiceConfig struct {
 	//	 - key with nil value indicates no filters. Get all instances for account.
 	//   - key with "some-filter-string" value indicates some filter. Get instances matching those filters only.
 	instanceFilters map[string][][]*ec2.Filter
+	// securityGroupMode is how security groups not created by nephe are treated on network interfaces.
+	securityGroupMode v1alpha1.SecurityGroupMode
+	// prefixListThreshold is the number of CIDRs of a rule realized as a prefix list, 0 if disabled.
+	prefixListThreshold int
+	// securityGroupQuotas are quotas of security groups of the account.
+	securityGroupQuotas awsSecurityGroupQuotas
 }
 
 // ec2ResourcesCacheSnapshot holds the results from querying for all instances.
@@ -51,9 +61,13 @@ type ec2ResourcesCacheSnapshot struct {
 	vpcIDs      map[string]struct{}
 	vpcNameToID map[string]string
 	vpcPeers    map[string][]string
+	vpcs        []*ec2.Vpc
 }
 
-func newEC2ServiceConfig(name string, service awsServiceClientCreateInterface) (internal.CloudServiceInterface, error) {
+func newEC2ServiceConfig(name string, serviceName internal.CloudServiceName, region string,
+	securityGroupMode v1alpha1.SecurityGroupMode, prefixListThreshold int, securityGroupQuotas awsSecurityGroupQuotas,
+	service awsServiceClientCreateInterface) (
+	internal.CloudServiceInterface, error) {
 	// create ec2 sdk api client
 	apiClient, err := service.compute()
 	if err != nil {
@@ -63,9 +77,15 @@ func newEC2ServiceConfig(name string, service awsServiceClientCreateInterface) (
 	config := &ec2ServiceConfig{
 		apiClient:       apiClient,
 		accountName:     name,
+		name:            serviceName,
+		region:          region,
 		resourcesCache:  &internal.CloudServiceResourcesCache{},
 		inventoryStats:  &internal.CloudServiceStats{},
 		instanceFilters: make(map[string][][]*ec2.Filter),
+
+		securityGroupMode:   securityGroupMode,
+		prefixListThreshold: prefixListThreshold,
+		securityGroupQuotas: securityGroupQuotas,
 	}
 	return config, nil
 }
@@ -121,7 +141,7 @@ func (ec2Cfg *ec2ServiceConfig) getInstanceResourceFilters() ([][]*ec2.Filter, b
 func (ec2Cfg *ec2ServiceConfig) getCachedInstances() []*ec2.Instance {
 	snapshot := ec2Cfg.resourcesCache.GetSnapshot()
 	if snapshot == nil {
-		awsPluginLogger().V(4).Info("cache snapshot nil", "service", awsComputeServiceNameEC2, "account", ec2Cfg.accountName)
+		awsPluginLogger().V(4).Info("cache snapshot nil", "service", ec2Cfg.name, "account", ec2Cfg.accountName)
 		return []*ec2.Instance{}
 	}
 	instances := snapshot.(*ec2ResourcesCacheSnapshot).instances
@@ -129,7 +149,7 @@ func (ec2Cfg *ec2ServiceConfig) getCachedInstances() []*ec2.Instance {
 	for _, instance := range instances {
 		instancesToReturn = append(instancesToReturn, instance)
 	}
-	awsPluginLogger().V(1).Info("cached instances", "service", awsComputeServiceNameEC2, "account", ec2Cfg.accountName,
+	awsPluginLogger().V(1).Info("cached instances", "service", ec2Cfg.name, "account", ec2Cfg.accountName,
 		"instances", len(instancesToReturn))
 	return instancesToReturn
 }
@@ -139,7 +159,7 @@ func (ec2Cfg *ec2ServiceConfig) getCachedVpcIDs() map[string]struct{} {
 	vpcIDsCopy := make(map[string]struct{})
 	snapshot := ec2Cfg.resourcesCache.GetSnapshot()
 	if snapshot == nil {
-		awsPluginLogger().V(4).Info("cache snapshot nil", "service", awsComputeServiceNameEC2, "account", ec2Cfg.accountName)
+		awsPluginLogger().V(4).Info("cache snapshot nil", "service", ec2Cfg.name, "account", ec2Cfg.accountName)
 		return vpcIDsCopy
 	}
 	vpcIDsSet := snapshot.(*ec2ResourcesCacheSnapshot).vpcIDs
@@ -168,6 +188,19 @@ func (ec2Cfg *ec2ServiceConfig) getCachedVpcNameToID() map[string]string {
 	return vpcNameToIDCopy
 }
 
+// getCachedVpcs returns vpcs from the cache for the account.
+func (ec2Cfg *ec2ServiceConfig) getCachedVpcs() []*ec2.Vpc {
+	snapshot := ec2Cfg.resourcesCache.GetSnapshot()
+	if snapshot == nil {
+		awsPluginLogger().V(4).Info("cache snapshot nil", "service", ec2Cfg.name, "account", ec2Cfg.accountName)
+		return []*ec2.Vpc{}
+	}
+	vpcs := snapshot.(*ec2ResourcesCacheSnapshot).vpcs
+	vpcsToReturn := make([]*ec2.Vpc, 0, len(vpcs))
+	vpcsToReturn = append(vpcsToReturn, vpcs...)
+	return vpcsToReturn
+}
+
 // getVpcPeers returns all the peers of a vpc.
 func (ec2Cfg *ec2ServiceConfig) getVpcPeers(vpcID string) []string {
 	snapshot := ec2Cfg.resourcesCache.GetSnapshot()
@@ -182,12 +215,16 @@ func (ec2Cfg *ec2ServiceConfig) getVpcPeers(vpcID string) []string {
 	return vpcPeersCopy
 }
 
-// getInstances gets instance for the account from aws EC2 API.
-func (ec2Cfg *ec2ServiceConfig) getInstances() ([]*ec2.Instance, error) {
+// getInstances gets instance for the account from aws EC2 API. If instanceIDs is not empty, only instances of
+// instanceIDs are returned.
+func (ec2Cfg *ec2ServiceConfig) getInstances(instanceIDs ...string) ([]*ec2.Instance, error) {
 	filters, _ := ec2Cfg.getInstanceResourceFilters()
 	if filters == nil {
 		var validInstanceStateFilters []*ec2.Filter
 		validInstanceStateFilters = append(validInstanceStateFilters, buildEc2FilterForValidInstanceStates())
+		if len(instanceIDs) > 0 {
+			validInstanceStateFilters = buildEc2FiltersForInstanceIDs(validInstanceStateFilters, instanceIDs)
+		}
 		request := &ec2.DescribeInstancesInput{Filters: validInstanceStateFilters}
 		return ec2Cfg.apiClient.pagedDescribeInstancesWrapper(request)
 	}
@@ -199,6 +236,11 @@ func (ec2Cfg *ec2ServiceConfig) getInstances() ([]*ec2.Instance, error) {
 				filter = buildFilterForVPCIDFromFilterForVPCName(filter, ec2Cfg.getCachedVpcNameToID())
 			}
 		}
+		if len(instanceIDs) > 0 {
+			if filter = buildEc2FiltersForInstanceIDs(filter, instanceIDs); filter == nil {
+				continue
+			}
+		}
 		request := &ec2.DescribeInstancesInput{Filters: filter}
 		filterInstances, e := ec2Cfg.apiClient.pagedDescribeInstancesWrapper(request)
 		if e == credentials.ErrNoValidProvidersFoundInChain {
@@ -215,7 +257,7 @@ func (ec2Cfg *ec2ServiceConfig) getInstances() ([]*ec2.Instance, error) {
 		instances = append(instances, filterInstances...)
 	}
 
-	awsPluginLogger().V(1).Info("instances from cloud", "service", awsComputeServiceNameEC2, "account", ec2Cfg.accountName,
+	awsPluginLogger().V(1).Info("instances from cloud", "service", ec2Cfg.name, "account", ec2Cfg.accountName,
 		"instances", len(instances))
 
 	return instances, nil
@@ -230,20 +272,82 @@ func (ec2Cfg *ec2ServiceConfig) DoResourceInventory() error {
 		exists := struct{}{}
 		vpcIDs := make(map[string]struct{})
 		instanceIDs := make(map[cloudcommon.InstanceID]*ec2.Instance)
-		vpcNameToID, _ := ec2Cfg.buildMapVpcNameToID()
+		vpcs, _ := ec2Cfg.getVpcs()
+		vpcNameToID := ec2Cfg.buildMapVpcNameToID(vpcs)
 		vpcPeers, _ := ec2Cfg.buildMapVpcPeers()
 		for _, instance := range instances {
 			id := cloudcommon.InstanceID(strings.ToLower(aws.StringValue(instance.InstanceId)))
 			instanceIDs[id] = instance
 			vpcIDs[strings.ToLower(*instance.VpcId)] = exists
 		}
-		ec2Cfg.resourcesCache.UpdateSnapshot(&ec2ResourcesCacheSnapshot{instanceIDs, vpcIDs, vpcNameToID, vpcPeers})
+		ec2Cfg.resourcesCache.UpdateSnapshot(&ec2ResourcesCacheSnapshot{instanceIDs, vpcIDs, vpcNameToID, vpcPeers, vpcs})
 	}
 	ec2Cfg.inventoryStats.UpdateInventoryPollStats(e)
 
 	return e
 }
 
+// ApplyInventoryEvents updates cached instances with events of instances of the service region. Instances of update
+// events are described with configured filters, so that the cache holds the instances a full inventory would.
+func (ec2Cfg *ec2ServiceConfig) ApplyInventoryEvents(events []*internal.InventoryEvent) (bool, error) {
+	snapshot, ok := ec2Cfg.resourcesCache.GetSnapshot().(*ec2ResourcesCacheSnapshot)
+	if !ok || snapshot == nil {
+		// events before the first inventory are covered by it.
+		return false, nil
+	}
+
+	eventTypes := make(map[string]internal.InventoryEventType)
+	for _, event := range events {
+		if len(event.Region) != 0 && event.Region != ec2Cfg.region {
+			continue
+		}
+		eventTypes[strings.ToLower(event.ResourceID)] = event.Type
+	}
+	var updatedInstanceIDs []string
+	for instanceID, eventType := range eventTypes {
+		if eventType == internal.InventoryEventUpdated {
+			updatedInstanceIDs = append(updatedInstanceIDs, instanceID)
+		}
+	}
+	var instances []*ec2.Instance
+	if len(updatedInstanceIDs) != 0 {
+		sort.Strings(updatedInstanceIDs)
+		var err error
+		if instances, err = ec2Cfg.getInstances(updatedInstanceIDs...); err != nil {
+			return false, err
+		}
+	}
+
+	instanceIDs := make(map[cloudcommon.InstanceID]*ec2.Instance, len(snapshot.instances))
+	for id, instance := range snapshot.instances {
+		if _, found := eventTypes[string(id)]; !found {
+			instanceIDs[id] = instance
+		}
+	}
+	for _, instance := range instances {
+		instanceIDs[cloudcommon.InstanceID(strings.ToLower(aws.StringValue(instance.InstanceId)))] = instance
+	}
+	updated := len(instanceIDs) != len(snapshot.instances)
+	for id, instance := range instanceIDs {
+		if !reflect.DeepEqual(snapshot.instances[id], instance) {
+			updated = true
+		}
+	}
+	if !updated {
+		return false, nil
+	}
+
+	vpcIDs := make(map[string]struct{})
+	for _, instance := range instanceIDs {
+		vpcIDs[strings.ToLower(aws.StringValue(instance.VpcId))] = struct{}{}
+	}
+	ec2Cfg.resourcesCache.UpdateSnapshot(&ec2ResourcesCacheSnapshot{instanceIDs, vpcIDs, snapshot.vpcNameToID, snapshot.vpcPeers,
+		snapshot.vpcs})
+	awsPluginLogger().V(1).Info("instances updated by events", "service", ec2Cfg.name, "account", ec2Cfg.accountName,
+		"instances", len(eventTypes))
+	return true, nil
+}
+
 // setInstanceFilters add/updates instances resource filter for the service.
 func (ec2Cfg *ec2ServiceConfig) SetResourceFilters(selector *v1alpha1.CloudEntitySelector) {
 	if filters, found := convertSelectorToEC2InstanceFilters(selector); found {
@@ -261,15 +365,22 @@ func (ec2Cfg *ec2ServiceConfig) GetResourceCRDs(namespace string) *internal.Clou
 	vmCRDs := make([]*v1alpha1.VirtualMachine, 0, len(instances))
 	for _, instance := range instances {
 		// build VirtualMachine CRD
-		vmCRD := ec2InstanceToVirtualMachineCRD(instance, namespace)
+		vmCRD := ec2InstanceToVirtualMachineCRD(instance, namespace, ec2Cfg.region)
 		vmCRDs = append(vmCRDs, vmCRD)
 	}
 
-	awsPluginLogger().V(1).Info("CRDs", "service", awsComputeServiceNameEC2, "account", ec2Cfg.accountName,
+	awsPluginLogger().V(1).Info("CRDs", "service", ec2Cfg.name, "account", ec2Cfg.accountName,
 		"virtual-machine CRDs", len(vmCRDs))
 
+	vpcs := ec2Cfg.getCachedVpcs()
+	vpcCRDs := make([]*v1alpha1.Vpc, 0, len(vpcs))
+	for _, vpc := range vpcs {
+		vpcCRDs = append(vpcCRDs, ec2VpcToVpcCRD(vpc, namespace, ec2Cfg.region, ec2Cfg.getVpcPeers(*vpc.VpcId)))
+	}
+
 	serviceResourceCRDs := &internal.CloudServiceResourceCRDs{}
 	serviceResourceCRDs.SetComputeResourceCRDs(vmCRDs)
+	serviceResourceCRDs.SetVpcResourceCRDs(vpcCRDs)
 
 	return serviceResourceCRDs
 }
@@ -281,7 +392,7 @@ func (ec2Cfg *ec2ServiceConfig) HasFiltersConfigured() (bool, bool) {
 }
 
 func (ec2Cfg *ec2ServiceConfig) GetName() internal.CloudServiceName {
-	return awsComputeServiceNameEC2
+	return ec2Cfg.name
 }
 
 func (ec2Cfg *ec2ServiceConfig) GetType() internal.CloudServiceType {
@@ -300,16 +411,24 @@ func (ec2Cfg *ec2ServiceConfig) ResetCachedState() {
 func (ec2Cfg *ec2ServiceConfig) UpdateServiceConfig(newConfig internal.CloudServiceInterface) {
 	newEc2ServiceConfig := newConfig.(*ec2ServiceConfig)
 	ec2Cfg.apiClient = newEc2ServiceConfig.apiClient
+	ec2Cfg.securityGroupMode = newEc2ServiceConfig.securityGroupMode
+	ec2Cfg.prefixListThreshold = newEc2ServiceConfig.prefixListThreshold
+	ec2Cfg.securityGroupQuotas = newEc2ServiceConfig.securityGroupQuotas
 }
 
-func (ec2Cfg *ec2ServiceConfig) buildMapVpcNameToID() (map[string]string, error) {
-	vpcNameToID := make(map[string]string)
+// getVpcs gets all vpcs of the account region from aws EC2 API.
+func (ec2Cfg *ec2ServiceConfig) getVpcs() ([]*ec2.Vpc, error) {
 	result, err := ec2Cfg.apiClient.describeVpcsWrapper(nil)
 	if err != nil {
 		awsPluginLogger().V(0).Info("error describing vpcs", "error", err)
-		return vpcNameToID, err
+		return nil, err
 	}
-	for _, vpc := range result.Vpcs {
+	return result.Vpcs, nil
+}
+
+func (ec2Cfg *ec2ServiceConfig) buildMapVpcNameToID(vpcs []*ec2.Vpc) map[string]string {
+	vpcNameToID := make(map[string]string)
+	for _, vpc := range vpcs {
 		if len(vpc.Tags) == 0 {
 			awsPluginLogger().V(4).Info("vpc name not found", "account", ec2Cfg.accountName, "vpc", vpc)
 			continue
@@ -323,7 +442,7 @@ func (ec2Cfg *ec2ServiceConfig) buildMapVpcNameToID() (map[string]string, error)
 		}
 		vpcNameToID[vpcName] = *vpc.VpcId
 	}
-	return vpcNameToID, nil
+	return vpcNameToID
 }
 
 func (ec2Cfg *ec2ServiceConfig) buildMapVpcPeers() (map[string][]string, error) {
@@ -0,0 +1,299 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"k8s.io/apimachinery/pkg/types"
+
+	"antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
+)
+
+const (
+	awsEventSourceEC2                     = "aws.ec2"
+	awsEventDetailTypeInstanceStateChange = "EC2 Instance State-change Notification"
+	awsEventDetailTypeAPICall             = "AWS API Call via CloudTrail"
+	awsAPICallEventSourceEC2              = "ec2.amazonaws.com"
+	awsInstanceIDPrefix                   = "i-"
+
+	// awsEventQueueWaitSeconds is the duration to wait for messages when receiving from the event queue.
+	awsEventQueueWaitSeconds = 20
+	// awsEventQueueMaxMessages is the maximum number of messages received, or deleted, at a time.
+	awsEventQueueMaxMessages = 10
+)
+
+// awsInventoryEventSource receives EC2 events of an account from an SQS queue. Events are delivered to the queue by
+// EventBridge rules, matching EC2 instance state-change notifications and EC2 API calls recorded by CloudTrail.
+type awsInventoryEventSource struct {
+	accountName string
+	queueURL    string
+	apiClient   awsSQSWrapper
+}
+
+// awsEvent is an EventBridge event.
+type awsEvent struct {
+	Source     string          `json:"source"`
+	DetailType string          `json:"detail-type"`
+	Region     string          `json:"region"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// awsInstanceStateChangeDetail is detail of an EC2 instance state-change notification.
+type awsInstanceStateChangeDetail struct {
+	InstanceID string `json:"instance-id"`
+	State      string `json:"state"`
+}
+
+// awsAPICallDetail is detail of an API call recorded by CloudTrail.
+type awsAPICallDetail struct {
+	EventSource       string              `json:"eventSource"`
+	ErrorCode         string              `json:"errorCode"`
+	RequestParameters *awsAPICallElements `json:"requestParameters"`
+	ResponseElements  *awsAPICallElements `json:"responseElements"`
+}
+
+// awsAPICallElements are request parameters or response elements of an EC2 API call, referring to instances.
+type awsAPICallElements struct {
+	InstanceID   string `json:"instanceId"`
+	InstancesSet struct {
+		Items []struct {
+			InstanceID string `json:"instanceId"`
+		} `json:"items"`
+	} `json:"instancesSet"`
+	ResourcesSet struct {
+		Items []struct {
+			ResourceID string `json:"resourceId"`
+		} `json:"items"`
+	} `json:"resourcesSet"`
+}
+
+// awsSNSNotification is an SNS notification, when events are delivered to the queue through an SNS topic.
+type awsSNSNotification struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+func newAwsInventoryEventSource(accountNamespacedName *types.NamespacedName, accCredentials interface{},
+	awsSpecificHelper interface{}) (internal.InventoryEventSource, error) {
+	awsServicesHelper := awsSpecificHelper.(awsServicesHelper)
+	awsAccountCredentials := accCredentials.(*awsAccountCredentials)
+	if len(awsAccountCredentials.eventQueueURL) == 0 {
+		return nil, nil
+	}
+
+	queueCredentials := *awsAccountCredentials
+	queueCredentials.region = getEventQueueRegion(awsAccountCredentials)
+	awsServiceClientCreator, err := awsServicesHelper.newServiceSdkConfigProvider(&queueCredentials)
+	if err != nil {
+		return nil, err
+	}
+	apiClient, err := awsServiceClientCreator.events(queueCredentials.eventQueueURL)
+	if err != nil {
+		return nil, fmt.Errorf("error creating sqs sdk api client for account : %v, err: %v", accountNamespacedName, err)
+	}
+
+	return &awsInventoryEventSource{
+		accountName: accountNamespacedName.String(),
+		queueURL:    queueCredentials.eventQueueURL,
+		apiClient:   apiClient,
+	}, nil
+}
+
+// getEventQueueRegion returns region of the event queue of an account. The region is taken from the queue URL, like
+// https://sqs.us-west-2.amazonaws.com/123456789012/queue, or is otherwise the first region of the account.
+func getEventQueueRegion(accCreds *awsAccountCredentials) string {
+	if u, err := url.Parse(accCreds.eventQueueURL); err == nil {
+		labels := strings.Split(u.Hostname(), ".")
+		if len(labels) == 4 && labels[0] == "sqs" && strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+			return labels[1]
+		}
+	}
+	if len(accCreds.regions) != 0 && accCreds.regions[0] != v1alpha1.AllRegions {
+		return accCreds.regions[0]
+	}
+	return awsDefaultRegion
+}
+
+// events returns AWS SQS SDK apiClient of the event queue. A queue not hosted by AWS, e.g. a local SQS compatible
+// queue, is accessed at the host of its URL.
+func (p *awsServiceSdkConfigProvider) events(queueURL string) (awsSQSWrapper, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return nil, err
+	}
+	var configs []*aws.Config
+	if !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		configs = append(configs, aws.NewConfig().WithEndpoint(u.Scheme+"://"+u.Host))
+	}
+
+	awsSQS := &awsSQSWrapperImpl{
+		sqs: sqs.New(p.session, configs...),
+	}
+
+	return awsSQS, nil
+}
+
+// ReceiveEvents waits for messages of the event queue and returns EC2 instance events of the messages.
+func (s *awsInventoryEventSource) ReceiveEvents() ([]*internal.InventoryEvent, error) {
+	output, err := s.apiClient.receiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(s.queueURL),
+		MaxNumberOfMessages: aws.Int64(awsEventQueueMaxMessages),
+		WaitTimeSeconds:     aws.Int64(awsEventQueueWaitSeconds),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error receiving messages from queue %v: %v", s.queueURL, err)
+	}
+
+	var events []*internal.InventoryEvent
+	var ignoredReceipts []string
+	for _, message := range output.Messages {
+		receipt := aws.StringValue(message.ReceiptHandle)
+		messageEvents := parseAwsEvents(aws.StringValue(message.Body))
+		if len(messageEvents) == 0 {
+			ignoredReceipts = append(ignoredReceipts, receipt)
+			continue
+		}
+		for _, event := range messageEvents {
+			event.Receipt = receipt
+		}
+		events = append(events, messageEvents...)
+	}
+	if len(ignoredReceipts) != 0 {
+		awsPluginLogger().V(1).Info("messages without instance events ignored", "account", s.accountName,
+			"messages", len(ignoredReceipts))
+		if err := s.deleteMessages(ignoredReceipts); err != nil {
+			// ignored messages are received again.
+			awsPluginLogger().Error(err, "failed to delete ignored messages", "account", s.accountName)
+		}
+	}
+	return events, nil
+}
+
+// AckEvents deletes messages of events from the event queue.
+func (s *awsInventoryEventSource) AckEvents(events []*internal.InventoryEvent) error {
+	var receipts []string
+	seen := make(map[string]struct{})
+	for _, event := range events {
+		if _, found := seen[event.Receipt]; found {
+			continue
+		}
+		seen[event.Receipt] = struct{}{}
+		receipts = append(receipts, event.Receipt)
+	}
+	return s.deleteMessages(receipts)
+}
+
+// deleteMessages deletes messages of receipts from the event queue.
+func (s *awsInventoryEventSource) deleteMessages(receipts []string) error {
+	for start := 0; start < len(receipts); start += awsEventQueueMaxMessages {
+		end := start + awsEventQueueMaxMessages
+		if end > len(receipts) {
+			end = len(receipts)
+		}
+		entries := make([]*sqs.DeleteMessageBatchRequestEntry, 0, end-start)
+		for i, receipt := range receipts[start:end] {
+			entries = append(entries, &sqs.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: aws.String(receipt),
+			})
+		}
+		output, err := s.apiClient.deleteMessageBatch(&sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(s.queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return fmt.Errorf("error deleting messages from queue %v: %v", s.queueURL, err)
+		}
+		if len(output.Failed) != 0 {
+			return fmt.Errorf("error deleting %v messages from queue %v: %v", len(output.Failed), s.queueURL,
+				aws.StringValue(output.Failed[0].Message))
+		}
+	}
+	return nil
+}
+
+// parseAwsEvents returns EC2 instance events of a message of the event queue.
+func parseAwsEvents(body string) []*internal.InventoryEvent {
+	notification := awsSNSNotification{}
+	if err := json.Unmarshal([]byte(body), &notification); err == nil && notification.Type == "Notification" {
+		body = notification.Message
+	}
+	event := awsEvent{}
+	if err := json.Unmarshal([]byte(body), &event); err != nil || event.Source != awsEventSourceEC2 {
+		return nil
+	}
+
+	switch event.DetailType {
+	case awsEventDetailTypeInstanceStateChange:
+		detail := awsInstanceStateChangeDetail{}
+		if err := json.Unmarshal(event.Detail, &detail); err != nil || len(detail.InstanceID) == 0 {
+			return nil
+		}
+		eventType := internal.InventoryEventUpdated
+		if detail.State == ec2.InstanceStateNameTerminated {
+			eventType = internal.InventoryEventDeleted
+		}
+		return []*internal.InventoryEvent{{Type: eventType, ResourceID: detail.InstanceID, Region: event.Region}}
+	case awsEventDetailTypeAPICall:
+		detail := awsAPICallDetail{}
+		if err := json.Unmarshal(event.Detail, &detail); err != nil || detail.EventSource != awsAPICallEventSourceEC2 ||
+			len(detail.ErrorCode) != 0 {
+			return nil
+		}
+		var events []*internal.InventoryEvent
+		seen := make(map[string]struct{})
+		for _, instanceID := range append(detail.RequestParameters.getInstanceIDs(), detail.ResponseElements.getInstanceIDs()...) {
+			if _, found := seen[instanceID]; found {
+				continue
+			}
+			seen[instanceID] = struct{}{}
+			events = append(events, &internal.InventoryEvent{Type: internal.InventoryEventUpdated, ResourceID: instanceID,
+				Region: event.Region})
+		}
+		return events
+	}
+	return nil
+}
+
+// getInstanceIDs returns ids of instances referred by API call elements.
+func (e *awsAPICallElements) getInstanceIDs() []string {
+	if e == nil {
+		return nil
+	}
+	var instanceIDs []string
+	if len(e.InstanceID) != 0 {
+		instanceIDs = append(instanceIDs, e.InstanceID)
+	}
+	for _, item := range e.InstancesSet.Items {
+		if len(item.InstanceID) != 0 {
+			instanceIDs = append(instanceIDs, item.InstanceID)
+		}
+	}
+	for _, item := range e.ResourcesSet.Items {
+		if strings.HasPrefix(item.ResourceID, awsInstanceIDPrefix) {
+			instanceIDs = append(instanceIDs, item.ResourceID)
+		}
+	}
+	return instanceIDs
+}
@@ -0,0 +1,303 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
+)
+
+// sqsStandIn is a local stand-in of an SQS queue, serving ReceiveMessage and DeleteMessageBatch of the SQS query API.
+// Received messages are not visible again until deleted.
+type sqsStandIn struct {
+	mutex    sync.Mutex
+	nextID   int
+	visible  []string
+	messages map[string]string
+}
+
+type sqsStandInMessage struct {
+	MessageID     string `xml:"MessageId"`
+	ReceiptHandle string `xml:"ReceiptHandle"`
+	MD5OfBody     string `xml:"MD5OfBody"`
+	Body          string `xml:"Body"`
+}
+
+func newSQSStandIn() *sqsStandIn {
+	return &sqsStandIn{messages: make(map[string]string)}
+}
+
+// send adds a message to the queue.
+func (q *sqsStandIn) send(body string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.nextID++
+	receipt := fmt.Sprintf("receipt-%d", q.nextID)
+	q.messages[receipt] = body
+	q.visible = append(q.visible, receipt)
+}
+
+// pending returns the number of messages not deleted.
+func (q *sqsStandIn) pending() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.messages)
+}
+
+func (q *sqsStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var response interface{}
+	switch r.Form.Get("Action") {
+	case "ReceiveMessage":
+		messages := q.receive()
+		if len(messages) == 0 {
+			// waits shortly, instead of WaitTimeSeconds, for no new message.
+			time.Sleep(100 * time.Millisecond)
+		}
+		response = struct {
+			XMLName  xml.Name            `xml:"ReceiveMessageResponse"`
+			Messages []sqsStandInMessage `xml:"ReceiveMessageResult>Message"`
+		}{Messages: messages}
+	case "DeleteMessageBatch":
+		var ids []string
+		for i := 1; len(r.Form.Get(fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.Id", i))) != 0; i++ {
+			ids = append(ids, r.Form.Get(fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.Id", i)))
+			q.delete(r.Form.Get(fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.ReceiptHandle", i)))
+		}
+		response = struct {
+			XMLName xml.Name `xml:"DeleteMessageBatchResponse"`
+			IDs     []string `xml:"DeleteMessageBatchResult>DeleteMessageBatchResultEntry>Id"`
+		}{IDs: ids}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	body, _ := xml.Marshal(response)
+	_, _ = w.Write(body)
+}
+
+func (q *sqsStandIn) receive() []sqsStandInMessage {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	messages := make([]sqsStandInMessage, 0, len(q.visible))
+	for _, receipt := range q.visible {
+		body := q.messages[receipt]
+		sum := md5.Sum([]byte(body))
+		messages = append(messages, sqsStandInMessage{MessageID: receipt, ReceiptHandle: receipt,
+			MD5OfBody: hex.EncodeToString(sum[:]), Body: body})
+	}
+	q.visible = nil
+	return messages
+}
+
+func (q *sqsStandIn) delete(receipt string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	delete(q.messages, receipt)
+}
+
+func getInstanceStateChangeEvent(instanceID, state string) string {
+	return fmt.Sprintf(`{"version":"0","source":"aws.ec2","detail-type":"EC2 Instance State-change Notification",`+
+		`"region":"us-east-1","detail":{"instance-id":%q,"state":%q}}`, instanceID, state)
+}
+
+// getInstanceIDFilterValues returns values of instance-id filter of a describe instances request.
+func getInstanceIDFilterValues(input *ec2.DescribeInstancesInput) []string {
+	for _, filter := range input.Filters {
+		if aws.StringValue(filter.Name) == awsFilterKeyVMID {
+			return aws.StringValueSlice(filter.Values)
+		}
+	}
+	return nil
+}
+
+var _ = Describe("AWS inventory events", func() {
+	var (
+		testAccountNamespacedName = types.NamespacedName{Namespace: "namespace01", Name: "account01"}
+
+		account  *v1alpha1.CloudProviderAccount
+		selector *v1alpha1.CloudEntitySelector
+		queue    *sqsStandIn
+		server   *httptest.Server
+		queueURL string
+
+		mockCtrl           *gomock.Controller
+		mockawsCloudHelper *MockawsServicesHelper
+		mockawsService     *MockawsServiceClientCreateInterface
+		mockawsEC2         *MockawsEC2Wrapper
+
+		sess           *session.Session
+		cloudMutex     sync.Mutex
+		cloudInstances []string
+	)
+
+	BeforeEach(func() {
+		queue = newSQSStandIn()
+		server = httptest.NewServer(queue)
+		queueURL = server.URL + "/123456789012/nephe-events"
+
+		// inventory is updated only by events in between polls.
+		var pollIntv uint = 600
+		account = &v1alpha1.CloudProviderAccount{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      testAccountNamespacedName.Name,
+				Namespace: testAccountNamespacedName.Namespace,
+			},
+			Spec: v1alpha1.CloudProviderAccountSpec{
+				PollIntervalInSeconds: &pollIntv,
+				AWSConfig: &v1alpha1.CloudProviderAccountAWSConfig{
+					AccountID:       "TestAccount01",
+					AccessKeyID:     "keyId",
+					AccessKeySecret: "keySecret",
+					Region:          "us-east-1",
+					EventQueueURL:   queueURL,
+				},
+			},
+		}
+		selector = &v1alpha1.CloudEntitySelector{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "selector-all",
+				Namespace: testAccountNamespacedName.Namespace,
+			},
+			Spec: v1alpha1.CloudEntitySelectorSpec{
+				AccountName: testAccountNamespacedName.Name,
+				VMSelector:  []v1alpha1.VirtualMachineSelector{},
+			},
+		}
+		cloudInstances = []string{"i-01", "i-02"}
+
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockawsCloudHelper = NewMockawsServicesHelper(mockCtrl)
+		mockawsService = NewMockawsServiceClientCreateInterface(mockCtrl)
+		mockawsEC2 = NewMockawsEC2Wrapper(mockCtrl)
+
+		sess = session.Must(session.NewSession(&aws.Config{
+			Region:      aws.String("us-east-1"),
+			Credentials: credentials.NewStaticCredentials("keyId", "keySecret", ""),
+		}))
+		mockawsService.EXPECT().compute().Return(mockawsEC2, nil).AnyTimes()
+		mockawsEC2.EXPECT().pagedDescribeInstancesWrapper(gomock.Any()).DoAndReturn(
+			func(input *ec2.DescribeInstancesInput) ([]*ec2.Instance, error) {
+				cloudMutex.Lock()
+				defer cloudMutex.Unlock()
+
+				instanceIDs := getInstanceIDFilterValues(input)
+				if instanceIDs == nil {
+					return getEc2InstanceObject(cloudInstances), nil
+				}
+				var matched []string
+				for _, instanceID := range instanceIDs {
+					for _, cloudInstance := range cloudInstances {
+						if instanceID == cloudInstance {
+							matched = append(matched, instanceID)
+						}
+					}
+				}
+				return getEc2InstanceObject(matched), nil
+			}).AnyTimes()
+		mockawsEC2.EXPECT().describeVpcsWrapper(gomock.Any()).Return(&ec2.DescribeVpcsOutput{}, nil).AnyTimes()
+		mockawsEC2.EXPECT().describeVpcPeeringConnectionsWrapper(gomock.Any()).Return(&ec2.DescribeVpcPeeringConnectionsOutput{},
+			nil).AnyTimes()
+	})
+
+	AfterEach(func() {
+		server.Close()
+		mockCtrl.Finish()
+	})
+
+	It("Should update inventory with events of the queue", func() {
+		// api clients of the account services and of the event queue.
+		mockawsCloudHelper.EXPECT().newServiceSdkConfigProvider(gomock.Any()).Return(mockawsService, nil).Times(2)
+		mockawsService.EXPECT().events(queueURL).DoAndReturn(func(queueURL string) (awsSQSWrapper, error) {
+			return (&awsServiceSdkConfigProvider{session: sess}).events(queueURL)
+		})
+
+		c := newAWSCloud(mockawsCloudHelper)
+		err := c.AddProviderAccount(account)
+		Expect(err).Should(BeNil())
+		err = c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+		Expect(err).Should(BeNil())
+		defer c.RemoveAccountResourcesSelector(&testAccountNamespacedName, selector.Name)
+		err = checkAccountAddSuccessCondition(c, testAccountNamespacedName, []string{"i-01", "i-02"})
+		Expect(err).Should(BeNil())
+
+		stopCh := make(chan struct{})
+		defer close(stopCh)
+		updateCh, err := c.WatchAccountInventory(&testAccountNamespacedName, stopCh)
+		Expect(err).Should(BeNil())
+
+		cloudMutex.Lock()
+		cloudInstances = []string{"i-02", "i-03"}
+		cloudMutex.Unlock()
+		queue.send(getInstanceStateChangeEvent("i-01", ec2.InstanceStateNameTerminated))
+		queue.send(getInstanceStateChangeEvent("i-03", ec2.InstanceStateNameRunning))
+		queue.send(`{"source":"aws.s3","detail-type":"Object Created","detail":{}}`)
+
+		Eventually(updateCh, 5*time.Second).Should(Receive())
+		err = checkAccountAddSuccessCondition(c, testAccountNamespacedName, []string{"i-02", "i-03"})
+		Expect(err).Should(BeNil())
+		Eventually(queue.pending, 5*time.Second).Should(BeZero())
+	})
+
+	It("Should receive events of EC2 API calls delivered through SNS", func() {
+		apiCallEvent := `{"source":"aws.ec2","detail-type":"AWS API Call via CloudTrail","region":"us-east-1",` +
+			`"detail":{"eventSource":"ec2.amazonaws.com","eventName":"CreateTags","requestParameters":{"resourcesSet":` +
+			`{"items":[{"resourceId":"i-01"},{"resourceId":"sg-01"}]}},"responseElements":null}}`
+		apiClient, err := (&awsServiceSdkConfigProvider{session: sess}).events(queueURL)
+		Expect(err).Should(BeNil())
+		source := &awsInventoryEventSource{accountName: testAccountNamespacedName.String(), queueURL: queueURL,
+			apiClient: apiClient}
+
+		queue.send(`{"Type":"Notification","Message":` + strconv.Quote(apiCallEvent) + `}`)
+		events, err := source.ReceiveEvents()
+		Expect(err).Should(BeNil())
+		Expect(events).To(Equal([]*internal.InventoryEvent{
+			{Type: internal.InventoryEventUpdated, ResourceID: "i-01", Region: "us-east-1", Receipt: "receipt-1"},
+		}))
+		Expect(queue.pending()).To(Equal(1))
+
+		err = source.AckEvents(events)
+		Expect(err).Should(BeNil())
+		Expect(queue.pending()).To(BeZero())
+	})
+})
@@ -29,6 +29,8 @@ const (
 	awsFilterKeyVPCID         = "vpc-id"
 	awsFilterKeyVMID          = "instance-id"
 	awsFilterKeyVMName        = "tag:Name"
+	awsFilterKeyTagPrefix     = "tag:"
+	awsFilterKeyTagKey        = "tag-key"
 	awsFilterKeyGroupName     = "group-name"
 	awsFilterKeyInstanceState = "instance-state-code"
 
@@ -63,6 +65,7 @@ func buildEc2Filters(vmSelector []v1alpha1.VirtualMachineSelector) [][]*ec2.Filt
 	var vmIDAndVMNameMatches []v1alpha1.EntityMatch
 	var vmNameOnlyMatches []v1alpha1.EntityMatch
 	var vpcNameOnlyMatches []v1alpha1.VirtualMachineSelector
+	var vmTagMatches []v1alpha1.VirtualMachineSelector
 
 	// vpcMatch contains VpcID and vmMatch contains nil:
 	// vpcIDsWithVpcIDOnlyMatches slice contains the corresponding vmSelector section.
@@ -78,6 +81,9 @@ func buildEc2Filters(vmSelector []v1alpha1.VirtualMachineSelector) [][]*ec2.Filt
 	// vpcMatch contains nil and vmMatch contains only vmName:
 	// vmNameOnlyMatches slice contains the specific vmMatch section(EntityMatch).
 	// ec2.Filter is created to match only vms matching the matchName.
+	// vpcMatch contains nil or VpcName and vmMatch contains tags:
+	// vmTagMatches slice contains the vpcMatch with the specific vmMatch section.
+	// ec2.Filter is created for each of them to match vms carrying all tags and matching other match criteria.
 
 	for _, match := range vmSelector {
 		isVpcIDPresent := false
@@ -112,6 +118,7 @@ func buildEc2Filters(vmSelector []v1alpha1.VirtualMachineSelector) [][]*ec2.Filt
 		for _, vmmatch := range match.VMMatch {
 			isVMIDPresent := false
 			isVMNamePresent := false
+			isVMTagsPresent := len(vmmatch.MatchTags) > 0
 			if len(strings.TrimSpace(vmmatch.MatchID)) > 0 {
 				isVMIDPresent = true
 			}
@@ -119,13 +126,22 @@ func buildEc2Filters(vmSelector []v1alpha1.VirtualMachineSelector) [][]*ec2.Filt
 				isVMNamePresent = true
 			}
 
-			if isVpcIDPresent && (isVMIDPresent || isVMNamePresent) {
+			if isVpcIDPresent && (isVMIDPresent || isVMNamePresent || isVMTagsPresent) {
 				if _, found := vpcIDsWithVpcIDOnlyMatches[networkMatch.MatchID]; found {
 					continue
 				}
 				vpcIDWithOtherMatches = append(vpcIDWithOtherMatches, match)
 			}
 
+			// vm tags matches.
+			if isVMTagsPresent && !isVpcIDPresent {
+				vmTagMatches = append(vmTagMatches, v1alpha1.VirtualMachineSelector{
+					VpcMatch: networkMatch,
+					VMMatch:  []v1alpha1.EntityMatch{vmmatch},
+				})
+				continue
+			}
+
 			// vm id only matches.
 			if isVMIDPresent && !isVMNamePresent && !isVpcIDPresent {
 				vmIDOnlyMatches = append(vmIDOnlyMatches, vmmatch)
@@ -146,7 +162,7 @@ func buildEc2Filters(vmSelector []v1alpha1.VirtualMachineSelector) [][]*ec2.Filt
 	awsPluginLogger().Info("selector stats", "VpcIdOnlyMatch", len(vpcIDsWithVpcIDOnlyMatches),
 		"VpcIdWithOtherMatches", len(vpcIDWithOtherMatches), "VmIdOnlyMatches", len(vmIDOnlyMatches),
 		"VmIdAndVmNameMatches", len(vmIDAndVMNameMatches), "VmNameOnlyMatches", len(vmNameOnlyMatches),
-		"VpcNameOnlyMatches", len(vpcNameOnlyMatches))
+		"VpcNameOnlyMatches", len(vpcNameOnlyMatches), "VmTagMatches", len(vmTagMatches))
 
 	var allEc2Filters [][]*ec2.Filter
 
@@ -180,6 +196,11 @@ func buildEc2Filters(vmSelector []v1alpha1.VirtualMachineSelector) [][]*ec2.Filt
 	if vpcNameOnlyEc2Filter != nil {
 		allEc2Filters = append(allEc2Filters, vpcNameOnlyEc2Filter)
 	}
+
+	vmTagEc2Filter := buildAwsEc2FilterForVMTagMatches(vmTagMatches)
+	if vmTagEc2Filter != nil {
+		allEc2Filters = append(allEc2Filters, vmTagEc2Filter...)
+	}
 	return allEc2Filters
 }
 
@@ -245,6 +266,7 @@ func buildAwsEc2FilterForVpcIDWithOtherMatches(vpcIDWithOtherMatches []v1alpha1.
 				}
 				filters = append(filters, vmIDsFilter)
 			}
+			filters = append(filters, buildEc2FiltersForTags(vmMatch.MatchTags)...)
 			filters = append(filters, buildEc2FilterForValidInstanceStates())
 			allFilters = append(allFilters, filters)
 		}
@@ -356,19 +378,91 @@ func buildAwsEc2FilterForVPCNameOnlyMatches(vpcNameOnlyMatches []v1alpha1.Virtua
 	return filters
 }
 
+// buildAwsEc2FilterForVMTagMatches builds ec2 filters for vm matches with tags, each of which may be in a vpc
+// matching vpc name.
+func buildAwsEc2FilterForVMTagMatches(vmTagMatches []v1alpha1.VirtualMachineSelector) [][]*ec2.Filter {
+	if len(vmTagMatches) == 0 {
+		return nil
+	}
+
+	var allFilters [][]*ec2.Filter
+	for _, match := range vmTagMatches {
+		var filters []*ec2.Filter
+		// vpc name filter must be the first one, to be converted to vpc ID filter before calling aws api.
+		if match.VpcMatch != nil && len(strings.TrimSpace(match.VpcMatch.MatchName)) > 0 {
+			vpcNameFilter := &ec2.Filter{
+				Name:   aws.String(awsCustomFilterKeyVPCName),
+				Values: []*string{aws.String(match.VpcMatch.MatchName)},
+			}
+			filters = append(filters, vpcNameFilter)
+		}
+
+		vmMatch := match.VMMatch[0]
+		if len(strings.TrimSpace(vmMatch.MatchID)) > 0 {
+			vmIDFilter := &ec2.Filter{
+				Name:   aws.String(awsFilterKeyVMID),
+				Values: []*string{aws.String(vmMatch.MatchID)},
+			}
+			filters = append(filters, vmIDFilter)
+		}
+		if len(strings.TrimSpace(vmMatch.MatchName)) > 0 {
+			vmNameFilter := &ec2.Filter{
+				Name:   aws.String(awsFilterKeyVMName),
+				Values: []*string{aws.String(vmMatch.MatchName)},
+			}
+			filters = append(filters, vmNameFilter)
+		}
+		filters = append(filters, buildEc2FiltersForTags(vmMatch.MatchTags)...)
+		filters = append(filters, buildEc2FilterForValidInstanceStates())
+		allFilters = append(allFilters, filters)
+	}
+	return allFilters
+}
+
+// buildEc2FiltersForTags builds ec2 filters matching all tags. A tag with empty value matches any value of the tag key.
+func buildEc2FiltersForTags(tags map[string]string) []*ec2.Filter {
+	var keys []string
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var filters []*ec2.Filter
+	for _, key := range keys {
+		filter := &ec2.Filter{
+			Name:   aws.String(awsFilterKeyTagPrefix + key),
+			Values: []*string{aws.String(tags[key])},
+		}
+		if len(tags[key]) == 0 {
+			filter = &ec2.Filter{
+				Name:   aws.String(awsFilterKeyTagKey),
+				Values: []*string{aws.String(key)},
+			}
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+// buildFilterForVPCIDFromFilterForVPCName converts vpc name filter to vpc ID filter, other filters are kept as is.
 func buildFilterForVPCIDFromFilterForVPCName(filtersForVPCName []*ec2.Filter, vpcNameToID map[string]string) []*ec2.Filter {
 	if len(filtersForVPCName) == 0 {
 		return nil
 	}
 
 	var filters []*ec2.Filter
+	var otherFilters []*ec2.Filter
 	var vpcIDs []*string
 
 	for _, filter := range filtersForVPCName {
-		if *filter.Name != awsFilterKeyInstanceState {
+		switch *filter.Name {
+		case awsCustomFilterKeyVPCName:
 			for _, vpcName := range filter.Values {
 				vpcIDs = append(vpcIDs, aws.String(vpcNameToID[*vpcName]))
 			}
+		case awsFilterKeyInstanceState:
+		default:
+			otherFilters = append(otherFilters, filter)
 		}
 	}
 
@@ -380,6 +474,7 @@ func buildFilterForVPCIDFromFilterForVPCName(filtersForVPCName []*ec2.Filter, vp
 		Values: vpcIDs,
 	}
 	filters = append(filters, filter)
+	filters = append(filters, otherFilters...)
 	filters = append(filters, buildEc2FilterForValidInstanceStates())
 
 	return filters
@@ -425,3 +520,34 @@ func buildEc2FilterForValidInstanceStates() *ec2.Filter {
 
 	return instanceStateFilter
 }
+
+// buildEc2FiltersForInstanceIDs returns filters restricted to instances of instanceIDs, nil if filters match none of
+// instanceIDs.
+func buildEc2FiltersForInstanceIDs(filters []*ec2.Filter, instanceIDs []string) []*ec2.Filter {
+	instanceIDFilter := &ec2.Filter{
+		Name:   aws.String(awsFilterKeyVMID),
+		Values: aws.StringSlice(instanceIDs),
+	}
+
+	restrictedFilters := make([]*ec2.Filter, 0, len(filters)+1)
+	for _, filter := range filters {
+		if aws.StringValue(filter.Name) != awsFilterKeyVMID {
+			restrictedFilters = append(restrictedFilters, filter)
+			continue
+		}
+		// instances are already filtered by ids, restrict to ids in both.
+		var values []*string
+		for _, value := range filter.Values {
+			for _, instanceID := range instanceIDs {
+				if strings.EqualFold(aws.StringValue(value), instanceID) {
+					values = append(values, value)
+				}
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		instanceIDFilter.Values = values
+	}
+	return append(restrictedFilters, instanceIDFilter)
+}
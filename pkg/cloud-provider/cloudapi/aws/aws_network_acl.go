@@ -0,0 +1,838 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"go.uber.org/multierr"
+
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+)
+
+// errUnsupportedDenyRule is returned when deny rules can not be realized faithfully by network ACL entries.
+var errUnsupportedDenyRule = errors.New("deny rule not supported by network acl")
+
+// AWS security groups can only allow traffic, hence deny rules are realized as network ACL entries on the
+// subnets of appliedTo network interfaces. Network ACL entries have no description, so the rule numbers of
+// entries owned by an appliedTo security group are kept in a network ACL tag keyed by the security group name.
+// Only deny entries within nephe controller rule numbers are considered owned, entries created by users at
+// those rule numbers are left untouched and their rule numbers are skipped.
+// Network ACL entries are stateless, evaluated ahead of security group rules, and do not filter traffic within a
+// subnet. Deny rules network ACL entries can not realize faithfully are rejected, leaving network ACLs unmodified.
+const (
+	// network ACL entries are evaluated in ascending rule number order, nephe controller uses rule numbers
+	// ahead of commonly used user rule numbers.
+	awsNetworkACLRuleNumberMin = 1
+	awsNetworkACLRuleNumberMax = 99
+	// awsNetworkACLDefaultRuleNumber is the rule number of the default IPv4 entries of a network ACL, default IPv6
+	// entries follow it.
+	awsNetworkACLDefaultRuleNumber = 32767
+
+	awsNetworkACLTagIngressPrefix = "i"
+	awsNetworkACLTagEgressPrefix  = "e"
+	awsNetworkACLTagSeparator     = ","
+
+	awsAnyIPv4Address = "0.0.0.0/0"
+	awsAnyIPv6Address = "::/0"
+)
+
+// splitIngressRulesByAction returns allow and deny ingress rules.
+func splitIngressRulesByAction(rules []*securitygroup.IngressRule) ([]*securitygroup.IngressRule, []*securitygroup.IngressRule) {
+	var allowRules, denyRules []*securitygroup.IngressRule
+	for _, rule := range rules {
+		if rule == nil {
+			continue
+		}
+		if rule.Action.IsDeny() {
+			denyRules = append(denyRules, rule)
+		} else {
+			allowRules = append(allowRules, rule)
+		}
+	}
+	return allowRules, denyRules
+}
+
+// splitEgressRulesByAction returns allow and deny egress rules.
+func splitEgressRulesByAction(rules []*securitygroup.EgressRule) ([]*securitygroup.EgressRule, []*securitygroup.EgressRule) {
+	var allowRules, denyRules []*securitygroup.EgressRule
+	for _, rule := range rules {
+		if rule == nil {
+			continue
+		}
+		if rule.Action.IsDeny() {
+			denyRules = append(denyRules, rule)
+		} else {
+			allowRules = append(allowRules, rule)
+		}
+	}
+	return allowRules, denyRules
+}
+
+// resolveCloudServiceIPs returns rules with cloud services replaced by ips of their prefix lists. A rule left without
+// peers is dropped, so that it does not match any peer.
+func (ec2Cfg *ec2ServiceConfig) resolveCloudServiceIPs(ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule,
+	cloudServicePrefixLists map[string]*ec2.ManagedPrefixList) ([]*securitygroup.IngressRule, []*securitygroup.EgressRule, error) {
+	cloudServiceIPs := make(map[string][]*net.IPNet)
+	getIPs := func(cloudServices []string) ([]*net.IPNet, error) {
+		var ips []*net.IPNet
+		for _, cloudService := range cloudServices {
+			if _, found := cloudServiceIPs[cloudService]; !found {
+				prefixList, found := cloudServicePrefixLists[cloudService]
+				if !found {
+					continue
+				}
+				prefixListIPs, err := ec2Cfg.getPrefixListIPs(prefixList.PrefixListId)
+				if err != nil {
+					return nil, err
+				}
+				cloudServiceIPs[cloudService] = prefixListIPs
+			}
+			ips = append(ips, cloudServiceIPs[cloudService]...)
+		}
+		return ips, nil
+	}
+
+	resolvedIngressRules := make([]*securitygroup.IngressRule, 0, len(ingressRules))
+	for _, rule := range ingressRules {
+		if len(rule.FromCloudServices) == 0 {
+			resolvedIngressRules = append(resolvedIngressRules, rule)
+			continue
+		}
+		ips, err := getIPs(rule.FromCloudServices)
+		if err != nil {
+			return nil, nil, err
+		}
+		resolvedRule := *rule
+		resolvedRule.FromSrcIP = append(append([]*net.IPNet{}, rule.FromSrcIP...), ips...)
+		resolvedRule.FromCloudServices = nil
+		if len(resolvedRule.FromSrcIP) > 0 || len(resolvedRule.FromSecurityGroups) > 0 {
+			resolvedIngressRules = append(resolvedIngressRules, &resolvedRule)
+		}
+	}
+	resolvedEgressRules := make([]*securitygroup.EgressRule, 0, len(egressRules))
+	for _, rule := range egressRules {
+		if len(rule.ToCloudServices) == 0 {
+			resolvedEgressRules = append(resolvedEgressRules, rule)
+			continue
+		}
+		ips, err := getIPs(rule.ToCloudServices)
+		if err != nil {
+			return nil, nil, err
+		}
+		resolvedRule := *rule
+		resolvedRule.ToDstIP = append(append([]*net.IPNet{}, rule.ToDstIP...), ips...)
+		resolvedRule.ToCloudServices = nil
+		if len(resolvedRule.ToDstIP) > 0 || len(resolvedRule.ToSecurityGroups) > 0 {
+			resolvedEgressRules = append(resolvedEgressRules, &resolvedRule)
+		}
+	}
+	return resolvedIngressRules, resolvedEgressRules, nil
+}
+
+// buildNetworkACLDenyEntries builds network ACL entries from deny rules. Security groups referred by rules are
+// resolved to IPs using cloudSgNameToIPs.
+func buildNetworkACLDenyEntries(ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule,
+	cloudSgNameToIPs map[string][]*net.IPNet) []*ec2.NetworkAclEntry {
+	var entries []*ec2.NetworkAclEntry
+	existing := make(map[string]struct{})
+	addEntries := func(egress bool, port *int, endPort *int, protocol *int, icmpType *int, icmpCode *int, ips []*net.IPNet,
+		groups []*securitygroup.CloudResourceID) {
+		var cidrs []string
+		for _, ip := range ips {
+			cidrs = append(cidrs, ip.String())
+		}
+		for _, group := range groups {
+			for _, ip := range cloudSgNameToIPs[group.GetCloudName(true)] {
+				cidrs = append(cidrs, ip.String())
+			}
+		}
+		if len(ips) == 0 && len(groups) == 0 {
+			cidrs = append(cidrs, awsAnyIPv4Address, awsAnyIPv6Address)
+		}
+		for _, cidr := range cidrs {
+			entry := buildNetworkACLDenyEntry(egress, port, endPort, protocol, icmpType, icmpCode, cidr)
+			key := getNetworkACLEntryKey(entry)
+			if _, found := existing[key]; found {
+				continue
+			}
+			existing[key] = struct{}{}
+			entries = append(entries, entry)
+		}
+	}
+	for _, rule := range ingressRules {
+		addEntries(false, rule.FromPort, rule.FromEndPort, rule.Protocol, rule.ICMPType, rule.ICMPCode, rule.FromSrcIP, rule.FromSecurityGroups)
+	}
+	for _, rule := range egressRules {
+		addEntries(true, rule.ToPort, rule.ToEndPort, rule.Protocol, rule.ICMPType, rule.ICMPCode, rule.ToDstIP, rule.ToSecurityGroups)
+	}
+	return entries
+}
+
+// buildNetworkACLDenyEntry builds a network ACL entry denying cidr. ICMP entries of IPv6 cidr use ICMPv6 protocol.
+func buildNetworkACLDenyEntry(egress bool, port *int, endPort *int, protocol *int, icmpType *int, icmpCode *int,
+	cidr string) *ec2.NetworkAclEntry {
+	entry := &ec2.NetworkAclEntry{
+		Egress:     aws.Bool(egress),
+		RuleAction: aws.String(ec2.RuleActionDeny),
+	}
+	if ip, _, err := net.ParseCIDR(cidr); err == nil && ip.To4() == nil {
+		protocol = securitygroup.GetIPv6Protocol(protocol)
+		entry.Ipv6CidrBlock = aws.String(cidr)
+	} else {
+		entry.CidrBlock = aws.String(cidr)
+	}
+	entry.Protocol = convertToIPPermissionProtocol(protocol)
+	if protocol == nil {
+		return entry
+	}
+	switch *protocol {
+	case 6, 17:
+		fromPort, toPort := convertToIPPermissionPort(port, endPort, protocol)
+		entry.PortRange = &ec2.PortRange{From: fromPort, To: toPort}
+	case 1, 58:
+		entry.IcmpTypeCode = &ec2.IcmpTypeCode{Type: aws.Int64(-1), Code: aws.Int64(-1)}
+		if icmpType != nil {
+			entry.IcmpTypeCode.Type = aws.Int64(int64(*icmpType))
+		}
+		if icmpCode != nil {
+			entry.IcmpTypeCode.Code = aws.Int64(int64(*icmpCode))
+		}
+	}
+	return entry
+}
+
+// getNetworkACLEntryKey returns a key identifying network ACL entry content, regardless of its rule number.
+func getNetworkACLEntryKey(entry *ec2.NetworkAclEntry) string {
+	var fromPort, toPort int64
+	if entry.PortRange != nil {
+		fromPort, toPort = aws.Int64Value(entry.PortRange.From), aws.Int64Value(entry.PortRange.To)
+	}
+	if entry.IcmpTypeCode != nil {
+		fromPort, toPort = aws.Int64Value(entry.IcmpTypeCode.Type), aws.Int64Value(entry.IcmpTypeCode.Code)
+	}
+	return fmt.Sprintf("%v/%v/%v-%v/%v", aws.BoolValue(entry.Egress), strings.ToLower(aws.StringValue(entry.Protocol)),
+		fromPort, toPort, getNetworkACLEntryCIDR(entry))
+}
+
+// getNetworkACLEntryCIDR returns IPv4 or IPv6 cidr of network ACL entry.
+func getNetworkACLEntryCIDR(entry *ec2.NetworkAclEntry) string {
+	if entry.Ipv6CidrBlock != nil {
+		return aws.StringValue(entry.Ipv6CidrBlock)
+	}
+	return aws.StringValue(entry.CidrBlock)
+}
+
+// isNetworkACLEntryOverlapping returns true if traffic in the same direction matches both network ACL entries.
+func isNetworkACLEntryOverlapping(entry1, entry2 *ec2.NetworkAclEntry) bool {
+	if aws.BoolValue(entry1.Egress) != aws.BoolValue(entry2.Egress) {
+		return false
+	}
+	_, ipNet1, err1 := net.ParseCIDR(getNetworkACLEntryCIDR(entry1))
+	_, ipNet2, err2 := net.ParseCIDR(getNetworkACLEntryCIDR(entry2))
+	if err1 != nil || err2 != nil || len(ipNet1.IP) != len(ipNet2.IP) ||
+		!(ipNet1.Contains(ipNet2.IP) || ipNet2.Contains(ipNet1.IP)) {
+		return false
+	}
+	protocol1 := convertFromNetworkACLProtocol(aws.StringValue(entry1.Protocol))
+	protocol2 := convertFromNetworkACLProtocol(aws.StringValue(entry2.Protocol))
+	if protocol1 == nil || protocol2 == nil {
+		return true
+	}
+	if *protocol1 != *protocol2 {
+		return false
+	}
+	if entry1.PortRange != nil && entry2.PortRange != nil {
+		return aws.Int64Value(entry1.PortRange.From) <= aws.Int64Value(entry2.PortRange.To) &&
+			aws.Int64Value(entry2.PortRange.From) <= aws.Int64Value(entry1.PortRange.To)
+	}
+	if entry1.IcmpTypeCode != nil && entry2.IcmpTypeCode != nil {
+		isMatching := func(v1, v2 *int64) bool {
+			return aws.Int64Value(v1) == -1 || aws.Int64Value(v2) == -1 || aws.Int64Value(v1) == aws.Int64Value(v2)
+		}
+		return isMatching(entry1.IcmpTypeCode.Type, entry2.IcmpTypeCode.Type) &&
+			isMatching(entry1.IcmpTypeCode.Code, entry2.IcmpTypeCode.Code)
+	}
+	return true
+}
+
+// getNetworkACLRules returns deny rules, and allow rules taking precedence over any deny rule of the same direction.
+// Deny rules are checked against allow rules taking precedence over them, hence rules are kept with priorities.
+func getNetworkACLRules(ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule) (
+	[]*securitygroup.IngressRule, []*securitygroup.EgressRule) {
+	allowIngressRules, denyIngressRules := splitIngressRulesByAction(ingressRules)
+	allowEgressRules, denyEgressRules := splitEgressRulesByAction(egressRules)
+	networkACLIngressRules := append([]*securitygroup.IngressRule{}, denyIngressRules...)
+	for _, rule := range allowIngressRules {
+		for _, denyRule := range denyIngressRules {
+			if rule.Priority.Less(denyRule.Priority) {
+				networkACLIngressRules = append(networkACLIngressRules, rule)
+				break
+			}
+		}
+	}
+	networkACLEgressRules := append([]*securitygroup.EgressRule{}, denyEgressRules...)
+	for _, rule := range allowEgressRules {
+		for _, denyRule := range denyEgressRules {
+			if rule.Priority.Less(denyRule.Priority) {
+				networkACLEgressRules = append(networkACLEgressRules, rule)
+				break
+			}
+		}
+	}
+	return networkACLIngressRules, networkACLEgressRules
+}
+
+// checkNetworkACLDenyRules returns an error wrapping errUnsupportedDenyRule if deny rules of ingressRules and
+// egressRules can not be realized faithfully by network ACL entries, that is if
+// - an egress deny rule has no port, or ICMP type, so that it drops replies to inbound connections allowed by other rules.
+// - a deny rule overlaps an allow rule taking precedence over it.
+// - a deny rule peer includes subnetPeerIPs, ips of network interfaces sharing a subnet with appliedTo members.
+// Security groups referred by rules are resolved to IPs using cloudSgNameToIPs.
+func checkNetworkACLDenyRules(ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule,
+	cloudSgNameToIPs map[string][]*net.IPNet, subnetPeerIPs []*net.IPNet) error {
+	allowIngressRules, denyIngressRules := splitIngressRulesByAction(ingressRules)
+	allowEgressRules, denyEgressRules := splitEgressRulesByAction(egressRules)
+	for _, rule := range denyIngressRules {
+		var precedingRules []*securitygroup.IngressRule
+		for _, allowRule := range allowIngressRules {
+			if allowRule.Priority.Less(rule.Priority) {
+				precedingRules = append(precedingRules, allowRule)
+			}
+		}
+		if err := checkNetworkACLDenyEntries(buildNetworkACLDenyEntries([]*securitygroup.IngressRule{rule}, nil, cloudSgNameToIPs),
+			buildNetworkACLDenyEntries(precedingRules, nil, cloudSgNameToIPs), subnetPeerIPs); err != nil {
+			return err
+		}
+	}
+	for _, rule := range denyEgressRules {
+		entries := buildNetworkACLDenyEntries(nil, []*securitygroup.EgressRule{rule}, cloudSgNameToIPs)
+		isRestricted := rule.ToPort != nil && rule.Protocol != nil &&
+			(*rule.Protocol == securitygroup.ProtocolNameNumMap["tcp"] || *rule.Protocol == securitygroup.ProtocolNameNumMap["udp"])
+		isRestricted = isRestricted || (securitygroup.IsICMPProtocol(rule.Protocol) && rule.ICMPType != nil)
+		if !isRestricted {
+			return fmt.Errorf("%w: egress deny rule without port or icmp type drops replies to allowed inbound "+
+				"connections, network acl entries are stateless", errUnsupportedDenyRule)
+		}
+		var precedingRules []*securitygroup.EgressRule
+		for _, allowRule := range allowEgressRules {
+			if allowRule.Priority.Less(rule.Priority) {
+				precedingRules = append(precedingRules, allowRule)
+			}
+		}
+		if err := checkNetworkACLDenyEntries(entries, buildNetworkACLDenyEntries(nil, precedingRules, cloudSgNameToIPs),
+			subnetPeerIPs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNetworkACLDenyEntries returns an error wrapping errUnsupportedDenyRule if entries of a deny rule overlap
+// precedingEntries of allow rules taking precedence over it, or include subnetPeerIPs.
+func checkNetworkACLDenyEntries(entries []*ec2.NetworkAclEntry, precedingEntries []*ec2.NetworkAclEntry,
+	subnetPeerIPs []*net.IPNet) error {
+	for _, entry := range entries {
+		cidr := getNetworkACLEntryCIDR(entry)
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			for _, ip := range subnetPeerIPs {
+				if ipNet.Contains(ip.IP) {
+					return fmt.Errorf("%w: deny rule peer %v includes %v in a subnet of appliedTo members, network acls "+
+						"do not filter traffic within a subnet", errUnsupportedDenyRule, cidr, ip.IP)
+				}
+			}
+		}
+		for _, precedingEntry := range precedingEntries {
+			if isNetworkACLEntryOverlapping(entry, precedingEntry) {
+				return fmt.Errorf("%w: deny rule peer %v overlaps peer %v of an allow rule of higher priority, network "+
+					"acl entries are evaluated ahead of security group rules", errUnsupportedDenyRule, cidr,
+					getNetworkACLEntryCIDR(precedingEntry))
+			}
+		}
+	}
+	return nil
+}
+
+func getNetworkACLTagToken(egress bool, ruleNumber int64) string {
+	if egress {
+		return awsNetworkACLTagEgressPrefix + strconv.FormatInt(ruleNumber, 10)
+	}
+	return awsNetworkACLTagIngressPrefix + strconv.FormatInt(ruleNumber, 10)
+}
+
+// getNetworkACLOwnedEntries returns network ACL entries owned by cloudSgName.
+func getNetworkACLOwnedEntries(networkACL *ec2.NetworkAcl, cloudSgName string) []*ec2.NetworkAclEntry {
+	tokens := make(map[string]struct{})
+	for _, tag := range networkACL.Tags {
+		if strings.Compare(strings.ToLower(aws.StringValue(tag.Key)), cloudSgName) != 0 {
+			continue
+		}
+		for _, token := range strings.Split(aws.StringValue(tag.Value), awsNetworkACLTagSeparator) {
+			tokens[token] = struct{}{}
+		}
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	var entries []*ec2.NetworkAclEntry
+	for _, entry := range networkACL.Entries {
+		if !isNetworkACLDenyEntryRuleNumber(entry) {
+			continue
+		}
+		if _, found := tokens[getNetworkACLTagToken(aws.BoolValue(entry.Egress), aws.Int64Value(entry.RuleNumber))]; found {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// isNetworkACLDenyEntryRuleNumber returns true if entry is a deny entry at a nephe controller rule number.
+func isNetworkACLDenyEntryRuleNumber(entry *ec2.NetworkAclEntry) bool {
+	ruleNumber := aws.Int64Value(entry.RuleNumber)
+	return ruleNumber >= awsNetworkACLRuleNumberMin && ruleNumber <= awsNetworkACLRuleNumberMax &&
+		strings.Compare(aws.StringValue(entry.RuleAction), ec2.RuleActionDeny) == 0
+}
+
+// checkNetworkACLEntryQuota returns an error wrapping securitygroup.ErrQuotaExceeded if network ACL entries,
+// after creating entriesToCreate and deleting staleEntries, exceed quota, the entries per network ACL quota. The quota
+// applies separately to inbound and outbound entries, and to IPv4 and IPv6 entries.
+func checkNetworkACLEntryQuota(networkACL *ec2.NetworkAcl, entriesToCreate []*ec2.NetworkAclEntry,
+	staleEntries map[string]*ec2.NetworkAclEntry, quota int) error {
+	type countKey struct {
+		egress bool
+		ipv6   bool
+	}
+	getCountKey := func(entry *ec2.NetworkAclEntry) countKey {
+		return countKey{egress: aws.BoolValue(entry.Egress), ipv6: entry.Ipv6CidrBlock != nil}
+	}
+	counts := make(map[countKey]int)
+	for _, entry := range networkACL.Entries {
+		if aws.Int64Value(entry.RuleNumber) < awsNetworkACLDefaultRuleNumber {
+			counts[getCountKey(entry)]++
+		}
+	}
+	for _, entry := range staleEntries {
+		counts[getCountKey(entry)]--
+	}
+	for _, entry := range entriesToCreate {
+		counts[getCountKey(entry)]++
+	}
+	for _, key := range []countKey{{false, false}, {false, true}, {true, false}, {true, true}} {
+		if counts[key] <= quota {
+			continue
+		}
+		direction, addressFamily := awsPrefixListDirectionIngress, awsPrefixListAddressFamilyIPv4
+		if key.egress {
+			direction = awsPrefixListDirectionEgress
+		}
+		if key.ipv6 {
+			addressFamily = awsPrefixListAddressFamilyIPv6
+		}
+		return fmt.Errorf("%w: network acl %v requires %v %v %v entries, entries per network acl quota is %v",
+			securitygroup.ErrQuotaExceeded, aws.StringValue(networkACL.NetworkAclId), counts[key], addressFamily,
+			direction, quota)
+	}
+	return nil
+}
+
+// getNetworkACLOwnerSgNames returns nephe controller appliedTo security group names owning entries in network ACL.
+func getNetworkACLOwnerSgNames(networkACL *ec2.NetworkAcl) []string {
+	var cloudSgNames []string
+	for _, tag := range networkACL.Tags {
+		cloudSgName := strings.ToLower(aws.StringValue(tag.Key))
+		if _, _, isAT := securitygroup.IsNepheControllerCreatedSG(cloudSgName); isAT {
+			cloudSgNames = append(cloudSgNames, cloudSgName)
+		}
+	}
+	return cloudSgNames
+}
+
+// getNetworkACLFreeRuleNumbers returns rule numbers reserved for nephe controller and not used by network ACL.
+func getNetworkACLFreeRuleNumbers(networkACL *ec2.NetworkAcl, egress bool) []int64 {
+	used := make(map[int64]struct{})
+	for _, entry := range networkACL.Entries {
+		if aws.BoolValue(entry.Egress) == egress {
+			used[aws.Int64Value(entry.RuleNumber)] = struct{}{}
+		}
+	}
+	var ruleNumbers []int64
+	for ruleNumber := int64(awsNetworkACLRuleNumberMin); ruleNumber <= awsNetworkACLRuleNumberMax; ruleNumber++ {
+		if _, found := used[ruleNumber]; !found {
+			ruleNumbers = append(ruleNumbers, ruleNumber)
+		}
+	}
+	return ruleNumbers
+}
+
+func (ec2Cfg *ec2ServiceConfig) getNetworkACLsOfVpc(vpcIDs map[string]struct{}) ([]*ec2.NetworkAcl, error) {
+	filters := buildAwsEc2FilterForVpcIDOnlyMatches(vpcIDs)
+	input := &ec2.DescribeNetworkAclsInput{
+		Filters: filters,
+	}
+	return ec2Cfg.apiClient.pagedDescribeNetworkACLsWrapper(input)
+}
+
+// getNetworkInterfaceIPs returns private IPs of network interface.
+func getNetworkInterfaceIPs(networkInterface *ec2.NetworkInterface) []*net.IPNet {
+	var ips []*net.IPNet
+	for _, privateIP := range networkInterface.PrivateIpAddresses {
+		if ip := net.ParseIP(aws.StringValue(privateIP.PrivateIpAddress)); ip != nil {
+			ips = append(ips, &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)})
+		}
+	}
+	for _, ipv6Address := range networkInterface.Ipv6Addresses {
+		if ip := net.ParseIP(aws.StringValue(ipv6Address.Ipv6Address)); ip != nil {
+			ips = append(ips, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+		}
+	}
+	return ips
+}
+
+// getSecurityGroupMemberIPs returns private IPs of network interfaces attached to each of cloudSgNames.
+func getSecurityGroupMemberIPs(networkInterfaces []*ec2.NetworkInterface, cloudSgNames map[string]struct{}) map[string][]*net.IPNet {
+	cloudSgNameToIPs := make(map[string][]*net.IPNet)
+	for _, networkInterface := range networkInterfaces {
+		ips := getNetworkInterfaceIPs(networkInterface)
+		for _, group := range networkInterface.Groups {
+			cloudSgName := strings.ToLower(aws.StringValue(group.GroupName))
+			if _, found := cloudSgNames[cloudSgName]; found {
+				cloudSgNameToIPs[cloudSgName] = append(cloudSgNameToIPs[cloudSgName], ips...)
+			}
+		}
+	}
+	return cloudSgNameToIPs
+}
+
+// getAppliedToSubnets returns subnets of network interfaces in vpcID attached to appliedTo security group
+// groupCloudSgName, and private IPs of other network interfaces in those subnets, whose traffic with members is not
+// filtered by network ACLs.
+func getAppliedToSubnets(networkInterfaces []*ec2.NetworkInterface, vpcID string, groupCloudSgName string) (
+	map[string]struct{}, []*net.IPNet) {
+	memberCounts := make(map[string]int)
+	isMember := make(map[*ec2.NetworkInterface]bool)
+	for _, networkInterface := range networkInterfaces {
+		if aws.StringValue(networkInterface.VpcId) != vpcID {
+			continue
+		}
+		for _, group := range networkInterface.Groups {
+			if strings.Compare(strings.ToLower(aws.StringValue(group.GroupName)), groupCloudSgName) == 0 {
+				memberCounts[aws.StringValue(networkInterface.SubnetId)]++
+				isMember[networkInterface] = true
+				break
+			}
+		}
+	}
+
+	memberSubnetIDs := make(map[string]struct{})
+	var subnetPeerIPs []*net.IPNet
+	for _, networkInterface := range networkInterfaces {
+		subnetID := aws.StringValue(networkInterface.SubnetId)
+		count, found := memberCounts[subnetID]
+		if !found || aws.StringValue(networkInterface.VpcId) != vpcID {
+			continue
+		}
+		memberSubnetIDs[subnetID] = struct{}{}
+		// a member is a peer of other members in its subnet.
+		if !isMember[networkInterface] || count > 1 {
+			subnetPeerIPs = append(subnetPeerIPs, getNetworkInterfaceIPs(networkInterface)...)
+		}
+	}
+	return memberSubnetIDs, subnetPeerIPs
+}
+
+// realizeNetworkACLDenyEntries realizes deny rules of appliedTo security group groupCloudSgName as network ACL entries
+// on subnets of network interfaces attached to the security group, and removes its entries from other network ACLs.
+// ingressRules and egressRules are deny rules, and allow rules taking precedence over them, with cloud services resolved
+// to IPs. Network ACLs are left unmodified if deny rules can not be realized faithfully.
+// If pruneOnly is true, only entries on network ACLs of subnets without group members are removed.
+func (ec2Cfg *ec2ServiceConfig) realizeNetworkACLDenyEntries(groupCloudSgName string, vpcID string,
+	ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule, pruneOnly bool) error {
+	vpcIDs := map[string]struct{}{vpcID: {}}
+	for _, peerID := range ec2Cfg.getVpcPeers(vpcID) {
+		vpcIDs[peerID] = struct{}{}
+	}
+	networkInterfaces, err := ec2Cfg.getNetworkInterfacesOfVpc(vpcIDs)
+	if err != nil {
+		return err
+	}
+	memberSubnetIDs, subnetPeerIPs := getAppliedToSubnets(networkInterfaces, vpcID, groupCloudSgName)
+
+	var entries []*ec2.NetworkAclEntry
+	if !pruneOnly {
+		cloudSgNames := make(map[string]struct{})
+		for _, rule := range ingressRules {
+			for _, group := range rule.FromSecurityGroups {
+				cloudSgNames[group.GetCloudName(true)] = struct{}{}
+			}
+		}
+		for _, rule := range egressRules {
+			for _, group := range rule.ToSecurityGroups {
+				cloudSgNames[group.GetCloudName(true)] = struct{}{}
+			}
+		}
+		cloudSgNameToIPs := getSecurityGroupMemberIPs(networkInterfaces, cloudSgNames)
+		if err := checkNetworkACLDenyRules(ingressRules, egressRules, cloudSgNameToIPs, subnetPeerIPs); err != nil {
+			return err
+		}
+		// network acl entries of deny rules are not ordered, priorities of deny rules are cleared.
+		_, denyIngressRules := splitIngressRulesByAction(ingressRules)
+		_, denyEgressRules := splitEgressRulesByAction(egressRules)
+		entries = buildNetworkACLDenyEntries(securitygroup.CompactIngressRulesIgnoringPriority(denyIngressRules),
+			securitygroup.CompactEgressRulesIgnoringPriority(denyEgressRules), cloudSgNameToIPs)
+	}
+
+	networkACLs, err := ec2Cfg.getNetworkACLsOfVpc(map[string]struct{}{vpcID: {}})
+	if err != nil {
+		return err
+	}
+	for _, networkACL := range networkACLs {
+		isMemberNetworkACL := false
+		for _, association := range networkACL.Associations {
+			if _, found := memberSubnetIDs[aws.StringValue(association.SubnetId)]; found {
+				isMemberNetworkACL = true
+				break
+			}
+		}
+		if isMemberNetworkACL && pruneOnly {
+			continue
+		}
+		var networkACLEntries []*ec2.NetworkAclEntry
+		if isMemberNetworkACL {
+			networkACLEntries = entries
+		}
+		if e := ec2Cfg.updateNetworkACLDenyEntries(networkACL, groupCloudSgName, networkACLEntries); e != nil {
+			err = multierr.Append(err, e)
+		}
+	}
+	return err
+}
+
+// updateNetworkACLDenyEntries updates entries owned by groupCloudSgName in networkACL to entries.
+func (ec2Cfg *ec2ServiceConfig) updateNetworkACLDenyEntries(networkACL *ec2.NetworkAcl, groupCloudSgName string,
+	entries []*ec2.NetworkAclEntry) error {
+	ownedEntries := getNetworkACLOwnedEntries(networkACL, groupCloudSgName)
+	if len(ownedEntries) == 0 && len(entries) == 0 {
+		return nil
+	}
+
+	// keep owned entries with same content, create missing entries and delete stale entries.
+	staleEntries := make(map[string]*ec2.NetworkAclEntry)
+	for _, entry := range ownedEntries {
+		staleEntries[getNetworkACLEntryKey(entry)] = entry
+	}
+	var keptEntries, entriesToCreate []*ec2.NetworkAclEntry
+	for _, entry := range entries {
+		key := getNetworkACLEntryKey(entry)
+		if ownedEntry, found := staleEntries[key]; found {
+			keptEntries = append(keptEntries, ownedEntry)
+			delete(staleEntries, key)
+			continue
+		}
+		entriesToCreate = append(entriesToCreate, entry)
+	}
+	if len(entriesToCreate) == 0 && len(staleEntries) == 0 {
+		return nil
+	}
+	if err := checkNetworkACLEntryQuota(networkACL, entriesToCreate, staleEntries,
+		ec2Cfg.getEntriesPerNetworkACLQuota()); err != nil {
+		return err
+	}
+
+	freeRuleNumbers := map[bool][]int64{
+		false: getNetworkACLFreeRuleNumbers(networkACL, false),
+		true:  getNetworkACLFreeRuleNumbers(networkACL, true),
+	}
+	for _, entry := range entriesToCreate {
+		egress := aws.BoolValue(entry.Egress)
+		if len(freeRuleNumbers[egress]) == 0 {
+			return fmt.Errorf("no free rule number in network acl %v for deny rules of %v",
+				aws.StringValue(networkACL.NetworkAclId), groupCloudSgName)
+		}
+		entry.RuleNumber = aws.Int64(freeRuleNumbers[egress][0])
+		freeRuleNumbers[egress] = freeRuleNumbers[egress][1:]
+	}
+
+	// user allow entries at lower rule numbers are evaluated ahead of deny entries, and would override them.
+	for _, entry := range append(append([]*ec2.NetworkAclEntry{}, keptEntries...), entriesToCreate...) {
+		for _, userEntry := range networkACL.Entries {
+			if strings.Compare(aws.StringValue(userEntry.RuleAction), ec2.RuleActionAllow) != 0 ||
+				aws.Int64Value(userEntry.RuleNumber) >= aws.Int64Value(entry.RuleNumber) ||
+				!isNetworkACLEntryOverlapping(entry, userEntry) {
+				continue
+			}
+			return fmt.Errorf("%w: allow entry %v of network acl %v overrides deny rule peer %v of %v",
+				errUnsupportedDenyRule, aws.Int64Value(userEntry.RuleNumber), aws.StringValue(networkACL.NetworkAclId),
+				getNetworkACLEntryCIDR(entry), groupCloudSgName)
+		}
+	}
+
+	// claim ownership of new entries before creating them, so that no entry is leaked on failure.
+	var staleEntryList []*ec2.NetworkAclEntry
+	for _, entry := range staleEntries {
+		staleEntryList = append(staleEntryList, entry)
+	}
+	ownedEntryList := append(append([]*ec2.NetworkAclEntry{}, keptEntries...), entriesToCreate...)
+	if len(entriesToCreate) > 0 {
+		if err := ec2Cfg.updateNetworkACLOwnerTag(networkACL, groupCloudSgName,
+			append(append([]*ec2.NetworkAclEntry{}, ownedEntryList...), staleEntryList...)); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entriesToCreate {
+		input := &ec2.CreateNetworkAclEntryInput{
+			NetworkAclId:  networkACL.NetworkAclId,
+			RuleNumber:    entry.RuleNumber,
+			Egress:        entry.Egress,
+			Protocol:      entry.Protocol,
+			RuleAction:    entry.RuleAction,
+			CidrBlock:     entry.CidrBlock,
+			Ipv6CidrBlock: entry.Ipv6CidrBlock,
+			PortRange:     entry.PortRange,
+			IcmpTypeCode:  entry.IcmpTypeCode,
+		}
+		if _, err := ec2Cfg.apiClient.createNetworkACLEntry(input); err != nil {
+			return err
+		}
+	}
+	for _, entry := range staleEntryList {
+		input := &ec2.DeleteNetworkAclEntryInput{
+			NetworkAclId: networkACL.NetworkAclId,
+			RuleNumber:   entry.RuleNumber,
+			Egress:       entry.Egress,
+		}
+		if _, err := ec2Cfg.apiClient.deleteNetworkACLEntry(input); err != nil {
+			return err
+		}
+	}
+	return ec2Cfg.updateNetworkACLOwnerTag(networkACL, groupCloudSgName, ownedEntryList)
+}
+
+// updateNetworkACLOwnerTag records rule numbers of entries owned by groupCloudSgName in network ACL tag.
+func (ec2Cfg *ec2ServiceConfig) updateNetworkACLOwnerTag(networkACL *ec2.NetworkAcl, groupCloudSgName string,
+	entries []*ec2.NetworkAclEntry) error {
+	if len(entries) == 0 {
+		input := &ec2.DeleteTagsInput{
+			Resources: []*string{networkACL.NetworkAclId},
+			Tags:      []*ec2.Tag{{Key: aws.String(groupCloudSgName)}},
+		}
+		_, err := ec2Cfg.apiClient.deleteTags(input)
+		return err
+	}
+	var tokens []string
+	for _, entry := range entries {
+		tokens = append(tokens, getNetworkACLTagToken(aws.BoolValue(entry.Egress), aws.Int64Value(entry.RuleNumber)))
+	}
+	sort.Strings(tokens)
+	input := &ec2.CreateTagsInput{
+		Resources: []*string{networkACL.NetworkAclId},
+		Tags:      []*ec2.Tag{{Key: aws.String(groupCloudSgName), Value: aws.String(strings.Join(tokens, awsNetworkACLTagSeparator))}},
+	}
+	_, err := ec2Cfg.apiClient.createTags(input)
+	return err
+}
+
+// getNetworkACLDenyRulesCloudView returns deny rules realized as network ACL entries by appliedTo security group.
+// Entries with same protocol and port are merged into one rule.
+func (ec2Cfg *ec2ServiceConfig) getNetworkACLDenyRulesCloudView(vpcIDs map[string]struct{}) (
+	map[securitygroup.CloudResourceID][]securitygroup.IngressRule, map[securitygroup.CloudResourceID][]securitygroup.EgressRule, error) {
+	networkACLs, err := ec2Cfg.getNetworkACLsOfVpc(vpcIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ingressRules := make(map[securitygroup.CloudResourceID][]securitygroup.IngressRule)
+	egressRules := make(map[securitygroup.CloudResourceID][]securitygroup.EgressRule)
+	ingressRuleIdx := make(map[string]int)
+	egressRuleIdx := make(map[string]int)
+	existing := make(map[string]struct{})
+	for _, networkACL := range networkACLs {
+		for _, cloudSgName := range getNetworkACLOwnerSgNames(networkACL) {
+			sgName, _, _ := securitygroup.IsNepheControllerCreatedSG(cloudSgName)
+			id := securitygroup.CloudResourceID{Name: sgName, Vpc: aws.StringValue(networkACL.VpcId)}
+			for _, entry := range getNetworkACLOwnedEntries(networkACL, cloudSgName) {
+				// network ACLs of member subnets have same entries.
+				entryKey := id.String() + "/" + getNetworkACLEntryKey(entry)
+				if _, found := existing[entryKey]; found {
+					continue
+				}
+				existing[entryKey] = struct{}{}
+
+				cidr := getNetworkACLEntryCIDR(entry)
+				var ips []*net.IPNet
+				if _, ipNet, err := net.ParseCIDR(cidr); err == nil && cidr != awsAnyIPv4Address && cidr != awsAnyIPv6Address {
+					ips = append(ips, ipNet)
+				}
+				// ICMPv6 entries realize IPv6 peers of ICMP rules.
+				protocol := convertFromNetworkACLProtocol(aws.StringValue(entry.Protocol))
+				if securitygroup.IsICMPProtocol(protocol) {
+					protocol = aws.Int(securitygroup.ProtocolNameNumMap["icmp"])
+				}
+				var port, endPort, icmpType, icmpCode *int
+				if entry.PortRange != nil {
+					port, endPort = convertFromIPPermissionPort(entry.PortRange.From, entry.PortRange.To)
+				}
+				if entry.IcmpTypeCode != nil {
+					icmpType, icmpCode = convertFromIPPermissionICMPTypeCode(entry.IcmpTypeCode.Type, entry.IcmpTypeCode.Code)
+				}
+				ruleKey := fmt.Sprintf("%v/%v/%v-%v/%v-%v", id.String(), aws.IntValue(protocol), aws.IntValue(port),
+					aws.IntValue(endPort), getNetworkACLICMPKey(icmpType), getNetworkACLICMPKey(icmpCode))
+				if aws.BoolValue(entry.Egress) {
+					if idx, found := egressRuleIdx[ruleKey]; found {
+						egressRules[id][idx].ToDstIP = append(egressRules[id][idx].ToDstIP, ips...)
+						continue
+					}
+					egressRuleIdx[ruleKey] = len(egressRules[id])
+					egressRules[id] = append(egressRules[id], securitygroup.EgressRule{ToPort: port, ToEndPort: endPort, ToDstIP: ips,
+						Protocol: protocol, ICMPType: icmpType, ICMPCode: icmpCode, Action: securitygroup.RuleActionDeny})
+				} else {
+					if idx, found := ingressRuleIdx[ruleKey]; found {
+						ingressRules[id][idx].FromSrcIP = append(ingressRules[id][idx].FromSrcIP, ips...)
+						continue
+					}
+					ingressRuleIdx[ruleKey] = len(ingressRules[id])
+					ingressRules[id] = append(ingressRules[id], securitygroup.IngressRule{FromPort: port, FromEndPort: endPort, FromSrcIP: ips,
+						Protocol: protocol, ICMPType: icmpType, ICMPCode: icmpCode, Action: securitygroup.RuleActionDeny})
+				}
+			}
+		}
+	}
+	return ingressRules, egressRules, nil
+}
+
+// getNetworkACLICMPKey returns ICMP type or code v as key, -1 if v matches any.
+func getNetworkACLICMPKey(v *int) int {
+	if v == nil {
+		return -1
+	}
+	return *v
+}
+
+func convertFromNetworkACLProtocol(protocol string) *int {
+	if strings.Compare(protocol, awsAnyProtocolValue) == 0 {
+		return nil
+	}
+	protoNum, err := strconv.Atoi(protocol)
+	if err != nil {
+		return convertFromIPPermissionProtocol(protocol)
+	}
+	return &protoNum
+}
@@ -0,0 +1,581 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/cenkalti/backoff/v4"
+	"go.uber.org/multierr"
+
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+)
+
+// Security group rules with many CIDRs of an address family may reference a customer-managed prefix list of the CIDRs,
+// instead of individual IP ranges. A prefix list is owned by the appliedTo security group whose rule references it,
+// recorded by a prefix list tag keyed by awsPrefixListSgTagKey with the security group ID, and is named after the
+// security group, rule direction, a hash of the rule content other than its IPs and address family, so that the prefix
+// list of a rule does not depend on the order of rules. Prefix lists are updated in place as CIDRs of rules change, and
+// deleted once no longer referenced by rules of their security group. Aws counts a referenced prefix list by its max
+// entries against the rules quota of the security group, so prefix lists do not save rules quota.
+const (
+	awsPrefixListSgTagKey = "nephe-security-group"
+
+	awsPrefixListDirectionIngress = "ingress"
+	awsPrefixListDirectionEgress  = "egress"
+
+	awsPrefixListAddressFamilyIPv4 = "IPv4"
+	awsPrefixListAddressFamilyIPv6 = "IPv6"
+
+	// aws limits entries added or removed by a single prefix list create or modify request.
+	awsPrefixListMaxEntriesPerRequest = 100
+	// prefix lists can only be modified once previous create or modify request completes.
+	awsPrefixListWaitTime = 30 * time.Second
+)
+
+// Security group rules may also reference prefix lists not owned by nephe controller, as cloud service peers of rules.
+// A cloud service is either the ID of a prefix list, or the name of a prefix list, such as the aws-managed prefix list
+// com.amazonaws.us-west-2.s3 of an aws service. In the cloud view of security groups, aws-managed prefix lists are
+// reported by name, other prefix lists are reported by ID.
+const (
+	awsPrefixListIDPrefix = "pl-"
+	awsPrefixListOwnerAWS = "AWS"
+)
+
+// getPrefixListName returns name of the prefix list of a security group rule.
+func getPrefixListName(cloudSgName string, direction string, ruleKey string, addressFamily string) string {
+	return fmt.Sprintf("%v-%v-%v-%v", cloudSgName, direction, ruleKey, strings.ToLower(addressFamily))
+}
+
+// getIngressRulePrefixListKey returns the key of prefix lists of an ingress rule.
+func getIngressRulePrefixListKey(rule *securitygroup.IngressRule) string {
+	return getRulePrefixListKey(rule.Protocol, rule.FromPort, rule.FromEndPort, rule.ICMPType, rule.ICMPCode, rule.Action,
+		rule.FromSecurityGroups, rule.FromCloudServices)
+}
+
+// getEgressRulePrefixListKey returns the key of prefix lists of an egress rule.
+func getEgressRulePrefixListKey(rule *securitygroup.EgressRule) string {
+	return getRulePrefixListKey(rule.Protocol, rule.ToPort, rule.ToEndPort, rule.ICMPType, rule.ICMPCode, rule.Action,
+		rule.ToSecurityGroups, rule.ToCloudServices)
+}
+
+// getRulePrefixListKey returns a hash of content of a rule other than its IPs. Rules of the same key only differ in
+// IPs, and share prefix lists of the union of their IPs.
+func getRulePrefixListKey(protocol, port, endPort, icmpType, icmpCode *int, action securitygroup.RuleAction,
+	securityGroups []*securitygroup.CloudResourceID, cloudServices []string) string {
+	intString := func(i *int) string {
+		if i == nil {
+			return ""
+		}
+		return strconv.Itoa(*i)
+	}
+	var peers []string
+	for _, securityGroup := range securityGroups {
+		peers = append(peers, securityGroup.String())
+	}
+	sort.Strings(peers)
+	services := append([]string{}, cloudServices...)
+	sort.Strings(services)
+	content := strings.Join([]string{intString(protocol), intString(port), intString(endPort), intString(icmpType),
+		intString(icmpCode), string(action), strings.Join(peers, ","), strings.Join(services, ",")}, "/")
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:8])
+}
+
+// splitIPNetsByAddressFamily returns IPv4 and IPv6 ips.
+func splitIPNetsByAddressFamily(ips []*net.IPNet) ([]*net.IPNet, []*net.IPNet) {
+	var ipv4IPs, ipv6IPs []*net.IPNet
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			ipv4IPs = append(ipv4IPs, ip)
+		} else {
+			ipv6IPs = append(ipv6IPs, ip)
+		}
+	}
+	return ipv4IPs, ipv6IPs
+}
+
+// getUniqueCIDRs returns unique CIDRs of ips, in order.
+func getUniqueCIDRs(ips []*net.IPNet) []string {
+	var cidrs []string
+	cidrSet := make(map[string]struct{})
+	for _, ip := range ips {
+		cidr := ip.String()
+		if _, found := cidrSet[cidr]; found {
+			continue
+		}
+		cidrSet[cidr] = struct{}{}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs
+}
+
+// hasPrefixListIDs returns true if any ip permission references a prefix list.
+func hasPrefixListIDs(ipPermissions []*ec2.IpPermission) bool {
+	for _, ipPermission := range ipPermissions {
+		if len(ipPermission.PrefixListIds) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// realizeRulePrefixLists creates or updates prefix lists of rules of security group cloudSgObj in direction, for rules
+// with at least prefixListThreshold CIDRs of an address family. rulesKeys are prefix list keys of rules. It returns
+// prefix lists to be referenced by each rule, IPs of each rule not in prefix lists, and prefix lists of direction owned
+// by the security group no longer referenced. Owned prefix lists are looked up only if prefix lists are enabled, or
+// currentIPPermissions reference prefix lists.
+func (ec2Cfg *ec2ServiceConfig) realizeRulePrefixLists(cloudSgObj *ec2.SecurityGroup, direction string,
+	currentIPPermissions []*ec2.IpPermission, rulesIPs [][]*net.IPNet, rulesKeys []string) ([][]*ec2.PrefixListId,
+	[][]*net.IPNet, []*ec2.ManagedPrefixList, error) {
+	rulesPrefixLists := make([][]*ec2.PrefixListId, len(rulesIPs))
+	if ec2Cfg.prefixListThreshold == 0 && !hasPrefixListIDs(currentIPPermissions) {
+		return rulesPrefixLists, rulesIPs, nil, nil
+	}
+
+	ownedPrefixLists, err := ec2Cfg.getOwnedPrefixLists(aws.StringValue(cloudSgObj.GroupId))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cloudSgName := aws.StringValue(cloudSgObj.GroupName)
+	type rulePrefixList struct {
+		addressFamily string
+		ips           []*net.IPNet
+		ruleIndexes   []int
+	}
+	namedPrefixLists := make(map[string]*rulePrefixList)
+	remainingRulesIPs := make([][]*net.IPNet, len(rulesIPs))
+	for i, ips := range rulesIPs {
+		ipv4IPs, ipv6IPs := splitIPNetsByAddressFamily(ips)
+		for _, addressFamilyIPs := range []struct {
+			addressFamily string
+			ips           []*net.IPNet
+		}{{awsPrefixListAddressFamilyIPv4, ipv4IPs}, {awsPrefixListAddressFamilyIPv6, ipv6IPs}} {
+			cidrs := getUniqueCIDRs(addressFamilyIPs.ips)
+			if ec2Cfg.prefixListThreshold == 0 || len(cidrs) < ec2Cfg.prefixListThreshold {
+				remainingRulesIPs[i] = append(remainingRulesIPs[i], addressFamilyIPs.ips...)
+				continue
+			}
+			name := getPrefixListName(cloudSgName, direction, rulesKeys[i], addressFamilyIPs.addressFamily)
+			prefixList, found := namedPrefixLists[name]
+			if !found {
+				prefixList = &rulePrefixList{addressFamily: addressFamilyIPs.addressFamily}
+				namedPrefixLists[name] = prefixList
+			}
+			prefixList.ips = append(prefixList.ips, addressFamilyIPs.ips...)
+			prefixList.ruleIndexes = append(prefixList.ruleIndexes, i)
+		}
+	}
+
+	names := make([]string, 0, len(namedPrefixLists))
+	for name := range namedPrefixLists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prefixList := namedPrefixLists[name]
+		prefixListID, err := ec2Cfg.realizePrefixList(ownedPrefixLists[name], name, prefixList.addressFamily,
+			aws.StringValue(cloudSgObj.GroupId), getUniqueCIDRs(prefixList.ips))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, i := range prefixList.ruleIndexes {
+			rulesPrefixLists[i] = append(rulesPrefixLists[i], &ec2.PrefixListId{PrefixListId: prefixListID})
+		}
+	}
+
+	var unusedPrefixLists []*ec2.ManagedPrefixList
+	directionPrefix := fmt.Sprintf("%v-%v-", cloudSgName, direction)
+	for name, prefixList := range ownedPrefixLists {
+		if _, found := namedPrefixLists[name]; found || !strings.HasPrefix(name, directionPrefix) {
+			continue
+		}
+		unusedPrefixLists = append(unusedPrefixLists, prefixList)
+	}
+	return rulesPrefixLists, remainingRulesIPs, unusedPrefixLists, nil
+}
+
+// getCloudServicePrefixLists returns prefix lists of cloud services referenced by rules, keyed by cloud service.
+func (ec2Cfg *ec2ServiceConfig) getCloudServicePrefixLists(ingressRules []*securitygroup.IngressRule,
+	egressRules []*securitygroup.EgressRule) (map[string]*ec2.ManagedPrefixList, error) {
+	cloudServices := make(map[string]struct{})
+	for _, rule := range ingressRules {
+		for _, cloudService := range rule.FromCloudServices {
+			cloudServices[cloudService] = struct{}{}
+		}
+	}
+	for _, rule := range egressRules {
+		for _, cloudService := range rule.ToCloudServices {
+			cloudServices[cloudService] = struct{}{}
+		}
+	}
+	cloudServicePrefixLists := make(map[string]*ec2.ManagedPrefixList)
+	if len(cloudServices) == 0 {
+		return cloudServicePrefixLists, nil
+	}
+
+	var prefixListIDs, prefixListNames []*string
+	for cloudService := range cloudServices {
+		if strings.HasPrefix(cloudService, awsPrefixListIDPrefix) {
+			prefixListIDs = append(prefixListIDs, aws.String(cloudService))
+		} else {
+			prefixListNames = append(prefixListNames, aws.String(cloudService))
+		}
+	}
+	var prefixLists []*ec2.ManagedPrefixList
+	if len(prefixListIDs) > 0 {
+		output, err := ec2Cfg.apiClient.pagedDescribeManagedPrefixLists(&ec2.DescribeManagedPrefixListsInput{PrefixListIds: prefixListIDs})
+		if err != nil {
+			return nil, err
+		}
+		prefixLists = append(prefixLists, output...)
+	}
+	if len(prefixListNames) > 0 {
+		input := &ec2.DescribeManagedPrefixListsInput{
+			Filters: []*ec2.Filter{{Name: aws.String("prefix-list-name"), Values: prefixListNames}},
+		}
+		output, err := ec2Cfg.apiClient.pagedDescribeManagedPrefixLists(input)
+		if err != nil {
+			return nil, err
+		}
+		prefixLists = append(prefixLists, output...)
+	}
+	for _, prefixList := range prefixLists {
+		for _, cloudService := range []string{aws.StringValue(prefixList.PrefixListId), aws.StringValue(prefixList.PrefixListName)} {
+			if _, found := cloudServices[cloudService]; found {
+				cloudServicePrefixLists[cloudService] = prefixList
+			}
+		}
+	}
+
+	var notFound []string
+	for cloudService := range cloudServices {
+		if _, found := cloudServicePrefixLists[cloudService]; !found {
+			notFound = append(notFound, cloudService)
+		}
+	}
+	if len(notFound) > 0 {
+		sort.Strings(notFound)
+		return nil, fmt.Errorf("prefix lists of cloud services %v not found", notFound)
+	}
+	return cloudServicePrefixLists, nil
+}
+
+// getCloudServicePrefixListsOf returns prefix lists of cloudServices of a rule.
+func getCloudServicePrefixListsOf(cloudServices []string,
+	cloudServicePrefixLists map[string]*ec2.ManagedPrefixList) []*ec2.ManagedPrefixList {
+	var prefixLists []*ec2.ManagedPrefixList
+	for _, cloudService := range cloudServices {
+		if prefixList, found := cloudServicePrefixLists[cloudService]; found {
+			prefixLists = append(prefixLists, prefixList)
+		}
+	}
+	return prefixLists
+}
+
+// getCloudServicePrefixListIDs returns IDs of prefix lists of cloudServices of a rule.
+func getCloudServicePrefixListIDs(cloudServices []string, cloudServicePrefixLists map[string]*ec2.ManagedPrefixList) []*ec2.PrefixListId {
+	var prefixListIDs []*ec2.PrefixListId
+	for _, prefixList := range getCloudServicePrefixListsOf(cloudServices, cloudServicePrefixLists) {
+		prefixListIDs = append(prefixListIDs, &ec2.PrefixListId{PrefixListId: prefixList.PrefixListId})
+	}
+	return prefixListIDs
+}
+
+// getPrefixListIPs returns IPs of entries of prefix list prefixListID.
+func (ec2Cfg *ec2ServiceConfig) getPrefixListIPs(prefixListID *string) ([]*net.IPNet, error) {
+	entries, err := ec2Cfg.apiClient.pagedGetManagedPrefixListEntries(&ec2.GetManagedPrefixListEntriesInput{PrefixListId: prefixListID})
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if _, ipNet, err := net.ParseCIDR(aws.StringValue(entry.Cidr)); err == nil {
+			ips = append(ips, ipNet)
+		}
+	}
+	return ips, nil
+}
+
+// getOwnedPrefixLists returns prefix lists owned by security group sgID, keyed by name.
+func (ec2Cfg *ec2ServiceConfig) getOwnedPrefixLists(sgID string) (map[string]*ec2.ManagedPrefixList, error) {
+	input := &ec2.DescribeManagedPrefixListsInput{
+		Filters: []*ec2.Filter{{Name: aws.String("tag:" + awsPrefixListSgTagKey), Values: []*string{aws.String(sgID)}}},
+	}
+	prefixLists, err := ec2Cfg.apiClient.pagedDescribeManagedPrefixLists(input)
+	if err != nil {
+		return nil, err
+	}
+	nameToPrefixList := make(map[string]*ec2.ManagedPrefixList)
+	for _, prefixList := range prefixLists {
+		nameToPrefixList[aws.StringValue(prefixList.PrefixListName)] = prefixList
+	}
+	return nameToPrefixList, nil
+}
+
+// realizePrefixList creates prefix list name with unique cidrs if prefixList is nil, otherwise updates entries of
+// prefixList to cidrs. Max entries of the prefix list is kept at the number of cidrs, as aws counts referenced prefix
+// lists by max entries against security group rule quotas. It returns the prefix list ID.
+func (ec2Cfg *ec2ServiceConfig) realizePrefixList(prefixList *ec2.ManagedPrefixList, name string, addressFamily string,
+	sgID string, cidrs []string) (*string, error) {
+	var err error
+	var currentCIDRs []string
+	if prefixList == nil {
+		numEntries := len(cidrs)
+		if numEntries > awsPrefixListMaxEntriesPerRequest {
+			numEntries = awsPrefixListMaxEntriesPerRequest
+		}
+		input := &ec2.CreateManagedPrefixListInput{
+			PrefixListName: aws.String(name),
+			AddressFamily:  aws.String(addressFamily),
+			MaxEntries:     aws.Int64(int64(len(cidrs))),
+			Entries:        buildAddPrefixListEntries(cidrs[:numEntries]),
+			TagSpecifications: []*ec2.TagSpecification{{
+				ResourceType: aws.String(ec2.ResourceTypePrefixList),
+				Tags:         []*ec2.Tag{{Key: aws.String(awsPrefixListSgTagKey), Value: aws.String(sgID)}},
+			}},
+		}
+		output, err := ec2Cfg.apiClient.createManagedPrefixList(input)
+		if err != nil {
+			return nil, err
+		}
+		if prefixList, err = ec2Cfg.waitForPrefixListModification(output.PrefixList.PrefixListId); err != nil {
+			return nil, err
+		}
+		currentCIDRs = cidrs[:numEntries]
+	} else {
+		input := &ec2.GetManagedPrefixListEntriesInput{PrefixListId: prefixList.PrefixListId}
+		entries, err := ec2Cfg.apiClient.pagedGetManagedPrefixListEntries(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			currentCIDRs = append(currentCIDRs, aws.StringValue(entry.Cidr))
+		}
+	}
+
+	cidrsToAdd, cidrsToRemove := diffCIDRs(currentCIDRs, cidrs)
+	// max entries and entries can not be modified by the same request.
+	if aws.Int64Value(prefixList.MaxEntries) < int64(len(cidrs)) {
+		if prefixList, err = ec2Cfg.resizePrefixList(prefixList, len(cidrs)); err != nil {
+			return nil, err
+		}
+	}
+	for len(cidrsToAdd) > 0 || len(cidrsToRemove) > 0 {
+		numAddEntries, numRemoveEntries := len(cidrsToAdd), len(cidrsToRemove)
+		if numAddEntries > awsPrefixListMaxEntriesPerRequest {
+			numAddEntries = awsPrefixListMaxEntriesPerRequest
+		}
+		if numRemoveEntries > awsPrefixListMaxEntriesPerRequest {
+			numRemoveEntries = awsPrefixListMaxEntriesPerRequest
+		}
+		input := &ec2.ModifyManagedPrefixListInput{
+			PrefixListId:   prefixList.PrefixListId,
+			CurrentVersion: prefixList.Version,
+			AddEntries:     buildAddPrefixListEntries(cidrsToAdd[:numAddEntries]),
+			RemoveEntries:  buildRemovePrefixListEntries(cidrsToRemove[:numRemoveEntries]),
+		}
+		if _, err = ec2Cfg.apiClient.modifyManagedPrefixList(input); err != nil {
+			return nil, err
+		}
+		if prefixList, err = ec2Cfg.waitForPrefixListModification(prefixList.PrefixListId); err != nil {
+			return nil, err
+		}
+		cidrsToAdd, cidrsToRemove = cidrsToAdd[numAddEntries:], cidrsToRemove[numRemoveEntries:]
+	}
+	if aws.Int64Value(prefixList.MaxEntries) > int64(len(cidrs)) {
+		if prefixList, err = ec2Cfg.resizePrefixList(prefixList, len(cidrs)); err != nil {
+			return nil, err
+		}
+	}
+	return prefixList.PrefixListId, nil
+}
+
+func (ec2Cfg *ec2ServiceConfig) resizePrefixList(prefixList *ec2.ManagedPrefixList, maxEntries int) (*ec2.ManagedPrefixList,
+	error) {
+	input := &ec2.ModifyManagedPrefixListInput{
+		PrefixListId: prefixList.PrefixListId,
+		MaxEntries:   aws.Int64(int64(maxEntries)),
+	}
+	if _, err := ec2Cfg.apiClient.modifyManagedPrefixList(input); err != nil {
+		return nil, err
+	}
+	return ec2Cfg.waitForPrefixListModification(prefixList.PrefixListId)
+}
+
+// waitForPrefixListModification waits till create or modify request of prefix list prefixListID completes, and
+// returns the prefix list.
+func (ec2Cfg *ec2ServiceConfig) waitForPrefixListModification(prefixListID *string) (*ec2.ManagedPrefixList, error) {
+	var prefixList *ec2.ManagedPrefixList
+	operation := func() error {
+		input := &ec2.DescribeManagedPrefixListsInput{PrefixListIds: []*string{prefixListID}}
+		prefixLists, err := ec2Cfg.apiClient.pagedDescribeManagedPrefixLists(input)
+		if err != nil {
+			return err
+		}
+		if len(prefixLists) == 0 {
+			return fmt.Errorf("failed to find prefix list %v", aws.StringValue(prefixListID))
+		}
+		prefixList = prefixLists[0]
+		state := aws.StringValue(prefixList.State)
+		if strings.HasSuffix(state, "-failed") {
+			return backoff.Permanent(fmt.Errorf("prefix list %v %v: %v", aws.StringValue(prefixListID), state,
+				aws.StringValue(prefixList.StateMessage)))
+		}
+		if !strings.HasSuffix(state, "-complete") {
+			return fmt.Errorf("prefix list %v %v", aws.StringValue(prefixListID), state)
+		}
+		return nil
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.MaxElapsedTime = awsPrefixListWaitTime
+	if err := backoff.Retry(operation, b); err != nil {
+		return nil, err
+	}
+	return prefixList, nil
+}
+
+// deletePrefixLists deletes prefix lists, no longer referenced by security group rules.
+func (ec2Cfg *ec2ServiceConfig) deletePrefixLists(prefixLists []*ec2.ManagedPrefixList) error {
+	var err error
+	for _, prefixList := range prefixLists {
+		input := &ec2.DeleteManagedPrefixListInput{PrefixListId: prefixList.PrefixListId}
+		_, e := ec2Cfg.apiClient.deleteManagedPrefixList(input)
+		err = multierr.Append(err, e)
+	}
+	return err
+}
+
+// deleteOwnedPrefixLists deletes all prefix lists owned by security group sgID.
+func (ec2Cfg *ec2ServiceConfig) deleteOwnedPrefixLists(sgID string) error {
+	ownedPrefixLists, err := ec2Cfg.getOwnedPrefixLists(sgID)
+	if err != nil {
+		return err
+	}
+	var prefixLists []*ec2.ManagedPrefixList
+	for _, prefixList := range ownedPrefixLists {
+		prefixLists = append(prefixLists, prefixList)
+	}
+	return ec2Cfg.deletePrefixLists(prefixLists)
+}
+
+// getPrefixListsCloudView returns IPs of prefix lists owned by nephe controller and referenced by ipPermissions, keyed
+// by prefix list ID, and cloud services of other prefix lists referenced by ipPermissions, keyed by prefix list ID.
+func (ec2Cfg *ec2ServiceConfig) getPrefixListsCloudView(ipPermissions []*ec2.IpPermission) (map[string][]*net.IPNet,
+	map[string]string, error) {
+	prefixListIDToIPs := make(map[string][]*net.IPNet)
+	prefixListIDToCloudService := make(map[string]string)
+	referencedPrefixListIDs := make(map[string]struct{})
+	for _, ipPermission := range ipPermissions {
+		for _, prefixListID := range ipPermission.PrefixListIds {
+			referencedPrefixListIDs[aws.StringValue(prefixListID.PrefixListId)] = struct{}{}
+		}
+	}
+	if len(referencedPrefixListIDs) == 0 {
+		return prefixListIDToIPs, prefixListIDToCloudService, nil
+	}
+
+	input := &ec2.DescribeManagedPrefixListsInput{
+		Filters: []*ec2.Filter{{Name: aws.String("tag-key"), Values: []*string{aws.String(awsPrefixListSgTagKey)}}},
+	}
+	prefixLists, err := ec2Cfg.apiClient.pagedDescribeManagedPrefixLists(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, prefixList := range prefixLists {
+		prefixListID := aws.StringValue(prefixList.PrefixListId)
+		if _, found := referencedPrefixListIDs[prefixListID]; !found {
+			continue
+		}
+		ips, err := ec2Cfg.getPrefixListIPs(prefixList.PrefixListId)
+		if err != nil {
+			return nil, nil, err
+		}
+		prefixListIDToIPs[prefixListID] = ips
+	}
+
+	var otherPrefixListIDs []*string
+	for prefixListID := range referencedPrefixListIDs {
+		if _, found := prefixListIDToIPs[prefixListID]; !found {
+			otherPrefixListIDs = append(otherPrefixListIDs, aws.String(prefixListID))
+		}
+	}
+	if len(otherPrefixListIDs) == 0 {
+		return prefixListIDToIPs, prefixListIDToCloudService, nil
+	}
+	prefixLists, err = ec2Cfg.apiClient.pagedDescribeManagedPrefixLists(&ec2.DescribeManagedPrefixListsInput{PrefixListIds: otherPrefixListIDs})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, prefixList := range prefixLists {
+		prefixListID := aws.StringValue(prefixList.PrefixListId)
+		prefixListIDToCloudService[prefixListID] = prefixListID
+		if aws.StringValue(prefixList.OwnerId) == awsPrefixListOwnerAWS {
+			prefixListIDToCloudService[prefixListID] = aws.StringValue(prefixList.PrefixListName)
+		}
+	}
+	return prefixListIDToIPs, prefixListIDToCloudService, nil
+}
+
+// diffCIDRs returns cidrs in desired not in current, and cidrs in current not in desired, sorted.
+func diffCIDRs(current []string, desired []string) ([]string, []string) {
+	currentSet := make(map[string]struct{}, len(current))
+	for _, cidr := range current {
+		currentSet[cidr] = struct{}{}
+	}
+	desiredSet := make(map[string]struct{}, len(desired))
+	var cidrsToAdd []string
+	for _, cidr := range desired {
+		desiredSet[cidr] = struct{}{}
+		if _, found := currentSet[cidr]; !found {
+			cidrsToAdd = append(cidrsToAdd, cidr)
+		}
+	}
+	var cidrsToRemove []string
+	for cidr := range currentSet {
+		if _, found := desiredSet[cidr]; !found {
+			cidrsToRemove = append(cidrsToRemove, cidr)
+		}
+	}
+	sort.Strings(cidrsToAdd)
+	sort.Strings(cidrsToRemove)
+	return cidrsToAdd, cidrsToRemove
+}
+
+func buildAddPrefixListEntries(cidrs []string) []*ec2.AddPrefixListEntry {
+	var entries []*ec2.AddPrefixListEntry
+	for _, cidr := range cidrs {
+		entries = append(entries, &ec2.AddPrefixListEntry{Cidr: aws.String(cidr)})
+	}
+	return entries
+}
+
+func buildRemovePrefixListEntries(cidrs []string) []*ec2.RemovePrefixListEntry {
+	var entries []*ec2.RemovePrefixListEntry
+	for _, cidr := range cidrs {
+		entries = append(entries, &ec2.RemovePrefixListEntry{Cidr: aws.String(cidr)})
+	}
+	return entries
+}
@@ -0,0 +1,138 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+	"antrea.io/nephe/pkg/metrics"
+)
+
+// Rules of a security group are checked against the account quota of rules per security group before they are
+// realized. The quota applies separately to inbound and outbound rules, and to IPv4 and IPv6 rules. Each CIDR of a
+// rule, including CIDRs of a prefix list realizing CIDRs of the rule, counts as one rule, each security group
+// referenced by a rule counts as one rule of each address family, and each prefix list of a cloud service referenced by
+// a rule counts as max entries of the prefix list.
+const (
+	awsDefaultRulesPerSecurityGroupQuota             = 60
+	awsDefaultSecurityGroupsPerNetworkInterfaceQuota = 5
+	awsDefaultEntriesPerNetworkACLQuota              = 20
+)
+
+// awsSecurityGroupQuotas are quotas of security groups, and of network ACLs realizing their deny rules, of an account, 0
+// for aws default quotas.
+type awsSecurityGroupQuotas struct {
+	rulesPerSecurityGroup             int
+	securityGroupsPerNetworkInterface int
+	entriesPerNetworkACL              int
+}
+
+// getRulesPerSecurityGroupQuota returns quota of inbound or outbound rules of an address family per security group.
+func (ec2Cfg *ec2ServiceConfig) getRulesPerSecurityGroupQuota() int {
+	if ec2Cfg.securityGroupQuotas.rulesPerSecurityGroup == 0 {
+		return awsDefaultRulesPerSecurityGroupQuota
+	}
+	return ec2Cfg.securityGroupQuotas.rulesPerSecurityGroup
+}
+
+// getSecurityGroupsPerNetworkInterfaceQuota returns quota of security groups per network interface.
+func (ec2Cfg *ec2ServiceConfig) getSecurityGroupsPerNetworkInterfaceQuota() int {
+	if ec2Cfg.securityGroupQuotas.securityGroupsPerNetworkInterface == 0 {
+		return awsDefaultSecurityGroupsPerNetworkInterfaceQuota
+	}
+	return ec2Cfg.securityGroupQuotas.securityGroupsPerNetworkInterface
+}
+
+// getEntriesPerNetworkACLQuota returns quota of inbound or outbound entries of an address family per network ACL.
+func (ec2Cfg *ec2ServiceConfig) getEntriesPerNetworkACLQuota() int {
+	if ec2Cfg.securityGroupQuotas.entriesPerNetworkACL == 0 {
+		return awsDefaultEntriesPerNetworkACLQuota
+	}
+	return ec2Cfg.securityGroupQuotas.entriesPerNetworkACL
+}
+
+// getRuleCount returns number of IPv4 and IPv6 rules counted against the quota for a rule with ips, numSgs referenced
+// security groups and referenced prefix lists. A rule with no peers allows all IPv4 and IPv6 addresses.
+func getRuleCount(ips []*net.IPNet, numSgs int, prefixLists []*ec2.ManagedPrefixList) (int, int) {
+	if len(ips) == 0 && numSgs == 0 && len(prefixLists) == 0 {
+		return 1, 1
+	}
+	ipv4IPs, ipv6IPs := splitIPNetsByAddressFamily(ips)
+	ipv4Count, ipv6Count := len(ipv4IPs)+numSgs, len(ipv6IPs)+numSgs
+	for _, prefixList := range prefixLists {
+		if aws.StringValue(prefixList.AddressFamily) == awsPrefixListAddressFamilyIPv6 {
+			ipv6Count += int(aws.Int64Value(prefixList.MaxEntries))
+		} else {
+			ipv4Count += int(aws.Int64Value(prefixList.MaxEntries))
+		}
+	}
+	return ipv4Count, ipv6Count
+}
+
+// checkSecurityGroupRuleQuota returns an error wrapping securitygroup.ErrQuotaExceeded if ingress or egress allow rules
+// of a security group exceed the rules per security group quota, and reports remaining rules of the security group.
+func (ec2Cfg *ec2ServiceConfig) checkSecurityGroupRuleQuota(cloudSgObj *ec2.SecurityGroup,
+	ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule,
+	cloudServicePrefixLists map[string]*ec2.ManagedPrefixList) error {
+	var ingressIPv4, ingressIPv6, egressIPv4, egressIPv6 int
+	for _, rule := range ingressRules {
+		if rule == nil {
+			continue
+		}
+		ipv4, ipv6 := getRuleCount(rule.FromSrcIP, len(rule.FromSecurityGroups),
+			getCloudServicePrefixListsOf(rule.FromCloudServices, cloudServicePrefixLists))
+		ingressIPv4, ingressIPv6 = ingressIPv4+ipv4, ingressIPv6+ipv6
+	}
+	for _, rule := range egressRules {
+		if rule == nil {
+			continue
+		}
+		ipv4, ipv6 := getRuleCount(rule.ToDstIP, len(rule.ToSecurityGroups),
+			getCloudServicePrefixListsOf(rule.ToCloudServices, cloudServicePrefixLists))
+		egressIPv4, egressIPv6 = egressIPv4+ipv4, egressIPv6+ipv6
+	}
+
+	quota := ec2Cfg.getRulesPerSecurityGroupQuota()
+	counts := []struct {
+		count         int
+		direction     string
+		addressFamily string
+	}{
+		{ingressIPv4, awsPrefixListDirectionIngress, awsPrefixListAddressFamilyIPv4},
+		{ingressIPv6, awsPrefixListDirectionIngress, awsPrefixListAddressFamilyIPv6},
+		{egressIPv4, awsPrefixListDirectionEgress, awsPrefixListAddressFamilyIPv4},
+		{egressIPv6, awsPrefixListDirectionEgress, awsPrefixListAddressFamilyIPv6},
+	}
+	cloudSgName := aws.StringValue(cloudSgObj.GroupName)
+	maxCount := 0
+	var err error
+	for _, c := range counts {
+		if c.count > maxCount {
+			maxCount = c.count
+		}
+		if c.count > quota && err == nil {
+			err = fmt.Errorf("%w: security group %v requires %v %v %v rules, rules per security group quota is %v",
+				securitygroup.ErrQuotaExceeded, cloudSgName, c.count, c.addressFamily, c.direction, quota)
+		}
+	}
+	headroom := metrics.SecurityGroupRuleHeadroom.WithLabelValues(string(providerType), aws.StringValue(cloudSgObj.GroupId))
+	headroom.Set(float64(quota - maxCount))
+	return err
+}
@@ -16,6 +16,9 @@ package aws
 
 import (
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,11 +29,20 @@ import (
 	"go.uber.org/multierr"
 	"k8s.io/apimachinery/pkg/types"
 
+	"antrea.io/nephe/apis/crd/v1alpha1"
 	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 const (
 	awsVpcDefaultSecurityGroupName = "default"
+	// awsNetworkInterfaceOriginalSgsTagKey is the network interface tag recording sgs attached to the network interface
+	// before nephe replaced them, restored once no nephe created AT sg is attached. Sgs not fitting in a tag value are
+	// recorded in further tags, keyed by awsNetworkInterfaceOriginalSgsTagKey suffixed with "-<index>".
+	awsNetworkInterfaceOriginalSgsTagKey    = "nephe-original-security-groups"
+	awsNetworkInterfaceOriginalSgsSeparator = ","
+	// awsTagValueMaxLength is the maximum length of an aws tag value.
+	awsTagValueMaxLength = 256
 )
 
 var (
@@ -193,8 +205,26 @@ func (ec2Cfg *ec2ServiceConfig) getCloudSecurityGroupsWithNameFromCloud(vpcIDs [
 }
 
 func (ec2Cfg *ec2ServiceConfig) realizeIngressIPPermissions(cloudSgObj *ec2.SecurityGroup, rules []*securitygroup.IngressRule,
-	cloudSGNameToObj map[string]*ec2.SecurityGroup) error {
-	var err error
+	cloudSGNameToObj map[string]*ec2.SecurityGroup, cloudServicePrefixLists map[string]*ec2.ManagedPrefixList) error {
+	// realize large ip sets of rules as prefix lists, except IPv6 ips of ICMP rules realized by ICMPv6 ip permissions.
+	rulesIPs := make([][]*net.IPNet, len(rules))
+	rulesICMPv6IPs := make([][]*net.IPNet, len(rules))
+	rulesKeys := make([]string, len(rules))
+	for i, rule := range rules {
+		if rule == nil {
+			continue
+		}
+		rulesIPs[i] = rule.FromSrcIP
+		if securitygroup.IsICMPProtocol(rule.Protocol) {
+			rulesIPs[i], rulesICMPv6IPs[i] = splitIPNetsByAddressFamily(rule.FromSrcIP)
+		}
+		rulesKeys[i] = getIngressRulePrefixListKey(rule)
+	}
+	rulesPrefixLists, rulesIPs, unusedPrefixLists, err := ec2Cfg.realizeRulePrefixLists(cloudSgObj, awsPrefixListDirectionIngress,
+		cloudSgObj.IpPermissions, rulesIPs, rulesKeys)
+	if err != nil {
+		return err
+	}
 
 	// revoke old ingress rules and add new rules
 	if len(cloudSgObj.IpPermissions) > 0 {
@@ -211,35 +241,65 @@ func (ec2Cfg *ec2ServiceConfig) realizeIngressIPPermissions(cloudSgObj *ec2.Secu
 	// build ingress IpPermissions to be added
 	if len(rules) > 0 {
 		var ipPermissionsToAdd []*ec2.IpPermission
-		for _, rule := range rules {
+		for i, rule := range rules {
 			if rule == nil {
 				continue
 			}
 			idGroupPairs := buildEc2UserIDGroupPairs(rule.FromSecurityGroups, cloudSGNameToObj)
-			ipRanges := convertToEc2IpRanges(rule.FromSrcIP, len(rule.FromSecurityGroups) > 0)
-			startPort, endPort := convertToIPPermissionPort(rule.FromPort, rule.Protocol)
+			prefixListIDs := append(rulesPrefixLists[i], getCloudServicePrefixListIDs(rule.FromCloudServices, cloudServicePrefixLists)...)
+			ips := append(append([]*net.IPNet{}, rulesIPs[i]...), rulesICMPv6IPs[i]...)
+			ruleHasGroups := len(rule.FromSecurityGroups) > 0 || len(prefixListIDs) > 0
+			ipRanges := convertToEc2IpRanges(ips, ruleHasGroups)
+			ipv6Ranges := convertToEc2Ipv6Ranges(ips, ruleHasGroups)
+			startPort, endPort := convertToIPPermissionPort(rule.FromPort, rule.FromEndPort, rule.Protocol)
+			if securitygroup.IsICMPProtocol(rule.Protocol) {
+				startPort, endPort = convertToIPPermissionICMPTypeCode(rule.ICMPType, rule.ICMPCode)
+			}
 			ipPermission := &ec2.IpPermission{
 				FromPort:         startPort,
 				ToPort:           endPort,
 				IpProtocol:       convertToIPPermissionProtocol(rule.Protocol),
 				IpRanges:         ipRanges,
+				Ipv6Ranges:       ipv6Ranges,
+				PrefixListIds:    prefixListIDs,
 				UserIdGroupPairs: idGroupPairs,
 			}
-			ipPermissionsToAdd = append(ipPermissionsToAdd, ipPermission)
+			ipPermissionsToAdd = append(ipPermissionsToAdd, splitICMPv6IPPermission(ipPermission)...)
 		}
 		request := &ec2.AuthorizeSecurityGroupIngressInput{
 			GroupId:       cloudSgObj.GroupId,
 			IpPermissions: ipPermissionsToAdd,
 		}
 		_, err = ec2Cfg.apiClient.authorizeSecurityGroupIngress(request)
+		if err != nil {
+			return err
+		}
 	}
 
-	return err
+	return ec2Cfg.deletePrefixLists(unusedPrefixLists)
 }
 
 func (ec2Cfg *ec2ServiceConfig) realizeEgressIPPermissions(group *ec2.SecurityGroup, rules []*securitygroup.EgressRule,
-	cloudSGNameToObj map[string]*ec2.SecurityGroup) error {
-	var err error
+	cloudSGNameToObj map[string]*ec2.SecurityGroup, cloudServicePrefixLists map[string]*ec2.ManagedPrefixList) error {
+	// realize large ip sets of rules as prefix lists, except IPv6 ips of ICMP rules realized by ICMPv6 ip permissions.
+	rulesIPs := make([][]*net.IPNet, len(rules))
+	rulesICMPv6IPs := make([][]*net.IPNet, len(rules))
+	rulesKeys := make([]string, len(rules))
+	for i, rule := range rules {
+		if rule == nil {
+			continue
+		}
+		rulesIPs[i] = rule.ToDstIP
+		if securitygroup.IsICMPProtocol(rule.Protocol) {
+			rulesIPs[i], rulesICMPv6IPs[i] = splitIPNetsByAddressFamily(rule.ToDstIP)
+		}
+		rulesKeys[i] = getEgressRulePrefixListKey(rule)
+	}
+	rulesPrefixLists, rulesIPs, unusedPrefixLists, err := ec2Cfg.realizeRulePrefixLists(group, awsPrefixListDirectionEgress,
+		group.IpPermissionsEgress, rulesIPs, rulesKeys)
+	if err != nil {
+		return err
+	}
 
 	// revoke old egress rules and add new rules
 	if len(group.IpPermissionsEgress) > 0 {
@@ -256,21 +316,30 @@ func (ec2Cfg *ec2ServiceConfig) realizeEgressIPPermissions(group *ec2.SecurityGr
 	// build egress IpPermissions to be added
 	if len(rules) > 0 {
 		var ipPermissionsToAdd []*ec2.IpPermission
-		for _, rule := range rules {
+		for i, rule := range rules {
 			if rule == nil {
 				continue
 			}
 			idGroupPairs := buildEc2UserIDGroupPairs(rule.ToSecurityGroups, cloudSGNameToObj)
-			ipRanges := convertToEc2IpRanges(rule.ToDstIP, len(rule.ToSecurityGroups) > 0)
-			startPort, endPort := convertToIPPermissionPort(rule.ToPort, rule.Protocol)
+			prefixListIDs := append(rulesPrefixLists[i], getCloudServicePrefixListIDs(rule.ToCloudServices, cloudServicePrefixLists)...)
+			ips := append(append([]*net.IPNet{}, rulesIPs[i]...), rulesICMPv6IPs[i]...)
+			ruleHasGroups := len(rule.ToSecurityGroups) > 0 || len(prefixListIDs) > 0
+			ipRanges := convertToEc2IpRanges(ips, ruleHasGroups)
+			ipv6Ranges := convertToEc2Ipv6Ranges(ips, ruleHasGroups)
+			startPort, endPort := convertToIPPermissionPort(rule.ToPort, rule.ToEndPort, rule.Protocol)
+			if securitygroup.IsICMPProtocol(rule.Protocol) {
+				startPort, endPort = convertToIPPermissionICMPTypeCode(rule.ICMPType, rule.ICMPCode)
+			}
 			ipPermission := &ec2.IpPermission{
 				FromPort:         startPort,
 				ToPort:           endPort,
 				IpProtocol:       convertToIPPermissionProtocol(rule.Protocol),
 				IpRanges:         ipRanges,
+				Ipv6Ranges:       ipv6Ranges,
+				PrefixListIds:    prefixListIDs,
 				UserIdGroupPairs: idGroupPairs,
 			}
-			ipPermissionsToAdd = append(ipPermissionsToAdd, ipPermission)
+			ipPermissionsToAdd = append(ipPermissionsToAdd, splitICMPv6IPPermission(ipPermission)...)
 		}
 
 		request := &ec2.AuthorizeSecurityGroupEgressInput{
@@ -278,8 +347,11 @@ func (ec2Cfg *ec2ServiceConfig) realizeEgressIPPermissions(group *ec2.SecurityGr
 			IpPermissions: ipPermissionsToAdd,
 		}
 		_, err = ec2Cfg.apiClient.authorizeSecurityGroupEgress(request)
+		if err != nil {
+			return err
+		}
 	}
-	return err
+	return ec2Cfg.deletePrefixLists(unusedPrefixLists)
 }
 
 func (ec2Cfg *ec2ServiceConfig) getVpcDefaultSecurityGroupID(vpcID string) (string, error) {
@@ -368,6 +440,10 @@ func (ec2Cfg *ec2ServiceConfig) updateSecurityGroupMembers(groupCloudSgID *strin
 
 	// find network interfaces which are using or need to use the provided SG
 	networkInterfacesToModify := make(map[string]map[string]struct{})
+	// original sgs of network interfaces to record before they are replaced, or to restore
+	networkInterfacesToRecordOriginalSgs := make(map[string]map[string]struct{})
+	networkInterfacesToRestoreOriginalSgs := make(map[string]map[string]struct{})
+	var skippedNetworkInterfaceErr error
 	for _, networkInterface := range networkInterfaces {
 		// for network interfaces not attached to any virtual machines, skip processing
 		attachment := networkInterface.Attachment
@@ -398,6 +474,9 @@ func (ec2Cfg *ec2ServiceConfig) updateSecurityGroupMembers(groupCloudSgID *strin
 			}
 			networkInterfaceNepheControllerCreatedCloudSgsSet[*group.GroupId] = struct{}{}
 		}
+		// in coexist mode, sgs not created by nephe stay attached along with nephe created sgs, except vpc default sg.
+		networkInterfaceCoexistingCloudSgsSet := ec2Cfg.getCoexistingCloudSgs(networkInterfaceOtherCloudSgsSet, vpcDefaultSgID)
+		networkInterfaceOriginalCloudSgsSet := getNetworkInterfaceOriginalSgs(networkInterface)
 
 		// if network interface is owned by any of member virtual machines or member interface, its sg needs update
 		_, isNicAttachedToMemberVM := memberVirtualMachines[*attachment.InstanceId]
@@ -407,11 +486,19 @@ func (ec2Cfg *ec2ServiceConfig) updateSecurityGroupMembers(groupCloudSgID *strin
 				delete(networkInterfaceNepheControllerCreatedCloudSgsSet, *groupCloudSgID)
 
 				networkInterfaceCloudSgsSetToAttach := networkInterfaceNepheControllerCreatedCloudSgsSet
+				for sgID := range networkInterfaceCoexistingCloudSgsSet {
+					networkInterfaceCloudSgsSetToAttach[sgID] = struct{}{}
+				}
 
 				// If network interface has only one AT sg attached, and we are processing AT sg to be removed, network interface
-				// will be attached to default sg along with any attached AG sg(s)
-				if !membershipOnly && numAppliedToGroupSgsAttached == 1 {
-					networkInterfaceCloudSgsSetToAttach[vpcDefaultSgID] = struct{}{}
+				// will be attached to its original sgs, if recorded, or default sg along with any attached AG sg(s), unless
+				// coexisting sgs stay attached
+				if !membershipOnly && numAppliedToGroupSgsAttached == 1 && len(networkInterfaceCoexistingCloudSgsSet) == 0 {
+					if networkInterfaceOriginalCloudSgsSet != nil {
+						networkInterfacesToRestoreOriginalSgs[*networkInterface.NetworkInterfaceId] = networkInterfaceOriginalCloudSgsSet
+					} else {
+						networkInterfaceCloudSgsSetToAttach[vpcDefaultSgID] = struct{}{}
+					}
 				}
 				// if network interface is not attached to AT sg and we processing detach from AG sg, keep all sgs. Also, if member-only
 				// address group will be the only sg attached to network interface, attach default sg along with AG security group.
@@ -424,9 +511,27 @@ func (ec2Cfg *ec2ServiceConfig) updateSecurityGroupMembers(groupCloudSgID *strin
 			}
 		} else {
 			if isNicAttachedToMemberVM || isNicMemberNetworkInterface {
+				if !membershipOnly && numAppliedToGroupSgsAttached == 0 {
+					networkInterfaceForeignCloudSgsSet := getForeignCloudSgs(networkInterfaceOtherCloudSgsSet, vpcDefaultSgID)
+					// in fail mode, network interfaces with sgs not created by nephe attached are left unmodified.
+					if ec2Cfg.securityGroupMode == v1alpha1.SecurityGroupModeFail && len(networkInterfaceForeignCloudSgsSet) != 0 {
+						skippedNetworkInterfaceErr = multierr.Append(skippedNetworkInterfaceErr, fmt.Errorf("network interface %v not attached to %v, "+
+							"security groups %v not created by nephe are attached", *networkInterface.NetworkInterfaceId,
+							groupCloudSgName, getSortedSgIDs(networkInterfaceForeignCloudSgsSet)))
+						continue
+					}
+					// in replace mode, sgs replaced by the first AT sg are recorded, to be restored along with the last AT sg.
+					if len(networkInterfaceCoexistingCloudSgsSet) == 0 && networkInterfaceOriginalCloudSgsSet == nil &&
+						len(networkInterfaceForeignCloudSgsSet) != 0 {
+						networkInterfacesToRecordOriginalSgs[*networkInterface.NetworkInterfaceId] = networkInterfaceOtherCloudSgsSet
+					}
+				}
 				networkInterfaceNepheControllerCreatedCloudSgsSet[*groupCloudSgID] = struct{}{}
 
 				networkInterfaceCloudSgsSetToAttach := networkInterfaceNepheControllerCreatedCloudSgsSet
+				for sgID := range networkInterfaceCoexistingCloudSgsSet {
+					networkInterfaceCloudSgsSetToAttach[sgID] = struct{}{}
+				}
 
 				// if network interface is not attached to AT sg and we processing attach of AG sg, keep all existing sgs. Also,
 				// if AG sg will be the only sg attached to network interface, attach default sg along with AG sg.
@@ -440,8 +545,155 @@ func (ec2Cfg *ec2ServiceConfig) updateSecurityGroupMembers(groupCloudSgID *strin
 		}
 	}
 
+	// network interfaces whose security groups would exceed the security groups per network interface quota are left
+	// unmodified.
+	quota := ec2Cfg.getSecurityGroupsPerNetworkInterfaceQuota()
+	for networkInterfaceID, sgIDSet := range networkInterfacesToModify {
+		if len(sgIDSet) > quota {
+			skippedNetworkInterfaceErr = multierr.Append(skippedNetworkInterfaceErr, fmt.Errorf("%w: network interface %v "+
+				"requires security groups %v, security groups per network interface quota is %v",
+				securitygroup.ErrQuotaExceeded, networkInterfaceID, getSortedSgIDs(sgIDSet), quota))
+			delete(networkInterfacesToModify, networkInterfaceID)
+			delete(networkInterfacesToRecordOriginalSgs, networkInterfaceID)
+			delete(networkInterfacesToRestoreOriginalSgs, networkInterfaceID)
+		}
+	}
+
+	// record original security groups before they are replaced.
+	for networkInterfaceID, sgIDSet := range networkInterfacesToRecordOriginalSgs {
+		if err := ec2Cfg.recordNetworkInterfaceOriginalSgs(networkInterfaceID, sgIDSet); err != nil {
+			// without a record, original security groups could not be restored.
+			skippedNetworkInterfaceErr = multierr.Append(skippedNetworkInterfaceErr, err)
+			delete(networkInterfacesToModify, networkInterfaceID)
+		}
+	}
+
+	// restore original security groups still present in the vpc, or attach default sg if none is.
+	if len(networkInterfacesToRestoreOriginalSgs) != 0 {
+		cloudSecurityGroups, err := ec2Cfg.getSecurityGroupsOfVpc(map[string]struct{}{vpcID: {}})
+		if err != nil {
+			return err
+		}
+		vpcSgIDSet := make(map[string]struct{})
+		for _, cloudSecurityGroup := range cloudSecurityGroups {
+			vpcSgIDSet[aws.StringValue(cloudSecurityGroup.GroupId)] = struct{}{}
+		}
+		for networkInterfaceID, sgIDSet := range networkInterfacesToRestoreOriginalSgs {
+			networkInterfaceCloudSgsSetToAttach := networkInterfacesToModify[networkInterfaceID]
+			isOriginalSgRestored := false
+			for sgID := range sgIDSet {
+				if _, found := vpcSgIDSet[sgID]; found {
+					networkInterfaceCloudSgsSetToAttach[sgID] = struct{}{}
+					isOriginalSgRestored = true
+				}
+			}
+			if !isOriginalSgRestored {
+				networkInterfaceCloudSgsSetToAttach[vpcDefaultSgID] = struct{}{}
+			}
+		}
+	}
+
 	// update network interface security groups
-	return ec2Cfg.processNetworkInterfaceModifyConcurrently(networkInterfacesToModify, vpcID)
+	err = ec2Cfg.processNetworkInterfaceModifyConcurrently(networkInterfacesToModify, vpcID)
+	if err == nil {
+		for networkInterfaceID, sgIDSet := range networkInterfacesToRestoreOriginalSgs {
+			err = multierr.Append(err, ec2Cfg.clearNetworkInterfaceOriginalSgs(networkInterfaceID, sgIDSet))
+		}
+	}
+	return multierr.Append(err, skippedNetworkInterfaceErr)
+}
+
+// getNetworkInterfaceOriginalSgs returns sgs recorded on a network interface before nephe replaced them, nil if not
+// recorded.
+func getNetworkInterfaceOriginalSgs(networkInterface *ec2.NetworkInterface) map[string]struct{} {
+	sgIDSet := make(map[string]struct{})
+	for _, tag := range networkInterface.TagSet {
+		if !isNetworkInterfaceOriginalSgsTagKey(aws.StringValue(tag.Key)) {
+			continue
+		}
+		for _, sgID := range strings.Split(aws.StringValue(tag.Value), awsNetworkInterfaceOriginalSgsSeparator) {
+			if len(sgID) != 0 {
+				sgIDSet[sgID] = struct{}{}
+			}
+		}
+	}
+	if len(sgIDSet) == 0 {
+		return nil
+	}
+	return sgIDSet
+}
+
+// isNetworkInterfaceOriginalSgsTagKey returns true if key is a network interface tag key recording original sgs.
+func isNetworkInterfaceOriginalSgsTagKey(key string) bool {
+	if key == awsNetworkInterfaceOriginalSgsTagKey {
+		return true
+	}
+	index := strings.TrimPrefix(key, awsNetworkInterfaceOriginalSgsTagKey+"-")
+	if index == key {
+		return false
+	}
+	_, err := strconv.Atoi(index)
+	return err == nil
+}
+
+// getNetworkInterfaceOriginalSgsTags returns network interface tags recording sgs, such that each tag value fits in
+// awsTagValueMaxLength.
+func getNetworkInterfaceOriginalSgsTags(sgIDSet map[string]struct{}) []*ec2.Tag {
+	var values []string
+	var value string
+	for _, sgID := range getSortedSgIDs(sgIDSet) {
+		if len(value) != 0 && len(value)+len(awsNetworkInterfaceOriginalSgsSeparator)+len(sgID) > awsTagValueMaxLength {
+			values = append(values, value)
+			value = ""
+		}
+		if len(value) != 0 {
+			value += awsNetworkInterfaceOriginalSgsSeparator
+		}
+		value += sgID
+	}
+	values = append(values, value)
+
+	tags := make([]*ec2.Tag, 0, len(values))
+	for i, value := range values {
+		key := awsNetworkInterfaceOriginalSgsTagKey
+		if i > 0 {
+			key = fmt.Sprintf("%v-%v", awsNetworkInterfaceOriginalSgsTagKey, i)
+		}
+		tags = append(tags, &ec2.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return tags
+}
+
+func (ec2Cfg *ec2ServiceConfig) recordNetworkInterfaceOriginalSgs(networkInterfaceID string, sgIDSet map[string]struct{}) error {
+	input := &ec2.CreateTagsInput{
+		Resources: []*string{aws.String(networkInterfaceID)},
+		Tags:      getNetworkInterfaceOriginalSgsTags(sgIDSet),
+	}
+	_, err := ec2Cfg.apiClient.createTags(input)
+	return err
+}
+
+// clearNetworkInterfaceOriginalSgs deletes network interface tags recording original sgs sgIDSet.
+func (ec2Cfg *ec2ServiceConfig) clearNetworkInterfaceOriginalSgs(networkInterfaceID string, sgIDSet map[string]struct{}) error {
+	var tags []*ec2.Tag
+	for _, tag := range getNetworkInterfaceOriginalSgsTags(sgIDSet) {
+		tags = append(tags, &ec2.Tag{Key: tag.Key})
+	}
+	input := &ec2.DeleteTagsInput{
+		Resources: []*string{aws.String(networkInterfaceID)},
+		Tags:      tags,
+	}
+	_, err := ec2Cfg.apiClient.deleteTags(input)
+	return err
+}
+
+func getSortedSgIDs(sgIDSet map[string]struct{}) []string {
+	sgIDs := make([]string, 0, len(sgIDSet))
+	for sgID := range sgIDSet {
+		sgIDs = append(sgIDs, sgID)
+	}
+	sort.Strings(sgIDs)
+	return sgIDs
 }
 
 func (ec2Cfg *ec2ServiceConfig) processNetworkInterfaceModifyConcurrently(networkInterfacesToModify map[string]map[string]struct{},
@@ -471,6 +723,28 @@ func (ec2Cfg *ec2ServiceConfig) processNetworkInterfaceModifyConcurrently(networ
 	return err
 }
 
+// getCoexistingCloudSgs returns sgs not created by nephe to keep attached to a network interface along with nephe
+// created sgs. Only in coexist mode sgs are kept, except vpc default sg, which is attached only in place of nephe
+// created AT sgs.
+func (ec2Cfg *ec2ServiceConfig) getCoexistingCloudSgs(networkInterfaceOtherCloudSgsSet map[string]struct{},
+	vpcDefaultSgID string) map[string]struct{} {
+	if ec2Cfg.securityGroupMode != v1alpha1.SecurityGroupModeCoexist {
+		return make(map[string]struct{})
+	}
+	return getForeignCloudSgs(networkInterfaceOtherCloudSgsSet, vpcDefaultSgID)
+}
+
+// getForeignCloudSgs returns sgs not created by nephe, except vpc default sg.
+func getForeignCloudSgs(networkInterfaceOtherCloudSgsSet map[string]struct{}, vpcDefaultSgID string) map[string]struct{} {
+	networkInterfaceForeignCloudSgsSet := make(map[string]struct{})
+	for sgID := range networkInterfaceOtherCloudSgsSet {
+		if sgID != vpcDefaultSgID {
+			networkInterfaceForeignCloudSgsSet[sgID] = struct{}{}
+		}
+	}
+	return networkInterfaceForeignCloudSgsSet
+}
+
 func buildEc2SgsToAttachForCaseMemberOnlySgWithNoATSgAttached(networkInterfaceNepheControllerCreatedCloudSgsSet map[string]struct{},
 	networkInterfaceOtherCloudSgsSet map[string]struct{}, vpcDefaultSgID string) map[string]struct{} {
 	networkInterfaceCloudSgsSet := make(map[string]struct{})
@@ -493,25 +767,46 @@ func buildEc2SgsToAttachForCaseMemberOnlySgWithNoATSgAttached(networkInterfaceNe
 	return networkInterfaceCloudSgsSet
 }
 
-func (ec2Cfg *ec2ServiceConfig) getNepheControllerManagedSecurityGroupsCloudView() []securitygroup.SynchronizationContent {
+// getNepheControllerManagedSecurityGroupsCloudView returns security groups created by nephe in vpcs of the account
+// inventory. It returns an error if the cloud view cannot be retrieved, such that callers do not mistake a failed
+// retrieval for security groups missing in the cloud.
+func (ec2Cfg *ec2ServiceConfig) getNepheControllerManagedSecurityGroupsCloudView() ([]securitygroup.SynchronizationContent,
+	error) {
 	vpcIDs := ec2Cfg.getCachedVpcIDs()
 	if len(vpcIDs) == 0 {
-		return []securitygroup.SynchronizationContent{}
+		return []securitygroup.SynchronizationContent{}, nil
 	}
 
 	// get all network interfaces for managed vpcs
 	networkInterfaces, err := ec2Cfg.getNetworkInterfacesOfVpc(vpcIDs)
 	if err != nil {
-		return []securitygroup.SynchronizationContent{}
+		return nil, err
 	}
 
 	// get all security groups for managed vpcs and build cloud-sg-id to sgObj map by sg managed/unmanaged type
 	cloudSecurityGroups, err := ec2Cfg.getSecurityGroupsOfVpc(vpcIDs)
 	if err != nil {
-		return []securitygroup.SynchronizationContent{}
+		return nil, err
 	}
 	managedSgIDToCloudSGObj, unmanagedSgIDToCloudSGObj := getCloudSecurityGroupsByType(cloudSecurityGroups)
 
+	// get ips of prefix lists referenced by managed security groups
+	var managedIPPermissions []*ec2.IpPermission
+	for _, cloudSgObj := range managedSgIDToCloudSGObj {
+		managedIPPermissions = append(managedIPPermissions, cloudSgObj.IpPermissions...)
+		managedIPPermissions = append(managedIPPermissions, cloudSgObj.IpPermissionsEgress...)
+	}
+	prefixListIDToIPs, prefixListIDToCloudService, err := ec2Cfg.getPrefixListsCloudView(managedIPPermissions)
+	if err != nil {
+		return nil, err
+	}
+
+	// get deny rules realized as network acl entries
+	denyIngressRules, denyEgressRules, err := ec2Cfg.getNetworkACLDenyRulesCloudView(vpcIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	// find all member network-interfaces-ids for managed cloud-security-groups
 	// also find all member network-interface-ids attached to non antrea+ sgs
 	managedSgIDToMemberCloudResourcesMap := make(map[string][]securitygroup.CloudResource)
@@ -526,7 +821,11 @@ func (ec2Cfg *ec2ServiceConfig) getNepheControllerManagedSecurityGroupsCloudView
 			sgID := *group.GroupId
 			_, isManagedSg := managedSgIDToCloudSGObj[*group.GroupId]
 			if !isManagedSg {
-				isAttachedToOtherSG = true
+				// in coexist mode, only vpc default sg is not expected along with nephe created sgs.
+				if ec2Cfg.securityGroupMode != v1alpha1.SecurityGroupModeCoexist ||
+					aws.StringValue(group.GroupName) == awsVpcDefaultSecurityGroupName {
+					isAttachedToOtherSG = true
+				}
 				continue
 			}
 			cloudResource := securitygroup.CloudResource{
@@ -568,8 +867,16 @@ func (ec2Cfg *ec2ServiceConfig) getNepheControllerManagedSecurityGroupsCloudView
 		}
 
 		// build ingress and egress rules
-		inRules := convertFromIPPermissionToIngressRule(cloudSgObj.IpPermissions, managedSgIDToCloudSGObj, unmanagedSgIDToCloudSGObj)
-		egRules := convertFromIPPermissionToEgressRule(cloudSgObj.IpPermissionsEgress, managedSgIDToCloudSGObj, unmanagedSgIDToCloudSGObj)
+		inRules := convertFromIPPermissionToIngressRule(mergeICMPv6IPPermissions(cloudSgObj.IpPermissions), managedSgIDToCloudSGObj,
+			unmanagedSgIDToCloudSGObj, prefixListIDToIPs, prefixListIDToCloudService)
+		egRules := convertFromIPPermissionToEgressRule(mergeICMPv6IPPermissions(cloudSgObj.IpPermissionsEgress), managedSgIDToCloudSGObj,
+			unmanagedSgIDToCloudSGObj,
+			prefixListIDToIPs, prefixListIDToCloudService)
+		if !isMembershipOnly {
+			sgID := securitygroup.CloudResourceID{Name: SgName, Vpc: vpcID}
+			inRules = append(inRules, denyIngressRules[sgID]...)
+			egRules = append(egRules, denyEgressRules[sgID]...)
+		}
 
 		// build sync object
 		groupSyncObj := securitygroup.SynchronizationContent{
@@ -587,7 +894,7 @@ func (ec2Cfg *ec2ServiceConfig) getNepheControllerManagedSecurityGroupsCloudView
 		enforcedSecurityCloudView = append(enforcedSecurityCloudView, groupSyncObj)
 	}
 
-	return enforcedSecurityCloudView
+	return enforcedSecurityCloudView, nil
 }
 
 func getCloudSecurityGroupsByType(cloudSecurityGroups []*ec2.SecurityGroup) (map[string]*ec2.SecurityGroup, map[string]*ec2.SecurityGroup) {
@@ -632,6 +939,72 @@ func getMemberNicCloudResourcesAttachedToOtherSGs(members []securitygroup.CloudR
 	return nicCloudResources
 }
 
+// getUnmanagedSecurityGroups returns security groups not created by nephe, attached to network interfaces of virtual
+// machines in cached vpcs, sorted by security group ID.
+func (ec2Cfg *ec2ServiceConfig) getUnmanagedSecurityGroups() ([]securitygroup.UnmanagedSecurityGroup, error) {
+	vpcIDs := ec2Cfg.getCachedVpcIDs()
+	if len(vpcIDs) == 0 {
+		return nil, nil
+	}
+	networkInterfaces, err := ec2Cfg.getNetworkInterfacesOfVpc(vpcIDs)
+	if err != nil {
+		return nil, err
+	}
+	cloudSecurityGroups, err := ec2Cfg.getSecurityGroupsOfVpc(vpcIDs)
+	if err != nil {
+		return nil, err
+	}
+	managedSgIDToCloudSGObj, unmanagedSgIDToCloudSGObj := getCloudSecurityGroupsByType(cloudSecurityGroups)
+
+	unmanagedSgIDToMemberCloudResourcesMap := make(map[string][]securitygroup.CloudResource)
+	for _, networkInterface := range networkInterfaces {
+		attachment := networkInterface.Attachment
+		if attachment == nil || attachment.InstanceId == nil {
+			continue
+		}
+		for _, group := range networkInterface.Groups {
+			sgID := aws.StringValue(group.GroupId)
+			if _, found := unmanagedSgIDToCloudSGObj[sgID]; !found {
+				continue
+			}
+			cloudResource := securitygroup.CloudResource{
+				Type: securitygroup.CloudResourceTypeNIC,
+				Name: securitygroup.CloudResourceID{
+					Name: aws.StringValue(networkInterface.NetworkInterfaceId),
+					Vpc:  aws.StringValue(networkInterface.VpcId),
+				},
+			}
+			unmanagedSgIDToMemberCloudResourcesMap[sgID] = append(unmanagedSgIDToMemberCloudResourcesMap[sgID], cloudResource)
+		}
+	}
+
+	unmanagedSGs := make([]securitygroup.UnmanagedSecurityGroup, 0, len(unmanagedSgIDToMemberCloudResourcesMap))
+	for sgID, members := range unmanagedSgIDToMemberCloudResourcesMap {
+		cloudSgObj := unmanagedSgIDToCloudSGObj[sgID]
+		sgName := aws.StringValue(cloudSgObj.GroupName)
+		inRules, inUnsupportedRules := convertFromUnmanagedIPPermissionToIngressRule(cloudSgObj.IpPermissions,
+			managedSgIDToCloudSGObj, unmanagedSgIDToCloudSGObj)
+		egRules, egUnsupportedRules := convertFromUnmanagedIPPermissionToEgressRule(cloudSgObj.IpPermissionsEgress,
+			managedSgIDToCloudSGObj, unmanagedSgIDToCloudSGObj)
+		unmanagedSGs = append(unmanagedSGs, securitygroup.UnmanagedSecurityGroup{
+			Resource: securitygroup.CloudResourceID{
+				Name: sgName,
+				Vpc:  aws.StringValue(cloudSgObj.VpcId),
+			},
+			CloudID:          sgID,
+			CloudName:        sgName,
+			Members:          members,
+			IngressRules:     inRules,
+			EgressRules:      egRules,
+			UnsupportedRules: append(inUnsupportedRules, egUnsupportedRules...),
+		})
+	}
+	sort.Slice(unmanagedSGs, func(i, j int) bool {
+		return unmanagedSGs[i].CloudID < unmanagedSGs[j].CloudID
+	})
+	return unmanagedSGs, nil
+}
+
 // ////////////////////////////////////////////////////////
 // 	SecurityInterface Implementation
 // ////////////////////////////////////////////////////////.
@@ -640,15 +1013,10 @@ func (c *awsCloud) CreateSecurityGroup(addressGroupIdentifier *securitygroup.Clo
 	defer mutex.Unlock()
 
 	vpcID := addressGroupIdentifier.Vpc
-	accCfg := c.getVpcAccount(vpcID)
-	if accCfg == nil {
+	_, ec2Service := c.getVpcAccount(vpcID)
+	if ec2Service == nil {
 		return nil, fmt.Errorf("aws account not found managing virtual private cloud [%v]", vpcID)
 	}
-	serviceCfg, err := accCfg.GetServiceConfigByName(awsComputeServiceNameEC2)
-	if err != nil {
-		return nil, err
-	}
-	ec2Service := serviceCfg.(*ec2ServiceConfig)
 
 	cloudSgName := addressGroupIdentifier.GetCloudName(membershipOnly)
 	resp, err := ec2Service.createOrGetSecurityGroups(addressGroupIdentifier.Vpc, map[string]struct{}{cloudSgName: {}})
@@ -666,16 +1034,16 @@ func (c *awsCloud) UpdateSecurityGroupRules(addressGroupIdentifier *securitygrou
 	defer mutex.Unlock()
 
 	vpcID := addressGroupIdentifier.Vpc
-	accCfg := c.getVpcAccount(addressGroupIdentifier.Vpc)
-	if accCfg == nil {
+	_, ec2Service := c.getVpcAccount(addressGroupIdentifier.Vpc)
+	if ec2Service == nil {
 		return fmt.Errorf("aws account not found managing virtual private cloud [%v]", vpcID)
 	}
+	// deny rules realized as network acl entries are checked against allow rules taking precedence over them.
+	networkACLIngressRules, networkACLEgressRules := getNetworkACLRules(ingressRules, egressRules)
 
-	serviceCfg, err := accCfg.GetServiceConfigByName(awsComputeServiceNameEC2)
-	if err != nil {
-		return err
-	}
-	ec2Service := serviceCfg.(*ec2ServiceConfig)
+	// security groups are not ordered, deny rules take precedence over allow rules irrespective of rule priorities.
+	ingressRules = securitygroup.CompactIngressRulesIgnoringPriority(ingressRules)
+	egressRules = securitygroup.CompactEgressRulesIgnoringPriority(egressRules)
 
 	// build from addressGroups, cloudSgNames from rules
 	cloudSgNames := buildEc2CloudSgNamesFromRules(addressGroupIdentifier, ingressRules, egressRules)
@@ -692,19 +1060,42 @@ func (c *awsCloud) UpdateSecurityGroupRules(addressGroupIdentifier *securitygrou
 		return fmt.Errorf("failed to find security groups")
 	}
 
-	// realize security group ingress and egress permissions
+	// resolve cloud services of rules to prefix lists.
+	cloudServicePrefixLists, err := ec2Service.getCloudServicePrefixLists(ingressRules, egressRules)
+	if err != nil {
+		return err
+	}
+
+	// realize security group ingress and egress permissions, security groups can only allow traffic.
+	allowIngressRules, _ := splitIngressRulesByAction(ingressRules)
+	allowEgressRules, _ := splitEgressRulesByAction(egressRules)
 	cloudSGObjToAddRules := cloudSGNameToCloudSGObj[addressGroupIdentifier.GetCloudName(false)]
-	err = ec2Service.realizeIngressIPPermissions(cloudSGObjToAddRules, ingressRules, cloudSGNameToCloudSGObj)
+	if err = ec2Service.checkSecurityGroupRuleQuota(cloudSGObjToAddRules, allowIngressRules, allowEgressRules,
+		cloudServicePrefixLists); err != nil {
+		return err
+	}
+	err = ec2Service.realizeIngressIPPermissions(cloudSGObjToAddRules, allowIngressRules, cloudSGNameToCloudSGObj,
+		cloudServicePrefixLists)
 	if err != nil {
 		return err
 	}
 
-	err = ec2Service.realizeEgressIPPermissions(cloudSGObjToAddRules, egressRules, cloudSGNameToCloudSGObj)
+	err = ec2Service.realizeEgressIPPermissions(cloudSGObjToAddRules, allowEgressRules, cloudSGNameToCloudSGObj,
+		cloudServicePrefixLists)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// network acl entries can not reference prefix lists, rules realized or checked as network acl entries refer to ips
+	// of prefix lists instead.
+	if networkACLIngressRules, networkACLEgressRules, err = ec2Service.resolveCloudServiceIPs(networkACLIngressRules,
+		networkACLEgressRules, cloudServicePrefixLists); err != nil {
+		return err
+	}
+
+	// realize deny rules as network acl entries.
+	return ec2Service.realizeNetworkACLDenyEntries(addressGroupIdentifier.GetCloudName(false), vpcID,
+		networkACLIngressRules, networkACLEgressRules, false)
 }
 
 func (c *awsCloud) UpdateSecurityGroupMembers(groupIdentifier *securitygroup.CloudResourceID,
@@ -713,17 +1104,11 @@ func (c *awsCloud) UpdateSecurityGroupMembers(groupIdentifier *securitygroup.Clo
 	defer mutex.Unlock()
 
 	vpcID := groupIdentifier.Vpc
-	accCfg := c.getVpcAccount(vpcID)
-	if accCfg == nil {
+	_, ec2Service := c.getVpcAccount(vpcID)
+	if ec2Service == nil {
 		return fmt.Errorf("aws account not found managing virtual private cloud [%v]", vpcID)
 	}
 
-	serviceCfg, err := accCfg.GetServiceConfigByName(awsComputeServiceNameEC2)
-	if err != nil {
-		return err
-	}
-	ec2Service := serviceCfg.(*ec2ServiceConfig)
-
 	groupCloudSgName := groupIdentifier.GetCloudName(membershipOnly)
 
 	// get addressGroup cloudSgID
@@ -744,6 +1129,10 @@ func (c *awsCloud) UpdateSecurityGroupMembers(groupIdentifier *securitygroup.Clo
 		return err
 	}
 
+	// remove deny rules from network acls of subnets no longer having appliedTo group members.
+	if !membershipOnly {
+		return ec2Service.realizeNetworkACLDenyEntries(groupCloudSgName, vpcID, nil, nil, true)
+	}
 	return nil
 }
 
@@ -752,17 +1141,11 @@ func (c *awsCloud) DeleteSecurityGroup(groupIdentifier *securitygroup.CloudResou
 	defer mutex.Unlock()
 
 	vpcID := groupIdentifier.Vpc
-	accCfg := c.getVpcAccount(vpcID)
-	if accCfg == nil {
+	_, ec2Service := c.getVpcAccount(vpcID)
+	if ec2Service == nil {
 		return fmt.Errorf("aws account not found managing virtual private cloud [%v]", vpcID)
 	}
 
-	serviceCfg, err := accCfg.GetServiceConfigByName(awsComputeServiceNameEC2)
-	if err != nil {
-		return err
-	}
-	ec2Service := serviceCfg.(*ec2ServiceConfig)
-
 	// check if sg exists in cloud and get its cloud sg id to delete
 	vpcIDs := []string{vpcID}
 	cloudSgNameToDelete := groupIdentifier.GetCloudName(membershipOnly)
@@ -780,6 +1163,14 @@ func (c *awsCloud) DeleteSecurityGroup(groupIdentifier *securitygroup.CloudResou
 		return err
 	}
 
+	// remove deny rules realized as network acl entries.
+	if !membershipOnly {
+		err = ec2Service.realizeNetworkACLDenyEntries(cloudSgNameToDelete, vpcID, nil, nil, false)
+		if err != nil {
+			return err
+		}
+	}
+
 	// delete security group
 	input := &ec2.DeleteSecurityGroupInput{
 		GroupId: cloudSgIDToDelete,
@@ -789,10 +1180,19 @@ func (c *awsCloud) DeleteSecurityGroup(groupIdentifier *securitygroup.CloudResou
 		return err
 	}
 
+	if !membershipOnly {
+		metrics.SecurityGroupRuleHeadroom.DeleteLabelValues(string(providerType), *cloudSgIDToDelete)
+	}
+
+	// delete prefix lists referenced by rules of deleted security group.
+	cloudSgObj := out[cloudSgNameToDelete]
+	if hasPrefixListIDs(cloudSgObj.IpPermissions) || hasPrefixListIDs(cloudSgObj.IpPermissionsEgress) {
+		return ec2Service.deleteOwnedPrefixLists(*cloudSgIDToDelete)
+	}
 	return nil
 }
 
-func (c *awsCloud) GetEnforcedSecurity() []securitygroup.SynchronizationContent {
+func (c *awsCloud) GetEnforcedSecurity() ([]securitygroup.SynchronizationContent, error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -805,6 +1205,8 @@ func (c *awsCloud) GetEnforcedSecurity() []securitygroup.SynchronizationContent
 	}
 
 	var enforcedSecurityCloudView []securitygroup.SynchronizationContent
+	var retErr error
+	var errMutex sync.Mutex
 	var wg sync.WaitGroup
 	ch := make(chan []securitygroup.SynchronizationContent)
 	wg.Add(len(accNamespacedNames))
@@ -828,18 +1230,30 @@ func (c *awsCloud) GetEnforcedSecurity() []securitygroup.SynchronizationContent
 				return
 			}
 
-			serviceCfg, err := accCfg.GetServiceConfigByName(awsComputeServiceNameEC2)
-			if err != nil {
-				awsPluginLogger().Error(err, "enforced-security-cloud-view GET for account skipped", "account", accCfg.GetNamespacedName())
-				return
+			var cloudView []securitygroup.SynchronizationContent
+			var err error
+			for _, ec2Service := range getEC2ServiceConfigs(accCfg) {
+				if e := ec2Service.waitForInventoryInit(inventoryInitWaitDuration); e != nil {
+					awsPluginLogger().Error(e, "enforced-security-cloud-view GET for account skipped", "account", accCfg.GetNamespacedName(),
+						"region", ec2Service.region)
+					err = multierr.Append(err, e)
+					continue
+				}
+				view, e := ec2Service.getNepheControllerManagedSecurityGroupsCloudView()
+				if e != nil {
+					awsPluginLogger().Error(e, "enforced-security-cloud-view GET for account failed", "account", accCfg.GetNamespacedName(),
+						"region", ec2Service.region)
+					err = multierr.Append(err, e)
+					continue
+				}
+				cloudView = append(cloudView, view...)
 			}
-			ec2Service := serviceCfg.(*ec2ServiceConfig)
-			err = ec2Service.waitForInventoryInit(inventoryInitWaitDuration)
 			if err != nil {
-				awsPluginLogger().Error(err, "enforced-security-cloud-view GET for account skipped", "account", accCfg.GetNamespacedName())
-				return
+				errMutex.Lock()
+				retErr = multierr.Append(retErr, fmt.Errorf("account %v: %w", name, err))
+				errMutex.Unlock()
 			}
-			sendCh <- ec2Service.getNepheControllerManagedSecurityGroupsCloudView()
+			sendCh <- cloudView
 		}(accNamespacedNameCopy, ch)
 	}
 
@@ -848,5 +1262,25 @@ func (c *awsCloud) GetEnforcedSecurity() []securitygroup.SynchronizationContent
 			enforcedSecurityCloudView = append(enforcedSecurityCloudView, val...)
 		}
 	}
-	return enforcedSecurityCloudView
+	return enforcedSecurityCloudView, retErr
+}
+
+// GetUnmanagedSecurityGroups returns security groups not created by nephe, attached to network interfaces of
+// virtual machines in vpcs of the account inventory.
+func (c *awsCloud) GetUnmanagedSecurityGroups(accNamespacedName *types.NamespacedName) ([]securitygroup.UnmanagedSecurityGroup, error) {
+	accCfg, found := c.cloudCommon.GetCloudAccountByName(accNamespacedName)
+	if !found {
+		return nil, fmt.Errorf("aws account %v not found", *accNamespacedName)
+	}
+
+	var unmanagedSGs []securitygroup.UnmanagedSecurityGroup
+	for _, ec2Service := range getEC2ServiceConfigs(accCfg) {
+		sgs, err := ec2Service.getUnmanagedSecurityGroups()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get security groups of account %v, region %v: %v", *accNamespacedName,
+				ec2Service.region, err)
+		}
+		unmanagedSGs = append(unmanagedSGs, sgs...)
+	}
+	return unmanagedSGs, nil
 }
@@ -15,8 +15,10 @@
 package aws
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
+	"net"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -24,11 +26,14 @@ import (
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
 	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 var _ = Describe("AWS Cloud Security", func() {
@@ -188,6 +193,933 @@ var _ = Describe("AWS Cloud Security", func() {
 			Expect(err).Should(BeNil())
 		})
 	})
+
+	Context("Port ranges", func() {
+		It("Should convert port ranges to and from ip permissions", func() {
+			tcp, port, endPort := 6, 8000, 8080
+			fromPort, toPort := convertToIPPermissionPort(&port, &endPort, &tcp)
+			Expect(*fromPort).To(Equal(int64(port)))
+			Expect(*toPort).To(Equal(int64(endPort)))
+			fromPort, toPort = convertToIPPermissionPort(&port, nil, &tcp)
+			Expect(*fromPort).To(Equal(int64(port)))
+			Expect(*toPort).To(Equal(int64(port)))
+
+			ingressRules := convertFromIPPermissionToIngressRule([]*ec2.IpPermission{
+				{IpProtocol: aws.String("6"), FromPort: aws.Int64(8000), ToPort: aws.Int64(8080)},
+				{IpProtocol: aws.String("6"), FromPort: aws.Int64(8000), ToPort: aws.Int64(8000)},
+				{IpProtocol: aws.String("6"), FromPort: aws.Int64(0), ToPort: aws.Int64(65535)},
+			}, nil, nil, nil, nil)
+			Expect(ingressRules).To(HaveLen(3))
+			Expect(*ingressRules[0].FromPort).To(Equal(port))
+			Expect(*ingressRules[0].FromEndPort).To(Equal(endPort))
+			Expect(*ingressRules[1].FromPort).To(Equal(port))
+			Expect(ingressRules[1].FromEndPort).To(BeNil())
+			Expect(ingressRules[2].FromPort).To(BeNil())
+			Expect(ingressRules[2].FromEndPort).To(BeNil())
+
+			entry := buildNetworkACLDenyEntry(true, &port, &endPort, &tcp, nil, nil, awsAnyIPv4Address)
+			Expect(entry.PortRange).To(Equal(&ec2.PortRange{From: aws.Int64(8000), To: aws.Int64(8080)}))
+		})
+	})
+
+	Context("IPv6", func() {
+		It("Should convert IPv6 ip blocks to and from ip permissions", func() {
+			_, ipv4Net, _ := net.ParseCIDR("10.0.0.0/16")
+			_, ipv6Net, _ := net.ParseCIDR("2600:1f14::/56")
+			ips := []*net.IPNet{ipv4Net, ipv6Net}
+			ipRanges := convertToEc2IpRanges(ips, false)
+			Expect(ipRanges).To(Equal([]*ec2.IpRange{{CidrIp: aws.String(ipv4Net.String())}}))
+			ipv6Ranges := convertToEc2Ipv6Ranges(ips, false)
+			Expect(ipv6Ranges).To(Equal([]*ec2.Ipv6Range{{CidrIpv6: aws.String(ipv6Net.String())}}))
+			Expect(convertToEc2IpRanges([]*net.IPNet{ipv6Net}, false)).To(BeEmpty())
+			// rules without peers allow all IPv4 and IPv6 addresses.
+			Expect(convertToEc2IpRanges(nil, false)).To(Equal([]*ec2.IpRange{{CidrIp: aws.String(awsAnyIPv4Address)}}))
+			Expect(convertToEc2Ipv6Ranges(nil, false)).To(Equal([]*ec2.Ipv6Range{{CidrIpv6: aws.String(awsAnyIPv6Address)}}))
+			Expect(convertToEc2Ipv6Ranges(nil, true)).To(BeEmpty())
+
+			egressRules := convertFromIPPermissionToEgressRule([]*ec2.IpPermission{
+				{IpProtocol: aws.String(awsAnyProtocolValue), IpRanges: ipRanges, Ipv6Ranges: ipv6Ranges},
+			}, nil, nil, nil, nil)
+			Expect(egressRules).To(HaveLen(1))
+			Expect(egressRules[0].ToDstIP).To(Equal(ips))
+		})
+
+		It("Should report IPv6 addresses of network interfaces", func() {
+			instance := &ec2.Instance{
+				InstanceId: aws.String("i-0123456789"),
+				VpcId:      aws.String(testVpcID01),
+				State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+					{
+						NetworkInterfaceId: aws.String("eni-0123456789"),
+						MacAddress:         aws.String("02:00:00:00:00:01"),
+						PrivateIpAddresses: []*ec2.InstancePrivateIpAddress{{PrivateIpAddress: aws.String("10.0.1.5")}},
+						Ipv6Addresses:      []*ec2.InstanceIpv6Address{{Ipv6Address: aws.String("2600:1f14::5")}},
+					},
+				},
+			}
+			vm := ec2InstanceToVirtualMachineCRD(instance, "default", "us-east-1")
+			Expect(vm.Status.NetworkInterfaces).To(HaveLen(1))
+			Expect(vm.Status.NetworkInterfaces[0].IPs).To(Equal([]v1alpha1.IPAddress{
+				{AddressType: v1alpha1.AddressTypeInternalIP, Address: "10.0.1.5"},
+				{AddressType: v1alpha1.AddressTypeInternalIP, Address: "2600:1f14::5"},
+			}))
+		})
+	})
+
+	Context("ICMP", func() {
+		It("Should convert ICMP type and code to and from ip permissions", func() {
+			icmp, icmpType, icmpCode := 1, 8, 0
+			fromPort, toPort := convertToIPPermissionICMPTypeCode(&icmpType, &icmpCode)
+			Expect(*fromPort).To(Equal(int64(icmpType)))
+			Expect(*toPort).To(Equal(int64(icmpCode)))
+			fromPort, toPort = convertToIPPermissionICMPTypeCode(&icmpType, nil)
+			Expect(*fromPort).To(Equal(int64(icmpType)))
+			Expect(*toPort).To(Equal(int64(-1)))
+
+			_, ipv4Net, _ := net.ParseCIDR("10.0.0.0/16")
+			_, ipv6Net, _ := net.ParseCIDR("2600:1f14::/56")
+			ipPermissions := splitICMPv6IPPermission(&ec2.IpPermission{
+				IpProtocol: aws.String("1"), FromPort: aws.Int64(8), ToPort: aws.Int64(0),
+				IpRanges:   convertToEc2IpRanges([]*net.IPNet{ipv4Net}, false),
+				Ipv6Ranges: convertToEc2Ipv6Ranges([]*net.IPNet{ipv6Net}, false),
+			})
+			Expect(ipPermissions).To(HaveLen(2))
+			Expect(ipPermissions[0].Ipv6Ranges).To(BeEmpty())
+			Expect(aws.StringValue(ipPermissions[1].IpProtocol)).To(Equal("58"))
+			Expect(ipPermissions[1].IpRanges).To(BeEmpty())
+
+			ingressRules := convertFromIPPermissionToIngressRule(mergeICMPv6IPPermissions([]*ec2.IpPermission{
+				{IpProtocol: aws.String("icmp"), FromPort: aws.Int64(8), ToPort: aws.Int64(0),
+					IpRanges: ipPermissions[0].IpRanges},
+				{IpProtocol: aws.String("icmpv6"), FromPort: aws.Int64(8), ToPort: aws.Int64(0),
+					Ipv6Ranges: ipPermissions[1].Ipv6Ranges},
+			}), nil, nil, nil, nil)
+			Expect(ingressRules).To(HaveLen(1))
+			Expect(*ingressRules[0].Protocol).To(Equal(icmp))
+			Expect(*ingressRules[0].ICMPType).To(Equal(icmpType))
+			Expect(*ingressRules[0].ICMPCode).To(Equal(icmpCode))
+			Expect(ingressRules[0].FromPort).To(BeNil())
+			Expect(ingressRules[0].FromSrcIP).To(Equal([]*net.IPNet{ipv4Net, ipv6Net}))
+		})
+
+		It("Should build ICMP network acl entries", func() {
+			icmp, icmpType := 1, 8
+			_, ipv6Net, _ := net.ParseCIDR("2600:1f14::/56")
+			entries := buildNetworkACLDenyEntries([]*securitygroup.IngressRule{
+				{Protocol: &icmp, ICMPType: &icmpType, FromSrcIP: []*net.IPNet{ipv6Net}, Action: securitygroup.RuleActionDeny},
+			}, nil, nil)
+			Expect(entries).To(Equal([]*ec2.NetworkAclEntry{
+				{Egress: aws.Bool(false), Protocol: aws.String("58"), RuleAction: aws.String(ec2.RuleActionDeny),
+					Ipv6CidrBlock: aws.String(ipv6Net.String()),
+					IcmpTypeCode:  &ec2.IcmpTypeCode{Type: aws.Int64(8), Code: aws.Int64(-1)}},
+			}))
+		})
+	})
+
+	Context("Deny rules", func() {
+		var (
+			tcp              = 6
+			port             = 22
+			testNetworkACLID = "acl-0a2b3c4d"
+			webGroupID       = &securitygroup.CloudResourceID{Name: "Web", Vpc: testVpcID01}
+			_, ipNet1, _     = net.ParseCIDR("1.1.1.0/24")
+			_, ipNet2, _     = net.ParseCIDR("2.2.2.0/24")
+		)
+
+		It("Should build network acl entries from deny rules", func() {
+			dbGroupID := &securitygroup.CloudResourceID{Name: "Db", Vpc: testVpcID01}
+			_, memberIPNet, _ := net.ParseCIDR("10.0.1.5/32")
+			ingressRules := []*securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionDeny},
+				{FromPort: &port, Protocol: &tcp, FromSecurityGroups: []*securitygroup.CloudResourceID{dbGroupID},
+					Action: securitygroup.RuleActionDeny},
+			}
+			egressRules := []*securitygroup.EgressRule{{Action: securitygroup.RuleActionDeny}}
+			entries := buildNetworkACLDenyEntries(ingressRules, egressRules,
+				map[string][]*net.IPNet{dbGroupID.GetCloudName(true): {memberIPNet}})
+			Expect(entries).To(Equal([]*ec2.NetworkAclEntry{
+				{Egress: aws.Bool(false), Protocol: aws.String("6"), RuleAction: aws.String(ec2.RuleActionDeny),
+					CidrBlock: aws.String(ipNet1.String()), PortRange: &ec2.PortRange{From: aws.Int64(22), To: aws.Int64(22)}},
+				{Egress: aws.Bool(false), Protocol: aws.String("6"), RuleAction: aws.String(ec2.RuleActionDeny),
+					CidrBlock: aws.String(memberIPNet.String()), PortRange: &ec2.PortRange{From: aws.Int64(22), To: aws.Int64(22)}},
+				{Egress: aws.Bool(true), Protocol: aws.String(awsAnyProtocolValue), RuleAction: aws.String(ec2.RuleActionDeny),
+					CidrBlock: aws.String(awsAnyIPv4Address)},
+				{Egress: aws.Bool(true), Protocol: aws.String(awsAnyProtocolValue), RuleAction: aws.String(ec2.RuleActionDeny),
+					Ipv6CidrBlock: aws.String(awsAnyIPv6Address)},
+			}))
+		})
+
+		It("Should update owned network acl entries only", func() {
+			networkACL := &ec2.NetworkAcl{
+				NetworkAclId: aws.String(testNetworkACLID),
+				VpcId:        aws.String(testVpcID01),
+				Entries: []*ec2.NetworkAclEntry{
+					{Egress: aws.Bool(false), RuleNumber: aws.Int64(1), Protocol: aws.String("6"),
+						RuleAction: aws.String(ec2.RuleActionDeny), CidrBlock: aws.String(ipNet2.String()),
+						PortRange: &ec2.PortRange{From: aws.Int64(22), To: aws.Int64(22)}},
+					{Egress: aws.Bool(false), RuleNumber: aws.Int64(2), Protocol: aws.String(awsAnyProtocolValue),
+						RuleAction: aws.String(ec2.RuleActionAllow), CidrBlock: aws.String(ipNet2.String())},
+				},
+				// user entry at a rule number recorded in tag is not owned.
+				Tags: []*ec2.Tag{{Key: aws.String(webGroupID.GetCloudName(false)), Value: aws.String("i1,i2")}},
+			}
+			ingressRules := []*securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionDeny},
+			}
+			entries := buildNetworkACLDenyEntries(ingressRules, nil, nil)
+
+			ec2Cfg := &ec2ServiceConfig{apiClient: mockawsEC2}
+			createTag := mockawsEC2.EXPECT().createTags(&ec2.CreateTagsInput{
+				Resources: []*string{aws.String(testNetworkACLID)},
+				Tags:      []*ec2.Tag{{Key: aws.String(webGroupID.GetCloudName(false)), Value: aws.String("i1,i3")}},
+			}).Return(&ec2.CreateTagsOutput{}, nil).Times(1)
+			createEntry := mockawsEC2.EXPECT().createNetworkACLEntry(gomock.Any()).After(createTag).
+				Do(func(input *ec2.CreateNetworkAclEntryInput) {
+					Expect(*input.RuleNumber).To(Equal(int64(3)))
+					Expect(*input.Egress).To(BeFalse())
+					Expect(*input.CidrBlock).To(Equal(ipNet1.String()))
+				}).Return(&ec2.CreateNetworkAclEntryOutput{}, nil).Times(1)
+			deleteEntry := mockawsEC2.EXPECT().deleteNetworkACLEntry(&ec2.DeleteNetworkAclEntryInput{
+				NetworkAclId: aws.String(testNetworkACLID),
+				RuleNumber:   aws.Int64(1),
+				Egress:       aws.Bool(false),
+			}).After(createEntry).Return(&ec2.DeleteNetworkAclEntryOutput{}, nil).Times(1)
+			mockawsEC2.EXPECT().createTags(&ec2.CreateTagsInput{
+				Resources: []*string{aws.String(testNetworkACLID)},
+				Tags:      []*ec2.Tag{{Key: aws.String(webGroupID.GetCloudName(false)), Value: aws.String("i3")}},
+			}).After(deleteEntry).Return(&ec2.CreateTagsOutput{}, nil).Times(1)
+
+			err := ec2Cfg.updateNetworkACLDenyEntries(networkACL, webGroupID.GetCloudName(false), entries)
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should not update network acl exceeding entries quota", func() {
+			networkACL := &ec2.NetworkAcl{
+				NetworkAclId: aws.String(testNetworkACLID),
+				VpcId:        aws.String(testVpcID01),
+				Entries: []*ec2.NetworkAclEntry{
+					{Egress: aws.Bool(false), RuleNumber: aws.Int64(awsNetworkACLDefaultRuleNumber),
+						Protocol: aws.String(awsAnyProtocolValue), RuleAction: aws.String(ec2.RuleActionDeny),
+						CidrBlock: aws.String(awsAnyIPv4Address)},
+					{Egress: aws.Bool(false), RuleNumber: aws.Int64(awsNetworkACLDefaultRuleNumber + 1),
+						Protocol: aws.String(awsAnyProtocolValue), RuleAction: aws.String(ec2.RuleActionDeny),
+						Ipv6CidrBlock: aws.String(awsAnyIPv6Address)},
+				},
+			}
+			for i := 0; i < awsDefaultEntriesPerNetworkACLQuota-1; i++ {
+				networkACL.Entries = append(networkACL.Entries, &ec2.NetworkAclEntry{Egress: aws.Bool(false),
+					RuleNumber: aws.Int64(int64(100 + i)), Protocol: aws.String(awsAnyProtocolValue),
+					RuleAction: aws.String(ec2.RuleActionAllow), CidrBlock: aws.String(ipNet2.String())})
+			}
+			ingressRules := []*securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1, ipNet2}, Action: securitygroup.RuleActionDeny},
+			}
+			entries := buildNetworkACLDenyEntries(ingressRules, nil, nil)
+
+			ec2Cfg := &ec2ServiceConfig{apiClient: mockawsEC2}
+			mockawsEC2.EXPECT().createTags(gomock.Any()).Times(0)
+			mockawsEC2.EXPECT().createNetworkACLEntry(gomock.Any()).Times(0)
+			err := ec2Cfg.updateNetworkACLDenyEntries(networkACL, webGroupID.GetCloudName(false), entries)
+			Expect(errors.Is(err, securitygroup.ErrQuotaExceeded)).To(BeTrue())
+		})
+
+		It("Should count network acl entries per address family against configured quota", func() {
+			networkACL := &ec2.NetworkAcl{NetworkAclId: aws.String(testNetworkACLID), VpcId: aws.String(testVpcID01)}
+			for i := 0; i < awsDefaultEntriesPerNetworkACLQuota-1; i++ {
+				networkACL.Entries = append(networkACL.Entries, &ec2.NetworkAclEntry{Egress: aws.Bool(false),
+					RuleNumber: aws.Int64(int64(100 + i)), Protocol: aws.String(awsAnyProtocolValue),
+					RuleAction: aws.String(ec2.RuleActionAllow), CidrBlock: aws.String(ipNet2.String())})
+			}
+			// a deny rule without peers requires one IPv4 and one IPv6 entry.
+			entries := buildNetworkACLDenyEntries([]*securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, Action: securitygroup.RuleActionDeny},
+			}, nil, nil)
+			Expect(checkNetworkACLEntryQuota(networkACL, entries, nil, awsDefaultEntriesPerNetworkACLQuota)).Should(BeNil())
+
+			entries = buildNetworkACLDenyEntries([]*securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1, ipNet2}, Action: securitygroup.RuleActionDeny},
+			}, nil, nil)
+			err := checkNetworkACLEntryQuota(networkACL, entries, nil, awsDefaultEntriesPerNetworkACLQuota)
+			Expect(errors.Is(err, securitygroup.ErrQuotaExceeded)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("21 IPv4 ingress entries"))
+
+			ec2Cfg := &ec2ServiceConfig{securityGroupQuotas: awsSecurityGroupQuotas{entriesPerNetworkACL: 40}}
+			Expect(checkNetworkACLEntryQuota(networkACL, entries, nil, ec2Cfg.getEntriesPerNetworkACLQuota())).Should(BeNil())
+		})
+
+		It("Should not realize deny entries overridden by user allow entries", func() {
+			networkACL := &ec2.NetworkAcl{
+				NetworkAclId: aws.String(testNetworkACLID),
+				VpcId:        aws.String(testVpcID01),
+				Entries: []*ec2.NetworkAclEntry{
+					{Egress: aws.Bool(false), RuleNumber: aws.Int64(1), Protocol: aws.String("6"),
+						RuleAction: aws.String(ec2.RuleActionAllow), CidrBlock: aws.String("1.1.0.0/16"),
+						PortRange: &ec2.PortRange{From: aws.Int64(20), To: aws.Int64(30)}},
+				},
+			}
+			ec2Cfg := &ec2ServiceConfig{apiClient: mockawsEC2}
+
+			// user allow entry of other ports does not override deny entry.
+			otherPort := 80
+			mockawsEC2.EXPECT().createTags(gomock.Any()).Return(&ec2.CreateTagsOutput{}, nil).Times(2)
+			mockawsEC2.EXPECT().createNetworkACLEntry(gomock.Any()).Return(&ec2.CreateNetworkAclEntryOutput{}, nil).Times(1)
+			entries := buildNetworkACLDenyEntries([]*securitygroup.IngressRule{
+				{FromPort: &otherPort, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionDeny},
+			}, nil, nil)
+			Expect(ec2Cfg.updateNetworkACLDenyEntries(networkACL, webGroupID.GetCloudName(false), entries)).Should(BeNil())
+
+			entries = buildNetworkACLDenyEntries([]*securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionDeny},
+			}, nil, nil)
+			err := ec2Cfg.updateNetworkACLDenyEntries(networkACL, webGroupID.GetCloudName(false), entries)
+			Expect(errors.Is(err, errUnsupportedDenyRule)).To(BeTrue())
+		})
+
+		It("Should reject deny rules network acls can not realize faithfully", func() {
+			dbGroupID := &securitygroup.CloudResourceID{Name: "Db", Vpc: testVpcID01}
+			_, memberIPNet, _ := net.ParseCIDR("10.0.1.5/32")
+			cloudSgNameToIPs := map[string][]*net.IPNet{dbGroupID.GetCloudName(true): {memberIPNet}}
+			_, emergencyIPNet, _ := net.ParseCIDR("1.1.1.5/32")
+			emergency := &securitygroup.RulePriority{TierPriority: 50, PolicyPriority: 1}
+			application := &securitygroup.RulePriority{TierPriority: 250, PolicyPriority: 1}
+			denyIngressRule := &securitygroup.IngressRule{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1},
+				Action: securitygroup.RuleActionDeny, Priority: application}
+			Expect(checkNetworkACLDenyRules([]*securitygroup.IngressRule{denyIngressRule}, nil, cloudSgNameToIPs, nil)).
+				Should(BeNil())
+
+			// allow rule of higher priority overlapping deny rule.
+			allowIngressRule := &securitygroup.IngressRule{FromPort: &port, Protocol: &tcp,
+				FromSrcIP: []*net.IPNet{emergencyIPNet}, Priority: emergency}
+			err := checkNetworkACLDenyRules([]*securitygroup.IngressRule{denyIngressRule, allowIngressRule}, nil,
+				cloudSgNameToIPs, nil)
+			Expect(errors.Is(err, errUnsupportedDenyRule)).To(BeTrue())
+			networkACLIngressRules, _ := getNetworkACLRules([]*securitygroup.IngressRule{denyIngressRule, allowIngressRule}, nil)
+			Expect(networkACLIngressRules).To(Equal([]*securitygroup.IngressRule{denyIngressRule, allowIngressRule}))
+			// allow rule of lower priority, or of other ports, is overridden by deny rule.
+			allowIngressRule.Priority = &securitygroup.RulePriority{TierPriority: 253}
+			Expect(checkNetworkACLDenyRules([]*securitygroup.IngressRule{denyIngressRule, allowIngressRule}, nil,
+				cloudSgNameToIPs, nil)).Should(BeNil())
+			networkACLIngressRules, _ = getNetworkACLRules([]*securitygroup.IngressRule{denyIngressRule, allowIngressRule}, nil)
+			Expect(networkACLIngressRules).To(Equal([]*securitygroup.IngressRule{denyIngressRule}))
+			otherPort := 80
+			allowIngressRule.Priority, allowIngressRule.FromPort = emergency, &otherPort
+			Expect(checkNetworkACLDenyRules([]*securitygroup.IngressRule{denyIngressRule, allowIngressRule}, nil,
+				cloudSgNameToIPs, nil)).Should(BeNil())
+
+			// egress deny rule without port drops replies.
+			denyEgressRule := &securitygroup.EgressRule{Protocol: &tcp, ToDstIP: []*net.IPNet{ipNet2},
+				Action: securitygroup.RuleActionDeny}
+			err = checkNetworkACLDenyRules(nil, []*securitygroup.EgressRule{denyEgressRule}, cloudSgNameToIPs, nil)
+			Expect(errors.Is(err, errUnsupportedDenyRule)).To(BeTrue())
+			denyEgressRule.ToPort = &port
+			Expect(checkNetworkACLDenyRules(nil, []*securitygroup.EgressRule{denyEgressRule}, cloudSgNameToIPs, nil)).
+				Should(BeNil())
+
+			// deny rule peers in subnets of appliedTo members.
+			denyIngressRule = &securitygroup.IngressRule{FromPort: &port, Protocol: &tcp,
+				FromSecurityGroups: []*securitygroup.CloudResourceID{dbGroupID}, Action: securitygroup.RuleActionDeny}
+			Expect(checkNetworkACLDenyRules([]*securitygroup.IngressRule{denyIngressRule}, nil, cloudSgNameToIPs, nil)).
+				Should(BeNil())
+			err = checkNetworkACLDenyRules([]*securitygroup.IngressRule{denyIngressRule}, nil, cloudSgNameToIPs,
+				[]*net.IPNet{memberIPNet})
+			Expect(errors.Is(err, errUnsupportedDenyRule)).To(BeTrue())
+		})
+
+		It("Should find peers in subnets of appliedTo members", func() {
+			newNetworkInterface := func(subnetID string, ip string, groupNames ...string) *ec2.NetworkInterface {
+				networkInterface := &ec2.NetworkInterface{VpcId: aws.String(testVpcID01), SubnetId: aws.String(subnetID),
+					PrivateIpAddresses: []*ec2.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String(ip)}}}
+				for _, groupName := range groupNames {
+					networkInterface.Groups = append(networkInterface.Groups, &ec2.GroupIdentifier{GroupName: aws.String(groupName)})
+				}
+				return networkInterface
+			}
+			webCloudSgName := webGroupID.GetCloudName(false)
+			networkInterfaces := []*ec2.NetworkInterface{
+				// a lone member is not a peer.
+				newNetworkInterface("subnet-01", "10.0.1.5", webCloudSgName),
+				newNetworkInterface("subnet-02", "10.0.2.5", webCloudSgName),
+				newNetworkInterface("subnet-02", "10.0.2.6", webCloudSgName),
+				newNetworkInterface("subnet-02", "10.0.2.7"),
+				newNetworkInterface("subnet-03", "10.0.3.5"),
+			}
+			memberSubnetIDs, subnetPeerIPs := getAppliedToSubnets(networkInterfaces, testVpcID01, webCloudSgName)
+			Expect(memberSubnetIDs).To(Equal(map[string]struct{}{"subnet-01": {}, "subnet-02": {}}))
+			var peers []string
+			for _, ip := range subnetPeerIPs {
+				peers = append(peers, ip.IP.String())
+			}
+			Expect(peers).To(ConsistOf("10.0.2.5", "10.0.2.6", "10.0.2.7"))
+		})
+
+		It("Should remove deny rules from network acls without appliedTo members", func() {
+			input := testAwsBuildDescribeSecurityGroupInput(webGroupID.Vpc,
+				map[string]struct{}{webGroupID.GetCloudName(false): {}})
+			mockawsEC2.EXPECT().describeSecurityGroups(gomock.Eq(input)).Return(constructEc2DescribeSecurityGroupsOutput(
+				webGroupID, false, false), nil).Times(1)
+			mockawsEC2.EXPECT().authorizeSecurityGroupIngress(gomock.Any()).Times(0)
+			mockawsEC2.EXPECT().pagedDescribeNetworkACLsWrapper(gomock.Any()).Return([]*ec2.NetworkAcl{
+				{
+					NetworkAclId: aws.String(testNetworkACLID),
+					VpcId:        aws.String(testVpcID01),
+					Entries: []*ec2.NetworkAclEntry{
+						{Egress: aws.Bool(true), RuleNumber: aws.Int64(1), Protocol: aws.String(awsAnyProtocolValue),
+							RuleAction: aws.String(ec2.RuleActionDeny), CidrBlock: aws.String(ipNet2.String())},
+					},
+					Tags: []*ec2.Tag{{Key: aws.String(webGroupID.GetCloudName(false)), Value: aws.String("e1")}},
+				},
+			}, nil).Times(1)
+			mockawsEC2.EXPECT().createTags(gomock.Any()).Times(0)
+			mockawsEC2.EXPECT().deleteNetworkACLEntry(&ec2.DeleteNetworkAclEntryInput{
+				NetworkAclId: aws.String(testNetworkACLID),
+				RuleNumber:   aws.Int64(1),
+				Egress:       aws.Bool(true),
+			}).Return(&ec2.DeleteNetworkAclEntryOutput{}, nil).Times(1)
+			mockawsEC2.EXPECT().deleteTags(&ec2.DeleteTagsInput{
+				Resources: []*string{aws.String(testNetworkACLID)},
+				Tags:      []*ec2.Tag{{Key: aws.String(webGroupID.GetCloudName(false))}},
+			}).Return(&ec2.DeleteTagsOutput{}, nil).Times(1)
+
+			egressRules := []*securitygroup.EgressRule{
+				{ToPort: &port, Protocol: &tcp, ToDstIP: []*net.IPNet{ipNet2}, Action: securitygroup.RuleActionDeny},
+			}
+			err := cloudInterface.UpdateSecurityGroupRules(webGroupID, nil, egressRules)
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should report deny rules from network acls", func() {
+			entries := []*ec2.NetworkAclEntry{
+				{Egress: aws.Bool(false), RuleNumber: aws.Int64(1), Protocol: aws.String("6"),
+					RuleAction: aws.String(ec2.RuleActionDeny), CidrBlock: aws.String(ipNet1.String()),
+					PortRange: &ec2.PortRange{From: aws.Int64(22), To: aws.Int64(22)}},
+				{Egress: aws.Bool(false), RuleNumber: aws.Int64(2), Protocol: aws.String("6"),
+					RuleAction: aws.String(ec2.RuleActionDeny), CidrBlock: aws.String(ipNet2.String()),
+					PortRange: &ec2.PortRange{From: aws.Int64(22), To: aws.Int64(22)}},
+				{Egress: aws.Bool(true), RuleNumber: aws.Int64(1), Protocol: aws.String(awsAnyProtocolValue),
+					RuleAction: aws.String(ec2.RuleActionDeny), CidrBlock: aws.String(awsAnyIPv4Address)},
+			}
+			tags := []*ec2.Tag{{Key: aws.String(webGroupID.GetCloudName(false)), Value: aws.String("e1,i1,i2")}}
+			mockawsEC2.EXPECT().pagedDescribeNetworkACLsWrapper(gomock.Any()).Return([]*ec2.NetworkAcl{
+				{NetworkAclId: aws.String(testNetworkACLID), VpcId: aws.String(testVpcID01), Entries: entries, Tags: tags},
+				{NetworkAclId: aws.String("acl-1a2b3c4d"), VpcId: aws.String(testVpcID01), Entries: entries, Tags: tags},
+			}, nil).Times(1)
+
+			ec2Cfg := &ec2ServiceConfig{apiClient: mockawsEC2}
+			ingressRules, egressRules, err := ec2Cfg.getNetworkACLDenyRulesCloudView(map[string]struct{}{testVpcID01: {}})
+			Expect(err).Should(BeNil())
+			id := securitygroup.CloudResourceID{Name: "web", Vpc: testVpcID01}
+			Expect(ingressRules[id]).To(Equal([]securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1, ipNet2}, Action: securitygroup.RuleActionDeny},
+			}))
+			Expect(egressRules[id]).To(Equal([]securitygroup.EgressRule{{Action: securitygroup.RuleActionDeny}}))
+		})
+	})
+
+	Context("Prefix lists", func() {
+		var (
+			tcp           = 6
+			sgID          = "sg-3333"
+			sgName        = "nephe-at-web"
+			_, ipNet1, _  = net.ParseCIDR("1.1.1.0/24")
+			_, ipNet2, _  = net.ParseCIDR("2.2.2.0/24")
+			_, ipNet3, _  = net.ParseCIDR("3.3.3.0/24")
+			_, ipNet4, _  = net.ParseCIDR("4.4.4.0/24")
+			_, ipNet5, _  = net.ParseCIDR("5.5.5.0/24")
+			_, ipv6Net, _ = net.ParseCIDR("2600:1f14::/56")
+
+			ec2Mock *MockawsEC2Wrapper
+			ec2Cfg  *ec2ServiceConfig
+			sgObj   *ec2.SecurityGroup
+		)
+
+		BeforeEach(func() {
+			ec2Mock = NewMockawsEC2Wrapper(mockCtrl)
+			ec2Cfg = &ec2ServiceConfig{apiClient: ec2Mock, prefixListThreshold: 3}
+			sgObj = &ec2.SecurityGroup{GroupId: aws.String(sgID), GroupName: aws.String(sgName)}
+		})
+
+		// expectPrefixLists returns owned prefix lists on lookup by tag, and prefix lists by ID completed at version 2 with
+		// maxEntries.
+		expectPrefixLists := func(maxEntries int64, owned ...*ec2.ManagedPrefixList) {
+			ec2Mock.EXPECT().pagedDescribeManagedPrefixLists(gomock.Any()).AnyTimes().DoAndReturn(
+				func(input *ec2.DescribeManagedPrefixListsInput) ([]*ec2.ManagedPrefixList, error) {
+					if len(input.PrefixListIds) == 0 {
+						Expect(input.Filters).To(Equal([]*ec2.Filter{{Name: aws.String("tag:" + awsPrefixListSgTagKey),
+							Values: []*string{aws.String(sgID)}}}))
+						return owned, nil
+					}
+					return []*ec2.ManagedPrefixList{{PrefixListId: input.PrefixListIds[0], Version: aws.Int64(2),
+						MaxEntries: aws.Int64(maxEntries), State: aws.String(ec2.PrefixListStateModifyComplete)}}, nil
+				})
+		}
+
+		It("Should realize large ip sets of rules as prefix lists", func() {
+			rules := []*securitygroup.IngressRule{
+				{Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1, ipNet2, ipNet3, ipNet1, ipv6Net}},
+				{Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet4}},
+			}
+			expectPrefixLists(3)
+			ec2Mock.EXPECT().createManagedPrefixList(gomock.Any()).Times(1).DoAndReturn(
+				func(input *ec2.CreateManagedPrefixListInput) (*ec2.CreateManagedPrefixListOutput, error) {
+					Expect(aws.StringValue(input.PrefixListName)).To(Equal(
+						sgName + "-ingress-" + getIngressRulePrefixListKey(rules[0]) + "-ipv4"))
+					Expect(aws.StringValue(input.AddressFamily)).To(Equal(awsPrefixListAddressFamilyIPv4))
+					Expect(aws.Int64Value(input.MaxEntries)).To(Equal(int64(3)))
+					Expect(input.Entries).To(Equal(buildAddPrefixListEntries(
+						[]string{ipNet1.String(), ipNet2.String(), ipNet3.String()})))
+					Expect(input.TagSpecifications[0].Tags).To(Equal([]*ec2.Tag{
+						{Key: aws.String(awsPrefixListSgTagKey), Value: aws.String(sgID)}}))
+					return &ec2.CreateManagedPrefixListOutput{PrefixList: &ec2.ManagedPrefixList{PrefixListId: aws.String("pl-1")}}, nil
+				})
+			ec2Mock.EXPECT().authorizeSecurityGroupIngress(gomock.Any()).Times(1).DoAndReturn(
+				func(input *ec2.AuthorizeSecurityGroupIngressInput) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+					Expect(input.IpPermissions).To(HaveLen(2))
+					Expect(input.IpPermissions[0].PrefixListIds).To(Equal([]*ec2.PrefixListId{{PrefixListId: aws.String("pl-1")}}))
+					Expect(input.IpPermissions[0].IpRanges).To(BeEmpty())
+					Expect(input.IpPermissions[0].Ipv6Ranges).To(Equal([]*ec2.Ipv6Range{{CidrIpv6: aws.String(ipv6Net.String())}}))
+					Expect(input.IpPermissions[1].PrefixListIds).To(BeEmpty())
+					Expect(input.IpPermissions[1].IpRanges).To(Equal([]*ec2.IpRange{{CidrIp: aws.String(ipNet4.String())}}))
+					return &ec2.AuthorizeSecurityGroupIngressOutput{}, nil
+				})
+
+			err := ec2Cfg.realizeIngressIPPermissions(sgObj, rules, nil, nil)
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should name prefix lists by rule content regardless of rule order", func() {
+			port := 22
+			rules := []*securitygroup.IngressRule{
+				{Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1, ipNet2, ipNet3}},
+				{Protocol: &tcp, FromPort: &port, FromSrcIP: []*net.IPNet{ipNet3, ipNet4, ipNet5}},
+				{Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet4, ipNet5, ipNet1}},
+			}
+			Expect(getIngressRulePrefixListKey(rules[0])).To(Equal(getIngressRulePrefixListKey(rules[2])))
+			Expect(getIngressRulePrefixListKey(rules[0])).ToNot(Equal(getIngressRulePrefixListKey(rules[1])))
+
+			ruleCIDRs := map[string][]string{
+				sgName + "-ingress-" + getIngressRulePrefixListKey(rules[0]) + "-ipv4": {ipNet1.String(), ipNet2.String(),
+					ipNet3.String(), ipNet4.String(), ipNet5.String()},
+				sgName + "-ingress-" + getIngressRulePrefixListKey(rules[1]) + "-ipv4": {ipNet3.String(), ipNet4.String(),
+					ipNet5.String()},
+			}
+			var owned []*ec2.ManagedPrefixList
+			ec2Mock.EXPECT().pagedDescribeManagedPrefixLists(gomock.Any()).AnyTimes().DoAndReturn(
+				func(input *ec2.DescribeManagedPrefixListsInput) ([]*ec2.ManagedPrefixList, error) {
+					if len(input.PrefixListIds) == 0 {
+						return owned, nil
+					}
+					name := aws.StringValue(input.PrefixListIds[0])
+					return []*ec2.ManagedPrefixList{{PrefixListId: input.PrefixListIds[0], Version: aws.Int64(2),
+						MaxEntries: aws.Int64(int64(len(ruleCIDRs[name]))), State: aws.String(ec2.PrefixListStateCreateComplete)}}, nil
+				})
+			ec2Mock.EXPECT().pagedGetManagedPrefixListEntries(gomock.Any()).AnyTimes().DoAndReturn(
+				func(input *ec2.GetManagedPrefixListEntriesInput) ([]*ec2.PrefixListEntry, error) {
+					var entries []*ec2.PrefixListEntry
+					for _, cidr := range ruleCIDRs[aws.StringValue(input.PrefixListId)] {
+						entries = append(entries, &ec2.PrefixListEntry{Cidr: aws.String(cidr)})
+					}
+					return entries, nil
+				})
+			// rules only differing in ips share the prefix list of the union of their ips.
+			ec2Mock.EXPECT().createManagedPrefixList(gomock.Any()).Times(2).DoAndReturn(
+				func(input *ec2.CreateManagedPrefixListInput) (*ec2.CreateManagedPrefixListOutput, error) {
+					cidrs, found := ruleCIDRs[aws.StringValue(input.PrefixListName)]
+					Expect(found).To(BeTrue())
+					Expect(input.Entries).To(ConsistOf(buildAddPrefixListEntries(cidrs)))
+					owned = append(owned, &ec2.ManagedPrefixList{PrefixListId: input.PrefixListName,
+						PrefixListName: input.PrefixListName, MaxEntries: input.MaxEntries, Version: aws.Int64(2)})
+					return &ec2.CreateManagedPrefixListOutput{PrefixList: &ec2.ManagedPrefixList{
+						PrefixListId: input.PrefixListName}}, nil
+				})
+
+			// reordered rules reference the same prefix lists, which are neither created nor modified again.
+			for _, order := range [][]int{{0, 1, 2}, {2, 1, 0}} {
+				var rulesIPs [][]*net.IPNet
+				var rulesKeys []string
+				for _, i := range order {
+					rulesIPs = append(rulesIPs, rules[i].FromSrcIP)
+					rulesKeys = append(rulesKeys, getIngressRulePrefixListKey(rules[i]))
+				}
+				rulesPrefixLists, _, unusedPrefixLists, err := ec2Cfg.realizeRulePrefixLists(sgObj,
+					awsPrefixListDirectionIngress, nil, rulesIPs, rulesKeys)
+				Expect(err).Should(BeNil())
+				Expect(unusedPrefixLists).To(BeEmpty())
+				for i, ruleIndex := range order {
+					Expect(rulesPrefixLists[i]).To(Equal([]*ec2.PrefixListId{{PrefixListId: aws.String(
+						sgName + "-ingress-" + getIngressRulePrefixListKey(rules[ruleIndex]) + "-ipv4")}}))
+				}
+			}
+		})
+
+		It("Should update prefix lists in place and delete unused prefix lists", func() {
+			rule := &securitygroup.EgressRule{Protocol: &tcp, ToDstIP: []*net.IPNet{ipNet1, ipNet3, ipNet4, ipNet5, ipv6Net}}
+			ruleKey := getEgressRulePrefixListKey(rule)
+			ruleList := &ec2.ManagedPrefixList{PrefixListId: aws.String("pl-1"),
+				PrefixListName: aws.String(sgName + "-egress-" + ruleKey + "-ipv4"), MaxEntries: aws.Int64(3), Version: aws.Int64(1)}
+			unusedList := &ec2.ManagedPrefixList{PrefixListId: aws.String("pl-2"), PrefixListName: aws.String(sgName + "-egress-0-ipv4")}
+			ingressList := &ec2.ManagedPrefixList{PrefixListId: aws.String("pl-3"),
+				PrefixListName: aws.String(sgName + "-ingress-" + ruleKey + "-ipv4")}
+			expectPrefixLists(4, ruleList, unusedList, ingressList)
+			sgObj.IpPermissionsEgress = []*ec2.IpPermission{{IpProtocol: aws.String("6"),
+				PrefixListIds: []*ec2.PrefixListId{{PrefixListId: aws.String("pl-1")}, {PrefixListId: aws.String("pl-2")}}}}
+
+			ec2Mock.EXPECT().pagedGetManagedPrefixListEntries(&ec2.GetManagedPrefixListEntriesInput{PrefixListId: aws.String("pl-1")}).
+				Return([]*ec2.PrefixListEntry{{Cidr: aws.String(ipNet1.String())}, {Cidr: aws.String(ipNet2.String())},
+					{Cidr: aws.String(ipNet3.String())}}, nil).Times(1)
+			// max entries is increased before entries are added.
+			gomock.InOrder(
+				ec2Mock.EXPECT().modifyManagedPrefixList(&ec2.ModifyManagedPrefixListInput{PrefixListId: aws.String("pl-1"),
+					MaxEntries: aws.Int64(4)}).Return(&ec2.ModifyManagedPrefixListOutput{}, nil).Times(1),
+				ec2Mock.EXPECT().modifyManagedPrefixList(&ec2.ModifyManagedPrefixListInput{PrefixListId: aws.String("pl-1"),
+					CurrentVersion: aws.Int64(2), AddEntries: buildAddPrefixListEntries([]string{ipNet4.String(), ipNet5.String()}),
+					RemoveEntries: buildRemovePrefixListEntries([]string{ipNet2.String()})}).
+					Return(&ec2.ModifyManagedPrefixListOutput{}, nil).Times(1),
+			)
+			ec2Mock.EXPECT().revokeSecurityGroupEgress(gomock.Any()).Return(&ec2.RevokeSecurityGroupEgressOutput{}, nil).Times(1)
+			ec2Mock.EXPECT().authorizeSecurityGroupEgress(gomock.Any()).Return(&ec2.AuthorizeSecurityGroupEgressOutput{}, nil).Times(1)
+			ec2Mock.EXPECT().deleteManagedPrefixList(&ec2.DeleteManagedPrefixListInput{PrefixListId: aws.String("pl-2")}).
+				Return(&ec2.DeleteManagedPrefixListOutput{}, nil).Times(1)
+
+			err := ec2Cfg.realizeEgressIPPermissions(sgObj, []*securitygroup.EgressRule{rule}, nil, nil)
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should report ips of prefix lists referenced by rules", func() {
+			ec2Mock.EXPECT().pagedDescribeManagedPrefixLists(&ec2.DescribeManagedPrefixListsInput{
+				Filters: []*ec2.Filter{{Name: aws.String("tag-key"), Values: []*string{aws.String(awsPrefixListSgTagKey)}}},
+			}).Return([]*ec2.ManagedPrefixList{{PrefixListId: aws.String("pl-1")}, {PrefixListId: aws.String("pl-2")}}, nil).Times(1)
+			ec2Mock.EXPECT().pagedGetManagedPrefixListEntries(&ec2.GetManagedPrefixListEntriesInput{PrefixListId: aws.String("pl-1")}).
+				Return([]*ec2.PrefixListEntry{{Cidr: aws.String(ipNet1.String())}, {Cidr: aws.String(ipNet2.String())}}, nil).Times(1)
+
+			ipPermissions := []*ec2.IpPermission{{IpProtocol: aws.String("6"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String(ipNet3.String())}},
+				PrefixListIds: []*ec2.PrefixListId{{PrefixListId: aws.String("pl-1")}}}}
+			prefixListIDToIPs, prefixListIDToCloudService, err := ec2Cfg.getPrefixListsCloudView(ipPermissions)
+			Expect(err).Should(BeNil())
+			ingressRules := convertFromIPPermissionToIngressRule(ipPermissions, nil, nil, prefixListIDToIPs, prefixListIDToCloudService)
+			Expect(ingressRules).To(HaveLen(1))
+			Expect(ingressRules[0].FromSrcIP).To(Equal([]*net.IPNet{ipNet3, ipNet1, ipNet2}))
+		})
+
+		It("Should reference prefix lists of cloud services", func() {
+			s3Name := "com.amazonaws.us-west-2.s3"
+			s3List := &ec2.ManagedPrefixList{PrefixListId: aws.String("pl-8"), PrefixListName: aws.String(s3Name),
+				OwnerId: aws.String(awsPrefixListOwnerAWS), AddressFamily: aws.String(awsPrefixListAddressFamilyIPv4),
+				MaxEntries: aws.Int64(10)}
+			customerList := &ec2.ManagedPrefixList{PrefixListId: aws.String("pl-9"), PrefixListName: aws.String("partners"),
+				OwnerId: aws.String("123456789012"), AddressFamily: aws.String(awsPrefixListAddressFamilyIPv4),
+				MaxEntries: aws.Int64(5)}
+			ec2Mock.EXPECT().pagedDescribeManagedPrefixLists(gomock.Any()).AnyTimes().DoAndReturn(
+				func(input *ec2.DescribeManagedPrefixListsInput) ([]*ec2.ManagedPrefixList, error) {
+					var prefixLists []*ec2.ManagedPrefixList
+					for _, prefixList := range []*ec2.ManagedPrefixList{s3List, customerList} {
+						for _, id := range input.PrefixListIds {
+							if aws.StringValue(id) == aws.StringValue(prefixList.PrefixListId) {
+								prefixLists = append(prefixLists, prefixList)
+							}
+						}
+						for _, filter := range input.Filters {
+							if aws.StringValue(filter.Name) == "prefix-list-name" &&
+								aws.StringValue(filter.Values[0]) == aws.StringValue(prefixList.PrefixListName) {
+								prefixLists = append(prefixLists, prefixList)
+							}
+						}
+					}
+					return prefixLists, nil
+				})
+			ec2Mock.EXPECT().pagedGetManagedPrefixListEntries(&ec2.GetManagedPrefixListEntriesInput{PrefixListId: aws.String("pl-8")}).
+				Return([]*ec2.PrefixListEntry{{Cidr: aws.String(ipNet1.String())}}, nil).Times(1)
+			ec2Mock.EXPECT().authorizeSecurityGroupEgress(gomock.Any()).Times(1).DoAndReturn(
+				func(input *ec2.AuthorizeSecurityGroupEgressInput) (*ec2.AuthorizeSecurityGroupEgressOutput, error) {
+					Expect(input.IpPermissions).To(HaveLen(1))
+					Expect(input.IpPermissions[0].PrefixListIds).To(Equal([]*ec2.PrefixListId{{PrefixListId: aws.String("pl-8")},
+						{PrefixListId: aws.String("pl-9")}}))
+					Expect(input.IpPermissions[0].IpRanges).To(BeEmpty())
+					return &ec2.AuthorizeSecurityGroupEgressOutput{}, nil
+				})
+
+			_, err := ec2Cfg.getCloudServicePrefixLists(nil, []*securitygroup.EgressRule{{ToCloudServices: []string{"unknown"}}})
+			Expect(err).Should(HaveOccurred())
+			egressRules := []*securitygroup.EgressRule{{Protocol: &tcp, ToCloudServices: []string{s3Name, "pl-9"}}}
+			denyIngressRules := []*securitygroup.IngressRule{{Protocol: &tcp, FromCloudServices: []string{s3Name},
+				Action: securitygroup.RuleActionDeny}}
+			cloudServicePrefixLists, err := ec2Cfg.getCloudServicePrefixLists(denyIngressRules, egressRules)
+			Expect(err).Should(BeNil())
+			Expect(cloudServicePrefixLists).To(Equal(map[string]*ec2.ManagedPrefixList{s3Name: s3List, "pl-9": customerList}))
+
+			// prefix lists count max entries against rule quota.
+			ec2Cfg.securityGroupQuotas.rulesPerSecurityGroup = 14
+			err = ec2Cfg.checkSecurityGroupRuleQuota(sgObj, nil, egressRules, cloudServicePrefixLists)
+			Expect(errors.Is(err, securitygroup.ErrQuotaExceeded)).To(BeTrue())
+			ec2Cfg.securityGroupQuotas.rulesPerSecurityGroup = 15
+			Expect(ec2Cfg.checkSecurityGroupRuleQuota(sgObj, nil, egressRules, cloudServicePrefixLists)).Should(BeNil())
+
+			Expect(ec2Cfg.realizeEgressIPPermissions(sgObj, egressRules, nil, cloudServicePrefixLists)).Should(BeNil())
+
+			// deny rules refer to ips of prefix lists.
+			resolvedIngressRules, _, err := ec2Cfg.resolveCloudServiceIPs(denyIngressRules, nil, cloudServicePrefixLists)
+			Expect(err).Should(BeNil())
+			Expect(resolvedIngressRules).To(Equal([]*securitygroup.IngressRule{{Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1},
+				Action: securitygroup.RuleActionDeny}}))
+
+			// aws-managed prefix lists are reported by name, other prefix lists by ID.
+			ipPermissions := []*ec2.IpPermission{{IpProtocol: aws.String("6"),
+				PrefixListIds: []*ec2.PrefixListId{{PrefixListId: aws.String("pl-8")}, {PrefixListId: aws.String("pl-9")}}}}
+			prefixListIDToIPs, prefixListIDToCloudService, err := ec2Cfg.getPrefixListsCloudView(ipPermissions)
+			Expect(err).Should(BeNil())
+			cloudEgressRules := convertFromIPPermissionToEgressRule(ipPermissions, nil, nil, prefixListIDToIPs, prefixListIDToCloudService)
+			Expect(cloudEgressRules).To(HaveLen(1))
+			Expect(cloudEgressRules[0].ToCloudServices).To(Equal([]string{s3Name, "pl-9"}))
+			Expect(cloudEgressRules[0].ToDstIP).To(BeEmpty())
+		})
+	})
+
+	Context("Unmanaged security groups", func() {
+		var (
+			tcp          = 6
+			port         = 443
+			defaultSgID  = "sg-0000"
+			webSgID      = "sg-1111"
+			dbSgID       = "sg-2222"
+			atSgID       = "sg-3333"
+			_, ipNet1, _ = net.ParseCIDR("1.1.1.0/24")
+		)
+
+		It("Should return security groups not created by nephe with members and rules", func() {
+			ec2Mock := NewMockawsEC2Wrapper(mockCtrl)
+			ec2Cfg := &ec2ServiceConfig{apiClient: ec2Mock, resourcesCache: &internal.CloudServiceResourcesCache{}}
+			ec2Cfg.resourcesCache.UpdateSnapshot(&ec2ResourcesCacheSnapshot{vpcIDs: map[string]struct{}{testVpcID01: {}}})
+
+			ec2Mock.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return([]*ec2.NetworkInterface{
+				{NetworkInterfaceId: aws.String("eni-1"), VpcId: aws.String(testVpcID01),
+					Attachment: &ec2.NetworkInterfaceAttachment{InstanceId: aws.String(testVMID01)},
+					Groups:     []*ec2.GroupIdentifier{{GroupId: aws.String(webSgID)}, {GroupId: aws.String(atSgID)}}},
+				{NetworkInterfaceId: aws.String("eni-2"), VpcId: aws.String(testVpcID01),
+					Attachment: &ec2.NetworkInterfaceAttachment{InstanceId: aws.String(testVMID02)},
+					Groups:     []*ec2.GroupIdentifier{{GroupId: aws.String(dbSgID)}}},
+				// network interfaces not attached to instances are ignored.
+				{NetworkInterfaceId: aws.String("eni-3"), VpcId: aws.String(testVpcID01),
+					Groups: []*ec2.GroupIdentifier{{GroupId: aws.String(defaultSgID)}}},
+			}, nil).Times(1)
+			ec2Mock.EXPECT().describeSecurityGroups(gomock.Any()).Return(&ec2.DescribeSecurityGroupsOutput{
+				SecurityGroups: []*ec2.SecurityGroup{
+					{GroupId: aws.String(defaultSgID), GroupName: aws.String(awsVpcDefaultSecurityGroupName), VpcId: aws.String(testVpcID01)},
+					{GroupId: aws.String(webSgID), GroupName: aws.String("web"), VpcId: aws.String(testVpcID01),
+						IpPermissions: []*ec2.IpPermission{
+							{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(443), ToPort: aws.Int64(443),
+								IpRanges: []*ec2.IpRange{{CidrIp: aws.String(ipNet1.String())}}},
+							{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(443), ToPort: aws.Int64(443),
+								PrefixListIds:    []*ec2.PrefixListId{{PrefixListId: aws.String("pl-1")}},
+								UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String(dbSgID)}}},
+						}},
+					{GroupId: aws.String(dbSgID), GroupName: aws.String("db"), VpcId: aws.String(testVpcID01)},
+					{GroupId: aws.String(atSgID), GroupName: aws.String("nephe-at-web"), VpcId: aws.String(testVpcID01)},
+				},
+			}, nil).Times(1)
+
+			sgs, err := ec2Cfg.getUnmanagedSecurityGroups()
+			Expect(err).Should(BeNil())
+			Expect(sgs).To(HaveLen(2))
+			Expect(sgs[0].CloudID).To(Equal(webSgID))
+			Expect(sgs[0].Resource).To(Equal(securitygroup.CloudResourceID{Name: "web", Vpc: testVpcID01}))
+			Expect(sgs[0].Members).To(Equal([]securitygroup.CloudResource{{Type: securitygroup.CloudResourceTypeNIC,
+				Name: securitygroup.CloudResourceID{Name: "eni-1", Vpc: testVpcID01}}}))
+			Expect(sgs[0].IngressRules).To(Equal([]securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}},
+				{FromPort: &port, Protocol: &tcp, FromSecurityGroups: []*securitygroup.CloudResourceID{{Name: "db", Vpc: testVpcID01}}},
+			}))
+			Expect(sgs[0].UnsupportedRules).To(Equal([]string{
+				"ingress rule protocol tcp ports 443-443: unsupported peers prefix list pl-1"}))
+			Expect(sgs[1].CloudID).To(Equal(dbSgID))
+			Expect(sgs[1].Members).To(HaveLen(1))
+		})
+
+		It("Should keep security groups not created by nephe in coexist mode only", func() {
+			others := map[string]struct{}{defaultSgID: {}, webSgID: {}}
+			ec2Cfg := &ec2ServiceConfig{}
+			Expect(ec2Cfg.getCoexistingCloudSgs(others, defaultSgID)).To(BeEmpty())
+			ec2Cfg.securityGroupMode = v1alpha1.SecurityGroupModeCoexist
+			Expect(ec2Cfg.getCoexistingCloudSgs(others, defaultSgID)).To(Equal(map[string]struct{}{webSgID: {}}))
+		})
+	})
+
+	Context("Security groups replaced on network interfaces", func() {
+		var (
+			defaultSgID = "sg-0000"
+			webSgID     = "sg-1111"
+			atSgID      = "sg-3333"
+			atSgName    = "nephe-at-web"
+
+			ec2Mock *MockawsEC2Wrapper
+			ec2Cfg  *ec2ServiceConfig
+			members []*securitygroup.CloudResource
+		)
+
+		BeforeEach(func() {
+			ec2Mock = NewMockawsEC2Wrapper(mockCtrl)
+			ec2Cfg = &ec2ServiceConfig{apiClient: ec2Mock}
+			members = []*securitygroup.CloudResource{{Type: securitygroup.CloudResourceTypeVM,
+				Name: securitygroup.CloudResourceID{Name: testVMID01, Vpc: testVpcID01}}}
+			vpcIDToDefaultSecurityGroup[testVpcID01] = defaultSgID
+		})
+
+		AfterEach(func() {
+			delete(vpcIDToDefaultSecurityGroup, testVpcID01)
+		})
+
+		getNetworkInterface := func(tags []*ec2.Tag, groups ...*ec2.GroupIdentifier) *ec2.NetworkInterface {
+			return &ec2.NetworkInterface{NetworkInterfaceId: aws.String("eni-1"), VpcId: aws.String(testVpcID01),
+				Attachment: &ec2.NetworkInterfaceAttachment{InstanceId: aws.String(testVMID01)},
+				Groups:     groups, TagSet: tags}
+		}
+		expectSgsAttached := func(sgIDs ...string) {
+			ec2Mock.EXPECT().modifyNetworkInterfaceAttribute(gomock.Any()).Times(1).DoAndReturn(
+				func(input *ec2.ModifyNetworkInterfaceAttributeInput) (*ec2.ModifyNetworkInterfaceAttributeOutput, error) {
+					Expect(aws.StringValue(input.NetworkInterfaceId)).To(Equal("eni-1"))
+					Expect(aws.StringValueSlice(input.Groups)).To(ConsistOf(sgIDs))
+					return &ec2.ModifyNetworkInterfaceAttributeOutput{}, nil
+				})
+		}
+		webSg := &ec2.GroupIdentifier{GroupId: aws.String(webSgID), GroupName: aws.String("web")}
+		defaultSg := &ec2.GroupIdentifier{GroupId: aws.String(defaultSgID), GroupName: aws.String(awsVpcDefaultSecurityGroupName)}
+		atSg := &ec2.GroupIdentifier{GroupId: aws.String(atSgID), GroupName: aws.String(atSgName)}
+		originalSgsTag := &ec2.Tag{Key: aws.String(awsNetworkInterfaceOriginalSgsTagKey),
+			Value: aws.String(defaultSgID + "," + webSgID)}
+
+		It("Should record security groups replaced by the first AT security group", func() {
+			ec2Mock.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return(
+				[]*ec2.NetworkInterface{getNetworkInterface(nil, webSg, defaultSg)}, nil).Times(1)
+			ec2Mock.EXPECT().createTags(&ec2.CreateTagsInput{Resources: []*string{aws.String("eni-1")},
+				Tags: []*ec2.Tag{originalSgsTag}}).Return(&ec2.CreateTagsOutput{}, nil).Times(1)
+			expectSgsAttached(atSgID)
+
+			err := ec2Cfg.updateSecurityGroupMembers(&atSgID, atSgName, testVpcID01, members, false)
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should restore recorded security groups with the last AT security group removed", func() {
+			ec2Mock.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return(
+				[]*ec2.NetworkInterface{getNetworkInterface([]*ec2.Tag{originalSgsTag}, atSg)}, nil).Times(1)
+			// web sg still exists in vpc, original default sg is restored along with it.
+			ec2Mock.EXPECT().describeSecurityGroups(gomock.Any()).Return(&ec2.DescribeSecurityGroupsOutput{
+				SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String(defaultSgID)}, {GroupId: aws.String(webSgID)},
+					{GroupId: aws.String(atSgID)}}}, nil).Times(1)
+			expectSgsAttached(defaultSgID, webSgID)
+			ec2Mock.EXPECT().deleteTags(&ec2.DeleteTagsInput{Resources: []*string{aws.String("eni-1")},
+				Tags: []*ec2.Tag{{Key: aws.String(awsNetworkInterfaceOriginalSgsTagKey)}}}).Return(&ec2.DeleteTagsOutput{}, nil).Times(1)
+
+			err := ec2Cfg.updateSecurityGroupMembers(&atSgID, atSgName, testVpcID01, nil, false)
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should record and restore security groups exceeding a tag value in multiple tags", func() {
+			var sgs []*ec2.GroupIdentifier
+			var sgIDs []string
+			for i := 0; i < 14; i++ {
+				sgID := fmt.Sprintf("sg-0123456789abcde%02d", i)
+				sgs = append(sgs, &ec2.GroupIdentifier{GroupId: aws.String(sgID), GroupName: aws.String(sgID)})
+				sgIDs = append(sgIDs, sgID)
+			}
+			var recordedTags []*ec2.Tag
+			ec2Mock.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return(
+				[]*ec2.NetworkInterface{getNetworkInterface(nil, sgs...)}, nil).Times(1)
+			ec2Mock.EXPECT().createTags(gomock.Any()).Times(1).DoAndReturn(
+				func(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+					recordedTags = input.Tags
+					return &ec2.CreateTagsOutput{}, nil
+				})
+			expectSgsAttached(atSgID)
+			err := ec2Cfg.updateSecurityGroupMembers(&atSgID, atSgName, testVpcID01, members, false)
+			Expect(err).Should(BeNil())
+			Expect(recordedTags).To(HaveLen(2))
+			Expect(aws.StringValue(recordedTags[0].Key)).To(Equal(awsNetworkInterfaceOriginalSgsTagKey))
+			Expect(aws.StringValue(recordedTags[1].Key)).To(Equal(awsNetworkInterfaceOriginalSgsTagKey + "-1"))
+			for _, tag := range recordedTags {
+				Expect(len(aws.StringValue(tag.Value))).To(BeNumerically("<=", awsTagValueMaxLength))
+			}
+
+			var vpcSgs []*ec2.SecurityGroup
+			for _, sgID := range sgIDs {
+				vpcSgs = append(vpcSgs, &ec2.SecurityGroup{GroupId: aws.String(sgID)})
+			}
+			ec2Mock.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return(
+				[]*ec2.NetworkInterface{getNetworkInterface(recordedTags, atSg)}, nil).Times(1)
+			ec2Mock.EXPECT().describeSecurityGroups(gomock.Any()).Return(&ec2.DescribeSecurityGroupsOutput{
+				SecurityGroups: vpcSgs}, nil).Times(1)
+			expectSgsAttached(sgIDs...)
+			ec2Mock.EXPECT().deleteTags(&ec2.DeleteTagsInput{Resources: []*string{aws.String("eni-1")},
+				Tags: []*ec2.Tag{{Key: aws.String(awsNetworkInterfaceOriginalSgsTagKey)},
+					{Key: aws.String(awsNetworkInterfaceOriginalSgsTagKey + "-1")}}}).Return(&ec2.DeleteTagsOutput{}, nil).Times(1)
+			err = ec2Cfg.updateSecurityGroupMembers(&atSgID, atSgName, testVpcID01, nil, false)
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should attach default security group if recorded security groups no longer exist", func() {
+			ec2Mock.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return(
+				[]*ec2.NetworkInterface{getNetworkInterface([]*ec2.Tag{{Key: aws.String(awsNetworkInterfaceOriginalSgsTagKey),
+					Value: aws.String(webSgID)}}, atSg)}, nil).Times(1)
+			ec2Mock.EXPECT().describeSecurityGroups(gomock.Any()).Return(&ec2.DescribeSecurityGroupsOutput{
+				SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String(defaultSgID)}}}, nil).Times(1)
+			expectSgsAttached(defaultSgID)
+			ec2Mock.EXPECT().deleteTags(gomock.Any()).Return(&ec2.DeleteTagsOutput{}, nil).Times(1)
+
+			err := ec2Cfg.updateSecurityGroupMembers(&atSgID, atSgName, testVpcID01, nil, false)
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should leave network interfaces with security groups not created by nephe unmodified in fail mode", func() {
+			ec2Cfg.securityGroupMode = v1alpha1.SecurityGroupModeFail
+			ec2Mock.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return(
+				[]*ec2.NetworkInterface{getNetworkInterface(nil, webSg, defaultSg)}, nil).Times(1)
+
+			err := ec2Cfg.updateSecurityGroupMembers(&atSgID, atSgName, testVpcID01, members, false)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("security groups [" + webSgID + "] not created by nephe are attached"))
+		})
+
+		It("Should leave network interfaces exceeding security groups quota unmodified", func() {
+			ec2Cfg.securityGroupMode = v1alpha1.SecurityGroupModeCoexist
+			ec2Cfg.securityGroupQuotas.securityGroupsPerNetworkInterface = 1
+			ec2Mock.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return(
+				[]*ec2.NetworkInterface{getNetworkInterface(nil, webSg, defaultSg)}, nil).Times(1)
+
+			err := ec2Cfg.updateSecurityGroupMembers(&atSgID, atSgName, testVpcID01, members, false)
+			Expect(errors.Is(err, securitygroup.ErrQuotaExceeded)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("network interface eni-1 requires security groups [" + webSgID + " " +
+				atSgID + "], security groups per network interface quota is 1"))
+		})
+	})
+
+	Context("Rule quotas", func() {
+		var (
+			tcp           = 6
+			sgObj         = &ec2.SecurityGroup{GroupId: aws.String("sg-3333"), GroupName: aws.String("nephe-at-web")}
+			_, ipNet1, _  = net.ParseCIDR("1.1.1.0/24")
+			_, ipNet2, _  = net.ParseCIDR("2.2.2.0/24")
+			_, ipv6Net, _ = net.ParseCIDR("2600:1f14::/56")
+			agSg          = &securitygroup.CloudResourceID{Name: "db", Vpc: testVpcID01}
+		)
+
+		It("Should count rules per direction and address family and report headroom", func() {
+			ec2Cfg := &ec2ServiceConfig{securityGroupQuotas: awsSecurityGroupQuotas{rulesPerSecurityGroup: 3}}
+			ingressRules := []*securitygroup.IngressRule{
+				{Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1, ipv6Net}, FromSecurityGroups: []*securitygroup.CloudResourceID{agSg}},
+				{Protocol: &tcp},
+			}
+			egressRules := []*securitygroup.EgressRule{{Protocol: &tcp, ToDstIP: []*net.IPNet{ipv6Net}}}
+			headroom := metrics.SecurityGroupRuleHeadroom.WithLabelValues(string(providerType), "sg-3333")
+
+			Expect(ec2Cfg.checkSecurityGroupRuleQuota(sgObj, ingressRules, egressRules, nil)).Should(BeNil())
+			Expect(testutil.ToFloat64(headroom)).To(Equal(float64(0)))
+
+			ingressRules[0].FromSrcIP = append(ingressRules[0].FromSrcIP, ipNet2)
+			err := ec2Cfg.checkSecurityGroupRuleQuota(sgObj, ingressRules, egressRules, nil)
+			Expect(errors.Is(err, securitygroup.ErrQuotaExceeded)).To(BeTrue())
+			Expect(err.Error()).To(Equal("quota exceeded: security group nephe-at-web requires 4 IPv4 ingress rules, " +
+				"rules per security group quota is 3"))
+			Expect(testutil.ToFloat64(headroom)).To(Equal(float64(-1)))
+		})
+	})
 })
 
 func testAwsBuildDescribeSecurityGroupInput(vpcID string, sgNamesSet map[string]struct{}) *ec2.DescribeSecurityGroupsInput {
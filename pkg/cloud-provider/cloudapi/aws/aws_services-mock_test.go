@@ -63,6 +63,21 @@ func (mr *MockawsServiceClientCreateInterfaceMockRecorder) compute() *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "compute", reflect.TypeOf((*MockawsServiceClientCreateInterface)(nil).compute))
 }
 
+// events mocks base method.
+func (m *MockawsServiceClientCreateInterface) events(queueURL string) (awsSQSWrapper, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "events", queueURL)
+	ret0, _ := ret[0].(awsSQSWrapper)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// events indicates an expected call of events.
+func (mr *MockawsServiceClientCreateInterfaceMockRecorder) events(queueURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "events", reflect.TypeOf((*MockawsServiceClientCreateInterface)(nil).events), queueURL)
+}
+
 // MockawsServicesHelper is a mock of awsServicesHelper interface.
 type MockawsServicesHelper struct {
 	ctrl     *gomock.Controller
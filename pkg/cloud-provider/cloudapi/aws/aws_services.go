@@ -16,24 +16,30 @@ package aws
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"k8s.io/apimachinery/pkg/types"
 
+	"antrea.io/nephe/apis/crd/v1alpha1"
 	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
 )
 
 const (
 	awsComputeServiceNameEC2 = internal.CloudServiceName("EC2")
+	// awsDefaultRegion is the region used to discover regions enabled in an account.
+	awsDefaultRegion = "us-east-1"
 )
 
 // awsServiceClientCreateInterface provides interface to create aws service clients.
 type awsServiceClientCreateInterface interface {
 	compute() (awsEC2Wrapper, error)
+	events(queueURL string) (awsSQSWrapper, error)
 	// Add any aws service (like rds, elb etc) apiClient creation methods here
 }
 
@@ -106,16 +112,60 @@ func newAwsServiceConfigs(accountNamespacedName *types.NamespacedName, accCreden
 
 	var serviceConfigs []internal.CloudServiceInterface
 
-	awsServiceClientCreator, err := awsServicesHelper.newServiceSdkConfigProvider(awsAccountCredentials)
+	regions := awsAccountCredentials.regions
+	if len(regions) == 1 && regions[0] == v1alpha1.AllRegions {
+		var err error
+		if regions, err = getEnabledRegions(awsServicesHelper, awsAccountCredentials); err != nil {
+			return nil, err
+		}
+	}
+
+	// create services of each region, with api clients of the region.
+	for _, region := range regions {
+		regionCredentials := *awsAccountCredentials
+		regionCredentials.region = region
+		awsServiceClientCreator, err := awsServicesHelper.newServiceSdkConfigProvider(&regionCredentials)
+		if err != nil {
+			return nil, err
+		}
+
+		ec2Service, err := newEC2ServiceConfig(accountNamespacedName.String(),
+			internal.GetRegionalServiceName(awsComputeServiceNameEC2, region, regions), region,
+			awsAccountCredentials.securityGroupMode, awsAccountCredentials.prefixListThreshold,
+			awsAccountCredentials.securityGroupQuotas, awsServiceClientCreator)
+		if err != nil {
+			return nil, err
+		}
+		serviceConfigs = append(serviceConfigs, ec2Service)
+	}
+
+	return serviceConfigs, nil
+}
+
+// getEnabledRegions returns regions enabled in the account.
+func getEnabledRegions(awsServicesHelper awsServicesHelper, accCreds *awsAccountCredentials) ([]string, error) {
+	regionCredentials := *accCreds
+	regionCredentials.region = awsDefaultRegion
+	awsServiceClientCreator, err := awsServicesHelper.newServiceSdkConfigProvider(&regionCredentials)
 	if err != nil {
 		return nil, err
 	}
-
-	ec2Service, err := newEC2ServiceConfig(accountNamespacedName.String(), awsServiceClientCreator)
+	apiClient, err := awsServiceClientCreator.compute()
 	if err != nil {
 		return nil, err
 	}
-	serviceConfigs = append(serviceConfigs, ec2Service)
+	output, err := apiClient.describeRegionsWrapper(&ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("error describing regions: %v", err)
+	}
 
-	return serviceConfigs, nil
+	regions := make([]string, 0, len(output.Regions))
+	for _, region := range output.Regions {
+		regions = append(regions, aws.StringValue(region.RegionName))
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no region enabled in account")
+	}
+	sort.Strings(regions)
+	return regions, nil
 }
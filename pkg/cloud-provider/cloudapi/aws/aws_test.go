@@ -22,16 +22,20 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 var (
@@ -197,6 +201,184 @@ var _ = Describe("AWS cloud", func() {
 				accCfg, found := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
 				Expect(found).To(BeTrue())
 				Expect(accCfg).To(Not(BeNil()))
+
+				status, err := c.GetAccountStatus(&testAccountNamespacedName)
+				Expect(err).Should(BeNil())
+				Expect(meta.IsStatusConditionTrue(status.Conditions, v1alpha1.AccountConditionCredentialsValid)).To(BeTrue())
+				inventoryCondition := meta.FindStatusCondition(status.Conditions, v1alpha1.AccountConditionInventorySynced)
+				Expect(inventoryCondition.Reason).To(Equal(v1alpha1.AccountReasonInventoryPending))
+				Expect(meta.IsStatusConditionFalse(status.Conditions, v1alpha1.AccountConditionSecurityEnforcementReady)).To(BeTrue())
+				Expect(status.LastSuccessfulPollTime).To(BeNil())
+			})
+			It("Should report account status after inventory poll", func() {
+				instanceIds := []string{"i-01", "i-02"}
+				mockawsEC2.EXPECT().pagedDescribeInstancesWrapper(gomock.Any()).Return(getEc2InstanceObject(instanceIds), nil).AnyTimes()
+				mockawsEC2.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return([]*ec2.NetworkInterface{}, nil).AnyTimes()
+				mockawsEC2.EXPECT().describeVpcsWrapper(gomock.Any()).Return(&ec2.DescribeVpcsOutput{}, nil).AnyTimes()
+				mockawsEC2.EXPECT().describeVpcPeeringConnectionsWrapper(gomock.Any()).Return(&ec2.DescribeVpcPeeringConnectionsOutput{},
+					nil).AnyTimes()
+
+				c := newAWSCloud(mockawsCloudHelper)
+				err := c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+				errSelAdd := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+				Expect(errSelAdd).Should(BeNil())
+
+				status, err := c.GetAccountStatus(&testAccountNamespacedName)
+				Expect(err).Should(BeNil())
+				Expect(status.Error).To(BeEmpty())
+				Expect(meta.IsStatusConditionTrue(status.Conditions, v1alpha1.AccountConditionInventorySynced)).To(BeTrue())
+				Expect(meta.IsStatusConditionTrue(status.Conditions, v1alpha1.AccountConditionSecurityEnforcementReady)).To(BeTrue())
+				Expect(status.LastSuccessfulPollTime).ToNot(BeNil())
+				Expect(status.ServiceStats).To(HaveLen(1))
+				Expect(status.ServiceStats[0].Name).To(Equal(string(awsComputeServiceNameEC2)))
+				Expect(status.ServiceStats[0].SuccessfulPollCount).ToNot(BeZero())
+			})
+			It("Should report inventory poll failure in account status", func() {
+				mockawsEC2.EXPECT().pagedDescribeInstancesWrapper(gomock.Any()).Return(nil, errors.New("access denied")).AnyTimes()
+				mockawsEC2.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return([]*ec2.NetworkInterface{}, nil).AnyTimes()
+				mockawsEC2.EXPECT().describeVpcsWrapper(gomock.Any()).Return(&ec2.DescribeVpcsOutput{}, nil).AnyTimes()
+				mockawsEC2.EXPECT().describeVpcPeeringConnectionsWrapper(gomock.Any()).Return(&ec2.DescribeVpcPeeringConnectionsOutput{},
+					nil).AnyTimes()
+
+				c := newAWSCloud(mockawsCloudHelper)
+				err := c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+				_ = c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+
+				status, err := c.GetAccountStatus(&testAccountNamespacedName)
+				Expect(err).Should(BeNil())
+				Expect(status.Error).To(ContainSubstring("access denied"))
+				inventoryCondition := meta.FindStatusCondition(status.Conditions, v1alpha1.AccountConditionInventorySynced)
+				Expect(inventoryCondition.Status).To(Equal(v1.ConditionFalse))
+				Expect(inventoryCondition.Reason).To(Equal(v1alpha1.AccountReasonInventoryPollFailed))
+				Expect(status.ServiceStats[0].LastPollError).To(ContainSubstring("access denied"))
+			})
+			It("Should record inventory poll metrics and remove them with account", func() {
+				mockawsEC2.EXPECT().pagedDescribeInstancesWrapper(gomock.Any()).Return(nil, errors.New("access denied")).AnyTimes()
+				mockawsEC2.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return([]*ec2.NetworkInterface{}, nil).AnyTimes()
+				mockawsEC2.EXPECT().describeVpcsWrapper(gomock.Any()).Return(&ec2.DescribeVpcsOutput{}, nil).AnyTimes()
+				mockawsEC2.EXPECT().describeVpcPeeringConnectionsWrapper(gomock.Any()).Return(&ec2.DescribeVpcPeeringConnectionsOutput{},
+					nil).AnyTimes()
+
+				c := newAWSCloud(mockawsCloudHelper)
+				err := c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+				_ = c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+
+				failedPolls := metrics.InventoryPolls.WithLabelValues(testAccountNamespacedName.Namespace,
+					testAccountNamespacedName.Name, string(awsComputeServiceNameEC2), metrics.ResultFailure)
+				Expect(testutil.ToFloat64(failedPolls)).ToNot(BeZero())
+				Expect(testutil.CollectAndCount(metrics.InventoryPollDuration)).To(Equal(1))
+
+				c.RemoveProviderAccount(&testAccountNamespacedName)
+				Expect(testutil.CollectAndCount(metrics.InventoryPolls)).To(BeZero())
+				Expect(testutil.CollectAndCount(metrics.InventoryPollDuration)).To(BeZero())
+			})
+			It("Should discover instances in all regions of a multi-region account", func() {
+				instanceIds := []string{"i-01", "i-02"}
+				account.Spec.AWSConfig.Region = ""
+				account.Spec.AWSConfig.Regions = []string{"us-west-2", "us-east-1"}
+				mockawsCloudHelper.EXPECT().newServiceSdkConfigProvider(gomock.Any()).Return(mockawsService, nil).AnyTimes()
+				mockawsEC2.EXPECT().pagedDescribeInstancesWrapper(gomock.Any()).Return(getEc2InstanceObject(instanceIds), nil).AnyTimes()
+				mockawsEC2.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return([]*ec2.NetworkInterface{}, nil).AnyTimes()
+				mockawsEC2.EXPECT().describeVpcsWrapper(gomock.Any()).Return(&ec2.DescribeVpcsOutput{}, nil).AnyTimes()
+				mockawsEC2.EXPECT().describeVpcPeeringConnectionsWrapper(gomock.Any()).Return(&ec2.DescribeVpcPeeringConnectionsOutput{},
+					nil).AnyTimes()
+
+				c := newAWSCloud(mockawsCloudHelper)
+				err := c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+				accCfg, found := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+				Expect(found).To(BeTrue())
+				Expect(accCfg.GetServiceConfigs()).To(HaveLen(2))
+
+				errSelAdd := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+				Expect(errSelAdd).Should(BeNil())
+
+				// service of the first region is not qualified by region.
+				serviceNames := []internal.CloudServiceName{awsComputeServiceNameEC2, "EC2/us-east-1"}
+				for i, region := range account.Spec.AWSConfig.Regions {
+					serviceConfig, err := accCfg.GetServiceConfigByName(serviceNames[i])
+					Expect(err).Should(BeNil())
+					ec2Service := serviceConfig.(*ec2ServiceConfig)
+					Expect(ec2Service.region).To(Equal(region))
+					Expect(ec2Service.getCachedInstances()).To(HaveLen(len(instanceIds)))
+				}
+				firstService, _ := accCfg.GetServiceConfigByName(awsComputeServiceNameEC2)
+
+				// services of regions removed from the account are removed, service of the first region is kept.
+				account.Spec.AWSConfig.Regions = []string{"us-west-2"}
+				err = c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+				Expect(accCfg.GetServiceConfigs()).To(HaveLen(1))
+				serviceConfig, err := accCfg.GetServiceConfigByName(awsComputeServiceNameEC2)
+				Expect(err).Should(BeNil())
+				Expect(serviceConfig).To(BeIdenticalTo(firstService))
+				Expect(serviceConfig.(*ec2ServiceConfig).getCachedInstances()).To(HaveLen(len(instanceIds)))
+
+				// removed selectors are not applied to services of regions added to the account.
+				c.RemoveAccountResourcesSelector(&testAccountNamespacedName, selector.Name)
+				account.Spec.AWSConfig.Regions = []string{"us-west-2", "us-east-1"}
+				err = c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+				serviceConfig, err = accCfg.GetServiceConfigByName("EC2/us-east-1")
+				Expect(err).Should(BeNil())
+				Expect(serviceConfig.(*ec2ServiceConfig).instanceFilters).To(BeEmpty())
+			})
+			It("Should discover vpcs of the account", func() {
+				vpcs := &ec2.DescribeVpcsOutput{Vpcs: []*ec2.Vpc{
+					{
+						VpcId:     aws.String("vpc-01"),
+						CidrBlock: aws.String("10.0.0.0/16"),
+						CidrBlockAssociationSet: []*ec2.VpcCidrBlockAssociation{
+							{CidrBlock: aws.String("10.1.0.0/16")},
+							{CidrBlock: aws.String("10.0.0.0/16")},
+						},
+						Tags: []*ec2.Tag{
+							{Key: aws.String("Name"), Value: aws.String("vpc-name-01")},
+							{Key: aws.String("env"), Value: aws.String("prod")},
+						},
+					},
+					{VpcId: aws.String("vpc-02"), CidrBlock: aws.String("192.168.0.0/24")},
+				}}
+				peerings := &ec2.DescribeVpcPeeringConnectionsOutput{VpcPeeringConnections: []*ec2.VpcPeeringConnection{
+					{
+						AccepterVpcInfo:  &ec2.VpcPeeringConnectionVpcInfo{VpcId: aws.String("vpc-01")},
+						RequesterVpcInfo: &ec2.VpcPeeringConnectionVpcInfo{VpcId: aws.String("vpc-02")},
+					},
+				}}
+				mockawsEC2.EXPECT().pagedDescribeInstancesWrapper(gomock.Any()).Return([]*ec2.Instance{}, nil).AnyTimes()
+				mockawsEC2.EXPECT().pagedDescribeNetworkInterfaces(gomock.Any()).Return([]*ec2.NetworkInterface{}, nil).AnyTimes()
+				mockawsEC2.EXPECT().describeVpcsWrapper(gomock.Any()).Return(vpcs, nil).AnyTimes()
+				mockawsEC2.EXPECT().describeVpcPeeringConnectionsWrapper(gomock.Any()).Return(peerings, nil).AnyTimes()
+
+				c := newAWSCloud(mockawsCloudHelper)
+				err := c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+				errSelAdd := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+				Expect(errSelAdd).Should(BeNil())
+
+				var vpcCRDs []*v1alpha1.Vpc
+				err = wait.PollImmediate(1*time.Second, 5*time.Second, func() (bool, error) {
+					vpcCRDs, err = c.VpcsGivenProviderAccount(&testAccountNamespacedName)
+					return len(vpcCRDs) == 2, err
+				})
+				Expect(err).Should(BeNil())
+				sort.Slice(vpcCRDs, func(i, j int) bool { return vpcCRDs[i].Name < vpcCRDs[j].Name })
+
+				Expect(vpcCRDs[0].Name).To(Equal("vpc-01"))
+				Expect(vpcCRDs[0].Namespace).To(Equal(testAccountNamespacedName.Namespace))
+				Expect(vpcCRDs[0].Status.ID).To(Equal("vpc-01"))
+				Expect(vpcCRDs[0].Status.Name).To(Equal("vpc-name-01"))
+				Expect(vpcCRDs[0].Status.Region).To(Equal("us-east-1"))
+				Expect(vpcCRDs[0].Status.CIDRs).To(Equal([]string{"10.0.0.0/16", "10.1.0.0/16"}))
+				Expect(vpcCRDs[0].Status.Tags).To(HaveKeyWithValue("env", "prod"))
+				Expect(vpcCRDs[0].Status.Peerings).To(Equal([]string{"vpc-02"}))
+
+				Expect(vpcCRDs[1].Status.Name).To(BeEmpty())
+				Expect(vpcCRDs[1].Status.CIDRs).To(Equal([]string{"192.168.0.0/24"}))
+				Expect(vpcCRDs[1].Status.Peerings).To(Equal([]string{"vpc-01"}))
 			})
 		})
 	})
@@ -524,6 +706,161 @@ var _ = Describe("AWS cloud", func() {
 			filters := serviceConfig.(*ec2ServiceConfig).instanceFilters[selector.Name]
 			Expect(filters).To(Equal(expectedFilters))
 		})
+		It("Should match expected filter - tags only and vm name with tags match", func() {
+			tagsFilters := []*ec2.Filter{
+				{Name: aws.String(awsFilterKeyTagKey), Values: []*string{aws.String("app")}},
+				{Name: aws.String("tag:env"), Values: []*string{aws.String("prod")}},
+			}
+			var expectedFilters [][]*ec2.Filter
+			expectedFilters = append(expectedFilters, append(append([]*ec2.Filter{}, tagsFilters...),
+				buildEc2FilterForValidInstanceStates()))
+			vmNameFilter := &ec2.Filter{
+				Name:   aws.String(awsFilterKeyVMName),
+				Values: []*string{aws.String(testVMName01)},
+			}
+			expectedFilters = append(expectedFilters, append(append([]*ec2.Filter{vmNameFilter}, tagsFilters...),
+				buildEc2FilterForValidInstanceStates()))
+
+			tags := map[string]string{"env": "prod", "app": ""}
+			vmSelector := []v1alpha1.VirtualMachineSelector{
+				{
+					VMMatch: []v1alpha1.EntityMatch{{MatchTags: tags}},
+				},
+				{
+					VMMatch: []v1alpha1.EntityMatch{{MatchName: testVMName01, MatchTags: tags}},
+				},
+			}
+
+			selector.Spec.VMSelector = vmSelector
+			err := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+			Expect(err).Should(BeNil())
+
+			accCfg, _ := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+			serviceConfig, _ := accCfg.GetServiceConfigByName(awsComputeServiceNameEC2)
+			filters := serviceConfig.(*ec2ServiceConfig).instanceFilters[selector.Name]
+			Expect(filters).To(Equal(expectedFilters))
+		})
+		It("Should match expected filter - vpcID and vpcName with tags match", func() {
+			tagFilter := &ec2.Filter{Name: aws.String("tag:env"), Values: []*string{aws.String("prod")}}
+			vpcIDFilter := &ec2.Filter{
+				Name:   aws.String(awsFilterKeyVPCID),
+				Values: []*string{aws.String(testVpcID01)},
+			}
+			vpcNameFilter := &ec2.Filter{
+				Name:   aws.String(awsCustomFilterKeyVPCName),
+				Values: []*string{aws.String(testVpcName02)},
+			}
+			var expectedFilters [][]*ec2.Filter
+			expectedFilters = append(expectedFilters,
+				[]*ec2.Filter{vpcIDFilter, tagFilter, buildEc2FilterForValidInstanceStates()},
+				[]*ec2.Filter{vpcNameFilter, tagFilter, buildEc2FilterForValidInstanceStates()})
+
+			tags := map[string]string{"env": "prod"}
+			vmSelector := []v1alpha1.VirtualMachineSelector{
+				{
+					VpcMatch: &v1alpha1.EntityMatch{MatchID: testVpcID01},
+					VMMatch:  []v1alpha1.EntityMatch{{MatchTags: tags}},
+				},
+				{
+					VpcMatch: &v1alpha1.EntityMatch{MatchName: testVpcName02},
+					VMMatch:  []v1alpha1.EntityMatch{{MatchTags: tags}},
+				},
+			}
+
+			selector.Spec.VMSelector = vmSelector
+			err := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+			Expect(err).Should(BeNil())
+
+			accCfg, _ := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+			serviceConfig, _ := accCfg.GetServiceConfigByName(awsComputeServiceNameEC2)
+			filters := serviceConfig.(*ec2ServiceConfig).instanceFilters[selector.Name]
+			Expect(filters).To(Equal(expectedFilters))
+
+			// vpc name filter is converted to vpc ID filter, keeping tags filter.
+			vpcNameToID := map[string]string{testVpcName02: testVpcID02}
+			converted := buildFilterForVPCIDFromFilterForVPCName(filters[1], vpcNameToID)
+			Expect(converted).To(Equal([]*ec2.Filter{
+				{Name: aws.String(awsFilterKeyVPCID), Values: []*string{aws.String(testVpcID02)}},
+				tagFilter, buildEc2FilterForValidInstanceStates()}))
+		})
+	})
+
+	Context("VirtualMachine CRD", func() {
+		It("Should report cloud properties of instance", func() {
+			launchTime := time.Date(2022, 10, 1, 8, 30, 15, 500, time.UTC)
+			instance := &ec2.Instance{
+				InstanceId:   aws.String("i-0123456789"),
+				VpcId:        aws.String(testVpcID01),
+				State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				InstanceType: aws.String("t2.micro"),
+				ImageId:      aws.String("ami-0123456789"),
+				LaunchTime:   &launchTime,
+				Placement:    &ec2.Placement{AvailabilityZone: aws.String("us-east-1a")},
+				Platform:     aws.String("windows"),
+				SecurityGroups: []*ec2.GroupIdentifier{
+					{GroupId: aws.String("sg-02"), GroupName: aws.String("web")},
+					{GroupId: aws.String("sg-01"), GroupName: aws.String("default")},
+				},
+				NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+					{
+						NetworkInterfaceId: aws.String("eni-0123456789"),
+						MacAddress:         aws.String("02:00:00:00:00:01"),
+						SubnetId:           aws.String("subnet-0123456789"),
+					},
+				},
+			}
+			vm := ec2InstanceToVirtualMachineCRD(instance, "default", "us-east-1")
+			Expect(vm.Status.Region).To(Equal("us-east-1"))
+			Expect(vm.Status.AvailabilityZone).To(Equal("us-east-1a"))
+			Expect(vm.Status.InstanceType).To(Equal("t2.micro"))
+			Expect(vm.Status.ImageID).To(Equal("ami-0123456789"))
+			Expect(vm.Status.OSType).To(Equal("windows"))
+			Expect(vm.Status.LaunchTime.Time.Equal(launchTime.Truncate(time.Second))).To(BeTrue())
+			Expect(vm.Status.SecurityGroups).To(Equal([]string{"sg-01", "sg-02"}))
+			Expect(vm.Status.NetworkInterfaces[0].Subnet).To(Equal("subnet-0123456789"))
+
+			instance.Platform = nil
+			vm = ec2InstanceToVirtualMachineCRD(instance, "default", "us-east-1")
+			Expect(vm.Status.OSType).To(Equal("linux"))
+		})
+
+		It("Should report public and Elastic IPs as external IPs", func() {
+			instance := &ec2.Instance{
+				InstanceId: aws.String("i-0123456789"),
+				VpcId:      aws.String(testVpcID01),
+				State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+					{
+						NetworkInterfaceId: aws.String("eni-0123456789"),
+						MacAddress:         aws.String("02:00:00:00:00:01"),
+						PrivateIpAddresses: []*ec2.InstancePrivateIpAddress{
+							{
+								PrivateIpAddress: aws.String("10.0.1.5"),
+								Association:      &ec2.InstanceNetworkInterfaceAssociation{PublicIp: aws.String("3.0.0.1")},
+							},
+							{
+								PrivateIpAddress: aws.String("10.0.1.6"),
+								Association: &ec2.InstanceNetworkInterfaceAssociation{
+									PublicIp: aws.String("3.0.0.2"), IpOwnerId: aws.String("123456789012"),
+								},
+							},
+							{
+								PrivateIpAddress: aws.String("10.0.1.7"),
+								Association:      &ec2.InstanceNetworkInterfaceAssociation{CarrierIp: aws.String("155.0.0.1")},
+							},
+						},
+					},
+				},
+			}
+			vm := ec2InstanceToVirtualMachineCRD(instance, "default", "us-east-1")
+			Expect(vm.Status.NetworkInterfaces[0].IPs).To(Equal([]v1alpha1.IPAddress{
+				{AddressType: v1alpha1.AddressTypeInternalIP, Address: "10.0.1.5"},
+				{AddressType: v1alpha1.AddressTypeExternalIP, Address: "3.0.0.1"},
+				{AddressType: v1alpha1.AddressTypeInternalIP, Address: "10.0.1.6"},
+				{AddressType: v1alpha1.AddressTypeExternalIP, Address: "3.0.0.2"},
+				{AddressType: v1alpha1.AddressTypeInternalIP, Address: "10.0.1.7"},
+			}))
+		})
 	})
 })
 
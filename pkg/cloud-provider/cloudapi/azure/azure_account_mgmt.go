@@ -15,6 +15,8 @@
 package azure
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	"antrea.io/nephe/apis/crd/v1alpha1"
@@ -26,8 +28,12 @@ type azureAccountCredentials struct {
 	clientID         string
 	tenantID         string
 	clientKey        string
-	region           string
+	region           string   // region of service api clients.
+	regions          []string // all regions of the account.
 	identityClientID string
+	eventQueueURL    string
+	// securityGroupMode is how network security groups not created by nephe are treated on network interfaces.
+	securityGroupMode v1alpha1.SecurityGroupMode
 }
 
 // setAccountCredentials sets account credentials.
@@ -38,8 +44,15 @@ func setAccountCredentials(credentials interface{}) (interface{}, error) {
 		clientID:         strings.TrimSpace(azureConfig.ClientID),
 		tenantID:         strings.TrimSpace(azureConfig.TenantID),
 		clientKey:        strings.TrimSpace(azureConfig.ClientKey),
-		region:           strings.TrimSpace(azureConfig.Region),
+		regions:          azureConfig.GetRegions(),
 		identityClientID: strings.TrimSpace(azureConfig.IdentityClientID),
+		eventQueueURL:    strings.TrimSpace(azureConfig.EventQueueURL),
+
+		securityGroupMode: azureConfig.SecurityGroupMode,
+	}
+
+	if len(accCreds.regions) == 0 {
+		return nil, fmt.Errorf("region cannot be blank or empty")
 	}
 
 	return accCreds, nil
@@ -66,32 +79,57 @@ func compareAccountCredentials(accountName string, existing interface{}, new int
 		credsChanged = true
 		azurePluginLogger().Info("account client key updated", "account", accountName)
 	}
-	if strings.Compare(existingCreds.region, newCreds.region) != 0 {
+	if strings.Join(existingCreds.regions, ",") != strings.Join(newCreds.regions, ",") {
+		credsChanged = true
+		azurePluginLogger().Info("account regions updated", "account", accountName)
+	}
+	if existingCreds.eventQueueURL != newCreds.eventQueueURL {
+		credsChanged = true
+		azurePluginLogger().Info("account event queue URL updated", "account", accountName)
+	}
+	if existingCreds.securityGroupMode != newCreds.securityGroupMode {
 		credsChanged = true
-		azurePluginLogger().Info("account region updated", "account", accountName)
+		azurePluginLogger().Info("account security group mode updated", "account", accountName)
 	}
 	return credsChanged
 }
 
-// getVnetAccount returns first found account config to which this vnet id belongs.
-func (c *azureCloud) getVnetAccount(vpcID string) internal.CloudAccountInterface {
+// getVnetAccount returns first found account config to which this vnet id belongs, and compute service config of
+// the region of the vnet.
+func (c *azureCloud) getVnetAccount(vpcID string) (internal.CloudAccountInterface, *computeServiceConfig) {
 	accCfgs := c.cloudCommon.GetCloudAccounts()
 	if len(accCfgs) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	for _, accCfg := range accCfgs {
-		ec2ServiceCfg, err := accCfg.GetServiceConfigByName(azureComputeServiceNameCompute)
-		if err != nil {
-			continue
+		for _, computeServiceCfg := range getComputeServiceConfigs(accCfg) {
+			accVpcIDs := computeServiceCfg.getCachedVnetIDs()
+			if len(accVpcIDs) == 0 {
+				continue
+			}
+			if _, found := accVpcIDs[strings.ToLower(vpcID)]; found {
+				return accCfg, computeServiceCfg
+			}
 		}
-		accVpcIDs := ec2ServiceCfg.(*computeServiceConfig).getCachedVnetIDs()
-		if len(accVpcIDs) == 0 {
+	}
+	return nil, nil
+}
+
+// getComputeServiceConfigs returns compute service configs of all regions of an account, sorted by region.
+func getComputeServiceConfigs(accCfg internal.CloudAccountInterface) []*computeServiceConfig {
+	var computeServiceCfgs []*computeServiceConfig
+	for name := range accCfg.GetServiceConfigs() {
+		serviceCfg, err := accCfg.GetServiceConfigByName(name)
+		if err != nil {
 			continue
 		}
-		if _, found := accVpcIDs[strings.ToLower(vpcID)]; found {
-			return accCfg
+		if computeServiceCfg, ok := serviceCfg.(*computeServiceConfig); ok {
+			computeServiceCfgs = append(computeServiceCfgs, computeServiceCfg)
 		}
 	}
-	return nil
+	sort.Slice(computeServiceCfgs, func(i, j int) bool {
+		return computeServiceCfgs[i].credentials.region < computeServiceCfgs[j].credentials.region
+	})
+	return computeServiceCfgs
 }
@@ -22,9 +22,11 @@ package azure
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	network "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-03-01/network"
 	resourcegraph "github.com/Azure/azure-sdk-for-go/services/resourcegraph/mgmt/2021-03-01/resourcegraph"
+	subscriptions "github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2021-01-01/subscriptions"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -335,3 +337,93 @@ func (mr *MockazureVirtualNetworksWrapperMockRecorder) listAllComplete(ctx inter
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "listAllComplete", reflect.TypeOf((*MockazureVirtualNetworksWrapper)(nil).listAllComplete), ctx)
 }
+
+// MockazureSubscriptionsWrapper is a mock of azureSubscriptionsWrapper interface.
+type MockazureSubscriptionsWrapper struct {
+	ctrl     *gomock.Controller
+	recorder *MockazureSubscriptionsWrapperMockRecorder
+}
+
+// MockazureSubscriptionsWrapperMockRecorder is the mock recorder for MockazureSubscriptionsWrapper.
+type MockazureSubscriptionsWrapperMockRecorder struct {
+	mock *MockazureSubscriptionsWrapper
+}
+
+// NewMockazureSubscriptionsWrapper creates a new mock instance.
+func NewMockazureSubscriptionsWrapper(ctrl *gomock.Controller) *MockazureSubscriptionsWrapper {
+	mock := &MockazureSubscriptionsWrapper{ctrl: ctrl}
+	mock.recorder = &MockazureSubscriptionsWrapperMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockazureSubscriptionsWrapper) EXPECT() *MockazureSubscriptionsWrapperMockRecorder {
+	return m.recorder
+}
+
+// listLocations mocks base method.
+func (m *MockazureSubscriptionsWrapper) listLocations(ctx context.Context, subscriptionID string) ([]subscriptions.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "listLocations", ctx, subscriptionID)
+	ret0, _ := ret[0].([]subscriptions.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// listLocations indicates an expected call of listLocations.
+func (mr *MockazureSubscriptionsWrapperMockRecorder) listLocations(ctx, subscriptionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "listLocations", reflect.TypeOf((*MockazureSubscriptionsWrapper)(nil).listLocations), ctx, subscriptionID)
+}
+
+// MockazureEventQueueWrapper is a mock of azureEventQueueWrapper interface.
+type MockazureEventQueueWrapper struct {
+	ctrl     *gomock.Controller
+	recorder *MockazureEventQueueWrapperMockRecorder
+}
+
+// MockazureEventQueueWrapperMockRecorder is the mock recorder for MockazureEventQueueWrapper.
+type MockazureEventQueueWrapperMockRecorder struct {
+	mock *MockazureEventQueueWrapper
+}
+
+// NewMockazureEventQueueWrapper creates a new mock instance.
+func NewMockazureEventQueueWrapper(ctrl *gomock.Controller) *MockazureEventQueueWrapper {
+	mock := &MockazureEventQueueWrapper{ctrl: ctrl}
+	mock.recorder = &MockazureEventQueueWrapperMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockazureEventQueueWrapper) EXPECT() *MockazureEventQueueWrapperMockRecorder {
+	return m.recorder
+}
+
+// deleteMessage mocks base method.
+func (m *MockazureEventQueueWrapper) deleteMessage(ctx context.Context, messageID, popReceipt string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "deleteMessage", ctx, messageID, popReceipt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// deleteMessage indicates an expected call of deleteMessage.
+func (mr *MockazureEventQueueWrapperMockRecorder) deleteMessage(ctx, messageID, popReceipt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "deleteMessage", reflect.TypeOf((*MockazureEventQueueWrapper)(nil).deleteMessage), ctx, messageID, popReceipt)
+}
+
+// getMessages mocks base method.
+func (m *MockazureEventQueueWrapper) getMessages(ctx context.Context, numOfMessages int, visibilityTimeout time.Duration) ([]azureQueueMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getMessages", ctx, numOfMessages, visibilityTimeout)
+	ret0, _ := ret[0].([]azureQueueMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// getMessages indicates an expected call of getMessages.
+func (mr *MockazureEventQueueWrapperMockRecorder) getMessages(ctx, numOfMessages, visibilityTimeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getMessages", reflect.TypeOf((*MockazureEventQueueWrapper)(nil).getMessages), ctx, numOfMessages, visibilityTimeout)
+}
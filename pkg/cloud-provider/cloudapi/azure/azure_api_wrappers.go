@@ -17,9 +17,14 @@ package azure
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-03-01/network"
 	"github.com/Azure/azure-sdk-for-go/services/resourcegraph/mgmt/2021-03-01/resourcegraph"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2021-01-01/subscriptions"
 	"github.com/Azure/go-autorest/autorest"
 )
 
@@ -250,3 +255,98 @@ func (vnet *azureVirtualNetworksWrapperImpl) listAllComplete(ctx context.Context
 
 	return VNListResultIterators, nil
 }
+
+type azureSubscriptionsWrapper interface {
+	listLocations(ctx context.Context, subscriptionID string) ([]subscriptions.Location, error)
+}
+type azureSubscriptionsWrapperImpl struct {
+	subscriptionsClient subscriptions.Client
+}
+
+func (s *azureSubscriptionsWrapperImpl) listLocations(ctx context.Context, subscriptionID string) ([]subscriptions.Location, error) {
+	result, err := s.subscriptionsClient.ListLocations(ctx, subscriptionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locations, reason %v", err)
+	}
+	if result.Value == nil {
+		return nil, nil
+	}
+	return *result.Value, nil
+}
+
+// azureQueueMessage is a message of a storage queue.
+type azureQueueMessage struct {
+	MessageID   string `xml:"MessageId"`
+	PopReceipt  string `xml:"PopReceipt"`
+	MessageText string `xml:"MessageText"`
+}
+
+type azureEventQueueWrapper interface {
+	getMessages(ctx context.Context, numOfMessages int, visibilityTimeout time.Duration) ([]azureQueueMessage, error)
+	deleteMessage(ctx context.Context, messageID string, popReceipt string) error
+}
+
+// azureEventQueueWrapperImpl accesses messages of a storage queue with the queue service REST API.
+type azureEventQueueWrapperImpl struct {
+	client   autorest.Client
+	queueURL *url.URL
+}
+
+func (q *azureEventQueueWrapperImpl) getMessages(ctx context.Context, numOfMessages int, visibilityTimeout time.Duration) (
+	[]azureQueueMessage, error) {
+	req, err := q.newRequest(ctx, http.MethodGet, "/messages", url.Values{
+		"numofmessages":     []string{strconv.Itoa(numOfMessages)},
+		"visibilitytimeout": []string{strconv.Itoa(int(visibilityTimeout.Seconds()))},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := q.client.Send(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue messages, reason %v", err)
+	}
+	result := struct {
+		Messages []azureQueueMessage `xml:"QueueMessage"`
+	}{}
+	if err := autorest.Respond(resp, autorest.WithErrorUnlessStatusCode(http.StatusOK), autorest.ByUnmarshallingXML(&result),
+		autorest.ByClosing()); err != nil {
+		return nil, fmt.Errorf("failed to get queue messages, reason %v", err)
+	}
+	return result.Messages, nil
+}
+
+func (q *azureEventQueueWrapperImpl) deleteMessage(ctx context.Context, messageID string, popReceipt string) error {
+	req, err := q.newRequest(ctx, http.MethodDelete, "/messages/"+url.PathEscape(messageID), url.Values{
+		"popreceipt": []string{popReceipt},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := q.client.Send(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete queue message %v, reason %v", messageID, err)
+	}
+	if err := autorest.Respond(resp, autorest.WithErrorUnlessStatusCode(http.StatusNoContent), autorest.ByClosing()); err != nil {
+		return fmt.Errorf("failed to delete queue message %v, reason %v", messageID, err)
+	}
+	return nil
+}
+
+// newRequest returns an authorized request of the queue service. Query parameters of the queue URL, e.g. a shared
+// access signature, are kept.
+func (q *azureEventQueueWrapperImpl) newRequest(ctx context.Context, method string, path string, params url.Values) (
+	*http.Request, error) {
+	u := *q.queueURL
+	u.Path = q.queueURL.Path + path
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureStorageAPIVersion)
+	return autorest.Prepare(req, q.client.WithAuthorization())
+}
@@ -29,3 +29,7 @@ func (h *azureCloudCommonHelperImpl) SetAccountCredentialsFunc() internal.CloudC
 func (h *azureCloudCommonHelperImpl) GetCloudCredentialsComparatorFunc() internal.CloudCredentialComparatorFunc {
 	return compareAccountCredentials
 }
+
+func (h *azureCloudCommonHelperImpl) GetInventoryEventSourceCreateFunc() internal.CloudInventoryEventSourceCreatorFunc {
+	return newAzureInventoryEventSource
+}
@@ -72,14 +72,28 @@ func (c *azureCloud) InstancesGivenProviderAccount(accountNamespacedName *types.
 	return vmCRDs, err
 }
 
+// VpcsGivenProviderAccount returns Vpc CRD for all virtual private clouds of a given cloud provider account.
+func (c *azureCloud) VpcsGivenProviderAccount(accountNamespacedName *types.NamespacedName) ([]*v1alpha1.Vpc, error) {
+	return c.cloudCommon.GetCloudAccountVpcCRDs(accountNamespacedName)
+}
+
 // IsVirtualPrivateCloudPresent returns true if given ID is managed by the cloud, else false.
 func (c *azureCloud) IsVirtualPrivateCloudPresent(vpcUniqueIdentifier string) bool {
-	if accCfg := c.getVnetAccount(vpcUniqueIdentifier); accCfg == nil {
+	if accCfg, _ := c.getVnetAccount(vpcUniqueIdentifier); accCfg == nil {
 		return false
 	}
 	return true
 }
 
+// GetVpcAccount returns namespaced name of the account managing given ID, nil if not managed by the cloud.
+func (c *azureCloud) GetVpcAccount(vpcUniqueIdentifier string) *types.NamespacedName {
+	accCfg, _ := c.getVnetAccount(vpcUniqueIdentifier)
+	if accCfg == nil {
+		return nil
+	}
+	return accCfg.GetNamespacedName()
+}
+
 // ////////////////////////////////////////////////////////
 // 	AccountMgmtInterface Implementation
 // ////////////////////////////////////////////////////////
@@ -106,3 +120,8 @@ func (c *azureCloud) RemoveAccountResourcesSelector(accNamespacedName *types.Nam
 func (c *azureCloud) GetAccountStatus(accNamespacedName *types.NamespacedName) (*cloudv1alpha1.CloudProviderAccountStatus, error) {
 	return c.cloudCommon.GetStatus(accNamespacedName)
 }
+
+// WatchAccountInventory returns a channel notified when account inventory is updated by cloud resource events.
+func (c *azureCloud) WatchAccountInventory(accNamespacedName *types.NamespacedName, stopCh <-chan struct{}) (<-chan struct{}, error) {
+	return c.cloudCommon.WatchInventory(accNamespacedName, stopCh)
+}
@@ -17,9 +17,12 @@ package azure
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-03-01/network"
 	"github.com/mohae/deepcopy"
 
 	"github.com/cenkalti/backoff/v4"
@@ -31,6 +34,7 @@ import (
 
 type computeServiceConfig struct {
 	accountName            string
+	name                   internal.CloudServiceName
 	nwIntfAPIClient        azureNwIntfWrapper
 	nsgAPIClient           azureNsgWrapper
 	asgAPIClient           azureAsgWrapper
@@ -46,9 +50,10 @@ type computeResourcesCacheSnapshot struct {
 	virtualMachines map[cloudcommon.InstanceID]*virtualMachineTable
 	vnetIDs         map[string]struct{}
 	vnetPeers       map[string][][]string
+	vnets           []network.VirtualNetwork
 }
 
-func newComputeServiceConfig(name string, service azureServiceClientCreateInterface,
+func newComputeServiceConfig(name string, serviceName internal.CloudServiceName, service azureServiceClientCreateInterface,
 	credentials *azureAccountCredentials) (internal.CloudServiceInterface, error) {
 	// create compute sdk api client
 	nwIntfAPIClient, err := service.networkInterfaces(credentials.subscriptionID)
@@ -79,6 +84,7 @@ func newComputeServiceConfig(name string, service azureServiceClientCreateInterf
 
 	config := &computeServiceConfig{
 		accountName:            name,
+		name:                   serviceName,
 		nwIntfAPIClient:        nwIntfAPIClient,
 		nsgAPIClient:           securityGroupsAPIClient,
 		asgAPIClient:           applicationSecurityGroupsAPIClient,
@@ -120,7 +126,7 @@ func (computeCfg *computeServiceConfig) getCachedVirtualMachines() []*virtualMac
 		instancesToReturn = append(instancesToReturn, virtualMachine)
 	}
 
-	azurePluginLogger().V(1).Info("cached instances", "service", azureComputeServiceNameCompute, "account", computeCfg.accountName,
+	azurePluginLogger().V(1).Info("cached instances", "service", computeCfg.name, "account", computeCfg.accountName,
 		"instances", len(instancesToReturn))
 	return instancesToReturn
 }
@@ -141,6 +147,18 @@ func (computeCfg *computeServiceConfig) getCachedVnetIDs() map[string]struct{} {
 	return vnetIDsCopy
 }
 
+// getCachedVnets returns virtual networks of the service region from the cache for the account.
+func (computeCfg *computeServiceConfig) getCachedVnets() []network.VirtualNetwork {
+	snapshot := computeCfg.resourcesCache.GetSnapshot()
+	if snapshot == nil {
+		return []network.VirtualNetwork{}
+	}
+	vnets := snapshot.(*computeResourcesCacheSnapshot).vnets
+	vnetsToReturn := make([]network.VirtualNetwork, 0, len(vnets))
+	vnetsToReturn = append(vnetsToReturn, vnets...)
+	return vnetsToReturn
+}
+
 func (computeCfg *computeServiceConfig) getVnetPeers(vnetID string) [][]string {
 	snapshot := computeCfg.resourcesCache.GetSnapshot()
 	if snapshot == nil {
@@ -154,13 +172,20 @@ func (computeCfg *computeServiceConfig) getVnetPeers(vnetID string) [][]string {
 	return vnetPeersCopy
 }
 
-func (computeCfg *computeServiceConfig) getVirtualMachines() ([]*virtualMachineTable, error) {
+// getVirtualMachines returns virtual machines matching configured filters, and restricted to vmIDs if any provided.
+func (computeCfg *computeServiceConfig) getVirtualMachines(vmIDs ...string) ([]*virtualMachineTable, error) {
 	var subscriptions []string
 	subscriptions = append(subscriptions, computeCfg.credentials.subscriptionID)
 
 	var virtualMachines []*virtualMachineTable
 	filters, _ := computeCfg.getComputeResourceFilters()
 	for _, filter := range filters {
+		if len(vmIDs) != 0 {
+			var err error
+			if filter, err = restrictVMsTableQueryToVMIDs(filter, vmIDs); err != nil {
+				return nil, err
+			}
+		}
 		virtualMachineRows, _, err := getVirtualMachineTable(computeCfg.resourceGraphAPIClient, filter, subscriptions)
 		if err != nil {
 			return nil, err
@@ -168,7 +193,7 @@ func (computeCfg *computeServiceConfig) getVirtualMachines() ([]*virtualMachineT
 		virtualMachines = append(virtualMachines, virtualMachineRows...)
 	}
 
-	azurePluginLogger().V(1).Info("instances from cloud", "service", azureComputeServiceNameCompute, "account", computeCfg.accountName,
+	azurePluginLogger().V(1).Info("instances from cloud", "service", computeCfg.name, "account", computeCfg.accountName,
 		"instances", len(virtualMachines))
 
 	return virtualMachines, nil
@@ -207,19 +232,103 @@ func (computeCfg *computeServiceConfig) DoResourceInventory() error {
 	if err == nil {
 		exists := struct{}{}
 		vnetIDs := make(map[string]struct{})
-		vpcPeers, _ := computeCfg.buildMapVpcPeers()
+		vnets, _ := computeCfg.getVirtualNetworks()
+		vpcPeers := computeCfg.buildMapVpcPeers(vnets)
 		vmIDToInfoMap := make(map[cloudcommon.InstanceID]*virtualMachineTable)
 		for _, vm := range virtualMachines {
 			id := cloudcommon.InstanceID(strings.ToLower(*vm.ID))
 			vmIDToInfoMap[id] = vm
 			vnetIDs[*vm.VnetID] = exists
 		}
-		computeCfg.resourcesCache.UpdateSnapshot(&computeResourcesCacheSnapshot{vmIDToInfoMap, vnetIDs, vpcPeers})
+		// virtual networks are listed for the subscription, only those of the service region are cached.
+		var regionVnets []network.VirtualNetwork
+		for _, vnet := range vnets {
+			if vnet.Location != nil && strings.EqualFold(*vnet.Location, computeCfg.credentials.region) {
+				regionVnets = append(regionVnets, vnet)
+			}
+		}
+		computeCfg.resourcesCache.UpdateSnapshot(&computeResourcesCacheSnapshot{vmIDToInfoMap, vnetIDs, vpcPeers, regionVnets})
 	}
 
 	return err
 }
 
+// ApplyInventoryEvents updates cached virtual machines with events of virtual machines and their network interfaces.
+// Virtual machines of update events are queried with configured filters, so that the cache holds the virtual machines a
+// full inventory would.
+func (computeCfg *computeServiceConfig) ApplyInventoryEvents(events []*internal.InventoryEvent) (bool, error) {
+	snapshot, ok := computeCfg.resourcesCache.GetSnapshot().(*computeResourcesCacheSnapshot)
+	if !ok || snapshot == nil {
+		// events before the first inventory are covered by it.
+		return false, nil
+	}
+
+	// network interface events apply to the cached virtual machines using them.
+	nicToVMIDs := make(map[string][]string)
+	for id, vm := range snapshot.virtualMachines {
+		for _, nic := range vm.NetworkInterfaces {
+			if nic.ID != nil {
+				nicID := strings.ToLower(*nic.ID)
+				nicToVMIDs[nicID] = append(nicToVMIDs[nicID], string(id))
+			}
+		}
+	}
+	eventTypes := make(map[string]internal.InventoryEventType)
+	for _, event := range events {
+		resourceID := strings.ToLower(event.ResourceID)
+		if !strings.Contains(resourceID, azureResourceTypeNetworkInterfaces) {
+			eventTypes[resourceID] = event.Type
+			continue
+		}
+		for _, vmID := range nicToVMIDs[resourceID] {
+			eventTypes[vmID] = internal.InventoryEventUpdated
+		}
+	}
+	var updatedVMIDs []string
+	for vmID, eventType := range eventTypes {
+		if eventType == internal.InventoryEventUpdated {
+			updatedVMIDs = append(updatedVMIDs, vmID)
+		}
+	}
+	var virtualMachines []*virtualMachineTable
+	if len(updatedVMIDs) != 0 {
+		sort.Strings(updatedVMIDs)
+		var err error
+		if virtualMachines, err = computeCfg.getVirtualMachines(updatedVMIDs...); err != nil {
+			return false, err
+		}
+	}
+
+	vmIDToInfoMap := make(map[cloudcommon.InstanceID]*virtualMachineTable, len(snapshot.virtualMachines))
+	for id, vm := range snapshot.virtualMachines {
+		if _, found := eventTypes[string(id)]; !found {
+			vmIDToInfoMap[id] = vm
+		}
+	}
+	for _, vm := range virtualMachines {
+		vmIDToInfoMap[cloudcommon.InstanceID(strings.ToLower(*vm.ID))] = vm
+	}
+	updated := len(vmIDToInfoMap) != len(snapshot.virtualMachines)
+	for id, vm := range vmIDToInfoMap {
+		if !reflect.DeepEqual(snapshot.virtualMachines[id], vm) {
+			updated = true
+		}
+	}
+	if !updated {
+		return false, nil
+	}
+
+	vnetIDs := make(map[string]struct{})
+	for _, vm := range vmIDToInfoMap {
+		vnetIDs[*vm.VnetID] = struct{}{}
+	}
+	computeCfg.resourcesCache.UpdateSnapshot(&computeResourcesCacheSnapshot{vmIDToInfoMap, vnetIDs, snapshot.vnetPeers,
+		snapshot.vnets})
+	azurePluginLogger().V(1).Info("instances updated by events", "service", computeCfg.name, "account",
+		computeCfg.accountName, "instances", len(eventTypes))
+	return true, nil
+}
+
 func (computeCfg *computeServiceConfig) SetResourceFilters(selector *v1alpha1.CloudEntitySelector) {
 	subscriptionIDs := []string{computeCfg.credentials.subscriptionID}
 	tenantIDs := []string{computeCfg.credentials.tenantID}
@@ -240,15 +349,27 @@ func (computeCfg *computeServiceConfig) GetResourceCRDs(namespace string) *inter
 
 	for _, virtualMachine := range virtualMachines {
 		// build VirtualMachine CRD
-		vmCRD := computeInstanceToVirtualMachineCRD(virtualMachine, namespace)
+		vmCRD := computeInstanceToVirtualMachineCRD(virtualMachine, namespace, computeCfg.credentials.region)
+		if vmCRD == nil {
+			continue
+		}
 		vmCRDs = append(vmCRDs, vmCRD)
 	}
 
-	azurePluginLogger().V(1).Info("CRDs", "service", azureComputeServiceNameCompute, "account", computeCfg.accountName,
+	azurePluginLogger().V(1).Info("CRDs", "service", computeCfg.name, "account", computeCfg.accountName,
 		"virtual-machine CRDs", len(vmCRDs))
 
+	vnets := computeCfg.getCachedVnets()
+	vpcCRDs := make([]*v1alpha1.Vpc, 0, len(vnets))
+	for i := range vnets {
+		if vpcCRD := virtualNetworkToVpcCRD(&vnets[i], namespace); vpcCRD != nil {
+			vpcCRDs = append(vpcCRDs, vpcCRD)
+		}
+	}
+
 	serviceResourceCRDs := &internal.CloudServiceResourceCRDs{}
 	serviceResourceCRDs.SetComputeResourceCRDs(vmCRDs)
+	serviceResourceCRDs.SetVpcResourceCRDs(vpcCRDs)
 
 	return serviceResourceCRDs
 }
@@ -259,7 +380,7 @@ func (computeCfg *computeServiceConfig) HasFiltersConfigured() (bool, bool) {
 }
 
 func (computeCfg *computeServiceConfig) GetName() internal.CloudServiceName {
-	return azureComputeServiceNameCompute
+	return computeCfg.name
 }
 
 func (computeCfg *computeServiceConfig) GetType() internal.CloudServiceType {
@@ -285,13 +406,18 @@ func (computeCfg *computeServiceConfig) UpdateServiceConfig(newConfig internal.C
 	computeCfg.credentials = newComputeServiceConfig.credentials
 }
 
-func (computeCfg *computeServiceConfig) buildMapVpcPeers() (map[string][][]string, error) {
-	vpcPeers := make(map[string][][]string)
-	results, err := computeCfg.vnetAPIClient.listAllComplete(context.Background())
+// getVirtualNetworks gets all virtual networks of the subscription from azure API.
+func (computeCfg *computeServiceConfig) getVirtualNetworks() ([]network.VirtualNetwork, error) {
+	vnets, err := computeCfg.vnetAPIClient.listAllComplete(context.Background())
 	if err != nil {
-		azurePluginLogger().V(0).Info("error getting peering connections", "error", err)
+		azurePluginLogger().V(0).Info("error getting virtual networks", "error", err)
 		return nil, err
 	}
+	return vnets, nil
+}
+
+func (computeCfg *computeServiceConfig) buildMapVpcPeers(results []network.VirtualNetwork) map[string][][]string {
+	vpcPeers := make(map[string][][]string)
 	for _, result := range results {
 		if len(*result.VirtualNetworkPropertiesFormat.VirtualNetworkPeerings) > 0 {
 			for _, peerConn := range *result.VirtualNetworkPropertiesFormat.VirtualNetworkPeerings {
@@ -305,5 +431,5 @@ func (computeCfg *computeServiceConfig) buildMapVpcPeers() (map[string][][]strin
 			}
 		}
 	}
-	return vpcPeers, nil
+	return vpcPeers
 }
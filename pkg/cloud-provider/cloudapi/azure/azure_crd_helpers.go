@@ -15,7 +15,11 @@
 package azure
 
 import (
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-03-01/network"
 
 	"antrea.io/nephe/apis/crd/v1alpha1"
 	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
@@ -31,7 +35,7 @@ var azureStatusMap = map[string]string{
 	"PowerState/starting":     "starting",
 }
 
-func computeInstanceToVirtualMachineCRD(instance *virtualMachineTable, namespace string) *v1alpha1.VirtualMachine {
+func computeInstanceToVirtualMachineCRD(instance *virtualMachineTable, namespace string, region string) *v1alpha1.VirtualMachine {
 	tags := make(map[string]string)
 
 	vmTags := instance.Tags
@@ -61,7 +65,7 @@ func computeInstanceToVirtualMachineCRD(instance *virtualMachineTable, namespace
 		if len(nwInf.PublicIps) > 0 {
 			for _, publicIP := range nwInf.PublicIps {
 				ipAddressCRD := v1alpha1.IPAddress{
-					AddressType: v1alpha1.AddressTypeInternalIP,
+					AddressType: v1alpha1.AddressTypeExternalIP,
 					Address:     *publicIP,
 				}
 				ipAddressCRDs = append(ipAddressCRDs, ipAddressCRD)
@@ -72,10 +76,16 @@ func computeInstanceToVirtualMachineCRD(instance *virtualMachineTable, namespace
 			macAddress = *nwInf.MacAddress
 		}
 
+		subnet := ""
+		if nwInf.SubnetID != nil {
+			subnet = *nwInf.SubnetID
+		}
+
 		networkInterface := v1alpha1.NetworkInterface{
-			Name: *nwInf.ID,
-			MAC:  macAddress,
-			IPs:  ipAddressCRDs,
+			Name:   *nwInf.ID,
+			MAC:    macAddress,
+			IPs:    ipAddressCRDs,
+			Subnet: subnet,
 		}
 		networkInterfaces = append(networkInterfaces, networkInterface)
 	}
@@ -96,5 +106,95 @@ func computeInstanceToVirtualMachineCRD(instance *virtualMachineTable, namespace
 
 	return utils.GenerateVirtualMachineCRD(crdName, strings.ToLower(cloudName), strings.ToLower(cloudID), namespace,
 		strings.ToLower(cloudNetworkID), cloudNetworkShortID,
-		status, tags, networkInterfaces, providerType)
+		status, tags, networkInterfaces, computeInstanceProperties(instance, region), providerType)
+}
+
+// computeInstanceProperties returns cloud properties of azure virtual machine.
+func computeInstanceProperties(instance *virtualMachineTable, region string) *utils.VirtualMachineProperties {
+	properties := &utils.VirtualMachineProperties{Region: region}
+	if instance.VMSize != nil {
+		properties.InstanceType = *instance.VMSize
+	}
+	if instance.OsType != nil {
+		properties.OSType = *instance.OsType
+	}
+	// zones are named as <region>-<zone number>, same as zone labels of AKS nodes.
+	if len(instance.Zones) > 0 && instance.Zones[0] != nil {
+		properties.AvailabilityZone = region + "-" + *instance.Zones[0]
+	}
+	if image := instance.ImageReference; image != nil {
+		if image.ID != nil {
+			properties.ImageID = strings.ToLower(*image.ID)
+		} else if image.Publisher != nil && image.Offer != nil && image.Sku != nil && image.Version != nil {
+			properties.ImageID = strings.Join([]string{*image.Publisher, *image.Offer, *image.Sku, *image.Version}, ":")
+		}
+	}
+	if instance.TimeCreated != nil {
+		if launchTime, err := time.Parse(time.RFC3339, *instance.TimeCreated); err == nil {
+			properties.LaunchTime = &launchTime
+		}
+	}
+
+	securityGroups := make(map[string]struct{})
+	for _, nwInf := range instance.NetworkInterfaces {
+		if nwInf.NsgID != nil && len(*nwInf.NsgID) > 0 {
+			securityGroups[strings.ToLower(*nwInf.NsgID)] = struct{}{}
+		}
+		for _, asgs := range nwInf.ApplicationSecurityGroups {
+			for _, asg := range asgs {
+				if asg != nil && asg.ID != nil {
+					securityGroups[strings.ToLower(*asg.ID)] = struct{}{}
+				}
+			}
+		}
+	}
+	for sg := range securityGroups {
+		properties.SecurityGroups = append(properties.SecurityGroups, sg)
+	}
+	sort.Strings(properties.SecurityGroups)
+	return properties
+}
+
+// virtualNetworkToVpcCRD converts azure virtual network to Vpc CRD.
+func virtualNetworkToVpcCRD(vnet *network.VirtualNetwork, namespace string) *v1alpha1.Vpc {
+	if vnet.ID == nil || vnet.Name == nil {
+		return nil
+	}
+	tags := make(map[string]string)
+	for key, value := range vnet.Tags {
+		if value != nil {
+			tags[key] = *value
+		}
+	}
+
+	var cidrs []string
+	var peerings []string
+	if properties := vnet.VirtualNetworkPropertiesFormat; properties != nil {
+		if properties.AddressSpace != nil && properties.AddressSpace.AddressPrefixes != nil {
+			cidrs = append(cidrs, *properties.AddressSpace.AddressPrefixes...)
+		}
+		if properties.VirtualNetworkPeerings != nil {
+			for _, peering := range *properties.VirtualNetworkPeerings {
+				if peering.VirtualNetworkPeeringPropertiesFormat == nil ||
+					peering.VirtualNetworkPeeringPropertiesFormat.RemoteVirtualNetwork == nil ||
+					peering.VirtualNetworkPeeringPropertiesFormat.RemoteVirtualNetwork.ID == nil {
+					continue
+				}
+				peerings = append(peerings, strings.ToLower(*peering.VirtualNetworkPeeringPropertiesFormat.RemoteVirtualNetwork.ID))
+			}
+		}
+	}
+	sort.Strings(cidrs)
+	sort.Strings(peerings)
+
+	region := ""
+	if vnet.Location != nil {
+		region = strings.ToLower(*vnet.Location)
+	}
+	cloudID := strings.ToLower(*vnet.ID)
+	cloudName := strings.ToLower(*vnet.Name)
+	// crd name matches the virtual private cloud of VirtualMachine CRDs in the virtual network.
+	crdName := utils.GenerateShortResourceIdentifier(cloudID, cloudName)
+
+	return utils.GenerateVpcCRD(crdName, cloudName, cloudID, namespace, region, cidrs, tags, peerings, providerType)
 }
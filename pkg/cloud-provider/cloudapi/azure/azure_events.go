@@ -0,0 +1,211 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/types"
+
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
+)
+
+const (
+	azureEventTypeResourceWriteSuccess  = "Microsoft.Resources.ResourceWriteSuccess"
+	azureEventTypeResourceActionSuccess = "Microsoft.Resources.ResourceActionSuccess"
+	azureEventTypeResourceDeleteSuccess = "Microsoft.Resources.ResourceDeleteSuccess"
+
+	azureResourceTypeVirtualMachines   = "/providers/microsoft.compute/virtualmachines/"
+	azureResourceTypeNetworkInterfaces = "/providers/microsoft.network/networkinterfaces/"
+	// azureResourceIDSegments is the number of segments of a resource ID, like
+	// /subscriptions/<id>/resourceGroups/<name>/providers/<namespace>/<type>/<name>.
+	azureResourceIDSegments = 9
+
+	// azureStorageAPIVersion is the version of the storage queue service REST API.
+	azureStorageAPIVersion = "2019-12-12"
+	// azureEventQueueMaxMessages is the maximum number of messages received at a time.
+	azureEventQueueMaxMessages = 32
+	// azureEventQueueVisibilityTimeout is the duration received messages are invisible to receivers, until deleted.
+	azureEventQueueVisibilityTimeout = 5 * time.Minute
+)
+
+// azureEventQueuePollInterval is the duration to wait before receiving again from an empty event queue.
+var azureEventQueuePollInterval = 5 * time.Second
+
+// azureInventoryEventSource receives resource events of a subscription from a storage queue. Events are delivered to
+// the queue by an Event Grid subscription of the Azure subscription, in Event Grid or CloudEvents schema.
+type azureInventoryEventSource struct {
+	accountName string
+	apiClient   azureEventQueueWrapper
+}
+
+// azureEvent is an Event Grid event, in Event Grid schema or CloudEvents schema.
+type azureEvent struct {
+	EventType string `json:"eventType"`
+	Type      string `json:"type"`
+	Subject   string `json:"subject"`
+	Data      struct {
+		ResourceURI string `json:"resourceUri"`
+	} `json:"data"`
+}
+
+func newAzureInventoryEventSource(accountNamespacedName *types.NamespacedName, accCredentials interface{},
+	azureSpecificHelper interface{}) (internal.InventoryEventSource, error) {
+	azureServicesHelper := azureSpecificHelper.(azureServicesHelper)
+	azureAccountCredentials := accCredentials.(*azureAccountCredentials)
+	if len(azureAccountCredentials.eventQueueURL) == 0 {
+		return nil, nil
+	}
+
+	azureServiceClientCreator, err := azureServicesHelper.newServiceSdkConfigProvider(azureAccountCredentials)
+	if err != nil {
+		return nil, err
+	}
+	apiClient, err := azureServiceClientCreator.eventQueue(azureAccountCredentials.eventQueueURL)
+	if err != nil {
+		return nil, fmt.Errorf("error creating event queue api client for account : %v, err: %v", accountNamespacedName, err)
+	}
+
+	return &azureInventoryEventSource{
+		accountName: accountNamespacedName.String(),
+		apiClient:   apiClient,
+	}, nil
+}
+
+// eventQueue returns api client of the storage queue at queueURL. A queue URL carrying a shared access signature is
+// accessed with it, otherwise with account credentials.
+func (p *azureServiceSdkConfigProvider) eventQueue(queueURL string) (azureEventQueueWrapper, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return nil, err
+	}
+	client := autorest.NewClientWithUserAgent("")
+	client.Authorizer = p.storageAuthorizer
+	if len(u.Query().Get("sig")) != 0 {
+		client.Authorizer = autorest.NullAuthorizer{}
+	}
+	return &azureEventQueueWrapperImpl{client: client, queueURL: u}, nil
+}
+
+// ReceiveEvents receives messages of the event queue and returns virtual machine and network interface events of the
+// messages.
+func (s *azureInventoryEventSource) ReceiveEvents() ([]*internal.InventoryEvent, error) {
+	messages, err := s.apiClient.getMessages(context.Background(), azureEventQueueMaxMessages, azureEventQueueVisibilityTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		// storage queues do not wait for messages.
+		time.Sleep(azureEventQueuePollInterval)
+		return nil, nil
+	}
+
+	var events []*internal.InventoryEvent
+	var ignoredMessages []*internal.InventoryEvent
+	for _, message := range messages {
+		receipt := message.MessageID + ":" + message.PopReceipt
+		messageEvents := parseAzureEvents(message.MessageText)
+		if len(messageEvents) == 0 {
+			ignoredMessages = append(ignoredMessages, &internal.InventoryEvent{Receipt: receipt})
+			continue
+		}
+		for _, event := range messageEvents {
+			event.Receipt = receipt
+		}
+		events = append(events, messageEvents...)
+	}
+	if len(ignoredMessages) != 0 {
+		azurePluginLogger().V(1).Info("messages without resource events ignored", "account", s.accountName,
+			"messages", len(ignoredMessages))
+		if err := s.AckEvents(ignoredMessages); err != nil {
+			// ignored messages are received again.
+			azurePluginLogger().Error(err, "failed to delete ignored messages", "account", s.accountName)
+		}
+	}
+	return events, nil
+}
+
+// AckEvents deletes messages of events from the event queue.
+func (s *azureInventoryEventSource) AckEvents(events []*internal.InventoryEvent) error {
+	var err error
+	seen := make(map[string]struct{})
+	for _, event := range events {
+		if _, found := seen[event.Receipt]; found {
+			continue
+		}
+		seen[event.Receipt] = struct{}{}
+		fields := strings.SplitN(event.Receipt, ":", 2)
+		if len(fields) != 2 {
+			err = multierr.Append(err, fmt.Errorf("invalid receipt %v", event.Receipt))
+			continue
+		}
+		err = multierr.Append(err, s.apiClient.deleteMessage(context.Background(), fields[0], fields[1]))
+	}
+	return err
+}
+
+// parseAzureEvents returns virtual machine and network interface events of a message of the event queue. Event Grid
+// encodes messages delivered to storage queues in base64, unless configured otherwise.
+func parseAzureEvents(text string) []*internal.InventoryEvent {
+	body := []byte(text)
+	if decoded, err := base64.StdEncoding.DecodeString(text); err == nil {
+		body = decoded
+	}
+	var azureEvents []azureEvent
+	if err := json.Unmarshal(body, &azureEvents); err != nil {
+		event := azureEvent{}
+		if err := json.Unmarshal(body, &event); err != nil {
+			return nil
+		}
+		azureEvents = []azureEvent{event}
+	}
+
+	var events []*internal.InventoryEvent
+	for _, azureEvent := range azureEvents {
+		eventType := azureEvent.EventType
+		if len(eventType) == 0 {
+			eventType = azureEvent.Type
+		}
+		var inventoryEventType internal.InventoryEventType
+		switch eventType {
+		case azureEventTypeResourceWriteSuccess, azureEventTypeResourceActionSuccess:
+			inventoryEventType = internal.InventoryEventUpdated
+		case azureEventTypeResourceDeleteSuccess:
+			inventoryEventType = internal.InventoryEventDeleted
+		default:
+			continue
+		}
+		resourceID := azureEvent.Data.ResourceURI
+		if len(resourceID) == 0 {
+			resourceID = azureEvent.Subject
+		}
+		resourceID = strings.ToLower(resourceID)
+		if len(strings.Split(resourceID, "/")) != azureResourceIDSegments ||
+			!(strings.Contains(resourceID, azureResourceTypeVirtualMachines) ||
+				strings.Contains(resourceID, azureResourceTypeNetworkInterfaces)) {
+			continue
+		}
+		events = append(events, &internal.InventoryEvent{Type: inventoryEventType, ResourceID: resourceID})
+	}
+	return events
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/services/resourcegraph/mgmt/2021-03-01/resourcegraph"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
+)
+
+const testQueueSAS = "sv=2019-12-12&sig=signature"
+
+// storageQueueStandIn is a local stand-in of a storage queue, serving get and delete messages of the queue service
+// REST API. Received messages are not visible again until deleted.
+type storageQueueStandIn struct {
+	mutex    sync.Mutex
+	nextID   int
+	visible  []string
+	messages map[string]string
+}
+
+type storageQueueStandInMessage struct {
+	MessageID   string `xml:"MessageId"`
+	PopReceipt  string `xml:"PopReceipt"`
+	MessageText string `xml:"MessageText"`
+}
+
+func newStorageQueueStandIn() *storageQueueStandIn {
+	return &storageQueueStandIn{messages: make(map[string]string)}
+}
+
+// send adds a message to the queue, encoded in base64 as by Event Grid.
+func (q *storageQueueStandIn) send(text string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.nextID++
+	id := fmt.Sprintf("message-%d", q.nextID)
+	q.messages[id] = base64.StdEncoding.EncodeToString([]byte(text))
+	q.visible = append(q.visible, id)
+}
+
+// pending returns the number of messages not deleted.
+func (q *storageQueueStandIn) pending() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.messages)
+}
+
+func (q *storageQueueStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if r.URL.Query().Get("sig") != "signature" || len(r.Header.Get("x-ms-version")) == 0 {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/queue/messages":
+		response := struct {
+			XMLName  xml.Name                     `xml:"QueueMessagesList"`
+			Messages []storageQueueStandInMessage `xml:"QueueMessage"`
+		}{}
+		for _, id := range q.visible {
+			response.Messages = append(response.Messages, storageQueueStandInMessage{MessageID: id,
+				PopReceipt: "pop-" + id, MessageText: q.messages[id]})
+		}
+		q.visible = nil
+		body, _ := xml.Marshal(response)
+		_, _ = w.Write(body)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/queue/messages/"):
+		id := strings.TrimPrefix(r.URL.Path, "/queue/messages/")
+		if _, found := q.messages[id]; !found || r.URL.Query().Get("popreceipt") != "pop-"+id {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(q.messages, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func getResourceEvent(eventType, resourceID string) string {
+	return fmt.Sprintf(`{"id":"event","topic":"/subscriptions/SubID","subject":"%s","eventType":"%s",`+
+		`"data":{"resourceUri":"%s","operationName":"write"},"dataVersion":"","metadataVersion":"1"}`,
+		resourceID, eventType, resourceID)
+}
+
+var _ = Describe("Azure inventory events", func() {
+	var (
+		testSubID = "SubID"
+		vmID01    = "/subscriptions/SubID/resourceGroups/testRG/providers/Microsoft.Compute/virtualMachines/vm01"
+		vmID02    = "/subscriptions/SubID/resourceGroups/testRG/providers/Microsoft.Compute/virtualMachines/vm02"
+		nicID01   = "/subscriptions/SubID/resourceGroups/testRG/providers/Microsoft.Network/networkInterfaces/nic01"
+		vnetID01  = "/subscriptions/subid/resourcegroups/testrg/providers/microsoft.network/virtualnetworks/vnet01"
+
+		mockCtrl *gomock.Controller
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("Should receive and acknowledge events of the storage queue", func() {
+		queue := newStorageQueueStandIn()
+		server := httptest.NewServer(queue)
+		defer server.Close()
+
+		queue.send(getResourceEvent(azureEventTypeResourceWriteSuccess, vmID01))
+		queue.send(getResourceEvent(azureEventTypeResourceDeleteSuccess, vmID02))
+		queue.send(getResourceEvent(azureEventTypeResourceWriteSuccess, vmID01+"/extensions/ext01"))
+		queue.send(`[{"specversion":"1.0","type":"` + azureEventTypeResourceActionSuccess + `","subject":"` + nicID01 + `"}]`)
+
+		mockAzureServiceHelper := NewMockazureServicesHelper(mockCtrl)
+		provider := &azureServiceSdkConfigProvider{}
+		mockAzureServiceHelper.EXPECT().newServiceSdkConfigProvider(gomock.Any()).Return(provider, nil)
+		source, err := newAzureInventoryEventSource(&types.NamespacedName{Namespace: "namespace01", Name: "account01"},
+			&azureAccountCredentials{subscriptionID: testSubID, eventQueueURL: server.URL + "/queue?" + testQueueSAS},
+			mockAzureServiceHelper)
+		Expect(err).ToNot(HaveOccurred())
+
+		events, err := source.ReceiveEvents()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(3))
+		Expect(events[0].Type).To(Equal(internal.InventoryEventUpdated))
+		Expect(events[0].ResourceID).To(Equal(strings.ToLower(vmID01)))
+		Expect(events[1].Type).To(Equal(internal.InventoryEventDeleted))
+		Expect(events[1].ResourceID).To(Equal(strings.ToLower(vmID02)))
+		Expect(events[2].Type).To(Equal(internal.InventoryEventUpdated))
+		Expect(events[2].ResourceID).To(Equal(strings.ToLower(nicID01)))
+		// message of the extension event is ignored and deleted.
+		Expect(queue.pending()).To(Equal(3))
+
+		Expect(source.AckEvents(events)).To(Succeed())
+		Expect(queue.pending()).To(Equal(0))
+	})
+
+	It("Should update cached virtual machines with events", func() {
+		mockResourceGraph := NewMockazureResourceGraphWrapper(mockCtrl)
+		computeCfg := &computeServiceConfig{
+			accountName:            "namespace01/account01",
+			name:                   azureComputeServiceNameCompute,
+			resourceGraphAPIClient: mockResourceGraph,
+			resourcesCache:         &internal.CloudServiceResourcesCache{},
+			credentials:            &azureAccountCredentials{subscriptionID: testSubID, tenantID: "TenantID", region: "eastus"},
+			computeFilters:         map[string][]*string{"selector": {to.StringPtr("query")}},
+		}
+		getVM := func(id, status string) *virtualMachineTable {
+			return &virtualMachineTable{ID: to.StringPtr(strings.ToLower(id)), Name: to.StringPtr(id[strings.LastIndex(id, "/")+1:]),
+				Status: to.StringPtr(status), VnetID: to.StringPtr(vnetID01),
+				NetworkInterfaces: []*networkInterface{{ID: to.StringPtr(strings.ToLower(nicID01))}}}
+		}
+
+		// events before the first inventory are ignored.
+		updated, err := computeCfg.ApplyInventoryEvents([]*internal.InventoryEvent{
+			{Type: internal.InventoryEventUpdated, ResourceID: strings.ToLower(vmID01)}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated).To(BeFalse())
+
+		computeCfg.resourcesCache.UpdateSnapshot(&computeResourcesCacheSnapshot{
+			virtualMachines: map[cloudcommon.InstanceID]*virtualMachineTable{
+				cloudcommon.InstanceID(strings.ToLower(vmID01)): getVM(vmID01, "PowerState/running"),
+				cloudcommon.InstanceID(strings.ToLower(vmID02)): getVM(vmID02, "PowerState/running"),
+			},
+			vnetIDs: map[string]struct{}{vnetID01: {}},
+		})
+
+		var records int64 = 1
+		row := map[string]interface{}{
+			"id":                strings.ToLower(vmID01),
+			"name":              "vm01",
+			"status":            "PowerState/stopped",
+			"vnetId":            vnetID01,
+			"networkInterfaces": []interface{}{map[string]interface{}{"id": strings.ToLower(nicID01)}},
+		}
+		expectedQuery, _ := restrictVMsTableQueryToVMIDs(to.StringPtr("query"), []string{vmID01})
+		mockResourceGraph.EXPECT().resources(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, request resourcegraph.QueryRequest) (resourcegraph.QueryResponse, error) {
+				Expect(*request.Query).To(Equal(*expectedQuery))
+				return resourcegraph.QueryResponse{TotalRecords: &records, Data: []interface{}{row}}, nil
+			})
+
+		updated, err = computeCfg.ApplyInventoryEvents([]*internal.InventoryEvent{
+			{Type: internal.InventoryEventUpdated, ResourceID: strings.ToLower(nicID01)},
+			{Type: internal.InventoryEventDeleted, ResourceID: strings.ToLower(vmID02)},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated).To(BeTrue())
+		vms := computeCfg.getCachedVirtualMachines()
+		Expect(vms).To(HaveLen(1))
+		Expect(*vms[0].ID).To(Equal(strings.ToLower(vmID01)))
+		Expect(*vms[0].Status).To(Equal("PowerState/stopped"))
+		Expect(computeCfg.getCachedVnetIDs()).To(HaveKey(vnetID01))
+	})
+})
@@ -25,6 +25,10 @@ import (
 	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
 )
 
+// networkInterfaceOriginalNsgTagKey is the network interface tag recording ID of network security group not created by
+// nephe, replaced by nephe created network security group.
+const networkInterfaceOriginalNsgTagKey = "nephe-original-nsg"
+
 // networkInterfaces returns network interfaces SDK api client.
 func (p *azureServiceSdkConfigProvider) networkInterfaces(subscriptionID string) (azureNwIntfWrapper, error) {
 	interfacesClient := network.NewInterfacesClient(subscriptionID)
@@ -120,25 +124,56 @@ func getAsgUpdatedIPConfigurations(nwIntfObj *network.Interface, asgObjToAttachO
 	return ipConfigurations
 }
 
+// getUpdatedNetworkInterfaceNsgAndTags returns the nsg and tags of network interface after attaching or detaching nsg.
+// A network security group not created by nephe replaced by nsg is recorded in a network interface tag, and is restored
+// when network interface is no longer attached to any nephe created nsg.
 func getUpdatedNetworkInterfaceNsgAndTags(nwIntfObj *network.Interface, nsgObjToAttachOrDetach network.SecurityGroup, isAttach bool,
 	tagKey string) (*network.SecurityGroup, map[string]*string) {
 	currentTags := nwIntfObj.Tags
 	if isAttach {
-		nwIntfObj.NetworkSecurityGroup = &nsgObjToAttachOrDetach
 		if currentTags == nil {
 			currentTags = make(map[string]*string)
 		}
+		if originalNsgID := getNetworkInterfaceForeignNsgID(nwIntfObj); len(originalNsgID) != 0 {
+			if _, found := currentTags[networkInterfaceOriginalNsgTagKey]; !found {
+				currentTags[networkInterfaceOriginalNsgTagKey] = to.StringPtr(originalNsgID)
+			}
+		}
+		nwIntfObj.NetworkSecurityGroup = &nsgObjToAttachOrDetach
 		currentTags[tagKey] = to.StringPtr("true")
 	} else {
 		delete(currentTags, tagKey)
 		if !hasAnyNepheControllerSecurityGroupTags(currentTags) {
 			nwIntfObj.NetworkSecurityGroup = nil
+			if originalNsgID, found := currentTags[networkInterfaceOriginalNsgTagKey]; found {
+				if originalNsgID != nil && len(*originalNsgID) != 0 {
+					nwIntfObj.NetworkSecurityGroup = &network.SecurityGroup{ID: to.StringPtr(*originalNsgID)}
+				}
+				delete(currentTags, networkInterfaceOriginalNsgTagKey)
+			}
 		}
 	}
 
 	return nwIntfObj.NetworkSecurityGroup, currentTags
 }
 
+// getNetworkInterfaceForeignNsgID returns the ID of network security group not created by nephe attached to network
+// interface, empty if none.
+func getNetworkInterfaceForeignNsgID(nwIntfObj *network.Interface) string {
+	if nwIntfObj.NetworkSecurityGroup == nil || nwIntfObj.NetworkSecurityGroup.ID == nil {
+		return ""
+	}
+	nsgID := *nwIntfObj.NetworkSecurityGroup.ID
+	_, _, nsgName, err := extractFieldsFromAzureResourceID(nsgID)
+	if err != nil {
+		return ""
+	}
+	if _, isAG, isAT := securitygroup.IsNepheControllerCreatedSG(nsgName); isAG || isAT {
+		return ""
+	}
+	return nsgID
+}
+
 func hasAnyNepheControllerSecurityGroupTags(tags map[string]*string) bool {
 	for key := range tags {
 		_, _, isATSG := securitygroup.IsNepheControllerCreatedSG(key)
@@ -17,6 +17,8 @@ package azure
 import (
 	"fmt"
 	"net"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -24,6 +26,7 @@ import (
 	"github.com/Azure/go-autorest/autorest/to"
 
 	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 const (
@@ -32,6 +35,8 @@ const (
 	vnetToVnetDenyRuleDescription = "nephe-at-" + appliedToSecurityGroupNamePerVnet
 	emptyPort                     = "*"
 	virtualnetworkAddressPrefix   = "VirtualNetwork"
+	// azure limits number of security rules per network security group.
+	azureMaxSecurityRulesPerNsg = 1000
 )
 
 var protoNumAzureNameMap = map[int]network.SecurityRuleProtocol{
@@ -46,35 +51,158 @@ var azureProtoNameToNumMap = map[network.SecurityRuleProtocol]int{
 	network.SecurityRuleProtocolUDP:  17,
 }
 
-func updateSecurityRuleNameAndPriority(existingRules []network.SecurityRule, newRules []network.SecurityRule) []network.SecurityRule {
+// Security rules are ordered by their rule priorities, derived from tier and priority of NetworkPolicies, so that
+// rules of higher precedence are evaluated first. The rule priority is encoded in the name of a security rule, such
+// that rules of all appliedTo groups sharing a network security group are ordered. Rules without rule priority are
+// ordered after rules with rule priority, deny rules ahead of allow rules.
+var securityRuleNamePriorityRegexp = regexp.MustCompile(`^\d+-\w+-t(-?\d+)-p(-?[0-9.]+)-r(-?\d+)$`)
+
+// getSecurityRuleName returns name of a security rule of rulePriority, at priority in direction.
+func getSecurityRuleName(priority int32, direction network.SecurityRuleDirection,
+	rulePriority *securitygroup.RulePriority) string {
+	if rulePriority == nil {
+		return fmt.Sprintf("%v-%v", priority, direction)
+	}
+	return fmt.Sprintf("%v-%v-t%v-p%v-r%v", priority, direction, rulePriority.TierPriority,
+		strconv.FormatFloat(rulePriority.PolicyPriority, 'f', -1, 64), rulePriority.RuleIndex)
+}
+
+// getSecurityRulePriority returns rule priority encoded in name of a security rule, or nil if there is none.
+func getSecurityRulePriority(name *string) *securitygroup.RulePriority {
+	if name == nil {
+		return nil
+	}
+	match := securityRuleNamePriorityRegexp.FindStringSubmatch(*name)
+	if match == nil {
+		return nil
+	}
+	tierPriority, err := strconv.ParseInt(match[1], 10, 32)
+	if err != nil {
+		return nil
+	}
+	policyPriority, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return nil
+	}
+	ruleIndex, err := strconv.ParseInt(match[3], 10, 32)
+	if err != nil {
+		return nil
+	}
+	return &securitygroup.RulePriority{TierPriority: int32(tierPriority), PolicyPriority: policyPriority,
+		RuleIndex: int32(ruleIndex)}
+}
+
+// setSecurityRuleNames names security rules realizing a rule of rulePriority.
+func setSecurityRuleNames(rules []network.SecurityRule, rulePriority *securitygroup.RulePriority) {
+	for i := range rules {
+		rules[i].Name = to.StringPtr(getSecurityRuleName(*rules[i].Priority, rules[i].Direction, rulePriority))
+	}
+}
+
+// updateSecurityRuleNameAndPriority assigns names and priorities to existingRules of other appliedTo groups, and
+// newRules of an appliedTo group replacing its currentRules. Priorities are kept stable across updates,
+//   - existingRules keep their priorities, and newRules take priorities of currentRules of the same rule priority and
+//     access, as long as rules remain ordered.
+//   - Other rules take the priority in the middle of the gap to the priority kept by a following rule, leaving room
+//     for rules inserted later. On collision, that is no gap is left, following rules are moved to the next priority
+//     until a gap is found.
+//   - Should priorities run out before vnetToVnetDenyRulePriority, all rules are renumbered from ruleStartPriority.
+func updateSecurityRuleNameAndPriority(existingRules []network.SecurityRule, newRules []network.SecurityRule,
+	currentRules []network.SecurityRule) []network.SecurityRule {
+	type orderedRule struct {
+		rule         network.SecurityRule
+		rulePriority *securitygroup.RulePriority
+		priority     *int32
+	}
+	getKey := func(rule network.SecurityRule, rulePriority *securitygroup.RulePriority) string {
+		if rulePriority == nil {
+			return fmt.Sprintf("none|%v", rule.Access)
+		}
+		return fmt.Sprintf("%+v|%v", *rulePriority, rule.Access)
+	}
+
 	var rules []network.SecurityRule
+	var orderedRules []*orderedRule
 	defaultRulesByName := make(map[string]network.SecurityRule)
-
-	rulePriority := int32(ruleStartPriority)
+	currentPriorities := make(map[string][]int32)
+	for _, rule := range currentRules {
+		if rule.Priority == nil || *rule.Priority == vnetToVnetDenyRulePriority {
+			continue
+		}
+		key := getKey(rule, getSecurityRulePriority(rule.Name))
+		currentPriorities[key] = append(currentPriorities[key], *rule.Priority)
+	}
+	for key := range currentPriorities {
+		priorities := currentPriorities[key]
+		sort.Slice(priorities, func(i, j int) bool { return priorities[i] < priorities[j] })
+	}
 	for _, rule := range existingRules {
 		if *rule.Priority == vnetToVnetDenyRulePriority {
 			defaultRulesByName[*rule.Name] = rule
 			continue
 		}
-		ruleName := fmt.Sprintf("%v-%v", rulePriority, rule.Direction)
-		rule.Name = &ruleName
-		rule.Priority = to.Int32Ptr(rulePriority)
-
-		rules = append(rules, rule)
-		rulePriority++
+		orderedRules = append(orderedRules, &orderedRule{rule: rule, rulePriority: getSecurityRulePriority(rule.Name),
+			priority: rule.Priority})
 	}
-
 	for _, rule := range newRules {
 		if *rule.Priority == vnetToVnetDenyRulePriority {
 			defaultRulesByName[*rule.Name] = rule
 			continue
 		}
-		ruleName := fmt.Sprintf("%v-%v", rulePriority, rule.Direction)
-		rule.Name = &ruleName
-		rule.Priority = to.Int32Ptr(rulePriority)
+		r := &orderedRule{rule: rule, rulePriority: getSecurityRulePriority(rule.Name)}
+		key := getKey(rule, r.rulePriority)
+		if priorities := currentPriorities[key]; len(priorities) > 0 {
+			r.priority = &priorities[0]
+			currentPriorities[key] = priorities[1:]
+		}
+		orderedRules = append(orderedRules, r)
+	}
 
+	sort.SliceStable(orderedRules, func(i, j int) bool {
+		ri, rj := orderedRules[i], orderedRules[j]
+		if ri.rulePriority.Less(rj.rulePriority) || rj.rulePriority.Less(ri.rulePriority) {
+			return ri.rulePriority.Less(rj.rulePriority)
+		}
+		if ri.rulePriority == nil {
+			if deny := ri.rule.Access == network.SecurityRuleAccessDeny; deny != (rj.rule.Access == network.SecurityRuleAccessDeny) {
+				return deny
+			}
+		}
+		if ri.priority != nil && rj.priority != nil {
+			return *ri.priority < *rj.priority
+		}
+		return ri.priority != nil && rj.priority == nil
+	})
+
+	priorities := make([]int32, len(orderedRules))
+	last := int32(ruleStartPriority - 1)
+	for i, r := range orderedRules {
+		priority := last + 1
+		if r.priority != nil && *r.priority > last {
+			priority = *r.priority
+		} else {
+			for _, next := range orderedRules[i+1:] {
+				if next.priority != nil && *next.priority > last {
+					if *next.priority-last > 1 {
+						priority = last + (*next.priority-last)/2
+					}
+					break
+				}
+			}
+		}
+		priorities[i], last = priority, priority
+	}
+	if last >= vnetToVnetDenyRulePriority {
+		for i := range priorities {
+			priorities[i] = int32(ruleStartPriority + i)
+		}
+	}
+
+	for i, r := range orderedRules {
+		rule := r.rule
+		rule.Priority = to.Int32Ptr(priorities[i])
+		rule.Name = to.StringPtr(getSecurityRuleName(priorities[i], rule.Direction, r.rulePriority))
 		rules = append(rules, rule)
-		rulePriority++
 	}
 
 	for _, rule := range defaultRulesByName {
@@ -84,6 +212,53 @@ func updateSecurityRuleNameAndPriority(existingRules []network.SecurityRule, new
 	return rules
 }
 
+// checkSecurityRuleQuota returns an error wrapping securitygroup.ErrQuotaExceeded if rules of a network security group
+// exceed the security rules per network security group quota, or if rules of a direction exceed priorities available
+// from ruleStartPriority to vnetToVnetDenyRulePriority, and reports remaining rules of the network security group.
+func checkSecurityRuleQuota(nsgName string, rules []network.SecurityRule) error {
+	numRulesByDirection := make(map[network.SecurityRuleDirection]int)
+	for _, rule := range rules {
+		if rule.Priority != nil && *rule.Priority == vnetToVnetDenyRulePriority {
+			continue
+		}
+		numRulesByDirection[rule.Direction]++
+	}
+
+	headroom := azureMaxSecurityRulesPerNsg - len(rules)
+	var err error
+	if headroom < 0 {
+		err = fmt.Errorf("%w: network security group %v requires %v security rules, security rules per network "+
+			"security group quota is %v", securitygroup.ErrQuotaExceeded, nsgName, len(rules), azureMaxSecurityRulesPerNsg)
+	}
+	numPriorities := vnetToVnetDenyRulePriority - ruleStartPriority
+	for _, direction := range []network.SecurityRuleDirection{network.SecurityRuleDirectionInbound,
+		network.SecurityRuleDirectionOutbound} {
+		numRules := numRulesByDirection[direction]
+		if numPriorities-numRules < headroom {
+			headroom = numPriorities - numRules
+		}
+		if numRules > numPriorities && err == nil {
+			err = fmt.Errorf("%w: network security group %v requires %v %v security rules, priorities %v to %v are "+
+				"available", securitygroup.ErrQuotaExceeded, nsgName, numRules, direction, ruleStartPriority,
+				vnetToVnetDenyRulePriority-1)
+		}
+	}
+	metrics.SecurityGroupRuleHeadroom.WithLabelValues(string(providerType), nsgName).Set(float64(headroom))
+	return err
+}
+
+// updateSecurityRuleHeadroom reports remaining rules of a network security group whose rules are removed, and stops
+// reporting it once no rules besides the vnet to vnet deny rule are left.
+func updateSecurityRuleHeadroom(nsgName string, rules []network.SecurityRule) {
+	for _, rule := range rules {
+		if rule.Priority == nil || *rule.Priority != vnetToVnetDenyRulePriority {
+			_ = checkSecurityRuleQuota(nsgName, rules)
+			return
+		}
+	}
+	metrics.SecurityGroupRuleHeadroom.DeleteLabelValues(string(providerType), nsgName)
+}
+
 func convertIngressToAzureNsgSecurityRules(appliedToGroupID *securitygroup.CloudResourceID, rules []*securitygroup.IngressRule,
 	agAsgMapByNepheControllerName map[string]network.ApplicationSecurityGroup,
 	atAsgMapByNepheControllerName map[string]network.ApplicationSecurityGroup) ([]network.SecurityRule, error) {
@@ -105,15 +280,16 @@ func convertIngressToAzureNsgSecurityRules(appliedToGroupID *securitygroup.Cloud
 			return []network.SecurityRule{}, err
 		}
 
-		srcPort := convertToAzurePortRange(rule.FromPort)
+		srcPort := convertToAzurePortRange(rule.FromPort, rule.FromEndPort)
+		access := convertToAzureSecurityRuleAccess(rule.Action)
+		ruleStart := len(securityRules)
 
-		if len(rule.FromSrcIP) != 0 || len(rule.FromSecurityGroups) == 0 {
-			srcAddrPrefix, srcAddrPrefixes := convertToAzureAddressPrefix(rule.FromSrcIP)
-			if srcAddrPrefix != nil || srcAddrPrefixes != nil {
+		if len(rule.FromSrcIP) != 0 || (len(rule.FromSecurityGroups) == 0 && len(rule.FromCloudServices) == 0) {
+			for _, addressPrefix := range convertToAzureAddressPrefix(rule.FromSrcIP) {
 				securityRule := buildSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionInbound,
-					to.StringPtr(emptyPort), srcAddrPrefix, srcAddrPrefixes, nil,
+					to.StringPtr(emptyPort), addressPrefix.prefix, addressPrefix.prefixes, nil,
 					&srcPort, nil, nil, &[]network.ApplicationSecurityGroup{dstAsgObj}, &description,
-					network.SecurityRuleAccessAllow)
+					access)
 				securityRules = append(securityRules, securityRule)
 				rulePriority++
 			}
@@ -124,10 +300,20 @@ func convertIngressToAzureNsgSecurityRules(appliedToGroupID *securitygroup.Cloud
 			securityRule := buildSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionInbound,
 				to.StringPtr(emptyPort), nil, nil, srcApplicationSecurityGroups,
 				&srcPort, nil, nil, &[]network.ApplicationSecurityGroup{dstAsgObj}, &description,
-				network.SecurityRuleAccessAllow)
+				access)
+			securityRules = append(securityRules, securityRule)
+			rulePriority++
+		}
+
+		for _, serviceTag := range rule.FromCloudServices {
+			securityRule := buildSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionInbound,
+				to.StringPtr(emptyPort), to.StringPtr(serviceTag), nil, nil,
+				&srcPort, nil, nil, &[]network.ApplicationSecurityGroup{dstAsgObj}, &description,
+				access)
 			securityRules = append(securityRules, securityRule)
 			rulePriority++
 		}
+		setSecurityRuleNames(securityRules[ruleStart:], rule.Priority)
 	}
 	// add vnet to vnet deny all rule
 	securityRule := buildSecurityRule(to.Int32Ptr(vnetToVnetDenyRulePriority), network.SecurityRuleProtocolAsterisk,
@@ -155,19 +341,28 @@ func convertIngressToAzurePeerNsgSecurityRules(appliedToGroupID *securitygroup.C
 			return []network.SecurityRule{}, err
 		}
 
-		srcPort := convertToAzurePortRange(rule.FromPort)
+		srcPort := convertToAzurePortRange(rule.FromPort, rule.FromEndPort)
+		access := convertToAzureSecurityRuleAccess(rule.Action)
+		ruleStart := len(securityRules)
 
-		if len(rule.FromSrcIP) != 0 || len(rule.FromSecurityGroups) == 0 {
-			srcAddrPrefix, srcAddrPrefixes := convertToAzureAddressPrefix(rule.FromSrcIP)
-			if srcAddrPrefix != nil || srcAddrPrefixes != nil {
+		if len(rule.FromSrcIP) != 0 || (len(rule.FromSecurityGroups) == 0 && len(rule.FromCloudServices) == 0) {
+			for _, addressPrefix := range convertToAzureAddressPrefix(rule.FromSrcIP) {
 				securityRule := buildPeerSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionInbound,
-					to.StringPtr(emptyPort), srcAddrPrefix, srcAddrPrefixes, nil,
+					to.StringPtr(emptyPort), addressPrefix.prefix, addressPrefix.prefixes, nil,
 					&srcPort, to.StringPtr(emptyPort), nil, nil, &description,
-					network.SecurityRuleAccessAllow, appliedToGroupID.Name)
+					access, appliedToGroupID.Name)
 				securityRules = append(securityRules, securityRule)
 				rulePriority++
 			}
 		}
+		for _, serviceTag := range rule.FromCloudServices {
+			securityRule := buildPeerSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionInbound,
+				to.StringPtr(emptyPort), to.StringPtr(serviceTag), nil, nil,
+				&srcPort, to.StringPtr(emptyPort), nil, nil, &description,
+				access, appliedToGroupID.Name)
+			securityRules = append(securityRules, securityRule)
+			rulePriority++
+		}
 		flag := 0
 		for _, fromSecurityGroup := range rule.FromSecurityGroups {
 			if fromSecurityGroup.Vpc == appliedToGroupID.Vpc {
@@ -176,7 +371,7 @@ func convertIngressToAzurePeerNsgSecurityRules(appliedToGroupID *securitygroup.C
 					securityRule := buildPeerSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionInbound,
 						to.StringPtr(emptyPort), nil, nil, srcApplicationSecurityGroups,
 						&srcPort, to.StringPtr(emptyPort), nil, nil, &description,
-						network.SecurityRuleAccessAllow, appliedToGroupID.Name)
+						access, appliedToGroupID.Name)
 					securityRules = append(securityRules, securityRule)
 					rulePriority++
 					flag = 1
@@ -188,10 +383,11 @@ func convertIngressToAzurePeerNsgSecurityRules(appliedToGroupID *securitygroup.C
 			securityRule := buildPeerSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionInbound,
 				to.StringPtr(emptyPort), ruleIP, nil, nil,
 				&srcPort, to.StringPtr(emptyPort), nil, nil, &description,
-				network.SecurityRuleAccessAllow, appliedToGroupID.Name)
+				access, appliedToGroupID.Name)
 			securityRules = append(securityRules, securityRule)
 			rulePriority++
 		}
+		setSecurityRuleNames(securityRules[ruleStart:], rule.Priority)
 	}
 	// add vnet to vnet deny all rule
 	securityRule := buildPeerSecurityRule(to.Int32Ptr(vnetToVnetDenyRulePriority), network.SecurityRuleProtocolAsterisk,
@@ -224,14 +420,15 @@ func convertEgressToAzureNsgSecurityRules(appliedToGroupID *securitygroup.CloudR
 			return []network.SecurityRule{}, err
 		}
 
-		dstPort := convertToAzurePortRange(rule.ToPort)
+		dstPort := convertToAzurePortRange(rule.ToPort, rule.ToEndPort)
+		access := convertToAzureSecurityRuleAccess(rule.Action)
+		ruleStart := len(securityRules)
 
-		if len(rule.ToDstIP) != 0 || len(rule.ToSecurityGroups) == 0 {
-			dstAddrPrefix, dstAddrPrefixes := convertToAzureAddressPrefix(rule.ToDstIP)
-			if dstAddrPrefix != nil || dstAddrPrefixes != nil {
+		if len(rule.ToDstIP) != 0 || (len(rule.ToSecurityGroups) == 0 && len(rule.ToCloudServices) == 0) {
+			for _, addressPrefix := range convertToAzureAddressPrefix(rule.ToDstIP) {
 				securityRule := buildSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionOutbound,
 					to.StringPtr(emptyPort), nil, nil, &[]network.ApplicationSecurityGroup{srcAsgObj},
-					&dstPort, dstAddrPrefix, dstAddrPrefixes, nil, &description, network.SecurityRuleAccessAllow)
+					&dstPort, addressPrefix.prefix, addressPrefix.prefixes, nil, &description, access)
 				securityRules = append(securityRules, securityRule)
 				rulePriority++
 			}
@@ -241,10 +438,19 @@ func convertEgressToAzureNsgSecurityRules(appliedToGroupID *securitygroup.CloudR
 		if dstApplicationSecurityGroups != nil && len(*dstApplicationSecurityGroups) != 0 {
 			securityRule := buildSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionOutbound,
 				to.StringPtr(emptyPort), nil, nil, &[]network.ApplicationSecurityGroup{srcAsgObj},
-				&dstPort, nil, nil, dstApplicationSecurityGroups, &description, network.SecurityRuleAccessAllow)
+				&dstPort, nil, nil, dstApplicationSecurityGroups, &description, access)
 			securityRules = append(securityRules, securityRule)
 			rulePriority++
 		}
+
+		for _, serviceTag := range rule.ToCloudServices {
+			securityRule := buildSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionOutbound,
+				to.StringPtr(emptyPort), nil, nil, &[]network.ApplicationSecurityGroup{srcAsgObj},
+				&dstPort, to.StringPtr(serviceTag), nil, nil, &description, access)
+			securityRules = append(securityRules, securityRule)
+			rulePriority++
+		}
+		setSecurityRuleNames(securityRules[ruleStart:], rule.Priority)
 	}
 
 	// add vnet to vnet deny all rule
@@ -273,18 +479,26 @@ func convertEgressToAzurePeerNsgSecurityRules(appliedToGroupID *securitygroup.Cl
 			return []network.SecurityRule{}, err
 		}
 
-		dstPort := convertToAzurePortRange(rule.ToPort)
+		dstPort := convertToAzurePortRange(rule.ToPort, rule.ToEndPort)
+		access := convertToAzureSecurityRuleAccess(rule.Action)
+		ruleStart := len(securityRules)
 
-		if len(rule.ToDstIP) != 0 || len(rule.ToSecurityGroups) == 0 {
-			dstAddrPrefix, dstAddrPrefixes := convertToAzureAddressPrefix(rule.ToDstIP)
-			if dstAddrPrefix != nil || dstAddrPrefixes != nil {
+		if len(rule.ToDstIP) != 0 || (len(rule.ToSecurityGroups) == 0 && len(rule.ToCloudServices) == 0) {
+			for _, addressPrefix := range convertToAzureAddressPrefix(rule.ToDstIP) {
 				securityRule := buildPeerSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionOutbound,
 					to.StringPtr(emptyPort), to.StringPtr(emptyPort), nil, nil,
-					&dstPort, dstAddrPrefix, dstAddrPrefixes, nil, &description, network.SecurityRuleAccessAllow, appliedToGroupID.Name)
+					&dstPort, addressPrefix.prefix, addressPrefix.prefixes, nil, &description, access, appliedToGroupID.Name)
 				securityRules = append(securityRules, securityRule)
 				rulePriority++
 			}
 		}
+		for _, serviceTag := range rule.ToCloudServices {
+			securityRule := buildPeerSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionOutbound,
+				to.StringPtr(emptyPort), to.StringPtr(emptyPort), nil, nil,
+				&dstPort, to.StringPtr(serviceTag), nil, nil, &description, access, appliedToGroupID.Name)
+			securityRules = append(securityRules, securityRule)
+			rulePriority++
+		}
 		flag := 0
 		for _, toSecurityGroup := range rule.ToSecurityGroups {
 			if toSecurityGroup.Vpc == appliedToGroupID.Vpc {
@@ -292,7 +506,7 @@ func convertEgressToAzurePeerNsgSecurityRules(appliedToGroupID *securitygroup.Cl
 				if dstApplicationSecurityGroups != nil && len(*dstApplicationSecurityGroups) != 0 {
 					securityRule := buildPeerSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionOutbound,
 						to.StringPtr(emptyPort), to.StringPtr(emptyPort), nil, nil,
-						&dstPort, nil, nil, dstApplicationSecurityGroups, &description, network.SecurityRuleAccessAllow, appliedToGroupID.Name)
+						&dstPort, nil, nil, dstApplicationSecurityGroups, &description, access, appliedToGroupID.Name)
 					securityRules = append(securityRules, securityRule)
 					rulePriority++
 					flag = 1
@@ -303,10 +517,11 @@ func convertEgressToAzurePeerNsgSecurityRules(appliedToGroupID *securitygroup.Cl
 		if flag == 0 {
 			securityRule := buildPeerSecurityRule(to.Int32Ptr(rulePriority), protoName, network.SecurityRuleDirectionOutbound,
 				to.StringPtr(emptyPort), to.StringPtr(emptyPort), nil, nil,
-				&dstPort, ruleIP, nil, nil, &description, network.SecurityRuleAccessAllow, appliedToGroupID.Name)
+				&dstPort, ruleIP, nil, nil, &description, access, appliedToGroupID.Name)
 			securityRules = append(securityRules, securityRule)
 			rulePriority++
 		}
+		setSecurityRuleNames(securityRules[ruleStart:], rule.Priority)
 	}
 
 	// add vnet to vnet deny all rule
@@ -389,6 +604,20 @@ func convertToAzureApplicationSecurityGroups(securityGroups []*securitygroup.Clo
 	return &asgsToReturn
 }
 
+func convertToAzureSecurityRuleAccess(action securitygroup.RuleAction) network.SecurityRuleAccess {
+	if action.IsDeny() {
+		return network.SecurityRuleAccessDeny
+	}
+	return network.SecurityRuleAccessAllow
+}
+
+func convertFromAzureSecurityRuleAccess(access network.SecurityRuleAccess) securitygroup.RuleAction {
+	if access == network.SecurityRuleAccessDeny {
+		return securitygroup.RuleActionDeny
+	}
+	return securitygroup.RuleActionAllow
+}
+
 func convertToAzureProtocolName(protoNum *int) (network.SecurityRuleProtocol, error) {
 	if protoNum == nil {
 		return network.SecurityRuleProtocolAsterisk, nil
@@ -402,27 +631,44 @@ func convertToAzureProtocolName(protoNum *int) (network.SecurityRuleProtocol, er
 	return protocolName, nil
 }
 
-func convertToAzurePortRange(port *int) string {
+func convertToAzurePortRange(port *int, endPort *int) string {
 	if port == nil {
 		return emptyPort
 	}
+	if endPort != nil && *endPort > *port {
+		return fmt.Sprintf("%v-%v", *port, *endPort)
+	}
 	return strconv.Itoa(*port)
 }
 
-func convertToAzureAddressPrefix(ruleIPs []*net.IPNet) (*string, *[]string) {
-	var prefixes []string
+// azureAddressPrefix is either a single address prefix or a list of address prefixes of a security rule.
+type azureAddressPrefix struct {
+	prefix   *string
+	prefixes *[]string
+}
+
+// convertToAzureAddressPrefix returns address prefixes of ruleIPs. A security rule cannot mix IPv4 and IPv6
+// address prefixes, hence address prefixes of each address family are returned separately.
+func convertToAzureAddressPrefix(ruleIPs []*net.IPNet) []azureAddressPrefix {
+	if len(ruleIPs) == 0 {
+		return []azureAddressPrefix{{prefix: to.StringPtr(emptyPort)}}
+	}
+	var ipv4Prefixes, ipv6Prefixes []string
 	for _, ip := range ruleIPs {
-		prefixes = append(prefixes, ip.String())
+		if ip.IP.To4() != nil {
+			ipv4Prefixes = append(ipv4Prefixes, ip.String())
+		} else {
+			ipv6Prefixes = append(ipv6Prefixes, ip.String())
+		}
 	}
-
-	var addressPrefix *string
-	var addressPrefixes *[]string
-	if len(prefixes) == 0 {
-		addressPrefix = to.StringPtr(emptyPort)
-	} else {
-		addressPrefixes = &prefixes
+	var addressPrefixes []azureAddressPrefix
+	for _, prefixes := range [][]string{ipv4Prefixes, ipv6Prefixes} {
+		if len(prefixes) > 0 {
+			familyPrefixes := prefixes
+			addressPrefixes = append(addressPrefixes, azureAddressPrefix{prefixes: &familyPrefixes})
+		}
 	}
-	return addressPrefix, addressPrefixes
+	return addressPrefixes
 }
 
 func convertToNepheControllerRulesByAppliedToSGName(azureSecurityRules *[]network.SecurityRule,
@@ -460,8 +706,9 @@ func convertToNepheControllerRulesByAppliedToSGName(azureSecurityRules *[]networ
 }
 
 func convertFromAzureSecurityRuleToNepheControllerIngressRule(rule network.SecurityRule, vnetID string) (securitygroup.IngressRule, error) {
-	port := convertFromAzurePortToNepheControllerPort(rule.DestinationPortRange)
+	port, endPort := convertFromAzurePortToNepheControllerPort(rule.DestinationPortRange)
 	srcIP := convertFromAzurePrefixesToNepheControllerIPs(rule.SourceAddressPrefix, rule.SourceAddressPrefixes)
+	serviceTags := convertFromAzurePrefixToNepheControllerServiceTags(rule.SourceAddressPrefix)
 	securityGroups := convertFromAzureASGsToNepheControllerSecurityGroups(rule.SourceApplicationSecurityGroups, vnetID)
 	protoNum, err := convertFromAzureProtocolToNepheControllerProtocol(rule.Protocol)
 	if err != nil {
@@ -469,17 +716,22 @@ func convertFromAzureSecurityRuleToNepheControllerIngressRule(rule network.Secur
 	}
 	ingressRule := securitygroup.IngressRule{
 		FromPort:           port,
+		FromEndPort:        endPort,
 		FromSrcIP:          srcIP,
 		FromSecurityGroups: securityGroups,
+		FromCloudServices:  serviceTags,
 		Protocol:           protoNum,
+		Action:             convertFromAzureSecurityRuleAccess(rule.Access),
+		Priority:           getSecurityRulePriority(rule.Name),
 	}
 
 	return ingressRule, nil
 }
 
 func convertFromAzureSecurityRuleToNepheControllerEgressRule(rule network.SecurityRule, vnetID string) (securitygroup.EgressRule, error) {
-	port := convertFromAzurePortToNepheControllerPort(rule.DestinationPortRange)
+	port, endPort := convertFromAzurePortToNepheControllerPort(rule.DestinationPortRange)
 	dstIP := convertFromAzurePrefixesToNepheControllerIPs(rule.DestinationAddressPrefix, rule.DestinationAddressPrefixes)
+	serviceTags := convertFromAzurePrefixToNepheControllerServiceTags(rule.DestinationAddressPrefix)
 	securityGroups := convertFromAzureASGsToNepheControllerSecurityGroups(rule.DestinationApplicationSecurityGroups, vnetID)
 	protoNum, err := convertFromAzureProtocolToNepheControllerProtocol(rule.Protocol)
 	if err != nil {
@@ -488,9 +740,13 @@ func convertFromAzureSecurityRuleToNepheControllerEgressRule(rule network.Securi
 
 	egressRule := securitygroup.EgressRule{
 		ToPort:           port,
+		ToEndPort:        endPort,
 		ToDstIP:          dstIP,
 		ToSecurityGroups: securityGroups,
+		ToCloudServices:  serviceTags,
 		Protocol:         protoNum,
+		Action:           convertFromAzureSecurityRuleAccess(rule.Access),
+		Priority:         getSecurityRulePriority(rule.Name),
 	}
 
 	return egressRule, err
@@ -547,6 +803,9 @@ func convertFromAzurePrefixesToNepheControllerIPs(ipPrefix *string, ipPrefixes *
 		}
 	}
 
+	if ipPrefixes == nil {
+		return ipNetList
+	}
 	for _, prefix := range *ipPrefixes {
 		_, ipNet, err := net.ParseCIDR(prefix)
 		if err != nil {
@@ -558,13 +817,172 @@ func convertFromAzurePrefixesToNepheControllerIPs(ipPrefix *string, ipPrefixes *
 	return ipNetList
 }
 
-func convertFromAzurePortToNepheControllerPort(port *string) *int {
-	if port == nil || *port == emptyPort {
+// convertFromAzurePrefixToNepheControllerServiceTags returns service tag of ipPrefix, if ipPrefix is neither an ip
+// address nor an ip block. VirtualNetwork is not returned, as nephe uses it for the vnet to vnet deny rule rather than
+// for cloud service peers.
+func convertFromAzurePrefixToNepheControllerServiceTags(ipPrefix *string) []string {
+	if ipPrefix == nil || *ipPrefix == emptyPort || *ipPrefix == "" || *ipPrefix == virtualnetworkAddressPrefix {
 		return nil
 	}
-	portNum, err := strconv.ParseInt(*port, 10, 32)
-	if err != nil {
+	if _, _, err := net.ParseCIDR(*ipPrefix); err == nil || net.ParseIP(*ipPrefix) != nil {
 		return nil
 	}
-	return to.IntPtr(int(portNum))
+	return []string{*ipPrefix}
+}
+
+// convertFromAzurePortToNepheControllerPort returns start and end port of port range. End port is nil for a single port.
+func convertFromAzurePortToNepheControllerPort(port *string) (*int, *int) {
+	if port == nil || *port == emptyPort {
+		return nil, nil
+	}
+	ports := strings.SplitN(*port, "-", 2)
+	portNum, err := strconv.ParseInt(ports[0], 10, 32)
+	if err != nil {
+		return nil, nil
+	}
+	if len(ports) == 1 {
+		return to.IntPtr(int(portNum)), nil
+	}
+	endPortNum, err := strconv.ParseInt(ports[1], 10, 32)
+	if err != nil || endPortNum <= portNum {
+		return to.IntPtr(int(portNum)), nil
+	}
+	return to.IntPtr(int(portNum)), to.IntPtr(int(endPortNum))
+}
+
+// convertFromUnmanagedAzureSecurityRules converts security rules of a network security group not created by nephe to
+// ingress and egress rules, in order of rule priority. Rules, or peers of rules, not supported are returned as
+// descriptions of unsupported rules. A rule left without peers is dropped, so that it does not match any peer.
+func convertFromUnmanagedAzureSecurityRules(azureSecurityRules *[]network.SecurityRule) ([]securitygroup.IngressRule,
+	[]securitygroup.EgressRule, []string) {
+	if azureSecurityRules == nil {
+		return nil, nil, nil
+	}
+	rules := make([]network.SecurityRule, 0, len(*azureSecurityRules))
+	for _, rule := range *azureSecurityRules {
+		if rule.SecurityRulePropertiesFormat != nil {
+			rules = append(rules, rule)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return to.Int32(rules[i].Priority) < to.Int32(rules[j].Priority)
+	})
+
+	var ingressRules []securitygroup.IngressRule
+	var egressRules []securitygroup.EgressRule
+	var unsupportedRules []string
+	for _, rule := range rules {
+		isIngress := rule.Direction == network.SecurityRuleDirectionInbound
+		direction := "egress"
+		peerPrefix, peerPrefixes, peerASGs := rule.DestinationAddressPrefix, rule.DestinationAddressPrefixes,
+			rule.DestinationApplicationSecurityGroups
+		localPrefix, localPrefixes, localASGs := rule.SourceAddressPrefix, rule.SourceAddressPrefixes,
+			rule.SourceApplicationSecurityGroups
+		if isIngress {
+			direction = "ingress"
+			peerPrefix, peerPrefixes, peerASGs, localPrefix, localPrefixes, localASGs = localPrefix, localPrefixes, localASGs,
+				peerPrefix, peerPrefixes, peerASGs
+		}
+		ruleName := fmt.Sprintf("%v rule %v", direction, to.String(rule.Name))
+
+		// rules are applied to all members of the security group, rules restricted to some members are not supported.
+		if !isAzureAnyAddressPrefix(localPrefix, localPrefixes) || (localASGs != nil && len(*localASGs) != 0) {
+			unsupportedRules = append(unsupportedRules, ruleName+": restricted to some members")
+			continue
+		}
+		if rule.SourcePortRange != nil && *rule.SourcePortRange != emptyPort ||
+			rule.SourcePortRanges != nil && len(*rule.SourcePortRanges) != 0 {
+			unsupportedRules = append(unsupportedRules, ruleName+": source ports")
+			continue
+		}
+		protoNum, err := convertFromAzureProtocolToNepheControllerProtocol(rule.Protocol)
+		if err != nil {
+			unsupportedRules = append(unsupportedRules, fmt.Sprintf("%v: %v", ruleName, err))
+			continue
+		}
+
+		ipNets, unsupportedPeers := convertFromUnmanagedAzurePrefixes(peerPrefix, peerPrefixes)
+		var securityGroups []*securitygroup.CloudResourceID
+		if peerASGs != nil {
+			for _, asg := range *peerASGs {
+				securityGroups = append(securityGroups, &securitygroup.CloudResourceID{Name: strings.ToLower(to.String(asg.ID))})
+			}
+		}
+		if len(unsupportedPeers) != 0 {
+			unsupportedRules = append(unsupportedRules, fmt.Sprintf("%v: unsupported peers %v", ruleName,
+				strings.Join(unsupportedPeers, ", ")))
+			if len(ipNets) == 0 && len(securityGroups) == 0 {
+				continue
+			}
+		}
+
+		action := convertFromAzureSecurityRuleAccess(rule.Access)
+		for _, portRange := range getAzureDestinationPortRanges(rule.DestinationPortRange, rule.DestinationPortRanges) {
+			port, endPort := convertFromAzurePortToNepheControllerPort(portRange)
+			if isIngress {
+				ingressRules = append(ingressRules, securitygroup.IngressRule{FromPort: port, FromEndPort: endPort,
+					FromSrcIP: ipNets, FromSecurityGroups: securityGroups, Protocol: protoNum, Action: action})
+			} else {
+				egressRules = append(egressRules, securitygroup.EgressRule{ToPort: port, ToEndPort: endPort,
+					ToDstIP: ipNets, ToSecurityGroups: securityGroups, Protocol: protoNum, Action: action})
+			}
+		}
+	}
+	return ingressRules, egressRules, unsupportedRules
+}
+
+// isAzureAnyAddressPrefix returns true if address prefixes match any address of the virtual network.
+func isAzureAnyAddressPrefix(prefix *string, prefixes *[]string) bool {
+	if prefixes != nil && len(*prefixes) != 0 {
+		return false
+	}
+	return prefix == nil || *prefix == emptyPort || *prefix == virtualnetworkAddressPrefix
+}
+
+// convertFromUnmanagedAzurePrefixes converts address prefixes to ip blocks. Single addresses are converted to host
+// ip blocks, service tags other than any are returned as unsupported.
+func convertFromUnmanagedAzurePrefixes(prefix *string, prefixes *[]string) ([]*net.IPNet, []string) {
+	var allPrefixes []string
+	if prefix != nil && len(*prefix) != 0 {
+		allPrefixes = append(allPrefixes, *prefix)
+	}
+	if prefixes != nil {
+		allPrefixes = append(allPrefixes, *prefixes...)
+	}
+
+	var ipNets []*net.IPNet
+	var unsupportedPrefixes []string
+	for _, addressPrefix := range allPrefixes {
+		if addressPrefix == emptyPort {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(addressPrefix); err == nil {
+			ipNets = append(ipNets, ipNet)
+			continue
+		}
+		if ip := net.ParseIP(addressPrefix); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			ipNets = append(ipNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		unsupportedPrefixes = append(unsupportedPrefixes, addressPrefix)
+	}
+	return ipNets, unsupportedPrefixes
+}
+
+// getAzureDestinationPortRanges returns destination port ranges of a security rule, any port if none.
+func getAzureDestinationPortRanges(portRange *string, portRanges *[]string) []*string {
+	var ranges []*string
+	if portRanges != nil {
+		for i := range *portRanges {
+			ranges = append(ranges, &(*portRanges)[i])
+		}
+	}
+	if len(ranges) == 0 {
+		ranges = append(ranges, portRange)
+	}
+	return ranges
 }
@@ -47,6 +47,7 @@ func buildQueries(vmSelector []v1alpha1.VirtualMachineSelector, subscriptionIDs
 	var vmIDOnlyMatches []v1alpha1.EntityMatch
 	var vmIDAndVMNameMatches []v1alpha1.EntityMatch
 	var vmNameOnlyMatches []v1alpha1.EntityMatch
+	var vmTagMatches []v1alpha1.VirtualMachineSelector
 
 	// vpcMatch contains VpcID and vmMatch contains nil:
 	// vpcIDsWithVpcIDOnlyMatches slice contains the corresponding vmSelector section.
@@ -57,6 +58,9 @@ func buildQueries(vmSelector []v1alpha1.VirtualMachineSelector, subscriptionIDs
 	// vpcMatch contains nil and vmMatch contains only vmName:
 	// vmNameOnlyMatches slice contains the specific vmMatch section(EntityMatch).
 	// Azure query is created to match only vms matching the matchName.
+	// vpcMatch contains nil or VpcID and vmMatch contains tags:
+	// vmTagMatches slice contains the vpcMatch with the specific vmMatch section.
+	// Azure query is created for each of them to match vms carrying all tags and matching other match criteria.
 
 	for _, match := range vmSelector {
 		isVpcIDPresent := false
@@ -88,6 +92,20 @@ func buildQueries(vmSelector []v1alpha1.VirtualMachineSelector, subscriptionIDs
 				isVMNamePresent = true
 			}
 
+			// vm tags matches
+			if len(vmmatch.MatchTags) > 0 {
+				if isVpcIDPresent {
+					if _, found := vpcIDsWithVpcIDOnlyMatches[networkMatch.MatchID]; found {
+						continue
+					}
+				}
+				vmTagMatches = append(vmTagMatches, v1alpha1.VirtualMachineSelector{
+					VpcMatch: networkMatch,
+					VMMatch:  []v1alpha1.EntityMatch{vmmatch},
+				})
+				continue
+			}
+
 			if isVpcIDPresent && (isVMIDPresent || isVMNamePresent) {
 				if _, found := vpcIDsWithVpcIDOnlyMatches[networkMatch.MatchID]; found {
 					continue
@@ -114,7 +132,8 @@ func buildQueries(vmSelector []v1alpha1.VirtualMachineSelector, subscriptionIDs
 
 	azurePluginLogger().Info("selector stats", "VpcIdOnlyMatch", len(vpcIDsWithVpcIDOnlyMatches),
 		"VpcIdWithOtherMatches", len(vpcIDWithOtherMatches), "VmIdOnlyMatches", len(vmIDOnlyMatches),
-		"VmIdAndVmNameMatches", len(vmIDAndVMNameMatches), "VmNameOnlyMatches", len(vmNameOnlyMatches))
+		"VmIdAndVmNameMatches", len(vmIDAndVMNameMatches), "VmNameOnlyMatches", len(vmNameOnlyMatches),
+		"VmTagMatches", len(vmTagMatches))
 
 	var allQueries []*string
 
@@ -142,6 +161,12 @@ func buildQueries(vmSelector []v1alpha1.VirtualMachineSelector, subscriptionIDs
 		allQueries = append(allQueries, vmIDOnlyQuery)
 	}
 
+	vmTagQueries, err := buildQueriesForVMTagMatches(vmTagMatches, subscriptionIDs, tenantIDs, locations)
+	if err != nil {
+		return nil, err
+	}
+	allQueries = append(allQueries, vmTagQueries...)
+
 	return allQueries, nil
 }
 
@@ -201,3 +226,25 @@ func buildQueryForVMIDOnlyMatches(vmIDOnlyMatches []v1alpha1.EntityMatch, subscr
 
 	return getVMsByVMIDsAndSubscriptionIDsAndTenantIDsAndLocationsMatchQuery(vmIDs, subscriptionIDs, tenantIDs, locations)
 }
+
+func buildQueriesForVMTagMatches(vmTagMatches []v1alpha1.VirtualMachineSelector, subscriptionIDs []string, tenantIDs []string,
+	locations []string) ([]*string, error) {
+	var queries []*string
+	for _, match := range vmTagMatches {
+		var vpcIDs, vmNames, vmIDs []string
+		if match.VpcMatch != nil {
+			vpcIDs = append(vpcIDs, strings.TrimSpace(match.VpcMatch.MatchID))
+		}
+		vmMatch := match.VMMatch[0]
+		vmNames = append(vmNames, strings.TrimSpace(vmMatch.MatchName))
+		vmIDs = append(vmIDs, strings.TrimSpace(vmMatch.MatchID))
+
+		query, err := getVMsByTagsAndSubscriptionIDsAndTenantIDsAndLocationsMatchQuery(vpcIDs, vmNames, vmIDs, vmMatch.MatchTags,
+			subscriptionIDs, tenantIDs, locations)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
@@ -17,6 +17,8 @@ package azure
 import (
 	"bytes"
 	"fmt"
+	"sort"
+	"strings"
 	"text/template"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-03-01/compute"
@@ -31,6 +33,11 @@ type virtualMachineTable struct {
 	Tags              map[string]*string
 	Status            *string
 	VnetID            *string
+	VMSize            *string
+	OsType            *string
+	ImageReference    *imageReference
+	TimeCreated       *string
+	Zones             []*string
 }
 type networkInterface struct {
 	ID         *string
@@ -40,6 +47,20 @@ type networkInterface struct {
 	PublicIps  []*string
 	Tags       map[string]*string
 	VnetID     *string
+	SubnetID   *string
+	NsgID      *string
+	// ApplicationSecurityGroups is a list of application security group lists, one per ip configuration.
+	ApplicationSecurityGroups [][]*subResource
+}
+type imageReference struct {
+	ID        *string
+	Publisher *string
+	Offer     *string
+	Sku       *string
+	Version   *string
+}
+type subResource struct {
+	ID *string
 }
 
 type vmTableQueryParameters struct {
@@ -49,6 +70,7 @@ type vmTableQueryParameters struct {
 	VnetIDs         *string
 	VMNames         *string
 	VMIDs           *string
+	Tags            *string
 }
 
 const (
@@ -74,6 +96,9 @@ const (
 		"{{ if .VMIDs}} " +
 		"| where id in ({{ .VMIDs }})" +
 		"{{ end }}" +
+		"{{ if .Tags}} " +
+		"| where {{ .Tags }}" +
+		"{{ end }}" +
 		"| mvexpand nic = properties.networkProfile.networkInterfaces" +
 		"| extend nicId = tolower(tostring(nic.id))" +
 		"| join kind = innerunique (" +
@@ -88,20 +113,28 @@ const (
 		"	{{ end }}" +
 		"	| extend publicIpId = tolower(tostring(ipconfig.properties.publicIPAddress.id))" +
 		"	| extend nicPrivateIp = ipconfig.properties.privateIPAddress" +
+		"	| extend subnetId = tolower(tostring(ipconfig.properties.subnet.id))" +
+		"	| extend asgs = ipconfig.properties.applicationSecurityGroups" +
+		"	| extend nsgId = tolower(tostring(properties.networkSecurityGroup.id))" +
 		"	| join kind = leftouter (" +
 		"		Resources" +
 		"		| where type =~ 'microsoft.network/publicipaddresses'" +
 		"		| project publicIpId = tolower(id), nicPublicIp = properties.ipAddress" +
 		"	) on publicIpId" +
 		"	| summarize nicTags = any(tags), macAddress = any(macAddress), vnetId = any(vnetId), " +
-		"nicPublicIps = make_list(nicPublicIp), nicPrivateIps = make_list(nicPrivateIp) by id, name" +
-		"	| project nicId = tolower(id), nicName = name, nicPublicIps, nicPrivateIps, vnetId, macAddress, nicTags" +
+		"nicPublicIps = make_list(nicPublicIp), nicPrivateIps = make_list(nicPrivateIp), subnetId = any(subnetId), " +
+		"nsgId = any(nsgId), asgs = make_list(asgs) by id, name" +
+		"	| project nicId = tolower(id), nicName = name, nicPublicIps, nicPrivateIps, vnetId, macAddress, nicTags, " +
+		"subnetId, nsgId, asgs" +
 		") on nicId" +
 		"| extend networkInterfaceDetails = pack(\"id\", nicId, \"name\", nicName, \"macAddress\", macAddress, \"privateIps\"," +
-		"nicPrivateIps, \"publicIps\", nicPublicIps, \"tags\", nicTags, \"vnetId\", vnetId)" +
-		"| summarize vnetId = any(vnetId), properties = make_bag(properties), tags = make_bag(tags), " +
+		"nicPrivateIps, \"publicIps\", nicPublicIps, \"tags\", nicTags, \"vnetId\", vnetId, \"subnetId\", subnetId, " +
+		"\"nsgId\", nsgId, \"applicationSecurityGroups\", asgs)" +
+		"| summarize vnetId = any(vnetId), properties = make_bag(properties), tags = make_bag(tags), zones = any(zones), " +
 		"networkInterfaces = make_list(networkInterfaceDetails) by id, name" +
-		"| project id, name, properties, status=properties.extended.instanceView.powerState.code, networkInterfaces, tags, vnetId"
+		"| project id, name, properties, status=properties.extended.instanceView.powerState.code, networkInterfaces, tags, vnetId, " +
+		"vmSize = properties.hardwareProfile.vmSize, osType = properties.storageProfile.osDisk.osType, " +
+		"imageReference = properties.storageProfile.imageReference, timeCreated = properties.timeCreated, zones"
 )
 
 func getVirtualMachineTable(resourceGraphAPIClient azureResourceGraphWrapper, query *string,
@@ -128,6 +161,16 @@ func getVirtualMachineTable(resourceGraphAPIClient azureResourceGraphWrapper, qu
 	return virtualMachines, count, nil
 }
 
+// restrictVMsTableQueryToVMIDs returns vms table query further matching vm IDs.
+func restrictVMsTableQueryToVMIDs(query *string, vmIDs []string) (*string, error) {
+	commaSeparatedVMIDs := convertStrSliceToLowercaseCommaSeparatedStr(vmIDs)
+	if len(commaSeparatedVMIDs) == 0 {
+		return nil, fmt.Errorf(vmIDsNotFoundErrorMsg)
+	}
+	queryString := fmt.Sprintf("%s| where id in (%s)", *query, commaSeparatedVMIDs)
+	return &queryString, nil
+}
+
 func getVMsByVnetIDsAndSubscriptionIDsAndTenantIDsAndLocationsMatchQuery(vnetIDs []string, subscriptionIDs []string, tenantIDs []string,
 	locations []string) (*string, error) {
 	commaSeparatedVnetIDs := convertStrSliceToLowercaseCommaSeparatedStr(vnetIDs)
@@ -236,6 +279,73 @@ func getVMsByVMIDsAndSubscriptionIDsAndTenantIDsAndLocationsMatchQuery(vmIDs []s
 	return queryString, nil
 }
 
+// getVMsByTagsAndSubscriptionIDsAndTenantIDsAndLocationsMatchQuery returns query for vms carrying all tags, and
+// optionally matching vnet IDs, vm names and vm IDs.
+func getVMsByTagsAndSubscriptionIDsAndTenantIDsAndLocationsMatchQuery(vnetIDs []string, vmNames []string, vmIDs []string,
+	tags map[string]string, subscriptionIDs []string, tenantIDs []string, locations []string) (*string, error) {
+	tagsPredicate := convertTagsToQueryPredicate(tags)
+	if len(tagsPredicate) == 0 {
+		return nil, fmt.Errorf(vmTagsNotFoundErrorMsg)
+	}
+
+	commaSeparatedSubscriptionIDs := convertStrSliceToLowercaseCommaSeparatedStr(subscriptionIDs)
+	if len(commaSeparatedSubscriptionIDs) == 0 {
+		return nil, fmt.Errorf(subscriptionIDsNotFoundErrorMsg)
+	}
+
+	commaSeparatedTenantIDs := convertStrSliceToLowercaseCommaSeparatedStr(tenantIDs)
+	if len(commaSeparatedTenantIDs) == 0 {
+		return nil, fmt.Errorf(tenantIDsNotFoundErrorMsg)
+	}
+
+	commaSeparatedLocations := convertStrSliceToLowercaseCommaSeparatedStr(locations)
+	if len(commaSeparatedLocations) == 0 {
+		return nil, fmt.Errorf(locationsNotFoundErrorMsg)
+	}
+
+	queryParams := &vmTableQueryParameters{
+		SubscriptionIDs: &commaSeparatedSubscriptionIDs,
+		TenantIDs:       &commaSeparatedTenantIDs,
+		Locations:       &commaSeparatedLocations,
+		Tags:            &tagsPredicate,
+	}
+	if commaSeparatedVnetIDs := convertStrSliceToLowercaseCommaSeparatedStr(vnetIDs); len(commaSeparatedVnetIDs) != 0 {
+		queryParams.VnetIDs = &commaSeparatedVnetIDs
+	}
+	if commaSeparatedVMNames := convertStrSliceToLowercaseCommaSeparatedStr(vmNames); len(commaSeparatedVMNames) != 0 {
+		queryParams.VMNames = &commaSeparatedVMNames
+	}
+	if commaSeparatedVMIDs := convertStrSliceToLowercaseCommaSeparatedStr(vmIDs); len(commaSeparatedVMIDs) != 0 {
+		queryParams.VMIDs = &commaSeparatedVMIDs
+	}
+
+	queryString, err := buildVmsTableQueryWithParams("getVMsByTagsAndSubscriptionIDsAndTenantIDsAndLocationsMatchQuery", queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return queryString, nil
+}
+
+// convertTagsToQueryPredicate converts tags to query predicate matching all tags. A tag with empty value matches
+// any value of the tag key.
+func convertTagsToQueryPredicate(tags map[string]string) string {
+	var keys []string
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var predicates []string
+	for _, key := range keys {
+		if len(tags[key]) == 0 {
+			predicates = append(predicates, fmt.Sprintf("isnotnull(tags[%q])", key))
+			continue
+		}
+		predicates = append(predicates, fmt.Sprintf("tostring(tags[%q]) == %q", key, tags[key]))
+	}
+	return strings.Join(predicates, " and ")
+}
+
 func getVMsBySubscriptionIDsAndTenantIDsAndLocationsMatchQuery(subscriptionIDs []string, tenantIDs []string,
 	locations []string) (*string, error) {
 	commaSeparatedSubscriptionIDs := convertStrSliceToLowercaseCommaSeparatedStr(subscriptionIDs)
@@ -17,6 +17,7 @@ package azure
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -26,6 +27,7 @@ import (
 	"go.uber.org/multierr"
 	"k8s.io/apimachinery/pkg/types"
 
+	"antrea.io/nephe/apis/crd/v1alpha1"
 	"antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
 	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
 	"antrea.io/nephe/pkg/cloud-provider/utils"
@@ -85,6 +87,7 @@ func (computeCfg *computeServiceConfig) processAppliedToMembership(addrGroupIden
 	// find network interfaces which are using or need to use the provided NSG
 	nwIntfIDSetNsgToAttach := make(map[string]struct{})
 	nwIntfIDSetNsgToDettach := make(map[string]struct{})
+	var failErr error
 	for _, networkInterface := range networkInterfaces {
 		nwIntfIDLowerCase := strings.ToLower(*networkInterface.ID)
 		// 	for network interfaces not attached to any virtual machines, skip processing
@@ -94,6 +97,7 @@ func (computeCfg *computeServiceConfig) processAppliedToMembership(addrGroupIden
 		}
 
 		isNsgAttached := false
+		otherNsgNameLowercase := ""
 		if networkInterface.NetworkSecurityGroupID != nil && len(*networkInterface.NetworkSecurityGroupID) > 0 {
 			nsgID := strings.ToLower(*networkInterface.NetworkSecurityGroupID)
 			_, _, nsgNameLowercase, err := extractFieldsFromAzureResourceID(nsgID)
@@ -101,6 +105,9 @@ func (computeCfg *computeServiceConfig) processAppliedToMembership(addrGroupIden
 				azurePluginLogger().Error(err, "nsg ID format not valid", "nsgID", nsgID)
 				return err
 			}
+			if _, _, isAT := securitygroup.IsNepheControllerCreatedSG(nsgNameLowercase); !isAT {
+				otherNsgNameLowercase = nsgNameLowercase
+			}
 			if len(networkInterface.Tags) > 0 {
 				tags := networkInterface.Tags[0]
 				_, found := tags[cloudSgNameLowercase]
@@ -117,13 +124,22 @@ func (computeCfg *computeServiceConfig) processAppliedToMembership(addrGroupIden
 			}
 		} else {
 			if isNicAttachedToMemberVM || isNicMemberNetworkInterface {
+				// network interface has a single nsg, in fail mode nsg not created by nephe is not replaced. Coexist mode
+				// is rejected for azure accounts.
+				if len(otherNsgNameLowercase) != 0 && computeCfg.credentials.securityGroupMode == v1alpha1.SecurityGroupModeFail {
+					failErr = multierr.Append(failErr, fmt.Errorf("network interface %v not attached to %v, "+
+						"network security group %v not created by nephe is attached", nwIntfIDLowerCase,
+						cloudSgNameLowercase, otherNsgNameLowercase))
+					continue
+				}
 				nwIntfIDSetNsgToAttach[nwIntfIDLowerCase] = struct{}{}
 			}
 		}
 	}
 
-	return computeCfg.processNsgAttachDetachConcurrently(nsgObj, asgObj, nwIntfIDSetNsgToAttach,
+	err = computeCfg.processNsgAttachDetachConcurrently(nsgObj, asgObj, nwIntfIDSetNsgToAttach,
 		nwIntfIDSetNsgToDettach, addrGroupOriginalNameToBeUsedAsTag)
+	return multierr.Append(err, failErr)
 }
 
 func (computeCfg *computeServiceConfig) processNsgAttachDetachConcurrently(nsgObj network.SecurityGroup,
@@ -273,6 +289,8 @@ func (computeCfg *computeServiceConfig) buildEffectiveNSGSecurityRulesToApply(ap
 
 	var currentNsgIngressRules []network.SecurityRule
 	var currentNsgEgressRules []network.SecurityRule
+	var appliedToNsgIngressRules []network.SecurityRule
+	var appliedToNsgEgressRules []network.SecurityRule
 	currentNsgSecurityRules := nsgObj.SecurityRules
 	appliedToGroupNepheControllerName := appliedToGroupID.GetCloudName(false)
 	azurePluginLogger().Info("building security rules", "applied to security group", appliedToGroupNepheControllerName)
@@ -286,8 +304,13 @@ func (computeCfg *computeServiceConfig) buildEffectiveNSGSecurityRulesToApply(ap
 		if !isNepheControllerCreatedRule {
 			continue
 		}
-		// skip any rules created by current processing appliedToGroup (as we have new rules for this group)
+		// rules created by current processing appliedToGroup are replaced by new rules, keep their priorities.
 		if strings.Compare(ruleAddrGroupName, appliedToGroupNepheControllerName) == 0 {
+			if rule.Direction == network.SecurityRuleDirectionInbound {
+				appliedToNsgIngressRules = append(appliedToNsgIngressRules, rule)
+			} else {
+				appliedToNsgEgressRules = append(appliedToNsgEgressRules, rule)
+			}
 			continue
 		}
 		if rule.Direction == network.SecurityRuleDirectionInbound {
@@ -313,8 +336,10 @@ func (computeCfg *computeServiceConfig) buildEffectiveNSGSecurityRulesToApply(ap
 	if err != nil {
 		return []network.SecurityRule{}, err
 	}
-	allIngressRules := updateSecurityRuleNameAndPriority(currentNsgIngressRules, newIngressSecurityRules)
-	allEgressRules := updateSecurityRuleNameAndPriority(currentNsgEgressRules, newEgressSecurityRules)
+	allIngressRules := updateSecurityRuleNameAndPriority(currentNsgIngressRules, newIngressSecurityRules,
+		appliedToNsgIngressRules)
+	allEgressRules := updateSecurityRuleNameAndPriority(currentNsgEgressRules, newEgressSecurityRules,
+		appliedToNsgEgressRules)
 
 	var rules []network.SecurityRule
 	rules = append(rules, allIngressRules...)
@@ -334,6 +359,8 @@ func (computeCfg *computeServiceConfig) buildEffectivePeerNSGSecurityRulesToAppl
 
 	var currentNsgIngressRules []network.SecurityRule
 	var currentNsgEgressRules []network.SecurityRule
+	var appliedToNsgIngressRules []network.SecurityRule
+	var appliedToNsgEgressRules []network.SecurityRule
 	currentNsgSecurityRules := nsgObj.SecurityRules
 	appliedToGroupNepheControllerName := appliedToGroupID.GetCloudName(false)
 	azurePluginLogger().Info("building peering security rules", "applied to security group", appliedToGroupNepheControllerName)
@@ -347,8 +374,13 @@ func (computeCfg *computeServiceConfig) buildEffectivePeerNSGSecurityRulesToAppl
 		if !isNepheControllerCreatedRule {
 			continue
 		}
-		// skip any rules created by current processing appliedToGroup (as we have new rules for this group)
+		// rules created by current processing appliedToGroup are replaced by new rules, keep their priorities.
 		if strings.Compare(ruleAddrGroupName, appliedToGroupNepheControllerName) == 0 {
+			if rule.Direction == network.SecurityRuleDirectionInbound {
+				appliedToNsgIngressRules = append(appliedToNsgIngressRules, rule)
+			} else {
+				appliedToNsgEgressRules = append(appliedToNsgEgressRules, rule)
+			}
 			continue
 		}
 		if rule.Direction == network.SecurityRuleDirectionInbound {
@@ -373,8 +405,10 @@ func (computeCfg *computeServiceConfig) buildEffectivePeerNSGSecurityRulesToAppl
 	if err != nil {
 		return []network.SecurityRule{}, err
 	}
-	allIngressRules := updateSecurityRuleNameAndPriority(currentNsgIngressRules, newIngressSecurityRules)
-	allEgressRules := updateSecurityRuleNameAndPriority(currentNsgEgressRules, newEgressSecurityRules)
+	allIngressRules := updateSecurityRuleNameAndPriority(currentNsgIngressRules, newIngressSecurityRules,
+		appliedToNsgIngressRules)
+	allEgressRules := updateSecurityRuleNameAndPriority(currentNsgEgressRules, newEgressSecurityRules,
+		appliedToNsgEgressRules)
 
 	var rules []network.SecurityRule
 	rules = append(rules, allIngressRules...)
@@ -477,8 +511,11 @@ func (computeCfg *computeServiceConfig) removeReferencesToSecurityGroup(id *secu
 		return nil
 	}
 	err = updateNetworkSecurityGroupRules(computeCfg.nsgAPIClient, location, rgName, perVnetNsgNepheControllerName, rulesToKeep)
-
-	return err
+	if err != nil {
+		return err
+	}
+	updateSecurityRuleHeadroom(perVnetNsgNepheControllerName, rulesToKeep)
+	return nil
 }
 
 func getAsgsToAdd(asgs *[]network.ApplicationSecurityGroup, addrGroupNepheControllerName string) (
@@ -697,8 +734,8 @@ func (c *azureCloud) CreateSecurityGroup(addressGroupIdentifier *securitygroup.C
 
 	// find account managing the vnet
 	vnetID := addressGroupIdentifier.Vpc
-	accCfg := c.getVnetAccount(vnetID)
-	if accCfg == nil {
+	_, computeService := c.getVnetAccount(vnetID)
+	if computeService == nil {
 		azurePluginLogger().Info("azure account not found managing virtual network", vnetID, "vnetID")
 		return nil, fmt.Errorf("azure account not found managing virtual network [%v]", vnetID)
 	}
@@ -710,11 +747,6 @@ func (c *azureCloud) CreateSecurityGroup(addressGroupIdentifier *securitygroup.C
 	}
 
 	// create/get nsg/asg on/from cloud
-	serviceCfg, err := accCfg.GetServiceConfigByName(azureComputeServiceNameCompute)
-	if err != nil {
-		return nil, err
-	}
-	computeService := serviceCfg.(*computeServiceConfig)
 	location := computeService.credentials.region
 
 	if !membershipOnly {
@@ -751,20 +783,20 @@ func (c *azureCloud) CreateSecurityGroup(addressGroupIdentifier *securitygroup.C
 
 func (c *azureCloud) UpdateSecurityGroupRules(addressGroupIdentifier *securitygroup.CloudResourceID,
 	ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule) error {
+	// azure security rules match all ICMP messages of a protocol.
+	if securitygroup.HasICMPTypeCode(ingressRules, egressRules) {
+		return fmt.Errorf("icmp type and code are not supported by azure network security groups")
+	}
+
 	mutex.Lock()
 	defer mutex.Unlock()
 
 	// find account managing the vnet and get compute service config
 	vnetID := addressGroupIdentifier.Vpc
-	accCfg := c.getVnetAccount(vnetID)
-	if accCfg == nil {
+	_, computeService := c.getVnetAccount(vnetID)
+	if computeService == nil {
 		return fmt.Errorf("azure account not found managing virtual network [%v]", vnetID)
 	}
-	serviceCfg, err := accCfg.GetServiceConfigByName(azureComputeServiceNameCompute)
-	if err != nil {
-		return err
-	}
-	computeService := serviceCfg.(*computeServiceConfig)
 	location := computeService.credentials.region
 
 	// extract resource-group-name from vnet ID
@@ -818,6 +850,9 @@ func (c *azureCloud) UpdateSecurityGroupRules(addressGroupIdentifier *securitygr
 			return err
 		}
 	}
+	if err = checkSecurityRuleQuota(appliedToGroupPerVnetNsgNepheControllerName, rules); err != nil {
+		return err
+	}
 	// update network security group with rules
 	err = updateNetworkSecurityGroupRules(computeService.nsgAPIClient, location, rgName, appliedToGroupPerVnetNsgNepheControllerName, rules)
 	if err != nil {
@@ -832,15 +867,10 @@ func (c *azureCloud) UpdateSecurityGroupMembers(addressGroupIdentifier *security
 	defer mutex.Unlock()
 
 	vnetID := addressGroupIdentifier.Vpc
-	accCfg := c.getVnetAccount(vnetID)
-	if accCfg == nil {
+	_, computeService := c.getVnetAccount(vnetID)
+	if computeService == nil {
 		return fmt.Errorf("azure account not found managing virtual network [%v]", vnetID)
 	}
-	serviceCfg, err := accCfg.GetServiceConfigByName(azureComputeServiceNameCompute)
-	if err != nil {
-		return err
-	}
-	computeService := serviceCfg.(*computeServiceConfig)
 
 	return computeService.updateSecurityGroupMembers(addressGroupIdentifier, computeResourceIdentifier, membershipOnly)
 }
@@ -850,21 +880,15 @@ func (c *azureCloud) DeleteSecurityGroup(addressGroupIdentifier *securitygroup.C
 	defer mutex.Unlock()
 
 	vnetID := addressGroupIdentifier.Vpc
-	accCfg := c.getVnetAccount(vnetID)
-	if accCfg == nil {
+	_, computeService := c.getVnetAccount(vnetID)
+	if computeService == nil {
 		return fmt.Errorf("azure account not found managing virtual network [%v]", vnetID)
 	}
-	serviceCfg, err := accCfg.GetServiceConfigByName(azureComputeServiceNameCompute)
-	if err != nil {
-		return err
-	}
-	computeService := serviceCfg.(*computeServiceConfig)
 	location := computeService.credentials.region
 
 	_ = computeService.updateSecurityGroupMembers(addressGroupIdentifier, nil, membershipOnly)
 
-	var rgName string
-	_, rgName, _, err = extractFieldsFromAzureResourceID(addressGroupIdentifier.Vpc)
+	_, rgName, _, err := extractFieldsFromAzureResourceID(addressGroupIdentifier.Vpc)
 	if err != nil {
 		return err
 	}
@@ -884,7 +908,7 @@ func (c *azureCloud) DeleteSecurityGroup(addressGroupIdentifier *securitygroup.C
 	return err
 }
 
-func (c *azureCloud) GetEnforcedSecurity() []securitygroup.SynchronizationContent {
+func (c *azureCloud) GetEnforcedSecurity() ([]securitygroup.SynchronizationContent, error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -897,6 +921,8 @@ func (c *azureCloud) GetEnforcedSecurity() []securitygroup.SynchronizationConten
 	}
 
 	var enforcedSecurityCloudView []securitygroup.SynchronizationContent
+	var retErr error
+	var errMutex sync.Mutex
 	var wg sync.WaitGroup
 	ch := make(chan []securitygroup.SynchronizationContent)
 	wg.Add(len(accNamespacedNames))
@@ -920,18 +946,30 @@ func (c *azureCloud) GetEnforcedSecurity() []securitygroup.SynchronizationConten
 				return
 			}
 
-			serviceCfg, err := accCfg.GetServiceConfigByName(azureComputeServiceNameCompute)
-			if err != nil {
-				azurePluginLogger().Error(err, "enforced-security-cloud-view GET for account skipped", "account", accCfg.GetNamespacedName())
-				return
+			var cloudView []securitygroup.SynchronizationContent
+			var err error
+			for _, computeService := range getComputeServiceConfigs(accCfg) {
+				if e := computeService.waitForInventoryInit(inventoryInitWaitDuration); e != nil {
+					azurePluginLogger().Error(e, "enforced-security-cloud-view GET for account skipped", "account", accCfg.GetNamespacedName(),
+						"region", computeService.credentials.region)
+					err = multierr.Append(err, e)
+					continue
+				}
+				view, e := computeService.getNepheControllerManagedSecurityGroupsCloudView()
+				if e != nil {
+					azurePluginLogger().Error(e, "enforced-security-cloud-view GET for account failed", "account", accCfg.GetNamespacedName(),
+						"region", computeService.credentials.region)
+					err = multierr.Append(err, e)
+					continue
+				}
+				cloudView = append(cloudView, view...)
 			}
-			computeService := serviceCfg.(*computeServiceConfig)
-			err = computeService.waitForInventoryInit(inventoryInitWaitDuration)
 			if err != nil {
-				azurePluginLogger().Error(err, "enforced-security-cloud-view GET for account skipped", "account", accCfg.GetNamespacedName())
-				return
+				errMutex.Lock()
+				retErr = multierr.Append(retErr, fmt.Errorf("account %v: %w", name, err))
+				errMutex.Unlock()
 			}
-			sendCh <- computeService.getNepheControllerManagedSecurityGroupsCloudView()
+			sendCh <- cloudView
 		}(accNamespacedNameCopy, ch)
 	}
 
@@ -940,35 +978,38 @@ func (c *azureCloud) GetEnforcedSecurity() []securitygroup.SynchronizationConten
 			enforcedSecurityCloudView = append(enforcedSecurityCloudView, val...)
 		}
 	}
-	return enforcedSecurityCloudView
+	return enforcedSecurityCloudView, retErr
 }
 
-func (computeCfg *computeServiceConfig) getNepheControllerManagedSecurityGroupsCloudView() []securitygroup.SynchronizationContent {
+// getNepheControllerManagedSecurityGroupsCloudView returns security groups created by nephe in vnets of the account
+// inventory, or an error if they cannot be retrieved.
+func (computeCfg *computeServiceConfig) getNepheControllerManagedSecurityGroupsCloudView() ([]securitygroup.SynchronizationContent,
+	error) {
 	vnetIDs := computeCfg.getCachedVnetIDs()
 	if len(vnetIDs) == 0 {
-		return []securitygroup.SynchronizationContent{}
+		return []securitygroup.SynchronizationContent{}, nil
 	}
 
 	networkInterfaces, err := computeCfg.getNetworkInterfacesOfVnet(vnetIDs)
 	if err != nil {
-		return []securitygroup.SynchronizationContent{}
+		return nil, err
 	}
 
 	appliedToSgEnforcedView, antreaATSgNameSet, err := computeCfg.processAndBuildATSgView(networkInterfaces)
 	if err != nil {
-		return []securitygroup.SynchronizationContent{}
+		return nil, err
 	}
 
 	addressGroupSgEnforcedView, err := computeCfg.processAndBuildAGSgView(networkInterfaces, antreaATSgNameSet)
 	if err != nil {
-		return []securitygroup.SynchronizationContent{}
+		return nil, err
 	}
 
 	var enforcedSecurityCloudView []securitygroup.SynchronizationContent
 	enforcedSecurityCloudView = append(enforcedSecurityCloudView, appliedToSgEnforcedView...)
 	enforcedSecurityCloudView = append(enforcedSecurityCloudView, addressGroupSgEnforcedView...)
 
-	return enforcedSecurityCloudView
+	return enforcedSecurityCloudView, nil
 }
 
 func (computeCfg *computeServiceConfig) ifPeerProcessing(vnetID string) bool {
@@ -982,3 +1023,120 @@ func (computeCfg *computeServiceConfig) ifPeerProcessing(vnetID string) bool {
 	}
 	return false
 }
+
+// getUnmanagedSecurityGroups returns network security groups and application security groups not created by nephe,
+// attached to network interfaces of virtual machines in vnets of the account inventory. Application security groups
+// are returned as membership only security groups. Security groups are identified by lowercase resource ID.
+func (computeCfg *computeServiceConfig) getUnmanagedSecurityGroups() ([]securitygroup.UnmanagedSecurityGroup, error) {
+	vnetIDs := computeCfg.getCachedVnetIDs()
+	if len(vnetIDs) == 0 {
+		return nil, nil
+	}
+	networkInterfaces, err := computeCfg.getNetworkInterfacesOfVnet(vnetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	nsgIDToMemberCloudResourcesMap := make(map[string][]securitygroup.CloudResource)
+	asgIDToMemberCloudResourcesMap := make(map[string][]securitygroup.CloudResource)
+	for _, networkInterface := range networkInterfaces {
+		if networkInterface.VirtualMachineID == nil || networkInterface.ID == nil {
+			continue
+		}
+		cloudResource := securitygroup.CloudResource{
+			Type: securitygroup.CloudResourceTypeNIC,
+			Name: securitygroup.CloudResourceID{
+				Name: *networkInterface.ID,
+				Vpc:  strings.ToLower(to.String(networkInterface.VnetID)),
+			},
+		}
+		if networkInterface.NetworkSecurityGroupID != nil {
+			nsgIDLowercase := strings.ToLower(*networkInterface.NetworkSecurityGroupID)
+			_, _, nsgName, err := extractFieldsFromAzureResourceID(nsgIDLowercase)
+			if err == nil {
+				if _, _, isAT := securitygroup.IsNepheControllerCreatedSG(nsgName); !isAT {
+					nsgIDToMemberCloudResourcesMap[nsgIDLowercase] = append(nsgIDToMemberCloudResourcesMap[nsgIDLowercase],
+						cloudResource)
+				}
+			}
+		}
+		for _, asgID := range networkInterface.ApplicationSecurityGroupIDs {
+			if asgID == nil {
+				continue
+			}
+			asgIDLowercase := strings.ToLower(*asgID)
+			_, _, asgName, err := extractFieldsFromAzureResourceID(asgIDLowercase)
+			if err != nil {
+				continue
+			}
+			if _, isAG, isAT := securitygroup.IsNepheControllerCreatedSG(asgName); isAG || isAT {
+				continue
+			}
+			asgIDToMemberCloudResourcesMap[asgIDLowercase] = append(asgIDToMemberCloudResourcesMap[asgIDLowercase], cloudResource)
+		}
+	}
+
+	var unmanagedSGs []securitygroup.UnmanagedSecurityGroup
+	for asgID, members := range asgIDToMemberCloudResourcesMap {
+		_, _, asgName, _ := extractFieldsFromAzureResourceID(asgID)
+		unmanagedSGs = append(unmanagedSGs, securitygroup.UnmanagedSecurityGroup{
+			Resource:       securitygroup.CloudResourceID{Name: asgID},
+			CloudID:        asgID,
+			CloudName:      asgName,
+			MembershipOnly: true,
+			Members:        members,
+		})
+	}
+	if len(nsgIDToMemberCloudResourcesMap) != 0 {
+		networkSecurityGroups, err := computeCfg.nsgAPIClient.listAllComplete(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, networkSecurityGroup := range networkSecurityGroups {
+			nsgIDLowercase := strings.ToLower(to.String(networkSecurityGroup.ID))
+			members, found := nsgIDToMemberCloudResourcesMap[nsgIDLowercase]
+			if !found {
+				continue
+			}
+			var securityRules *[]network.SecurityRule
+			if networkSecurityGroup.SecurityGroupPropertiesFormat != nil {
+				securityRules = networkSecurityGroup.SecurityRules
+			}
+			// default security rules of network security groups are not imported.
+			ingressRules, egressRules, unsupportedRules := convertFromUnmanagedAzureSecurityRules(securityRules)
+			unmanagedSGs = append(unmanagedSGs, securitygroup.UnmanagedSecurityGroup{
+				Resource:         securitygroup.CloudResourceID{Name: nsgIDLowercase},
+				CloudID:          nsgIDLowercase,
+				CloudName:        to.String(networkSecurityGroup.Name),
+				Members:          members,
+				IngressRules:     ingressRules,
+				EgressRules:      egressRules,
+				UnsupportedRules: unsupportedRules,
+			})
+		}
+	}
+	sort.Slice(unmanagedSGs, func(i, j int) bool {
+		return unmanagedSGs[i].CloudID < unmanagedSGs[j].CloudID
+	})
+	return unmanagedSGs, nil
+}
+
+// GetUnmanagedSecurityGroups returns network security groups and application security groups not created by nephe,
+// attached to network interfaces of virtual machines in vnets of the account inventory.
+func (c *azureCloud) GetUnmanagedSecurityGroups(accNamespacedName *types.NamespacedName) ([]securitygroup.UnmanagedSecurityGroup, error) {
+	accCfg, found := c.cloudCommon.GetCloudAccountByName(accNamespacedName)
+	if !found {
+		return nil, fmt.Errorf("azure account %v not found", *accNamespacedName)
+	}
+
+	var unmanagedSGs []securitygroup.UnmanagedSecurityGroup
+	for _, computeService := range getComputeServiceConfigs(accCfg) {
+		sgs, err := computeService.getUnmanagedSecurityGroups()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get security groups of account %v, region %v: %v", *accNamespacedName,
+				computeService.credentials.region, err)
+		}
+		unmanagedSGs = append(unmanagedSGs, sgs...)
+	}
+	return unmanagedSGs, nil
+}
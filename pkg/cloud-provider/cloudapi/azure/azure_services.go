@@ -15,12 +15,18 @@
 package azure
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2021-01-01/subscriptions"
 	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"k8s.io/apimachinery/pkg/types"
 
+	"antrea.io/nephe/apis/crd/v1alpha1"
 	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
 )
 
@@ -35,6 +41,8 @@ type azureServiceClientCreateInterface interface {
 	securityGroups(subscriptionID string) (azureNsgWrapper, error)
 	applicationSecurityGroups(subscriptionID string) (azureAsgWrapper, error)
 	virtualNetworks(subscriptionID string) (azureVirtualNetworksWrapper, error)
+	subscriptions() (azureSubscriptionsWrapper, error)
+	eventQueue(queueURL string) (azureEventQueueWrapper, error)
 	// Add any azure service api client creation methods here
 }
 
@@ -42,6 +50,8 @@ type azureServiceClientCreateInterface interface {
 // Implements azureServiceClientCreateInterface interface.
 type azureServiceSdkConfigProvider struct {
 	authorizer autorest.Authorizer
+	// storageAuthorizer authorizes access to data of storage services, like messages of storage queues.
+	storageAuthorizer autorest.Authorizer
 }
 
 // azureServicesHelper.
@@ -54,27 +64,43 @@ type azureServicesHelperImpl struct{}
 // newServiceSdkConfigProvider returns config to create azure services clients.
 func (h *azureServicesHelperImpl) newServiceSdkConfigProvider(accCreds *azureAccountCredentials) (
 	azureServiceClientCreateInterface, error) {
+	authorizer, err := newAuthorizer(accCreds, azure.PublicCloud.ResourceManagerEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	storageAuthorizer, err := newAuthorizer(accCreds, azure.PublicCloud.ResourceIdentifiers.Storage)
+	if err != nil {
+		return nil, err
+	}
+	configProvider := &azureServiceSdkConfigProvider{
+		authorizer:        authorizer,
+		storageAuthorizer: storageAuthorizer,
+	}
+	return configProvider, nil
+}
+
+// newAuthorizer returns authorizer to access resource with account credentials.
+func newAuthorizer(accCreds *azureAccountCredentials, resource string) (autorest.Authorizer, error) {
 	var authorizer autorest.Authorizer
 	var err error
 	// use msi role base access if identity client id provided
 	if len(accCreds.identityClientID) != 0 {
 		msiConfig := auth.NewMSIConfig()
 		msiConfig.ClientID = accCreds.identityClientID
+		msiConfig.Resource = resource
 		authorizer, err = msiConfig.Authorizer()
 		if err != nil {
 			return nil, fmt.Errorf("unable to initialize Azure authorizer with identity: %v", err)
 		}
 	} else {
 		clientConfig := auth.NewClientCredentialsConfig(accCreds.clientID, accCreds.clientKey, accCreds.tenantID)
+		clientConfig.Resource = resource
 		authorizer, err = clientConfig.Authorizer()
 		if err != nil {
 			return nil, fmt.Errorf("unable to initialize Azure authorizer from credentials: %v", err)
 		}
 	}
-	configProvider := &azureServiceSdkConfigProvider{
-		authorizer: authorizer,
-	}
-	return configProvider, nil
+	return authorizer, nil
 }
 
 func newAzureServiceConfigs(accountNamespacedName *types.NamespacedName, accCredentials interface{}, azureSpecificHelper interface{}) (
@@ -89,11 +115,49 @@ func newAzureServiceConfigs(accountNamespacedName *types.NamespacedName, accCred
 		return nil, err
 	}
 
-	ec2Service, err := newComputeServiceConfig(accountNamespacedName.String(), azureServiceClientCreator, azureAccountCredentials)
-	if err != nil {
-		return nil, err
+	regions := azureAccountCredentials.regions
+	if len(regions) == 1 && regions[0] == v1alpha1.AllRegions {
+		if regions, err = getSubscriptionLocations(azureServiceClientCreator, azureAccountCredentials.subscriptionID); err != nil {
+			return nil, err
+		}
+	}
+
+	// create a compute service per region, all sharing the api clients of the subscription.
+	for _, region := range regions {
+		regionalCredentials := *azureAccountCredentials
+		regionalCredentials.region = region
+		serviceName := internal.GetRegionalServiceName(azureComputeServiceNameCompute, region, regions)
+		computeService, err := newComputeServiceConfig(accountNamespacedName.String(), serviceName, azureServiceClientCreator,
+			&regionalCredentials)
+		if err != nil {
+			return nil, err
+		}
+		serviceConfigs = append(serviceConfigs, computeService)
 	}
-	serviceConfigs = append(serviceConfigs, ec2Service)
 
 	return serviceConfigs, nil
 }
+
+// getSubscriptionLocations returns sorted names of all regions available to a subscription.
+func getSubscriptionLocations(serviceClientCreator azureServiceClientCreateInterface, subscriptionID string) ([]string, error) {
+	subscriptionsClient, err := serviceClientCreator.subscriptions()
+	if err != nil {
+		return nil, err
+	}
+	locations, err := subscriptionsClient.listLocations(context.Background(), subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	var regions []string
+	for _, location := range locations {
+		if location.Name == nil || (location.Type != "" && location.Type != subscriptions.LocationTypeRegion) {
+			continue
+		}
+		regions = append(regions, strings.ToLower(*location.Name))
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no regions available to subscription %v", subscriptionID)
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
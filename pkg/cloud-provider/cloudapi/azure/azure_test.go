@@ -15,17 +15,27 @@
 package azure
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"strings"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-03-01/network"
 	"github.com/Azure/azure-sdk-for-go/services/resourcegraph/mgmt/2021-03-01/resourcegraph"
 	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 var _ = Describe("Azure", func() {
@@ -242,6 +252,448 @@ var _ = Describe("Azure", func() {
 			filters := serviceConfig.(*computeServiceConfig).computeFilters[selector.Name]
 			Expect(filters).To(Equal(expectedQueryStrs))
 		})
+		It("Should match expected filter - tags only match", func() {
+			tags := map[string]string{"env": "prod", "app": ""}
+			expectedQueryStr, _ := getVMsByTagsAndSubscriptionIDsAndTenantIDsAndLocationsMatchQuery(nil, nil, nil, tags,
+				subIDs, tenantIDs, locations)
+			Expect(*expectedQueryStr).To(ContainSubstring(`| where isnotnull(tags["app"]) and tostring(tags["env"]) == "prod"`))
+			Expect(*expectedQueryStr).ToNot(ContainSubstring("| where vnetId in"))
+			vmSelector := []v1alpha1.VirtualMachineSelector{
+				{
+					VMMatch: []v1alpha1.EntityMatch{{MatchTags: tags}},
+				},
+			}
+
+			err := c.AddProviderAccount(account)
+			Expect(err).Should(BeNil())
+			selector.Spec.VMSelector = vmSelector
+			err = c.AddAccountResourceSelector(testAccountNamespacedName, selector)
+			Expect(err).Should(BeNil())
+
+			accCfg, _ := c.cloudCommon.GetCloudAccountByName(testAccountNamespacedName)
+			serviceConfig, _ := accCfg.GetServiceConfigByName(azureComputeServiceNameCompute)
+			filters := serviceConfig.(*computeServiceConfig).computeFilters[selector.Name]
+			Expect(filters).To(Equal([]*string{expectedQueryStr}))
+		})
+		It("Should match expected filter - vpcID and tags match", func() {
+			tags := map[string]string{"env": "prod"}
+			vnetIDs = []string{testVnetID02}
+			expectedVnetQueryStr, _ := getVMsByVnetIDsAndSubscriptionIDsAndTenantIDsAndLocationsMatchQuery(vnetIDs,
+				subIDs, tenantIDs, locations)
+			expectedTagQueryStr, _ := getVMsByTagsAndSubscriptionIDsAndTenantIDsAndLocationsMatchQuery([]string{testVnetID01},
+				[]string{"vm01"}, nil, tags, subIDs, tenantIDs, locations)
+			Expect(*expectedTagQueryStr).To(ContainSubstring("| where vnetId in"))
+			Expect(*expectedTagQueryStr).To(ContainSubstring("| where name in"))
+			vmSelector := []v1alpha1.VirtualMachineSelector{
+				{
+					VpcMatch: &v1alpha1.EntityMatch{MatchID: testVnetID01},
+					VMMatch:  []v1alpha1.EntityMatch{{MatchName: "vm01", MatchTags: tags}},
+				},
+				{
+					// vpcID only match overrides tags match in the same vpc.
+					VpcMatch: &v1alpha1.EntityMatch{MatchID: testVnetID02},
+				},
+				{
+					VpcMatch: &v1alpha1.EntityMatch{MatchID: testVnetID02},
+					VMMatch:  []v1alpha1.EntityMatch{{MatchTags: tags}},
+				},
+			}
+
+			err := c.AddProviderAccount(account)
+			Expect(err).Should(BeNil())
+			selector.Spec.VMSelector = vmSelector
+			err = c.AddAccountResourceSelector(testAccountNamespacedName, selector)
+			Expect(err).Should(BeNil())
+
+			accCfg, _ := c.cloudCommon.GetCloudAccountByName(testAccountNamespacedName)
+			serviceConfig, _ := accCfg.GetServiceConfigByName(azureComputeServiceNameCompute)
+			filters := serviceConfig.(*computeServiceConfig).computeFilters[selector.Name]
+			Expect(filters).To(Equal([]*string{expectedVnetQueryStr, expectedTagQueryStr}))
+		})
+	})
+
+	Context("NSG security rules", func() {
+		var (
+			atGroupID = &securitygroup.CloudResourceID{Name: "at1", Vpc: testVnetID01}
+			atAsgMap  = map[string]network.ApplicationSecurityGroup{
+				"at1": {ID: to.StringPtr(fmt.Sprintf("/subscriptions/%v/resourceGroups/%v/providers/"+
+					"Microsoft.Network/applicationSecurityGroups/nephe-at-at1", testSubID, testRG))},
+			}
+			tcp          = 6
+			port         = 22
+			_, ipNet1, _ = net.ParseCIDR("1.1.1.0/24")
+			_, ipNet2, _ = net.ParseCIDR("2.2.2.0/24")
+		)
+
+		It("Should place deny rules ahead of allow rules", func() {
+			ingressRules := []*securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionAllow},
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet2}, Action: securitygroup.RuleActionDeny},
+			}
+			existingRule := buildSecurityRule(to.Int32Ptr(ruleStartPriority), network.SecurityRuleProtocolTCP,
+				network.SecurityRuleDirectionInbound, to.StringPtr(emptyPort), to.StringPtr(emptyPort), nil, nil,
+				to.StringPtr(emptyPort), nil, nil, nil, to.StringPtr("nephe-at-at2"), network.SecurityRuleAccessAllow)
+			newRules, err := convertIngressToAzureNsgSecurityRules(atGroupID, ingressRules, nil, atAsgMap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(newRules)).To(Equal(3))
+			Expect(newRules[0].Access).To(Equal(network.SecurityRuleAccessAllow))
+			Expect(newRules[1].Access).To(Equal(network.SecurityRuleAccessDeny))
+
+			rules := updateSecurityRuleNameAndPriority([]network.SecurityRule{existingRule}, newRules, nil)
+			Expect(len(rules)).To(Equal(4))
+			Expect(rules[0].Access).To(Equal(network.SecurityRuleAccessDeny))
+			Expect(*rules[0].Priority).To(Equal(int32(ruleStartPriority)))
+			for _, rule := range rules[1:3] {
+				Expect(rule.Access).To(Equal(network.SecurityRuleAccessAllow))
+				Expect(*rule.Priority).To(BeNumerically(">", ruleStartPriority))
+			}
+			Expect(*rules[3].Priority).To(Equal(int32(vnetToVnetDenyRulePriority)))
+
+			ingressRulesBySgName, _ := convertToNepheControllerRulesByAppliedToSGName(&rules, testVnetID01)
+			Expect(ingressRulesBySgName["at1"]).To(ConsistOf(
+				securitygroup.IngressRule{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1},
+					Action: securitygroup.RuleActionAllow},
+				securitygroup.IngressRule{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet2},
+					Action: securitygroup.RuleActionDeny},
+			))
+		})
+
+		It("Should order rules by rule priorities and keep priorities stable", func() {
+			tier50 := &securitygroup.RulePriority{TierPriority: 50, PolicyPriority: 1}
+			tier100 := &securitygroup.RulePriority{TierPriority: 100, PolicyPriority: 10}
+			tier100Insert := &securitygroup.RulePriority{TierPriority: 100, PolicyPriority: 7.5}
+			existingRule := buildSecurityRule(to.Int32Ptr(150), network.SecurityRuleProtocolTCP,
+				network.SecurityRuleDirectionInbound, to.StringPtr(emptyPort), to.StringPtr(emptyPort), nil, nil,
+				to.StringPtr(emptyPort), nil, nil, nil, to.StringPtr("nephe-at-at2"), network.SecurityRuleAccessAllow)
+			existingRule.Name = to.StringPtr(getSecurityRuleName(150, network.SecurityRuleDirectionInbound,
+				&securitygroup.RulePriority{TierPriority: 100, PolicyPriority: 5}))
+			ingressRules := []*securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet2}, Action: securitygroup.RuleActionAllow},
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet2}, Action: securitygroup.RuleActionAllow,
+					Priority: tier100},
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionDeny,
+					Priority: tier50},
+			}
+			getPriorities := func(rules []network.SecurityRule) []int32 {
+				var priorities []int32
+				for _, rule := range rules {
+					priorities = append(priorities, *rule.Priority)
+				}
+				return priorities
+			}
+			update := func(currentRules []network.SecurityRule) []network.SecurityRule {
+				newRules, err := convertIngressToAzureNsgSecurityRules(atGroupID, ingressRules, nil, atAsgMap)
+				Expect(err).ToNot(HaveOccurred())
+				rules := updateSecurityRuleNameAndPriority([]network.SecurityRule{existingRule}, newRules, currentRules)
+				Expect(*rules[1].Name).To(Equal(*existingRule.Name))
+				return rules
+			}
+
+			rules := update(nil)
+			Expect(getPriorities(rules)).To(Equal([]int32{124, 150, 151, 152, vnetToVnetDenyRulePriority}))
+			Expect(*rules[0].Name).To(Equal("124-Inbound-t50-p1-r0"))
+			Expect(*rules[2].Name).To(Equal("151-Inbound-t100-p10-r0"))
+			Expect(*rules[3].Name).To(Equal("152-Inbound"))
+
+			ingressRulesBySgName, _ := convertToNepheControllerRulesByAppliedToSGName(&rules, testVnetID01)
+			Expect(ingressRulesBySgName["at1"]).To(ConsistOf(
+				*ingressRules[0], *ingressRules[1], *ingressRules[2]))
+			Expect(ingressRulesBySgName["at2"][0].Priority).To(Equal(
+				&securitygroup.RulePriority{TierPriority: 100, PolicyPriority: 5}))
+
+			currentRules := []network.SecurityRule{rules[0], rules[2], rules[3]}
+			rules = update(currentRules)
+			Expect(getPriorities(rules)).To(Equal([]int32{124, 150, 151, 152, vnetToVnetDenyRulePriority}))
+
+			ingressRules = append(ingressRules, &securitygroup.IngressRule{FromPort: &port, Protocol: &tcp,
+				FromSrcIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionAllow, Priority: tier100Insert})
+			rules = update(currentRules)
+			Expect(getPriorities(rules)).To(Equal([]int32{124, 150, 151, 152, 153, vnetToVnetDenyRulePriority}))
+			Expect(*rules[2].Name).To(Equal("151-Inbound-t100-p7.5-r0"))
+			Expect(*rules[3].Name).To(Equal("152-Inbound-t100-p10-r0"))
+		})
+
+		It("Should convert deny egress rules", func() {
+			egressRules := []*securitygroup.EgressRule{
+				{ToPort: &port, Protocol: &tcp, ToDstIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionDeny},
+			}
+			rules, err := convertEgressToAzureNsgSecurityRules(atGroupID, egressRules, nil, atAsgMap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rules)).To(Equal(2))
+			Expect(rules[0].Access).To(Equal(network.SecurityRuleAccessDeny))
+			Expect(*rules[0].DestinationAddressPrefixes).To(Equal([]string{ipNet1.String()}))
+		})
+
+		It("Should split IPv4 and IPv6 address prefixes into separate rules", func() {
+			_, ipv6Net, _ := net.ParseCIDR("fd00:1::/64")
+			ingressRules := []*securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1, ipv6Net, ipNet2},
+					Action: securitygroup.RuleActionAllow},
+			}
+			rules, err := convertIngressToAzureNsgSecurityRules(atGroupID, ingressRules, nil, atAsgMap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rules)).To(Equal(3))
+			Expect(*rules[0].SourceAddressPrefixes).To(Equal([]string{ipNet1.String(), ipNet2.String()}))
+			Expect(*rules[1].SourceAddressPrefixes).To(Equal([]string{ipv6Net.String()}))
+			Expect(*rules[1].Priority).To(Equal(*rules[0].Priority + 1))
+
+			ingressRulesBySgName, _ := convertToNepheControllerRulesByAppliedToSGName(&rules, testVnetID01)
+			Expect(ingressRulesBySgName["at1"]).To(ConsistOf(
+				securitygroup.IngressRule{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1, ipNet2},
+					Action: securitygroup.RuleActionAllow},
+				securitygroup.IngressRule{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipv6Net},
+					Action: securitygroup.RuleActionAllow},
+			))
+		})
+
+		It("Should convert port ranges", func() {
+			endPort := 8080
+			ingressRules := []*securitygroup.IngressRule{
+				{FromPort: &port, FromEndPort: &endPort, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1},
+					Action: securitygroup.RuleActionAllow},
+			}
+			egressRules := []*securitygroup.EgressRule{
+				{ToPort: &port, ToEndPort: &endPort, Protocol: &tcp, ToDstIP: []*net.IPNet{ipNet2},
+					Action: securitygroup.RuleActionAllow},
+			}
+			newIngressRules, err := convertIngressToAzureNsgSecurityRules(atGroupID, ingressRules, nil, atAsgMap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*newIngressRules[0].DestinationPortRange).To(Equal("22-8080"))
+			newEgressRules, err := convertEgressToAzureNsgSecurityRules(atGroupID, egressRules, nil, atAsgMap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*newEgressRules[0].DestinationPortRange).To(Equal("22-8080"))
+
+			rules := updateSecurityRuleNameAndPriority(nil, append(newIngressRules, newEgressRules...), nil)
+			ingressRulesBySgName, egressRulesBySgName := convertToNepheControllerRulesByAppliedToSGName(&rules, testVnetID01)
+			Expect(ingressRulesBySgName["at1"]).To(Equal([]securitygroup.IngressRule{*ingressRules[0]}))
+			for _, rule := range ingressRulesBySgName[appliedToSecurityGroupNamePerVnet] {
+				Expect(rule.FromCloudServices).To(BeEmpty())
+			}
+			for _, rule := range egressRulesBySgName[appliedToSecurityGroupNamePerVnet] {
+				Expect(rule.ToCloudServices).To(BeEmpty())
+			}
+			Expect(egressRulesBySgName["at1"]).To(Equal([]securitygroup.EgressRule{*egressRules[0]}))
+		})
+
+		It("Should convert cloud service peers to service tags", func() {
+			ingressRules := []*securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromCloudServices: []string{"AzureLoadBalancer"},
+					Action: securitygroup.RuleActionAllow},
+			}
+			egressRules := []*securitygroup.EgressRule{
+				{ToPort: &port, Protocol: &tcp, ToDstIP: []*net.IPNet{ipNet1},
+					ToCloudServices: []string{"Sql.WestUS2", "Storage.WestUS2"}, Action: securitygroup.RuleActionAllow},
+			}
+			newIngressRules, err := convertIngressToAzureNsgSecurityRules(atGroupID, ingressRules, nil, atAsgMap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(newIngressRules)).To(Equal(2))
+			Expect(*newIngressRules[0].SourceAddressPrefix).To(Equal("AzureLoadBalancer"))
+			newEgressRules, err := convertEgressToAzureNsgSecurityRules(atGroupID, egressRules, nil, atAsgMap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(newEgressRules)).To(Equal(4))
+			Expect(*newEgressRules[0].DestinationAddressPrefixes).To(Equal([]string{ipNet1.String()}))
+			Expect(*newEgressRules[1].DestinationAddressPrefix).To(Equal("Sql.WestUS2"))
+			Expect(*newEgressRules[2].DestinationAddressPrefix).To(Equal("Storage.WestUS2"))
+
+			rules := updateSecurityRuleNameAndPriority(nil, append(newIngressRules, newEgressRules...), nil)
+			ingressRulesBySgName, egressRulesBySgName := convertToNepheControllerRulesByAppliedToSGName(&rules, testVnetID01)
+			Expect(ingressRulesBySgName["at1"]).To(Equal([]securitygroup.IngressRule{*ingressRules[0]}))
+			Expect(egressRulesBySgName["at1"]).To(ConsistOf(
+				securitygroup.EgressRule{ToPort: &port, Protocol: &tcp, ToDstIP: []*net.IPNet{ipNet1},
+					Action: securitygroup.RuleActionAllow},
+				securitygroup.EgressRule{ToPort: &port, Protocol: &tcp, ToCloudServices: []string{"Sql.WestUS2"},
+					Action: securitygroup.RuleActionAllow},
+				securitygroup.EgressRule{ToPort: &port, Protocol: &tcp, ToCloudServices: []string{"Storage.WestUS2"},
+					Action: securitygroup.RuleActionAllow},
+			))
+		})
+
+		It("Should convert security rules of network security groups not created by nephe", func() {
+			asgID := fmt.Sprintf("/subscriptions/%v/resourceGroups/%v/providers/Microsoft.Network/applicationSecurityGroups/web",
+				testSubID, testRG)
+			getRule := func(name string, priority int32, direction network.SecurityRuleDirection, access network.SecurityRuleAccess,
+				source, destination, destinationPort string) network.SecurityRule {
+				return network.SecurityRule{Name: to.StringPtr(name), SecurityRulePropertiesFormat: &network.SecurityRulePropertiesFormat{
+					Priority: to.Int32Ptr(priority), Direction: direction, Access: access, Protocol: network.SecurityRuleProtocolTCP,
+					SourceAddressPrefix: to.StringPtr(source), DestinationAddressPrefix: to.StringPtr(destination),
+					SourcePortRange: to.StringPtr(emptyPort), DestinationPortRange: to.StringPtr(destinationPort)}}
+			}
+			asgRule := getRule("asg", 300, network.SecurityRuleDirectionInbound, network.SecurityRuleAccessAllow, "", "*", "22")
+			asgRule.SourceAddressPrefix = nil
+			asgRule.SourceApplicationSecurityGroups = &[]network.ApplicationSecurityGroup{{ID: to.StringPtr(asgID)}}
+			rules := []network.SecurityRule{
+				asgRule,
+				getRule("deny", 200, network.SecurityRuleDirectionInbound, network.SecurityRuleAccessDeny, "1.1.1.0/24", "*", "22"),
+				getRule("internet", 400, network.SecurityRuleDirectionInbound, network.SecurityRuleAccessAllow, "Internet", "*", "22"),
+				getRule("local", 500, network.SecurityRuleDirectionInbound, network.SecurityRuleAccessAllow, "*", "10.0.0.4", "22"),
+				getRule("egress", 100, network.SecurityRuleDirectionOutbound, network.SecurityRuleAccessAllow, "VirtualNetwork",
+					"2.2.2.2", "*"),
+			}
+
+			ingressRules, egressRules, unsupportedRules := convertFromUnmanagedAzureSecurityRules(&rules)
+			Expect(ingressRules).To(Equal([]securitygroup.IngressRule{
+				{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionDeny},
+				{FromPort: &port, Protocol: &tcp, Action: securitygroup.RuleActionAllow,
+					FromSecurityGroups: []*securitygroup.CloudResourceID{{Name: strings.ToLower(asgID)}}},
+			}))
+			_, hostNet, _ := net.ParseCIDR("2.2.2.2/32")
+			Expect(egressRules).To(HaveLen(1))
+			Expect(egressRules[0].ToDstIP[0].String()).To(Equal(hostNet.String()))
+			Expect(unsupportedRules).To(Equal([]string{
+				"ingress rule internet: unsupported peers Internet",
+				"ingress rule local: restricted to some members",
+			}))
+		})
+
+		It("Should check security rules against network security group quotas", func() {
+			var ingressRules []*securitygroup.IngressRule
+			for i := 0; i < azureMaxSecurityRulesPerNsg-1; i++ {
+				ingressRules = append(ingressRules, &securitygroup.IngressRule{Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}})
+			}
+			newRules, err := convertIngressToAzureNsgSecurityRules(atGroupID, ingressRules, nil, atAsgMap)
+			Expect(err).ToNot(HaveOccurred())
+			rules := updateSecurityRuleNameAndPriority(nil, newRules, nil)
+			headroom := metrics.SecurityGroupRuleHeadroom.WithLabelValues(string(providerType), "nephe-at-test")
+			Expect(checkSecurityRuleQuota("nephe-at-test", rules)).To(Succeed())
+			Expect(testutil.ToFloat64(headroom)).To(Equal(float64(0)))
+
+			rules = append(rules, rules[0])
+			err = checkSecurityRuleQuota("nephe-at-test", rules)
+			Expect(errors.Is(err, securitygroup.ErrQuotaExceeded)).To(BeTrue())
+			Expect(err.Error()).To(Equal("quota exceeded: network security group nephe-at-test requires 1001 security rules, " +
+				"security rules per network security group quota is 1000"))
+			Expect(testutil.ToFloat64(headroom)).To(Equal(float64(-1)))
+		})
+
+		It("Should stop reporting headroom of network security group once its rules are removed", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			defer mockCtrl.Finish()
+			mockazureNsgWrapper := NewMockazureNsgWrapper(mockCtrl)
+			computeCfg := &computeServiceConfig{nsgAPIClient: mockazureNsgWrapper,
+				resourcesCache: &internal.CloudServiceResourcesCache{}}
+			nsgName := "nephe-at-" + appliedToSecurityGroupNamePerVnet + "-" + testVnet01
+
+			ingressRules := []*securitygroup.IngressRule{{Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}}}
+			newRules, err := convertIngressToAzureNsgSecurityRules(atGroupID, ingressRules, nil, atAsgMap)
+			Expect(err).ToNot(HaveOccurred())
+			rules := updateSecurityRuleNameAndPriority(nil, newRules, nil)
+			rules = append(rules, network.SecurityRule{SecurityRulePropertiesFormat: &network.SecurityRulePropertiesFormat{
+				Priority: to.Int32Ptr(vnetToVnetDenyRulePriority), Direction: network.SecurityRuleDirectionInbound}})
+			Expect(checkSecurityRuleQuota(nsgName, rules)).To(Succeed())
+
+			mockazureNsgWrapper.EXPECT().get(gomock.Any(), testRG, nsgName, "").Return(network.SecurityGroup{
+				SecurityGroupPropertiesFormat: &network.SecurityGroupPropertiesFormat{SecurityRules: &rules}}, nil)
+			mockazureNsgWrapper.EXPECT().createOrUpdate(gomock.Any(), testRG, nsgName, gomock.Any()).
+				Return(network.SecurityGroup{}, nil)
+			Expect(computeCfg.removeReferencesToSecurityGroup(atGroupID, testRG, testRegion, false)).To(Succeed())
+			Expect(metrics.SecurityGroupRuleHeadroom.DeleteLabelValues(string(providerType), nsgName)).To(BeFalse())
+		})
+	})
+
+	Context("Network security groups replaced on network interfaces", func() {
+		var (
+			nsgIDPrefix = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/"
+			userNsgID   = nsgIDPrefix + "User-NSG"
+			nepheNsg    = network.SecurityGroup{ID: to.StringPtr(nsgIDPrefix + "nephe-at-appliedtosecuritygroup-vnet")}
+		)
+
+		It("Should record network security group replaced by nephe and restore it", func() {
+			nwIntf := &network.Interface{InterfacePropertiesFormat: &network.InterfacePropertiesFormat{
+				NetworkSecurityGroup: &network.SecurityGroup{ID: to.StringPtr(userNsgID)}}}
+			nsg, tags := getUpdatedNetworkInterfaceNsgAndTags(nwIntf, nepheNsg, true, "nephe-at-web")
+			Expect(*nsg.ID).To(Equal(*nepheNsg.ID))
+			Expect(tags).To(HaveKeyWithValue(networkInterfaceOriginalNsgTagKey, to.StringPtr(userNsgID)))
+
+			nwIntf.Tags = tags
+			nsg, tags = getUpdatedNetworkInterfaceNsgAndTags(nwIntf, nepheNsg, true, "nephe-at-db")
+			Expect(*nsg.ID).To(Equal(*nepheNsg.ID))
+			Expect(tags).To(HaveKeyWithValue(networkInterfaceOriginalNsgTagKey, to.StringPtr(userNsgID)))
+
+			nwIntf.Tags = tags
+			nsg, tags = getUpdatedNetworkInterfaceNsgAndTags(nwIntf, nepheNsg, false, "nephe-at-web")
+			Expect(*nsg.ID).To(Equal(*nepheNsg.ID))
+			Expect(tags).To(HaveKey(networkInterfaceOriginalNsgTagKey))
+
+			nwIntf.Tags = tags
+			nsg, tags = getUpdatedNetworkInterfaceNsgAndTags(nwIntf, nepheNsg, false, "nephe-at-db")
+			Expect(*nsg.ID).To(Equal(userNsgID))
+			Expect(tags).To(BeEmpty())
+		})
+
+		It("Should detach network security group created by nephe without a record", func() {
+			nwIntf := &network.Interface{InterfacePropertiesFormat: &network.InterfacePropertiesFormat{}}
+			_, tags := getUpdatedNetworkInterfaceNsgAndTags(nwIntf, nepheNsg, true, "nephe-at-web")
+			Expect(tags).ToNot(HaveKey(networkInterfaceOriginalNsgTagKey))
+
+			nwIntf.Tags = tags
+			nsg, tags := getUpdatedNetworkInterfaceNsgAndTags(nwIntf, nepheNsg, false, "nephe-at-web")
+			Expect(nsg).To(BeNil())
+			Expect(tags).To(BeEmpty())
+		})
+	})
+
+	Context("VirtualMachine CRD", func() {
+		var mockCtrl *gomock.Controller
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("Should report cloud properties of virtual machine", func() {
+			vnetID := "/subscriptions/" + testSubID + "/resourceGroups/" + testRG + "/providers/Microsoft.Network/virtualNetworks/vnet01"
+			nsgID := "/subscriptions/" + testSubID + "/resourceGroups/" + testRG + "/providers/Microsoft.Network/networkSecurityGroups/nsg01"
+			asgID := "/subscriptions/" + testSubID + "/resourceGroups/" + testRG + "/providers/Microsoft.Network/applicationSecurityGroups/asg01"
+			row := map[string]interface{}{
+				"id":     "/subscriptions/" + testSubID + "/resourcegroups/" + testRG + "/providers/microsoft.compute/virtualmachines/vm01",
+				"name":   "vm01",
+				"status": "PowerState/running",
+				"vnetId": vnetID,
+				"vmSize": "Standard_B1s",
+				"osType": "Linux",
+				"zones":  []interface{}{"2"},
+				"imageReference": map[string]interface{}{
+					"publisher": "Canonical", "offer": "UbuntuServer", "sku": "18.04-LTS", "version": "latest",
+				},
+				"timeCreated": "2022-10-01T08:02:03.1234567Z",
+				"networkInterfaces": []interface{}{
+					map[string]interface{}{
+						"id":                        "nic01",
+						"name":                      "nic01",
+						"privateIps":                []interface{}{"10.0.0.4"},
+						"publicIps":                 []interface{}{"20.0.0.4"},
+						"vnetId":                    vnetID,
+						"subnetId":                  vnetID + "/subnets/default",
+						"nsgId":                     nsgID,
+						"applicationSecurityGroups": []interface{}{[]interface{}{map[string]interface{}{"id": asgID}}},
+					},
+				},
+			}
+			var records int64 = 1
+			mockResourceGraph := NewMockazureResourceGraphWrapper(mockCtrl)
+			mockResourceGraph.EXPECT().resources(gomock.Any(), gomock.Any()).
+				Return(resourcegraph.QueryResponse{TotalRecords: &records, Data: []interface{}{row}}, nil)
+
+			vms, _, err := getVirtualMachineTable(mockResourceGraph, to.StringPtr("query"), []string{testSubID})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vms).To(HaveLen(1))
+			vm := computeInstanceToVirtualMachineCRD(vms[0], "default", testRegion)
+			Expect(vm.Status.Region).To(Equal(testRegion))
+			Expect(vm.Status.AvailabilityZone).To(Equal(testRegion + "-2"))
+			Expect(vm.Status.InstanceType).To(Equal("Standard_B1s"))
+			Expect(vm.Status.OSType).To(Equal("linux"))
+			Expect(vm.Status.ImageID).To(Equal("Canonical:UbuntuServer:18.04-LTS:latest"))
+			Expect(vm.Status.LaunchTime.UTC().Format(time.RFC3339)).To(Equal("2022-10-01T08:02:03Z"))
+			Expect(vm.Status.SecurityGroups).To(Equal([]string{strings.ToLower(asgID), strings.ToLower(nsgID)}))
+			Expect(vm.Status.NetworkInterfaces[0].Subnet).To(Equal(vnetID + "/subnets/default"))
+			Expect(vm.Status.NetworkInterfaces[0].IPs).To(Equal([]v1alpha1.IPAddress{
+				{AddressType: v1alpha1.AddressTypeInternalIP, Address: "10.0.0.4"},
+				{AddressType: v1alpha1.AddressTypeExternalIP, Address: "20.0.0.4"},
+			}))
+		})
 	})
 })
 
@@ -124,11 +124,12 @@ func (mr *MockCloudInterfaceMockRecorder) GetAccountStatus(accNamespacedName int
 }
 
 // GetEnforcedSecurity mocks base method.
-func (m *MockCloudInterface) GetEnforcedSecurity() []securitygroup.SynchronizationContent {
+func (m *MockCloudInterface) GetEnforcedSecurity() ([]securitygroup.SynchronizationContent, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetEnforcedSecurity")
 	ret0, _ := ret[0].([]securitygroup.SynchronizationContent)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GetEnforcedSecurity indicates an expected call of GetEnforcedSecurity.
@@ -137,6 +138,35 @@ func (mr *MockCloudInterfaceMockRecorder) GetEnforcedSecurity() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEnforcedSecurity", reflect.TypeOf((*MockCloudInterface)(nil).GetEnforcedSecurity))
 }
 
+// GetUnmanagedSecurityGroups mocks base method.
+func (m *MockCloudInterface) GetUnmanagedSecurityGroups(accNamespacedName *types.NamespacedName) ([]securitygroup.UnmanagedSecurityGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUnmanagedSecurityGroups", accNamespacedName)
+	ret0, _ := ret[0].([]securitygroup.UnmanagedSecurityGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUnmanagedSecurityGroups indicates an expected call of GetUnmanagedSecurityGroups.
+func (mr *MockCloudInterfaceMockRecorder) GetUnmanagedSecurityGroups(accNamespacedName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUnmanagedSecurityGroups", reflect.TypeOf((*MockCloudInterface)(nil).GetUnmanagedSecurityGroups), accNamespacedName)
+}
+
+// GetVpcAccount mocks base method.
+func (m *MockCloudInterface) GetVpcAccount(uniqueIdentifier string) *types.NamespacedName {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVpcAccount", uniqueIdentifier)
+	ret0, _ := ret[0].(*types.NamespacedName)
+	return ret0
+}
+
+// GetVpcAccount indicates an expected call of GetVpcAccount.
+func (mr *MockCloudInterfaceMockRecorder) GetVpcAccount(uniqueIdentifier interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVpcAccount", reflect.TypeOf((*MockCloudInterface)(nil).GetVpcAccount), uniqueIdentifier)
+}
+
 // Instances mocks base method.
 func (m *MockCloudInterface) Instances() ([]*v1alpha1.VirtualMachine, error) {
 	m.ctrl.T.Helper()
@@ -247,6 +277,36 @@ func (mr *MockCloudInterfaceMockRecorder) UpdateSecurityGroupRules(addressGroupI
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSecurityGroupRules", reflect.TypeOf((*MockCloudInterface)(nil).UpdateSecurityGroupRules), addressGroupIdentifier, ingressRules, egressRules)
 }
 
+// VpcsGivenProviderAccount mocks base method.
+func (m *MockCloudInterface) VpcsGivenProviderAccount(namespacedName *types.NamespacedName) ([]*v1alpha1.Vpc, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VpcsGivenProviderAccount", namespacedName)
+	ret0, _ := ret[0].([]*v1alpha1.Vpc)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VpcsGivenProviderAccount indicates an expected call of VpcsGivenProviderAccount.
+func (mr *MockCloudInterfaceMockRecorder) VpcsGivenProviderAccount(namespacedName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VpcsGivenProviderAccount", reflect.TypeOf((*MockCloudInterface)(nil).VpcsGivenProviderAccount), namespacedName)
+}
+
+// WatchAccountInventory mocks base method.
+func (m *MockCloudInterface) WatchAccountInventory(accNamespacedName *types.NamespacedName, stopCh <-chan struct{}) (<-chan struct{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchAccountInventory", accNamespacedName, stopCh)
+	ret0, _ := ret[0].(<-chan struct{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchAccountInventory indicates an expected call of WatchAccountInventory.
+func (mr *MockCloudInterfaceMockRecorder) WatchAccountInventory(accNamespacedName, stopCh interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchAccountInventory", reflect.TypeOf((*MockCloudInterface)(nil).WatchAccountInventory), accNamespacedName, stopCh)
+}
+
 // MockAccountMgmtInterface is a mock of AccountMgmtInterface interface.
 type MockAccountMgmtInterface struct {
 	ctrl     *gomock.Controller
@@ -337,6 +397,21 @@ func (mr *MockAccountMgmtInterfaceMockRecorder) RemoveProviderAccount(namespaced
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveProviderAccount", reflect.TypeOf((*MockAccountMgmtInterface)(nil).RemoveProviderAccount), namespacedName)
 }
 
+// WatchAccountInventory mocks base method.
+func (m *MockAccountMgmtInterface) WatchAccountInventory(accNamespacedName *types.NamespacedName, stopCh <-chan struct{}) (<-chan struct{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchAccountInventory", accNamespacedName, stopCh)
+	ret0, _ := ret[0].(<-chan struct{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchAccountInventory indicates an expected call of WatchAccountInventory.
+func (mr *MockAccountMgmtInterfaceMockRecorder) WatchAccountInventory(accNamespacedName, stopCh interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchAccountInventory", reflect.TypeOf((*MockAccountMgmtInterface)(nil).WatchAccountInventory), accNamespacedName, stopCh)
+}
+
 // MockComputeInterface is a mock of ComputeInterface interface.
 type MockComputeInterface struct {
 	ctrl     *gomock.Controller
@@ -360,6 +435,20 @@ func (m *MockComputeInterface) EXPECT() *MockComputeInterfaceMockRecorder {
 	return m.recorder
 }
 
+// GetVpcAccount mocks base method.
+func (m *MockComputeInterface) GetVpcAccount(uniqueIdentifier string) *types.NamespacedName {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVpcAccount", uniqueIdentifier)
+	ret0, _ := ret[0].(*types.NamespacedName)
+	return ret0
+}
+
+// GetVpcAccount indicates an expected call of GetVpcAccount.
+func (mr *MockComputeInterfaceMockRecorder) GetVpcAccount(uniqueIdentifier interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVpcAccount", reflect.TypeOf((*MockComputeInterface)(nil).GetVpcAccount), uniqueIdentifier)
+}
+
 // Instances mocks base method.
 func (m *MockComputeInterface) Instances() ([]*v1alpha1.VirtualMachine, error) {
 	m.ctrl.T.Helper()
@@ -404,6 +493,21 @@ func (mr *MockComputeInterfaceMockRecorder) IsVirtualPrivateCloudPresent(uniqueI
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsVirtualPrivateCloudPresent", reflect.TypeOf((*MockComputeInterface)(nil).IsVirtualPrivateCloudPresent), uniqueIdentifier)
 }
 
+// VpcsGivenProviderAccount mocks base method.
+func (m *MockComputeInterface) VpcsGivenProviderAccount(namespacedName *types.NamespacedName) ([]*v1alpha1.Vpc, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VpcsGivenProviderAccount", namespacedName)
+	ret0, _ := ret[0].([]*v1alpha1.Vpc)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VpcsGivenProviderAccount indicates an expected call of VpcsGivenProviderAccount.
+func (mr *MockComputeInterfaceMockRecorder) VpcsGivenProviderAccount(namespacedName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VpcsGivenProviderAccount", reflect.TypeOf((*MockComputeInterface)(nil).VpcsGivenProviderAccount), namespacedName)
+}
+
 // MockSecurityInterface is a mock of SecurityInterface interface.
 type MockSecurityInterface struct {
 	ctrl     *gomock.Controller
@@ -457,11 +561,12 @@ func (mr *MockSecurityInterfaceMockRecorder) DeleteSecurityGroup(addressGroupIde
 }
 
 // GetEnforcedSecurity mocks base method.
-func (m *MockSecurityInterface) GetEnforcedSecurity() []securitygroup.SynchronizationContent {
+func (m *MockSecurityInterface) GetEnforcedSecurity() ([]securitygroup.SynchronizationContent, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetEnforcedSecurity")
 	ret0, _ := ret[0].([]securitygroup.SynchronizationContent)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GetEnforcedSecurity indicates an expected call of GetEnforcedSecurity.
@@ -470,6 +575,21 @@ func (mr *MockSecurityInterfaceMockRecorder) GetEnforcedSecurity() *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEnforcedSecurity", reflect.TypeOf((*MockSecurityInterface)(nil).GetEnforcedSecurity))
 }
 
+// GetUnmanagedSecurityGroups mocks base method.
+func (m *MockSecurityInterface) GetUnmanagedSecurityGroups(accNamespacedName *types.NamespacedName) ([]securitygroup.UnmanagedSecurityGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUnmanagedSecurityGroups", accNamespacedName)
+	ret0, _ := ret[0].([]securitygroup.UnmanagedSecurityGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUnmanagedSecurityGroups indicates an expected call of GetUnmanagedSecurityGroups.
+func (mr *MockSecurityInterfaceMockRecorder) GetUnmanagedSecurityGroups(accNamespacedName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUnmanagedSecurityGroups", reflect.TypeOf((*MockSecurityInterface)(nil).GetUnmanagedSecurityGroups), accNamespacedName)
+}
+
 // UpdateSecurityGroupMembers mocks base method.
 func (m *MockSecurityInterface) UpdateSecurityGroupMembers(addressGroupIdentifier *securitygroup.CloudResourceID, computeResourceIdentifier []*securitygroup.CloudResource, membershipOnly bool) error {
 	m.ctrl.T.Helper()
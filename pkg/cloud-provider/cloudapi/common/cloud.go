@@ -29,6 +29,7 @@ var (
 	APIVersion                      = "crd.cloud.antrea.io/v1alpha1"
 	NetworkInterfaceCRDKind         = reflect.TypeOf(v1alpha1.NetworkInterface{}).Name()
 	VirtualMachineCRDKind           = reflect.TypeOf(v1alpha1.VirtualMachine{}).Name()
+	VpcCRDKind                      = reflect.TypeOf(v1alpha1.Vpc{}).Name()
 	VirtualPrivateCloud             = "vpc"
 	AnnotationCloudAssignedIDKey    = "cloud-assigned-id"
 	AnnotationCloudAssignedNameKey  = "cloud-assigned-name"
@@ -63,6 +64,9 @@ type AccountMgmtInterface interface {
 	RemoveAccountResourcesSelector(accNamespacedName *types.NamespacedName, selector string)
 	// GetAccountStatus gets accounts status.
 	GetAccountStatus(accNamespacedName *types.NamespacedName) (*cloudv1alpha1.CloudProviderAccountStatus, error)
+	// WatchAccountInventory returns a channel notified when account inventory is updated by cloud resource events,
+	// until stopCh is closed.
+	WatchAccountInventory(accNamespacedName *types.NamespacedName, stopCh <-chan struct{}) (<-chan struct{}, error)
 }
 
 // ComputeInterface is an abstract providing set of methods to get Instance details to be implemented by cloud providers.
@@ -71,8 +75,13 @@ type ComputeInterface interface {
 	Instances() ([]*v1alpha1.VirtualMachine, error)
 	// InstancesGivenProviderAccount returns VirtualMachineStatus for a given account of a cloud provider.
 	InstancesGivenProviderAccount(namespacedName *types.NamespacedName) ([]*v1alpha1.VirtualMachine, error)
+	// VpcsGivenProviderAccount returns Vpcs for a given account of a cloud provider.
+	VpcsGivenProviderAccount(namespacedName *types.NamespacedName) ([]*v1alpha1.Vpc, error)
 	// IsVirtualPrivateCloudPresent returns true if given virtual private cloud uniqueIdentifier is managed by the cloud, else false.
 	IsVirtualPrivateCloudPresent(uniqueIdentifier string) bool
+	// GetVpcAccount returns namespaced name of the account managing given virtual private cloud uniqueIdentifier, nil if
+	// not managed by the cloud.
+	GetVpcAccount(uniqueIdentifier string) *types.NamespacedName
 }
 
 type SecurityInterface interface {
@@ -85,7 +94,8 @@ type SecurityInterface interface {
 	// UpdateSecurityGroupMembers updates membership of cloud security group corresponding to provided address group. Only
 	// provided computeResources will remain attached to cloud security group. UpdateSecurityGroupMembers will also make sure that
 	// after membership update, if compute resource is no longer attached to any nephe created cloud security group, then
-	// compute resource will get moved to cloud default security group
+	// compute resource will get moved to cloud default security group. In Coexist security group mode, cloud security groups not
+	// created by nephe remain attached to compute resources.
 	UpdateSecurityGroupMembers(addressGroupIdentifier *securitygroup.CloudResourceID, computeResourceIdentifier []*securitygroup.CloudResource,
 		membershipOnly bool) error
 	// DeleteSecurityGroup will delete the cloud security group corresponding to provided address group. DeleteSecurityGroup expects that
@@ -93,6 +103,10 @@ type SecurityInterface interface {
 	// do the best effort to find resources using this address group and detach the cloud security group from those resources.Also if the
 	// compute resource is attached to only this security group, it will be moved to cloud default security group.
 	DeleteSecurityGroup(addressGroupIdentifier *securitygroup.CloudResourceID, membershipOnly bool) error
-	// GetEnforcedSecurity returns the cloud view of enforced security
-	GetEnforcedSecurity() []securitygroup.SynchronizationContent
+	// GetEnforcedSecurity returns the cloud view of enforced security, and an error if the view of any account cannot
+	// be retrieved, in which case the view is incomplete.
+	GetEnforcedSecurity() ([]securitygroup.SynchronizationContent, error)
+	// GetUnmanagedSecurityGroups returns cloud security groups, not created by nephe, attached to network interfaces of
+	// the account inventory, to import them as network policies.
+	GetUnmanagedSecurityGroups(accNamespacedName *types.NamespacedName) ([]securitygroup.UnmanagedSecurityGroup, error)
 }
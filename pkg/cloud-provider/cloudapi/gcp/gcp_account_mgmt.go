@@ -0,0 +1,106 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
+)
+
+// gcpRegionRegex matches GCP region names, e.g. us-central1, europe-west4, northamerica-northeast2.
+var gcpRegionRegex = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+$`)
+
+type gcpAccountCredentials struct {
+	projectID           string
+	serviceAccountKey   string
+	serviceAccountEmail string
+	region              string
+}
+
+// setAccountCredentials sets account credentials.
+func setAccountCredentials(credentials interface{}) (interface{}, error) {
+	gcpConfig := credentials.(*v1alpha1.CloudProviderAccountGCPConfig)
+	accCreds := &gcpAccountCredentials{
+		projectID:           strings.TrimSpace(gcpConfig.ProjectID),
+		serviceAccountKey:   strings.TrimSpace(gcpConfig.ServiceAccountKey),
+		serviceAccountEmail: strings.TrimSpace(gcpConfig.ServiceAccountEmail),
+		region:              strings.TrimSpace(gcpConfig.Region),
+	}
+
+	if !gcpRegionRegex.MatchString(accCreds.region) {
+		return nil, fmt.Errorf("%v is not a valid gcp region name", accCreds.region)
+	}
+	if len(accCreds.serviceAccountEmail) == 0 && !json.Valid([]byte(accCreds.serviceAccountKey)) {
+		return nil, fmt.Errorf("service account key for project %v is not valid json", accCreds.projectID)
+	}
+
+	return accCreds, nil
+}
+
+// compareAccountCredentials compares two account credentials.
+func compareAccountCredentials(accountName string, existing interface{}, new interface{}) bool {
+	existingCreds := existing.(*gcpAccountCredentials)
+	newCreds := new.(*gcpAccountCredentials)
+
+	credsChanged := false
+	if strings.Compare(existingCreds.projectID, newCreds.projectID) != 0 {
+		credsChanged = true
+		gcpPluginLogger().Info("account project ID updated", "account", accountName)
+	}
+	if strings.Compare(existingCreds.serviceAccountKey, newCreds.serviceAccountKey) != 0 {
+		credsChanged = true
+		gcpPluginLogger().Info("account service account key updated", "account", accountName)
+	}
+	if strings.Compare(existingCreds.serviceAccountEmail, newCreds.serviceAccountEmail) != 0 {
+		credsChanged = true
+		gcpPluginLogger().Info("account service account email updated", "account", accountName)
+	}
+	if strings.Compare(existingCreds.region, newCreds.region) != 0 {
+		credsChanged = true
+		gcpPluginLogger().Info("account region updated", "account", accountName)
+	}
+	return credsChanged
+}
+
+// getVpcAccount returns the account managing the given network ID.
+func (c *gcpCloud) getVpcAccount(vpcID string) internal.CloudAccountInterface {
+	accCfgs := c.cloudCommon.GetCloudAccounts()
+	if len(accCfgs) == 0 {
+		return nil
+	}
+
+	for _, accCfg := range accCfgs {
+		gceServiceCfg, err := accCfg.GetServiceConfigByName(gcpComputeServiceNameGCE)
+		if err != nil {
+			gcpPluginLogger().Error(err, "get gce service config failed", "vpcID", vpcID, "account", accCfg.GetNamespacedName())
+			continue
+		}
+		accVpcIDs := gceServiceCfg.(*gceServiceConfig).getCachedVpcIDs()
+		if len(accVpcIDs) == 0 {
+			gcpPluginLogger().Info("no vpc found for account", "vpcID", vpcID, "account", accCfg.GetNamespacedName())
+			continue
+		}
+		if _, found := accVpcIDs[strings.ToLower(vpcID)]; found {
+			return accCfg
+		}
+		gcpPluginLogger().Info("vpcID not found in cache", "vpcID", vpcID, "account", accCfg.GetNamespacedName())
+	}
+	return nil
+}
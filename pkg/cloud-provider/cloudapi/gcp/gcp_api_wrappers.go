@@ -0,0 +1,138 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/compute/v1"
+)
+
+type gcpComputeWrapper interface {
+	// instances
+	pagedListInstancesWrapper() ([]*compute.Instance, error)
+	setInstanceTags(zone string, instance string, tags *compute.Tags) error
+
+	// networks
+	pagedListNetworksWrapper() ([]*compute.Network, error)
+
+	// firewalls
+	pagedListFirewallsWrapper() ([]*compute.Firewall, error)
+	insertFirewall(firewall *compute.Firewall) error
+	updateFirewall(firewall *compute.Firewall) error
+	deleteFirewall(name string) error
+}
+
+type gcpComputeWrapperImpl struct {
+	projectID string
+	compute   *compute.Service
+}
+
+func (computeWrapper *gcpComputeWrapperImpl) pagedListInstancesWrapper() ([]*compute.Instance, error) {
+	var instances []*compute.Instance
+	call := computeWrapper.compute.Instances.AggregatedList(computeWrapper.projectID)
+	err := call.Pages(context.Background(), func(page *compute.InstanceAggregatedList) error {
+		for _, scopedList := range page.Items {
+			instances = append(instances, scopedList.Instances...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing gce instances: %q", err)
+	}
+	return instances, nil
+}
+
+func (computeWrapper *gcpComputeWrapperImpl) setInstanceTags(zone string, instance string, tags *compute.Tags) error {
+	op, err := computeWrapper.compute.Instances.SetTags(computeWrapper.projectID, zone, instance, tags).Do()
+	if err != nil {
+		return err
+	}
+	op, err = computeWrapper.compute.ZoneOperations.Wait(computeWrapper.projectID, zone, op.Name).Do()
+	if err != nil {
+		return err
+	}
+	return operationError(op)
+}
+
+func (computeWrapper *gcpComputeWrapperImpl) pagedListNetworksWrapper() ([]*compute.Network, error) {
+	var networks []*compute.Network
+	call := computeWrapper.compute.Networks.List(computeWrapper.projectID)
+	err := call.Pages(context.Background(), func(page *compute.NetworkList) error {
+		networks = append(networks, page.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing gce networks: %q", err)
+	}
+	return networks, nil
+}
+
+func (computeWrapper *gcpComputeWrapperImpl) pagedListFirewallsWrapper() ([]*compute.Firewall, error) {
+	var firewalls []*compute.Firewall
+	call := computeWrapper.compute.Firewalls.List(computeWrapper.projectID)
+	err := call.Pages(context.Background(), func(page *compute.FirewallList) error {
+		firewalls = append(firewalls, page.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing gce firewalls: %q", err)
+	}
+	return firewalls, nil
+}
+
+func (computeWrapper *gcpComputeWrapperImpl) insertFirewall(firewall *compute.Firewall) error {
+	op, err := computeWrapper.compute.Firewalls.Insert(computeWrapper.projectID, firewall).Do()
+	if err != nil {
+		return err
+	}
+	return computeWrapper.waitForGlobalOperation(op)
+}
+
+func (computeWrapper *gcpComputeWrapperImpl) updateFirewall(firewall *compute.Firewall) error {
+	op, err := computeWrapper.compute.Firewalls.Update(computeWrapper.projectID, firewall.Name, firewall).Do()
+	if err != nil {
+		return err
+	}
+	return computeWrapper.waitForGlobalOperation(op)
+}
+
+func (computeWrapper *gcpComputeWrapperImpl) deleteFirewall(name string) error {
+	op, err := computeWrapper.compute.Firewalls.Delete(computeWrapper.projectID, name).Do()
+	if err != nil {
+		return err
+	}
+	return computeWrapper.waitForGlobalOperation(op)
+}
+
+func (computeWrapper *gcpComputeWrapperImpl) waitForGlobalOperation(op *compute.Operation) error {
+	op, err := computeWrapper.compute.GlobalOperations.Wait(computeWrapper.projectID, op.Name).Do()
+	if err != nil {
+		return err
+	}
+	return operationError(op)
+}
+
+// operationError returns error if gce operation is not done or has finished with errors.
+func operationError(op *compute.Operation) error {
+	if op.Status != "DONE" {
+		return fmt.Errorf("gce operation %v did not complete, status %v", op.Name, op.Status)
+	}
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		return fmt.Errorf("gce operation %v failed: %v", op.Name, op.Error.Errors[0].Message)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"google.golang.org/api/compute/v1"
+
+	"antrea.io/nephe/apis/crd/v1alpha1"
+	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
+)
+
+type gceServiceConfig struct {
+	accountName    string
+	region         string
+	apiClient      gcpComputeWrapper
+	resourcesCache *internal.CloudServiceResourcesCache
+	inventoryStats *internal.CloudServiceStats
+	// instanceFilters has following possible values
+	// - empty map indicates no selectors configured for this account. NO cloud api call for inventory will be made.
+	// - non-empty map indicates selectors are configured. Cloud api call for inventory will be made.
+	//	 - key with nil value indicates no filters. Get all instances for account.
+	//   - key with non-nil value indicates some filter. Get instances matching those filters only.
+	instanceFilters map[string][]*gceInstanceFilter
+}
+
+// gceResourcesCacheSnapshot holds the results from querying for all instances.
+type gceResourcesCacheSnapshot struct {
+	instances map[cloudcommon.InstanceID]*compute.Instance
+	vpcIDs    map[string]struct{}
+	// networks is keyed by network self link, as referenced by instance network interfaces and firewalls.
+	networks map[string]*compute.Network
+}
+
+func newGCEServiceConfig(name string, region string, service gcpServiceClientCreateInterface) (internal.CloudServiceInterface, error) {
+	// create gce sdk api client
+	apiClient, err := service.compute()
+	if err != nil {
+		return nil, fmt.Errorf("error creating gce sdk api client for account : %v, err: %v", name, err)
+	}
+
+	config := &gceServiceConfig{
+		apiClient:       apiClient,
+		accountName:     name,
+		region:          region,
+		resourcesCache:  &internal.CloudServiceResourcesCache{},
+		inventoryStats:  &internal.CloudServiceStats{},
+		instanceFilters: make(map[string][]*gceInstanceFilter),
+	}
+	return config, nil
+}
+
+// compute returns GCP Compute (gce) SDK apiClient.
+func (p *gcpServiceSdkConfigProvider) compute() (gcpComputeWrapper, error) {
+	computeService, err := compute.NewService(context.Background(), p.clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	gcpCompute := &gcpComputeWrapperImpl{
+		projectID: p.projectID,
+		compute:   computeService,
+	}
+
+	return gcpCompute, nil
+}
+
+func (gceCfg *gceServiceConfig) waitForInventoryInit(duration time.Duration) error {
+	operation := func() error {
+		done := gceCfg.inventoryStats.IsInventoryInitialized()
+		if !done {
+			return fmt.Errorf("inventory for account %v not initialized (waited %v duration)", gceCfg.accountName, duration)
+		}
+		return nil
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.MaxElapsedTime = duration
+
+	return backoff.Retry(operation, b)
+}
+
+// getInstanceResourceFilters returns filters to be applied to listed instances if filters are configured.
+// Otherwise returns (nil, false). false indicates no selectors configured for the account and hence no cloud api needs
+// to be made for instance inventory.
+func (gceCfg *gceServiceConfig) getInstanceResourceFilters() ([]*gceInstanceFilter, bool) {
+	var allFilters []*gceInstanceFilter
+
+	instanceFilters := gceCfg.instanceFilters
+	if len(instanceFilters) == 0 {
+		return nil, false
+	}
+
+	for _, filters := range gceCfg.instanceFilters {
+		// if any selector found with nil filter, skip all other selectors. As nil indicates all
+		if len(filters) == 0 {
+			return nil, true
+		}
+		allFilters = append(allFilters, filters...)
+	}
+	return allFilters, true
+}
+
+// getCachedInstances returns instances from the cache for the account.
+func (gceCfg *gceServiceConfig) getCachedInstances() []*compute.Instance {
+	snapshot := gceCfg.resourcesCache.GetSnapshot()
+	if snapshot == nil {
+		gcpPluginLogger().V(4).Info("cache snapshot nil", "service", gcpComputeServiceNameGCE, "account", gceCfg.accountName)
+		return []*compute.Instance{}
+	}
+	instances := snapshot.(*gceResourcesCacheSnapshot).instances
+	instancesToReturn := make([]*compute.Instance, 0, len(instances))
+	for _, instance := range instances {
+		instancesToReturn = append(instancesToReturn, instance)
+	}
+	gcpPluginLogger().V(1).Info("cached instances", "service", gcpComputeServiceNameGCE, "account", gceCfg.accountName,
+		"instances", len(instancesToReturn))
+	return instancesToReturn
+}
+
+// getCachedVpcIDs returns vpcIDs from the cache for the account.
+func (gceCfg *gceServiceConfig) getCachedVpcIDs() map[string]struct{} {
+	vpcIDsCopy := make(map[string]struct{})
+	snapshot := gceCfg.resourcesCache.GetSnapshot()
+	if snapshot == nil {
+		gcpPluginLogger().V(4).Info("cache snapshot nil", "service", gcpComputeServiceNameGCE, "account", gceCfg.accountName)
+		return vpcIDsCopy
+	}
+	vpcIDsSet := snapshot.(*gceResourcesCacheSnapshot).vpcIDs
+
+	for vpcID := range vpcIDsSet {
+		vpcIDsCopy[vpcID] = struct{}{}
+	}
+
+	return vpcIDsCopy
+}
+
+// getCachedNetworks returns networks keyed by self link from the cache for the account.
+func (gceCfg *gceServiceConfig) getCachedNetworks() map[string]*compute.Network {
+	networksCopy := make(map[string]*compute.Network)
+	snapshot := gceCfg.resourcesCache.GetSnapshot()
+	if snapshot == nil {
+		gcpPluginLogger().V(4).Info("cache snapshot nil", "service", gcpComputeServiceNameGCE, "account", gceCfg.accountName)
+		return networksCopy
+	}
+	for selfLink, network := range snapshot.(*gceResourcesCacheSnapshot).networks {
+		networksCopy[selfLink] = network
+	}
+	return networksCopy
+}
+
+// getNetworks gets networks for the account from gce API, keyed by network self link.
+func (gceCfg *gceServiceConfig) getNetworks() (map[string]*compute.Network, error) {
+	networks, err := gceCfg.apiClient.pagedListNetworksWrapper()
+	if err != nil {
+		return nil, err
+	}
+	selfLinkToNetwork := make(map[string]*compute.Network)
+	for _, network := range networks {
+		selfLinkToNetwork[network.SelfLink] = network
+	}
+	return selfLinkToNetwork, nil
+}
+
+// getRegionInstances gets instances in the account region from gce API.
+func (gceCfg *gceServiceConfig) getRegionInstances() ([]*compute.Instance, error) {
+	instances, err := gceCfg.apiClient.pagedListInstancesWrapper()
+	if err != nil {
+		return nil, err
+	}
+	var regionInstances []*compute.Instance
+	for _, instance := range instances {
+		if getRegionFromZone(getResourceNameFromURL(instance.Zone)) != gceCfg.region {
+			continue
+		}
+		regionInstances = append(regionInstances, instance)
+	}
+	return regionInstances, nil
+}
+
+// getInstances gets instances for the account from gce API and applies the configured filters.
+func (gceCfg *gceServiceConfig) getInstances(networks map[string]*compute.Network) ([]*compute.Instance, error) {
+	instances, err := gceCfg.getRegionInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	filters, _ := gceCfg.getInstanceResourceFilters()
+	if filters == nil {
+		return instances, nil
+	}
+
+	var filteredInstances []*compute.Instance
+	for _, instance := range instances {
+		network := networks[getInstanceNetworkSelfLink(instance)]
+		for _, filter := range filters {
+			if filter.matches(instance, network) {
+				filteredInstances = append(filteredInstances, instance)
+				break
+			}
+		}
+	}
+
+	gcpPluginLogger().V(1).Info("instances from cloud", "service", gcpComputeServiceNameGCE, "account", gceCfg.accountName,
+		"instances", len(filteredInstances))
+
+	return filteredInstances, nil
+}
+
+// DoResourceInventory gets inventory from cloud for given cloud account.
+func (gceCfg *gceServiceConfig) DoResourceInventory() error {
+	networks, e := gceCfg.getNetworks()
+	if e != nil {
+		gcpPluginLogger().V(0).Info("error fetching gce networks", "account", gceCfg.accountName, "error", e)
+		gceCfg.inventoryStats.UpdateInventoryPollStats(e)
+		return e
+	}
+	instances, e := gceCfg.getInstances(networks)
+	if e != nil {
+		gcpPluginLogger().V(0).Info("error fetching gce instances", "account", gceCfg.accountName, "error", e)
+	} else {
+		exists := struct{}{}
+		vpcIDs := make(map[string]struct{})
+		instanceIDs := make(map[cloudcommon.InstanceID]*compute.Instance)
+		for _, instance := range instances {
+			id := cloudcommon.InstanceID(strconv.FormatUint(instance.Id, 10))
+			instanceIDs[id] = instance
+			if network, found := networks[getInstanceNetworkSelfLink(instance)]; found {
+				vpcIDs[strconv.FormatUint(network.Id, 10)] = exists
+			}
+		}
+		gceCfg.resourcesCache.UpdateSnapshot(&gceResourcesCacheSnapshot{instanceIDs, vpcIDs, networks})
+	}
+	gceCfg.inventoryStats.UpdateInventoryPollStats(e)
+
+	return e
+}
+
+// SetResourceFilters add/updates instances resource filter for the service.
+func (gceCfg *gceServiceConfig) SetResourceFilters(selector *v1alpha1.CloudEntitySelector) {
+	if filters, found := convertSelectorToGCEInstanceFilters(selector); found {
+		gceCfg.instanceFilters[selector.GetName()] = filters
+	} else {
+		if selector != nil {
+			delete(gceCfg.instanceFilters, selector.GetName())
+		}
+		gceCfg.resourcesCache.UpdateSnapshot(nil)
+	}
+}
+
+func (gceCfg *gceServiceConfig) GetResourceCRDs(namespace string) *internal.CloudServiceResourceCRDs {
+	instances := gceCfg.getCachedInstances()
+	networks := gceCfg.getCachedNetworks()
+	vmCRDs := make([]*v1alpha1.VirtualMachine, 0, len(instances))
+	for _, instance := range instances {
+		// build VirtualMachine CRD
+		vmCRD := computeInstanceToVirtualMachineCRD(instance, networks[getInstanceNetworkSelfLink(instance)], namespace)
+		if vmCRD == nil {
+			continue
+		}
+		vmCRDs = append(vmCRDs, vmCRD)
+	}
+
+	gcpPluginLogger().V(1).Info("CRDs", "service", gcpComputeServiceNameGCE, "account", gceCfg.accountName,
+		"virtual-machine CRDs", len(vmCRDs))
+
+	vpcCRDs := make([]*v1alpha1.Vpc, 0, len(networks))
+	for _, network := range networks {
+		vpcCRDs = append(vpcCRDs, networkToVpcCRD(network, networks, namespace))
+	}
+
+	serviceResourceCRDs := &internal.CloudServiceResourceCRDs{}
+	serviceResourceCRDs.SetComputeResourceCRDs(vmCRDs)
+	serviceResourceCRDs.SetVpcResourceCRDs(vpcCRDs)
+
+	return serviceResourceCRDs
+}
+
+func (gceCfg *gceServiceConfig) HasFiltersConfigured() (bool, bool) {
+	filters, found := gceCfg.getInstanceResourceFilters()
+
+	return found, filters == nil
+}
+
+func (gceCfg *gceServiceConfig) GetName() internal.CloudServiceName {
+	return gcpComputeServiceNameGCE
+}
+
+func (gceCfg *gceServiceConfig) GetType() internal.CloudServiceType {
+	return internal.CloudServiceTypeCompute
+}
+
+func (gceCfg *gceServiceConfig) GetInventoryStats() *internal.CloudServiceStats {
+	return gceCfg.inventoryStats
+}
+
+func (gceCfg *gceServiceConfig) ResetCachedState() {
+	gceCfg.SetResourceFilters(nil)
+	gceCfg.inventoryStats.ResetInventoryPollStats()
+}
+
+func (gceCfg *gceServiceConfig) UpdateServiceConfig(newConfig internal.CloudServiceInterface) {
+	newGceServiceConfig := newConfig.(*gceServiceConfig)
+	gceCfg.apiClient = newGceServiceConfig.apiClient
+	gceCfg.region = newGceServiceConfig.region
+}
+
+// getResourceNameFromURL returns the last path component of gce resource URL, e.g. zone name from zone URL.
+func getResourceNameFromURL(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+// getRegionFromZone returns region of a gce zone, e.g. us-central1 for us-central1-a.
+func getRegionFromZone(zone string) string {
+	idx := strings.LastIndex(zone, "-")
+	if idx < 0 {
+		return zone
+	}
+	return zone[:idx]
+}
+
+// getInstanceNetworkSelfLink returns the network of the instance primary network interface.
+func getInstanceNetworkSelfLink(instance *compute.Instance) string {
+	if len(instance.NetworkInterfaces) == 0 {
+		return ""
+	}
+	return instance.NetworkInterfaces[0].Network
+}
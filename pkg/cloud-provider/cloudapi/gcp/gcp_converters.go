@@ -0,0 +1,184 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+)
+
+var gceProtocolNumToName = map[int]string{
+	1:   "icmp",
+	6:   "tcp",
+	17:  "udp",
+	132: "sctp",
+}
+
+func convertToFirewallAllowed(protocol *int, port *int, endPort *int) []*compute.FirewallAllowed {
+	if protocol == nil {
+		return []*compute.FirewallAllowed{{IPProtocol: gceAnyProtocolValue}}
+	}
+	protoName, found := gceProtocolNumToName[*protocol]
+	if !found {
+		protoName = strconv.Itoa(*protocol)
+	}
+	allowed := &compute.FirewallAllowed{IPProtocol: protoName}
+	// gce accepts ports only for tcp, udp and sctp. No ports indicates all ports.
+	if port != nil && (*protocol == 6 || *protocol == 17 || *protocol == 132) {
+		allowed.Ports = []string{strconv.Itoa(*port)}
+		if endPort != nil && *endPort > *port {
+			allowed.Ports = []string{fmt.Sprintf("%v-%v", *port, *endPort)}
+		}
+	}
+	return []*compute.FirewallAllowed{allowed}
+}
+
+func convertToFirewallDenied(protocol *int, port *int, endPort *int) []*compute.FirewallDenied {
+	var denied []*compute.FirewallDenied
+	for _, allowed := range convertToFirewallAllowed(protocol, port, endPort) {
+		denied = append(denied, &compute.FirewallDenied{IPProtocol: allowed.IPProtocol, Ports: allowed.Ports})
+	}
+	return denied
+}
+
+func convertFromFirewallDenied(denied []*compute.FirewallDenied) (*int, *int, *int) {
+	var allowed []*compute.FirewallAllowed
+	for _, d := range denied {
+		allowed = append(allowed, &compute.FirewallAllowed{IPProtocol: d.IPProtocol, Ports: d.Ports})
+	}
+	return convertFromFirewallAllowed(allowed)
+}
+
+// convertFromFirewallAllowed returns protocol, start and end port of allowed. End port is nil for a single port.
+func convertFromFirewallAllowed(allowed []*compute.FirewallAllowed) (*int, *int, *int) {
+	if len(allowed) == 0 || strings.Compare(allowed[0].IPProtocol, gceAnyProtocolValue) == 0 {
+		return nil, nil, nil
+	}
+	var protoNum int
+	if num, err := strconv.Atoi(allowed[0].IPProtocol); err == nil {
+		protoNum = num
+	} else if num, found := securitygroup.ProtocolNameNumMap[strings.ToLower(allowed[0].IPProtocol)]; found {
+		protoNum = num
+	} else {
+		for num, name := range gceProtocolNumToName {
+			if name == strings.ToLower(allowed[0].IPProtocol) {
+				protoNum = num
+			}
+		}
+	}
+
+	// all ports case returns nil.
+	if len(allowed[0].Ports) != 1 {
+		return &protoNum, nil, nil
+	}
+	ports := strings.SplitN(allowed[0].Ports[0], "-", 2)
+	port, err := strconv.Atoi(ports[0])
+	if err != nil {
+		return &protoNum, nil, nil
+	}
+	if len(ports) == 1 {
+		return &protoNum, &port, nil
+	}
+	endPort, err := strconv.Atoi(ports[1])
+	if err != nil || endPort <= port {
+		return &protoNum, &port, nil
+	}
+	return &protoNum, &port, &endPort
+}
+
+// splitFirewallRangesByFamily returns IPv4 and IPv6 ranges of ranges.
+func splitFirewallRangesByFamily(ranges []string) ([]string, []string) {
+	var ipv4Ranges, ipv6Ranges []string
+	for _, r := range ranges {
+		if ip, _, err := net.ParseCIDR(r); err == nil && ip.To4() == nil {
+			ipv6Ranges = append(ipv6Ranges, r)
+		} else {
+			ipv4Ranges = append(ipv4Ranges, r)
+		}
+	}
+	return ipv4Ranges, ipv6Ranges
+}
+
+func convertToFirewallRanges(ips []*net.IPNet) []string {
+	var ranges []string
+	for _, ip := range ips {
+		ranges = append(ranges, ip.String())
+	}
+	sort.Strings(ranges)
+	return ranges
+}
+
+func convertFromFirewallRanges(ranges []string) []*net.IPNet {
+	var ipNets []*net.IPNet
+	for _, ipRange := range ranges {
+		_, ipNet, err := net.ParseCIDR(ipRange)
+		if err != nil {
+			// gce accepts plain ip addresses as ranges.
+			ip := net.ParseIP(ipRange)
+			if ip == nil {
+				continue
+			}
+			ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets
+}
+
+// convertFromFirewallSourceTags converts nephe address group network tags to cloud resource IDs in vpc.
+func convertFromFirewallSourceTags(tags []string, vpcID string) []*securitygroup.CloudResourceID {
+	var cloudResourceIDs []*securitygroup.CloudResourceID
+	for _, tag := range tags {
+		sgName, isAG, _ := securitygroup.IsNepheControllerCreatedSG(tag)
+		if !isAG {
+			continue
+		}
+		cloudResourceIDs = append(cloudResourceIDs, &securitygroup.CloudResourceID{Name: sgName, Vpc: vpcID})
+	}
+	return cloudResourceIDs
+}
+
+// encodeAddressGroupsDescription builds firewall description holding address groups resolved to ip ranges.
+func encodeAddressGroupsDescription(groups []*securitygroup.CloudResourceID) string {
+	var groupIDs []string
+	for _, group := range groups {
+		groupIDs = append(groupIDs, strings.ToLower(group.Name)+"/"+group.Vpc)
+	}
+	sort.Strings(groupIDs)
+	return gceAddressGroupsDescriptionPrefix + strings.Join(groupIDs, ",")
+}
+
+// decodeAddressGroupsDescription returns address groups from firewall description built by encodeAddressGroupsDescription.
+func decodeAddressGroupsDescription(description string) []*securitygroup.CloudResourceID {
+	if !strings.HasPrefix(description, gceAddressGroupsDescriptionPrefix) {
+		return nil
+	}
+	var groups []*securitygroup.CloudResourceID
+	for _, groupID := range strings.Split(strings.TrimPrefix(description, gceAddressGroupsDescriptionPrefix), ",") {
+		idx := strings.LastIndex(groupID, "/")
+		if idx < 0 {
+			continue
+		}
+		groups = append(groups, &securitygroup.CloudResourceID{Name: groupID[:idx], Vpc: groupID[idx+1:]})
+	}
+	return groups
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+
+	"antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/cloud-provider/utils"
+)
+
+var gceStatusMap = map[string]string{
+	"PROVISIONING": "starting",
+	"STAGING":      "starting",
+	"RUNNING":      "running",
+	"STOPPING":     "stopping",
+	"STOPPED":      "stopped",
+	"SUSPENDING":   "stopping",
+	"SUSPENDED":    "suspended",
+	"REPAIRING":    "repairing",
+	"TERMINATED":   "stopped",
+}
+
+// computeInstanceToVirtualMachineCRD converts gce instance to VirtualMachine CRD.
+func computeInstanceToVirtualMachineCRD(instance *compute.Instance, network *compute.Network, namespace string) *v1alpha1.VirtualMachine {
+	if network == nil {
+		gcpPluginLogger().Error(fmt.Errorf("network not found"), "failed to create VirtualMachine CRD",
+			"instance", instance.Name)
+		return nil
+	}
+
+	tags := make(map[string]string)
+	for key, value := range instance.Labels {
+		tags[key] = value
+	}
+
+	cloudID := strconv.FormatUint(instance.Id, 10)
+	cloudName := instance.Name
+	crdName := utils.GenerateShortResourceIdentifier(cloudID, cloudName)
+
+	// Network interfaces associated with Virtual machine
+	networkInterfaces := make([]v1alpha1.NetworkInterface, 0, len(instance.NetworkInterfaces))
+	for _, nwInf := range instance.NetworkInterfaces {
+		var ipAddressCRDs []v1alpha1.IPAddress
+		if len(nwInf.NetworkIP) > 0 {
+			ipAddressCRDs = append(ipAddressCRDs, v1alpha1.IPAddress{
+				AddressType: v1alpha1.AddressTypeInternalIP,
+				Address:     nwInf.NetworkIP,
+			})
+		}
+		for _, accessConfig := range nwInf.AccessConfigs {
+			if len(accessConfig.NatIP) == 0 {
+				continue
+			}
+			ipAddressCRDs = append(ipAddressCRDs, v1alpha1.IPAddress{
+				AddressType: v1alpha1.AddressTypeExternalIP,
+				Address:     accessConfig.NatIP,
+			})
+		}
+		if len(nwInf.Ipv6Address) > 0 {
+			ipAddressCRDs = append(ipAddressCRDs, v1alpha1.IPAddress{
+				AddressType: v1alpha1.AddressTypeInternalIP,
+				Address:     nwInf.Ipv6Address,
+			})
+		}
+		for _, accessConfig := range nwInf.Ipv6AccessConfigs {
+			if len(accessConfig.ExternalIpv6) == 0 {
+				continue
+			}
+			ipAddressCRDs = append(ipAddressCRDs, v1alpha1.IPAddress{
+				AddressType: v1alpha1.AddressTypeExternalIP,
+				Address:     accessConfig.ExternalIpv6,
+			})
+		}
+		// gce does not expose nic MAC address and nic names (nic0, nic1 ..) are unique only within instance.
+		networkInterface := v1alpha1.NetworkInterface{
+			Name:   cloudID + "-" + nwInf.Name,
+			IPs:    ipAddressCRDs,
+			Subnet: getResourceNameFromURL(nwInf.Subnetwork),
+		}
+		networkInterfaces = append(networkInterfaces, networkInterface)
+	}
+
+	cloudNetwork := strconv.FormatUint(network.Id, 10)
+	status := gceStatusMap[instance.Status]
+
+	return utils.GenerateVirtualMachineCRD(crdName, cloudName, cloudID, namespace, cloudNetwork, network.Name,
+		status, tags, networkInterfaces, computeInstanceProperties(instance), providerType)
+}
+
+// computeInstanceProperties returns cloud properties of gce instance. Network tags are reported as security groups, as
+// firewall rules select instances by network tags. gce instance does not carry its boot image, image ID is left empty.
+func computeInstanceProperties(instance *compute.Instance) *utils.VirtualMachineProperties {
+	zone := getResourceNameFromURL(instance.Zone)
+	properties := &utils.VirtualMachineProperties{
+		Region:           getRegionFromZone(zone),
+		AvailabilityZone: zone,
+		InstanceType:     getResourceNameFromURL(instance.MachineType),
+		OSType:           "linux",
+	}
+	for _, disk := range instance.Disks {
+		if !disk.Boot {
+			continue
+		}
+		for _, feature := range disk.GuestOsFeatures {
+			if feature.Type == "WINDOWS" {
+				properties.OSType = "windows"
+			}
+		}
+	}
+
+	launchTimestamp := instance.LastStartTimestamp
+	if len(launchTimestamp) == 0 {
+		launchTimestamp = instance.CreationTimestamp
+	}
+	if launchTime, err := time.Parse(time.RFC3339, launchTimestamp); err == nil {
+		properties.LaunchTime = &launchTime
+	}
+
+	if instance.Tags != nil {
+		properties.SecurityGroups = append(properties.SecurityGroups, instance.Tags.Items...)
+		sort.Strings(properties.SecurityGroups)
+	}
+	return properties
+}
+
+// networkToVpcCRD converts gce network to Vpc CRD. gce networks are global, and have no region.
+func networkToVpcCRD(network *compute.Network, networks map[string]*compute.Network, namespace string) *v1alpha1.Vpc {
+	var cidrs []string
+	if len(network.IPv4Range) != 0 {
+		cidrs = append(cidrs, network.IPv4Range)
+	}
+
+	var peerings []string
+	for _, peering := range network.Peerings {
+		if peer, found := networks[peering.Network]; found {
+			peerings = append(peerings, strconv.FormatUint(peer.Id, 10))
+		} else {
+			peerings = append(peerings, getResourceNameFromURL(peering.Network))
+		}
+	}
+	sort.Strings(peerings)
+
+	cloudID := strconv.FormatUint(network.Id, 10)
+	// crd name matches the virtual private cloud of VirtualMachine CRDs in the network.
+	return utils.GenerateVpcCRD(network.Name, network.Name, cloudID, namespace, "", cidrs, nil, peerings, providerType)
+}
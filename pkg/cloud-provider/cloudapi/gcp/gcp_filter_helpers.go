@@ -0,0 +1,109 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+
+	"antrea.io/nephe/apis/crd/v1alpha1"
+)
+
+// gceInstanceFilter holds match criteria for a gce instance. Empty fields match any value.
+// GCE list APIs filter expressions cannot be ORed across fields, hence filters are applied on the listed instances.
+type gceInstanceFilter struct {
+	vpcID   string
+	vpcName string
+	vmID    string
+	vmName  string
+	// vmTags is matched against gce instance labels.
+	vmTags map[string]string
+}
+
+// convertSelectorToGCEInstanceFilters converts vm selector to gce instance filters.
+func convertSelectorToGCEInstanceFilters(selector *v1alpha1.CloudEntitySelector) ([]*gceInstanceFilter, bool) {
+	if selector == nil {
+		return nil, false
+	}
+	if selector.Spec.VMSelector == nil {
+		return nil, true
+	}
+
+	return buildGCEInstanceFilters(selector.Spec.VMSelector), true
+}
+
+// buildGCEInstanceFilters builds gce instance filters for VirtualMachineSelector. Returns nil if any selector
+// section matches all instances.
+func buildGCEInstanceFilters(vmSelector []v1alpha1.VirtualMachineSelector) []*gceInstanceFilter {
+	var filters []*gceInstanceFilter
+	for _, match := range vmSelector {
+		var vpcID, vpcName string
+		if match.VpcMatch != nil {
+			vpcID = strings.TrimSpace(match.VpcMatch.MatchID)
+			vpcName = strings.TrimSpace(match.VpcMatch.MatchName)
+		}
+
+		// select all entry found. No need to process any other matches.
+		if len(vpcID) == 0 && len(vpcName) == 0 && len(match.VMMatch) == 0 {
+			return nil
+		}
+
+		if len(match.VMMatch) == 0 {
+			filters = append(filters, &gceInstanceFilter{vpcID: vpcID, vpcName: vpcName})
+			continue
+		}
+		for _, vmMatch := range match.VMMatch {
+			filters = append(filters, &gceInstanceFilter{
+				vpcID:   vpcID,
+				vpcName: vpcName,
+				vmID:    strings.TrimSpace(vmMatch.MatchID),
+				vmName:  strings.TrimSpace(vmMatch.MatchName),
+				vmTags:  vmMatch.MatchTags,
+			})
+		}
+	}
+	return filters
+}
+
+// matches returns true if instance attached to network satisfies the filter.
+func (f *gceInstanceFilter) matches(instance *compute.Instance, network *compute.Network) bool {
+	if len(f.vmID) > 0 && f.vmID != strconv.FormatUint(instance.Id, 10) {
+		return false
+	}
+	if len(f.vmName) > 0 && f.vmName != instance.Name {
+		return false
+	}
+	for key, value := range f.vmTags {
+		label, found := instance.Labels[key]
+		if !found || (len(value) > 0 && value != label) {
+			return false
+		}
+	}
+	if len(f.vpcID) == 0 && len(f.vpcName) == 0 {
+		return true
+	}
+	if network == nil {
+		return false
+	}
+	if len(f.vpcID) > 0 && f.vpcID != strconv.FormatUint(network.Id, 10) {
+		return false
+	}
+	if len(f.vpcName) > 0 && f.vpcName != network.Name {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,887 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+)
+
+// GCE has no security group object. A nephe security group is realized as a network tag set on member instances;
+// appliedTo security group rules are realized as firewalls targeting that tag within the group network. Each
+// appliedTo security group has a pair of lowest priority deny firewalls, so that, as for security groups of other
+// clouds, only traffic allowed by the group rules is permitted to and from its members.
+//
+// Firewall names are <tag>-<network hash>-<suffix>, where suffix is deny-in/deny-eg for the deny firewalls,
+// in-<rule index>/eg-<rule index> for the allow rules and din-<rule index>/deg-<rule index> for the deny rules. Deny
+// rules are realized at a higher priority than allow rules, so that they take precedence. GCE egress firewalls cannot
+// match destination tags and ingress firewalls cannot match source tags across networks, hence such address groups
+// are resolved to member ip addresses in a separate firewall suffixed -ag, whose description holds the resolved
+// address groups. A firewall cannot mix IPv4 and IPv6 ranges, hence IPv6 ranges of a rule are realized in a separate
+// firewall suffixed -v6.
+const (
+	gceAnyProtocolValue  = "all"
+	gceAllowPriority     = 1000
+	gceDenyRulePriority  = 900
+	gceDenyPriority      = 65534
+	gceAnyAddress        = "0.0.0.0/0"
+	gceAnyIPv6Address    = "::/0"
+	gceIngressDirection  = "INGRESS"
+	gceEgressDirection   = "EGRESS"
+	gceIngressSuffix     = "in"
+	gceEgressSuffix      = "eg"
+	gceDenyRuleInSuffix  = "din"
+	gceDenyRuleEgSuffix  = "deg"
+	gceDenyIngressSuffix = "deny-in"
+	gceDenyEgressSuffix  = "deny-eg"
+	gceAddrGroupSuffix   = "-ag"
+	gceIPv6Suffix        = "-v6"
+
+	gceFirewallDescription            = "Managed by nephe controller"
+	gceAddressGroupsDescriptionPrefix = gceFirewallDescription + ", address groups: "
+)
+
+var (
+	mutex sync.Mutex
+)
+
+// getFirewallName returns name of firewall realizing suffix part of security group cloudSgName in network vpcID.
+func getFirewallName(cloudSgName string, vpcID string, suffix string) string {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(vpcID))
+	return fmt.Sprintf("%v-%08x-%v", cloudSgName, hash.Sum32(), suffix)
+}
+
+// getNetworkSelfLink returns self link of network vpcID.
+func (gceCfg *gceServiceConfig) getNetworkSelfLink(vpcID string) (string, error) {
+	for selfLink, network := range gceCfg.getCachedNetworks() {
+		if strconv.FormatUint(network.Id, 10) == vpcID {
+			return selfLink, nil
+		}
+	}
+	return "", fmt.Errorf("network %v not found for account %v", vpcID, gceCfg.accountName)
+}
+
+// getNetworkIDToSelfLink returns network ID to network self link map of all networks of the account.
+func (gceCfg *gceServiceConfig) getNetworkIDToSelfLink() map[string]string {
+	vpcIDToSelfLink := make(map[string]string)
+	for selfLink, network := range gceCfg.getCachedNetworks() {
+		vpcIDToSelfLink[strconv.FormatUint(network.Id, 10)] = selfLink
+	}
+	return vpcIDToSelfLink
+}
+
+// getSecurityGroupFirewalls returns firewalls, keyed by name, realizing security group cloudSgName in network vpcID.
+func (gceCfg *gceServiceConfig) getSecurityGroupFirewalls(cloudSgName string, vpcID string) (map[string]*compute.Firewall, error) {
+	selfLink, err := gceCfg.getNetworkSelfLink(vpcID)
+	if err != nil {
+		return nil, err
+	}
+	firewalls, err := gceCfg.apiClient.pagedListFirewallsWrapper()
+	if err != nil {
+		return nil, err
+	}
+	namePrefix := getFirewallName(cloudSgName, vpcID, "")
+	sgFirewalls := make(map[string]*compute.Firewall)
+	for _, firewall := range firewalls {
+		if firewall.Network != selfLink || !strings.HasPrefix(firewall.Name, namePrefix) {
+			continue
+		}
+		sgFirewalls[firewall.Name] = firewall
+	}
+	return sgFirewalls, nil
+}
+
+// createDenyFirewalls creates the deny all ingress and egress firewalls of appliedTo security group cloudSgName.
+func (gceCfg *gceServiceConfig) createDenyFirewalls(cloudSgName string, vpcID string) error {
+	selfLink, err := gceCfg.getNetworkSelfLink(vpcID)
+	if err != nil {
+		return err
+	}
+	existingFirewalls, err := gceCfg.getSecurityGroupFirewalls(cloudSgName, vpcID)
+	if err != nil {
+		return err
+	}
+
+	denyFirewalls := []*compute.Firewall{
+		{
+			Name:         getFirewallName(cloudSgName, vpcID, gceDenyIngressSuffix),
+			Description:  gceFirewallDescription,
+			Network:      selfLink,
+			Direction:    gceIngressDirection,
+			Priority:     gceDenyPriority,
+			Denied:       []*compute.FirewallDenied{{IPProtocol: gceAnyProtocolValue}},
+			SourceRanges: []string{gceAnyAddress},
+			TargetTags:   []string{cloudSgName},
+		},
+		{
+			Name:              getFirewallName(cloudSgName, vpcID, gceDenyEgressSuffix),
+			Description:       gceFirewallDescription,
+			Network:           selfLink,
+			Direction:         gceEgressDirection,
+			Priority:          gceDenyPriority,
+			Denied:            []*compute.FirewallDenied{{IPProtocol: gceAnyProtocolValue}},
+			DestinationRanges: []string{gceAnyAddress},
+			TargetTags:        []string{cloudSgName},
+		},
+	}
+	for _, firewall := range denyFirewalls {
+		if _, found := existingFirewalls[firewall.Name]; found {
+			continue
+		}
+		if err := gceCfg.apiClient.insertFirewall(firewall); err != nil {
+			return err
+		}
+		gcpPluginLogger().Info("Firewall created", "name", firewall.Name, "vpcID", vpcID)
+	}
+	return nil
+}
+
+// resolveAddressGroupIPs returns internal ip addresses of address groups members.
+func resolveAddressGroupIPs(groups []*securitygroup.CloudResourceID, instances []*compute.Instance,
+	vpcIDToSelfLink map[string]string) []string {
+	ipSet := make(map[string]struct{})
+	for _, group := range groups {
+		tag := group.GetCloudName(true)
+		selfLink := vpcIDToSelfLink[group.Vpc]
+		for _, instance := range instances {
+			if getInstanceNetworkSelfLink(instance) != selfLink || instance.Tags == nil {
+				continue
+			}
+			for _, item := range instance.Tags.Items {
+				if item == tag && len(instance.NetworkInterfaces[0].NetworkIP) > 0 {
+					ipSet[instance.NetworkInterfaces[0].NetworkIP+"/32"] = struct{}{}
+				}
+			}
+		}
+	}
+	var ips []string
+	for ip := range ipSet {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// checkCloudServicePeers returns an error if rules reference cloud services, which gce firewalls cannot realize.
+func checkCloudServicePeers(ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule) error {
+	var cloudServices []string
+	for _, rule := range ingressRules {
+		if rule != nil {
+			cloudServices = append(cloudServices, rule.FromCloudServices...)
+		}
+	}
+	for _, rule := range egressRules {
+		if rule != nil {
+			cloudServices = append(cloudServices, rule.ToCloudServices...)
+		}
+	}
+	if len(cloudServices) > 0 {
+		return fmt.Errorf("cloud services %v are not supported by gcp firewalls", cloudServices)
+	}
+	return nil
+}
+
+// buildFirewalls builds firewalls, keyed by name, realizing rules of appliedTo security group cloudSgName.
+func buildFirewalls(cloudSgName string, vpcID string, selfLink string, ingressRules []*securitygroup.IngressRule,
+	egressRules []*securitygroup.EgressRule, instances []*compute.Instance, vpcIDToSelfLink map[string]string) map[string]*compute.Firewall {
+	firewalls := make(map[string]*compute.Firewall)
+	newFirewall := func(suffix string, direction string, action securitygroup.RuleAction, protocol *int,
+		port *int, endPort *int) *compute.Firewall {
+		firewall := &compute.Firewall{
+			Name:            getFirewallName(cloudSgName, vpcID, suffix),
+			Description:     gceFirewallDescription,
+			Network:         selfLink,
+			Direction:       direction,
+			Priority:        gceAllowPriority,
+			TargetTags:      []string{cloudSgName},
+			ForceSendFields: []string{"Disabled"},
+		}
+		if action.IsDeny() {
+			firewall.Priority = gceDenyRulePriority
+			firewall.Denied = convertToFirewallDenied(protocol, port, endPort)
+		} else {
+			firewall.Allowed = convertToFirewallAllowed(protocol, port, endPort)
+		}
+		return firewall
+	}
+
+	for idx, rule := range ingressRules {
+		if rule == nil {
+			continue
+		}
+		suffix := fmt.Sprintf("%v-%v", gceIngressSuffix, idx)
+		if rule.Action.IsDeny() {
+			suffix = fmt.Sprintf("%v-%v", gceDenyRuleInSuffix, idx)
+		}
+		var sourceTags []string
+		var otherNetworkGroups []*securitygroup.CloudResourceID
+		for _, group := range rule.FromSecurityGroups {
+			if group.Vpc == vpcID {
+				sourceTags = append(sourceTags, group.GetCloudName(true))
+			} else {
+				otherNetworkGroups = append(otherNetworkGroups, group)
+			}
+		}
+		sort.Strings(sourceTags)
+		sourceRanges, ipv6SourceRanges := splitFirewallRangesByFamily(convertToFirewallRanges(rule.FromSrcIP))
+		if len(rule.FromSrcIP) == 0 && len(rule.FromSecurityGroups) == 0 {
+			sourceRanges, ipv6SourceRanges = []string{gceAnyAddress}, []string{gceAnyIPv6Address}
+		}
+		if len(sourceRanges) > 0 || len(sourceTags) > 0 {
+			firewall := newFirewall(suffix, gceIngressDirection, rule.Action,
+				rule.Protocol, rule.FromPort, rule.FromEndPort)
+			firewall.SourceRanges = sourceRanges
+			firewall.SourceTags = sourceTags
+			firewalls[firewall.Name] = firewall
+		}
+		if len(ipv6SourceRanges) > 0 {
+			firewall := newFirewall(suffix+gceIPv6Suffix, gceIngressDirection, rule.Action,
+				securitygroup.GetIPv6Protocol(rule.Protocol), rule.FromPort, rule.FromEndPort)
+			firewall.SourceRanges = ipv6SourceRanges
+			firewalls[firewall.Name] = firewall
+		}
+		if len(otherNetworkGroups) > 0 {
+			firewall := newFirewall(suffix+gceAddrGroupSuffix, gceIngressDirection, rule.Action,
+				rule.Protocol, rule.FromPort, rule.FromEndPort)
+			firewall.Description = encodeAddressGroupsDescription(otherNetworkGroups)
+			firewall.SourceRanges = resolveAddressGroupIPs(otherNetworkGroups, instances, vpcIDToSelfLink)
+			firewall.Disabled = len(firewall.SourceRanges) == 0
+			firewalls[firewall.Name] = firewall
+		}
+	}
+
+	for idx, rule := range egressRules {
+		if rule == nil {
+			continue
+		}
+		suffix := fmt.Sprintf("%v-%v", gceEgressSuffix, idx)
+		if rule.Action.IsDeny() {
+			suffix = fmt.Sprintf("%v-%v", gceDenyRuleEgSuffix, idx)
+		}
+		destinationRanges, ipv6DestinationRanges := splitFirewallRangesByFamily(convertToFirewallRanges(rule.ToDstIP))
+		if len(rule.ToDstIP) == 0 && len(rule.ToSecurityGroups) == 0 {
+			destinationRanges, ipv6DestinationRanges = []string{gceAnyAddress}, []string{gceAnyIPv6Address}
+		}
+		if len(destinationRanges) > 0 {
+			firewall := newFirewall(suffix, gceEgressDirection, rule.Action,
+				rule.Protocol, rule.ToPort, rule.ToEndPort)
+			firewall.DestinationRanges = destinationRanges
+			firewalls[firewall.Name] = firewall
+		}
+		if len(ipv6DestinationRanges) > 0 {
+			firewall := newFirewall(suffix+gceIPv6Suffix, gceEgressDirection, rule.Action,
+				securitygroup.GetIPv6Protocol(rule.Protocol), rule.ToPort, rule.ToEndPort)
+			firewall.DestinationRanges = ipv6DestinationRanges
+			firewalls[firewall.Name] = firewall
+		}
+		if len(rule.ToSecurityGroups) > 0 {
+			firewall := newFirewall(suffix+gceAddrGroupSuffix, gceEgressDirection, rule.Action,
+				rule.Protocol, rule.ToPort, rule.ToEndPort)
+			firewall.Description = encodeAddressGroupsDescription(rule.ToSecurityGroups)
+			firewall.DestinationRanges = resolveAddressGroupIPs(rule.ToSecurityGroups, instances, vpcIDToSelfLink)
+			firewall.Disabled = len(firewall.DestinationRanges) == 0
+			firewalls[firewall.Name] = firewall
+		}
+	}
+	return firewalls
+}
+
+// isFirewallUpdated returns true if desired firewall differs from existing firewall.
+func isFirewallUpdated(desired *compute.Firewall, existing *compute.Firewall) bool {
+	stringsEqual := func(s1 []string, s2 []string) bool {
+		c1 := append([]string{}, s1...)
+		c2 := append([]string{}, s2...)
+		sort.Strings(c1)
+		sort.Strings(c2)
+		return strings.Join(c1, ",") == strings.Join(c2, ",")
+	}
+	allowedString := func(allowed []*compute.FirewallAllowed) string {
+		var items []string
+		for _, a := range allowed {
+			items = append(items, a.IPProtocol+":"+strings.Join(a.Ports, ","))
+		}
+		return strings.Join(items, ";")
+	}
+	deniedString := func(denied []*compute.FirewallDenied) string {
+		var items []string
+		for _, d := range denied {
+			items = append(items, d.IPProtocol+":"+strings.Join(d.Ports, ","))
+		}
+		return strings.Join(items, ";")
+	}
+
+	return desired.Description != existing.Description ||
+		desired.Disabled != existing.Disabled ||
+		desired.Priority != existing.Priority ||
+		desired.Direction != existing.Direction ||
+		allowedString(desired.Allowed) != allowedString(existing.Allowed) ||
+		deniedString(desired.Denied) != deniedString(existing.Denied) ||
+		!stringsEqual(desired.SourceRanges, existing.SourceRanges) ||
+		!stringsEqual(desired.SourceTags, existing.SourceTags) ||
+		!stringsEqual(desired.DestinationRanges, existing.DestinationRanges) ||
+		!stringsEqual(desired.TargetTags, existing.TargetTags)
+}
+
+// realizeFirewalls inserts, updates and deletes existing firewalls to match desired firewalls.
+func (gceCfg *gceServiceConfig) realizeFirewalls(desired map[string]*compute.Firewall, existing map[string]*compute.Firewall) error {
+	for name, firewall := range desired {
+		existingFirewall, found := existing[name]
+		if !found {
+			if err := gceCfg.apiClient.insertFirewall(firewall); err != nil {
+				return err
+			}
+			continue
+		}
+		if isFirewallUpdated(firewall, existingFirewall) {
+			if err := gceCfg.apiClient.updateFirewall(firewall); err != nil {
+				return err
+			}
+		}
+	}
+	for name := range existing {
+		if _, found := desired[name]; found {
+			continue
+		}
+		if err := gceCfg.apiClient.deleteFirewall(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshAddressGroupFirewalls updates ip ranges of firewalls holding resolved members of address group.
+func (gceCfg *gceServiceConfig) refreshAddressGroupFirewalls(group *securitygroup.CloudResourceID) error {
+	firewalls, err := gceCfg.apiClient.pagedListFirewallsWrapper()
+	if err != nil {
+		return err
+	}
+	var instances []*compute.Instance
+	vpcIDToSelfLink := gceCfg.getNetworkIDToSelfLink()
+	for _, firewall := range firewalls {
+		if !strings.HasPrefix(firewall.Name, securitygroup.NepheControllerAppliedToPrefix) ||
+			!strings.HasSuffix(firewall.Name, gceAddrGroupSuffix) {
+			continue
+		}
+		groups := decodeAddressGroupsDescription(firewall.Description)
+		isReferenced := false
+		for _, g := range groups {
+			if g.Name == strings.ToLower(group.Name) && g.Vpc == group.Vpc {
+				isReferenced = true
+				break
+			}
+		}
+		if !isReferenced {
+			continue
+		}
+
+		if instances == nil {
+			if instances, err = gceCfg.getRegionInstances(); err != nil {
+				return err
+			}
+		}
+		ips := resolveAddressGroupIPs(groups, instances, vpcIDToSelfLink)
+		updatedFirewall := *firewall
+		updatedFirewall.Disabled = len(ips) == 0
+		updatedFirewall.ForceSendFields = []string{"Disabled"}
+		if firewall.Direction == gceEgressDirection {
+			updatedFirewall.DestinationRanges = ips
+		} else {
+			updatedFirewall.SourceRanges = ips
+		}
+		if !isFirewallUpdated(&updatedFirewall, firewall) {
+			continue
+		}
+		if err := gceCfg.apiClient.updateFirewall(&updatedFirewall); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateInstanceTags attaches or detaches network tag cloudSgName to instance.
+func (gceCfg *gceServiceConfig) updateInstanceTags(instance *compute.Instance, cloudSgName string, attach bool) error {
+	tags := &compute.Tags{}
+	if instance.Tags != nil {
+		tags.Fingerprint = instance.Tags.Fingerprint
+		for _, item := range instance.Tags.Items {
+			if item != cloudSgName {
+				tags.Items = append(tags.Items, item)
+			}
+		}
+	}
+	if attach {
+		tags.Items = append(tags.Items, cloudSgName)
+	}
+	return gceCfg.apiClient.setInstanceTags(getResourceNameFromURL(instance.Zone), instance.Name, tags)
+}
+
+// updateSecurityGroupMembers sets network tag cloudSgName on member instances of network vpcID and removes it
+// from all other instances.
+func (gceCfg *gceServiceConfig) updateSecurityGroupMembers(cloudSgName string, vpcID string,
+	cloudResourceIdentifiers []*securitygroup.CloudResource) error {
+	selfLink, err := gceCfg.getNetworkSelfLink(vpcID)
+	if err != nil {
+		return err
+	}
+	instances, err := gceCfg.getRegionInstances()
+	if err != nil {
+		return err
+	}
+	memberVirtualMachines, memberNetworkInterfaces := securitygroup.FindResourcesBasedOnKind(cloudResourceIdentifiers)
+
+	instancesToModify := make(map[*compute.Instance]bool)
+	for _, instance := range instances {
+		if getInstanceNetworkSelfLink(instance) != selfLink {
+			continue
+		}
+		instanceID := strconv.FormatUint(instance.Id, 10)
+		_, isMember := memberVirtualMachines[instanceID]
+		for _, nwInf := range instance.NetworkInterfaces {
+			if _, found := memberNetworkInterfaces[instanceID+"-"+nwInf.Name]; found {
+				isMember = true
+			}
+		}
+		isTagged := false
+		if instance.Tags != nil {
+			for _, item := range instance.Tags.Items {
+				if item == cloudSgName {
+					isTagged = true
+				}
+			}
+		}
+		if isMember != isTagged {
+			instancesToModify[instance] = isMember
+		}
+	}
+
+	return gceCfg.processInstanceTagsModifyConcurrently(instancesToModify, cloudSgName)
+}
+
+func (gceCfg *gceServiceConfig) processInstanceTagsModifyConcurrently(instancesToModify map[*compute.Instance]bool,
+	cloudSgName string) error {
+	ch := make(chan error)
+	var err error
+	var wg sync.WaitGroup
+
+	wg.Add(len(instancesToModify))
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	for instance, attach := range instancesToModify {
+		go func(instance *compute.Instance, attach bool, ch chan error) {
+			defer wg.Done()
+			ch <- gceCfg.updateInstanceTags(instance, cloudSgName, attach)
+		}(instance, attach, ch)
+	}
+	for e := range ch {
+		if e != nil {
+			err = multierr.Append(err, e)
+		}
+	}
+
+	return err
+}
+
+// getNepheControllerManagedSecurityGroupsCloudView returns security groups created by nephe in vpcs of the account
+// inventory, or an error if they cannot be retrieved.
+func (gceCfg *gceServiceConfig) getNepheControllerManagedSecurityGroupsCloudView() ([]securitygroup.SynchronizationContent,
+	error) {
+	vpcIDs := gceCfg.getCachedVpcIDs()
+	if len(vpcIDs) == 0 {
+		return []securitygroup.SynchronizationContent{}, nil
+	}
+	selfLinkToVpcID := make(map[string]string)
+	for vpcID, selfLink := range gceCfg.getNetworkIDToSelfLink() {
+		if _, found := vpcIDs[vpcID]; found {
+			selfLinkToVpcID[selfLink] = vpcID
+		}
+	}
+
+	instances, err := gceCfg.getRegionInstances()
+	if err != nil {
+		return nil, err
+	}
+	firewalls, err := gceCfg.apiClient.pagedListFirewallsWrapper()
+	if err != nil {
+		return nil, err
+	}
+
+	type groupKey struct {
+		id             securitygroup.CloudResourceID
+		membershipOnly bool
+	}
+	groups := make(map[groupKey]*securitygroup.SynchronizationContent)
+	getGroup := func(sgName string, vpcID string, membershipOnly bool) *securitygroup.SynchronizationContent {
+		key := groupKey{id: securitygroup.CloudResourceID{Name: sgName, Vpc: vpcID}, membershipOnly: membershipOnly}
+		group, found := groups[key]
+		if !found {
+			group = &securitygroup.SynchronizationContent{Resource: key.id, MembershipOnly: membershipOnly}
+			groups[key] = group
+		}
+		return group
+	}
+
+	// find members of managed security groups from instance network tags
+	for _, instance := range instances {
+		vpcID, found := selfLinkToVpcID[getInstanceNetworkSelfLink(instance)]
+		if !found || instance.Tags == nil {
+			continue
+		}
+		for _, item := range instance.Tags.Items {
+			sgName, isAG, isAT := securitygroup.IsNepheControllerCreatedSG(item)
+			if !isAG && !isAT {
+				continue
+			}
+			group := getGroup(sgName, vpcID, isAG)
+			group.Members = append(group.Members, securitygroup.CloudResource{
+				Type: securitygroup.CloudResourceTypeVM,
+				Name: securitygroup.CloudResourceID{
+					Name: strconv.FormatUint(instance.Id, 10),
+					Vpc:  vpcID,
+				},
+			})
+		}
+	}
+
+	// build rules of managed appliedTo security groups from firewalls
+	ingressRules := make(map[groupKey]map[int]*securitygroup.IngressRule)
+	egressRules := make(map[groupKey]map[int]*securitygroup.EgressRule)
+	for _, firewall := range firewalls {
+		vpcID, found := selfLinkToVpcID[firewall.Network]
+		if !found || len(firewall.TargetTags) != 1 {
+			continue
+		}
+		sgName, _, isAT := securitygroup.IsNepheControllerCreatedSG(firewall.TargetTags[0])
+		if !isAT {
+			continue
+		}
+		suffix := strings.TrimPrefix(firewall.Name, getFirewallName(firewall.TargetTags[0], vpcID, ""))
+		if len(suffix) == len(firewall.Name) {
+			continue
+		}
+		key := groupKey{id: getGroup(sgName, vpcID, false).Resource}
+
+		isAddrGroupFirewall := strings.HasSuffix(suffix, gceAddrGroupSuffix)
+		suffixParts := strings.SplitN(strings.TrimSuffix(strings.TrimSuffix(suffix, gceAddrGroupSuffix), gceIPv6Suffix), "-", 2)
+		if len(suffixParts) != 2 {
+			continue
+		}
+		idx, err := strconv.Atoi(suffixParts[1])
+		if err != nil {
+			continue
+		}
+		protocol, port, endPort := convertFromFirewallAllowed(firewall.Allowed)
+		action := securitygroup.RuleActionAllow
+		if suffixParts[0] == gceDenyRuleInSuffix || suffixParts[0] == gceDenyRuleEgSuffix {
+			protocol, port, endPort = convertFromFirewallDenied(firewall.Denied)
+			action = securitygroup.RuleActionDeny
+		}
+		// IPv6 firewalls of ICMP rules use ICMPv6 protocol.
+		if securitygroup.IsICMPProtocol(protocol) {
+			icmp := securitygroup.ProtocolNameNumMap["icmp"]
+			protocol = &icmp
+		}
+
+		switch suffixParts[0] {
+		case gceIngressSuffix, gceDenyRuleInSuffix:
+			if ingressRules[key] == nil {
+				ingressRules[key] = make(map[int]*securitygroup.IngressRule)
+			}
+			rule, found := ingressRules[key][idx]
+			if !found {
+				rule = &securitygroup.IngressRule{Protocol: protocol, FromPort: port, FromEndPort: endPort,
+					Action: action}
+				ingressRules[key][idx] = rule
+			}
+			if isAddrGroupFirewall {
+				rule.FromSecurityGroups = append(rule.FromSecurityGroups, decodeAddressGroupsDescription(firewall.Description)...)
+			} else {
+				rule.FromSrcIP = append(rule.FromSrcIP, convertFromFirewallRanges(firewall.SourceRanges)...)
+				rule.FromSecurityGroups = append(rule.FromSecurityGroups, convertFromFirewallSourceTags(firewall.SourceTags, vpcID)...)
+			}
+		case gceEgressSuffix, gceDenyRuleEgSuffix:
+			if egressRules[key] == nil {
+				egressRules[key] = make(map[int]*securitygroup.EgressRule)
+			}
+			rule, found := egressRules[key][idx]
+			if !found {
+				rule = &securitygroup.EgressRule{Protocol: protocol, ToPort: port, ToEndPort: endPort,
+					Action: action}
+				egressRules[key][idx] = rule
+			}
+			if isAddrGroupFirewall {
+				rule.ToSecurityGroups = append(rule.ToSecurityGroups, decodeAddressGroupsDescription(firewall.Description)...)
+			} else {
+				rule.ToDstIP = append(rule.ToDstIP, convertFromFirewallRanges(firewall.DestinationRanges)...)
+			}
+		}
+	}
+
+	// build sync objects for managed security groups
+	var enforcedSecurityCloudView []securitygroup.SynchronizationContent
+	for key, group := range groups {
+		var indexes []int
+		for idx := range ingressRules[key] {
+			indexes = append(indexes, idx)
+		}
+		sort.Ints(indexes)
+		for _, idx := range indexes {
+			group.IngressRules = append(group.IngressRules, *ingressRules[key][idx])
+		}
+
+		indexes = nil
+		for idx := range egressRules[key] {
+			indexes = append(indexes, idx)
+		}
+		sort.Ints(indexes)
+		for _, idx := range indexes {
+			group.EgressRules = append(group.EgressRules, *egressRules[key][idx])
+		}
+		enforcedSecurityCloudView = append(enforcedSecurityCloudView, *group)
+	}
+
+	return enforcedSecurityCloudView, nil
+}
+
+// ////////////////////////////////////////////////////////
+// 	SecurityInterface Implementation
+// ////////////////////////////////////////////////////////.
+func (c *gcpCloud) CreateSecurityGroup(addressGroupIdentifier *securitygroup.CloudResourceID, membershipOnly bool) (*string, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	vpcID := addressGroupIdentifier.Vpc
+	accCfg := c.getVpcAccount(vpcID)
+	if accCfg == nil {
+		return nil, fmt.Errorf("gcp account not found managing virtual private cloud [%v]", vpcID)
+	}
+	serviceCfg, err := accCfg.GetServiceConfigByName(gcpComputeServiceNameGCE)
+	if err != nil {
+		return nil, err
+	}
+	gceService := serviceCfg.(*gceServiceConfig)
+
+	// address group is only a network tag, nothing to be created in cloud.
+	cloudSgName := addressGroupIdentifier.GetCloudName(membershipOnly)
+	if membershipOnly {
+		return &cloudSgName, nil
+	}
+	if err := gceService.createDenyFirewalls(cloudSgName, vpcID); err != nil {
+		return nil, err
+	}
+	return &cloudSgName, nil
+}
+
+func (c *gcpCloud) UpdateSecurityGroupRules(addressGroupIdentifier *securitygroup.CloudResourceID,
+	ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	vpcID := addressGroupIdentifier.Vpc
+	accCfg := c.getVpcAccount(vpcID)
+	if accCfg == nil {
+		return fmt.Errorf("gcp account not found managing virtual private cloud [%v]", vpcID)
+	}
+	serviceCfg, err := accCfg.GetServiceConfigByName(gcpComputeServiceNameGCE)
+	if err != nil {
+		return err
+	}
+	gceService := serviceCfg.(*gceServiceConfig)
+	// firewalls are realized at fixed priorities of deny and allow rules irrespective of rule priorities.
+	ingressRules = securitygroup.CompactIngressRulesIgnoringPriority(ingressRules)
+	egressRules = securitygroup.CompactEgressRulesIgnoringPriority(egressRules)
+	if err := checkCloudServicePeers(ingressRules, egressRules); err != nil {
+		return err
+	}
+	if securitygroup.HasICMPTypeCode(ingressRules, egressRules) {
+		return fmt.Errorf("icmp type and code are not supported by gcp firewalls")
+	}
+
+	cloudSgName := addressGroupIdentifier.GetCloudName(false)
+	selfLink, err := gceService.getNetworkSelfLink(vpcID)
+	if err != nil {
+		return err
+	}
+	existingFirewalls, err := gceService.getSecurityGroupFirewalls(cloudSgName, vpcID)
+	if err != nil {
+		return err
+	}
+	delete(existingFirewalls, getFirewallName(cloudSgName, vpcID, gceDenyIngressSuffix))
+	delete(existingFirewalls, getFirewallName(cloudSgName, vpcID, gceDenyEgressSuffix))
+
+	instances, err := gceService.getRegionInstances()
+	if err != nil {
+		return err
+	}
+	desiredFirewalls := buildFirewalls(cloudSgName, vpcID, selfLink, ingressRules, egressRules, instances,
+		gceService.getNetworkIDToSelfLink())
+
+	return gceService.realizeFirewalls(desiredFirewalls, existingFirewalls)
+}
+
+func (c *gcpCloud) UpdateSecurityGroupMembers(groupIdentifier *securitygroup.CloudResourceID,
+	cloudResourceIdentifiers []*securitygroup.CloudResource, membershipOnly bool) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	vpcID := groupIdentifier.Vpc
+	accCfg := c.getVpcAccount(vpcID)
+	if accCfg == nil {
+		return fmt.Errorf("gcp account not found managing virtual private cloud [%v]", vpcID)
+	}
+	serviceCfg, err := accCfg.GetServiceConfigByName(gcpComputeServiceNameGCE)
+	if err != nil {
+		return err
+	}
+	gceService := serviceCfg.(*gceServiceConfig)
+
+	cloudSgName := groupIdentifier.GetCloudName(membershipOnly)
+	err = gceService.updateSecurityGroupMembers(cloudSgName, vpcID, cloudResourceIdentifiers)
+	if err != nil {
+		return err
+	}
+
+	// address group members resolved to ip addresses in firewalls need to be refreshed.
+	if membershipOnly {
+		return gceService.refreshAddressGroupFirewalls(groupIdentifier)
+	}
+	return nil
+}
+
+func (c *gcpCloud) DeleteSecurityGroup(groupIdentifier *securitygroup.CloudResourceID, membershipOnly bool) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	vpcID := groupIdentifier.Vpc
+	accCfg := c.getVpcAccount(vpcID)
+	if accCfg == nil {
+		return fmt.Errorf("gcp account not found managing virtual private cloud [%v]", vpcID)
+	}
+	serviceCfg, err := accCfg.GetServiceConfigByName(gcpComputeServiceNameGCE)
+	if err != nil {
+		return err
+	}
+	gceService := serviceCfg.(*gceServiceConfig)
+
+	cloudSgNameToDelete := groupIdentifier.GetCloudName(membershipOnly)
+	err = gceService.updateSecurityGroupMembers(cloudSgNameToDelete, vpcID, nil)
+	if err != nil {
+		return err
+	}
+	if membershipOnly {
+		return nil
+	}
+
+	// delete all firewalls of appliedTo security group
+	firewalls, err := gceService.getSecurityGroupFirewalls(cloudSgNameToDelete, vpcID)
+	if err != nil {
+		return err
+	}
+	return gceService.realizeFirewalls(nil, firewalls)
+}
+
+func (c *gcpCloud) GetEnforcedSecurity() ([]securitygroup.SynchronizationContent, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	inventoryInitWaitDuration := 30 * time.Second
+
+	var accNamespacedNames []types.NamespacedName
+	accountConfigs := c.cloudCommon.GetCloudAccounts()
+	for _, accCfg := range accountConfigs {
+		accNamespacedNames = append(accNamespacedNames, *accCfg.GetNamespacedName())
+	}
+
+	var enforcedSecurityCloudView []securitygroup.SynchronizationContent
+	var retErr error
+	var errMutex sync.Mutex
+	var wg sync.WaitGroup
+	ch := make(chan []securitygroup.SynchronizationContent)
+	wg.Add(len(accNamespacedNames))
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	for _, accNamespacedName := range accNamespacedNames {
+		accNamespacedNameCopy := &types.NamespacedName{
+			Namespace: accNamespacedName.Namespace,
+			Name:      accNamespacedName.Name,
+		}
+
+		go func(name *types.NamespacedName, sendCh chan<- []securitygroup.SynchronizationContent) {
+			defer wg.Done()
+
+			accCfg, found := c.cloudCommon.GetCloudAccountByName(name)
+			if !found {
+				gcpPluginLogger().Info("enforced-security-cloud-view GET for account skipped (account no longer exists)", "account", name)
+				return
+			}
+
+			appendErr := func(err error) {
+				errMutex.Lock()
+				defer errMutex.Unlock()
+				retErr = multierr.Append(retErr, fmt.Errorf("account %v: %w", name, err))
+			}
+			serviceCfg, err := accCfg.GetServiceConfigByName(gcpComputeServiceNameGCE)
+			if err != nil {
+				gcpPluginLogger().Error(err, "enforced-security-cloud-view GET for account skipped", "account", accCfg.GetNamespacedName())
+				appendErr(err)
+				return
+			}
+			gceService := serviceCfg.(*gceServiceConfig)
+			err = gceService.waitForInventoryInit(inventoryInitWaitDuration)
+			if err != nil {
+				gcpPluginLogger().Error(err, "enforced-security-cloud-view GET for account skipped", "account", accCfg.GetNamespacedName())
+				appendErr(err)
+				return
+			}
+			cloudView, err := gceService.getNepheControllerManagedSecurityGroupsCloudView()
+			if err != nil {
+				gcpPluginLogger().Error(err, "enforced-security-cloud-view GET for account failed", "account", accCfg.GetNamespacedName())
+				appendErr(err)
+				return
+			}
+			sendCh <- cloudView
+		}(accNamespacedNameCopy, ch)
+	}
+
+	for val := range ch {
+		if val != nil {
+			enforcedSecurityCloudView = append(enforcedSecurityCloudView, val...)
+		}
+	}
+	return enforcedSecurityCloudView, retErr
+}
+
+// GetUnmanagedSecurityGroups is not supported. GCE firewalls are network wide and target network tags or service
+// accounts, they are not attached to network interfaces as security groups of other clouds.
+func (c *gcpCloud) GetUnmanagedSecurityGroups(accNamespacedName *types.NamespacedName) ([]securitygroup.UnmanagedSecurityGroup, error) {
+	return nil, fmt.Errorf("importing firewalls of gcp account %v is not supported", *accNamespacedName)
+}
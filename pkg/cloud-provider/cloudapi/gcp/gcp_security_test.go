@@ -0,0 +1,441 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/api/compute/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+)
+
+var _ = Describe("GCP Cloud Security", func() {
+	var (
+		testVpcID01 = strconv.FormatUint(testNetworkID01, 10)
+		testVMID01  = uint64(1001)
+		testVMID02  = uint64(1002)
+
+		testAccountNamespacedName = &types.NamespacedName{Namespace: "namespace01", Name: "account01"}
+		testEntitySelectorName    = "testEntitySelector01"
+
+		cloudInterface *gcpCloud
+		account        *v1alpha1.CloudProviderAccount
+		selector       *v1alpha1.CloudEntitySelector
+
+		// instances and firewalls hold the cloud state served by the mocked gce api.
+		instances []*compute.Instance
+		firewalls map[string]*compute.Firewall
+
+		mockCtrl           *gomock.Controller
+		mockgcpCloudHelper *MockgcpServicesHelper
+		mockgcpCompute     *MockgcpComputeWrapper
+		mockgcpService     *MockgcpServiceClientCreateInterface
+	)
+
+	BeforeEach(func() {
+		var pollIntv uint = 1
+		account = &v1alpha1.CloudProviderAccount{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      testAccountNamespacedName.Name,
+				Namespace: testAccountNamespacedName.Namespace,
+			},
+			Spec: v1alpha1.CloudProviderAccountSpec{
+				PollIntervalInSeconds: &pollIntv,
+				GCPConfig: &v1alpha1.CloudProviderAccountGCPConfig{
+					ProjectID:         testProjectID,
+					ServiceAccountKey: testServiceAccount,
+					Region:            testRegion,
+				},
+			},
+		}
+		selector = &v1alpha1.CloudEntitySelector{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      testEntitySelectorName,
+				Namespace: testAccountNamespacedName.Namespace,
+			},
+			Spec: v1alpha1.CloudEntitySelectorSpec{
+				AccountName: testAccountNamespacedName.Name,
+				VMSelector: []v1alpha1.VirtualMachineSelector{
+					{
+						VpcMatch: &v1alpha1.EntityMatch{
+							MatchID: testVpcID01,
+						},
+					},
+				},
+			},
+		}
+
+		instances = getGceInstanceObjects([]uint64{testVMID01, testVMID02})
+		firewalls = make(map[string]*compute.Firewall)
+
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockgcpCloudHelper = NewMockgcpServicesHelper(mockCtrl)
+
+		mockgcpService = NewMockgcpServiceClientCreateInterface(mockCtrl)
+		mockgcpCompute = NewMockgcpComputeWrapper(mockCtrl)
+
+		mockgcpCloudHelper.EXPECT().newServiceSdkConfigProvider(gomock.Any()).Return(mockgcpService, nil).Times(1)
+		mockgcpService.EXPECT().compute().Return(mockgcpCompute, nil).AnyTimes()
+		mockgcpCompute.EXPECT().pagedListNetworksWrapper().Return(getGceNetworkObjects(), nil).AnyTimes()
+		mockgcpCompute.EXPECT().pagedListInstancesWrapper().DoAndReturn(func() ([]*compute.Instance, error) {
+			return instances, nil
+		}).AnyTimes()
+		mockgcpCompute.EXPECT().pagedListFirewallsWrapper().DoAndReturn(func() ([]*compute.Firewall, error) {
+			var out []*compute.Firewall
+			for _, firewall := range firewalls {
+				out = append(out, firewall)
+			}
+			return out, nil
+		}).AnyTimes()
+
+		cloudInterface = newGCPCloud(mockgcpCloudHelper)
+		err := cloudInterface.AddProviderAccount(account)
+		Expect(err).Should(BeNil())
+
+		err = cloudInterface.AddAccountResourceSelector(testAccountNamespacedName, selector)
+		Expect(err).Should(BeNil())
+
+		// wait for instances to be populated
+		time.Sleep(time.Duration(pollIntv+1) * time.Second)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("CreateSecurityGroup", func() {
+		It("Should return address group network tag without calling cloud api", func() {
+			webAddressGroupIdentifier := &securitygroup.CloudResourceID{
+				Name: "Web",
+				Vpc:  testVpcID01,
+			}
+			mockgcpCompute.EXPECT().insertFirewall(gomock.Any()).Times(0)
+
+			cloudSgID, err := cloudInterface.CreateSecurityGroup(webAddressGroupIdentifier, true)
+			Expect(err).Should(BeNil())
+			Expect(*cloudSgID).Should(Equal(webAddressGroupIdentifier.GetCloudName(true)))
+		})
+		It("Should create deny firewalls for appliedTo group", func() {
+			webAppliedToGroupIdentifier := &securitygroup.CloudResourceID{
+				Name: "Web",
+				Vpc:  testVpcID01,
+			}
+			mockgcpCompute.EXPECT().insertFirewall(gomock.Any()).DoAndReturn(func(firewall *compute.Firewall) error {
+				Expect(firewall.TargetTags).To(Equal([]string{webAppliedToGroupIdentifier.GetCloudName(false)}))
+				Expect(firewall.Priority).To(Equal(int64(gceDenyPriority)))
+				firewalls[firewall.Name] = firewall
+				return nil
+			}).Times(2)
+
+			cloudSgID, err := cloudInterface.CreateSecurityGroup(webAppliedToGroupIdentifier, false)
+			Expect(err).Should(BeNil())
+			Expect(*cloudSgID).Should(Equal(webAppliedToGroupIdentifier.GetCloudName(false)))
+
+			// deny firewalls are not created again.
+			_, err = cloudInterface.CreateSecurityGroup(webAppliedToGroupIdentifier, false)
+			Expect(err).Should(BeNil())
+		})
+		It("Should fail for unknown vpc", func() {
+			_, err := cloudInterface.CreateSecurityGroup(&securitygroup.CloudResourceID{Name: "Web", Vpc: "unknown"}, true)
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("UpdateSecurityGroupMembers", func() {
+		It("Should set network tag on member instances only", func() {
+			webAddressGroupIdentifier := &securitygroup.CloudResourceID{
+				Name: "Web",
+				Vpc:  testVpcID01,
+			}
+			cloudSgName := webAddressGroupIdentifier.GetCloudName(true)
+			instances[1].Tags.Items = []string{"user-tag"}
+			members := []*securitygroup.CloudResource{
+				{
+					Type: securitygroup.CloudResourceTypeVM,
+					Name: securitygroup.CloudResourceID{Name: strconv.FormatUint(testVMID02, 10), Vpc: testVpcID01},
+				},
+			}
+			mockgcpCompute.EXPECT().setInstanceTags(testRegion+"-a", instances[1].Name,
+				&compute.Tags{Items: []string{"user-tag", cloudSgName}, Fingerprint: "fp"}).Return(nil).Times(1)
+
+			err := cloudInterface.UpdateSecurityGroupMembers(webAddressGroupIdentifier, members, true)
+			Expect(err).Should(BeNil())
+		})
+	})
+
+	Context("UpdateSecurityGroupRules", func() {
+		var (
+			webAppliedToGroupIdentifier = &securitygroup.CloudResourceID{Name: "web", Vpc: testVpcID01}
+			dbAddressGroupIdentifier    = &securitygroup.CloudResourceID{Name: "db", Vpc: testVpcID01}
+			peerAddressGroupIdentifier  = &securitygroup.CloudResourceID{Name: "peer", Vpc: "other"}
+			tcpProtocol                 = 6
+			httpPort                    = 80
+		)
+
+		BeforeEach(func() {
+			mockgcpCompute.EXPECT().insertFirewall(gomock.Any()).DoAndReturn(func(firewall *compute.Firewall) error {
+				firewalls[firewall.Name] = firewall
+				return nil
+			}).AnyTimes()
+			mockgcpCompute.EXPECT().updateFirewall(gomock.Any()).DoAndReturn(func(firewall *compute.Firewall) error {
+				firewalls[firewall.Name] = firewall
+				return nil
+			}).AnyTimes()
+			mockgcpCompute.EXPECT().deleteFirewall(gomock.Any()).DoAndReturn(func(name string) error {
+				delete(firewalls, name)
+				return nil
+			}).AnyTimes()
+			mockgcpCompute.EXPECT().setInstanceTags(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		})
+
+		It("Should realize rules as firewalls and report them in cloud view", func() {
+			_, ipNet, _ := net.ParseCIDR("192.168.1.0/24")
+			ingressRules := []*securitygroup.IngressRule{
+				{
+					Protocol:           &tcpProtocol,
+					FromPort:           &httpPort,
+					FromSrcIP:          []*net.IPNet{ipNet},
+					FromSecurityGroups: []*securitygroup.CloudResourceID{dbAddressGroupIdentifier, peerAddressGroupIdentifier},
+				},
+			}
+			egressRules := []*securitygroup.EgressRule{
+				{
+					ToSecurityGroups: []*securitygroup.CloudResourceID{dbAddressGroupIdentifier},
+				},
+			}
+			instances[0].Tags.Items = []string{webAppliedToGroupIdentifier.GetCloudName(false)}
+			instances[1].Tags.Items = []string{dbAddressGroupIdentifier.GetCloudName(true)}
+
+			_, err := cloudInterface.CreateSecurityGroup(webAppliedToGroupIdentifier, false)
+			Expect(err).Should(BeNil())
+			err = cloudInterface.UpdateSecurityGroupRules(webAppliedToGroupIdentifier, ingressRules, egressRules)
+			Expect(err).Should(BeNil())
+			// deny firewalls, ingress firewalls for same and other network groups, egress firewall for resolved group.
+			Expect(firewalls).To(HaveLen(5))
+
+			egressFirewall := firewalls[getFirewallName(webAppliedToGroupIdentifier.GetCloudName(false), testVpcID01,
+				gceEgressSuffix+"-0"+gceAddrGroupSuffix)]
+			Expect(egressFirewall).ToNot(BeNil())
+			Expect(egressFirewall.DestinationRanges).To(Equal([]string{instances[1].NetworkInterfaces[0].NetworkIP + "/32"}))
+			Expect(egressFirewall.Disabled).To(BeFalse())
+
+			cloudView, err := cloudInterface.GetEnforcedSecurity()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudView).To(HaveLen(2))
+			for _, content := range cloudView {
+				Expect(content.Members).To(HaveLen(1))
+				if content.MembershipOnly {
+					Expect(content.Resource).To(Equal(*dbAddressGroupIdentifier))
+					continue
+				}
+				Expect(content.Resource).To(Equal(*webAppliedToGroupIdentifier))
+				Expect(content.IngressRules).To(HaveLen(1))
+				Expect(*content.IngressRules[0].Protocol).To(Equal(tcpProtocol))
+				Expect(*content.IngressRules[0].FromPort).To(Equal(httpPort))
+				Expect(content.IngressRules[0].FromSrcIP).To(Equal([]*net.IPNet{ipNet}))
+				Expect(content.IngressRules[0].FromSecurityGroups).To(ConsistOf(dbAddressGroupIdentifier, peerAddressGroupIdentifier))
+				Expect(content.EgressRules).To(HaveLen(1))
+				Expect(content.EgressRules[0].Protocol).To(BeNil())
+				Expect(content.EgressRules[0].ToDstIP).To(BeNil())
+				Expect(content.EgressRules[0].ToSecurityGroups).To(ConsistOf(dbAddressGroupIdentifier))
+			}
+
+			// address group members update refreshes resolved ip addresses.
+			instances[1].Tags.Items = nil
+			err = cloudInterface.UpdateSecurityGroupMembers(dbAddressGroupIdentifier, nil, true)
+			Expect(err).Should(BeNil())
+			egressFirewall = firewalls[egressFirewall.Name]
+			Expect(egressFirewall.DestinationRanges).To(BeEmpty())
+			Expect(egressFirewall.Disabled).To(BeTrue())
+
+			// clearing rules keeps only deny firewalls.
+			err = cloudInterface.UpdateSecurityGroupRules(webAppliedToGroupIdentifier, nil, nil)
+			Expect(err).Should(BeNil())
+			Expect(firewalls).To(HaveLen(2))
+
+			err = cloudInterface.DeleteSecurityGroup(webAppliedToGroupIdentifier, false)
+			Expect(err).Should(BeNil())
+			Expect(firewalls).To(BeEmpty())
+		})
+
+		It("Should realize deny rules as higher priority denied firewalls", func() {
+			_, ipNet, _ := net.ParseCIDR("192.168.1.0/24")
+			ingressRules := []*securitygroup.IngressRule{
+				{
+					Protocol:  &tcpProtocol,
+					FromPort:  &httpPort,
+					FromSrcIP: []*net.IPNet{ipNet},
+					Action:    securitygroup.RuleActionDeny,
+				},
+				{
+					Protocol: &tcpProtocol,
+					FromPort: &httpPort,
+					Action:   securitygroup.RuleActionAllow,
+				},
+			}
+			egressRules := []*securitygroup.EgressRule{
+				{
+					ToDstIP: []*net.IPNet{ipNet},
+					Action:  securitygroup.RuleActionDeny,
+				},
+			}
+			instances[0].Tags.Items = []string{webAppliedToGroupIdentifier.GetCloudName(false)}
+
+			_, err := cloudInterface.CreateSecurityGroup(webAppliedToGroupIdentifier, false)
+			Expect(err).Should(BeNil())
+			err = cloudInterface.UpdateSecurityGroupRules(webAppliedToGroupIdentifier, ingressRules, egressRules)
+			Expect(err).Should(BeNil())
+			// deny firewalls, deny rule firewalls, IPv4 and IPv6 firewalls of allow rule without peers.
+			Expect(firewalls).To(HaveLen(6))
+
+			denyFirewall := firewalls[getFirewallName(webAppliedToGroupIdentifier.GetCloudName(false), testVpcID01,
+				gceDenyRuleInSuffix+"-0")]
+			Expect(denyFirewall).ToNot(BeNil())
+			Expect(denyFirewall.Allowed).To(BeEmpty())
+			Expect(denyFirewall.Denied).To(Equal([]*compute.FirewallDenied{{IPProtocol: "tcp", Ports: []string{"80"}}}))
+			Expect(denyFirewall.Priority).To(BeNumerically("<", int64(gceAllowPriority)))
+			allowFirewall := firewalls[getFirewallName(webAppliedToGroupIdentifier.GetCloudName(false), testVpcID01,
+				gceIngressSuffix+"-1")]
+			Expect(allowFirewall).ToNot(BeNil())
+			Expect(allowFirewall.Denied).To(BeEmpty())
+
+			cloudView, err := cloudInterface.GetEnforcedSecurity()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudView).To(HaveLen(1))
+			Expect(cloudView[0].IngressRules).To(HaveLen(2))
+			Expect(cloudView[0].IngressRules[0].Action).To(Equal(securitygroup.RuleActionDeny))
+			Expect(*cloudView[0].IngressRules[0].Protocol).To(Equal(tcpProtocol))
+			Expect(*cloudView[0].IngressRules[0].FromPort).To(Equal(httpPort))
+			Expect(cloudView[0].IngressRules[0].FromSrcIP).To(Equal([]*net.IPNet{ipNet}))
+			Expect(cloudView[0].IngressRules[1].Action).To(Equal(securitygroup.RuleActionAllow))
+			Expect(cloudView[0].EgressRules).To(HaveLen(1))
+			Expect(cloudView[0].EgressRules[0].Action).To(Equal(securitygroup.RuleActionDeny))
+			Expect(cloudView[0].EgressRules[0].Protocol).To(BeNil())
+			Expect(cloudView[0].EgressRules[0].ToDstIP).To(Equal([]*net.IPNet{ipNet}))
+		})
+
+		It("Should realize port ranges", func() {
+			endPort := 8080
+			ingressRules := []*securitygroup.IngressRule{
+				{Protocol: &tcpProtocol, FromPort: &httpPort, FromEndPort: &endPort},
+			}
+			instances[0].Tags.Items = []string{webAppliedToGroupIdentifier.GetCloudName(false)}
+
+			_, err := cloudInterface.CreateSecurityGroup(webAppliedToGroupIdentifier, false)
+			Expect(err).Should(BeNil())
+			err = cloudInterface.UpdateSecurityGroupRules(webAppliedToGroupIdentifier, ingressRules, nil)
+			Expect(err).Should(BeNil())
+			firewall := firewalls[getFirewallName(webAppliedToGroupIdentifier.GetCloudName(false), testVpcID01,
+				gceIngressSuffix+"-0")]
+			Expect(firewall).ToNot(BeNil())
+			Expect(firewall.Allowed).To(Equal([]*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"80-8080"}}}))
+
+			cloudView, err := cloudInterface.GetEnforcedSecurity()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudView).To(HaveLen(1))
+			Expect(cloudView[0].IngressRules).To(HaveLen(1))
+			Expect(*cloudView[0].IngressRules[0].FromPort).To(Equal(httpPort))
+			Expect(*cloudView[0].IngressRules[0].FromEndPort).To(Equal(endPort))
+		})
+
+		It("Should realize IPv6 ranges in separate firewalls", func() {
+			_, ipv4Net, _ := net.ParseCIDR("192.168.1.0/24")
+			_, ipv6Net, _ := net.ParseCIDR("fd20:1::/64")
+			egressRules := []*securitygroup.EgressRule{
+				{Protocol: &tcpProtocol, ToPort: &httpPort, ToDstIP: []*net.IPNet{ipv4Net, ipv6Net}},
+			}
+			instances[0].Tags.Items = []string{webAppliedToGroupIdentifier.GetCloudName(false)}
+
+			_, err := cloudInterface.CreateSecurityGroup(webAppliedToGroupIdentifier, false)
+			Expect(err).Should(BeNil())
+			err = cloudInterface.UpdateSecurityGroupRules(webAppliedToGroupIdentifier, nil, egressRules)
+			Expect(err).Should(BeNil())
+			// deny firewalls, egress firewalls for IPv4 and IPv6 ranges.
+			Expect(firewalls).To(HaveLen(4))
+			firewall := firewalls[getFirewallName(webAppliedToGroupIdentifier.GetCloudName(false), testVpcID01,
+				gceEgressSuffix+"-0")]
+			Expect(firewall.DestinationRanges).To(Equal([]string{ipv4Net.String()}))
+			firewall = firewalls[getFirewallName(webAppliedToGroupIdentifier.GetCloudName(false), testVpcID01,
+				gceEgressSuffix+"-0"+gceIPv6Suffix)]
+			Expect(firewall.DestinationRanges).To(Equal([]string{ipv6Net.String()}))
+
+			cloudView, err := cloudInterface.GetEnforcedSecurity()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudView).To(HaveLen(1))
+			Expect(cloudView[0].EgressRules).To(HaveLen(1))
+			Expect(cloudView[0].EgressRules[0].ToDstIP).To(ConsistOf(ipv4Net, ipv6Net))
+		})
+
+		It("Should realize rules without peers for IPv4 and IPv6 addresses", func() {
+			egressRules := []*securitygroup.EgressRule{{Protocol: &tcpProtocol, ToPort: &httpPort}}
+			_, err := cloudInterface.CreateSecurityGroup(webAppliedToGroupIdentifier, false)
+			Expect(err).Should(BeNil())
+			err = cloudInterface.UpdateSecurityGroupRules(webAppliedToGroupIdentifier, nil, egressRules)
+			Expect(err).Should(BeNil())
+			firewall := firewalls[getFirewallName(webAppliedToGroupIdentifier.GetCloudName(false), testVpcID01,
+				gceEgressSuffix+"-0")]
+			Expect(firewall.DestinationRanges).To(Equal([]string{gceAnyAddress}))
+			firewall = firewalls[getFirewallName(webAppliedToGroupIdentifier.GetCloudName(false), testVpcID01,
+				gceEgressSuffix+"-0"+gceIPv6Suffix)]
+			Expect(firewall.DestinationRanges).To(Equal([]string{gceAnyIPv6Address}))
+		})
+
+		It("Should realize ICMP rules of IPv6 ranges as ICMPv6 firewalls", func() {
+			icmpProtocol, icmpType := 1, 8
+			_, ipv6Net, _ := net.ParseCIDR("fd20:1::/64")
+			egressRules := []*securitygroup.EgressRule{
+				{Protocol: &icmpProtocol, ToDstIP: []*net.IPNet{ipv6Net}},
+			}
+			_, err := cloudInterface.CreateSecurityGroup(webAppliedToGroupIdentifier, false)
+			Expect(err).Should(BeNil())
+			err = cloudInterface.UpdateSecurityGroupRules(webAppliedToGroupIdentifier, nil, egressRules)
+			Expect(err).Should(BeNil())
+			firewall := firewalls[getFirewallName(webAppliedToGroupIdentifier.GetCloudName(false), testVpcID01,
+				gceEgressSuffix+"-0"+gceIPv6Suffix)]
+			Expect(firewall.Allowed).To(Equal([]*compute.FirewallAllowed{{IPProtocol: "58"}}))
+
+			cloudView, err := cloudInterface.GetEnforcedSecurity()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudView).To(HaveLen(1))
+			Expect(cloudView[0].EgressRules).To(HaveLen(1))
+			Expect(*cloudView[0].EgressRules[0].Protocol).To(Equal(icmpProtocol))
+
+			egressRules[0].ICMPType = &icmpType
+			err = cloudInterface.UpdateSecurityGroupRules(webAppliedToGroupIdentifier, nil, egressRules)
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("Should reject rules with cloud service peers", func() {
+			egressRules := []*securitygroup.EgressRule{
+				{Protocol: &tcpProtocol, ToPort: &httpPort, ToCloudServices: []string{"Storage.WestUS2"}},
+			}
+			_, err := cloudInterface.CreateSecurityGroup(webAppliedToGroupIdentifier, false)
+			Expect(err).Should(BeNil())
+			err = cloudInterface.UpdateSecurityGroupRules(webAppliedToGroupIdentifier, nil, egressRules)
+			Expect(err).Should(HaveOccurred())
+			// only deny firewalls created with appliedTo group.
+			Expect(firewalls).To(HaveLen(2))
+		})
+	})
+})
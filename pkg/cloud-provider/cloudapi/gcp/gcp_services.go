@@ -0,0 +1,84 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"k8s.io/apimachinery/pkg/types"
+
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/internal"
+)
+
+const (
+	gcpComputeServiceNameGCE = internal.CloudServiceName("GCE")
+)
+
+type gcpServiceClientCreateInterface interface {
+	compute() (gcpComputeWrapper, error)
+	// Add any gcp service api client creation methods here
+}
+
+type gcpServiceSdkConfigProvider struct {
+	projectID     string
+	clientOptions []option.ClientOption
+}
+
+type gcpServicesHelper interface {
+	newServiceSdkConfigProvider(accCfg *gcpAccountCredentials) (gcpServiceClientCreateInterface, error)
+}
+
+type gcpServicesHelperImpl struct{}
+
+func (h *gcpServicesHelperImpl) newServiceSdkConfigProvider(accCreds *gcpAccountCredentials) (gcpServiceClientCreateInterface, error) {
+	var clientOptions []option.ClientOption
+
+	if len(accCreds.serviceAccountEmail) != 0 {
+		// use the credentials available to the controller (workload identity or node service account) to impersonate
+		// the customer service account. Controller identity needs roles/iam.serviceAccountTokenCreator on it.
+		clientOptions = append(clientOptions, option.ImpersonateCredentials(accCreds.serviceAccountEmail))
+	} else {
+		// use service account key passed in
+		clientOptions = append(clientOptions, option.WithCredentialsJSON([]byte(accCreds.serviceAccountKey)))
+	}
+	clientOptions = append(clientOptions, option.WithScopes(compute.ComputeScope))
+
+	configProvider := &gcpServiceSdkConfigProvider{
+		projectID:     accCreds.projectID,
+		clientOptions: clientOptions,
+	}
+	return configProvider, nil
+}
+
+func newGcpServiceConfigs(accountNamespacedName *types.NamespacedName, accCredentials interface{}, gcpSpecificHelper interface{}) (
+	[]internal.CloudServiceInterface, error) {
+	gcpServicesHelper := gcpSpecificHelper.(gcpServicesHelper)
+	gcpAccountCredentials := accCredentials.(*gcpAccountCredentials)
+
+	var serviceConfigs []internal.CloudServiceInterface
+
+	gcpServiceClientCreator, err := gcpServicesHelper.newServiceSdkConfigProvider(gcpAccountCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	gceService, err := newGCEServiceConfig(accountNamespacedName.String(), gcpAccountCredentials.region, gcpServiceClientCreator)
+	if err != nil {
+		return nil, err
+	}
+	serviceConfigs = append(serviceConfigs, gceService)
+
+	return serviceConfigs, nil
+}
@@ -0,0 +1,437 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"google.golang.org/api/compute/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"antrea.io/nephe/apis/crd/v1alpha1"
+)
+
+var (
+	testProjectID      = "test-project"
+	testRegion         = "us-central1"
+	testNetworkID01    = uint64(6518436472339437510)
+	testNetworkName01  = "network-01"
+	testServiceAccount = `{"type": "service_account", "project_id": "test-project"}`
+)
+
+var _ = Describe("GCP cloud", func() {
+	var (
+		testAccountNamespacedName = types.NamespacedName{Namespace: "namespace01", Name: "account01"}
+	)
+
+	Context("AddProviderAccount", func() {
+		var (
+			account            *v1alpha1.CloudProviderAccount
+			mockCtrl           *gomock.Controller
+			mockgcpCloudHelper *MockgcpServicesHelper
+		)
+
+		BeforeEach(func() {
+			var pollIntv uint = 1
+			account = &v1alpha1.CloudProviderAccount{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      testAccountNamespacedName.Name,
+					Namespace: testAccountNamespacedName.Namespace,
+				},
+				Spec: v1alpha1.CloudProviderAccountSpec{
+					PollIntervalInSeconds: &pollIntv,
+					GCPConfig: &v1alpha1.CloudProviderAccountGCPConfig{
+						ProjectID:         testProjectID,
+						ServiceAccountKey: testServiceAccount,
+						Region:            testRegion,
+					},
+				},
+			}
+
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockgcpCloudHelper = NewMockgcpServicesHelper(mockCtrl)
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		Context("New account add fail scenarios", func() {
+			It("Should fail for invalid region", func() {
+				account.Spec.GCPConfig.Region = "us-central1-a"
+
+				c := newGCPCloud(mockgcpCloudHelper)
+				err := c.AddProviderAccount(account)
+
+				Expect(err).ShouldNot(BeNil())
+				accCfg, found := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+				Expect(found).To(BeFalse())
+				Expect(accCfg).To(BeNil())
+			})
+			It("Should fail for invalid service account key", func() {
+				account.Spec.GCPConfig.ServiceAccountKey = "invalid"
+
+				c := newGCPCloud(mockgcpCloudHelper)
+				err := c.AddProviderAccount(account)
+
+				Expect(err).ShouldNot(BeNil())
+				accCfg, found := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+				Expect(found).To(BeFalse())
+				Expect(accCfg).To(BeNil())
+			})
+		})
+
+		Context("New account add success scenarios", func() {
+			var (
+				selector *v1alpha1.CloudEntitySelector
+
+				mockgcpService *MockgcpServiceClientCreateInterface
+				mockgcpCompute *MockgcpComputeWrapper
+			)
+
+			BeforeEach(func() {
+				selector = &v1alpha1.CloudEntitySelector{
+					ObjectMeta: v1.ObjectMeta{
+						Name:      "selector-all",
+						Namespace: testAccountNamespacedName.Namespace,
+					},
+					Spec: v1alpha1.CloudEntitySelectorSpec{
+						AccountName: testAccountNamespacedName.Name,
+						VMSelector:  []v1alpha1.VirtualMachineSelector{},
+					},
+				}
+
+				mockgcpService = NewMockgcpServiceClientCreateInterface(mockCtrl)
+				mockgcpCompute = NewMockgcpComputeWrapper(mockCtrl)
+
+				mockgcpCloudHelper.EXPECT().newServiceSdkConfigProvider(gomock.Any()).Return(mockgcpService, nil)
+				mockgcpService.EXPECT().compute().Return(mockgcpCompute, nil).AnyTimes()
+			})
+
+			It("Should discover few instances with get ALL selector using service account key", func() {
+				instanceIds := []uint64{1001, 1002}
+				mockgcpCompute.EXPECT().pagedListNetworksWrapper().Return(getGceNetworkObjects(), nil).AnyTimes()
+				mockgcpCompute.EXPECT().pagedListInstancesWrapper().Return(getGceInstanceObjects(instanceIds), nil).AnyTimes()
+
+				c := newGCPCloud(mockgcpCloudHelper)
+				err := c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+				accCfg, found := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+				Expect(found).To(BeTrue())
+				Expect(accCfg).To(Not(BeNil()))
+
+				errSelAdd := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+				Expect(errSelAdd).Should(BeNil())
+
+				err = checkAccountAddSuccessCondition(c, testAccountNamespacedName, instanceIds)
+				Expect(err).Should(BeNil())
+			})
+			It("Should discover few instances with get ALL selector using service account impersonation", func() {
+				instanceIds := []uint64{1001, 1002}
+				account.Spec.GCPConfig.ServiceAccountKey = ""
+				account.Spec.GCPConfig.ServiceAccountEmail = "nephe@test-project.iam.gserviceaccount.com"
+				mockgcpCompute.EXPECT().pagedListNetworksWrapper().Return(getGceNetworkObjects(), nil).AnyTimes()
+				mockgcpCompute.EXPECT().pagedListInstancesWrapper().Return(getGceInstanceObjects(instanceIds), nil).AnyTimes()
+
+				c := newGCPCloud(mockgcpCloudHelper)
+				err := c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+				accCfg, found := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+				Expect(found).To(BeTrue())
+				Expect(accCfg).To(Not(BeNil()))
+
+				errSelAdd := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+				Expect(errSelAdd).Should(BeNil())
+
+				err = checkAccountAddSuccessCondition(c, testAccountNamespacedName, instanceIds)
+				Expect(err).Should(BeNil())
+			})
+			It("Should discover only instances in account region", func() {
+				instances := getGceInstanceObjects([]uint64{1001, 1002})
+				instances[1].Zone = "https://www.googleapis.com/compute/v1/projects/test-project/zones/europe-west4-a"
+				instances[0].MachineType = "https://www.googleapis.com/compute/v1/projects/test-project/zones/" + testRegion +
+					"-a/machineTypes/e2-medium"
+				instances[0].CreationTimestamp = "2022-10-01T01:02:03.456-07:00"
+				instances[0].Disks = []*compute.AttachedDisk{
+					{Boot: true, GuestOsFeatures: []*compute.GuestOsFeature{{Type: "WINDOWS"}}},
+				}
+				instances[0].Tags.Items = []string{"web", "nephe-at-test"}
+				instances[0].NetworkInterfaces[0].Subnetwork = "https://www.googleapis.com/compute/v1/projects/test-project/" +
+					"regions/" + testRegion + "/subnetworks/subnet-01"
+				mockgcpCompute.EXPECT().pagedListNetworksWrapper().Return(getGceNetworkObjects(), nil).AnyTimes()
+				mockgcpCompute.EXPECT().pagedListInstancesWrapper().Return(instances, nil).AnyTimes()
+
+				c := newGCPCloud(mockgcpCloudHelper)
+				err := c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+
+				errSelAdd := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+				Expect(errSelAdd).Should(BeNil())
+
+				err = checkAccountAddSuccessCondition(c, testAccountNamespacedName, []uint64{1001})
+				Expect(err).Should(BeNil())
+
+				vmCRDs, err := c.InstancesGivenProviderAccount(&testAccountNamespacedName)
+				Expect(err).Should(BeNil())
+				Expect(vmCRDs).To(HaveLen(1))
+				Expect(vmCRDs[0].Status.VirtualPrivateCloud).To(Equal(testNetworkName01))
+				Expect(vmCRDs[0].Status.State).To(Equal("running"))
+				Expect(vmCRDs[0].Status.Region).To(Equal(testRegion))
+				Expect(vmCRDs[0].Status.AvailabilityZone).To(Equal(testRegion + "-a"))
+				Expect(vmCRDs[0].Status.InstanceType).To(Equal("e2-medium"))
+				Expect(vmCRDs[0].Status.OSType).To(Equal("windows"))
+				Expect(vmCRDs[0].Status.LaunchTime.UTC().Format(time.RFC3339)).To(Equal("2022-10-01T08:02:03Z"))
+				Expect(vmCRDs[0].Status.SecurityGroups).To(Equal([]string{"nephe-at-test", "web"}))
+				Expect(vmCRDs[0].Status.NetworkInterfaces[0].Subnet).To(Equal("subnet-01"))
+				Expect(c.IsVirtualPrivateCloudPresent(strconv.FormatUint(testNetworkID01, 10))).To(BeTrue())
+			})
+			It("Should not call cloud api's with NO selector", func() {
+				mockgcpCompute.EXPECT().pagedListNetworksWrapper().Times(0)
+				mockgcpCompute.EXPECT().pagedListInstancesWrapper().Times(0)
+
+				c := newGCPCloud(mockgcpCloudHelper)
+				err := c.AddProviderAccount(account)
+				Expect(err).Should(BeNil())
+				accCfg, found := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+				Expect(found).To(BeTrue())
+				Expect(accCfg).To(Not(BeNil()))
+			})
+		})
+	})
+
+	Context("AddAccountResourceSelector", func() {
+		const (
+			testVpcID01 = "1234"
+			testVpcID02 = "5678"
+
+			testVpcName01 = "vpcName-01"
+
+			testVMName01 = "vmName-01"
+			testVMID02   = "vmID-02"
+		)
+		var (
+			c        *gcpCloud
+			account  *v1alpha1.CloudProviderAccount
+			selector *v1alpha1.CloudEntitySelector
+
+			mockCtrl           *gomock.Controller
+			mockgcpCloudHelper *MockgcpServicesHelper
+			mockgcpCompute     *MockgcpComputeWrapper
+			mockgcpService     *MockgcpServiceClientCreateInterface
+		)
+
+		BeforeEach(func() {
+			var pollIntv uint = 2
+			account = &v1alpha1.CloudProviderAccount{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      testAccountNamespacedName.Name,
+					Namespace: testAccountNamespacedName.Namespace,
+				},
+				Spec: v1alpha1.CloudProviderAccountSpec{
+					PollIntervalInSeconds: &pollIntv,
+					GCPConfig: &v1alpha1.CloudProviderAccountGCPConfig{
+						ProjectID:         testProjectID,
+						ServiceAccountKey: testServiceAccount,
+						Region:            testRegion,
+					},
+				},
+			}
+			selector = &v1alpha1.CloudEntitySelector{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "selector-VpcID",
+					Namespace: testAccountNamespacedName.Namespace,
+				},
+				Spec: v1alpha1.CloudEntitySelectorSpec{
+					AccountName: testAccountNamespacedName.Name,
+				},
+			}
+
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockgcpCloudHelper = NewMockgcpServicesHelper(mockCtrl)
+
+			mockgcpService = NewMockgcpServiceClientCreateInterface(mockCtrl)
+			mockgcpCompute = NewMockgcpComputeWrapper(mockCtrl)
+
+			mockgcpCloudHelper.EXPECT().newServiceSdkConfigProvider(gomock.Any()).Return(mockgcpService, nil).Times(1)
+			mockgcpService.EXPECT().compute().Return(mockgcpCompute, nil).AnyTimes()
+			mockgcpCompute.EXPECT().pagedListNetworksWrapper().Return(getGceNetworkObjects(), nil).AnyTimes()
+			mockgcpCompute.EXPECT().pagedListInstancesWrapper().Return(getGceInstanceObjects([]uint64{}), nil).AnyTimes()
+
+			c = newGCPCloud(mockgcpCloudHelper)
+			_ = c.AddProviderAccount(account)
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		Context("VM Selector scenarios", func() {
+			It("Should match expected filter - vpcID only and vpcName with vmName matches", func() {
+				expectedFilters := []*gceInstanceFilter{
+					{vpcID: testVpcID01},
+					{vpcName: testVpcName01, vmName: testVMName01},
+				}
+				selector.Spec.VMSelector = []v1alpha1.VirtualMachineSelector{
+					{
+						VpcMatch: &v1alpha1.EntityMatch{MatchID: testVpcID01},
+					},
+					{
+						VpcMatch: &v1alpha1.EntityMatch{MatchName: testVpcName01},
+						VMMatch:  []v1alpha1.EntityMatch{{MatchName: testVMName01}},
+					},
+				}
+				err := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+				Expect(err).Should(BeNil())
+
+				accCfg, _ := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+				serviceConfig, _ := accCfg.GetServiceConfigByName(gcpComputeServiceNameGCE)
+				filters := serviceConfig.(*gceServiceConfig).instanceFilters[selector.Name]
+				Expect(filters).To(Equal(expectedFilters))
+			})
+			It("Should match expected filter - vpcID with multiple vmID/vmName matches", func() {
+				expectedFilters := []*gceInstanceFilter{
+					{vpcID: testVpcID02, vmName: testVMName01},
+					{vpcID: testVpcID02, vmID: testVMID02},
+				}
+				selector.Spec.VMSelector = []v1alpha1.VirtualMachineSelector{
+					{
+						VpcMatch: &v1alpha1.EntityMatch{MatchID: testVpcID02},
+						VMMatch:  []v1alpha1.EntityMatch{{MatchName: testVMName01}, {MatchID: testVMID02}},
+					},
+				}
+				err := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+				Expect(err).Should(BeNil())
+
+				accCfg, _ := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+				serviceConfig, _ := accCfg.GetServiceConfigByName(gcpComputeServiceNameGCE)
+				filters := serviceConfig.(*gceServiceConfig).instanceFilters[selector.Name]
+				Expect(filters).To(Equal(expectedFilters))
+			})
+			It("Should match expected filter - select all entry overrides other matches", func() {
+				selector.Spec.VMSelector = []v1alpha1.VirtualMachineSelector{
+					{
+						VpcMatch: &v1alpha1.EntityMatch{MatchID: testVpcID01},
+					},
+					{},
+				}
+				err := c.AddAccountResourceSelector(&testAccountNamespacedName, selector)
+				Expect(err).Should(BeNil())
+
+				accCfg, _ := c.cloudCommon.GetCloudAccountByName(&testAccountNamespacedName)
+				serviceConfig, _ := accCfg.GetServiceConfigByName(gcpComputeServiceNameGCE)
+				filters, found := serviceConfig.(*gceServiceConfig).getInstanceResourceFilters()
+				Expect(found).To(BeTrue())
+				Expect(filters).To(BeNil())
+			})
+			It("Should match instances with filters", func() {
+				network := getGceNetworkObjects()[0]
+				instances := getGceInstanceObjects([]uint64{1001, 1002})
+				filter := &gceInstanceFilter{vpcName: testNetworkName01, vmID: "1002"}
+				Expect(filter.matches(instances[0], network)).To(BeFalse())
+				Expect(filter.matches(instances[1], network)).To(BeTrue())
+				filter = &gceInstanceFilter{vpcID: testVpcID01}
+				Expect(filter.matches(instances[1], network)).To(BeFalse())
+			})
+			It("Should match instances with tags filters", func() {
+				network := getGceNetworkObjects()[0]
+				instances := getGceInstanceObjects([]uint64{1001, 1002})
+				instances[0].Labels = map[string]string{"env": "prod", "app": "web"}
+				instances[1].Labels = map[string]string{"env": "dev"}
+
+				selector.Spec.VMSelector = []v1alpha1.VirtualMachineSelector{
+					{
+						VpcMatch: &v1alpha1.EntityMatch{MatchName: testNetworkName01},
+						VMMatch:  []v1alpha1.EntityMatch{{MatchTags: map[string]string{"env": "prod", "app": ""}}},
+					},
+				}
+				filters := buildGCEInstanceFilters(selector.Spec.VMSelector)
+				Expect(filters).To(HaveLen(1))
+				Expect(filters[0].matches(instances[0], network)).To(BeTrue())
+				Expect(filters[0].matches(instances[1], network)).To(BeFalse())
+
+				filter := &gceInstanceFilter{vmTags: map[string]string{"env": ""}}
+				Expect(filter.matches(instances[1], network)).To(BeTrue())
+			})
+		})
+	})
+})
+
+func getGceNetworkObjects() []*compute.Network {
+	return []*compute.Network{
+		{
+			Id:       testNetworkID01,
+			Name:     testNetworkName01,
+			SelfLink: "https://www.googleapis.com/compute/v1/projects/test-project/global/networks/" + testNetworkName01,
+		},
+	}
+}
+
+func getGceInstanceObjects(instanceIDs []uint64) []*compute.Instance {
+	var instances []*compute.Instance
+	for _, instanceID := range instanceIDs {
+		instance := &compute.Instance{
+			Id:     instanceID,
+			Name:   "vm-" + strconv.FormatUint(instanceID, 10),
+			Zone:   "https://www.googleapis.com/compute/v1/projects/test-project/zones/" + testRegion + "-a",
+			Status: "RUNNING",
+			NetworkInterfaces: []*compute.NetworkInterface{
+				{
+					Name:      "nic0",
+					Network:   "https://www.googleapis.com/compute/v1/projects/test-project/global/networks/" + testNetworkName01,
+					NetworkIP: "10.0.0." + strconv.FormatUint(instanceID%256, 10),
+				},
+			},
+			Tags: &compute.Tags{Fingerprint: "fp"},
+		}
+		instances = append(instances, instance)
+	}
+	return instances
+}
+
+func checkAccountAddSuccessCondition(c *gcpCloud, namespacedName types.NamespacedName, ids []uint64) error {
+	conditionFunc := func() (done bool, e error) {
+		accCfg, found := c.cloudCommon.GetCloudAccountByName(&namespacedName)
+		if !found {
+			return true, errors.New("failed to find account")
+		}
+
+		serviceConfig, _ := accCfg.GetServiceConfigByName(gcpComputeServiceNameGCE)
+		instances := serviceConfig.(*gceServiceConfig).getCachedInstances()
+		instanceIds := make([]uint64, 0, len(instances))
+		for _, instance := range instances {
+			instanceIds = append(instanceIds, instance.Id)
+		}
+
+		sort.Slice(instanceIds, func(i, j int) bool { return instanceIds[i] < instanceIds[j] })
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		equal := reflect.DeepEqual(instanceIds, ids)
+		if equal {
+			return true, nil
+		}
+		return false, nil
+	}
+
+	return wait.PollImmediate(1*time.Second, 5*time.Second, conditionFunc)
+}
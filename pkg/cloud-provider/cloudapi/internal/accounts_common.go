@@ -16,16 +16,20 @@ package internal
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"go.uber.org/multierr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
 
 	"antrea.io/nephe/pkg/logging"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 type CloudAccountInterface interface {
@@ -34,19 +38,31 @@ type CloudAccountInterface interface {
 	GetServiceConfigByName(name CloudServiceName) (CloudServiceInterface, error)
 	GetStatus() *cloudv1alpha1.CloudProviderAccountStatus
 
+	setResourceFilters(selector *cloudv1alpha1.CloudEntitySelector)
+	removeResourceFilters(selectorName string)
 	startPeriodicInventorySync() error
 	stopPeriodicInventorySync()
+	watchInventory(stopCh <-chan struct{}) <-chan struct{}
 }
 
 type cloudAccountConfig struct {
-	mutex                 sync.Mutex
-	namespacedName        *types.NamespacedName
-	credentials           interface{}
-	serviceConfigs        map[CloudServiceName]*CloudServiceCommon
+	mutex          sync.Mutex
+	namespacedName *types.NamespacedName
+	credentials    interface{}
+	// serviceMutex protects serviceConfigs, which changes when regions of the account are updated.
+	serviceMutex   sync.RWMutex
+	serviceConfigs map[CloudServiceName]*CloudServiceCommon
+	// selectors are resource selectors applied to services, keyed by selector name. They are applied to services
+	// added by account update.
+	selectors             map[string]*cloudv1alpha1.CloudEntitySelector
 	inventoryPollInterval time.Duration
 	inventoryChannel      chan struct{}
-	logger                func() logging.Logger
-	Status                *cloudv1alpha1.CloudProviderAccountStatus
+	// eventMutex protects inventoryEventSource, which changes when credentials of the account are updated, and
+	// inventoryWatchers.
+	eventMutex           sync.Mutex
+	inventoryEventSource InventoryEventSource
+	inventoryWatchers    []*inventoryWatcher
+	logger               func() logging.Logger
 }
 
 type CloudCredentialValidatorFunc func(credentials interface{}) (interface{}, error)
@@ -83,15 +99,19 @@ func (c *cloudCommon) newCloudAccountConfig(namespacedName *types.NamespacedName
 		}
 		serviceConfigMap[serviceCfg.GetName()] = serviceConfig
 	}
+	eventSource, err := c.newInventoryEventSource(namespacedName, cloudConvertedCredential)
+	if err != nil {
+		return nil, err
+	}
 
-	status := &cloudv1alpha1.CloudProviderAccountStatus{}
 	return &cloudAccountConfig{
 		logger:                loggerFunc,
 		namespacedName:        namespacedName,
 		inventoryPollInterval: pollInterval,
 		serviceConfigs:        serviceConfigMap,
+		selectors:             make(map[string]*cloudv1alpha1.CloudEntitySelector),
 		credentials:           cloudConvertedCredential,
-		Status:                status,
+		inventoryEventSource:  eventSource,
 	}, nil
 }
 
@@ -128,14 +148,18 @@ func (c *cloudCommon) updateCloudAccountConfig(credentials interface{}, config C
 	for _, serviceCfg := range serviceConfigs {
 		serviceConfigMap[serviceCfg.GetName()] = serviceCfg
 	}
+	eventSource, err := c.newInventoryEventSource(currentConfig.namespacedName, cloudConvertedNewCredential)
+	if err != nil {
+		return err
+	}
 
-	currentConfig.update(credentialsComparatorFunc, cloudConvertedNewCredential, serviceConfigMap, c.logger())
+	currentConfig.update(credentialsComparatorFunc, cloudConvertedNewCredential, serviceConfigMap, eventSource, c.logger())
 
 	return nil
 }
 
 func (accCfg *cloudAccountConfig) update(credentialComparator CloudCredentialComparatorFunc, newCredentials interface{},
-	newSvcConfigMap map[CloudServiceName]CloudServiceInterface, logger logging.Logger) {
+	newSvcConfigMap map[CloudServiceName]CloudServiceInterface, newEventSource InventoryEventSource, logger logging.Logger) {
 	accCfg.mutex.Lock()
 	defer accCfg.mutex.Unlock()
 
@@ -146,19 +170,40 @@ func (accCfg *cloudAccountConfig) update(credentialComparator CloudCredentialCom
 	}
 
 	accCfg.credentials = newCredentials
+	accCfg.setInventoryEventSource(newEventSource)
 	logger.Info("credentials updated.", "account", accCfg.namespacedName)
 
-	existingSvcConfigMap := accCfg.serviceConfigs
-	for name, svcConfig := range existingSvcConfigMap {
+	accCfg.serviceMutex.Lock()
+	defer accCfg.serviceMutex.Unlock()
+
+	for name, svcConfig := range accCfg.serviceConfigs {
 		newSvcCfg, found := newSvcConfigMap[name]
 		if !found {
-			// should not happen
+			// service of a region no longer managed by the account.
+			svcConfig.resetCachedState()
+			delete(accCfg.serviceConfigs, name)
+			metrics.DeleteAccountServiceMetrics(accCfg.namespacedName.Namespace, accCfg.namespacedName.Name, string(name))
+			logger.Info("service config removed", "account", accCfg.namespacedName, "serviceName", name)
 			continue
 		}
 		svcConfig.updateServiceConfig(newSvcCfg)
 		logger.Info("service config updated (api-clients to use new creds)", "account", accCfg.namespacedName,
 			"serviceName", name)
 	}
+	for name, newSvcCfg := range newSvcConfigMap {
+		if _, found := accCfg.serviceConfigs[name]; found {
+			continue
+		}
+		// service of a region newly managed by the account.
+		svcConfig := &CloudServiceCommon{
+			serviceInterface: newSvcCfg,
+		}
+		for _, selector := range accCfg.selectors {
+			svcConfig.setResourceFilters(selector)
+		}
+		accCfg.serviceConfigs[name] = svcConfig
+		logger.Info("service config added", "account", accCfg.namespacedName, "serviceName", name)
+	}
 }
 
 func (accCfg *cloudAccountConfig) performInventorySync() error {
@@ -185,11 +230,12 @@ func (accCfg *cloudAccountConfig) performInventorySync() error {
 					"account", accCfg.namespacedName, "resource-filters", "not-configured")
 				return
 			}
+			start := time.Now()
 			err := serviceCfg.doResourceInventory()
+			accCfg.recordInventoryPollMetrics(serviceCfg.getName(), time.Since(start), err)
 			if err != nil {
 				accCfg.logger().Error(err, "error fetching resources from cloud", "service", serviceCfg.getName(),
 					"account", accCfg.namespacedName)
-				accCfg.Status.Error = err.Error()
 			} else {
 				if isFilterNil {
 					accCfg.logger().V(1).Info("fetching resources from cloud", "service", serviceCfg.getName(),
@@ -214,11 +260,21 @@ func (accCfg *cloudAccountConfig) performInventorySync() error {
 	return err
 }
 
+// recordInventoryPollMetrics records latency and result of an inventory poll of an account service.
+func (accCfg *cloudAccountConfig) recordInventoryPollMetrics(name CloudServiceName, duration time.Duration, err error) {
+	namespace, account, service := accCfg.namespacedName.Namespace, accCfg.namespacedName.Name, string(name)
+	metrics.InventoryPollDuration.WithLabelValues(namespace, account, service).Observe(duration.Seconds())
+	metrics.InventoryPolls.WithLabelValues(namespace, account, service, metrics.Result(err)).Inc()
+}
+
 func (accCfg *cloudAccountConfig) GetNamespacedName() *types.NamespacedName {
 	return accCfg.namespacedName
 }
 
 func (accCfg *cloudAccountConfig) GetServiceConfigs() map[CloudServiceName]*CloudServiceCommon {
+	accCfg.serviceMutex.RLock()
+	defer accCfg.serviceMutex.RUnlock()
+
 	svcNameCfgMap := make(map[CloudServiceName]*CloudServiceCommon)
 	for name, serviceCommon := range accCfg.serviceConfigs {
 		svcNameCfgMap[name] = serviceCommon
@@ -227,6 +283,9 @@ func (accCfg *cloudAccountConfig) GetServiceConfigs() map[CloudServiceName]*Clou
 }
 
 func (accCfg *cloudAccountConfig) GetServiceConfigByName(name CloudServiceName) (CloudServiceInterface, error) {
+	accCfg.serviceMutex.RLock()
+	defer accCfg.serviceMutex.RUnlock()
+
 	if serviceCfg, found := accCfg.serviceConfigs[name]; found {
 		return serviceCfg.serviceInterface, nil
 	}
@@ -234,8 +293,80 @@ func (accCfg *cloudAccountConfig) GetServiceConfigByName(name CloudServiceName)
 	return nil, fmt.Errorf("%v service not found for account %v", name, accCfg.namespacedName)
 }
 
+// GetStatus returns account status derived from inventory statistics of the account cloud services.
 func (accCfg *cloudAccountConfig) GetStatus() *cloudv1alpha1.CloudProviderAccountStatus {
-	return accCfg.Status
+	status := &cloudv1alpha1.CloudProviderAccountStatus{}
+	// account config exists only if its credentials are accepted by the plugin.
+	status.Conditions = append(status.Conditions, metav1.Condition{
+		Type:    cloudv1alpha1.AccountConditionCredentialsValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  cloudv1alpha1.AccountReasonCredentialsAccepted,
+		Message: "Credentials accepted by cloud plugin",
+	})
+
+	polled, initialized := true, true
+	var pollErrs []string
+	for name, serviceCfg := range accCfg.GetServiceConfigs() {
+		inventoryStats := serviceCfg.getInventoryStats()
+		stats := inventoryStats.toCRDStats(name, serviceCfg.getType())
+		status.ServiceStats = append(status.ServiceStats, stats)
+
+		if stats.TotalPollCount == 0 {
+			polled = false
+		}
+		if !inventoryStats.IsInventoryInitialized() {
+			initialized = false
+		}
+		if inventoryStats.isLastPollFailed() {
+			pollErrs = append(pollErrs, fmt.Sprintf("%v: %v", name, stats.LastPollError))
+		}
+		// account is polled successfully only when all its services are.
+		if stats.LastSuccessfulPollTime == nil {
+			polled = false
+		} else if status.LastSuccessfulPollTime == nil || stats.LastSuccessfulPollTime.Before(status.LastSuccessfulPollTime) {
+			status.LastSuccessfulPollTime = stats.LastSuccessfulPollTime
+		}
+	}
+	sort.Slice(status.ServiceStats, func(i, j int) bool {
+		return status.ServiceStats[i].Name < status.ServiceStats[j].Name
+	})
+	if !polled {
+		status.LastSuccessfulPollTime = nil
+	}
+
+	inventoryCondition := metav1.Condition{
+		Type:    cloudv1alpha1.AccountConditionInventorySynced,
+		Status:  metav1.ConditionTrue,
+		Reason:  cloudv1alpha1.AccountReasonInventoryPollSucceeded,
+		Message: "Inventory poll succeeded",
+	}
+	if len(pollErrs) != 0 {
+		sort.Strings(pollErrs)
+		status.Error = strings.Join(pollErrs, "; ")
+		inventoryCondition.Status = metav1.ConditionFalse
+		inventoryCondition.Reason = cloudv1alpha1.AccountReasonInventoryPollFailed
+		inventoryCondition.Message = status.Error
+	} else if !polled {
+		inventoryCondition.Status = metav1.ConditionFalse
+		inventoryCondition.Reason = cloudv1alpha1.AccountReasonInventoryPending
+		inventoryCondition.Message = "Inventory not polled yet"
+	}
+	status.Conditions = append(status.Conditions, inventoryCondition)
+
+	enforcementCondition := metav1.Condition{
+		Type:    cloudv1alpha1.AccountConditionSecurityEnforcementReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  cloudv1alpha1.AccountReasonInventoryInitialized,
+		Message: "Inventory initialized",
+	}
+	if !initialized {
+		enforcementCondition.Status = metav1.ConditionFalse
+		enforcementCondition.Reason = cloudv1alpha1.AccountReasonInventoryNotInitialized
+		enforcementCondition.Message = "Security enforcement waits for initial inventory of the account"
+	}
+	status.Conditions = append(status.Conditions, enforcementCondition)
+
+	return status
 }
 
 func (accCfg *cloudAccountConfig) startPeriodicInventorySync() error {
@@ -255,6 +386,7 @@ func (accCfg *cloudAccountConfig) startPeriodicInventorySync() error {
 		}
 		// nolint:errcheck
 		go wait.PollUntil(accCfg.inventoryPollInterval, condFunc, ch)
+		go accCfg.consumeInventoryEvents(ch)
 		accCfg.inventoryChannel = ch
 	}
 
@@ -262,12 +394,34 @@ func (accCfg *cloudAccountConfig) startPeriodicInventorySync() error {
 }
 
 func (accCfg *cloudAccountConfig) stopPeriodicInventorySync() {
+	accCfg.mutex.Lock()
 	if accCfg.inventoryChannel != nil {
 		close(accCfg.inventoryChannel)
 		accCfg.inventoryChannel = nil
 	}
+	accCfg.mutex.Unlock()
 
-	for _, serviceConfig := range accCfg.serviceConfigs {
+	for _, serviceConfig := range accCfg.GetServiceConfigs() {
 		serviceConfig.resetCachedState()
 	}
 }
+
+// setResourceFilters applies a resource selector to services of the account.
+func (accCfg *cloudAccountConfig) setResourceFilters(selector *cloudv1alpha1.CloudEntitySelector) {
+	accCfg.serviceMutex.Lock()
+	defer accCfg.serviceMutex.Unlock()
+
+	accCfg.selectors[selector.GetName()] = selector
+	for _, serviceCfg := range accCfg.serviceConfigs {
+		serviceCfg.setResourceFilters(selector)
+	}
+}
+
+// removeResourceFilters removes a resource selector of the account, such that it is not applied to services added by
+// account update.
+func (accCfg *cloudAccountConfig) removeResourceFilters(selectorName string) {
+	accCfg.serviceMutex.Lock()
+	defer accCfg.serviceMutex.Unlock()
+
+	delete(accCfg.selectors, selectorName)
+}
@@ -24,6 +24,7 @@ import (
 
 	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
 	"antrea.io/nephe/pkg/logging"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 // CloudCommonHelperInterface interface needs to be implemented by each cloud-plugin. It provides a way to inject
@@ -35,6 +36,12 @@ type CloudCommonHelperInterface interface {
 	GetCloudCredentialsComparatorFunc() CloudCredentialComparatorFunc
 }
 
+// CloudInventoryEventHelperInterface is implemented, in addition to CloudCommonHelperInterface, by cloud-plugins
+// supporting event driven inventory.
+type CloudInventoryEventHelperInterface interface {
+	GetInventoryEventSourceCreateFunc() CloudInventoryEventSourceCreatorFunc
+}
+
 // CloudCommonInterface implements functionality common across all supported cloud-plugins. Each cloud plugin uses
 // this interface by composition.
 type CloudCommonInterface interface {
@@ -44,6 +51,7 @@ type CloudCommonInterface interface {
 	GetCloudAccountComputeResourceCRDs(namespacedName *types.NamespacedName) ([]*cloudv1alpha1.VirtualMachine,
 		error)
 	GetAllCloudAccountsComputeResourceCRDs() ([]*cloudv1alpha1.VirtualMachine, error)
+	GetCloudAccountVpcCRDs(namespacedName *types.NamespacedName) ([]*cloudv1alpha1.Vpc, error)
 
 	AddCloudAccount(account *cloudv1alpha1.CloudProviderAccount, credentials interface{}) error
 	RemoveCloudAccount(namespacedName *types.NamespacedName)
@@ -52,6 +60,7 @@ type CloudCommonInterface interface {
 	RemoveSelector(accNamespacedName *types.NamespacedName, selectorName string)
 
 	GetStatus(accNamespacedName *types.NamespacedName) (*cloudv1alpha1.CloudProviderAccountStatus, error)
+	WatchInventory(accNamespacedName *types.NamespacedName, stopCh <-chan struct{}) (<-chan struct{}, error)
 }
 
 type cloudCommon struct {
@@ -106,12 +115,18 @@ func (c *cloudCommon) deleteCloudAccount(namespacedName *types.NamespacedName) {
 }
 
 func (c *cloudCommon) RemoveCloudAccount(namespacedName *types.NamespacedName) {
-	_, found := c.GetCloudAccountByName(namespacedName)
+	accCfg, found := c.GetCloudAccountByName(namespacedName)
 	if !found {
 		c.logger().V(0).Info("unable to find cloud account", "account", *namespacedName)
 		return
 	}
 	c.deleteCloudAccount(namespacedName)
+
+	services := make([]string, 0)
+	for name := range accCfg.GetServiceConfigs() {
+		services = append(services, string(name))
+	}
+	metrics.DeleteAccountMetrics(namespacedName.Namespace, namespacedName.Name, services)
 }
 
 func (c *cloudCommon) GetCloudAccountByName(namespacedName *types.NamespacedName) (CloudAccountInterface, bool) {
@@ -152,6 +167,28 @@ func (c *cloudCommon) GetCloudAccountComputeResourceCRDs(accountNamespacedName *
 	return computeCRDs, nil
 }
 
+func (c *cloudCommon) GetCloudAccountVpcCRDs(accountNamespacedName *types.NamespacedName) ([]*cloudv1alpha1.Vpc, error) {
+	accCfg, found := c.GetCloudAccountByName(accountNamespacedName)
+	if !found {
+		return nil, fmt.Errorf("unable to find cloud account:%v", *accountNamespacedName)
+	}
+	namespace := accCfg.GetNamespacedName().Namespace
+
+	var vpcCRDs []*cloudv1alpha1.Vpc
+	serviceConfigs := accCfg.GetServiceConfigs()
+	for _, serviceConfig := range serviceConfigs {
+		if serviceConfig.getType() == CloudServiceTypeCompute {
+			resourceCRDs := serviceConfig.getResourceCRDs(namespace)
+			vpcCRDs = append(vpcCRDs, resourceCRDs.vpcs...)
+		}
+	}
+
+	c.logger().V(1).Info("account CRDs", "account", accountNamespacedName, "service-type", CloudServiceTypeCompute,
+		"vpc", len(vpcCRDs))
+
+	return vpcCRDs, nil
+}
+
 func (c *cloudCommon) GetAllCloudAccountsComputeResourceCRDs() ([]*cloudv1alpha1.VirtualMachine,
 	error) {
 	var err error
@@ -174,9 +211,7 @@ func (c *cloudCommon) AddSelector(accountNamespacedName *types.NamespacedName, s
 		return fmt.Errorf("account not found %v", *accountNamespacedName)
 	}
 
-	for _, serviceCfg := range accCfg.GetServiceConfigs() {
-		serviceCfg.setResourceFilters(selector)
-	}
+	accCfg.setResourceFilters(selector)
 
 	err := accCfg.startPeriodicInventorySync()
 	if err != nil {
@@ -193,6 +228,7 @@ func (c *cloudCommon) RemoveSelector(accNamespacedName *types.NamespacedName, se
 		return
 	}
 
+	accCfg.removeResourceFilters(selectorName)
 	accCfg.stopPeriodicInventorySync()
 }
 
@@ -204,3 +240,14 @@ func (c *cloudCommon) GetStatus(accountNamespacedName *types.NamespacedName) (*c
 
 	return accCfg.GetStatus(), nil
 }
+
+// WatchInventory returns a channel notified when inventory of an account is updated by cloud resource events, until
+// stopCh is closed.
+func (c *cloudCommon) WatchInventory(accNamespacedName *types.NamespacedName, stopCh <-chan struct{}) (<-chan struct{}, error) {
+	accCfg, found := c.GetCloudAccountByName(accNamespacedName)
+	if !found {
+		return nil, fmt.Errorf("account not found %v", *accNamespacedName)
+	}
+
+	return accCfg.watchInventory(stopCh), nil
+}
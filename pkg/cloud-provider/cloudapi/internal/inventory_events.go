@@ -0,0 +1,215 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// inventoryEventRetryInterval is the interval to receive inventory events again after a failure, or to check for an
+// inventory event source when none is configured for the account.
+var inventoryEventRetryInterval = 10 * time.Second
+
+type InventoryEventType string
+
+const (
+	// InventoryEventUpdated indicates a cloud resource is created, or its state or attributes are changed.
+	InventoryEventUpdated = InventoryEventType("Updated")
+	// InventoryEventDeleted indicates a cloud resource is deleted.
+	InventoryEventDeleted = InventoryEventType("Deleted")
+)
+
+// InventoryEvent is a cloud resource event received from an InventoryEventSource.
+type InventoryEvent struct {
+	Type InventoryEventType
+	// ResourceID is the cloud assigned identifier of the resource.
+	ResourceID string
+	// Region of the resource, empty if the event does not carry it.
+	Region string
+	// Receipt identifies the message of the event in its source. Events of the same message share a receipt.
+	Receipt string
+}
+
+// InventoryEventSource provides cloud resource events of an account, e.g. from a cloud message queue. Events are
+// delivered at least once, until acknowledged.
+type InventoryEventSource interface {
+	// ReceiveEvents waits, up to a source defined duration, for events and returns them. Messages not describing
+	// cloud resource events are removed from the source.
+	ReceiveEvents() ([]*InventoryEvent, error)
+	// AckEvents removes messages of processed events from the source.
+	AckEvents(events []*InventoryEvent) error
+}
+
+// CloudInventoryEventSourceCreatorFunc returns inventory event source of an account, nil if the account is not
+// configured with one.
+type CloudInventoryEventSourceCreatorFunc func(namespacedName *types.NamespacedName, cloudConvertedCredentials interface{},
+	helper interface{}) (InventoryEventSource, error)
+
+// CloudServiceEventInterface is implemented by cloud-services supporting event driven inventory. Resource events are
+// applied to service cache in between periodic inventory, which corrects the cache for any event missed.
+type CloudServiceEventInterface interface {
+	// ApplyInventoryEvents updates resources saved in CloudServiceResourcesCache with resource events. It returns true
+	// if the cache is updated.
+	ApplyInventoryEvents(events []*InventoryEvent) (bool, error)
+}
+
+func (cfg *CloudServiceCommon) applyInventoryEvents(events []*InventoryEvent) (bool, error) {
+	cfg.mutex.Lock()
+	defer cfg.mutex.Unlock()
+
+	serviceEventInterface, ok := cfg.serviceInterface.(CloudServiceEventInterface)
+	if !ok {
+		return false, nil
+	}
+	return serviceEventInterface.ApplyInventoryEvents(events)
+}
+
+// inventoryWatcher is notified of inventory updates of an account until its stopCh is closed.
+type inventoryWatcher struct {
+	ch     chan struct{}
+	stopCh <-chan struct{}
+}
+
+// newInventoryEventSource creates inventory event source of an account, if the cloud plugin supports one.
+func (c *cloudCommon) newInventoryEventSource(namespacedName *types.NamespacedName, cloudConvertedCredentials interface{}) (
+	InventoryEventSource, error) {
+	eventSourceHelper, ok := c.commonHelper.(CloudInventoryEventHelperInterface)
+	if !ok {
+		return nil, nil
+	}
+	eventSourceCreateFunc := eventSourceHelper.GetInventoryEventSourceCreateFunc()
+	if eventSourceCreateFunc == nil {
+		return nil, nil
+	}
+	eventSource, err := eventSourceCreateFunc(namespacedName, cloudConvertedCredentials, c.cloudSpecificHelper)
+	if err != nil {
+		return nil, fmt.Errorf("error creating inventory event source for account %v: %v", namespacedName, err)
+	}
+	return eventSource, nil
+}
+
+func (accCfg *cloudAccountConfig) getInventoryEventSource() InventoryEventSource {
+	accCfg.eventMutex.Lock()
+	defer accCfg.eventMutex.Unlock()
+
+	return accCfg.inventoryEventSource
+}
+
+func (accCfg *cloudAccountConfig) setInventoryEventSource(eventSource InventoryEventSource) {
+	accCfg.eventMutex.Lock()
+	defer accCfg.eventMutex.Unlock()
+
+	accCfg.inventoryEventSource = eventSource
+}
+
+// consumeInventoryEvents receives events from the inventory event source of the account and applies them to caches
+// of the account services, until stopCh is closed.
+func (accCfg *cloudAccountConfig) consumeInventoryEvents(stopCh <-chan struct{}) {
+	for {
+		interval := inventoryEventRetryInterval
+		if eventSource := accCfg.getInventoryEventSource(); eventSource != nil {
+			if err := accCfg.processInventoryEvents(eventSource, stopCh); err != nil {
+				accCfg.logger().Error(err, "failed to process inventory events", "account", accCfg.namespacedName)
+			} else {
+				interval = 0
+			}
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// processInventoryEvents receives events once and applies them to service caches. Events are acknowledged only if
+// applied to all services, so that events failed to apply are received again.
+func (accCfg *cloudAccountConfig) processInventoryEvents(eventSource InventoryEventSource, stopCh <-chan struct{}) error {
+	events, err := eventSource.ReceiveEvents()
+	if err != nil {
+		return fmt.Errorf("failed to receive inventory events: %v", err)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	accCfg.mutex.Lock()
+	defer accCfg.mutex.Unlock()
+
+	select {
+	case <-stopCh:
+		// inventory sync is stopped and service caches are cleared.
+		return nil
+	default:
+	}
+
+	updated := false
+	for _, serviceCfg := range accCfg.GetServiceConfigs() {
+		if hasFilters, _ := serviceCfg.hasFiltersConfigured(); !hasFilters {
+			continue
+		}
+		serviceUpdated, e := serviceCfg.applyInventoryEvents(events)
+		if e != nil {
+			err = multierr.Append(err, fmt.Errorf("service %v: %v", serviceCfg.getName(), e))
+			continue
+		}
+		updated = updated || serviceUpdated
+	}
+	accCfg.logger().V(1).Info("inventory events applied", "account", accCfg.namespacedName, "events", len(events),
+		"updated", updated)
+	if updated {
+		accCfg.notifyInventoryWatchers()
+	}
+	if err != nil {
+		return err
+	}
+	return eventSource.AckEvents(events)
+}
+
+// watchInventory returns a channel notified when inventory of the account is updated by cloud resource events, until
+// stopCh is closed. Notifications are coalesced if not yet received.
+func (accCfg *cloudAccountConfig) watchInventory(stopCh <-chan struct{}) <-chan struct{} {
+	accCfg.eventMutex.Lock()
+	defer accCfg.eventMutex.Unlock()
+
+	watcher := &inventoryWatcher{ch: make(chan struct{}, 1), stopCh: stopCh}
+	accCfg.inventoryWatchers = append(accCfg.inventoryWatchers, watcher)
+	return watcher.ch
+}
+
+// notifyInventoryWatchers notifies watchers of the account inventory, and removes stopped watchers.
+func (accCfg *cloudAccountConfig) notifyInventoryWatchers() {
+	accCfg.eventMutex.Lock()
+	defer accCfg.eventMutex.Unlock()
+
+	watchers := make([]*inventoryWatcher, 0, len(accCfg.inventoryWatchers))
+	for _, watcher := range accCfg.inventoryWatchers {
+		select {
+		case <-watcher.stopCh:
+			continue
+		default:
+		}
+		select {
+		case watcher.ch <- struct{}{}:
+		default:
+		}
+		watchers = append(watchers, watcher)
+	}
+	accCfg.inventoryWatchers = watchers
+}
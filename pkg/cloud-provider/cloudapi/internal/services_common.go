@@ -15,9 +15,12 @@
 package internal
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
 )
 
@@ -61,6 +64,16 @@ type CloudServiceInterface interface {
 	ResetCachedState()
 }
 
+// GetRegionalServiceName returns name of a service in region. Services of the first region of the account are not
+// qualified by region, such that their names, hence their cached inventory and statistics, are kept when regions are
+// added to or removed from the account.
+func GetRegionalServiceName(name CloudServiceName, region string, regions []string) CloudServiceName {
+	if len(regions) == 0 || region == regions[0] {
+		return name
+	}
+	return CloudServiceName(fmt.Sprintf("%v/%v", name, region))
+}
+
 func (cfg *CloudServiceCommon) updateServiceConfig(newConfig CloudServiceInterface) {
 	cfg.mutex.Lock()
 	defer cfg.mutex.Unlock()
@@ -120,6 +133,7 @@ func (cfg *CloudServiceCommon) resetCachedState() {
 
 type CloudServiceResourceCRDs struct {
 	virtualMachines []*cloudv1alpha1.VirtualMachine
+	vpcs            []*cloudv1alpha1.Vpc
 }
 
 // SetComputeResourceCRDs sets Service resource CRDs for accessing it from cloudCommon interface.
@@ -127,6 +141,11 @@ func (s *CloudServiceResourceCRDs) SetComputeResourceCRDs(vms []*cloudv1alpha1.V
 	s.virtualMachines = vms
 }
 
+// SetVpcResourceCRDs sets Service vpc CRDs for accessing it from cloudCommon interface.
+func (s *CloudServiceResourceCRDs) SetVpcResourceCRDs(vpcs []*cloudv1alpha1.Vpc) {
+	s.vpcs = vpcs
+}
+
 // CloudServiceResourcesCache is cache used by all services. Each service can maintain its resources specific cache by
 // updating the snapshot.
 type CloudServiceResourcesCache struct {
@@ -156,11 +175,12 @@ func (cache *CloudServiceResourcesCache) GetSnapshot() interface{} {
 }
 
 type CloudServiceStats struct {
-	mutex           sync.Mutex
-	totalPollCnt    uint64
-	successPollCnt  uint64
-	lastPollErr     error
-	lastPollErrTime time.Time
+	mutex               sync.Mutex
+	totalPollCnt        uint64
+	successPollCnt      uint64
+	lastPollErr         error
+	lastPollErrTime     time.Time
+	lastSuccessPollTime time.Time
 }
 
 func (s *CloudServiceStats) IsInventoryInitialized() bool {
@@ -177,6 +197,7 @@ func (s *CloudServiceStats) UpdateInventoryPollStats(err error) {
 	s.totalPollCnt++
 	if err == nil {
 		s.successPollCnt++
+		s.lastSuccessPollTime = time.Now()
 		return
 	}
 	s.lastPollErrTime = time.Now()
@@ -191,4 +212,34 @@ func (s *CloudServiceStats) ResetInventoryPollStats() {
 	s.successPollCnt = 0
 	s.lastPollErrTime = time.Time{}
 	s.lastPollErr = nil
+	s.lastSuccessPollTime = time.Time{}
+}
+
+// isLastPollFailed returns true if the most recent inventory poll failed.
+func (s *CloudServiceStats) isLastPollFailed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.lastPollErr != nil && s.lastPollErrTime.After(s.lastSuccessPollTime)
+}
+
+// toCRDStats converts inventory statistics of the service to CloudServiceStats reported in account status.
+func (s *CloudServiceStats) toCRDStats(name CloudServiceName, serviceType CloudServiceType) cloudv1alpha1.CloudServiceStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := cloudv1alpha1.CloudServiceStats{
+		Name:                string(name),
+		Type:                string(serviceType),
+		TotalPollCount:      s.totalPollCnt,
+		SuccessfulPollCount: s.successPollCnt,
+	}
+	if !s.lastSuccessPollTime.IsZero() {
+		stats.LastSuccessfulPollTime = &metav1.Time{Time: s.lastSuccessPollTime}
+	}
+	if s.lastPollErr != nil {
+		stats.LastPollError = s.lastPollErr.Error()
+		stats.LastPollErrorTime = &metav1.Time{Time: s.lastPollErrTime}
+	}
+	return stats
 }
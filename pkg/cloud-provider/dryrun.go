@@ -0,0 +1,250 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
+	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+)
+
+// EnforcementDryRun, if true, computes cloud security groups of all accounts without applying them to the cloud.
+var EnforcementDryRun bool
+
+var (
+	dryRunMutex sync.Mutex
+	// dryRunAccounts are accounts with dry-run enabled in spec.
+	dryRunAccounts = make(map[types.NamespacedName]struct{})
+	// plannedSecurityGroups are intended cloud security groups of dry-run accounts, keyed by account and security group.
+	plannedSecurityGroups = make(map[types.NamespacedName]map[string]*cloudv1alpha1.PlannedSecurityGroup)
+)
+
+// SetAccountDryRun sets dry-run mode of an account. Planned security groups of the account are cleared when the account
+// is no longer in dry-run mode.
+func SetAccountDryRun(namespacedName *types.NamespacedName, dryRun bool) {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+
+	if dryRun {
+		dryRunAccounts[*namespacedName] = struct{}{}
+		return
+	}
+	delete(dryRunAccounts, *namespacedName)
+	if !EnforcementDryRun {
+		delete(plannedSecurityGroups, *namespacedName)
+	}
+}
+
+// RemoveAccountDryRun removes dry-run mode and planned security groups of an account.
+func RemoveAccountDryRun(namespacedName *types.NamespacedName) {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+
+	delete(dryRunAccounts, *namespacedName)
+	delete(plannedSecurityGroups, *namespacedName)
+}
+
+// GetPlannedSecurityGroups returns intended cloud security groups of a dry-run account, sorted by VPC and name.
+func GetPlannedSecurityGroups(namespacedName *types.NamespacedName) []cloudv1alpha1.PlannedSecurityGroup {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+
+	sgs := plannedSecurityGroups[*namespacedName]
+	if len(sgs) == 0 {
+		return nil
+	}
+	ret := make([]cloudv1alpha1.PlannedSecurityGroup, 0, len(sgs))
+	for _, sg := range sgs {
+		ret = append(ret, *sg.DeepCopy())
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].VpcID != ret[j].VpcID {
+			return ret[i].VpcID < ret[j].VpcID
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// IsSecurityGroupDryRun returns true if the account managing the VPC of a security group is in dry-run mode, such that
+// operations on the security group are planned instead of applied to the cloud.
+func IsSecurityGroupDryRun(addressGroupIdentifier *securitygroup.CloudResourceID) bool {
+	for _, providerType := range GetSupportedCloudProviderTypes() {
+		cloudInterface, err := GetCloudInterface(providerType)
+		if err != nil {
+			continue
+		}
+		if _, ok := getDryRunAccount(cloudInterface, addressGroupIdentifier); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getDryRunAccount returns the account managing the VPC of a security group if the account is in dry-run mode.
+func getDryRunAccount(cloudInterface cloudcommon.CloudInterface, addressGroupIdentifier *securitygroup.CloudResourceID) (
+	*types.NamespacedName, bool) {
+	namespacedName := cloudInterface.GetVpcAccount(addressGroupIdentifier.Vpc)
+	if namespacedName == nil {
+		return nil, false
+	}
+
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+	if _, ok := dryRunAccounts[*namespacedName]; ok || EnforcementDryRun {
+		return namespacedName, true
+	}
+	return nil, false
+}
+
+// recordPlannedOperation records an intended operation on a cloud security group of a dry-run account. update, if not
+// nil, is applied to the planned security group after the operation is recorded.
+func recordPlannedOperation(namespacedName *types.NamespacedName, addressGroupIdentifier *securitygroup.CloudResourceID,
+	membershipOnly bool, operation string, update func(sg *cloudv1alpha1.PlannedSecurityGroup)) {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+
+	sgs, ok := plannedSecurityGroups[*namespacedName]
+	if !ok {
+		sgs = make(map[string]*cloudv1alpha1.PlannedSecurityGroup)
+		plannedSecurityGroups[*namespacedName] = sgs
+	}
+	name := addressGroupIdentifier.GetCloudName(membershipOnly)
+	key := addressGroupIdentifier.Vpc + "/" + name
+	sg, ok := sgs[key]
+	if !ok {
+		sg = &cloudv1alpha1.PlannedSecurityGroup{Name: name, VpcID: addressGroupIdentifier.Vpc, MembershipOnly: membershipOnly}
+		sgs[key] = sg
+	}
+
+	lastOperation := ""
+	if len(sg.Operations) > 0 {
+		lastOperation = sg.Operations[len(sg.Operations)-1]
+	}
+	switch {
+	case operation == cloudv1alpha1.SecurityGroupOperationDelete:
+		sg.Operations = []string{operation}
+		sg.Members = nil
+		sg.IngressRules = nil
+		sg.EgressRules = nil
+	case lastOperation == cloudv1alpha1.SecurityGroupOperationDelete:
+		sg.Operations = []string{operation}
+	case !containsString(sg.Operations, operation):
+		sg.Operations = append(sg.Operations, operation)
+	}
+	if update != nil {
+		update(sg)
+	}
+	sg.LastOperationTime = metav1.Now()
+}
+
+func containsString(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// plannedMembers returns sorted string representations of security group members.
+func plannedMembers(members []*securitygroup.CloudResource) []string {
+	ret := make([]string, 0, len(members))
+	for _, member := range members {
+		ret = append(ret, member.String())
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// plannedIngressRules returns string representations of ingress rules, e.g. "Allow tcp/22 from 10.0.0.0/8".
+func plannedIngressRules(rules []*securitygroup.IngressRule) []string {
+	ret := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		ret = append(ret, formatPlannedRule(rule.Action, rule.Protocol, rule.FromPort, rule.FromEndPort, rule.ICMPType,
+			rule.ICMPCode, "from", rule.FromSrcIP, rule.FromSecurityGroups, rule.FromCloudServices))
+	}
+	return ret
+}
+
+// plannedEgressRules returns string representations of egress rules, e.g. "Deny udp/53 to nephe-ag-web".
+func plannedEgressRules(rules []*securitygroup.EgressRule) []string {
+	ret := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		ret = append(ret, formatPlannedRule(rule.Action, rule.Protocol, rule.ToPort, rule.ToEndPort, rule.ICMPType,
+			rule.ICMPCode, "to", rule.ToDstIP, rule.ToSecurityGroups, rule.ToCloudServices))
+	}
+	return ret
+}
+
+func formatPlannedRule(action securitygroup.RuleAction, protocol, port, endPort, icmpType, icmpCode *int, direction string,
+	ips []*net.IPNet, sgs []*securitygroup.CloudResourceID, cloudServices []string) string {
+	if action == "" {
+		action = securitygroup.RuleActionAllow
+	}
+	proto := "any"
+	if protocol != nil {
+		switch *protocol {
+		case 1:
+			proto = "icmp"
+		case 6:
+			proto = "tcp"
+		case 17:
+			proto = "udp"
+		default:
+			proto = strconv.Itoa(*protocol)
+		}
+	}
+	if port != nil {
+		proto += "/" + strconv.Itoa(*port)
+		if endPort != nil && *endPort != *port {
+			proto += "-" + strconv.Itoa(*endPort)
+		}
+	}
+	if icmpType != nil || icmpCode != nil {
+		proto += "/type="
+		if icmpType != nil {
+			proto += strconv.Itoa(*icmpType)
+		} else {
+			proto += "any"
+		}
+		if icmpCode != nil {
+			proto += ",code=" + strconv.Itoa(*icmpCode)
+		}
+	}
+	peers := make([]string, 0, len(ips)+len(sgs)+len(cloudServices))
+	for _, ip := range ips {
+		peers = append(peers, ip.String())
+	}
+	for _, sg := range sgs {
+		peers = append(peers, sg.GetCloudName(true))
+	}
+	peers = append(peers, cloudServices...)
+	peer := "any"
+	if len(peers) > 0 {
+		peer = strings.Join(peers, ",")
+	}
+	return fmt.Sprintf("%v %v %v %v", action, proto, direction, peer)
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
+	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+)
+
+// fakeCloud manages a single VPC for an account. Security group calls to fakeCloud panic, as they are not
+// expected in dry-run mode.
+type fakeCloud struct {
+	cloudcommon.CloudInterface
+	vpc     string
+	account *types.NamespacedName
+}
+
+func (c *fakeCloud) IsVirtualPrivateCloudPresent(vpc string) bool {
+	return vpc == c.vpc
+}
+
+func (c *fakeCloud) GetVpcAccount(vpc string) *types.NamespacedName {
+	if vpc != c.vpc {
+		return nil
+	}
+	return c.account
+}
+
+var _ = Describe("Dry-run", func() {
+	var (
+		fakeProviderType = cloudcommon.ProviderType("fake")
+		account          = &types.NamespacedName{Namespace: "default", Name: "account"}
+		cloud            = &fakeCloud{vpc: "vpc-1", account: account}
+		sgImpl           = &SecurityGroupImpl{}
+		atID             = &securitygroup.CloudResourceID{Name: "AT", Vpc: "vpc-1"}
+		agID             = &securitygroup.CloudResourceID{Name: "ag", Vpc: "vpc-1"}
+	)
+
+	BeforeEach(func() {
+		registerCloudProvider(fakeProviderType, cloud)
+		SetAccountDryRun(account, true)
+	})
+
+	AfterEach(func() {
+		RemoveAccountDryRun(account)
+		EnforcementDryRun = false
+		providersMutex.Lock()
+		delete(providers, fakeProviderType)
+		providersMutex.Unlock()
+	})
+
+	It("Should record planned security groups without calling cloud", func() {
+		tcp, port, endPort := 6, 22, 23
+		_, cidr, _ := net.ParseCIDR("10.0.0.0/8")
+		members := []*securitygroup.CloudResource{
+			{Type: securitygroup.CloudResourceTypeVM, Name: securitygroup.CloudResourceID{Name: "vm-2", Vpc: "vpc-1"}},
+			{Type: securitygroup.CloudResourceTypeVM, Name: securitygroup.CloudResourceID{Name: "vm-1", Vpc: "vpc-1"}},
+		}
+		ingress := []*securitygroup.IngressRule{{Protocol: &tcp, FromPort: &port, FromEndPort: &endPort,
+			FromSrcIP: []*net.IPNet{cidr}, FromSecurityGroups: []*securitygroup.CloudResourceID{agID}}}
+		egress := []*securitygroup.EgressRule{{Action: securitygroup.RuleActionDeny}}
+
+		Expect(<-sgImpl.CreateSecurityGroup(atID, false)).ToNot(HaveOccurred())
+		Expect(<-sgImpl.UpdateSecurityGroupMembers(atID, members, false)).ToNot(HaveOccurred())
+		Expect(<-sgImpl.UpdateSecurityGroupRules(atID, ingress, egress)).ToNot(HaveOccurred())
+		Expect(<-sgImpl.CreateSecurityGroup(agID, true)).ToNot(HaveOccurred())
+
+		sgs := GetPlannedSecurityGroups(account)
+		Expect(sgs).To(HaveLen(2))
+		Expect(sgs[0].Name).To(Equal(agID.GetCloudName(true)))
+		Expect(sgs[0].MembershipOnly).To(BeTrue())
+		Expect(sgs[0].Operations).To(Equal([]string{cloudv1alpha1.SecurityGroupOperationCreate}))
+		Expect(sgs[1].Name).To(Equal(atID.GetCloudName(false)))
+		Expect(sgs[1].VpcID).To(Equal("vpc-1"))
+		Expect(sgs[1].Operations).To(Equal([]string{cloudv1alpha1.SecurityGroupOperationCreate,
+			cloudv1alpha1.SecurityGroupOperationUpdateMembers, cloudv1alpha1.SecurityGroupOperationUpdateRules}))
+		Expect(sgs[1].Members).To(Equal([]string{"VirtualMachine/vm-1/vpc-1", "VirtualMachine/vm-2/vpc-1"}))
+		Expect(sgs[1].IngressRules).To(Equal([]string{"Allow tcp/22-23 from 10.0.0.0/8,nephe-ag-ag"}))
+		Expect(sgs[1].EgressRules).To(Equal([]string{"Deny any to any"}))
+		Expect(sgs[1].LastOperationTime.IsZero()).To(BeFalse())
+
+		Expect(<-sgImpl.DeleteSecurityGroup(atID, false)).ToNot(HaveOccurred())
+		sgs = GetPlannedSecurityGroups(account)
+		Expect(sgs[1].Operations).To(Equal([]string{cloudv1alpha1.SecurityGroupOperationDelete}))
+		Expect(sgs[1].Members).To(BeEmpty())
+		Expect(sgs[1].IngressRules).To(BeEmpty())
+	})
+
+	It("Should clear planned security groups when account leaves dry-run", func() {
+		Expect(<-sgImpl.CreateSecurityGroup(atID, false)).ToNot(HaveOccurred())
+		Expect(GetPlannedSecurityGroups(account)).To(HaveLen(1))
+		Expect(IsSecurityGroupDryRun(atID)).To(BeTrue())
+
+		SetAccountDryRun(account, false)
+		Expect(GetPlannedSecurityGroups(account)).To(BeEmpty())
+		_, ok := getDryRunAccount(cloud, atID)
+		Expect(ok).To(BeFalse())
+		Expect(IsSecurityGroupDryRun(atID)).To(BeFalse())
+	})
+
+	It("Should apply controller dry-run to all accounts", func() {
+		SetAccountDryRun(account, false)
+		EnforcementDryRun = true
+		Expect(<-sgImpl.CreateSecurityGroup(atID, false)).ToNot(HaveOccurred())
+		Expect(GetPlannedSecurityGroups(account)).To(HaveLen(1))
+
+		SetAccountDryRun(account, false)
+		Expect(GetPlannedSecurityGroups(account)).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,338 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
+	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+	"antrea.io/nephe/pkg/cloud-provider/utils"
+	"antrea.io/nephe/pkg/controllers/config"
+)
+
+const (
+	importedGroupAPIVersion         = "crd.antrea.io/v1alpha3"
+	importedGroupKind               = "Group"
+	importedNetworkPolicyAPIVersion = "crd.antrea.io/v1alpha1"
+	importedNetworkPolicyKind       = "NetworkPolicy"
+	// importedNetworkPolicyPriority is the priority of imported Antrea NetworkPolicies within the application tier.
+	importedNetworkPolicyPriority = float64(10)
+	// importedNameMaxLength is the maximum length of the cloud name part of imported object names.
+	importedNameMaxLength = 40
+)
+
+var importedNameInvalidChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// SecurityGroupImport is the result of importing cloud security groups of an account. Objects are Antrea Groups and
+// Antrea NetworkPolicies equivalent to the cloud security groups, Warnings describe cloud security groups, rules or
+// rule peers which are not imported.
+type SecurityGroupImport struct {
+	Objects  []*unstructured.Unstructured
+	Warnings []string
+}
+
+// ImportSecurityGroups returns Antrea Groups and Antrea NetworkPolicies equivalent to cloud security groups, not
+// created by nephe, attached to virtual machines of an account inventory. Each security group attached to virtual
+// machines is imported as an Antrea Group selecting its virtual machines, and each security group with rules is
+// imported as an Antrea NetworkPolicy applied to that Group, so that nephe enforces the same rules once the security
+// groups are detached. Objects are returned for review, they are not created.
+func ImportSecurityGroups(cloudInterface cloudcommon.CloudInterface, accNamespacedName *types.NamespacedName) (
+	*SecurityGroupImport, error) {
+	sgs, err := cloudInterface.GetUnmanagedSecurityGroups(accNamespacedName)
+	if err != nil {
+		return nil, err
+	}
+	vms, err := cloudInterface.InstancesGivenProviderAccount(accNamespacedName)
+	if err != nil {
+		return nil, err
+	}
+	return generateSecurityGroupImport(accNamespacedName.Namespace, sgs, vms), nil
+}
+
+// generateSecurityGroupImport generates Antrea Groups and Antrea NetworkPolicies in namespace for security groups sgs,
+// whose members are network interfaces of virtual machines vms.
+func generateSecurityGroupImport(namespace string, sgs []securitygroup.UnmanagedSecurityGroup,
+	vms []*cloudv1alpha1.VirtualMachine) *SecurityGroupImport {
+	result := &SecurityGroupImport{}
+
+	nicToVMName := make(map[string]string)
+	vmNameToNICs := make(map[string]map[string]struct{})
+	for _, vm := range vms {
+		nics := make(map[string]struct{})
+		for _, nic := range vm.Status.NetworkInterfaces {
+			nicToVMName[strings.ToLower(nic.Name)] = vm.Name
+			nics[strings.ToLower(nic.Name)] = struct{}{}
+		}
+		vmNameToNICs[vm.Name] = nics
+	}
+
+	// import security groups with members as Groups.
+	usedNames := make(map[string]struct{})
+	sgResourceToGroupName := make(map[securitygroup.CloudResourceID]string)
+	var groups []*unstructured.Unstructured
+	var importedSGs []securitygroup.UnmanagedSecurityGroup
+	for _, sg := range sgs {
+		memberNICs := make(map[string]struct{})
+		memberVMNames := make(map[string]struct{})
+		for _, member := range sg.Members {
+			nic := strings.ToLower(member.Name.Name)
+			vmName, found := nicToVMName[nic]
+			if !found {
+				continue
+			}
+			memberNICs[nic] = struct{}{}
+			memberVMNames[vmName] = struct{}{}
+		}
+		if len(memberVMNames) == 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("security group %v (%v) skipped: not attached to "+
+				"virtual machines of the account inventory", sg.CloudName, sg.CloudID))
+			continue
+		}
+		vmNames := make([]string, 0, len(memberVMNames))
+		for vmName := range memberVMNames {
+			vmNames = append(vmNames, vmName)
+			for nic := range vmNameToNICs[vmName] {
+				if _, found := memberNICs[nic]; !found {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("security group %v (%v) is attached to some "+
+						"network interfaces of virtual machine %v, imported policy applies to all its network interfaces",
+						sg.CloudName, sg.CloudID, vmName))
+					break
+				}
+			}
+		}
+		sort.Strings(vmNames)
+
+		name := generateImportedName(sg, usedNames)
+		sgResourceToGroupName[sg.Resource] = name
+		groups = append(groups, generateImportedGroup(namespace, name, sg, vmNames))
+		importedSGs = append(importedSGs, sg)
+	}
+
+	// import rules of security groups as NetworkPolicies applied to their Groups.
+	var policies []*unstructured.Unstructured
+	for _, sg := range importedSGs {
+		for _, rule := range sg.UnsupportedRules {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("security group %v (%v) %v not imported", sg.CloudName,
+				sg.CloudID, rule))
+		}
+		if sg.MembershipOnly {
+			continue
+		}
+		policy, warnings := generateImportedNetworkPolicy(namespace, sgResourceToGroupName[sg.Resource], sg,
+			sgResourceToGroupName)
+		result.Warnings = append(result.Warnings, warnings...)
+		policies = append(policies, policy)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].GetName() < groups[j].GetName()
+	})
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].GetName() < policies[j].GetName()
+	})
+	result.Objects = append(groups, policies...)
+	return result
+}
+
+// generateImportedName returns a unique DNS-1123 name for imported objects of a security group.
+func generateImportedName(sg securitygroup.UnmanagedSecurityGroup, usedNames map[string]struct{}) string {
+	base := importedNameInvalidChars.ReplaceAllString(strings.ToLower(sg.CloudName), "-")
+	if len(base) > importedNameMaxLength {
+		base = base[:importedNameMaxLength]
+	}
+	base = strings.Trim(base, "-")
+	if len(base) == 0 {
+		base = "sg"
+	}
+	name := utils.GenerateShortResourceIdentifier(sg.CloudID, base)
+	uniqueName := name
+	for i := 1; ; i++ {
+		if _, found := usedNames[uniqueName]; !found {
+			break
+		}
+		uniqueName = fmt.Sprintf("%v-%d", name, i)
+	}
+	usedNames[uniqueName] = struct{}{}
+	return uniqueName
+}
+
+func generateImportedObject(apiVersion, kind, namespace, name string, sg securitygroup.UnmanagedSecurityGroup,
+	spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetAnnotations(map[string]string{
+		cloudcommon.AnnotationCloudAssignedIDKey:   sg.CloudID,
+		cloudcommon.AnnotationCloudAssignedNameKey: sg.CloudName,
+	})
+	return obj
+}
+
+// generateImportedGroup returns a Group selecting virtual machines vmNames.
+func generateImportedGroup(namespace, name string, sg securitygroup.UnmanagedSecurityGroup,
+	vmNames []string) *unstructured.Unstructured {
+	values := make([]interface{}, 0, len(vmNames))
+	for _, vmName := range vmNames {
+		values = append(values, strings.ToLower(vmName))
+	}
+	spec := map[string]interface{}{
+		"externalEntitySelector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				config.ExternalEntityLabelKeyKind: strings.ToLower(cloudcommon.VirtualMachineCRDKind),
+			},
+			"matchExpressions": []interface{}{
+				map[string]interface{}{
+					"key":      config.ExternalEntityLabelKeyName,
+					"operator": "In",
+					"values":   values,
+				},
+			},
+		},
+	}
+	return generateImportedObject(importedGroupAPIVersion, importedGroupKind, namespace, name, sg, spec)
+}
+
+// generateImportedNetworkPolicy returns a NetworkPolicy applied to Group name with rules of security group sg, in
+// order. Peer security groups are referred to by Groups of sgResourceToGroupName.
+func generateImportedNetworkPolicy(namespace, name string, sg securitygroup.UnmanagedSecurityGroup,
+	sgResourceToGroupName map[securitygroup.CloudResourceID]string) (*unstructured.Unstructured, []string) {
+	var warnings []string
+	warn := func(direction string, index int, format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf("security group %v (%v) %v rule %d: %v", sg.CloudName, sg.CloudID,
+			direction, index, fmt.Sprintf(format, args...)))
+	}
+
+	ingress := make([]interface{}, 0, len(sg.IngressRules))
+	for i, rule := range sg.IngressRules {
+		peers, ok := generateImportedPeers(rule.FromSrcIP, rule.FromSecurityGroups, sgResourceToGroupName,
+			func(format string, args ...interface{}) { warn("ingress", i, format, args...) })
+		if !ok {
+			continue
+		}
+		ports, err := generateImportedPorts(rule.Protocol, rule.FromPort, rule.FromEndPort)
+		if err != nil {
+			warn("ingress", i, "not imported, %v", err)
+			continue
+		}
+		ingress = append(ingress, generateImportedRule(rule.Action, "from", peers, ports))
+	}
+	egress := make([]interface{}, 0, len(sg.EgressRules))
+	for i, rule := range sg.EgressRules {
+		peers, ok := generateImportedPeers(rule.ToDstIP, rule.ToSecurityGroups, sgResourceToGroupName,
+			func(format string, args ...interface{}) { warn("egress", i, format, args...) })
+		if !ok {
+			continue
+		}
+		ports, err := generateImportedPorts(rule.Protocol, rule.ToPort, rule.ToEndPort)
+		if err != nil {
+			warn("egress", i, "not imported, %v", err)
+			continue
+		}
+		egress = append(egress, generateImportedRule(rule.Action, "to", peers, ports))
+	}
+
+	spec := map[string]interface{}{
+		"priority":  importedNetworkPolicyPriority,
+		"appliedTo": []interface{}{map[string]interface{}{"group": name}},
+		"ingress":   ingress,
+		"egress":    egress,
+	}
+	return generateImportedObject(importedNetworkPolicyAPIVersion, importedNetworkPolicyKind, namespace, name, sg, spec),
+		warnings
+}
+
+// generateImportedPeers returns NetworkPolicy peers of rule ip blocks and security groups. Peer security groups not
+// imported match no virtual machine and are dropped; it returns false if all peers of the rule are dropped, as the rule
+// matches no traffic.
+func generateImportedPeers(ipNets []*net.IPNet, sgs []*securitygroup.CloudResourceID,
+	sgResourceToGroupName map[securitygroup.CloudResourceID]string, warn func(string, ...interface{})) ([]interface{}, bool) {
+	var peers []interface{}
+	for _, ipNet := range ipNets {
+		peers = append(peers, map[string]interface{}{"ipBlock": map[string]interface{}{"cidr": ipNet.String()}})
+	}
+	for _, sg := range sgs {
+		groupName, found := sgResourceToGroupName[*sg]
+		if !found {
+			warn("peer security group %v dropped, not attached to virtual machines of the account inventory", sg.Name)
+			continue
+		}
+		peers = append(peers, map[string]interface{}{"group": groupName})
+	}
+	if len(peers) == 0 && len(ipNets)+len(sgs) != 0 {
+		warn("not imported, all peers dropped")
+		return nil, false
+	}
+	return peers, true
+}
+
+// generateImportedPorts returns NetworkPolicy ports of a rule protocol and port range. Ports of any protocol are
+// imported as TCP and UDP ports.
+func generateImportedPorts(protocol, port, endPort *int) ([]interface{}, error) {
+	var protocols []string
+	if protocol == nil {
+		if port == nil {
+			return nil, nil
+		}
+		protocols = []string{"TCP", "UDP"}
+	} else {
+		switch *protocol {
+		case securitygroup.ProtocolNameNumMap["tcp"]:
+			protocols = []string{"TCP"}
+		case securitygroup.ProtocolNameNumMap["udp"]:
+			protocols = []string{"UDP"}
+		default:
+			return nil, fmt.Errorf("protocol %d not supported", *protocol)
+		}
+	}
+
+	ports := make([]interface{}, 0, len(protocols))
+	for _, proto := range protocols {
+		p := map[string]interface{}{"protocol": proto}
+		if port != nil {
+			p["port"] = int64(*port)
+			if endPort != nil && *endPort != *port {
+				p["endPort"] = int64(*endPort)
+			}
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+func generateImportedRule(action securitygroup.RuleAction, peersKey string, peers, ports []interface{}) interface{} {
+	ruleAction := "Allow"
+	if action.IsDeny() {
+		ruleAction = "Drop"
+	}
+	rule := map[string]interface{}{"action": ruleAction}
+	if len(peers) != 0 {
+		rule[peersKey] = peers
+	}
+	if len(ports) != 0 {
+		rule["ports"] = ports
+	}
+	return rule
+}
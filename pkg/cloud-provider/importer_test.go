@@ -0,0 +1,153 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+import (
+	"fmt"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
+	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+)
+
+// importCloud returns fixed unmanaged security groups and virtual machines of an account.
+type importCloud struct {
+	cloudcommon.CloudInterface
+	sgs []securitygroup.UnmanagedSecurityGroup
+	vms []*cloudv1alpha1.VirtualMachine
+}
+
+func (c *importCloud) GetUnmanagedSecurityGroups(_ *types.NamespacedName) ([]securitygroup.UnmanagedSecurityGroup, error) {
+	return c.sgs, nil
+}
+
+func (c *importCloud) InstancesGivenProviderAccount(_ *types.NamespacedName) ([]*cloudv1alpha1.VirtualMachine, error) {
+	return c.vms, nil
+}
+
+var _ = Describe("Security group import", func() {
+	var (
+		account = &types.NamespacedName{Namespace: "default", Name: "account"}
+		tcp     = 6
+		icmp    = 1
+		port    = 22
+		endPort = 23
+	)
+
+	getVM := func(name string, nics ...string) *cloudv1alpha1.VirtualMachine {
+		vm := &cloudv1alpha1.VirtualMachine{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: account.Namespace}}
+		for _, nic := range nics {
+			vm.Status.NetworkInterfaces = append(vm.Status.NetworkInterfaces, cloudv1alpha1.NetworkInterface{Name: nic})
+		}
+		return vm
+	}
+	getMembers := func(nics ...string) []securitygroup.CloudResource {
+		var members []securitygroup.CloudResource
+		for _, nic := range nics {
+			members = append(members, securitygroup.CloudResource{Type: securitygroup.CloudResourceTypeNIC,
+				Name: securitygroup.CloudResourceID{Name: nic, Vpc: "vpc-1"}})
+		}
+		return members
+	}
+	getSpec := func(obj *unstructured.Unstructured, fields ...string) interface{} {
+		value, found, err := unstructured.NestedFieldNoCopy(obj.Object, append([]string{"spec"}, fields...)...)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue(), fmt.Sprintf("%v of %v", fields, obj.GetName()))
+		return value
+	}
+
+	It("Should import security groups as Groups and NetworkPolicies", func() {
+		_, cidr, _ := net.ParseCIDR("10.0.0.0/8")
+		web := securitygroup.CloudResourceID{Name: "web", Vpc: "vpc-1"}
+		db := securitygroup.CloudResourceID{Name: "db", Vpc: "vpc-1"}
+		unused := securitygroup.CloudResourceID{Name: "unused", Vpc: "vpc-1"}
+		cloud := &importCloud{
+			vms: []*cloudv1alpha1.VirtualMachine{getVM("vm-1", "eni-1"), getVM("vm-2", "ENI-2", "eni-3")},
+			sgs: []securitygroup.UnmanagedSecurityGroup{
+				{Resource: web, CloudID: "sg-1", CloudName: "Web Servers", Members: getMembers("eni-1", "eni-2"),
+					IngressRules: []securitygroup.IngressRule{
+						{Protocol: &tcp, FromPort: &port, FromEndPort: &endPort, FromSrcIP: []*net.IPNet{cidr}},
+						{FromSecurityGroups: []*securitygroup.CloudResourceID{&db, &unused}},
+						{FromSecurityGroups: []*securitygroup.CloudResourceID{&unused}},
+						{Protocol: &icmp},
+					},
+					EgressRules:      []securitygroup.EgressRule{{Action: securitygroup.RuleActionDeny, ToDstIP: []*net.IPNet{cidr}}},
+					UnsupportedRules: []string{"ingress rule protocol tcp: unsupported peers prefix list pl-1"},
+				},
+				{Resource: db, CloudID: "sg-2", CloudName: "db", Members: getMembers("eni-3"), MembershipOnly: true},
+				{Resource: unused, CloudID: "sg-3", CloudName: "unused", Members: getMembers("eni-4")},
+			},
+		}
+
+		result, err := ImportSecurityGroups(cloud, account)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Objects).To(HaveLen(3))
+		dbGroup, webGroup, webPolicy := result.Objects[0], result.Objects[1], result.Objects[2]
+
+		Expect(dbGroup.GetKind()).To(Equal("Group"))
+		Expect(dbGroup.GetName()).To(Equal("db-313"))
+		Expect(dbGroup.GetNamespace()).To(Equal(account.Namespace))
+		Expect(dbGroup.GetAnnotations()).To(HaveKeyWithValue(cloudcommon.AnnotationCloudAssignedIDKey, "sg-2"))
+		Expect(getSpec(dbGroup, "externalEntitySelector", "matchExpressions")).To(Equal([]interface{}{
+			map[string]interface{}{"key": "name.nephe", "operator": "In", "values": []interface{}{"vm-2"}}}))
+		Expect(getSpec(dbGroup, "externalEntitySelector", "matchLabels")).To(Equal(map[string]interface{}{
+			"kind.nephe": "virtualmachine"}))
+
+		Expect(webGroup.GetName()).To(Equal("web-servers-312"))
+		Expect(getSpec(webGroup, "externalEntitySelector", "matchExpressions")).To(Equal([]interface{}{
+			map[string]interface{}{"key": "name.nephe", "operator": "In", "values": []interface{}{"vm-1", "vm-2"}}}))
+
+		Expect(webPolicy.GetKind()).To(Equal("NetworkPolicy"))
+		Expect(webPolicy.GetName()).To(Equal(webGroup.GetName()))
+		Expect(getSpec(webPolicy, "appliedTo")).To(Equal([]interface{}{map[string]interface{}{"group": webGroup.GetName()}}))
+		Expect(getSpec(webPolicy, "ingress")).To(Equal([]interface{}{
+			map[string]interface{}{"action": "Allow",
+				"from":  []interface{}{map[string]interface{}{"ipBlock": map[string]interface{}{"cidr": "10.0.0.0/8"}}},
+				"ports": []interface{}{map[string]interface{}{"protocol": "TCP", "port": int64(22), "endPort": int64(23)}}},
+			map[string]interface{}{"action": "Allow",
+				"from": []interface{}{map[string]interface{}{"group": dbGroup.GetName()}}},
+		}))
+		Expect(getSpec(webPolicy, "egress")).To(Equal([]interface{}{
+			map[string]interface{}{"action": "Drop",
+				"to": []interface{}{map[string]interface{}{"ipBlock": map[string]interface{}{"cidr": "10.0.0.0/8"}}}},
+		}))
+
+		Expect(result.Warnings).To(ConsistOf(
+			ContainSubstring("security group unused (sg-3) skipped"),
+			ContainSubstring("security group Web Servers (sg-1) is attached to some network interfaces of virtual machine vm-2"),
+			ContainSubstring("security group db (sg-2) is attached to some network interfaces of virtual machine vm-2"),
+			ContainSubstring("prefix list pl-1 not imported"),
+			ContainSubstring("ingress rule 1: peer security group unused dropped"),
+			ContainSubstring("ingress rule 2: peer security group unused dropped"),
+			ContainSubstring("ingress rule 2: not imported, all peers dropped"),
+			ContainSubstring("ingress rule 3: not imported, protocol 1 not supported"),
+		))
+	})
+
+	It("Should generate unique names", func() {
+		usedNames := make(map[string]struct{})
+		sg := securitygroup.UnmanagedSecurityGroup{CloudID: "ab", CloudName: "--"}
+		Expect(generateImportedName(sg, usedNames)).To(Equal("sg-195"))
+		sg.CloudID = "ba"
+		Expect(generateImportedName(sg, usedNames)).To(Equal("sg-195-1"))
+	})
+})
@@ -0,0 +1,328 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package securitygroup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// ErrQuotaExceeded is returned by cloud plug-ins when rules or memberships of a SecurityGroup exceed a cloud quota.
+// Cloud resources whose quota would be exceeded are left unmodified.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// AggregateIPNets returns the smallest set of ip blocks covering exactly ipNets. IP blocks contained in other ip
+// blocks are removed, and adjacent ip blocks are merged into their common supernet. Returned ip blocks are sorted,
+// IPv4 ip blocks first.
+func AggregateIPNets(ipNets []*net.IPNet) []*net.IPNet {
+	var ipv4Nets, ipv6Nets []*net.IPNet
+	for _, ipNet := range ipNets {
+		ones, bits := ipNet.Mask.Size()
+		ip := ipNet.IP.To4()
+		if bits == 8*net.IPv6len {
+			ip = ipNet.IP.To16()
+		}
+		if ip == nil {
+			continue
+		}
+		mask := net.CIDRMask(ones, bits)
+		normalized := &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+		if bits == 8*net.IPv4len {
+			ipv4Nets = append(ipv4Nets, normalized)
+		} else {
+			ipv6Nets = append(ipv6Nets, normalized)
+		}
+	}
+	return append(aggregateIPNetsOfFamily(ipv4Nets), aggregateIPNetsOfFamily(ipv6Nets)...)
+}
+
+// aggregateIPNetsOfFamily aggregates normalized ip blocks of the same address family.
+func aggregateIPNetsOfFamily(ipNets []*net.IPNet) []*net.IPNet {
+	sort.Slice(ipNets, func(i, j int) bool {
+		if c := bytes.Compare(ipNets[i].IP, ipNets[j].IP); c != 0 {
+			return c < 0
+		}
+		onesI, _ := ipNets[i].Mask.Size()
+		onesJ, _ := ipNets[j].Mask.Size()
+		return onesI < onesJ
+	})
+
+	// sorted ip blocks are either disjoint or contained in the last kept ip block.
+	var aggregated []*net.IPNet
+	for _, ipNet := range ipNets {
+		if len(aggregated) > 0 && aggregated[len(aggregated)-1].Contains(ipNet.IP) {
+			continue
+		}
+		aggregated = append(aggregated, ipNet)
+		// merge the last two ip blocks as long as they are the two halves of a supernet.
+		for len(aggregated) >= 2 {
+			lower, upper := aggregated[len(aggregated)-2], aggregated[len(aggregated)-1]
+			ones, bits := lower.Mask.Size()
+			upperOnes, _ := upper.Mask.Size()
+			if ones == 0 || ones != upperOnes {
+				break
+			}
+			supernetMask := net.CIDRMask(ones-1, bits)
+			if !lower.IP.Equal(lower.IP.Mask(supernetMask)) || !upper.IP.Mask(supernetMask).Equal(lower.IP) {
+				break
+			}
+			aggregated = append(aggregated[:len(aggregated)-2], &net.IPNet{IP: lower.IP, Mask: supernetMask})
+		}
+	}
+	return aggregated
+}
+
+// compactRule is the direction independent part of IngressRule and EgressRule.
+type compactRule struct {
+	port     *int
+	endPort  *int
+	ips      []*net.IPNet
+	sgs      []*CloudResourceID
+	services []string
+	proto    *int
+	icmpType *int
+	icmpCode *int
+	action   RuleAction
+	priority *RulePriority
+}
+
+// peersKey returns a key identifying protocol, ICMP type and code, action, priority and peers of rule.
+func (r *compactRule) peersKey() string {
+	var peers []string
+	for _, ip := range r.ips {
+		peers = append(peers, ip.String())
+	}
+	for _, sg := range r.sgs {
+		peers = append(peers, "sg:"+sg.String())
+	}
+	for _, service := range r.services {
+		peers = append(peers, "service:"+service)
+	}
+	sort.Strings(peers)
+	priority := ""
+	if r.priority != nil {
+		priority = fmt.Sprintf("%+v", *r.priority)
+	}
+	return fmt.Sprintf("%v|%v|%v|%v|%v|%v", *r.proto, intString(r.icmpType), intString(r.icmpCode), r.action, priority,
+		strings.Join(peers, ","))
+}
+
+// intString returns string of i, or "*" if i is nil.
+func intString(i *int) string {
+	if i == nil {
+		return "*"
+	}
+	return fmt.Sprint(*i)
+}
+
+// isPortMergeable returns true if rule port range may be merged with port ranges of other rules.
+func (r *compactRule) isPortMergeable() bool {
+	return r.port != nil && r.proto != nil && (*r.proto == ProtocolNameNumMap["tcp"] || *r.proto == ProtocolNameNumMap["udp"])
+}
+
+// compactRules aggregates ip blocks of each rule, and merges overlapping or adjacent port ranges of rules with the same
+// protocol, action and peers. Rules are returned in the order they are first seen.
+func compactRules(rules []*compactRule) []*compactRule {
+	// each slot holds either a rule not merged, or key of rules to be merged.
+	type slot struct {
+		rule *compactRule
+		key  string
+	}
+	var slots []slot
+	mergeableRules := make(map[string][]*compactRule)
+	for _, rule := range rules {
+		if len(rule.ips) > 0 {
+			rule.ips = AggregateIPNets(rule.ips)
+		}
+		if !rule.isPortMergeable() {
+			slots = append(slots, slot{rule: rule})
+			continue
+		}
+		key := rule.peersKey()
+		if _, ok := mergeableRules[key]; !ok {
+			slots = append(slots, slot{key: key})
+		}
+		mergeableRules[key] = append(mergeableRules[key], rule)
+	}
+
+	compacted := make([]*compactRule, 0, len(rules))
+	for _, s := range slots {
+		if s.rule != nil {
+			compacted = append(compacted, s.rule)
+			continue
+		}
+		compacted = append(compacted, mergePortRanges(mergeableRules[s.key])...)
+	}
+	return compacted
+}
+
+// mergePortRanges merges overlapping or adjacent port ranges of rules with the same protocol, action and peers.
+func mergePortRanges(rules []*compactRule) []*compactRule {
+	getEndPort := func(r *compactRule) int {
+		if r.endPort == nil || *r.endPort < *r.port {
+			return *r.port
+		}
+		return *r.endPort
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return *rules[i].port < *rules[j].port
+	})
+
+	var merged []*compactRule
+	for _, rule := range rules {
+		if len(merged) > 0 {
+			last := merged[len(merged)-1]
+			lastEndPort := getEndPort(last)
+			if *rule.port <= lastEndPort+1 {
+				if endPort := getEndPort(rule); endPort > lastEndPort {
+					last.endPort = &endPort
+				}
+				continue
+			}
+		}
+		port, endPort := *rule.port, getEndPort(rule)
+		mergedRule := *rule
+		mergedRule.port = &port
+		mergedRule.endPort = nil
+		if endPort != port {
+			mergedRule.endPort = &endPort
+		}
+		merged = append(merged, &mergedRule)
+	}
+	return merged
+}
+
+// mergeRulePriorities returns rules with priorities cleared, and peers of rules differing only in priority merged.
+func mergeRulePriorities(rules []*compactRule) []*compactRule {
+	merged := make([]*compactRule, 0, len(rules))
+	mergedByKey := make(map[string]*compactRule)
+	for _, rule := range rules {
+		key := fmt.Sprintf("%v|%v|%v|%v|%v|%v", intString(rule.proto), intString(rule.port), intString(rule.endPort),
+			intString(rule.icmpType), intString(rule.icmpCode), rule.action)
+		m, ok := mergedByKey[key]
+		if !ok {
+			m = &compactRule{port: rule.port, endPort: rule.endPort, proto: rule.proto, icmpType: rule.icmpType,
+				icmpCode: rule.icmpCode, action: rule.action}
+			mergedByKey[key] = m
+			merged = append(merged, m)
+		}
+		m.ips = append(m.ips, rule.ips...)
+		for _, sg := range rule.sgs {
+			found := false
+			for _, existing := range m.sgs {
+				if existing.String() == sg.String() {
+					found = true
+					break
+				}
+			}
+			if !found {
+				m.sgs = append(m.sgs, sg)
+			}
+		}
+		for _, service := range rule.services {
+			found := false
+			for _, existing := range m.services {
+				if existing == service {
+					found = true
+					break
+				}
+			}
+			if !found {
+				m.services = append(m.services, service)
+			}
+		}
+	}
+	return merged
+}
+
+// toIngressCompactRules returns compactRules of ingressRules.
+func toIngressCompactRules(ingressRules []*IngressRule) []*compactRule {
+	rules := make([]*compactRule, 0, len(ingressRules))
+	for _, r := range ingressRules {
+		if r == nil {
+			continue
+		}
+		rules = append(rules, &compactRule{port: r.FromPort, endPort: r.FromEndPort, ips: r.FromSrcIP,
+			sgs: r.FromSecurityGroups, services: r.FromCloudServices, proto: r.Protocol, icmpType: r.ICMPType,
+			icmpCode: r.ICMPCode, action: r.Action, priority: r.Priority})
+	}
+	return rules
+}
+
+// fromIngressCompactRules returns ingressRules of compactRules.
+func fromIngressCompactRules(rules []*compactRule) []*IngressRule {
+	ingressRules := make([]*IngressRule, 0, len(rules))
+	for _, r := range rules {
+		ingressRules = append(ingressRules, &IngressRule{FromPort: r.port, FromEndPort: r.endPort, FromSrcIP: r.ips,
+			FromSecurityGroups: r.sgs, FromCloudServices: r.services, Protocol: r.proto, ICMPType: r.icmpType,
+			ICMPCode: r.icmpCode, Action: r.action, Priority: r.priority})
+	}
+	return ingressRules
+}
+
+// toEgressCompactRules returns compactRules of egressRules.
+func toEgressCompactRules(egressRules []*EgressRule) []*compactRule {
+	rules := make([]*compactRule, 0, len(egressRules))
+	for _, r := range egressRules {
+		if r == nil {
+			continue
+		}
+		rules = append(rules, &compactRule{port: r.ToPort, endPort: r.ToEndPort, ips: r.ToDstIP,
+			sgs: r.ToSecurityGroups, services: r.ToCloudServices, proto: r.Protocol, icmpType: r.ICMPType,
+			icmpCode: r.ICMPCode, action: r.Action, priority: r.Priority})
+	}
+	return rules
+}
+
+// fromEgressCompactRules returns egressRules of compactRules.
+func fromEgressCompactRules(rules []*compactRule) []*EgressRule {
+	egressRules := make([]*EgressRule, 0, len(rules))
+	for _, r := range rules {
+		egressRules = append(egressRules, &EgressRule{ToPort: r.port, ToEndPort: r.endPort, ToDstIP: r.ips,
+			ToSecurityGroups: r.sgs, ToCloudServices: r.services, Protocol: r.proto, ICMPType: r.icmpType,
+			ICMPCode: r.icmpCode, Action: r.action, Priority: r.priority})
+	}
+	return egressRules
+}
+
+// CompactIngressRules returns ingressRules with adjacent ip blocks of each rule aggregated, and overlapping or adjacent
+// port ranges of rules with the same protocol, action, priority and peers merged, to realize ingressRules with fewer
+// cloud rules.
+func CompactIngressRules(ingressRules []*IngressRule) []*IngressRule {
+	return fromIngressCompactRules(compactRules(toIngressCompactRules(ingressRules)))
+}
+
+// CompactEgressRules returns egressRules with adjacent ip blocks of each rule aggregated, and overlapping or adjacent
+// port ranges of rules with the same protocol, action, priority and peers merged, to realize egressRules with fewer
+// cloud rules.
+func CompactEgressRules(egressRules []*EgressRule) []*EgressRule {
+	return fromEgressCompactRules(compactRules(toEgressCompactRules(egressRules)))
+}
+
+// CompactIngressRulesIgnoringPriority is CompactIngressRules for clouds not ordering rules, priorities of ingressRules
+// are cleared and rules differing only in priority are merged.
+func CompactIngressRulesIgnoringPriority(ingressRules []*IngressRule) []*IngressRule {
+	return fromIngressCompactRules(compactRules(mergeRulePriorities(toIngressCompactRules(ingressRules))))
+}
+
+// CompactEgressRulesIgnoringPriority is CompactEgressRules for clouds not ordering rules, priorities of egressRules
+// are cleared and rules differing only in priority are merged.
+func CompactEgressRulesIgnoringPriority(egressRules []*EgressRule) []*EgressRule {
+	return fromEgressCompactRules(compactRules(mergeRulePriorities(toEgressCompactRules(egressRules))))
+}
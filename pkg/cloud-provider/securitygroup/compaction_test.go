@@ -0,0 +1,132 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package securitygroup_test
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+)
+
+var _ = Describe("Rule compaction", func() {
+	parseCIDRs := func(cidrs ...string) []*net.IPNet {
+		var ipNets []*net.IPNet
+		for _, cidr := range cidrs {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			Expect(err).ToNot(HaveOccurred())
+			ipNets = append(ipNets, ipNet)
+		}
+		return ipNets
+	}
+	toStrings := func(ipNets []*net.IPNet) []string {
+		var cidrs []string
+		for _, ipNet := range ipNets {
+			cidrs = append(cidrs, ipNet.String())
+		}
+		return cidrs
+	}
+	intPtr := func(i int) *int {
+		return &i
+	}
+
+	It("Should aggregate contained and adjacent ip blocks", func() {
+		ipNets := parseCIDRs("10.0.1.0/24", "10.0.0.0/24", "10.0.2.0/23", "10.0.2.5/32", "192.168.0.1/32",
+			"192.168.0.3/32", "2001:db8::/33", "2001:db8:8000::/33", "10.0.4.0/24")
+		Expect(toStrings(securitygroup.AggregateIPNets(ipNets))).To(Equal([]string{"10.0.0.0/22", "10.0.4.0/24",
+			"192.168.0.1/32", "192.168.0.3/32", "2001:db8::/32"}))
+	})
+
+	It("Should merge adjacent port ranges of rules with same peers", func() {
+		tcp, udp := 6, 17
+		peers := parseCIDRs("10.0.0.0/25", "10.0.0.128/25")
+		sg := &securitygroup.CloudResourceID{Name: "ag", Vpc: "vpc"}
+		rules := []*securitygroup.IngressRule{
+			{Protocol: &tcp, FromPort: intPtr(82), FromEndPort: intPtr(90), FromSrcIP: peers},
+			{Protocol: &udp, FromPort: intPtr(53), FromSrcIP: peers},
+			{Protocol: &tcp, FromPort: intPtr(80), FromSrcIP: peers[1:]},
+			{Protocol: &tcp, FromPort: intPtr(80), FromSrcIP: parseCIDRs("10.0.0.0/24")},
+			{Protocol: &tcp, FromPort: intPtr(81), FromSrcIP: parseCIDRs("10.0.0.0/24")},
+			{Protocol: &tcp, FromPort: intPtr(100), FromSrcIP: peers},
+			{Protocol: &tcp, FromPort: intPtr(80), FromSecurityGroups: []*securitygroup.CloudResourceID{sg}},
+			{Protocol: &tcp, FromPort: intPtr(81), FromSecurityGroups: []*securitygroup.CloudResourceID{sg},
+				Action: securitygroup.RuleActionDeny},
+			{FromSrcIP: peers},
+		}
+		compacted := securitygroup.CompactIngressRules(rules)
+		Expect(compacted).To(Equal([]*securitygroup.IngressRule{
+			{Protocol: &tcp, FromPort: intPtr(80), FromEndPort: intPtr(90), FromSrcIP: parseCIDRs("10.0.0.0/24")},
+			{Protocol: &tcp, FromPort: intPtr(100), FromSrcIP: parseCIDRs("10.0.0.0/24")},
+			{Protocol: &udp, FromPort: intPtr(53), FromSrcIP: parseCIDRs("10.0.0.0/24")},
+			{Protocol: &tcp, FromPort: intPtr(80), FromSrcIP: parseCIDRs("10.0.0.128/25")},
+			{Protocol: &tcp, FromPort: intPtr(80), FromSecurityGroups: []*securitygroup.CloudResourceID{sg}},
+			{Protocol: &tcp, FromPort: intPtr(81), FromSecurityGroups: []*securitygroup.CloudResourceID{sg},
+				Action: securitygroup.RuleActionDeny},
+			{FromSrcIP: parseCIDRs("10.0.0.0/24")},
+		}))
+
+		egressCompacted := securitygroup.CompactEgressRules([]*securitygroup.EgressRule{
+			{Protocol: &tcp, ToPort: intPtr(443), ToDstIP: peers},
+			{Protocol: &tcp, ToPort: intPtr(444), ToEndPort: intPtr(444), ToDstIP: peers},
+			{Protocol: &tcp, ToPort: intPtr(443), ToCloudServices: []string{"Storage.WestUS2"}},
+			{Protocol: &tcp, ToPort: intPtr(444), ToCloudServices: []string{"Storage.WestUS2"}},
+			{Protocol: &tcp, ToPort: intPtr(445), ToCloudServices: []string{"Sql.WestUS2"}},
+		})
+		Expect(egressCompacted).To(Equal([]*securitygroup.EgressRule{
+			{Protocol: &tcp, ToPort: intPtr(443), ToEndPort: intPtr(444), ToDstIP: parseCIDRs("10.0.0.0/24")},
+			{Protocol: &tcp, ToPort: intPtr(443), ToEndPort: intPtr(444), ToCloudServices: []string{"Storage.WestUS2"}},
+			{Protocol: &tcp, ToPort: intPtr(445), ToCloudServices: []string{"Sql.WestUS2"}},
+		}))
+	})
+
+	It("Should merge rules differing only in priority if priorities are ignored", func() {
+		tcp := 6
+		peers := parseCIDRs("10.0.0.0/25", "10.0.0.128/25")
+		p1 := &securitygroup.RulePriority{TierPriority: 50, PolicyPriority: 1}
+		p2 := &securitygroup.RulePriority{TierPriority: 250, PolicyPriority: 1, RuleIndex: 1}
+		rules := []*securitygroup.IngressRule{
+			{Protocol: &tcp, FromPort: intPtr(80), FromSrcIP: peers[:1], Priority: p1},
+			{Protocol: &tcp, FromPort: intPtr(80), FromSrcIP: peers[1:], Priority: p2},
+			{Protocol: &tcp, FromPort: intPtr(81), FromSrcIP: parseCIDRs("10.0.0.0/24"), Priority: p2},
+		}
+		Expect(securitygroup.CompactIngressRules(rules)).To(ConsistOf(
+			&securitygroup.IngressRule{Protocol: &tcp, FromPort: intPtr(80), FromSrcIP: peers[:1], Priority: p1},
+			&securitygroup.IngressRule{Protocol: &tcp, FromPort: intPtr(80), FromSrcIP: peers[1:], Priority: p2},
+			&securitygroup.IngressRule{Protocol: &tcp, FromPort: intPtr(81), FromSrcIP: parseCIDRs("10.0.0.0/24"),
+				Priority: p2},
+		))
+		Expect(securitygroup.CompactIngressRulesIgnoringPriority(rules)).To(Equal([]*securitygroup.IngressRule{
+			{Protocol: &tcp, FromPort: intPtr(80), FromEndPort: intPtr(81), FromSrcIP: parseCIDRs("10.0.0.0/24")},
+		}))
+	})
+
+	It("Should keep ICMP rules of different type and code apart", func() {
+		icmp := 1
+		peers := parseCIDRs("10.0.0.0/25", "10.0.0.128/25")
+		rules := []*securitygroup.EgressRule{
+			{Protocol: &icmp, ICMPType: intPtr(8), ICMPCode: intPtr(0), ToDstIP: peers[:1]},
+			{Protocol: &icmp, ICMPType: intPtr(8), ICMPCode: intPtr(0), ToDstIP: peers[1:]},
+			{Protocol: &icmp, ICMPType: intPtr(0), ToDstIP: peers[:1]},
+		}
+		Expect(securitygroup.CompactEgressRulesIgnoringPriority(rules)).To(ConsistOf(
+			&securitygroup.EgressRule{Protocol: &icmp, ICMPType: intPtr(8), ICMPCode: intPtr(0),
+				ToDstIP: parseCIDRs("10.0.0.0/24")},
+			&securitygroup.EgressRule{Protocol: &icmp, ICMPType: intPtr(0), ToDstIP: peers[:1]},
+		))
+		Expect(securitygroup.HasICMPTypeCode(nil, rules)).To(BeTrue())
+	})
+})
@@ -31,7 +31,7 @@ Network CRD
 Each Antrea internal NetworkPolicy contains
 -- name and namespace that uniquely identifies an Antrea internal NetworkPolicy.
    name and namespace corresponds to user facing Antrea NetworkPolicy.
--- list of rules (presently all are while list rules), each rule contains
+-- list of rules (allow or drop/reject rules), each rule contains
     -- direction
     -- service (port) of this rule. ( TODO: how is it produced on Antrea Controller ?)
     -- To/From:  IPBlock and  reference to AddressGroup.
@@ -101,6 +101,17 @@ Antrea internal NetworkPolicy To SecurityGroup Mapping strategy
 // CloudResourceType specifies the type of cloud resource.
 type CloudResourceType string
 
+// RuleAction specifies the action of a cloud SecurityGroup rule.
+type RuleAction string
+
+const (
+	// RuleActionAllow permits traffic matching a rule. An empty RuleAction is treated as RuleActionAllow.
+	RuleActionAllow RuleAction = "Allow"
+	// RuleActionDeny drops traffic matching a rule. Deny rules take precedence over allow rules of the same
+	// RulePriority.
+	RuleActionDeny RuleAction = "Deny"
+)
+
 const (
 	NepheControllerPrefix             = "nephe-"
 	NepheControllerAddressGroupPrefix = NepheControllerPrefix + "ag-"
@@ -151,20 +162,110 @@ func (c *CloudResourceID) String() string {
 	return c.Name + "/" + c.Vpc
 }
 
+// IsDeny returns true if action denies traffic.
+func (a RuleAction) IsDeny() bool {
+	return a == RuleActionDeny
+}
+
+// RulePriority specifies the precedence of a rule, derived from the tier and priority of its NetworkPolicy and its index
+// within the NetworkPolicy. Clouds ordering rules, such as Azure, realize rules of higher precedence first; other clouds
+// ignore RulePriority.
+type RulePriority struct {
+	TierPriority   int32
+	PolicyPriority float64
+	RuleIndex      int32
+}
+
+// BaselineTierPriority is the priority of the Antrea baseline tier, whose rules take precedence after K8s NetworkPolicy
+// rules.
+const BaselineTierPriority int32 = 253
+
+// Less returns true if p takes precedence over o. A rule without priority, of a K8s NetworkPolicy, takes precedence
+// after rules of tiers other than the baseline tier, and before rules of the baseline tier.
+func (p *RulePriority) Less(o *RulePriority) bool {
+	if p == nil && o == nil {
+		return false
+	}
+	if p == nil {
+		return o.TierPriority >= BaselineTierPriority
+	}
+	if o == nil {
+		return p.TierPriority < BaselineTierPriority
+	}
+	if p.TierPriority != o.TierPriority {
+		return p.TierPriority < o.TierPriority
+	}
+	if p.PolicyPriority != o.PolicyPriority {
+		return p.PolicyPriority < o.PolicyPriority
+	}
+	return p.RuleIndex < o.RuleIndex
+}
+
+// IsICMPProtocol returns true if protocol is ICMP or ICMPv6.
+func IsICMPProtocol(protocol *int) bool {
+	return protocol != nil && (*protocol == ProtocolNameNumMap["icmp"] || *protocol == ProtocolNameNumMap["icmpv6"])
+}
+
+// GetIPv6Protocol returns protocol of a rule of protocol for IPv6 peers. An ICMP rule matches ICMPv6 of IPv6 peers.
+func GetIPv6Protocol(protocol *int) *int {
+	if protocol == nil || *protocol != ProtocolNameNumMap["icmp"] {
+		return protocol
+	}
+	icmpv6 := ProtocolNameNumMap["icmpv6"]
+	return &icmpv6
+}
+
 // IngressRule specifies one ingress rule of cloud SecurityGroup.
+// FromEndPort, if set, is the last port of the port range starting at FromPort.
+// FromCloudServices are cloud services, Azure service tags or AWS prefix lists, of permitted incoming traffic.
+// ICMPType and ICMPCode, if set, restrict an ICMP rule to ICMP messages of the type and code; an ICMP rule matches
+// ICMPv6 messages of IPv6 peers.
+// Priority, if set, is the precedence of the rule.
 type IngressRule struct {
 	FromPort           *int
+	FromEndPort        *int
 	FromSrcIP          []*net.IPNet
 	FromSecurityGroups []*CloudResourceID
+	FromCloudServices  []string
 	Protocol           *int
+	ICMPType           *int
+	ICMPCode           *int
+	Action             RuleAction
+	Priority           *RulePriority
 }
 
 // EgressRule specifies one egress rule of cloud SecurityGroup.
+// ToEndPort, if set, is the last port of the port range starting at ToPort.
+// ToCloudServices are cloud services, Azure service tags or AWS prefix lists, of permitted outgoing traffic.
+// ICMPType and ICMPCode, if set, restrict an ICMP rule to ICMP messages of the type and code; an ICMP rule matches
+// ICMPv6 messages of IPv6 peers.
+// Priority, if set, is the precedence of the rule.
 type EgressRule struct {
 	ToPort           *int
+	ToEndPort        *int
 	ToDstIP          []*net.IPNet
 	ToSecurityGroups []*CloudResourceID
+	ToCloudServices  []string
 	Protocol         *int
+	ICMPType         *int
+	ICMPCode         *int
+	Action           RuleAction
+	Priority         *RulePriority
+}
+
+// HasICMPTypeCode returns true if any rule is restricted to an ICMP type or code.
+func HasICMPTypeCode(ingressRules []*IngressRule, egressRules []*EgressRule) bool {
+	for _, rule := range ingressRules {
+		if rule != nil && (rule.ICMPType != nil || rule.ICMPCode != nil) {
+			return true
+		}
+	}
+	for _, rule := range egressRules {
+		if rule != nil && (rule.ICMPType != nil || rule.ICMPCode != nil) {
+			return true
+		}
+	}
+	return false
 }
 
 // SynchronizationContent returns a SecurityGroup content in cloud.
@@ -177,6 +278,25 @@ type SynchronizationContent struct {
 	EgressRules                []EgressRule
 }
 
+// UnmanagedSecurityGroup is the content of a cloud SecurityGroup not created by nephe, e.g. a SecurityGroup created
+// by users before nephe manages the account.
+type UnmanagedSecurityGroup struct {
+	// Resource identifies the SecurityGroup within its account. Rules of UnmanagedSecurityGroups refer to
+	// SecurityGroups by Resource.
+	Resource CloudResourceID
+	// CloudID is the cloud assigned identifier of the SecurityGroup.
+	CloudID string
+	// CloudName is the cloud name of the SecurityGroup.
+	CloudName      string
+	MembershipOnly bool
+	// Members are network interfaces the SecurityGroup is attached to, named by cloud network interface identifier.
+	Members      []CloudResource
+	IngressRules []IngressRule
+	EgressRules  []EgressRule
+	// UnsupportedRules describes cloud rules of the SecurityGroup not carried in IngressRules and EgressRules.
+	UnsupportedRules []string
+}
+
 // SecurityGroup declares interface to program cloud security groups.
 type CloudSecurityGroupAPI interface {
 	// CreateSecurityGroup request to create SecurityGroup name..
@@ -209,7 +329,9 @@ type CloudSecurityGroupAPI interface {
 	// 2. Controller waits on channel returned in 1, and expects that when channel wakes up it return the entire SGs configured.
 	// 3. Plug-in shall wake up the channel initially after sync up with the cloud; and then periodically.
 	// 4. Controller, upon receive entire SGs set, proceed to reconcile between K8s configuration and cloud configuration.
+	// 5. The returned error channel yields, after the SG channel is closed, an error if SGs of any account cannot be
+	// retrieved, in which case the SGs set is incomplete. It is closed without an error otherwise.
 	// This API ensures cloud plug-in stays stateless.
 	// - Correct SGs accidentally changed by customers via cloud API/console directly.
-	GetSecurityGroupSyncChan() <-chan SynchronizationContent
+	GetSecurityGroupSyncChan() (<-chan SynchronizationContent, <-chan error)
 }
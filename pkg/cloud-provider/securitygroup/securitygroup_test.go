@@ -0,0 +1,44 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package securitygroup_test
+
+import (
+	"sort"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+)
+
+var _ = Describe("Rule priority", func() {
+	It("Should order K8s NetworkPolicy rules between non-baseline tiers and baseline tier", func() {
+		emergency := &securitygroup.RulePriority{TierPriority: 50, PolicyPriority: 1}
+		application := &securitygroup.RulePriority{TierPriority: 250, PolicyPriority: 1, RuleIndex: 1}
+		baseline := &securitygroup.RulePriority{TierPriority: securitygroup.BaselineTierPriority, PolicyPriority: 1}
+		priorities := []*securitygroup.RulePriority{baseline, nil, application, emergency}
+		sort.SliceStable(priorities, func(i, j int) bool {
+			return priorities[i].Less(priorities[j])
+		})
+		Expect(priorities).To(Equal([]*securitygroup.RulePriority{emergency, application, nil, baseline}))
+
+		var k8sNP *securitygroup.RulePriority
+		Expect(k8sNP.Less(nil)).To(BeFalse())
+		Expect(k8sNP.Less(baseline)).To(BeTrue())
+		Expect(baseline.Less(k8sNP)).To(BeFalse())
+		Expect(k8sNP.Less(application)).To(BeFalse())
+		Expect(application.Less(k8sNP)).To(BeTrue())
+	})
+})
@@ -17,9 +17,14 @@ package cloudprovider
 import (
 	"fmt"
 	"sync"
+	"time"
 
+	"go.uber.org/multierr"
+
+	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
 	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
 	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 type SecurityGroupImpl struct{}
@@ -28,8 +33,10 @@ func init() {
 	securitygroup.CloudSecurityGroup = &SecurityGroupImpl{}
 }
 
-func getCloudInterfaceForCloudResource(addressGroupIdentifier *securitygroup.CloudResourceID) (cloudcommon.CloudInterface, error) {
+func getCloudInterfaceForCloudResource(addressGroupIdentifier *securitygroup.CloudResourceID) (cloudcommon.CloudInterface,
+	cloudcommon.ProviderType, error) {
 	var cloudInterfaceForResource cloudcommon.CloudInterface
+	var providerTypeForResource cloudcommon.ProviderType
 
 	providerTypes := GetSupportedCloudProviderTypes()
 	for _, providerType := range providerTypes {
@@ -40,13 +47,21 @@ func getCloudInterfaceForCloudResource(addressGroupIdentifier *securitygroup.Clo
 		}
 		if cloudInterface.IsVirtualPrivateCloudPresent(addressGroupIdentifier.Vpc) {
 			cloudInterfaceForResource = cloudInterface
+			providerTypeForResource = providerType
 			break
 		}
 	}
 	if cloudInterfaceForResource != nil {
-		return cloudInterfaceForResource, nil
+		return cloudInterfaceForResource, providerTypeForResource, nil
 	}
-	return nil, fmt.Errorf("virtual private cloud [%v] not managed by supported clouds [%v]", addressGroupIdentifier.Vpc, providerTypes)
+	return nil, "", fmt.Errorf("virtual private cloud [%v] not managed by supported clouds [%v]", addressGroupIdentifier.Vpc, providerTypes)
+}
+
+// recordSecurityGroupOperationMetrics records latency and result of a cloud security group operation.
+func recordSecurityGroupOperationMetrics(providerType cloudcommon.ProviderType, operation string, start time.Time, err error) {
+	provider := string(providerType)
+	metrics.SecurityGroupOperationDuration.WithLabelValues(provider, operation).Observe(time.Since(start).Seconds())
+	metrics.SecurityGroupOperations.WithLabelValues(provider, operation, metrics.Result(err)).Inc()
 }
 
 func (sg *SecurityGroupImpl) CreateSecurityGroup(addressGroupIdentifier *securitygroup.CloudResourceID, membershipOnly bool) <-chan error {
@@ -55,13 +70,21 @@ func (sg *SecurityGroupImpl) CreateSecurityGroup(addressGroupIdentifier *securit
 	go func() {
 		defer close(ch)
 
-		cloudInterface, err := getCloudInterfaceForCloudResource(addressGroupIdentifier)
+		cloudInterface, providerType, err := getCloudInterfaceForCloudResource(addressGroupIdentifier)
 		if err != nil {
 			ch <- err
 			return
 		}
 
+		if account, ok := getDryRunAccount(cloudInterface, addressGroupIdentifier); ok {
+			recordPlannedOperation(account, addressGroupIdentifier, membershipOnly, cloudv1alpha1.SecurityGroupOperationCreate, nil)
+			ch <- nil
+			return
+		}
+
+		start := time.Now()
 		_, err = cloudInterface.CreateSecurityGroup(addressGroupIdentifier, membershipOnly)
+		recordSecurityGroupOperationMetrics(providerType, metrics.SecurityGroupOperationCreate, start, err)
 		if err != nil {
 			ch <- err
 			return
@@ -80,13 +103,24 @@ func (sg *SecurityGroupImpl) UpdateSecurityGroupMembers(addressGroupIdentifier *
 	go func() {
 		defer close(ch)
 
-		cloudInterface, err := getCloudInterfaceForCloudResource(addressGroupIdentifier)
+		cloudInterface, providerType, err := getCloudInterfaceForCloudResource(addressGroupIdentifier)
 		if err != nil {
 			ch <- err
 			return
 		}
 
+		if account, ok := getDryRunAccount(cloudInterface, addressGroupIdentifier); ok {
+			recordPlannedOperation(account, addressGroupIdentifier, membershipOnly, cloudv1alpha1.SecurityGroupOperationUpdateMembers,
+				func(sg *cloudv1alpha1.PlannedSecurityGroup) {
+					sg.Members = plannedMembers(members)
+				})
+			ch <- nil
+			return
+		}
+
+		start := time.Now()
 		err = cloudInterface.UpdateSecurityGroupMembers(addressGroupIdentifier, members, membershipOnly)
+		recordSecurityGroupOperationMetrics(providerType, metrics.SecurityGroupOperationUpdateMembers, start, err)
 		if err != nil {
 			ch <- err
 			return
@@ -105,13 +139,25 @@ func (sg *SecurityGroupImpl) UpdateSecurityGroupRules(addressGroupIdentifier *se
 	go func() {
 		defer close(ch)
 
-		cloudInterface, err := getCloudInterfaceForCloudResource(addressGroupIdentifier)
+		cloudInterface, providerType, err := getCloudInterfaceForCloudResource(addressGroupIdentifier)
 		if err != nil {
 			ch <- err
 			return
 		}
 
+		if account, ok := getDryRunAccount(cloudInterface, addressGroupIdentifier); ok {
+			recordPlannedOperation(account, addressGroupIdentifier, false, cloudv1alpha1.SecurityGroupOperationUpdateRules,
+				func(sg *cloudv1alpha1.PlannedSecurityGroup) {
+					sg.IngressRules = plannedIngressRules(ingressRules)
+					sg.EgressRules = plannedEgressRules(egressRules)
+				})
+			ch <- nil
+			return
+		}
+
+		start := time.Now()
 		err = cloudInterface.UpdateSecurityGroupRules(addressGroupIdentifier, ingressRules, egressRules)
+		recordSecurityGroupOperationMetrics(providerType, metrics.SecurityGroupOperationUpdateRules, start, err)
 		if err != nil {
 			ch <- err
 			return
@@ -129,13 +175,21 @@ func (sg *SecurityGroupImpl) DeleteSecurityGroup(addressGroupIdentifier *securit
 	go func() {
 		defer close(ch)
 
-		cloudInterface, err := getCloudInterfaceForCloudResource(addressGroupIdentifier)
+		cloudInterface, providerType, err := getCloudInterfaceForCloudResource(addressGroupIdentifier)
 		if err != nil {
 			ch <- err
 			return
 		}
 
+		if account, ok := getDryRunAccount(cloudInterface, addressGroupIdentifier); ok {
+			recordPlannedOperation(account, addressGroupIdentifier, membershipOnly, cloudv1alpha1.SecurityGroupOperationDelete, nil)
+			ch <- nil
+			return
+		}
+
+		start := time.Now()
 		err = cloudInterface.DeleteSecurityGroup(addressGroupIdentifier, membershipOnly)
+		recordSecurityGroupOperationMetrics(providerType, metrics.SecurityGroupOperationDelete, start, err)
 		if err != nil {
 			ch <- err
 			return
@@ -147,13 +201,17 @@ func (sg *SecurityGroupImpl) DeleteSecurityGroup(addressGroupIdentifier *securit
 	return ch
 }
 
-func (sg *SecurityGroupImpl) GetSecurityGroupSyncChan() <-chan securitygroup.SynchronizationContent {
+func (sg *SecurityGroupImpl) GetSecurityGroupSyncChan() (<-chan securitygroup.SynchronizationContent, <-chan error) {
 	retCh := make(chan securitygroup.SynchronizationContent)
+	retErrCh := make(chan error, 1)
 
 	go func() {
+		defer close(retErrCh)
 		defer close(retCh)
 
 		var wg sync.WaitGroup
+		var retErr error
+		var errMutex sync.Mutex
 		ch := make(chan []securitygroup.SynchronizationContent)
 		providerTypes := GetSupportedCloudProviderTypes()
 
@@ -171,8 +229,14 @@ func (sg *SecurityGroupImpl) GetSecurityGroupSyncChan() <-chan securitygroup.Syn
 			}
 
 			go func() {
-				ch <- cloudInterface.GetEnforcedSecurity()
-				wg.Done()
+				defer wg.Done()
+				cloudView, err := cloudInterface.GetEnforcedSecurity()
+				if err != nil {
+					errMutex.Lock()
+					retErr = multierr.Append(retErr, err)
+					errMutex.Unlock()
+				}
+				ch <- cloudView
 			}()
 		}
 
@@ -181,7 +245,10 @@ func (sg *SecurityGroupImpl) GetSecurityGroupSyncChan() <-chan securitygroup.Syn
 				retCh <- sg
 			}
 		}
+		if retErr != nil {
+			retErrCh <- retErr
+		}
 	}()
 
-	return retCh
+	return retCh, retErrCh
 }
@@ -17,6 +17,7 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
@@ -25,9 +26,20 @@ import (
 	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
 )
 
+// VirtualMachineProperties are cloud discovered properties of a VirtualMachine.
+type VirtualMachineProperties struct {
+	Region           string
+	AvailabilityZone string
+	InstanceType     string
+	ImageID          string
+	OSType           string
+	LaunchTime       *time.Time
+	SecurityGroups   []string
+}
+
 func GenerateVirtualMachineCRD(crdName string, cloudName string, cloudID string, namespace string, cloudNetwork string,
 	shortNetworkID string, status string, tags map[string]string, networkInterfaces []cloudv1alpha1.NetworkInterface,
-	provider cloudcommon.ProviderType) *cloudv1alpha1.VirtualMachine {
+	properties *VirtualMachineProperties, provider cloudcommon.ProviderType) *cloudv1alpha1.VirtualMachine {
 	vmStatus := &cloudv1alpha1.VirtualMachineStatus{
 		Provider:            cloudv1alpha1.CloudProvider(provider),
 		VirtualPrivateCloud: shortNetworkID,
@@ -35,6 +47,19 @@ func GenerateVirtualMachineCRD(crdName string, cloudName string, cloudID string,
 		State:               status,
 		NetworkInterfaces:   networkInterfaces,
 	}
+	if properties != nil {
+		vmStatus.Region = properties.Region
+		vmStatus.AvailabilityZone = properties.AvailabilityZone
+		vmStatus.InstanceType = properties.InstanceType
+		vmStatus.ImageID = properties.ImageID
+		vmStatus.OSType = strings.ToLower(properties.OSType)
+		vmStatus.SecurityGroups = properties.SecurityGroups
+		if properties.LaunchTime != nil {
+			// status is stored with seconds precision, truncate to detect changes correctly.
+			launchTime := v1.NewTime(properties.LaunchTime.Truncate(time.Second))
+			vmStatus.LaunchTime = &launchTime
+		}
+	}
 	annotationsMap := map[string]string{
 		cloudcommon.AnnotationCloudAssignedIDKey:    cloudID,
 		cloudcommon.AnnotationCloudAssignedNameKey:  cloudName,
@@ -58,6 +83,39 @@ func GenerateVirtualMachineCRD(crdName string, cloudName string, cloudID string,
 	return vmCrd
 }
 
+func GenerateVpcCRD(crdName string, cloudName string, cloudID string, namespace string, region string, cidrs []string,
+	tags map[string]string, peerings []string, provider cloudcommon.ProviderType) *cloudv1alpha1.Vpc {
+	vpcStatus := &cloudv1alpha1.VpcStatus{
+		Provider: cloudv1alpha1.CloudProvider(provider),
+		ID:       cloudID,
+		Name:     cloudName,
+		Region:   region,
+		CIDRs:    cidrs,
+		Tags:     tags,
+		Peerings: peerings,
+	}
+	annotationsMap := map[string]string{
+		cloudcommon.AnnotationCloudAssignedIDKey:   cloudID,
+		cloudcommon.AnnotationCloudAssignedNameKey: cloudName,
+	}
+
+	vpcCrd := &cloudv1alpha1.Vpc{
+		TypeMeta: v1.TypeMeta{
+			Kind:       cloudcommon.VpcCRDKind,
+			APIVersion: cloudcommon.APIVersion,
+		},
+		ObjectMeta: v1.ObjectMeta{
+			UID:         uuid.NewUUID(),
+			Name:        crdName,
+			Namespace:   namespace,
+			Annotations: annotationsMap,
+		},
+		Status: *vpcStatus,
+	}
+
+	return vpcCrd
+}
+
 func GenerateShortResourceIdentifier(id string, prefixToAdd string) string {
 	idTrim := strings.Trim(id, " ")
 	if len(idTrim) == 0 {
@@ -15,26 +15,47 @@
 package cloud
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"github.com/go-logr/logr"
 	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
 	"strings"
+	"sync"
 
 	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
 	cloudprovider "antrea.io/nephe/pkg/cloud-provider"
 	"antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 const (
 	accountResourceToCreate = "TO_CREATE"
 	accountResourceToDelete = "TO_DELETE"
 	accountResourceToUpdate = "TO_UPDATE"
+
+	// accountPollerFieldOwner is the field manager of VirtualMachine CRDs applied by account pollers.
+	accountPollerFieldOwner = "nephe-account-poller"
+	// legacyVirtualMachineFieldOwner is the field manager of VirtualMachine CRDs created and updated, instead of
+	// applied, by earlier releases. It is the default field manager, i.e. the binary name, of the controller.
+	legacyVirtualMachineFieldOwner = "nephe-controller"
+	// vmOperationWorkers is the maximum number of concurrent VirtualMachine CRD writes of an account poller.
+	vmOperationWorkers = 8
+	// vmMissedPollsToDelete is the number of consecutive polls a virtual machine is not discovered before its
+	// VirtualMachine CRD is deleted.
+	vmMissedPollsToDelete = 3
+	// vmCacheResyncPolls is the number of polls after which the desired state cache is resynced with VirtualMachine
+	// CRDs.
+	vmCacheResyncPolls = 10
 )
 
 type accountPoller struct {
@@ -47,182 +68,500 @@ type accountPoller struct {
 	namespacedName    *types.NamespacedName
 	selector          *cloudv1alpha1.CloudEntitySelector
 	ch                chan struct{}
+	// mutex serializes periodic polls and polls triggered by inventory events.
+	mutex sync.Mutex
+
+	// vmCache is the desired state of VirtualMachine CRDs owned by the selector, as last written by the poller.
+	vmCache map[string]*cloudv1alpha1.VirtualMachine
+	// vmMissedPolls is the number of consecutive periodic polls a cached virtual machine is not discovered.
+	vmMissedPolls map[string]int
+	// vmCachePolls is the number of polls since vmCache is synced with VirtualMachine CRDs.
+	vmCachePolls int
 }
 
+// doAccountPoller polls the account periodically.
 func (p *accountPoller) doAccountPoller() {
+	p.pollAccount(true)
+}
+
+// pollAccount polls the account, and reconciles CRDs owned by the selector with its inventory. periodic is false if the
+// poll is triggered by inventory events.
+func (p *accountPoller) pollAccount(periodic bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
 	cloudInterface, e := cloudprovider.GetCloudInterface(common.ProviderType(p.cloudType))
 	if e != nil {
 		p.log.Info("failed to get cloud interface", "account", p.namespacedName, "error", e)
 		return
 	}
+	p.pollAccountInventory(cloudInterface, periodic)
+}
 
+// pollAccountInventory updates account status with inventory of cloudInterface, and reconciles CRDs owned by the
+// selector with the inventory. CRDs of a kind are not reconciled if their discovery fails, so that they are not deleted
+// on cloud API failures.
+func (p *accountPoller) pollAccountInventory(cloudInterface common.CloudInterface, periodic bool) {
 	account := &cloudv1alpha1.CloudProviderAccount{}
-	e = p.Get(context.TODO(), *p.namespacedName, account)
+	e := p.Get(context.TODO(), *p.namespacedName, account)
 	if e != nil {
 		p.log.Info("failed to get account", "account", p.namespacedName, "account", account, "error", e)
 	}
 
+	virtualMachines, vmErr := p.getComputeResources(cloudInterface)
+	if vmErr != nil {
+		p.log.Info("failed to discover compute resources", "account", p.namespacedName, "error", vmErr)
+	} else {
+		metrics.InventoryVirtualMachines.WithLabelValues(p.namespacedName.Namespace, p.namespacedName.Name).
+			Set(float64(len(virtualMachines)))
+	}
+
 	discoveredstatus, e := cloudInterface.GetAccountStatus(p.namespacedName)
 	if e != nil {
 		p.log.Info("failed to get account status", "account", p.namespacedName, "error", e)
 	} else {
-		updateAccountStatus(&account.Status, discoveredstatus)
+		if vmErr != nil {
+			// keep counts of last discovery.
+			discoveredstatus.DiscoveredVirtualMachines = account.Status.DiscoveredVirtualMachines
+			discoveredstatus.DiscoveredVpcs = account.Status.DiscoveredVpcs
+		} else {
+			setDiscoveredResourceCounts(discoveredstatus, virtualMachines)
+		}
+		updateAccountStatus(&account.Status, discoveredstatus, account.Generation)
 	}
+	account.Status.PlannedSecurityGroups = cloudprovider.GetPlannedSecurityGroups(p.namespacedName)
 
 	e = p.Client.Status().Update(context.TODO(), account)
 	if e != nil {
 		p.log.Info("failed to update account status", "account", p.namespacedName, "err", e)
 	}
-	virtualMachines := p.getComputeResources(cloudInterface)
 
-	e = p.doVirtualMachineOperations(virtualMachines)
+	if vpcs, e := p.getVpcResources(cloudInterface); e != nil {
+		p.log.Info("failed to discover vpc resources", "account", p.namespacedName, "error", e)
+	} else if e = p.doVpcOperations(vpcs); e != nil {
+		p.log.Info("failed to perform vpc operations", "account", p.namespacedName, "error", e)
+	}
+
+	if vmErr != nil {
+		return
+	}
+	e = p.doVirtualMachineOperations(virtualMachines, periodic)
 	if e != nil {
 		p.log.Info("failed to perform virtual-machine operations", "account", p.namespacedName, "error", e)
 	}
 }
 
-func (p *accountPoller) getComputeResources(cloudInterface common.CloudInterface) []*cloudv1alpha1.VirtualMachine {
-	var e error
+// watchAccountInventory polls the account whenever its inventory is updated by cloud resource events, in between
+// periodic polls, until the poller is stopped.
+func (p *accountPoller) watchAccountInventory(cloudInterface common.CloudInterface) {
+	inventoryUpdated, e := cloudInterface.WatchAccountInventory(p.namespacedName, p.ch)
+	if e != nil {
+		p.log.Info("failed to watch account inventory", "account", p.namespacedName, "error", e)
+		return
+	}
+	for {
+		select {
+		case <-p.ch:
+			return
+		case <-inventoryUpdated:
+			p.pollAccount(false)
+		}
+	}
+}
 
+func (p *accountPoller) getComputeResources(cloudInterface common.CloudInterface) ([]*cloudv1alpha1.VirtualMachine, error) {
 	virtualMachines, e := cloudInterface.InstancesGivenProviderAccount(p.namespacedName)
 	if e != nil {
-		p.log.Info("failed to discover compute resources", "account", p.namespacedName, "error", e)
-		return []*cloudv1alpha1.VirtualMachine{}
+		return nil, e
 	}
 
 	p.log.Info("discovered compute resources statistics", "account", p.namespacedName, "virtual-machines",
 		len(virtualMachines))
 
-	return virtualMachines
+	return virtualMachines, nil
+}
+
+func (p *accountPoller) getVpcResources(cloudInterface common.CloudInterface) ([]*cloudv1alpha1.Vpc, error) {
+	return cloudInterface.VpcsGivenProviderAccount(p.namespacedName)
 }
 
-func (p *accountPoller) doVirtualMachineOperations(virtualMachines []*cloudv1alpha1.VirtualMachine) error {
-	virtualMachinesBasedOnOperation, err := p.findVirtualMachinesByOperation(virtualMachines)
+// doVpcOperations creates, updates and deletes Vpc CRDs owned by the selector to match discovered vpcs.
+func (p *accountPoller) doVpcOperations(discoveredVpcs []*cloudv1alpha1.Vpc) error {
+	currentVpcList := &cloudv1alpha1.VpcList{}
+	err := p.Client.List(context.TODO(), currentVpcList, client.InNamespace(p.selector.Namespace))
 	if err != nil {
 		return err
 	}
+	ownerSelector := map[string]*cloudv1alpha1.CloudEntitySelector{p.selector.Name: p.selector}
+	currentVpcsByName := make(map[string]*cloudv1alpha1.Vpc)
+	for i := range currentVpcList.Items {
+		currentVpc := &currentVpcList.Items[i]
+		if isOwnedBy(currentVpc.OwnerReferences, ownerSelector) {
+			currentVpcsByName[currentVpc.Name] = currentVpc
+		}
+	}
 
-	virtualMachinesToCreate, found := virtualMachinesBasedOnOperation[accountResourceToCreate]
-	if found {
-		for _, vm := range virtualMachinesToCreate {
-			e := controllerutil.SetControllerReference(p.selector, vm, p.scheme)
-			if e != nil {
-				p.log.Info("error setting controller owner reference", "err", e)
-				err = multierr.Append(err, e)
-				continue
-			}
-			// save status since Create will update vm object and remove status field from it
-			vmStatus := vm.Status
-			e = p.Client.Create(context.TODO(), vm)
-			if e != nil {
-				p.log.Info("virtual machine create failed", "name", vm.Name, "err", e)
-				err = multierr.Append(err, e)
+	var created, updated, deleted int
+	for _, vpc := range discoveredVpcs {
+		currentVpc, found := currentVpcsByName[vpc.Name]
+		if found {
+			delete(currentVpcsByName, vpc.Name)
+			if areDiscoveredFieldsSameVpcStatus(currentVpc.Status, vpc.Status) {
 				continue
 			}
-			vm.Status = vmStatus
-			e = p.Client.Status().Update(context.TODO(), vm)
-			if e != nil {
-				p.log.Info("virtual machine status update failed", "account", p.namespacedName, "name", vm.Name, "err", e)
+			currentVpc.Status = vpc.Status
+			if e := p.Client.Status().Update(context.TODO(), currentVpc); e != nil {
+				p.log.Info("vpc status update failed", "account", p.namespacedName, "name", vpc.Name, "err", e)
 				err = multierr.Append(err, e)
 				continue
 			}
+			updated++
+			continue
+		}
+
+		e := controllerutil.SetControllerReference(p.selector, vpc, p.scheme)
+		if e != nil {
+			p.log.Info("error setting controller owner reference", "err", e)
+			err = multierr.Append(err, e)
+			continue
+		}
+		// save status since Create will update vpc object and remove status field from it
+		vpcStatus := vpc.Status
+		if e = p.Client.Create(context.TODO(), vpc); e != nil {
+			p.log.Info("vpc create failed", "name", vpc.Name, "err", e)
+			err = multierr.Append(err, e)
+			continue
+		}
+		vpc.Status = vpcStatus
+		if e = p.Client.Status().Update(context.TODO(), vpc); e != nil {
+			p.log.Info("vpc status update failed", "account", p.namespacedName, "name", vpc.Name, "err", e)
+			err = multierr.Append(err, e)
+			continue
 		}
+		created++
 	}
 
-	virtualMachinesToDelete, found := virtualMachinesBasedOnOperation[accountResourceToDelete]
-	if found {
-		for _, vm := range virtualMachinesToDelete {
-			e := p.Delete(context.TODO(), vm)
-			if e != nil {
-				if client.IgnoreNotFound(e) != nil {
-					err = multierr.Append(err, e)
-					p.log.Info("unable to delete", "vm-name", vm.Name)
-					continue
-				}
-			}
-			p.log.Info("deleted", "vm-name", vm.Name)
+	// All entries remaining in currentVpcsByName are no longer in cloud.
+	for _, vpc := range currentVpcsByName {
+		if e := p.Delete(context.TODO(), vpc); client.IgnoreNotFound(e) != nil {
+			p.log.Info("unable to delete", "vpc-name", vpc.Name)
+			err = multierr.Append(err, e)
+			continue
 		}
+		deleted++
 	}
 
-	virtualMachinesToUpdate, found := virtualMachinesBasedOnOperation[accountResourceToUpdate]
-	if found {
-		for _, vm := range virtualMachinesToUpdate {
-			vmNamespacedName := types.NamespacedName{
-				Namespace: vm.Namespace,
-				Name:      vm.Name,
-			}
-			currentVM := &cloudv1alpha1.VirtualMachine{}
-			e := p.Get(context.TODO(), vmNamespacedName, currentVM)
-			if e != nil {
-				if client.IgnoreNotFound(e) != nil {
-					err = multierr.Append(err, e)
-					p.log.Info("unable to find to update", "vm-name", vm.Name)
-					continue
-				}
-			}
+	if created != 0 || updated != 0 || deleted != 0 {
+		p.log.Info("vpc crd statistics", "account", p.namespacedName, "created", created, "deleted", deleted,
+			"updated", updated)
+	}
+	return err
+}
 
-			updateCloudDiscoveredFieldsOfVirtualMachineStatus(&currentVM.Status, &vm.Status)
-			e = p.Client.Status().Update(context.TODO(), currentVM)
-			if e != nil {
-				p.log.Info("virtual machine status update failed", "account", p.namespacedName, "name", vm.Name, "err", e)
-				err = multierr.Append(err, e)
-				continue
-			}
-			p.log.Info("updated", "vm-name", vm.Name)
+// doVirtualMachineOperations reconciles VirtualMachine CRDs owned by the selector with discovered virtual machines. It
+// diffs against the desired state cache of the poller, instead of listing VirtualMachine CRDs on every poll, and writes
+// VirtualMachine CRDs concurrently, with at most vmOperationWorkers writes at a time. Cached virtual machines not
+// discovered count as missed only if periodic is true.
+func (p *accountPoller) doVirtualMachineOperations(virtualMachines []*cloudv1alpha1.VirtualMachine, periodic bool) error {
+	err := p.syncVirtualMachineCache()
+	if err != nil {
+		return err
+	}
+	virtualMachinesBasedOnOperation := p.findVirtualMachinesByOperation(virtualMachines, periodic)
+
+	virtualMachinesToCreate := virtualMachinesBasedOnOperation[accountResourceToCreate]
+	var created int
+	for i, e := range p.runVirtualMachineOperations(virtualMachinesToCreate, p.createVirtualMachine) {
+		vm := virtualMachinesToCreate[i]
+		if e != nil {
+			// not cached, create is retried on next poll.
+			p.log.Info("virtual machine create failed", "account", p.namespacedName, "name", vm.Name, "err", e)
+			err = multierr.Append(err, e)
+			continue
+		}
+		p.vmCache[vm.Name] = vm
+		created++
+	}
+
+	virtualMachinesToDelete := virtualMachinesBasedOnOperation[accountResourceToDelete]
+	var deleted int
+	for i, e := range p.runVirtualMachineOperations(virtualMachinesToDelete, p.deleteVirtualMachine) {
+		vm := virtualMachinesToDelete[i]
+		if e != nil {
+			// still cached, delete is retried on next poll.
+			p.log.Info("unable to delete", "account", p.namespacedName, "vm-name", vm.Name, "err", e)
+			err = multierr.Append(err, e)
+			continue
+		}
+		delete(p.vmCache, vm.Name)
+		delete(p.vmMissedPolls, vm.Name)
+		p.log.Info("deleted", "vm-name", vm.Name)
+		deleted++
+	}
+
+	virtualMachinesToUpdate := virtualMachinesBasedOnOperation[accountResourceToUpdate]
+	var updated int
+	for i, e := range p.runVirtualMachineOperations(virtualMachinesToUpdate, p.applyVirtualMachineStatus) {
+		vm := virtualMachinesToUpdate[i]
+		if e != nil {
+			// cached status differs from discovered one, update is retried on next poll.
+			p.log.Info("virtual machine status update failed", "account", p.namespacedName, "name", vm.Name, "err", e)
+			err = multierr.Append(err, e)
+			continue
 		}
+		p.vmCache[vm.Name].Status = vm.Status
+		p.log.V(1).Info("updated", "vm-name", vm.Name)
+		updated++
 	}
 
 	if len(virtualMachinesToCreate) != 0 || len(virtualMachinesToDelete) != 0 || len(virtualMachinesToUpdate) != 0 {
 		p.log.Info("virtual-machine crd statistics", "account", p.namespacedName,
-			"created", len(virtualMachinesToCreate), "deleted", len(virtualMachinesToDelete), "updated", len(virtualMachinesToUpdate))
+			"created", created, "deleted", deleted, "updated", updated, "failed",
+			len(virtualMachinesToCreate)+len(virtualMachinesToDelete)+len(virtualMachinesToUpdate)-created-deleted-updated)
 	}
 
 	return err
 }
 
-func (p *accountPoller) findVirtualMachinesByOperation(discoveredVirtualMachines []*cloudv1alpha1.VirtualMachine) (
-	map[string][]*cloudv1alpha1.VirtualMachine, error) {
-	virtualMachinesByOperation := make(map[string][]*cloudv1alpha1.VirtualMachine)
+// syncVirtualMachineCache initializes the desired state cache with VirtualMachine CRDs owned by the selector, and
+// resyncs it every vmCacheResyncPolls polls, so that VirtualMachine CRDs changed or deleted by others are reconciled.
+// Field ownership of VirtualMachine CRDs written by earlier releases is migrated on sync.
+func (p *accountPoller) syncVirtualMachineCache() error {
+	p.vmCachePolls++
+	if p.vmCache != nil && p.vmCachePolls < vmCacheResyncPolls {
+		return nil
+	}
 
 	currentVirtualMachinesByName, err := p.getCurrentVirtualMachinesByName()
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	// if no virtual machines in etcd, all discovered needs to be created.
-	if len(currentVirtualMachinesByName) == 0 {
-		virtualMachinesByOperation[accountResourceToCreate] = discoveredVirtualMachines
-		return virtualMachinesByOperation, nil
+	p.vmCache = make(map[string]*cloudv1alpha1.VirtualMachine, len(currentVirtualMachinesByName))
+	for name := range currentVirtualMachinesByName {
+		currentVirtualMachine := currentVirtualMachinesByName[name]
+		if e := p.migrateVirtualMachineFieldOwnership(&currentVirtualMachine); e != nil {
+			// retried on next sync.
+			p.log.Info("failed to migrate virtual machine field ownership", "account", p.namespacedName,
+				"vm-name", name, "err", e)
+		}
+		p.vmCache[name] = &currentVirtualMachine
+	}
+	if p.vmMissedPolls == nil {
+		p.vmMissedPolls = make(map[string]int)
+	}
+	for name := range p.vmMissedPolls {
+		if _, found := p.vmCache[name]; !found {
+			delete(p.vmMissedPolls, name)
+		}
 	}
+	p.vmCachePolls = 0
+	return nil
+}
+
+// findVirtualMachinesByOperation diffs discovered virtual machines against the desired state cache. A cached virtual
+// machine is deleted only if not discovered for vmMissedPollsToDelete consecutive periodic polls, so that VirtualMachine
+// CRDs survive partial failures of cloud APIs, however frequently inventory events trigger polls.
+func (p *accountPoller) findVirtualMachinesByOperation(discoveredVirtualMachines []*cloudv1alpha1.VirtualMachine,
+	periodic bool) map[string][]*cloudv1alpha1.VirtualMachine {
+	virtualMachinesByOperation := make(map[string][]*cloudv1alpha1.VirtualMachine)
 
-	// find virtual machines to be created.
-	// And also removed any vm which needs to be created from currentVirtualMachineByName map.
-	var virtualMachinesToCreate []*cloudv1alpha1.VirtualMachine
-	var virtualMachinesToUpdate []*cloudv1alpha1.VirtualMachine
+	discoveredVirtualMachineNames := make(map[string]struct{}, len(discoveredVirtualMachines))
 	for _, discoveredVirtualMachine := range discoveredVirtualMachines {
-		currentVirtualMachine, found := currentVirtualMachinesByName[discoveredVirtualMachine.Name]
+		discoveredVirtualMachineNames[discoveredVirtualMachine.Name] = struct{}{}
+		delete(p.vmMissedPolls, discoveredVirtualMachine.Name)
+		cachedVirtualMachine, found := p.vmCache[discoveredVirtualMachine.Name]
+		setVirtualMachineStateHistory(discoveredVirtualMachine, cachedVirtualMachine)
 		if !found {
-			virtualMachinesToCreate = append(virtualMachinesToCreate, discoveredVirtualMachine)
+			virtualMachinesByOperation[accountResourceToCreate] = append(virtualMachinesByOperation[accountResourceToCreate],
+				discoveredVirtualMachine)
+		} else if !areDiscoveredFieldsSameVirtualMachineStatus(cachedVirtualMachine.Status, discoveredVirtualMachine.Status) ||
+			len(cachedVirtualMachine.Status.StateHistory) != len(discoveredVirtualMachine.Status.StateHistory) {
+			virtualMachinesByOperation[accountResourceToUpdate] = append(virtualMachinesByOperation[accountResourceToUpdate],
+				discoveredVirtualMachine)
+		}
+	}
+
+	for name, cachedVirtualMachine := range p.vmCache {
+		if _, found := discoveredVirtualMachineNames[name]; found {
+			continue
+		}
+		if periodic {
+			p.vmMissedPolls[name]++
+		}
+		if p.vmMissedPolls[name] < vmMissedPollsToDelete {
+			p.log.V(1).Info("virtual machine not discovered", "account", p.namespacedName, "vm-name", name,
+				"missed-polls", p.vmMissedPolls[name])
+			continue
+		}
+		virtualMachinesByOperation[accountResourceToDelete] = append(virtualMachinesByOperation[accountResourceToDelete],
+			cachedVirtualMachine.DeepCopy())
+	}
+
+	return virtualMachinesByOperation
+}
+
+// setVirtualMachineStateHistory carries the state history of the cached VirtualMachine over to the discovered one, and
+// records a transition if the discovered state differs from the last recorded one. At most
+// MaxVirtualMachineStateHistory transitions are kept.
+func setVirtualMachineStateHistory(discovered, cached *cloudv1alpha1.VirtualMachine) {
+	var history []cloudv1alpha1.VirtualMachineStateTransition
+	if cached != nil {
+		history = cached.Status.DeepCopy().StateHistory
+	}
+	state := discovered.Status.State
+	if len(state) != 0 && (len(history) == 0 || history[len(history)-1].State != state) {
+		history = append(history, cloudv1alpha1.VirtualMachineStateTransition{State: state, TransitionTime: metav1.Now()})
+	}
+	if len(history) > cloudv1alpha1.MaxVirtualMachineStateHistory {
+		history = history[len(history)-cloudv1alpha1.MaxVirtualMachineStateHistory:]
+	}
+	discovered.Status.StateHistory = history
+}
+
+// runVirtualMachineOperations runs operation on virtual machines concurrently, and returns error of each.
+func (p *accountPoller) runVirtualMachineOperations(virtualMachines []*cloudv1alpha1.VirtualMachine,
+	operation func(vm *cloudv1alpha1.VirtualMachine) error) []error {
+	errs := make([]error, len(virtualMachines))
+	workqueue.ParallelizeUntil(context.TODO(), vmOperationWorkers, len(virtualMachines), func(i int) {
+		errs[i] = operation(virtualMachines[i])
+	})
+	return errs
+}
+
+// createVirtualMachine creates the VirtualMachine CRD owned by the selector, and applies its status. The CRD is
+// applied, so that creating a VirtualMachine CRD which already exists succeeds.
+func (p *accountPoller) createVirtualMachine(vm *cloudv1alpha1.VirtualMachine) error {
+	vmToApply := vm.DeepCopy()
+	vmToApply.TypeMeta = virtualMachineTypeMeta()
+	vmToApply.Status = cloudv1alpha1.VirtualMachineStatus{}
+	if err := controllerutil.SetControllerReference(p.selector, vmToApply, p.scheme); err != nil {
+		return fmt.Errorf("error setting controller owner reference: %v", err)
+	}
+	if err := p.Client.Patch(context.TODO(), vmToApply, client.Apply, client.FieldOwner(accountPollerFieldOwner),
+		client.ForceOwnership); err != nil {
+		return err
+	}
+	return p.applyVirtualMachineStatus(vm)
+}
+
+// applyVirtualMachineStatus applies discovered status of the VirtualMachine CRD with server-side apply, without reading
+// the VirtualMachine CRD first.
+func (p *accountPoller) applyVirtualMachineStatus(vm *cloudv1alpha1.VirtualMachine) error {
+	vmToApply := &cloudv1alpha1.VirtualMachine{
+		TypeMeta: virtualMachineTypeMeta(),
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vm.Namespace,
+			Name:      vm.Name,
+		},
+		Status: *vm.Status.DeepCopy(),
+	}
+	return p.Client.Status().Patch(context.TODO(), vmToApply, client.Apply, client.FieldOwner(accountPollerFieldOwner),
+		client.ForceOwnership)
+}
+
+// migrateVirtualMachineFieldOwnership transfers fields of the VirtualMachine CRD owned by legacyVirtualMachineFieldOwner
+// to accountPollerFieldOwner. Otherwise fields no longer applied by the account poller, e.g. removed network
+// interfaces, are kept as owned by the legacy field manager. It is a no-op once fields are transferred.
+func (p *accountPoller) migrateVirtualMachineFieldOwnership(vm *cloudv1alpha1.VirtualMachine) error {
+	managedFields, migrated, err := migrateManagedFields(vm.ManagedFields, legacyVirtualMachineFieldOwner,
+		accountPollerFieldOwner)
+	if err != nil || !migrated {
+		return err
+	}
+	vmToPatch := vm.DeepCopy()
+	vmToPatch.ManagedFields = managedFields
+	if err := p.Client.Patch(context.TODO(), vmToPatch, client.MergeFromWithOptions(vm,
+		client.MergeFromWithOptimisticLock{})); err != nil {
+		return err
+	}
+	p.log.Info("migrated virtual machine field ownership", "account", p.namespacedName, "vm-name", vm.Name)
+	vm.ManagedFields = vmToPatch.ManagedFields
+	vm.ResourceVersion = vmToPatch.ResourceVersion
+	return nil
+}
+
+// migrateManagedFields merges fields owned by Update operations of field manager from into the Apply entry of field
+// manager to, per subresource. It returns false if there are no fields owned by Update operations of from.
+func migrateManagedFields(entries []metav1.ManagedFieldsEntry, from, to string) ([]metav1.ManagedFieldsEntry, bool,
+	error) {
+	var migrated []metav1.ManagedFieldsEntry
+	var subresources []string
+	legacyEntries := make(map[string]metav1.ManagedFieldsEntry)
+	legacyFields := make(map[string]*fieldpath.Set)
+	for _, entry := range entries {
+		if entry.Manager != from || entry.Operation != metav1.ManagedFieldsOperationUpdate {
+			migrated = append(migrated, entry)
+			continue
+		}
+		fields, err := decodeManagedFields(entry)
+		if err != nil {
+			return nil, false, err
+		}
+		if _, ok := legacyFields[entry.Subresource]; !ok {
+			subresources = append(subresources, entry.Subresource)
+			legacyEntries[entry.Subresource] = entry
+			legacyFields[entry.Subresource] = fields
+			continue
+		}
+		legacyFields[entry.Subresource] = legacyFields[entry.Subresource].Union(fields)
+	}
+	if len(subresources) == 0 {
+		return entries, false, nil
+	}
+
+	for _, subresource := range subresources {
+		fields := legacyFields[subresource]
+		index := -1
+		for i, entry := range migrated {
+			if entry.Manager == to && entry.Operation == metav1.ManagedFieldsOperationApply &&
+				entry.Subresource == subresource {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			entry := legacyEntries[subresource]
+			entry.Manager = to
+			entry.Operation = metav1.ManagedFieldsOperationApply
+			migrated = append(migrated, entry)
+			index = len(migrated) - 1
 		} else {
-			delete(currentVirtualMachinesByName, currentVirtualMachine.Name)
-			if !areDiscoveredFieldsSameVirtualMachineStatus(currentVirtualMachine.Status, discoveredVirtualMachine.Status) {
-				virtualMachinesToUpdate = append(virtualMachinesToUpdate, discoveredVirtualMachine)
+			applied, err := decodeManagedFields(migrated[index])
+			if err != nil {
+				return nil, false, err
 			}
+			fields = fields.Union(applied)
 		}
+		raw, err := fields.ToJSON()
+		if err != nil {
+			return nil, false, err
+		}
+		migrated[index].FieldsType = "FieldsV1"
+		migrated[index].FieldsV1 = &metav1.FieldsV1{Raw: raw}
 	}
+	return migrated, true, nil
+}
 
-	// find virtual machines to be deleted.
-	// All entries remaining in currentVirtualMachineByName are to be deleted from etcd
-	var virtualMachinesToDelete []*cloudv1alpha1.VirtualMachine
-	for _, vmToDelete := range currentVirtualMachinesByName {
-		virtualMachinesToDelete = append(virtualMachinesToDelete, vmToDelete.DeepCopy())
+func decodeManagedFields(entry metav1.ManagedFieldsEntry) (*fieldpath.Set, error) {
+	fields := &fieldpath.Set{}
+	if entry.FieldsV1 == nil {
+		return fields, nil
 	}
+	if err := fields.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+		return nil, fmt.Errorf("failed to decode managed fields of %v: %w", entry.Manager, err)
+	}
+	return fields, nil
+}
 
-	virtualMachinesByOperation[accountResourceToCreate] = virtualMachinesToCreate
-	virtualMachinesByOperation[accountResourceToDelete] = virtualMachinesToDelete
-	virtualMachinesByOperation[accountResourceToUpdate] = virtualMachinesToUpdate
+func (p *accountPoller) deleteVirtualMachine(vm *cloudv1alpha1.VirtualMachine) error {
+	return client.IgnoreNotFound(p.Delete(context.TODO(), vm))
+}
 
-	return virtualMachinesByOperation, nil
+func virtualMachineTypeMeta() metav1.TypeMeta {
+	return metav1.TypeMeta{
+		APIVersion: cloudv1alpha1.GroupVersion.String(),
+		Kind:       reflect.TypeOf(cloudv1alpha1.VirtualMachine{}).Name(),
+	}
 }
 
 func (p *accountPoller) getCurrentVirtualMachinesByName() (map[string]cloudv1alpha1.VirtualMachine, error) {
@@ -247,8 +586,12 @@ func (p *accountPoller) getCurrentVirtualMachinesByName() (map[string]cloudv1alp
 
 func isVirtualMachineOwnedBy(virtualMachine cloudv1alpha1.VirtualMachine,
 	ownerSelector map[string]*cloudv1alpha1.CloudEntitySelector) bool {
-	vmOwnerReferences := virtualMachine.OwnerReferences
-	for _, vmOwnerReference := range vmOwnerReferences {
+	return isOwnedBy(virtualMachine.OwnerReferences, ownerSelector)
+}
+
+// isOwnedBy returns true if owner references include one of the selectors.
+func isOwnedBy(ownerReferences []metav1.OwnerReference, ownerSelector map[string]*cloudv1alpha1.CloudEntitySelector) bool {
+	for _, vmOwnerReference := range ownerReferences {
 		vmOwnerName := vmOwnerReference.Name
 		vmOwnerKind := vmOwnerReference.Kind
 
@@ -274,6 +617,16 @@ func areDiscoveredFieldsSameVirtualMachineStatus(s1, s2 cloudv1alpha1.VirtualMac
 	if s1.VirtualPrivateCloud != s2.VirtualPrivateCloud {
 		return false
 	}
+	if s1.Region != s2.Region {
+		return false
+	}
+	if s1.InstanceType != s2.InstanceType || s1.AvailabilityZone != s2.AvailabilityZone || s1.ImageID != s2.ImageID ||
+		s1.OSType != s2.OSType {
+		return false
+	}
+	if !areTimesSame(s1.LaunchTime, s2.LaunchTime) || !areStringsSame(s1.SecurityGroups, s2.SecurityGroups) {
+		return false
+	}
 	if len(s1.Tags) != len(s2.Tags) ||
 		len(s1.NetworkInterfaces) != len(s2.NetworkInterfaces) {
 		return false
@@ -287,6 +640,35 @@ func areDiscoveredFieldsSameVirtualMachineStatus(s1, s2 cloudv1alpha1.VirtualMac
 	return true
 }
 
+func areDiscoveredFieldsSameVpcStatus(s1, s2 cloudv1alpha1.VpcStatus) bool {
+	if s1.Provider != s2.Provider || s1.ID != s2.ID || s1.Name != s2.Name || s1.Region != s2.Region {
+		return false
+	}
+	if len(s1.Tags) != len(s2.Tags) || !areTagsSame(s1.Tags, s2.Tags) {
+		return false
+	}
+	return areStringsSame(s1.CIDRs, s2.CIDRs) && areStringsSame(s1.Peerings, s2.Peerings)
+}
+
+func areTimesSame(t1, t2 *metav1.Time) bool {
+	if t1 == nil || t2 == nil {
+		return t1 == t2
+	}
+	return t1.Equal(t2)
+}
+
+func areStringsSame(s1, s2 []string) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+	for i := range s1 {
+		if s1[i] != s2[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func areTagsSame(s1, s2 map[string]string) bool {
 	for key1, value1 := range s1 {
 		value2, found := s2[key1]
@@ -321,6 +703,9 @@ func areNetworkInterfacesSame(s1, s2 []cloudv1alpha1.NetworkInterface) bool {
 		if strings.Compare(strings.ToLower(value1.MAC), strings.ToLower(value2.MAC)) != 0 {
 			return false
 		}
+		if value1.Subnet != value2.Subnet {
+			return false
+		}
 		if len(value1.IPs) != len(value2.IPs) {
 			return false
 		}
@@ -366,9 +751,36 @@ func updateCloudDiscoveredFieldsOfVirtualMachineStatus(current, discovered *clou
 	current.State = discovered.State
 	current.NetworkInterfaces = discovered.NetworkInterfaces
 	current.VirtualPrivateCloud = discovered.VirtualPrivateCloud
+	current.Region = discovered.Region
 	current.Tags = discovered.Tags
+	current.InstanceType = discovered.InstanceType
+	current.AvailabilityZone = discovered.AvailabilityZone
+	current.ImageID = discovered.ImageID
+	current.OSType = discovered.OSType
+	current.LaunchTime = discovered.LaunchTime
+	current.SecurityGroups = discovered.SecurityGroups
 }
 
-func updateAccountStatus(current, discovered *cloudv1alpha1.CloudProviderAccountStatus) {
+func updateAccountStatus(current, discovered *cloudv1alpha1.CloudProviderAccountStatus, generation int64) {
 	current.Error = discovered.Error
+	current.LastSuccessfulPollTime = discovered.LastSuccessfulPollTime
+	current.DiscoveredVirtualMachines = discovered.DiscoveredVirtualMachines
+	current.DiscoveredVpcs = discovered.DiscoveredVpcs
+	current.ServiceStats = discovered.ServiceStats
+	for _, condition := range discovered.Conditions {
+		condition.ObservedGeneration = generation
+		meta.SetStatusCondition(&current.Conditions, condition)
+	}
+}
+
+// setDiscoveredResourceCounts sets number of discovered virtual machines and their VPCs in account status.
+func setDiscoveredResourceCounts(status *cloudv1alpha1.CloudProviderAccountStatus, virtualMachines []*cloudv1alpha1.VirtualMachine) {
+	vpcs := make(map[string]struct{})
+	for _, vm := range virtualMachines {
+		if len(vm.Status.VirtualPrivateCloud) != 0 {
+			vpcs[vm.Status.VirtualPrivateCloud] = struct{}{}
+		}
+	}
+	status.DiscoveredVirtualMachines = len(virtualMachines)
+	status.DiscoveredVpcs = len(vpcs)
 }
@@ -0,0 +1,343 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"errors"
+	"time"
+
+	mock "github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/testing/controllerruntimeclient"
+)
+
+// fakeCloudInterface discovers inventory of an account, or fails to.
+type fakeCloudInterface struct {
+	common.CloudInterface
+	virtualMachines []*v1alpha1.VirtualMachine
+	vmErr           error
+	vpcs            []*v1alpha1.Vpc
+	vpcErr          error
+}
+
+func (c *fakeCloudInterface) InstancesGivenProviderAccount(_ *types.NamespacedName) ([]*v1alpha1.VirtualMachine, error) {
+	return c.virtualMachines, c.vmErr
+}
+
+func (c *fakeCloudInterface) VpcsGivenProviderAccount(_ *types.NamespacedName) ([]*v1alpha1.Vpc, error) {
+	return c.vpcs, c.vpcErr
+}
+
+func (c *fakeCloudInterface) GetAccountStatus(_ *types.NamespacedName) (*v1alpha1.CloudProviderAccountStatus, error) {
+	return &v1alpha1.CloudProviderAccountStatus{}, nil
+}
+
+var _ = Describe("Account poller", func() {
+	var status v1alpha1.VirtualMachineStatus
+
+	BeforeEach(func() {
+		launchTime := v1.NewTime(time.Date(2022, 10, 1, 8, 2, 3, 0, time.UTC))
+		status = v1alpha1.VirtualMachineStatus{
+			Provider:            v1alpha1.AWSCloudProvider,
+			VirtualPrivateCloud: "vpc-01",
+			Region:              "us-east-1",
+			AvailabilityZone:    "us-east-1a",
+			InstanceType:        "t2.micro",
+			ImageID:             "ami-01",
+			OSType:              "linux",
+			LaunchTime:          &launchTime,
+			SecurityGroups:      []string{"sg-01", "sg-02"},
+			NetworkInterfaces: []v1alpha1.NetworkInterface{
+				{Name: "eni-01", MAC: "02:00:00:00:00:01", Subnet: "subnet-01"},
+			},
+		}
+	})
+
+	It("Should treat VirtualMachine status with same cloud properties as same", func() {
+		discovered := *status.DeepCopy()
+		// launch time read from the VirtualMachine CRD may be in a different location.
+		launchTime := v1.NewTime(status.LaunchTime.In(time.FixedZone("PDT", -7*3600)))
+		discovered.LaunchTime = &launchTime
+		Expect(areDiscoveredFieldsSameVirtualMachineStatus(status, discovered)).To(BeTrue())
+	})
+
+	It("Should detect changes of VirtualMachine cloud properties", func() {
+		changes := []func(s *v1alpha1.VirtualMachineStatus){
+			func(s *v1alpha1.VirtualMachineStatus) { s.AvailabilityZone = "us-east-1b" },
+			func(s *v1alpha1.VirtualMachineStatus) { s.InstanceType = "t2.large" },
+			func(s *v1alpha1.VirtualMachineStatus) { s.ImageID = "ami-02" },
+			func(s *v1alpha1.VirtualMachineStatus) { s.OSType = "windows" },
+			func(s *v1alpha1.VirtualMachineStatus) { s.LaunchTime = nil },
+			func(s *v1alpha1.VirtualMachineStatus) { s.SecurityGroups = []string{"sg-01"} },
+			func(s *v1alpha1.VirtualMachineStatus) { s.NetworkInterfaces[0].Subnet = "subnet-02" },
+		}
+		for _, change := range changes {
+			discovered := *status.DeepCopy()
+			change(&discovered)
+			Expect(areDiscoveredFieldsSameVirtualMachineStatus(status, discovered)).To(BeFalse())
+
+			current := *status.DeepCopy()
+			updateCloudDiscoveredFieldsOfVirtualMachineStatus(&current, &discovered)
+			Expect(areDiscoveredFieldsSameVirtualMachineStatus(current, discovered)).To(BeTrue())
+		}
+	})
+
+	Context("VirtualMachine operations", func() {
+		var (
+			poller   *accountPoller
+			selector *v1alpha1.CloudEntitySelector
+		)
+
+		newVirtualMachine := func(name string, state string) *v1alpha1.VirtualMachine {
+			return &v1alpha1.VirtualMachine{
+				ObjectMeta: v1.ObjectMeta{Namespace: "namespace01", Name: name},
+				Status:     v1alpha1.VirtualMachineStatus{Provider: v1alpha1.AWSCloudProvider, State: state},
+			}
+		}
+
+		BeforeEach(func() {
+			mockCtrl = mock.NewController(GinkgoT())
+			mockClient = controllerruntimeclient.NewMockClient(mockCtrl)
+			mockStatusWriter = controllerruntimeclient.NewMockStatusWriter(mockCtrl)
+			mockClient.EXPECT().Status().Return(mockStatusWriter).AnyTimes()
+			selector = &v1alpha1.CloudEntitySelector{
+				TypeMeta:   v1.TypeMeta{Kind: "CloudEntitySelector", APIVersion: v1alpha1.GroupVersion.String()},
+				ObjectMeta: v1.ObjectMeta{Namespace: "namespace01", Name: "selector01", UID: "uid01"},
+			}
+			poller = &accountPoller{
+				Client:         mockClient,
+				log:            ctrl.Log.WithName("account-poller"),
+				scheme:         scheme,
+				namespacedName: &types.NamespacedName{Namespace: "namespace01", Name: "account01"},
+				selector:       selector,
+			}
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("Should write only changed VirtualMachines", func() {
+			vm01 := newVirtualMachine("vm01", "running")
+			vm01.OwnerReferences = []v1.OwnerReference{{Kind: "CloudEntitySelector", Name: selector.Name}}
+			vm02 := vm01.DeepCopy()
+			vm02.Name = "vm02"
+			mockClient.EXPECT().List(mock.Any(), mock.Any(), mock.Any()).
+				Do(func(_ interface{}, list *v1alpha1.VirtualMachineList, _ ...interface{}) {
+					list.Items = []v1alpha1.VirtualMachine{*vm01, *vm02}
+				}).Return(nil).Times(1)
+
+			// vm01 is updated, vm02 is unchanged and vm03 is created.
+			mockStatusWriter.EXPECT().Patch(mock.Any(), mock.Any(), client.Apply, mock.Any()).
+				Do(func(_ interface{}, vm *v1alpha1.VirtualMachine, _ client.Patch, _ ...interface{}) {
+					Expect(vm.Name).To(BeElementOf("vm01", "vm03"))
+					Expect(vm.Kind).To(Equal("VirtualMachine"))
+					Expect(vm.Status.State).To(Equal("stopped"))
+				}).Return(nil).Times(2)
+			mockClient.EXPECT().Patch(mock.Any(), mock.Any(), client.Apply, mock.Any()).
+				Do(func(_ interface{}, vm *v1alpha1.VirtualMachine, _ client.Patch, _ ...interface{}) {
+					Expect(vm.Name).To(Equal("vm03"))
+					Expect(vm.OwnerReferences).To(HaveLen(1))
+					Expect(vm.OwnerReferences[0].Name).To(Equal(selector.Name))
+				}).Return(nil).Times(1)
+
+			discovered := []*v1alpha1.VirtualMachine{newVirtualMachine("vm01", "stopped"), newVirtualMachine("vm02", "running"),
+				newVirtualMachine("vm03", "stopped")}
+			Expect(poller.doVirtualMachineOperations(discovered, true)).To(Succeed())
+			// VirtualMachines are not listed, nor written, again while unchanged.
+			Expect(poller.doVirtualMachineOperations(discovered, true)).To(Succeed())
+			Expect(poller.vmCache).To(HaveLen(3))
+		})
+
+		It("Should record bounded state history of VirtualMachines", func() {
+			mockClient.EXPECT().List(mock.Any(), mock.Any(), mock.Any()).Return(nil).Times(1)
+			mockClient.EXPECT().Patch(mock.Any(), mock.Any(), client.Apply, mock.Any()).Return(nil).Times(1)
+			mockStatusWriter.EXPECT().Patch(mock.Any(), mock.Any(), client.Apply, mock.Any()).Return(nil).
+				Times(v1alpha1.MaxVirtualMachineStateHistory + 2)
+
+			states := []string{"running", "stopped"}
+			for i := 0; i < v1alpha1.MaxVirtualMachineStateHistory+2; i++ {
+				vm := newVirtualMachine("vm01", states[i%2])
+				Expect(poller.doVirtualMachineOperations([]*v1alpha1.VirtualMachine{vm}, true)).To(Succeed())
+				// unchanged state is not recorded.
+				vm = newVirtualMachine("vm01", states[i%2])
+				Expect(poller.doVirtualMachineOperations([]*v1alpha1.VirtualMachine{vm}, true)).To(Succeed())
+			}
+			history := poller.vmCache["vm01"].Status.StateHistory
+			Expect(history).To(HaveLen(v1alpha1.MaxVirtualMachineStateHistory))
+			Expect(history[len(history)-1].State).To(Equal("stopped"))
+			Expect(history[len(history)-2].State).To(Equal("running"))
+			Expect(history[0].TransitionTime.IsZero()).To(BeFalse())
+		})
+
+		It("Should delete VirtualMachine not discovered for consecutive polls", func() {
+			mockClient.EXPECT().List(mock.Any(), mock.Any(), mock.Any()).Return(nil).Times(1)
+			mockClient.EXPECT().Patch(mock.Any(), mock.Any(), client.Apply, mock.Any()).Return(nil).Times(1)
+			mockStatusWriter.EXPECT().Patch(mock.Any(), mock.Any(), client.Apply, mock.Any()).Return(nil).Times(1)
+			Expect(poller.doVirtualMachineOperations([]*v1alpha1.VirtualMachine{newVirtualMachine("vm01", "running")},
+				true)).To(Succeed())
+
+			// a poll discovering vm01 again resets its missed polls.
+			for i := 0; i < vmMissedPollsToDelete-1; i++ {
+				Expect(poller.doVirtualMachineOperations(nil, true)).To(Succeed())
+			}
+			Expect(poller.doVirtualMachineOperations([]*v1alpha1.VirtualMachine{newVirtualMachine("vm01", "running")},
+				true)).To(Succeed())
+			for i := 0; i < vmMissedPollsToDelete-1; i++ {
+				Expect(poller.doVirtualMachineOperations(nil, true)).To(Succeed())
+			}
+			// polls triggered by inventory events are not counted.
+			for i := 0; i < vmMissedPollsToDelete; i++ {
+				Expect(poller.doVirtualMachineOperations(nil, false)).To(Succeed())
+			}
+			Expect(poller.vmCache).To(HaveKey("vm01"))
+
+			mockClient.EXPECT().Delete(mock.Any(), mock.Any()).
+				Do(func(_ interface{}, vm *v1alpha1.VirtualMachine, _ ...interface{}) {
+					Expect(vm.Name).To(Equal("vm01"))
+				}).Return(nil).Times(1)
+			Expect(poller.doVirtualMachineOperations(nil, true)).To(Succeed())
+			Expect(poller.vmCache).To(BeEmpty())
+		})
+
+		It("Should migrate field ownership of VirtualMachines written by earlier releases", func() {
+			vm01 := newVirtualMachine("vm01", "running")
+			vm01.OwnerReferences = []v1.OwnerReference{{Kind: "CloudEntitySelector", Name: selector.Name}}
+			vm01.ManagedFields = []v1.ManagedFieldsEntry{
+				{Manager: legacyVirtualMachineFieldOwner, Operation: v1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1",
+					FieldsV1: &v1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:ownerReferences":{}}}`)}},
+				{Manager: legacyVirtualMachineFieldOwner, Operation: v1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1",
+					Subresource: "status", FieldsV1: &v1.FieldsV1{Raw: []byte(`{"f:status":{"f:state":{}}}`)}},
+				{Manager: accountPollerFieldOwner, Operation: v1.ManagedFieldsOperationApply, FieldsType: "FieldsV1",
+					Subresource: "status", FieldsV1: &v1.FieldsV1{Raw: []byte(`{"f:status":{"f:region":{}}}`)}},
+				{Manager: "kubectl-label", Operation: v1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1",
+					FieldsV1: &v1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{}}}`)}},
+			}
+			mockClient.EXPECT().List(mock.Any(), mock.Any(), mock.Any()).
+				Do(func(_ interface{}, list *v1alpha1.VirtualMachineList, _ ...interface{}) {
+					list.Items = []v1alpha1.VirtualMachine{*vm01}
+				}).Return(nil).Times(1)
+			mockClient.EXPECT().Patch(mock.Any(), mock.Any(), mock.Any()).
+				Do(func(_ interface{}, vm *v1alpha1.VirtualMachine, _ client.Patch, _ ...interface{}) {
+					Expect(vm.ManagedFields).To(HaveLen(3))
+					for _, entry := range vm.ManagedFields {
+						Expect(entry.Manager).ToNot(Equal(legacyVirtualMachineFieldOwner))
+					}
+					Expect(vm.ManagedFields[0].Manager).To(Equal(accountPollerFieldOwner))
+					Expect(vm.ManagedFields[0].Subresource).To(Equal("status"))
+					Expect(string(vm.ManagedFields[0].FieldsV1.Raw)).To(And(ContainSubstring("f:state"),
+						ContainSubstring("f:region")))
+					Expect(vm.ManagedFields[1].Manager).To(Equal("kubectl-label"))
+					Expect(vm.ManagedFields[2].Manager).To(Equal(accountPollerFieldOwner))
+					Expect(vm.ManagedFields[2].Operation).To(Equal(v1.ManagedFieldsOperationApply))
+					Expect(string(vm.ManagedFields[2].FieldsV1.Raw)).To(ContainSubstring("f:ownerReferences"))
+				}).Return(nil).Times(1)
+			// state history is recorded.
+			mockStatusWriter.EXPECT().Patch(mock.Any(), mock.Any(), client.Apply, mock.Any()).Return(nil).Times(1)
+
+			Expect(poller.doVirtualMachineOperations([]*v1alpha1.VirtualMachine{newVirtualMachine("vm01", "running")},
+				true)).To(Succeed())
+			// migrated VirtualMachines are not patched again.
+			_, migrated, err := migrateManagedFields(poller.vmCache["vm01"].ManagedFields, legacyVirtualMachineFieldOwner,
+				accountPollerFieldOwner)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(migrated).To(BeFalse())
+		})
+	})
+	Context("Inventory discovery failures", func() {
+		var (
+			poller         *accountPoller
+			selector       *v1alpha1.CloudEntitySelector
+			cloudInterface *fakeCloudInterface
+		)
+
+		BeforeEach(func() {
+			mockCtrl = mock.NewController(GinkgoT())
+			mockClient = controllerruntimeclient.NewMockClient(mockCtrl)
+			mockStatusWriter = controllerruntimeclient.NewMockStatusWriter(mockCtrl)
+			mockClient.EXPECT().Status().Return(mockStatusWriter).AnyTimes()
+			mockClient.EXPECT().Get(mock.Any(), mock.Any(), mock.Any()).Return(nil).AnyTimes()
+			mockStatusWriter.EXPECT().Update(mock.Any(), mock.Any()).Return(nil).AnyTimes()
+			selector = &v1alpha1.CloudEntitySelector{
+				TypeMeta:   v1.TypeMeta{Kind: "CloudEntitySelector", APIVersion: v1alpha1.GroupVersion.String()},
+				ObjectMeta: v1.ObjectMeta{Namespace: "namespace01", Name: "selector01", UID: "uid01"},
+			}
+			poller = &accountPoller{
+				Client:         mockClient,
+				log:            ctrl.Log.WithName("account-poller"),
+				scheme:         scheme,
+				namespacedName: &types.NamespacedName{Namespace: "namespace01", Name: "account01"},
+				selector:       selector,
+			}
+			cloudInterface = &fakeCloudInterface{}
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("Should not delete Vpcs if vpc discovery fails", func() {
+			vpc := &v1alpha1.Vpc{ObjectMeta: v1.ObjectMeta{Namespace: "namespace01", Name: "vpc01",
+				OwnerReferences: []v1.OwnerReference{{Kind: "CloudEntitySelector", Name: selector.Name}}}}
+			mockClient.EXPECT().List(mock.Any(), mock.Any(), mock.Any()).
+				Do(func(_ interface{}, list client.ObjectList, _ ...interface{}) {
+					if vpcList, ok := list.(*v1alpha1.VpcList); ok {
+						vpcList.Items = []v1alpha1.Vpc{*vpc}
+					}
+				}).Return(nil).AnyTimes()
+
+			cloudInterface.vpcErr = errors.New("failed to describe vpcs")
+			for i := 0; i < 3; i++ {
+				poller.pollAccountInventory(cloudInterface, true)
+			}
+
+			// Vpc no longer discovered is deleted.
+			cloudInterface.vpcErr = nil
+			mockClient.EXPECT().Delete(mock.Any(), mock.Any()).
+				Do(func(_ interface{}, deleted *v1alpha1.Vpc, _ ...interface{}) {
+					Expect(deleted.Name).To(Equal(vpc.Name))
+				}).Return(nil).Times(1)
+			poller.pollAccountInventory(cloudInterface, true)
+		})
+
+		It("Should not delete VirtualMachines if compute discovery fails", func() {
+			mockClient.EXPECT().List(mock.Any(), mock.Any(), mock.Any()).Return(nil).AnyTimes()
+			mockClient.EXPECT().Patch(mock.Any(), mock.Any(), client.Apply, mock.Any()).Return(nil).Times(1)
+			mockStatusWriter.EXPECT().Patch(mock.Any(), mock.Any(), client.Apply, mock.Any()).Return(nil).Times(1)
+			cloudInterface.virtualMachines = []*v1alpha1.VirtualMachine{{
+				ObjectMeta: v1.ObjectMeta{Namespace: "namespace01", Name: "vm01"},
+				Status:     v1alpha1.VirtualMachineStatus{Provider: v1alpha1.AWSCloudProvider, State: "running"},
+			}}
+			poller.pollAccountInventory(cloudInterface, true)
+			Expect(poller.vmCache).To(HaveKey("vm01"))
+
+			// failed polls are not counted as missed.
+			cloudInterface.virtualMachines, cloudInterface.vmErr = nil, errors.New("failed to describe instances")
+			for i := 0; i < vmMissedPollsToDelete+1; i++ {
+				poller.pollAccountInventory(cloudInterface, true)
+			}
+			Expect(poller.vmCache).To(HaveKey("vm01"))
+			Expect(poller.vmMissedPolls).To(BeEmpty())
+		})
+	})
+})
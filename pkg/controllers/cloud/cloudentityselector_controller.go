@@ -30,6 +30,7 @@ import (
 	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
 	cloudprovider "antrea.io/nephe/pkg/cloud-provider"
 	"antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 const (
@@ -49,6 +50,8 @@ type CloudEntitySelectorReconciler struct {
 
 // +kubebuilder:rbac:groups=crd.cloud.antrea.io,resources=cloudentityselectors,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=crd.cloud.antrea.io,resources=cloudentityselectors/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=crd.cloud.antrea.io,resources=vpcs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=crd.cloud.antrea.io,resources=vpcs/status,verbs=get;update;patch
 
 func (r *CloudEntitySelectorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = r.Log.WithValues("cloudentityselector", req.NamespacedName)
@@ -90,22 +93,11 @@ func (r *CloudEntitySelectorReconciler) processCreateOrUpdate(selector *cloudv1a
 	selectorNamespacedName *types.NamespacedName) error {
 	accPoller, preExists := r.addAccountPoller(selector)
 
-	if selector.Spec.VMSelector != nil {
-		vmList := &cloudv1alpha1.VirtualMachineList{}
-		if err := r.List(context.TODO(), vmList, client.MatchingFields{
-			virtualMachineIndexerByCloudAccount: selector.Name}, client.InNamespace(selector.Namespace)); err != nil {
-			r.Log.Error(err, "Unable to get virtual machines for external entity selector", "Name", selector.Name)
-		} else {
-			var vms []*cloudv1alpha1.VirtualMachine
-			for i := range vmList.Items {
-				vm := &vmList.Items[i]
-				// vm selector changed, trigger to recompute.
-				vms = append(vms, vm)
-			}
-			if err := accPoller.doVirtualMachineOperations(vms); err != nil {
-				r.Log.Error(err, "Unable to update virtual machines")
-			}
-		}
+	if preExists && selector.Spec.VMSelector != nil {
+		// vm selector changed, trigger to recompute from VirtualMachine CRDs on next poll.
+		accPoller.mutex.Lock()
+		accPoller.vmCache = nil
+		accPoller.mutex.Unlock()
 	}
 
 	cloudInterface, err := cloudprovider.GetCloudInterface(common.ProviderType(accPoller.cloudType))
@@ -127,6 +119,7 @@ func (r *CloudEntitySelectorReconciler) processCreateOrUpdate(selector *cloudv1a
 
 	if !preExists {
 		go wait.Until(accPoller.doAccountPoller, time.Duration(accPoller.pollIntvInSeconds)*time.Second, accPoller.ch)
+		go accPoller.watchAccountInventory(cloudInterface)
 	}
 	return nil
 }
@@ -141,6 +134,7 @@ func (r *CloudEntitySelectorReconciler) processDelete(selectorNamespacedName *ty
 		return err
 	}
 	cloudInterface.RemoveAccountResourcesSelector(poller.namespacedName, selectorNamespacedName.Name)
+	metrics.InventoryVirtualMachines.DeleteLabelValues(poller.namespacedName.Namespace, poller.namespacedName.Name)
 
 	return nil
 }
@@ -19,15 +19,22 @@ import (
 	"sync"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	cloudv1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
 	cloudprovider "antrea.io/nephe/pkg/cloud-provider"
 	"antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
+	"antrea.io/nephe/pkg/controllers/utils"
 )
 
 // CloudProviderAccountReconciler reconciles a CloudProviderAccount object.
@@ -44,6 +51,7 @@ type CloudProviderAccountReconciler struct {
 // nolint:lll
 // +kubebuilder:rbac:groups=crd.cloud.antrea.io,resources=cloudprovideraccounts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=crd.cloud.antrea.io,resources=cloudprovideraccounts/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 func (r *CloudProviderAccountReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = r.Log.WithValues("cloudprovideraccount", req.NamespacedName)
@@ -58,21 +66,85 @@ func (r *CloudProviderAccountReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, err
 	}
 
-	err = r.processCreate(&req.NamespacedName, providerAccount)
-	if err != nil {
+	// failure to resolve the credential Secret, which may be transient or not yet created, keeps the account
+	// as is, and the request is retried with backoff. The account is removed only when it is deleted.
+	account := providerAccount.DeepCopy()
+	if err = utils.ResolveSecretRef(ctx, r.Client, account); err != nil {
+		r.updateCredentialsCondition(ctx, providerAccount, err)
+		return ctrl.Result{}, err
+	}
+	if err = r.processCreate(&req.NamespacedName, account); err != nil {
 		_ = r.processDelete(&req.NamespacedName)
 	}
+	r.updateCredentialsCondition(ctx, providerAccount, err)
 
 	return ctrl.Result{}, err
 }
 
+// updateCredentialsCondition updates CredentialsValid condition of the account based on the result of adding
+// account to the cloud plugin.
+func (r *CloudProviderAccountReconciler) updateCredentialsCondition(ctx context.Context,
+	account *cloudv1alpha1.CloudProviderAccount, addErr error) {
+	condition := metav1.Condition{
+		Type:               cloudv1alpha1.AccountConditionCredentialsValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             cloudv1alpha1.AccountReasonCredentialsAccepted,
+		Message:            "Credentials accepted by cloud plugin",
+		ObservedGeneration: account.Generation,
+	}
+	if addErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = cloudv1alpha1.AccountReasonCredentialsInvalid
+		condition.Message = addErr.Error()
+	}
+	current := meta.FindStatusCondition(account.Status.Conditions, condition.Type)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason &&
+		current.Message == condition.Message && current.ObservedGeneration == condition.ObservedGeneration {
+		return
+	}
+
+	meta.SetStatusCondition(&account.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, account); err != nil {
+		r.Log.Info("failed to update account status", "account", client.ObjectKeyFromObject(account), "err", err)
+	}
+}
+
 func (r *CloudProviderAccountReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.accountProviderType = make(map[types.NamespacedName]common.ProviderType)
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&cloudv1alpha1.CloudProviderAccount{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.getAccountsForSecret)).
 		Complete(r)
 }
 
+// getAccountsForSecret returns reconcile requests for CloudProviderAccounts referencing the Secret, so that
+// rotated credentials are pushed to the cloud plugin. Only accounts permitted to reference the Secret are looked up,
+// that is accounts in namespace of the Secret, or all accounts if the Secret is in nephe controller namespace.
+func (r *CloudProviderAccountReconciler) getAccountsForSecret(obj client.Object) []reconcile.Request {
+	var opts []client.ListOption
+	if len(cloudv1alpha1.NepheNamespace) == 0 || obj.GetNamespace() != cloudv1alpha1.NepheNamespace {
+		opts = append(opts, client.InNamespace(obj.GetNamespace()))
+	}
+	accountList := &cloudv1alpha1.CloudProviderAccountList{}
+	if err := r.List(context.TODO(), accountList, opts...); err != nil {
+		r.Log.Error(err, "failed to list CloudProviderAccounts", "secret", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range accountList.Items {
+		account := &accountList.Items[i]
+		secretRef := utils.GetAccountSecretRef(account)
+		if secretRef == nil {
+			continue
+		}
+		if secretRef.Name == obj.GetName() && utils.GetSecretRefNamespace(account, secretRef) == obj.GetNamespace() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(account)})
+		}
+	}
+	return requests
+}
+
 func (r *CloudProviderAccountReconciler) processCreate(namespacedName *types.NamespacedName,
 	account *cloudv1alpha1.CloudProviderAccount) error {
 	accountCloudType, err := account.GetAccountProviderType()
@@ -84,6 +156,7 @@ func (r *CloudProviderAccountReconciler) processCreate(namespacedName *types.Nam
 	if err != nil {
 		return err
 	}
+	cloudprovider.SetAccountDryRun(namespacedName, account.Spec.DryRun)
 	return cloudInterface.AddProviderAccount(account)
 }
 
@@ -94,6 +167,7 @@ func (r *CloudProviderAccountReconciler) processDelete(namespacedName *types.Nam
 		return err
 	}
 	cloudInterface.RemoveProviderAccount(namespacedName)
+	cloudprovider.RemoveAccountDryRun(namespacedName)
 	r.removeAccountProviderType(namespacedName)
 
 	return nil
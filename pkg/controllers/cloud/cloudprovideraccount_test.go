@@ -15,11 +15,20 @@
 package cloud
 
 import (
+	"context"
+
 	v1alpha1 "antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/controllers/utils"
+	"antrea.io/nephe/pkg/testing/controllerruntimeclient"
+	mock "github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 var (
@@ -106,6 +115,43 @@ var _ = Describe("Cloudprovideraccount", func() {
 			err := accountAWS.ValidateCreate()
 			Expect(err).Should(BeNil())
 		})
+
+		It("Should fail with no region", func() {
+			accountAWS.Spec.AWSConfig.Region = "			"
+
+			err := accountAWS.ValidateCreate()
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should fail with all regions combined with other regions", func() {
+			accountAWS.Spec.AWSConfig.Regions = []string{v1alpha1.AllRegions}
+
+			err := accountAWS.ValidateCreate()
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should validate AWS account with multiple regions successfully", func() {
+			accountAWS.Spec.AWSConfig.Region = ""
+			accountAWS.Spec.AWSConfig.Regions = []string{"us-east-1", "us-west-2", "us-east-1"}
+
+			err := accountAWS.ValidateCreate()
+			Expect(err).Should(BeNil())
+			Expect(accountAWS.Spec.AWSConfig.GetRegions()).To(Equal([]string{"us-east-1", "us-west-2"}))
+		})
+
+		It("Should fail with invalid event queue URL", func() {
+			accountAWS.Spec.AWSConfig.EventQueueURL = "sqs.us-east-1.amazonaws.com/123456789012/nephe-events"
+
+			err := accountAWS.ValidateCreate()
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should validate AWS account with event queue URL successfully", func() {
+			accountAWS.Spec.AWSConfig.EventQueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/nephe-events"
+
+			err := accountAWS.ValidateCreate()
+			Expect(err).Should(BeNil())
+		})
 	})
 
 	Context("New Azure account add fail scenarios", func() {
@@ -145,5 +191,232 @@ var _ = Describe("Cloudprovideraccount", func() {
 			err := accountAzure.ValidateCreate()
 			Expect(err).Should(BeNil())
 		})
+
+		It("Should fail with invalid event queue URL", func() {
+			accountAzure.Spec.AzureConfig.EventQueueURL = "nephe.queue.core.windows.net/nephe-events"
+
+			err := accountAzure.ValidateCreate()
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should fail with coexist security group mode", func() {
+			accountAzure.Spec.AzureConfig.SecurityGroupMode = v1alpha1.SecurityGroupModeCoexist
+
+			err := accountAzure.ValidateCreate()
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should validate Azure account with event queue URL successfully", func() {
+			accountAzure.Spec.AzureConfig.EventQueueURL = "https://nephe.queue.core.windows.net/nephe-events"
+
+			err := accountAzure.ValidateCreate()
+			Expect(err).Should(BeNil())
+		})
+	})
+
+	Context("Account update fail scenarios", func() {
+		It("Should fail to update to coexist security group mode", func() {
+			oldAccount := accountAzure.DeepCopy()
+			accountAzure.Spec.AzureConfig.SecurityGroupMode = v1alpha1.SecurityGroupModeCoexist
+
+			err := accountAzure.ValidateUpdate(oldAccount)
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should fail to update to no region", func() {
+			oldAccount := accountAWS.DeepCopy()
+			accountAWS.Spec.AWSConfig.Region = ""
+
+			err := accountAWS.ValidateUpdate(oldAccount)
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should fail to update to all regions combined with other regions", func() {
+			oldAccount := accountAWS.DeepCopy()
+			accountAWS.Spec.AWSConfig.Regions = []string{v1alpha1.AllRegions}
+
+			err := accountAWS.ValidateUpdate(oldAccount)
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should fail to update to invalid event queue URL", func() {
+			oldAccount := accountAWS.DeepCopy()
+			accountAWS.Spec.AWSConfig.EventQueueURL = "sqs.us-east-1.amazonaws.com/123456789012/nephe-events"
+
+			err := accountAWS.ValidateUpdate(oldAccount)
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should fail to update to poll interval less than 30 seconds", func() {
+			oldAccount := accountAWS.DeepCopy()
+			var pollIntv uint = 10
+			accountAWS.Spec.PollIntervalInSeconds = &pollIntv
+
+			err := accountAWS.ValidateUpdate(oldAccount)
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should update AWS account successfully", func() {
+			oldAccount := accountAWS.DeepCopy()
+			accountAWS.Spec.AWSConfig.Regions = []string{"us-west-2"}
+
+			err := accountAWS.ValidateUpdate(oldAccount)
+			Expect(err).Should(BeNil())
+		})
+	})
+
+	Context("Account credential secretRef", func() {
+		var secretRef *v1alpha1.SecretReference
+
+		BeforeEach(func() {
+			secretRef = &v1alpha1.SecretReference{Name: "secret01", Key: "credential"}
+		})
+
+		AfterEach(func() {
+			v1alpha1.AllowInlineCredentials = true
+		})
+
+		It("Should validate AWS account with secretRef successfully", func() {
+			accountAWS.Spec.AWSConfig.RoleArn = ""
+			accountAWS.Spec.AWSConfig.AccessKeySecret = ""
+			accountAWS.Spec.AWSConfig.SecretRef = secretRef
+
+			err := accountAWS.ValidateCreate()
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should fail with both inline credential and secretRef", func() {
+			accountAzure.Spec.AzureConfig.IdentityClientID = ""
+			accountAzure.Spec.AzureConfig.SecretRef = secretRef
+
+			err := accountAzure.ValidateCreate()
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should fail with blank secretRef key", func() {
+			accountAzure.Spec.AzureConfig.IdentityClientID = ""
+			accountAzure.Spec.AzureConfig.ClientKey = ""
+			secretRef.Key = "			"
+			accountAzure.Spec.AzureConfig.SecretRef = secretRef
+
+			err := accountAzure.ValidateCreate()
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("Should fail with inline credential when forbidden by policy", func() {
+			v1alpha1.AllowInlineCredentials = false
+			accountAzure.Spec.AzureConfig.IdentityClientID = ""
+
+			err := accountAzure.ValidateCreate()
+			Expect(err).ShouldNot(BeNil())
+			err = accountAzure.ValidateUpdate(accountAzure)
+			Expect(err).ShouldNot(BeNil())
+
+			accountAzure.Spec.AzureConfig.ClientKey = ""
+			accountAzure.Spec.AzureConfig.SecretRef = secretRef
+			err = accountAzure.ValidateCreate()
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should resolve credential from referenced Secret", func() {
+			mockCtrl = mock.NewController(GinkgoT())
+			mockClient = controllerruntimeclient.NewMockClient(mockCtrl)
+			accountAWS.Spec.AWSConfig.AccessKeySecret = ""
+			accountAWS.Spec.AWSConfig.SecretRef = secretRef
+			secret := &corev1.Secret{
+				ObjectMeta: v1.ObjectMeta{Name: secretRef.Name, Namespace: testAccountNamespacedName.Namespace},
+				Data:       map[string][]byte{secretRef.Key: []byte("keySecret")},
+			}
+			key := client.ObjectKey{Name: secret.Name, Namespace: secret.Namespace}
+			mockClient.EXPECT().Get(mock.Any(), key, mock.Any()).Return(nil).Times(2).
+				Do(func(_ context.Context, key client.ObjectKey, out *corev1.Secret) {
+					secret.DeepCopyInto(out)
+				})
+
+			err := utils.ResolveSecretRef(context.TODO(), mockClient, accountAWS)
+			Expect(err).Should(BeNil())
+			Expect(accountAWS.Spec.AWSConfig.AccessKeySecret).To(Equal("keySecret"))
+
+			secretRef.Key = "missing"
+			err = utils.ResolveSecretRef(context.TODO(), mockClient, accountAWS)
+			Expect(err).ShouldNot(BeNil())
+
+			// Secret of another namespace is not read.
+			secretRef.Namespace = "other-namespace"
+			err = utils.ResolveSecretRef(context.TODO(), mockClient, accountAWS)
+			Expect(err).ShouldNot(BeNil())
+			mockCtrl.Finish()
+		})
+
+		It("Should fail with secretRef to namespace other than account or nephe namespace", func() {
+			defer func() { v1alpha1.NepheNamespace = "" }()
+			accountAWS.Spec.AWSConfig.RoleArn = ""
+			accountAWS.Spec.AWSConfig.AccessKeySecret = ""
+			accountAWS.Spec.AWSConfig.SecretRef = secretRef
+
+			secretRef.Namespace = "kube-system"
+			err := accountAWS.ValidateCreate()
+			Expect(err).ShouldNot(BeNil())
+			v1alpha1.NepheNamespace = "kube-system"
+			err = accountAWS.ValidateCreate()
+			Expect(err).Should(BeNil())
+			secretRef.Namespace = accountAWS.Namespace
+			err = accountAWS.ValidateUpdate(accountAWS)
+			Expect(err).Should(BeNil())
+		})
+
+		It("Should map Secret to accounts in its namespace only", func() {
+			mockCtrl = mock.NewController(GinkgoT())
+			mockClient = controllerruntimeclient.NewMockClient(mockCtrl)
+			reconciler := &CloudProviderAccountReconciler{
+				Log:    logf.Log,
+				Client: mockClient,
+			}
+			accountAWS.Spec.AWSConfig.SecretRef = secretRef
+			secret := &corev1.Secret{ObjectMeta: v1.ObjectMeta{Name: secretRef.Name, Namespace: accountAWS.Namespace}}
+			mockClient.EXPECT().List(mock.Any(), mock.Any(), client.InNamespace(secret.Namespace)).Return(nil).Times(1).
+				Do(func(_ context.Context, list *v1alpha1.CloudProviderAccountList, _ ...client.ListOption) {
+					list.Items = []v1alpha1.CloudProviderAccount{*accountAWS}
+				})
+
+			requests := reconciler.getAccountsForSecret(secret)
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].NamespacedName).To(Equal(client.ObjectKeyFromObject(accountAWS)))
+			mockCtrl.Finish()
+		})
+	})
+
+	Context("Account status", func() {
+		It("Should update account status with discovered status", func() {
+			vms := []*v1alpha1.VirtualMachine{
+				{Status: v1alpha1.VirtualMachineStatus{VirtualPrivateCloud: "vpc01"}},
+				{Status: v1alpha1.VirtualMachineStatus{VirtualPrivateCloud: "vpc01"}},
+				{Status: v1alpha1.VirtualMachineStatus{VirtualPrivateCloud: "vpc02"}},
+			}
+			discovered := &v1alpha1.CloudProviderAccountStatus{
+				Conditions: []v1.Condition{{
+					Type:   v1alpha1.AccountConditionInventorySynced,
+					Status: v1.ConditionTrue,
+					Reason: v1alpha1.AccountReasonInventoryPollSucceeded,
+				}},
+				ServiceStats: []v1alpha1.CloudServiceStats{{Name: "EC2", TotalPollCount: 1, SuccessfulPollCount: 1}},
+			}
+			setDiscoveredResourceCounts(discovered, vms)
+			updateAccountStatus(&accountAWS.Status, discovered, 2)
+
+			Expect(accountAWS.Status.DiscoveredVirtualMachines).To(Equal(3))
+			Expect(accountAWS.Status.DiscoveredVpcs).To(Equal(2))
+			Expect(accountAWS.Status.ServiceStats).To(Equal(discovered.ServiceStats))
+			condition := meta.FindStatusCondition(accountAWS.Status.Conditions, v1alpha1.AccountConditionInventorySynced)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.ObservedGeneration).To(Equal(int64(2)))
+			transitionTime := condition.LastTransitionTime
+
+			// condition with unchanged status keeps its transition time.
+			updateAccountStatus(&accountAWS.Status, discovered, 3)
+			condition = meta.FindStatusCondition(accountAWS.Status.Conditions, v1alpha1.AccountConditionInventorySynced)
+			Expect(condition.LastTransitionTime).To(Equal(transitionTime))
+			Expect(condition.ObservedGeneration).To(Equal(int64(3)))
+		})
 	})
 })
@@ -19,14 +19,18 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/mohae/deepcopy"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	antreanetworking "antrea.io/antrea/pkg/apis/controlplane/v1beta2"
+	antreacrd "antrea.io/antrea/pkg/apis/crd/v1alpha1"
 	antreanetcore "antrea.io/antrea/pkg/apis/crd/v1alpha2"
 	cloud "antrea.io/nephe/apis/crd/v1alpha1"
 	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
@@ -99,10 +103,25 @@ var (
 	_ cloudSecurityGroup = &appliedToSecurityGroup{}
 
 	AntreaProtocolMap = map[antreanetworking.Protocol]int{
+		antreanetworking.ProtocolICMP: 1,
 		antreanetworking.ProtocolTCP:  6,
 		antreanetworking.ProtocolUDP:  17,
 		antreanetworking.ProtocolSCTP: 132,
 	}
+
+	// AntreaRuleActionMap maps Antrea rule actions to cloud rule actions. Cloud does not
+	// send reject responses, hence Reject is realized same as Drop.
+	AntreaRuleActionMap = map[antreacrd.RuleAction]securitygroup.RuleAction{
+		antreacrd.RuleActionAllow:  securitygroup.RuleActionAllow,
+		antreacrd.RuleActionDrop:   securitygroup.RuleActionDeny,
+		antreacrd.RuleActionReject: securitygroup.RuleActionDeny,
+	}
+
+	// anyIPNets are all IPv4 and IPv6 addresses.
+	anyIPNets = []*net.IPNet{
+		{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, net.IPv4len*8)},
+		{IP: net.IPv6zero, Mask: net.CIDRMask(0, net.IPv6len*8)},
+	}
 )
 
 const (
@@ -219,12 +238,54 @@ func mergeIPs(src, added, removed []*net.IPNet) (list []*net.IPNet) {
 	return
 }
 
+// mergeCloudServices added and removed cloud services from src.
+func mergeCloudServices(src, added, removed []string) (list []string) {
+	srcMap := make(map[string]struct{})
+	for _, s := range src {
+		srcMap[s] = struct{}{}
+	}
+	for _, r := range removed {
+		delete(srcMap, r)
+	}
+	for _, a := range added {
+		srcMap[a] = struct{}{}
+	}
+	for k := range srcMap {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return
+}
+
+// getHostIPNet returns the host ip block, /32 for IPv4 or /128 for IPv6, of ip. It returns nil if ip is invalid.
+func getHostIPNet(ip string) *net.IPNet {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return nil
+	}
+	if ipv4 := parsedIP.To4(); ipv4 != nil {
+		return &net.IPNet{IP: ipv4, Mask: net.CIDRMask(net.IPv4len*8, net.IPv4len*8)}
+	}
+	return &net.IPNet{IP: parsedIP, Mask: net.CIDRMask(net.IPv6len*8, net.IPv6len*8)}
+}
+
+// getIPBlockIPNet returns ip block of an Antrea IPBlock, in its IPv4 or IPv6 address family.
+func getIPBlockIPNet(ipBlock antreanetworking.IPBlock) *net.IPNet {
+	ip := net.IP(ipBlock.CIDR.IP)
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return &net.IPNet{IP: ipv4, Mask: net.CIDRMask(int(ipBlock.CIDR.PrefixLength), net.IPv4len*8)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(int(ipBlock.CIDR.PrefixLength), net.IPv6len*8)}
+}
+
 // vpcsFromGroupMembers, provided with a list of ExternalEntityReferences, returns corresponding CloudResources keyed by VPC.
-// If an ExternalEntity does not correspond to a CloudResource, its IP(s) is returned.
+// If an ExternalEntity does not correspond to a CloudResource, its IP(s) is returned. If an ExternalEntity stands for
+// a cloud service, the cloud service is returned keyed by the ExternalEntity.
 func vpcsFromGroupMembers(members []antreanetworking.GroupMember, r *NetworkPolicyReconciler) (
-	map[string][]*securitygroup.CloudResource, []*net.IPNet, []string, error) {
+	map[string][]*securitygroup.CloudResource, []*net.IPNet, map[string]string, []string, error) {
 	vpcs := make(map[string][]*securitygroup.CloudResource)
 	var ipBlocks []*net.IPNet
+	var cloudServices map[string]string
 	var notFoundMember []string
 	for _, m := range members {
 		if m.ExternalEntity == nil {
@@ -236,8 +297,9 @@ func vpcsFromGroupMembers(members []antreanetworking.GroupMember, r *NetworkPoli
 			if apierrors.IsNotFound(err) {
 				if ips, ok := r.fedExternalEntityIPs[key.String()]; ok {
 					for _, ip := range ips {
-						_, ipnet, _ := net.ParseCIDR(ip + "/32")
-						ipBlocks = append(ipBlocks, ipnet)
+						if ipnet := getHostIPNet(ip); ipnet != nil {
+							ipBlocks = append(ipBlocks, ipnet)
+						}
 					}
 				} else {
 					notFoundMember = append(notFoundMember, m.ExternalEntity.Name)
@@ -245,7 +307,14 @@ func vpcsFromGroupMembers(members []antreanetworking.GroupMember, r *NetworkPoli
 				continue
 			}
 			r.Log.Error(err, "Client get ExternalEntity", "key", key)
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, err
+		}
+		if cloudService, ok := e.Labels[config.ExternalEntityLabelKeyCloudService]; ok {
+			if cloudServices == nil {
+				cloudServices = make(map[string]string)
+			}
+			cloudServices[key.String()] = cloudService
+			continue
 		}
 		kind, ok := e.Labels[config.ExternalEntityLabelKeyKind]
 		if !ok {
@@ -283,13 +352,27 @@ func vpcsFromGroupMembers(members []antreanetworking.GroupMember, r *NetworkPoli
 			for _, ep := range e.Spec.Endpoints {
 				var ipnet *net.IPNet
 				if _, ipnet, _ = net.ParseCIDR(ep.IP); ipnet == nil {
-					_, ipnet, _ = net.ParseCIDR(ep.IP + "/32")
+					ipnet = getHostIPNet(ep.IP)
+				}
+				if ipnet != nil {
+					ipBlocks = append(ipBlocks, ipnet)
 				}
-				ipBlocks = append(ipBlocks, ipnet)
 			}
 		}
 	}
-	return vpcs, ipBlocks, notFoundMember, nil
+	return vpcs, ipBlocks, cloudServices, notFoundMember, nil
+}
+
+// externalEntityKeys returns namespaced names of ExternalEntities in members.
+func externalEntityKeys(members []antreanetworking.GroupMember) []string {
+	var keys []string
+	for _, m := range members {
+		if m.ExternalEntity == nil {
+			continue
+		}
+		keys = append(keys, client.ObjectKey{Name: m.ExternalEntity.Name, Namespace: m.ExternalEntity.Namespace}.String())
+	}
+	return keys
 }
 
 func getOwnerAnnotations(e *antreanetcore.ExternalEntity, r *NetworkPolicyReconciler) (map[string]string, error) {
@@ -312,15 +395,57 @@ func getOwnerAnnotations(e *antreanetcore.ExternalEntity, r *NetworkPolicyReconc
 
 // deduplicateKey is used for deduplicate network policy rules.
 type deduplicateKey struct {
-	port     int
-	protocol int
+	port        int
+	endPort     int
+	protocol    int
+	action      securitygroup.RuleAction
+	priority    securitygroup.RulePriority
+	hasPriority bool
+	// icmpType and icmpCode are -1 if not set, as 0 is a valid ICMP type and code.
+	icmpType int
+	icmpCode int
+}
+
+// getICMPKey returns ICMP type or code v in deduplicateKey.
+func getICMPKey(v *int) int {
+	if v == nil {
+		return -1
+	}
+	return *v
+}
+
+// getICMPTypeCode returns ICMP type and code of deduplicateKey.
+func (k deduplicateKey) getICMPTypeCode() (*int, *int) {
+	var icmpType, icmpCode *int
+	if k.icmpType >= 0 {
+		v := k.icmpType
+		icmpType = &v
+	}
+	if k.icmpCode >= 0 {
+		v := k.icmpCode
+		icmpCode = &v
+	}
+	return icmpType, icmpCode
+}
+
+// getPriority returns rule priority of deduplicateKey.
+func (k deduplicateKey) getPriority() *securitygroup.RulePriority {
+	if !k.hasPriority {
+		return nil
+	}
+	priority := k.priority
+	return &priority
 }
 
 // overlap decides whether two ip blocks overlap(one contains the other).
 // If so, return the one with smaller range. Otherwise return nil.
 func overlap(ip1 *net.IPNet, ip2 *net.IPNet) *net.IPNet {
-	maskLen1, _ := ip1.Mask.Size()
-	maskLen2, _ := ip2.Mask.Size()
+	maskLen1, bits1 := ip1.Mask.Size()
+	maskLen2, bits2 := ip2.Mask.Size()
+	// ip blocks of different address families never overlap.
+	if bits1 != bits2 {
+		return nil
+	}
 	if maskLen1 < maskLen2 {
 		if ip1.Contains(ip2.IP) {
 			return ip2
@@ -374,34 +499,63 @@ func deduplicateSG(inputSG []*securitygroup.CloudResourceID) []*securitygroup.Cl
 	return outputSG
 }
 
+// hasDenyRules returns true if any of ingressRules or egressRules is a deny rule.
+func hasDenyRules(ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule) bool {
+	for _, rule := range ingressRules {
+		if rule.Action.IsDeny() {
+			return true
+		}
+	}
+	for _, rule := range egressRules {
+		if rule.Action.IsDeny() {
+			return true
+		}
+	}
+	return false
+}
+
 // deduplicateIngressRules merges duplicated ingress rules on one port into one.
 func deduplicateIngressRules(ingressRules []*securitygroup.IngressRule) []*securitygroup.IngressRule {
 	inRuleIPSet := make(map[deduplicateKey][]*net.IPNet)
 	inRuleSGSet := make(map[deduplicateKey][]*securitygroup.CloudResourceID)
+	inRuleServiceSet := make(map[deduplicateKey][]string)
 	mergedInRules := make([]*securitygroup.IngressRule, 0)
 	for _, r := range ingressRules {
-		port, protocol := 0, 0
+		port, endPort, protocol := 0, 0, 0
 		if r.FromPort != nil {
 			port = *(r.FromPort)
 		}
+		if r.FromEndPort != nil {
+			endPort = *(r.FromEndPort)
+		}
 		if r.Protocol != nil {
 			protocol = *(r.Protocol)
 		}
-		ruleKey := deduplicateKey{port: port, protocol: protocol}
+		ruleKey := deduplicateKey{port: port, endPort: endPort, protocol: protocol, action: r.Action,
+			icmpType: getICMPKey(r.ICMPType), icmpCode: getICMPKey(r.ICMPCode)}
+		if r.Priority != nil {
+			ruleKey.priority, ruleKey.hasPriority = *r.Priority, true
+		}
 		inRuleIPSet[ruleKey] = append(inRuleIPSet[ruleKey], r.FromSrcIP...)
 		inRuleSGSet[ruleKey] = append(inRuleSGSet[ruleKey], r.FromSecurityGroups...)
+		inRuleServiceSet[ruleKey] = append(inRuleServiceSet[ruleKey], r.FromCloudServices...)
 	}
 	for k, v := range inRuleIPSet {
-		port, protocol := k.port, k.protocol
-		var portP, protocolP *int
+		port, endPort, protocol := k.port, k.endPort, k.protocol
+		var portP, endPortP, protocolP *int
 		if port != 0 {
 			portP = &(port)
 		}
+		if endPort != 0 {
+			endPortP = &(endPort)
+		}
 		if protocol != 0 {
 			protocolP = &(protocol)
 		}
-		inRule := securitygroup.IngressRule{FromPort: portP, FromSrcIP: deduplicateIP(v),
-			FromSecurityGroups: deduplicateSG(inRuleSGSet[k]), Protocol: protocolP}
+		inRule := securitygroup.IngressRule{FromPort: portP, FromEndPort: endPortP, FromSrcIP: deduplicateIP(v),
+			FromSecurityGroups: deduplicateSG(inRuleSGSet[k]), FromCloudServices: mergeCloudServices(nil, inRuleServiceSet[k], nil),
+			Protocol: protocolP, Action: k.action, Priority: k.getPriority()}
+		inRule.ICMPType, inRule.ICMPCode = k.getICMPTypeCode()
 		mergedInRules = append(mergedInRules, &inRule)
 	}
 	return mergedInRules
@@ -411,35 +565,65 @@ func deduplicateIngressRules(ingressRules []*securitygroup.IngressRule) []*secur
 func deduplicateEgressRules(egressRules []*securitygroup.EgressRule) []*securitygroup.EgressRule {
 	eRuleIPSet := make(map[deduplicateKey][]*net.IPNet)
 	eRuleSGSet := make(map[deduplicateKey][]*securitygroup.CloudResourceID)
+	eRuleServiceSet := make(map[deduplicateKey][]string)
 	mergedERules := make([]*securitygroup.EgressRule, 0)
 	for _, r := range egressRules {
-		port, protocol := 0, 0
+		port, endPort, protocol := 0, 0, 0
 		if r.ToPort != nil {
 			port = *(r.ToPort)
 		}
+		if r.ToEndPort != nil {
+			endPort = *(r.ToEndPort)
+		}
 		if r.Protocol != nil {
 			protocol = *(r.Protocol)
 		}
-		ruleKey := deduplicateKey{port: port, protocol: protocol}
+		ruleKey := deduplicateKey{port: port, endPort: endPort, protocol: protocol, action: r.Action,
+			icmpType: getICMPKey(r.ICMPType), icmpCode: getICMPKey(r.ICMPCode)}
+		if r.Priority != nil {
+			ruleKey.priority, ruleKey.hasPriority = *r.Priority, true
+		}
 		eRuleIPSet[ruleKey] = append(eRuleIPSet[ruleKey], r.ToDstIP...)
 		eRuleSGSet[ruleKey] = append(eRuleSGSet[ruleKey], r.ToSecurityGroups...)
+		eRuleServiceSet[ruleKey] = append(eRuleServiceSet[ruleKey], r.ToCloudServices...)
 	}
 	for k, v := range eRuleIPSet {
-		port, protocol := k.port, k.protocol
-		var portP, protocolP *int
+		port, endPort, protocol := k.port, k.endPort, k.protocol
+		var portP, endPortP, protocolP *int
 		if port != 0 {
 			portP = &(port)
 		}
+		if endPort != 0 {
+			endPortP = &(endPort)
+		}
 		if protocol != 0 {
 			protocolP = &(protocol)
 		}
-		eRule := securitygroup.EgressRule{ToPort: portP, ToDstIP: deduplicateIP(v),
-			ToSecurityGroups: deduplicateSG(eRuleSGSet[k]), Protocol: protocolP}
+		eRule := securitygroup.EgressRule{ToPort: portP, ToEndPort: endPortP, ToDstIP: deduplicateIP(v),
+			ToSecurityGroups: deduplicateSG(eRuleSGSet[k]), ToCloudServices: mergeCloudServices(nil, eRuleServiceSet[k], nil),
+			Protocol: protocolP, Action: k.action, Priority: k.getPriority()}
+		eRule.ICMPType, eRule.ICMPCode = k.getICMPTypeCode()
 		mergedERules = append(mergedERules, &eRule)
 	}
 	return mergedERules
 }
 
+// compactRules returns deduplicated ingress and egress rules, with adjacent ip blocks and port ranges merged to be
+// realized with fewer cloud rules.
+func compactRules(ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule) (
+	[]*securitygroup.IngressRule, []*securitygroup.EgressRule) {
+	return securitygroup.CompactIngressRules(deduplicateIngressRules(ingressRules)),
+		securitygroup.CompactEgressRules(deduplicateEgressRules(egressRules))
+}
+
+// compactRulesIgnoringPriority returns compacted ingress and egress rules as compactRules, with priorities of rules
+// cleared, and rules differing only in priority merged.
+func compactRulesIgnoringPriority(ingressRules []*securitygroup.IngressRule, egressRules []*securitygroup.EgressRule) (
+	[]*securitygroup.IngressRule, []*securitygroup.EgressRule) {
+	return securitygroup.CompactIngressRulesIgnoringPriority(deduplicateIngressRules(ingressRules)),
+		securitygroup.CompactEgressRulesIgnoringPriority(deduplicateEgressRules(egressRules))
+}
+
 // securityGroupImpl supplies common implementations for addrSecurityGroup and appliedToSecurityGroup.
 type securityGroupImpl struct {
 	// Members of this SecurityGroup.
@@ -637,6 +821,9 @@ type addrSecurityGroup struct {
 	securityGroupImpl
 	// IPs presents IPs of these non-cloud ExternalEntities associated with this AddressGroup.
 	ipBlocks []*net.IPNet
+	// cloudServices presents cloud services of ExternalEntities associated with this AddressGroup, keyed by
+	// ExternalEntity namespaced name.
+	cloudServices map[string]string
 }
 
 // newAddrSecurityGroup creates a new addSecurityGroup from Antrea AddressGroup.
@@ -678,6 +865,24 @@ func (a *addrSecurityGroup) updateIPs(added, removed []*net.IPNet, r *NetworkPol
 	a.ipBlocks = mergeIPs(a.ipBlocks, added, removed)
 }
 
+// updateCloudServices updates cloud services stored in an addrSecurityGroup, with cloud services of added
+// ExternalEntities and namespaced names of removed ExternalEntities. It returns true if cloud services of the
+// addrSecurityGroup changed. It does not trigger operations to cloud plug-in.
+func (a *addrSecurityGroup) updateCloudServices(added map[string]string, removed []string, r *NetworkPolicyReconciler) bool {
+	r.Log.V(1).Info("AddrSecurityGroup UpdateCloudServices", "Name", a.id)
+	prev := a.getCloudServices()
+	for _, key := range removed {
+		delete(a.cloudServices, key)
+	}
+	for key, service := range added {
+		if a.cloudServices == nil {
+			a.cloudServices = make(map[string]string)
+		}
+		a.cloudServices[key] = service
+	}
+	return !reflect.DeepEqual(prev, a.getCloudServices())
+}
+
 // update invokes cloud plug-in to update an addrSecurityGroup.
 func (a *addrSecurityGroup) update(added, removed []*securitygroup.CloudResource, r *NetworkPolicyReconciler) error {
 	if a.isIPBlocks() {
@@ -731,6 +936,14 @@ func (a *addrSecurityGroup) notify(op securityGroupOperation, status error, r *N
 		if a.state == securityGroupStateInit {
 			a.state = securityGroupStateCreated
 		}
+	case securityGroupOperationUpdateMembers:
+		for _, i := range nps {
+			np := i.(*networkPolicy)
+			if err := np.notifyAddrGrpMembershipChanges(r); err != nil {
+				r.Log.Error(err, "NetworkPolicy", "name", np.Name)
+			}
+		}
+		return nil
 	default:
 		r.Log.V(1).Info("AddrSecurityGroup no response processing.")
 		return nil
@@ -767,6 +980,15 @@ func (a *addrSecurityGroup) getIPs() []*net.IPNet {
 	return a.ipBlocks
 }
 
+// getCloudServices returns cloud services in an addrSecurityGroup.
+func (a *addrSecurityGroup) getCloudServices() []string {
+	services := make([]string, 0, len(a.cloudServices))
+	for _, service := range a.cloudServices {
+		services = append(services, service)
+	}
+	return mergeCloudServices(nil, services, nil)
+}
+
 // notifyNetworkPolicyChange notifies some NetworkPolicy reference to this securityGroup has changed.
 func (a *addrSecurityGroup) notifyNetworkPolicyChange(r *NetworkPolicyReconciler) {
 	if !a.deletePending {
@@ -788,8 +1010,9 @@ func (a *addrSecurityGroup) notifyNetworkPolicyChange(r *NetworkPolicyReconciler
 // appliedToSecurityGroup contains information to create a cloud appliedToSecurityGroup.
 type appliedToSecurityGroup struct {
 	securityGroupImpl
-	hasRules   bool
-	hasMembers bool
+	hasRules     bool
+	hasMembers   bool
+	hasDenyRules bool
 }
 
 // newAddrAppliedGroup creates a new addSecurityGroup from Antrea AddressGroup membership.
@@ -874,7 +1097,11 @@ func (a *appliedToSecurityGroup) updateRules(r *NetworkPolicyReconciler) error {
 		irules = append(irules, deepcopy.Copy(np.ingressRules).([]*securitygroup.IngressRule)...)
 		erules = append(erules, deepcopy.Copy(np.egressRules).([]*securitygroup.EgressRule)...)
 	}
-	irules, erules = deduplicateIngressRules(irules), deduplicateEgressRules(erules)
+	ing, eg := a.nonIsolatedRules(nps, r)
+	irules = append(irules, ing...)
+	erules = append(erules, eg...)
+	irules, erules = compactRules(irules, erules)
+	a.hasDenyRules = hasDenyRules(irules, erules)
 	r.Log.V(1).Info("AppliedToSecurityGroup update rules", "Name", a.id,
 		"ingressRules", irules, "egressRules", erules)
 	ch := securitygroup.CloudSecurityGroup.UpdateSecurityGroupRules(&a.id, irules, erules)
@@ -897,7 +1124,74 @@ func (a *appliedToSecurityGroup) update(added, removed []*securitygroup.CloudRes
 			_ = tracker.update(a, false, r)
 		}
 	}
-	return a.updateImpl(a, added, removed, false, r)
+	if err := a.updateImpl(a, added, removed, false, r); err != nil {
+		return err
+	}
+	// directions isolated by Kubernetes NetworkPolicies of this appliedToSecurityGroup change for its members.
+	if a.hasK8sNetworkPolicy(r) {
+		a.notifyNetworkPolicyChange(r)
+		r.notifyIsolationChange(append(added, removed...), map[string]struct{}{a.id.String(): {}})
+	}
+	return nil
+}
+
+// hasK8sNetworkPolicy returns true if a Kubernetes NetworkPolicy is applied to appliedToSecurityGroup.
+func (a *appliedToSecurityGroup) hasK8sNetworkPolicy(r *NetworkPolicyReconciler) bool {
+	nps, _ := r.networkPolicyIndexer.ByIndex(networkPolicyIndexerByAppliedToGrp, a.id.Name)
+	for _, i := range nps {
+		if i.(*networkPolicy).isK8sNetworkPolicy() {
+			return true
+		}
+	}
+	return false
+}
+
+// nonIsolatedRules returns rules allowing all traffic in directions not isolated for members of appliedToSecurityGroup,
+// if Kubernetes NetworkPolicies are among networkPolicies nps applied to it. A cloud resource is allowed the union of
+// rules of its appliedToSecurityGroups, hence a direction is opened only if no Kubernetes NetworkPolicy applied to any
+// member, through any appliedToSecurityGroup, isolates it.
+func (a *appliedToSecurityGroup) nonIsolatedRules(nps []interface{}, r *NetworkPolicyReconciler) (
+	ingressList []*securitygroup.IngressRule, egressList []*securitygroup.EgressRule) {
+	selected := make(map[string]*networkPolicy)
+	for _, i := range nps {
+		if np := i.(*networkPolicy); np.isK8sNetworkPolicy() {
+			selected[getNetworkPolicyKey(&np.NetworkPolicy)] = np
+		}
+	}
+	if len(selected) == 0 {
+		return
+	}
+	for _, rsc := range a.members {
+		tracker := r.getCloudResourceNPTracker(rsc, false)
+		if tracker == nil {
+			continue
+		}
+		for _, asg := range tracker.appliedToSGs {
+			memberNPs, err := r.networkPolicyIndexer.ByIndex(networkPolicyIndexerByAppliedToGrp, asg.id.Name)
+			if err != nil {
+				r.Log.Error(err, "Get networkPolicy indexer", "Key", asg.id.Name)
+				continue
+			}
+			for _, i := range memberNPs {
+				if np := i.(*networkPolicy); np.isK8sNetworkPolicy() {
+					selected[getNetworkPolicyKey(&np.NetworkPolicy)] = np
+				}
+			}
+		}
+	}
+	isolateIngress, isolateEgress := false, false
+	for _, np := range selected {
+		ingress, egress := np.isolatedDirections()
+		isolateIngress = isolateIngress || ingress
+		isolateEgress = isolateEgress || egress
+	}
+	if !isolateIngress {
+		ingressList = append(ingressList, &securitygroup.IngressRule{FromSrcIP: anyIPNets, Action: securitygroup.RuleActionAllow})
+	}
+	if !isolateEgress {
+		egressList = append(egressList, &securitygroup.EgressRule{ToDstIP: anyIPNets, Action: securitygroup.RuleActionAllow})
+	}
+	return
 }
 
 // getStatus returns status of this appliedToSecurityGroup.
@@ -947,6 +1241,10 @@ func (a *appliedToSecurityGroup) notify(op securityGroupOperation, status error,
 		}
 	case securityGroupOperationUpdateMembers:
 		a.hasMembers = true
+		// Cloud plug-in may realize deny rules based on members, e.g. on subnets of members, re-apply rules.
+		if a.hasDenyRules {
+			return a.updateRules(r)
+		}
 	case securityGroupOperationUpdateRules:
 		// AppliedToSecurityGroup added rules, now add members.
 		a.hasRules = true
@@ -982,9 +1280,76 @@ func (a *appliedToSecurityGroup) notifyNetworkPolicyChange(r *NetworkPolicyRecon
 	}
 }
 
+// notifyIsolationChange notifies appliedToSecurityGroups of cloud resources, other than notified ones, that directions
+// isolated by Kubernetes NetworkPolicies for the cloud resources may have changed.
+func (r *NetworkPolicyReconciler) notifyIsolationChange(rscs []*securitygroup.CloudResource, notified map[string]struct{}) {
+	for _, rsc := range rscs {
+		tracker := r.getCloudResourceNPTracker(rsc, false)
+		if tracker == nil {
+			continue
+		}
+		for key, asg := range tracker.appliedToSGs {
+			if _, ok := notified[key]; ok {
+				continue
+			}
+			notified[key] = struct{}{}
+			asg.notifyNetworkPolicyChange(r)
+		}
+	}
+}
+
 // networkPolicyRule describe an Antrea networkPolicy rule.
 type networkPolicyRule struct {
-	rule *antreanetworking.NetworkPolicyRule
+	rule     *antreanetworking.NetworkPolicyRule
+	priority *securitygroup.RulePriority
+}
+
+// getServicePortRange returns start and end port of service s. End port is nil if service is not a port range.
+// It returns false if service is on a named port, which cannot be resolved for cloud resources.
+func getServicePortRange(s antreanetworking.Service) (*int, *int, bool) {
+	if s.Port.Type == intstr.String {
+		return nil, nil, false
+	}
+	port := int(s.Port.IntVal)
+	if s.EndPort == nil || int(*s.EndPort) <= port {
+		return &port, nil, true
+	}
+	endPort := int(*s.EndPort)
+	return &port, &endPort, true
+}
+
+// getNamedPortError returns an error if rules have services on named ports. Named ports cannot be resolved for cloud
+// resources, hence those services are not realized, and the cloud rules allow or deny less traffic than the rules.
+func getNamedPortError(rules []antreanetworking.NetworkPolicyRule) error {
+	var ports []string
+	for _, rule := range rules {
+		for _, s := range rule.Services {
+			if s.Port != nil && s.Port.Type == intstr.String {
+				ports = append(ports, s.Port.StrVal)
+			}
+		}
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+	return fmt.Errorf("named ports %v are not supported, services on named ports are not realized", ports)
+}
+
+// getServiceICMPTypeCode returns ICMP type and code of service s, nil if s is not ICMP or matches any ICMP type or code.
+func getServiceICMPTypeCode(s antreanetworking.Service) (*int, *int) {
+	if s.Protocol == nil || *s.Protocol != antreanetworking.ProtocolICMP {
+		return nil, nil
+	}
+	var icmpType, icmpCode *int
+	if s.ICMPType != nil {
+		v := int(*s.ICMPType)
+		icmpType = &v
+	}
+	if s.ICMPCode != nil {
+		v := int(*s.ICMPCode)
+		icmpCode = &v
+	}
+	return icmpType, icmpCode
 }
 
 // rules generate cloud plug-in ingressRule and/or egressRule from an networkPolicyRule.
@@ -992,11 +1357,14 @@ func (r *networkPolicyRule) rules(rr *NetworkPolicyReconciler) (ingressList []*s
 	egressList []*securitygroup.EgressRule, ready bool) {
 	ready = true
 	rule := r.rule
+	action := securitygroup.RuleActionAllow
+	if rule.Action != nil {
+		action = AntreaRuleActionMap[*rule.Action]
+	}
 	if rule.Direction == antreanetworking.DirectionIn {
-		ingress := &securitygroup.IngressRule{}
+		ingress := &securitygroup.IngressRule{Action: action, Priority: r.priority}
 		for _, ip := range rule.From.IPBlocks {
-			ipNet := net.IPNet{IP: net.IP(ip.CIDR.IP), Mask: net.CIDRMask(int(ip.CIDR.PrefixLength), 32)}
-			ingress.FromSrcIP = append(ingress.FromSrcIP, &ipNet)
+			ingress.FromSrcIP = append(ingress.FromSrcIP, getIPBlockIPNet(ip))
 		}
 		for _, ag := range rule.From.AddressGroups {
 			sgs, err := rr.addrSGIndexer.ByIndex(addrAppliedToIndexerByGroupID, ag)
@@ -1012,13 +1380,15 @@ func (r *networkPolicyRule) rules(rr *NetworkPolicyReconciler) (ingressList []*s
 			for _, i := range sgs {
 				sg := i.(*addrSecurityGroup)
 				ingress.FromSrcIP = append(ingress.FromSrcIP, sg.getIPs()...)
+				ingress.FromCloudServices = append(ingress.FromCloudServices, sg.getCloudServices()...)
 				id := sg.getID()
 				if len(id.Vpc) > 0 {
 					ingress.FromSecurityGroups = append(ingress.FromSecurityGroups, &id)
 				}
 			}
 		}
-		if ingress.Protocol == nil && ingress.FromSecurityGroups == nil && ingress.FromSrcIP == nil {
+		if ingress.Protocol == nil && ingress.FromSecurityGroups == nil && ingress.FromSrcIP == nil &&
+			ingress.FromCloudServices == nil {
 			return
 		}
 		if rule.Services == nil {
@@ -1032,18 +1402,22 @@ func (r *networkPolicyRule) rules(rr *NetworkPolicyReconciler) (ingressList []*s
 					ii.Protocol = &p
 				}
 			}
+			ii.ICMPType, ii.ICMPCode = getServiceICMPTypeCode(s)
 			if s.Port != nil {
-				port := int(s.Port.IntVal)
-				ii.FromPort = &port
+				port, endPort, ok := getServicePortRange(s)
+				if !ok {
+					rr.Log.Info("Ingress rule with named port is not supported, ignored", "Port", s.Port.StrVal)
+					continue
+				}
+				ii.FromPort, ii.FromEndPort = port, endPort
 			}
 			ingressList = append(ingressList, ii)
 		}
 		return
 	}
-	egress := &securitygroup.EgressRule{}
+	egress := &securitygroup.EgressRule{Action: action, Priority: r.priority}
 	for _, ip := range rule.To.IPBlocks {
-		ipNet := net.IPNet{IP: net.IP(ip.CIDR.IP), Mask: net.CIDRMask(int(ip.CIDR.PrefixLength), 32)}
-		egress.ToDstIP = append(egress.ToDstIP, &ipNet)
+		egress.ToDstIP = append(egress.ToDstIP, getIPBlockIPNet(ip))
 	}
 	for _, ag := range rule.To.AddressGroups {
 		sgs, err := rr.addrSGIndexer.ByIndex(addrAppliedToIndexerByGroupID, ag)
@@ -1059,13 +1433,14 @@ func (r *networkPolicyRule) rules(rr *NetworkPolicyReconciler) (ingressList []*s
 		for _, i := range sgs {
 			sg := i.(*addrSecurityGroup)
 			egress.ToDstIP = append(egress.ToDstIP, sg.getIPs()...)
+			egress.ToCloudServices = append(egress.ToCloudServices, sg.getCloudServices()...)
 			id := sg.getID()
 			if len(id.Vpc) > 0 {
 				egress.ToSecurityGroups = append(egress.ToSecurityGroups, &id)
 			}
 		}
 	}
-	if egress.Protocol == nil && egress.ToSecurityGroups == nil && egress.ToDstIP == nil {
+	if egress.Protocol == nil && egress.ToSecurityGroups == nil && egress.ToDstIP == nil && egress.ToCloudServices == nil {
 		return
 	}
 	if rule.Services == nil {
@@ -1080,9 +1455,14 @@ func (r *networkPolicyRule) rules(rr *NetworkPolicyReconciler) (ingressList []*s
 				ee.Protocol = &p
 			}
 		}
+		ee.ICMPType, ee.ICMPCode = getServiceICMPTypeCode(s)
 		if s.Port != nil {
-			port := int(s.Port.IntVal)
-			ee.ToPort = &port
+			port, endPort, ok := getServicePortRange(s)
+			if !ok {
+				rr.Log.Info("Egress rule with named port is not supported, ignored", "Port", s.Port.StrVal)
+				continue
+			}
+			ee.ToPort, ee.ToEndPort = port, endPort
 		}
 		egressList = append(egressList, ee)
 	}
@@ -1117,6 +1497,9 @@ func (n *networkPolicy) update(anp *antreanetworking.NetworkPolicy, recompute bo
 		}
 		modifiedAppliedTo = n.AppliedToGroups
 	} else {
+		// priorities of rules change with tier or priority of networkPolicy.
+		priorityChanged := !reflect.DeepEqual(anp.TierPriority, n.TierPriority) || !reflect.DeepEqual(anp.Priority, n.Priority)
+		n.TierPriority, n.Priority = anp.TierPriority, anp.Priority
 		if !reflect.DeepEqual(anp.Rules, n.Rules) {
 			// Indexer does not work with in-place update. Do delete->update->add
 			if err := r.networkPolicyIndexer.Delete(n); err != nil {
@@ -1132,6 +1515,10 @@ func (n *networkPolicy) update(anp *antreanetworking.NetworkPolicy, recompute bo
 			if ok := n.computeRules(r); ok {
 				modifiedAppliedTo = n.AppliedToGroups
 			}
+		} else if priorityChanged {
+			if ok := n.computeRules(r); ok {
+				modifiedAppliedTo = n.AppliedToGroups
+			}
 		}
 		if !reflect.DeepEqual(anp.AppliedToGroups, n.AppliedToGroups) {
 			// Indexer does not work with in-place update. Do delete->update->add
@@ -1161,6 +1548,8 @@ func (n *networkPolicy) update(anp *antreanetworking.NetworkPolicy, recompute bo
 	// process appliedToGroups needs updates in this networkPolicy.
 	modifiedAppliedTo = append(modifiedAppliedTo, removedAppliedTo...)
 	modifiedAppliedTo = append(modifiedAppliedTo, addedAppliedTo...)
+	notified := make(map[string]struct{})
+	var members []*securitygroup.CloudResource
 	for _, id := range modifiedAppliedTo {
 		sgs, err := r.appliedToSGIndexer.ByIndex(addrAppliedToIndexerByGroupID, id)
 		if err != nil {
@@ -1170,8 +1559,13 @@ func (n *networkPolicy) update(anp *antreanetworking.NetworkPolicy, recompute bo
 		for _, i := range sgs {
 			sg := i.(*appliedToSecurityGroup)
 			sg.notifyNetworkPolicyChange(r)
+			notified[sg.id.String()] = struct{}{}
+			members = append(members, sg.members...)
 		}
 	}
+	if n.isK8sNetworkPolicy() {
+		r.notifyIsolationChange(members, notified)
+	}
 }
 
 // delete deletes a networkPolicy.
@@ -1180,6 +1574,8 @@ func (n *networkPolicy) delete(r *NetworkPolicyReconciler) error {
 	if err := r.networkPolicyIndexer.Delete(n); err != nil {
 		r.Log.Error(err, "delete from networkPolicy indexer", "Name", n.Name, "Namespace", n.Namespace)
 	}
+	notified := make(map[string]struct{})
+	var members []*securitygroup.CloudResource
 	for _, gname := range n.AppliedToGroups {
 		sgs, err := r.appliedToSGIndexer.ByIndex(addrAppliedToIndexerByGroupID, gname)
 		if err != nil {
@@ -1188,8 +1584,13 @@ func (n *networkPolicy) delete(r *NetworkPolicyReconciler) error {
 		for _, i := range sgs {
 			sg := i.(*appliedToSecurityGroup)
 			sg.notifyNetworkPolicyChange(r)
+			notified[sg.id.String()] = struct{}{}
+			members = append(members, sg.members...)
 		}
 	}
+	if n.isK8sNetworkPolicy() {
+		r.notifyIsolationChange(members, notified)
+	}
 	var addrGrp []string
 	for _, rule := range n.Rules {
 		addrGrp = append(addrGrp, rule.To.AddressGroups...)
@@ -1280,6 +1681,88 @@ func (n *networkPolicy) notifyAddrGrpChanges(r *NetworkPolicyReconciler) error {
 	return nil
 }
 
+// notifyAddrGrpMembershipChanges notifies networkPolicy members of a referenced addrSecurityGroup have changed.
+// Cloud plug-in may resolve addrSecurityGroups referenced in deny rules to IPs, hence rules are re-applied.
+func (n *networkPolicy) notifyAddrGrpMembershipChanges(r *NetworkPolicyReconciler) error {
+	if !n.rulesReady || !n.hasDenyRulesWithAddrGrp() {
+		return nil
+	}
+	for _, gname := range n.AppliedToGroups {
+		sgs, err := r.appliedToSGIndexer.ByIndex(addrAppliedToIndexerByGroupID, gname)
+		if err != nil {
+			return fmt.Errorf("unable to get appliedToSGs %s from indexer: %w", gname, err)
+		}
+		for _, i := range sgs {
+			sg := i.(*appliedToSecurityGroup)
+			if err := sg.updateRules(r); err != nil {
+				r.Log.Error(err, "NetworkPolicy update rules")
+			}
+		}
+	}
+	return nil
+}
+
+// hasDenyRulesWithAddrGrp returns true if networkPolicy has deny rules referencing addrSecurityGroups.
+func (n *networkPolicy) hasDenyRulesWithAddrGrp() bool {
+	for _, rule := range n.ingressRules {
+		if rule.Action.IsDeny() && len(rule.FromSecurityGroups) > 0 {
+			return true
+		}
+	}
+	for _, rule := range n.egressRules {
+		if rule.Action.IsDeny() && len(rule.ToSecurityGroups) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rulePriority returns priority of rule of networkPolicy. It returns nil if networkPolicy is not in a tier.
+func (n *networkPolicy) rulePriority(rule *antreanetworking.NetworkPolicyRule) *securitygroup.RulePriority {
+	if n.TierPriority == nil || n.Priority == nil {
+		return nil
+	}
+	return &securitygroup.RulePriority{TierPriority: *n.TierPriority, PolicyPriority: *n.Priority, RuleIndex: rule.Priority}
+}
+
+// isK8sNetworkPolicy returns true if networkPolicy is converted from a Kubernetes NetworkPolicy.
+func (n *networkPolicy) isK8sNetworkPolicy() bool {
+	return n.SourceRef != nil && n.SourceRef.Type == antreanetworking.K8sNetworkPolicy
+}
+
+// getNetworkPolicyStatusName returns name of an Antrea internal NetworkPolicy in NetworkPolicyStatus. A Kubernetes
+// NetworkPolicy may have the same name as an ANP in its namespace, its name is hence qualified by its source type.
+func getNetworkPolicyStatusName(np *antreanetworking.NetworkPolicy) string {
+	if np.SourceRef != nil && np.SourceRef.Type == antreanetworking.K8sNetworkPolicy {
+		return string(antreanetworking.K8sNetworkPolicy) + ":" + np.Name
+	}
+	return np.Name
+}
+
+// getNetworkPolicyKey returns key of an Antrea internal NetworkPolicy in networkPolicyIndexer, which is its namespace
+// and status name.
+func getNetworkPolicyKey(np *antreanetworking.NetworkPolicy) string {
+	return types.NamespacedName{Namespace: np.Namespace, Name: getNetworkPolicyStatusName(np)}.String()
+}
+
+// isolatedDirections returns whether a Kubernetes networkPolicy isolates ingress and egress. Antrea converts a
+// Kubernetes NetworkPolicy isolating a direction per its policy types to at least one rule in that direction; a
+// default deny policy has a rule without peers, which computes to no rule, as appliedTo security groups deny any
+// traffic not allowed by their rules.
+func (n *networkPolicy) isolatedDirections() (isolateIngress, isolateEgress bool) {
+	if !n.isK8sNetworkPolicy() {
+		return false, false
+	}
+	for _, r := range n.Rules {
+		if r.Direction == antreanetworking.DirectionIn {
+			isolateIngress = true
+		} else {
+			isolateEgress = true
+		}
+	}
+	return
+}
+
 // computeRules computes ingress and egress rules associated with networkPolicy.
 func (n *networkPolicy) computeRules(rr *NetworkPolicyReconciler) bool {
 	rr.Log.V(1).Info("Compute rules", "networkPolicy", n.Name)
@@ -1287,7 +1770,7 @@ func (n *networkPolicy) computeRules(rr *NetworkPolicyReconciler) bool {
 	n.egressRules = nil
 	n.rulesReady = false
 	for _, r := range n.Rules {
-		ing, eg, ready := (&networkPolicyRule{rule: &r}).rules(rr)
+		ing, eg, ready := (&networkPolicyRule{rule: &r, priority: n.rulePriority(&r)}).rules(rr)
 		if !ready {
 			n.ingressRules = nil
 			n.egressRules = nil
@@ -1300,6 +1783,13 @@ func (n *networkPolicy) computeRules(rr *NetworkPolicyReconciler) bool {
 			n.egressRules = append(n.egressRules, eg...)
 		}
 	}
+	// rules of a default deny networkPolicy are computed, though there is no rule.
+	if n.ingressRules == nil {
+		n.ingressRules = make([]*securitygroup.IngressRule, 0)
+	}
+	if n.egressRules == nil {
+		n.egressRules = make([]*securitygroup.EgressRule, 0)
+	}
 	_ = n.computeRulesReady(false, rr)
 	return n.rulesReady
 }
@@ -1324,5 +1814,8 @@ func (n *networkPolicy) getStatus(r *NetworkPolicyReconciler) error {
 	if n.ingressRules == nil && n.egressRules == nil {
 		return &InProgress{}
 	}
+	if err := getNamedPortError(n.Rules); err != nil {
+		return err
+	}
 	return n.computeRulesReady(true, r)
 }
@@ -16,19 +16,30 @@ package cloud
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync/atomic"
 
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	antreanetworking "antrea.io/antrea/pkg/apis/controlplane/v1beta2"
 	cloud "antrea.io/nephe/apis/crd/v1alpha1"
+	runtimev1alpha1 "antrea.io/nephe/apis/runtime/v1alpha1"
+	cloudprovider "antrea.io/nephe/pkg/cloud-provider"
 	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+	"antrea.io/nephe/pkg/metrics"
 )
 
 const (
 	NetworkPolicyStatusApplied = "applied"
+	// NetworkPolicyStatusDryRun is the status of a NetworkPolicy planned but not applied to the cloud in dry-run mode.
+	NetworkPolicyStatusDryRun = "dry-run"
+	// NetworkPolicyReasonQuotaExceeded prefixes the status of a NetworkPolicy whose rules or memberships exceed a
+	// cloud quota, distinguishing it from other realization failures.
+	NetworkPolicyReasonQuotaExceeded = "QuotaExceeded"
 )
 
 var (
@@ -38,6 +49,15 @@ var (
 	}
 )
 
+// realizationOrder orders realization states of a NetworkPolicy on cloud resources, the NetworkPolicy realization
+// is the highest state on any cloud resource.
+var realizationOrder = map[runtimev1alpha1.Realization]int{
+	runtimev1alpha1.Success:    0,
+	runtimev1alpha1.DryRun:     1,
+	runtimev1alpha1.InProgress: 2,
+	runtimev1alpha1.Failed:     3,
+}
+
 const (
 	AppliedSecurityGroupDeleteError = "Deleting/Detaching %v: %v"
 )
@@ -54,14 +74,17 @@ func vmNPStatusSetter(tracker *cloudResourceNPTracker, r *NetworkPolicyReconcile
 		return false, err
 	}
 	for _, vm := range vmList.Items {
+		// status of cluster scoped network policies applies to VMs of all namespaces.
 		npStatus, ok := status[vm.Namespace]
 		if len(status[""]) > 0 {
-			if npStatus == nil {
-				npStatus = make(map[string]string)
-			}
+			clusterNPStatus := make(map[string]string, len(npStatus)+len(status[""]))
 			for k, v := range status[""] {
-				npStatus[k] = v
+				clusterNPStatus[k] = v
+			}
+			for k, v := range npStatus {
+				clusterNPStatus[k] = v
 			}
+			npStatus, ok = clusterNPStatus, true
 		}
 		indexKey := types.NamespacedName{Namespace: vm.Namespace, Name: vm.Name}
 		obj, found, _ := r.virtualMachinePolicyIndexer.GetByKey(indexKey.String())
@@ -101,6 +124,21 @@ type NetworkPolicyStatus struct {
 	types.NamespacedName
 	// map of network policy (ANP) name to their realization status.
 	NPStatus map[string]string
+	// ResourceVersion is set when NetworkPolicyStatus is changed in a watchable indexer.
+	ResourceVersion uint64
+}
+
+// deepCopy returns a copy of NetworkPolicyStatus.
+func (s *NetworkPolicyStatus) deepCopy() *NetworkPolicyStatus {
+	npStatus := &NetworkPolicyStatus{
+		NamespacedName:  s.NamespacedName,
+		NPStatus:        make(map[string]string, len(s.NPStatus)),
+		ResourceVersion: s.ResourceVersion,
+	}
+	for k, v := range s.NPStatus {
+		npStatus.NPStatus[k] = v
+	}
+	return npStatus
 }
 
 func newNetworkPolicyStatus(namespace, name string) *NetworkPolicyStatus {
@@ -148,12 +186,19 @@ func (r *NetworkPolicyReconciler) getCloudResourceNPTracker(rsc *securitygroup.C
 
 func (r *NetworkPolicyReconciler) processCloudResourceNPTrackers() {
 	log := r.Log.WithName("NPTracker")
+	statusUpdated := false
+	defer func() {
+		if statusUpdated {
+			r.updateNetworkPolicyRealizationMetrics()
+		}
+	}()
 	for _, i := range r.cloudResourceNPTrackerIndexer.List() {
 		tracker := i.(*cloudResourceNPTracker)
 		if !tracker.isDirty() {
 			continue
 		}
-		_, err := resourceNPStatusSetter[tracker.cloudResource.Type](tracker, r)
+		updated, err := resourceNPStatusSetter[tracker.cloudResource.Type](tracker, r)
+		statusUpdated = statusUpdated || updated
 		if err != nil {
 			log.Error(err, "Set cloud resource NetworkPolicy status", "crd", tracker.cloudResource)
 			continue
@@ -167,6 +212,61 @@ func (r *NetworkPolicyReconciler) processCloudResourceNPTrackers() {
 	}
 }
 
+// updateNetworkPolicyRealizationMetrics reports number of NetworkPolicies per realization state. A NetworkPolicy
+// is realized on a cloud resource basis, it is failed if it fails on any cloud resource, and it is in progress
+// if it is in progress on any cloud resource.
+func (r *NetworkPolicyReconciler) updateNetworkPolicyRealizationMetrics() {
+	inProgress := (&InProgress{}).String()
+	realizations := make(map[types.NamespacedName]runtimev1alpha1.Realization)
+	for _, i := range r.virtualMachinePolicyIndexer.List() {
+		npStatus := i.(*NetworkPolicyStatus)
+		for name, status := range npStatus.NPStatus {
+			key := types.NamespacedName{Namespace: npStatus.Namespace, Name: name}
+			if r.isClusterNetworkPolicy(key) {
+				key.Namespace = ""
+			}
+			realization := runtimev1alpha1.Success
+			if status == NetworkPolicyStatusDryRun {
+				realization = runtimev1alpha1.DryRun
+			} else if strings.Contains(status, inProgress) {
+				realization = runtimev1alpha1.InProgress
+			} else if status != NetworkPolicyStatusApplied {
+				realization = runtimev1alpha1.Failed
+			}
+			if prev, ok := realizations[key]; ok && realizationOrder[prev] >= realizationOrder[realization] {
+				continue
+			}
+			realizations[key] = realization
+		}
+	}
+	counts := map[runtimev1alpha1.Realization]int{
+		runtimev1alpha1.Success:    0,
+		runtimev1alpha1.DryRun:     0,
+		runtimev1alpha1.InProgress: 0,
+		runtimev1alpha1.Failed:     0,
+	}
+	for _, realization := range realizations {
+		counts[realization]++
+	}
+	for realization, count := range counts {
+		metrics.NetworkPolicyRealization.WithLabelValues(string(realization)).Set(float64(count))
+	}
+}
+
+// isClusterNetworkPolicy returns true if the network policy of status key is an Antrea ClusterNetworkPolicy, such that
+// its realization is counted once across namespaces.
+func (r *NetworkPolicyReconciler) isClusterNetworkPolicy(key types.NamespacedName) bool {
+	i, found, _ := r.networkPolicyIndexer.GetByKey(key.String())
+	if !found {
+		// status of cluster scoped network policies is reported in namespaces of cloud resources.
+		if i, found, _ = r.networkPolicyIndexer.GetByKey(types.NamespacedName{Name: key.Name}.String()); !found {
+			return false
+		}
+	}
+	np := i.(*networkPolicy)
+	return np.SourceRef != nil && np.SourceRef.Type == antreanetworking.AntreaClusterNetworkPolicy
+}
+
 func (c *cloudResourceNPTracker) update(sg *appliedToSecurityGroup, isDelete bool, r *NetworkPolicyReconciler) error {
 	_, found := c.appliedToSGs[sg.id.String()]
 	if found != isDelete {
@@ -229,20 +329,28 @@ func (c *cloudResourceNPTracker) computeNPStatus(r *NetworkPolicyReconciler) map
 		// networkPolicy rules are ready to be sent, and
 		// appliedToSG of this cloud resource is ready.
 		if status := np.getStatus(r); status != nil {
-			npList[np.Name] = status.Error()
+			npList[getNetworkPolicyStatusName(&np.NetworkPolicy)] = status.Error()
 			continue
 		}
 		i, found, _ := r.appliedToSGIndexer.GetByKey(asgName)
 		if !found {
-			npList[np.Name] = asgName + "=Internal Error "
+			npList[getNetworkPolicyStatusName(&np.NetworkPolicy)] = asgName + "=Internal Error "
 			continue
 		}
 		asg := i.(*appliedToSecurityGroup)
 		if status := asg.getStatus(); status != nil {
-			npList[np.Name] = asgName + "=" + status.Error()
+			if errors.Is(status, securitygroup.ErrQuotaExceeded) {
+				npList[getNetworkPolicyStatusName(&np.NetworkPolicy)] = asgName + "=" + NetworkPolicyReasonQuotaExceeded + ": " + status.Error()
+			} else {
+				npList[getNetworkPolicyStatusName(&np.NetworkPolicy)] = asgName + "=" + status.Error()
+			}
+			continue
+		}
+		if cloudprovider.IsSecurityGroupDryRun(&asg.id) {
+			npList[getNetworkPolicyStatusName(&np.NetworkPolicy)] = NetworkPolicyStatusDryRun
 			continue
 		}
-		npList[np.Name] = NetworkPolicyStatusApplied
+		npList[getNetworkPolicyStatusName(&np.NetworkPolicy)] = NetworkPolicyStatusApplied
 	}
 
 	newPrevSgs := make(map[string]*appliedToSecurityGroup)
@@ -269,7 +377,7 @@ func (c *cloudResourceNPTracker) computeNPStatus(r *NetworkPolicyReconciler) map
 				npList = make(map[string]string)
 				ret[np.Namespace] = npList
 			}
-			npList[np.Name] = errMsg
+			npList[getNetworkPolicyStatusName(&np.NetworkPolicy)] = errMsg
 		}
 		if len(nps) == 0 {
 			// handle dangling appliedToGroups with no namespaces.
@@ -27,7 +27,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -57,6 +56,10 @@ const (
 	// NetworkPolicy controller is ready to sync after it receives bookmarks from
 	// networkpolicy, addrssGroup and appliedToGroup.
 	npSyncReadyBookMarkCnt = 3
+
+	// Names of PendingItemQueues reported in metrics.
+	pendingDeleteQueueName = "pending_delete"
+	retryQueueName         = "retry"
 )
 
 // +kubebuilder:rbac:groups=controlplane.antrea.io,resources=networkpolicies,verbs=get;list;watch
@@ -110,13 +113,22 @@ func (r *NetworkPolicyReconciler) isNetworkPolicySupported(anp *antreanetworking
 	if anp.SourceRef == nil {
 		return fmt.Errorf("source reference not set in network policy")
 	}
-	if anp.SourceRef.Type != antreanetworking.AntreaNetworkPolicy {
-		return fmt.Errorf("only antrea network policy is supported")
+	if anp.SourceRef.Type != antreanetworking.AntreaNetworkPolicy &&
+		anp.SourceRef.Type != antreanetworking.AntreaClusterNetworkPolicy &&
+		anp.SourceRef.Type != antreanetworking.K8sNetworkPolicy {
+		return fmt.Errorf("only antrea network policy, antrea cluster network policy and kubernetes network policy are supported")
 	}
 	// Check for support actions
 	for _, rule := range anp.Rules {
-		if rule.Action != nil && *rule.Action != v1alpha1.RuleActionAllow {
-			return fmt.Errorf("only Allow action is supported in antrea network policy")
+		if rule.Action != nil && *rule.Action != v1alpha1.RuleActionAllow &&
+			*rule.Action != v1alpha1.RuleActionDrop && *rule.Action != v1alpha1.RuleActionReject {
+			return fmt.Errorf("only Allow, Drop and Reject actions are supported in antrea network policy")
+		}
+		// clouds match an ICMP code only within an ICMP type.
+		for _, s := range rule.Services {
+			if s.ICMPCode != nil && s.ICMPType == nil {
+				return fmt.Errorf("icmp code %v without icmp type is not supported", *s.ICMPCode)
+			}
 		}
 	}
 	return nil
@@ -165,9 +177,10 @@ func (r *NetworkPolicyReconciler) processMemberGrp(name string, eventType watch.
 
 	var addedMembers, removedMembers map[string][]*securitygroup.CloudResource
 	var addedIPs, removedIPs []*net.IPNet
-	var notFoundMember []string
+	var addedCloudServices map[string]string
+	var removedEntities, notFoundMember []string
 	if eventType == watch.Added {
-		if addedMembers, addedIPs, notFoundMember, err = vpcsFromGroupMembers(added, r); err != nil {
+		if addedMembers, addedIPs, addedCloudServices, notFoundMember, err = vpcsFromGroupMembers(added, r); err != nil {
 			return err
 		}
 		if len(notFoundMember) > 0 {
@@ -175,16 +188,18 @@ func (r *NetworkPolicyReconciler) processMemberGrp(name string, eventType watch.
 			return err
 		}
 	} else if eventType == watch.Modified {
-		if addedMembers, addedIPs, notFoundMember, err = vpcsFromGroupMembers(added, r); err != nil {
+		if addedMembers, addedIPs, addedCloudServices, notFoundMember, err = vpcsFromGroupMembers(added, r); err != nil {
 			return err
 		}
 		if len(notFoundMember) > 0 {
 			err = fmt.Errorf("missing externalEntities: %v", notFoundMember)
 			return err
 		}
-		if removedMembers, removedIPs, notFoundMember, err = vpcsFromGroupMembers(removed, r); err != nil {
+		if removedMembers, removedIPs, _, notFoundMember, err = vpcsFromGroupMembers(removed, r); err != nil {
 			return err
 		}
+		// cloud services are removed by ExternalEntity, as removed ExternalEntities may be deleted or relabeled.
+		removedEntities = externalEntityKeys(removed)
 		if len(notFoundMember) > 0 {
 			sgs, _ := r.addrSGIndexer.ByIndex(addrAppliedToIndexerByGroupID, name)
 			for _, i := range sgs {
@@ -256,18 +271,24 @@ func (r *NetworkPolicyReconciler) processMemberGrp(name string, eventType watch.
 			continue
 		}
 	}
-	if isAddrGrp && (addedIPs != nil || removedIPs != nil) {
+	if isAddrGrp && (addedIPs != nil || removedIPs != nil || addedCloudServices != nil || removedEntities != nil) {
 		key := &securitygroup.CloudResourceID{Name: name, Vpc: ""}
 		sg, _, _ := indexer.GetByKey(key.String())
 		if sg != nil {
 			sg.(*addrSecurityGroup).updateIPs(addedIPs, removedIPs, r)
+			if sg.(*addrSecurityGroup).updateCloudServices(addedCloudServices, removedEntities, r) ||
+				addedIPs != nil || removedIPs != nil {
+				sgChanges = true
+			}
 		} else if eventType == watch.Added {
 			sg = creator(key, addedIPs, nil)
+			sg.(*addrSecurityGroup).updateCloudServices(addedCloudServices, nil, r)
 			_ = sg.(*addrSecurityGroup).add(r)
-		} else {
+			sgChanges = true
+		} else if addedIPs != nil || removedIPs != nil || addedCloudServices != nil {
 			r.Log.Error(nil, "Update to IP block does find security group", "key", key)
+			sgChanges = true
 		}
-		sgChanges = true
 	}
 	// Empty membershipGroup
 	if eventType == watch.Added && len(added) == 0 && len(removed) == 0 && isAddrGrp {
@@ -347,14 +368,15 @@ func (r *NetworkPolicyReconciler) processNetworkPolicy(event watch.Event) error
 		return err
 	}
 	if anp.Namespace == "" {
-		// anp comes from antrea controller, recover to its original name/namespace
+		// anp comes from antrea controller, recover to its original name/namespace. Namespace of an antrea cluster
+		// network policy remains empty, so that it is keyed by cluster scope.
 		anp.Name = anp.SourceRef.Name
 		anp.Namespace = anp.SourceRef.Namespace
 	}
 
 	var np *networkPolicy
 	isCreate := false
-	npKey := types.NamespacedName{Name: anp.Name, Namespace: anp.Namespace}.String()
+	npKey := getNetworkPolicyKey(anp)
 	if i, ok, _ := r.networkPolicyIndexer.GetByKey(npKey); !ok {
 		np = &networkPolicy{}
 		anp.DeepCopyInto(&np.NetworkPolicy)
@@ -370,7 +392,8 @@ func (r *NetworkPolicyReconciler) processNetworkPolicy(event watch.Event) error
 		return nil
 	}
 	if !isCreate && reflect.DeepEqual(anp.Rules, np.Rules) &&
-		reflect.DeepEqual(anp.AppliedToGroups, np.AppliedToGroups) {
+		reflect.DeepEqual(anp.AppliedToGroups, np.AppliedToGroups) &&
+		reflect.DeepEqual(anp.TierPriority, np.TierPriority) && reflect.DeepEqual(anp.Priority, np.Priority) {
 		r.Log.V(1).Info("Ignore update unchanged NetworkPolicy", "Name", anp.Name, "Namespace", anp.Namespace)
 		return nil
 	}
@@ -515,7 +538,7 @@ func (r *NetworkPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.networkPolicyIndexer = cache.NewIndexer(
 		func(obj interface{}) (string, error) {
 			np := obj.(*networkPolicy)
-			return types.NamespacedName{Name: np.Name, Namespace: np.Namespace}.String(), nil
+			return getNetworkPolicyKey(&np.NetworkPolicy), nil
 		},
 		cache.Indexers{
 			// networkPolicy indexed by Antrea AddrGroup ID.
@@ -554,26 +577,13 @@ func (r *NetworkPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				return sgs, nil
 			},
 		})
-	r.virtualMachinePolicyIndexer = cache.NewIndexer(
-		// Each VirtualMachinePolicy is uniquely identified by namespaced name of corresponding crd object.
-		func(obj interface{}) (string, error) {
-			npStatus := obj.(*NetworkPolicyStatus)
-			return npStatus.String(), nil
-		},
-		// VirtualMachinePolicy indexed by namespace
-		cache.Indexers{
-			NetworkPolicyStatusIndexerByNamespace: func(obj interface{}) ([]string, error) {
-				npStatus := obj.(*NetworkPolicyStatus)
-				ret := []string{npStatus.Namespace}
-				return ret, nil
-			},
-		})
+	r.virtualMachinePolicyIndexer = NewNetworkPolicyStatusIndexer()
 	r.localRequest = make(chan watch.Event)
 	r.cloudResponse = make(chan *securityGroupStatus)
-	r.pendingDeleteGroups = NewPendingItemQueue(r, nil)
+	r.pendingDeleteGroups = NewPendingItemQueue(pendingDeleteQueueName, r, nil)
 	r.fedExternalEntityIPs = make(map[string][]string)
 	opCnt := operationCount
-	r.retryQueue = NewPendingItemQueue(r, &opCnt)
+	r.retryQueue = NewPendingItemQueue(retryQueueName, r, &opCnt)
 
 	if mgr == nil {
 		return nil
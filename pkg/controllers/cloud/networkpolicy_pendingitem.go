@@ -21,6 +21,8 @@ import (
 	"github.com/mohae/deepcopy"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
+
+	"antrea.io/nephe/pkg/metrics"
 )
 
 type PendingItem interface {
@@ -40,22 +42,29 @@ type countingPendingItem struct {
 }
 
 type PendingItemQueue struct {
+	name    string
 	items   map[string]countingPendingItem
 	context interface{}
 	opCnt   *int
 }
 
-// Returns an new PendingItemQueue.
+// Returns an new PendingItemQueue, name identifies queue in metrics.
 // If opCnt is not provided, item is removed if item.RunOrDeletePendingItem returns true;
 // if opCnt is provided, item is also removed when item.RunPendingItem is called opCnt.
-func NewPendingItemQueue(context interface{}, opCnt *int) *PendingItemQueue {
+func NewPendingItemQueue(name string, context interface{}, opCnt *int) *PendingItemQueue {
 	return &PendingItemQueue{
+		name:    name,
 		items:   make(map[string]countingPendingItem),
 		context: context,
 		opCnt:   opCnt,
 	}
 }
 
+// updateDepthMetric reports number of items in queue.
+func (q *PendingItemQueue) updateDepthMetric() {
+	metrics.PendingItemQueueDepth.WithLabelValues(q.name).Set(float64(len(q.items)))
+}
+
 // Adds an pending item to queue.
 func (q *PendingItemQueue) Add(id string, p PendingItem) {
 	log := q.context.(*NetworkPolicyReconciler).Log.WithName("PendingItemQueue")
@@ -64,11 +73,13 @@ func (q *PendingItemQueue) Add(id string, p PendingItem) {
 		return
 	}
 	q.items[id] = countingPendingItem{p, deepcopy.Copy(q.opCnt).(*int)}
+	q.updateDepthMetric()
 }
 
 // Removes an pending item from queue.
 func (q *PendingItemQueue) Remove(id string) {
 	delete(q.items, id)
+	q.updateDepthMetric()
 }
 
 // Has returns true if an item in the queue.
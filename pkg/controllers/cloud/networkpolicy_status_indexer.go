@@ -0,0 +1,204 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// networkPolicyStatusHistorySize is number of recent changes kept to resume watches from a resource version.
+	networkPolicyStatusHistorySize = 1000
+	// networkPolicyStatusWatchChanSize is number of changes buffered for a watcher, slow watchers are terminated.
+	networkPolicyStatusWatchChanSize = 100
+)
+
+// NetworkPolicyStatusEvent is a change of NetworkPolicyStatus of a cloud resource.
+type NetworkPolicyStatusEvent struct {
+	Type watch.EventType
+	// Status is a snapshot of NetworkPolicyStatus after the change, or before the change if deleted.
+	Status *NetworkPolicyStatus
+	// PrevStatus is a snapshot of NetworkPolicyStatus before the change if modified.
+	PrevStatus *NetworkPolicyStatus
+}
+
+// NetworkPolicyStatusWatcher watches changes of NetworkPolicyStatus.
+type NetworkPolicyStatusWatcher interface {
+	// GetResourceVersion returns resource version of the most recent change.
+	GetResourceVersion() uint64
+	// Watch returns a channel of changes after resourceVersion, and a function to stop the watch. If
+	// resourceVersion is 0, changes are preceded by Added events of all existing NetworkPolicyStatus.
+	// The channel is closed when watch is stopped or watcher falls behind.
+	Watch(resourceVersion uint64) (<-chan NetworkPolicyStatusEvent, func(), error)
+}
+
+// networkPolicyStatusIndexer is a cache.Indexer of NetworkPolicyStatus. It stamps resource version on
+// NetworkPolicyStatus and notifies watchers when NetworkPolicyStatus is added, updated or deleted.
+type networkPolicyStatusIndexer struct {
+	cache.Indexer
+	mutex           sync.Mutex
+	resourceVersion uint64
+	// snapshots are the most recent notified NetworkPolicyStatus keyed by namespaced name.
+	snapshots map[string]*NetworkPolicyStatus
+	history   []NetworkPolicyStatusEvent
+	watchers  map[chan NetworkPolicyStatusEvent]struct{}
+}
+
+var _ NetworkPolicyStatusWatcher = &networkPolicyStatusIndexer{}
+
+// NewNetworkPolicyStatusIndexer returns a watchable cache.Indexer of NetworkPolicyStatus indexed by namespace.
+func NewNetworkPolicyStatusIndexer() cache.Indexer {
+	return &networkPolicyStatusIndexer{
+		Indexer: cache.NewIndexer(
+			// Each VirtualMachinePolicy is uniquely identified by namespaced name of corresponding crd object.
+			func(obj interface{}) (string, error) {
+				npStatus := obj.(*NetworkPolicyStatus)
+				return npStatus.String(), nil
+			},
+			// VirtualMachinePolicy indexed by namespace
+			cache.Indexers{
+				NetworkPolicyStatusIndexerByNamespace: func(obj interface{}) ([]string, error) {
+					npStatus := obj.(*NetworkPolicyStatus)
+					ret := []string{npStatus.Namespace}
+					return ret, nil
+				},
+			}),
+		snapshots: make(map[string]*NetworkPolicyStatus),
+		watchers:  make(map[chan NetworkPolicyStatusEvent]struct{}),
+	}
+}
+
+func (i *networkPolicyStatusIndexer) Add(obj interface{}) error {
+	return i.Update(obj)
+}
+
+func (i *networkPolicyStatusIndexer) Update(obj interface{}) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	npStatus := obj.(*NetworkPolicyStatus)
+	prevRV := npStatus.ResourceVersion
+	npStatus.ResourceVersion = i.resourceVersion + 1
+	if err := i.Indexer.Update(obj); err != nil {
+		npStatus.ResourceVersion = prevRV
+		return err
+	}
+	i.resourceVersion++
+	eventType := watch.Modified
+	if _, ok := i.snapshots[npStatus.String()]; !ok {
+		eventType = watch.Added
+	}
+	i.notify(eventType, npStatus.deepCopy())
+	return nil
+}
+
+func (i *networkPolicyStatusIndexer) Delete(obj interface{}) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if err := i.Indexer.Delete(obj); err != nil {
+		return err
+	}
+	npStatus := obj.(*NetworkPolicyStatus).deepCopy()
+	if _, ok := i.snapshots[npStatus.String()]; !ok {
+		return nil
+	}
+	i.resourceVersion++
+	npStatus.ResourceVersion = i.resourceVersion
+	i.notify(watch.Deleted, npStatus)
+	return nil
+}
+
+// notify records a change and sends it to watchers. Watchers not able to keep up are terminated.
+// Caller must hold mutex.
+func (i *networkPolicyStatusIndexer) notify(eventType watch.EventType, npStatus *NetworkPolicyStatus) {
+	key := npStatus.String()
+	event := NetworkPolicyStatusEvent{Type: eventType, Status: npStatus}
+	if eventType == watch.Modified {
+		event.PrevStatus = i.snapshots[key]
+	}
+	if eventType == watch.Deleted {
+		delete(i.snapshots, key)
+	} else {
+		i.snapshots[key] = npStatus
+	}
+
+	i.history = append(i.history, event)
+	if len(i.history) > networkPolicyStatusHistorySize {
+		i.history = i.history[len(i.history)-networkPolicyStatusHistorySize:]
+	}
+	for ch := range i.watchers {
+		select {
+		case ch <- event:
+		default:
+			delete(i.watchers, ch)
+			close(ch)
+		}
+	}
+}
+
+// GetResourceVersion returns resource version of the most recent change.
+func (i *networkPolicyStatusIndexer) GetResourceVersion() uint64 {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	return i.resourceVersion
+}
+
+// Watch returns a channel of changes after resourceVersion, and a function to stop the watch.
+func (i *networkPolicyStatusIndexer) Watch(resourceVersion uint64) (<-chan NetworkPolicyStatusEvent, func(), error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	var events []NetworkPolicyStatusEvent
+	if resourceVersion == 0 {
+		for _, npStatus := range i.snapshots {
+			events = append(events, NetworkPolicyStatusEvent{Type: watch.Added, Status: npStatus})
+		}
+	} else if resourceVersion > i.resourceVersion {
+		return nil, nil, errors.NewBadRequest(fmt.Sprintf("resourceVersion %v is newer than current %v",
+			resourceVersion, i.resourceVersion))
+	} else if resourceVersion < i.resourceVersion {
+		if len(i.history) == 0 || i.history[0].Status.ResourceVersion > resourceVersion+1 {
+			return nil, nil, errors.NewResourceExpired(fmt.Sprintf("resourceVersion %v is too old", resourceVersion))
+		}
+		for _, event := range i.history {
+			if event.Status.ResourceVersion > resourceVersion {
+				events = append(events, event)
+			}
+		}
+	}
+
+	ch := make(chan NetworkPolicyStatusEvent, len(events)+networkPolicyStatusWatchChanSize)
+	for _, event := range events {
+		ch <- event
+	}
+	i.watchers[ch] = struct{}{}
+	stop := func() {
+		i.mutex.Lock()
+		defer i.mutex.Unlock()
+
+		if _, ok := i.watchers[ch]; ok {
+			delete(i.watchers, ch)
+			close(ch)
+		}
+	}
+	return ch, stop, nil
+}
@@ -17,6 +17,7 @@ package cloud
 import (
 	"antrea.io/nephe/apis/crd/v1alpha1"
 	"antrea.io/nephe/pkg/cloud-provider/securitygroup"
+	"antrea.io/nephe/pkg/metrics"
 	"context"
 	"fmt"
 	"k8s.io/apimachinery/pkg/watch"
@@ -43,16 +44,24 @@ func (s *securityGroupImpl) syncImpl(csg cloudSecurityGroup, c *securitygroup.Sy
 			log.V(1).Info("Same SecurityGroup found", "Name", s.id, "State", s.state)
 			return true
 		}
-	} else if len(s.members) == 0 {
-		log.V(1).Info("Emmpty memberships", "Name", s.id)
-		return true
+	} else {
+		// SecurityGroup is not found in cloud, e.g. removed out of band or planned in dry-run mode, re-create it.
+		if s.state == securityGroupStateCreated {
+			s.state = securityGroupStateInit
+		}
+		if len(s.members) == 0 {
+			log.V(1).Info("Emmpty memberships", "Name", s.id)
+			return true
+		}
 	}
 
 	if s.state == securityGroupStateCreated {
 		log.V(1).Info("Update securityGroup", "Name", s.id, "MembershipOnly", membershipOnly, "CloudSecurityGroup", c)
+		metrics.CloudSyncCorrections.WithLabelValues(metrics.CloudSyncCorrectionMembers).Inc()
 		_ = s.updateImpl(csg, nil, nil, membershipOnly, r)
 	} else if s.state == securityGroupStateInit {
 		log.V(1).Info("Add securityGroup", "Name", s.id, "MembershipOnly", membershipOnly, "CloudSecurityGroup", c)
+		metrics.CloudSyncCorrections.WithLabelValues(metrics.CloudSyncCorrectionMembers).Inc()
 		_ = s.addImpl(csg, membershipOnly, r)
 	}
 	return false
@@ -69,6 +78,49 @@ func (a *addrSecurityGroup) sync(c *securitygroup.SynchronizationContent,
 	_ = a.syncImpl(a, c, true, r)
 }
 
+const (
+	denyRuleInNetworkPolicy = 1 << iota
+	denyRuleInCloud
+)
+
+// denyRuleSyncKey returns key used to compare deny rules with cloud. Cloud plug-in may realize a deny rule
+// as multiple cloud rules and with addrSecurityGroups resolved to IPs, hence deny rules are compared by presence
+// of protocol and port only.
+func denyRuleSyncKey(proto int, port string) string {
+	return fmt.Sprintf("action=%v,protocol=%v,port=%v", securitygroup.RuleActionDeny, proto, port)
+}
+
+// cloudServiceSyncKey returns key used to compare cloud service peers of rules with cloud.
+func cloudServiceSyncKey(cloudService string) string {
+	return "cloud-service=" + cloudService
+}
+
+// rulePortSyncString returns port or port range of a rule used to compare rules with cloud.
+func rulePortSyncString(port, endPort *int) string {
+	if port == nil {
+		return "0"
+	}
+	if endPort == nil || *endPort == *port {
+		return fmt.Sprintf("%v", *port)
+	}
+	return fmt.Sprintf("%v-%v", *port, *endPort)
+}
+
+// ruleICMPSyncString returns ICMP type and code of a rule used to compare rules with cloud, empty if the rule is not
+// restricted to an ICMP type or code.
+func ruleICMPSyncString(icmpType, icmpCode *int) string {
+	if icmpType == nil && icmpCode == nil {
+		return ""
+	}
+	typeCode := []string{"*", "*"}
+	for i, v := range []*int{icmpType, icmpCode} {
+		if v != nil {
+			typeCode[i] = fmt.Sprintf("%v", *v)
+		}
+	}
+	return fmt.Sprintf(",icmp=%v/%v", typeCode[0], typeCode[1])
+}
+
 // sync synchronizes appliedToSecurityGroup with cloud.
 func (a *appliedToSecurityGroup) sync(c *securitygroup.SynchronizationContent,
 	r *NetworkPolicyReconciler) {
@@ -85,7 +137,9 @@ func (a *appliedToSecurityGroup) sync(c *securitygroup.SynchronizationContent,
 		log.Error(err, "Get networkPolicy by indexer", "Index", networkPolicyIndexerByAppliedToGrp, "Key", a.id.Name)
 		return
 	}
-	items := make(map[string]int)
+	// compare rules as realized by updateRules.
+	irules := make([]*securitygroup.IngressRule, 0)
+	erules := make([]*securitygroup.EgressRule, 0)
 	for _, i := range nps {
 		np := i.(*networkPolicy)
 
@@ -93,62 +147,92 @@ func (a *appliedToSecurityGroup) sync(c *securitygroup.SynchronizationContent,
 			log.V(1).Info("Skip sync, networkPolicy not ready", "Name", np.Name, "Namespace", np.Namespace)
 			return
 		}
-		for _, iRule := range np.ingressRules {
-			proto := 0
-			if iRule.Protocol != nil {
-				proto = *iRule.Protocol
-			}
-			port := 0
-			if iRule.FromPort != nil {
-				port = *iRule.FromPort
-			}
-			if proto > 0 || port > 0 {
-				portStr := fmt.Sprintf("protocol=%v,port=%v", proto, port)
-				items[portStr]++
-			}
-			for _, ip := range iRule.FromSrcIP {
-				items[ip.String()]++
-			}
-			for _, sg := range iRule.FromSecurityGroups {
-				items[sg.String()]++
-			}
+		irules = append(irules, np.ingressRules...)
+		erules = append(erules, np.egressRules...)
+	}
+	ing, eg := a.nonIsolatedRules(nps, r)
+	irules = append(irules, ing...)
+	erules = append(erules, eg...)
+	// priorities of rules are not compared, they are not reported by all clouds.
+	irules, erules = compactRulesIgnoringPriority(irules, erules)
+	items := make(map[string]int)
+	denyItems := make(map[string]int)
+	for _, iRule := range irules {
+		proto := 0
+		if iRule.Protocol != nil {
+			proto = *iRule.Protocol
 		}
-		for _, eRule := range np.egressRules {
-			proto := 0
-			if eRule.Protocol != nil {
-				proto = *eRule.Protocol
-			}
-			port := 0
-			if eRule.ToPort != nil {
-				port = *eRule.ToPort
-			}
-			if proto > 0 || port > 0 {
-				portStr := fmt.Sprintf("protocol=%v,port=%v", proto, port)
-				items[portStr]++
-			}
-			for _, ip := range eRule.ToDstIP {
-				items[ip.String()]++
-			}
-			for _, sg := range eRule.ToSecurityGroups {
-				items[sg.String()]++
-			}
+		port := rulePortSyncString(iRule.FromPort, iRule.FromEndPort) + ruleICMPSyncString(iRule.ICMPType, iRule.ICMPCode)
+		if iRule.Action.IsDeny() {
+			denyItems[denyRuleSyncKey(proto, port)] |= denyRuleInNetworkPolicy
+			continue
+		}
+		if proto > 0 || port != "0" {
+			portStr := fmt.Sprintf("protocol=%v,port=%v", proto, port)
+			items[portStr]++
+		}
+		for _, ip := range iRule.FromSrcIP {
+			items[ip.String()]++
+		}
+		for _, sg := range iRule.FromSecurityGroups {
+			items[sg.String()]++
+		}
+		for _, service := range iRule.FromCloudServices {
+			items[cloudServiceSyncKey(service)]++
+		}
+	}
+	for _, eRule := range erules {
+		proto := 0
+		if eRule.Protocol != nil {
+			proto = *eRule.Protocol
+		}
+		port := rulePortSyncString(eRule.ToPort, eRule.ToEndPort) + ruleICMPSyncString(eRule.ICMPType, eRule.ICMPCode)
+		if eRule.Action.IsDeny() {
+			denyItems[denyRuleSyncKey(proto, port)] |= denyRuleInNetworkPolicy
+			continue
+		}
+		if proto > 0 || port != "0" {
+			portStr := fmt.Sprintf("protocol=%v,port=%v", proto, port)
+			items[portStr]++
+		}
+		for _, ip := range eRule.ToDstIP {
+			items[ip.String()]++
+		}
+		for _, sg := range eRule.ToSecurityGroups {
+			items[sg.String()]++
+		}
+		for _, service := range eRule.ToCloudServices {
+			items[cloudServiceSyncKey(service)]++
 		}
 	}
 	if c == nil {
+		if len(nps) > 0 {
+			metrics.CloudSyncCorrections.WithLabelValues(metrics.CloudSyncCorrectionRules).Inc()
+		}
 		_ = a.updateRules(r)
 		return
 	}
-	// Rough compare rules
-	for _, iRule := range c.IngressRules {
+	// Rough compare rules, as compacted by controller, since cloud plug-ins may realize a rule as multiple cloud rules.
+	cloudIngressRules := make([]*securitygroup.IngressRule, 0, len(c.IngressRules))
+	for i := range c.IngressRules {
+		cloudIngressRules = append(cloudIngressRules, &c.IngressRules[i])
+	}
+	cloudEgressRules := make([]*securitygroup.EgressRule, 0, len(c.EgressRules))
+	for i := range c.EgressRules {
+		cloudEgressRules = append(cloudEgressRules, &c.EgressRules[i])
+	}
+	cloudIngressRules, cloudEgressRules = compactRulesIgnoringPriority(cloudIngressRules, cloudEgressRules)
+	for _, iRule := range cloudIngressRules {
 		proto := 0
 		if iRule.Protocol != nil {
 			proto = *iRule.Protocol
 		}
-		port := 0
-		if iRule.FromPort != nil {
-			port = *iRule.FromPort
+		port := rulePortSyncString(iRule.FromPort, iRule.FromEndPort) + ruleICMPSyncString(iRule.ICMPType, iRule.ICMPCode)
+		if iRule.Action.IsDeny() {
+			denyItems[denyRuleSyncKey(proto, port)] |= denyRuleInCloud
+			continue
 		}
-		if proto > 0 || port > 0 {
+		if proto > 0 || port != "0" {
 			portStr := fmt.Sprintf("protocol=%v,port=%v", proto, port)
 			items[portStr]--
 		}
@@ -158,17 +242,21 @@ func (a *appliedToSecurityGroup) sync(c *securitygroup.SynchronizationContent,
 		for _, sg := range iRule.FromSecurityGroups {
 			items[sg.String()]--
 		}
+		for _, service := range iRule.FromCloudServices {
+			items[cloudServiceSyncKey(service)]--
+		}
 	}
-	for _, eRule := range c.EgressRules {
+	for _, eRule := range cloudEgressRules {
 		proto := 0
 		if eRule.Protocol != nil {
 			proto = *eRule.Protocol
 		}
-		port := 0
-		if eRule.ToPort != nil {
-			port = *eRule.ToPort
+		port := rulePortSyncString(eRule.ToPort, eRule.ToEndPort) + ruleICMPSyncString(eRule.ICMPType, eRule.ICMPCode)
+		if eRule.Action.IsDeny() {
+			denyItems[denyRuleSyncKey(proto, port)] |= denyRuleInCloud
+			continue
 		}
-		if proto > 0 || port > 0 {
+		if proto > 0 || port != "0" {
 			portStr := fmt.Sprintf("protocol=%v,port=%v", proto, port)
 			items[portStr]--
 		}
@@ -178,10 +266,22 @@ func (a *appliedToSecurityGroup) sync(c *securitygroup.SynchronizationContent,
 		for _, sg := range eRule.ToSecurityGroups {
 			items[sg.String()]--
 		}
+		for _, service := range eRule.ToCloudServices {
+			items[cloudServiceSyncKey(service)]--
+		}
 	}
 	for k, i := range items {
 		if i != 0 {
 			log.V(1).Info("Update appliedToSecurityGroup rules", "Name", a.id.String(), "CloudSecurityGroup", c, "Item", k, "Diff", i)
+			metrics.CloudSyncCorrections.WithLabelValues(metrics.CloudSyncCorrectionRules).Inc()
+			_ = a.updateRules(r)
+			return
+		}
+	}
+	for k, i := range denyItems {
+		if i != denyRuleInNetworkPolicy|denyRuleInCloud {
+			log.V(1).Info("Update appliedToSecurityGroup deny rules", "Name", a.id.String(), "CloudSecurityGroup", c, "Item", k)
+			metrics.CloudSyncCorrections.WithLabelValues(metrics.CloudSyncCorrectionRules).Inc()
 			_ = a.updateRules(r)
 			return
 		}
@@ -204,7 +304,7 @@ func (r *NetworkPolicyReconciler) syncWithCloud() {
 	if r.bookmarkCnt < npSyncReadyBookMarkCnt {
 		return
 	}
-	ch := securitygroup.CloudSecurityGroup.GetSecurityGroupSyncChan()
+	ch, errCh := securitygroup.CloudSecurityGroup.GetSecurityGroupSyncChan()
 	cloudAddrSGs := make(map[securitygroup.CloudResourceID]*securitygroup.SynchronizationContent)
 	cloudAppliedToSGs := make(map[securitygroup.CloudResourceID]*securitygroup.SynchronizationContent)
 	rscWithUnknownSGs := make(map[securitygroup.CloudResource]struct{})
@@ -219,6 +319,7 @@ func (r *NetworkPolicyReconciler) syncWithCloud() {
 		// Removes unknown sg.
 		if _, ok, _ := indexer.GetByKey(content.Resource.String()); !ok {
 			state := securityGroupStateCreated
+			metrics.CloudSyncCorrections.WithLabelValues(metrics.CloudSyncCorrectionStaleSecurityGroup).Inc()
 			_ = sgNew(&content.Resource, []*securitygroup.CloudResource{}, &state).delete(r)
 			continue
 		}
@@ -234,17 +335,31 @@ func (r *NetworkPolicyReconciler) syncWithCloud() {
 			}
 		}
 	}
+	// Cloud view is incomplete if it cannot be retrieved from some accounts, security groups absent from the view
+	// may still exist in cloud.
+	cloudViewErr := <-errCh
+	if cloudViewErr != nil {
+		log.Error(cloudViewErr, "Cloud view is incomplete, skip synchronizing security groups not found in cloud")
+	}
 	r.syncedWithCloud = true
 	for _, i := range r.addrSGIndexer.List() {
 		sg := i.(*addrSecurityGroup)
 		if sg.isIPBlocks() {
 			continue
 		}
-		sg.sync(cloudAddrSGs[sg.getID()], r)
+		c := cloudAddrSGs[sg.getID()]
+		if c == nil && cloudViewErr != nil {
+			continue
+		}
+		sg.sync(c, r)
 	}
 	for _, i := range r.appliedToSGIndexer.List() {
 		sg := i.(*appliedToSecurityGroup)
-		sg.sync(cloudAppliedToSGs[sg.getID()], r)
+		c := cloudAppliedToSGs[sg.getID()]
+		if c == nil && cloudViewErr != nil {
+			continue
+		}
+		sg.sync(c, r)
 	}
 	// For cloud resource with any non-antrea+ SG, tricking plug-in to remove them by explicitly
 	// updating a single instance of associated security group.
@@ -256,6 +371,7 @@ func (r *NetworkPolicyReconciler) syncWithCloud() {
 		}
 		tracker := i.(*cloudResourceNPTracker)
 		for _, sg := range tracker.appliedToSGs {
+			metrics.CloudSyncCorrections.WithLabelValues(metrics.CloudSyncCorrectionUnmanagedAttachments).Inc()
 			_ = sg.update(nil, nil, r)
 			break
 		}
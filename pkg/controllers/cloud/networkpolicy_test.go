@@ -36,6 +36,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	antreanetworking "antrea.io/antrea/pkg/apis/controlplane/v1beta2"
+	antreacrd "antrea.io/antrea/pkg/apis/crd/v1alpha1"
 	antreatypes "antrea.io/antrea/pkg/apis/crd/v1alpha2"
 	cloud "antrea.io/nephe/apis/crd/v1alpha1"
 	cloudcommon "antrea.io/nephe/pkg/cloud-provider/cloudapi/common"
@@ -228,12 +229,14 @@ var _ = Describe("NetworkPolicy", func() {
 			Protocol:           &tcp,
 			FromSecurityGroups: []*securitygroup.CloudResourceID{addrGrpIDs[addrGrps[0].Name]},
 			FromSrcIP:          []*net.IPNet{ingressIPBlock},
+			Action:             securitygroup.RuleActionAllow,
 		}
 		egressRule = &securitygroup.EgressRule{
 			ToPort:           &portInt,
 			Protocol:         &tcp,
 			ToSecurityGroups: []*securitygroup.CloudResourceID{addrGrpIDs[addrGrps[1].Name]},
 			ToDstIP:          []*net.IPNet{egressIPBlock},
+			Action:           securitygroup.RuleActionAllow,
 		}
 
 		for i := appliedToVMIdx; i < patchVMIdx; i++ {
@@ -918,6 +921,53 @@ var _ = Describe("NetworkPolicy", func() {
 		verifyNPStatus(trackedVMs, false, false)
 	})
 
+	It("Tracking cluster networkPolicy", func() {
+		anp.Name = "acnp-test"
+		anp.Namespace = ""
+		anp.SourceRef = &antreanetworking.NetworkPolicyReference{
+			Type: antreanetworking.AntreaClusterNetworkPolicy,
+			Name: anp.Name,
+		}
+		trackedVMs := make(map[string]*cloud.VirtualMachine)
+		createAndVerifyNP(false)
+		_, found, _ := reconciler.networkPolicyIndexer.GetByKey(types.NamespacedName{Name: anp.Name}.String())
+		Expect(found).To(BeTrue())
+		Expect(reconciler.isClusterNetworkPolicy(types.NamespacedName{Namespace: "any", Name: anp.Name})).To(BeTrue())
+		// cluster networkPolicy is shown in status of VMs in any namespace.
+		verifyNPTracker(trackedVMs, true, false)
+		verifyNPStatus(trackedVMs, true, false)
+	})
+
+	It("Kubernetes NetworkPolicy with the same name as ANP", func() {
+		np := &networkPolicy{}
+		anp.DeepCopyInto(&np.NetworkPolicy)
+		k8sNP := &networkPolicy{}
+		anp.DeepCopyInto(&k8sNP.NetworkPolicy)
+		k8sNP.SourceRef = &antreanetworking.NetworkPolicyReference{
+			Type:      antreanetworking.K8sNetworkPolicy,
+			Namespace: anp.Namespace,
+			Name:      anp.Name,
+		}
+		Expect(getNetworkPolicyStatusName(&np.NetworkPolicy)).To(Equal(anp.Name))
+		Expect(getNetworkPolicyStatusName(&k8sNP.NetworkPolicy)).To(Equal("K8sNetworkPolicy:" + anp.Name))
+
+		// both networkPolicies are indexed.
+		Expect(reconciler.networkPolicyIndexer.Add(np)).To(Succeed())
+		Expect(reconciler.networkPolicyIndexer.Add(k8sNP)).To(Succeed())
+		Expect(reconciler.networkPolicyIndexer.List()).To(HaveLen(2))
+		nps, err := reconciler.networkPolicyIndexer.ByIndex(networkPolicyIndexerByAppliedToGrp, appliedToGrpsNames[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nps).To(HaveLen(2))
+
+		// deleting one networkPolicy keeps the other.
+		Expect(reconciler.networkPolicyIndexer.Delete(k8sNP)).To(Succeed())
+		i, found, _ := reconciler.networkPolicyIndexer.GetByKey(getNetworkPolicyKey(anp))
+		Expect(found).To(BeTrue())
+		Expect(i.(*networkPolicy).isK8sNetworkPolicy()).To(BeFalse())
+		_, found, _ = reconciler.networkPolicyIndexer.GetByKey(getNetworkPolicyKey(&k8sNP.NetworkPolicy))
+		Expect(found).To(BeFalse())
+	})
+
 	It("Create NetworkPolicy groups after security group garbage collection", func() {
 		createAndVerifyNP(false)
 		sgConfig.sgDeletePending = true
@@ -938,6 +988,239 @@ var _ = Describe("NetworkPolicy", func() {
 		Expect(len(reconciler.pendingDeleteGroups.items)).To(BeZero())
 	})
 
+	It("Supported NetworkPolicy rule actions", func() {
+		np := anp.DeepCopy()
+		for _, action := range []antreacrd.RuleAction{antreacrd.RuleActionAllow, antreacrd.RuleActionDrop,
+			antreacrd.RuleActionReject} {
+			ruleAction := action
+			np.Rules[0].Action = &ruleAction
+			Expect(reconciler.isNetworkPolicySupported(np)).ToNot(HaveOccurred())
+		}
+		ruleAction := antreacrd.RuleActionPass
+		np.Rules[0].Action = &ruleAction
+		Expect(reconciler.isNetworkPolicySupported(np)).To(HaveOccurred())
+	})
+
+	It("Supported NetworkPolicy ICMP type and code", func() {
+		np := anp.DeepCopy()
+		icmp := antreanetworking.ProtocolICMP
+		icmpType, icmpCode := int32(3), int32(4)
+		np.Rules[0].Services = []antreanetworking.Service{{Protocol: &icmp, ICMPType: &icmpType, ICMPCode: &icmpCode}}
+		Expect(reconciler.isNetworkPolicySupported(np)).ToNot(HaveOccurred())
+		np.Rules[0].Services = []antreanetworking.Service{{Protocol: &icmp, ICMPType: &icmpType}}
+		Expect(reconciler.isNetworkPolicySupported(np)).ToNot(HaveOccurred())
+		// an ICMP code without an ICMP type is rejected.
+		np.Rules[0].Services = []antreanetworking.Service{{Protocol: &icmp, ICMPCode: &icmpCode}}
+		Expect(reconciler.isNetworkPolicySupported(np)).To(HaveOccurred())
+	})
+
+	It("Kubernetes NetworkPolicy isolation", func() {
+		np := &networkPolicy{}
+		anp.DeepCopyInto(&np.NetworkPolicy)
+		np.SourceRef = &antreanetworking.NetworkPolicyReference{
+			Type:      antreanetworking.K8sNetworkPolicy,
+			Namespace: namespace,
+			Name:      "default-deny-ingress",
+		}
+		Expect(reconciler.isNetworkPolicySupported(&np.NetworkPolicy)).ToNot(HaveOccurred())
+
+		// ingress and egress rules isolate both directions.
+		isolateIngress, isolateEgress := np.isolatedDirections()
+		Expect(isolateIngress).To(BeTrue())
+		Expect(isolateEgress).To(BeTrue())
+
+		// default deny ingress.
+		np.Rules = []antreanetworking.NetworkPolicyRule{{Direction: antreanetworking.DirectionIn}}
+		ing, eg, ready := (&networkPolicyRule{rule: &np.Rules[0]}).rules(reconciler)
+		Expect(ready).To(BeTrue())
+		Expect(ing).To(BeEmpty())
+		Expect(eg).To(BeEmpty())
+		isolateIngress, isolateEgress = np.isolatedDirections()
+		Expect(isolateIngress).To(BeTrue())
+		Expect(isolateEgress).To(BeFalse())
+
+		// Antrea networkPolicy does not isolate directions.
+		np.SourceRef.Type = antreanetworking.AntreaNetworkPolicy
+		isolateIngress, isolateEgress = np.isolatedDirections()
+		Expect(isolateIngress).To(BeFalse())
+		Expect(isolateEgress).To(BeFalse())
+	})
+
+	It("Kubernetes NetworkPolicies isolation of the same VM", func() {
+		vm := vmMembers[vmNames[0]]
+		for name, direction := range map[string]antreanetworking.Direction{
+			"k8s-grp-ingress": antreanetworking.DirectionIn,
+			"k8s-grp-egress":  antreanetworking.DirectionOut,
+		} {
+			np := &networkPolicy{}
+			np.Name = "default-deny-" + string(direction)
+			np.Namespace = namespace
+			np.SourceRef = &antreanetworking.NetworkPolicyReference{
+				Type:      antreanetworking.K8sNetworkPolicy,
+				Namespace: namespace,
+				Name:      np.Name,
+			}
+			np.AppliedToGroups = []string{name}
+			np.Rules = []antreanetworking.NetworkPolicyRule{{Direction: direction}}
+			Expect(reconciler.networkPolicyIndexer.Add(np)).To(Succeed())
+		}
+		ingressSg := newAppliedToSecurityGroup(&securitygroup.CloudResourceID{Name: "k8s-grp-ingress", Vpc: vpc},
+			[]*securitygroup.CloudResource{vm}, nil).(*appliedToSecurityGroup)
+		egressSg := newAppliedToSecurityGroup(&securitygroup.CloudResourceID{Name: "k8s-grp-egress", Vpc: vpc},
+			[]*securitygroup.CloudResource{vm}, nil).(*appliedToSecurityGroup)
+		ingressNPs, err := reconciler.networkPolicyIndexer.ByIndex(networkPolicyIndexerByAppliedToGrp, "k8s-grp-ingress")
+		Expect(err).ToNot(HaveOccurred())
+		egressNPs, err := reconciler.networkPolicyIndexer.ByIndex(networkPolicyIndexerByAppliedToGrp, "k8s-grp-egress")
+		Expect(err).ToNot(HaveOccurred())
+
+		// egress is opened while the VM is selected by the ingress isolating policy only.
+		ingressList, egressList := ingressSg.nonIsolatedRules(ingressNPs, reconciler)
+		Expect(ingressList).To(BeEmpty())
+		Expect(egressList).To(Equal([]*securitygroup.EgressRule{
+			{ToDstIP: anyIPNets, Action: securitygroup.RuleActionAllow}}))
+
+		// neither direction is opened once the VM is also selected by the egress isolating policy.
+		tracker := reconciler.getCloudResourceNPTracker(vm, true)
+		Expect(tracker.update(ingressSg, false, reconciler)).To(Succeed())
+		Expect(tracker.update(egressSg, false, reconciler)).To(Succeed())
+		ingressList, egressList = ingressSg.nonIsolatedRules(ingressNPs, reconciler)
+		Expect(ingressList).To(BeEmpty())
+		Expect(egressList).To(BeEmpty())
+		ingressList, egressList = egressSg.nonIsolatedRules(egressNPs, reconciler)
+		Expect(ingressList).To(BeEmpty())
+		Expect(egressList).To(BeEmpty())
+	})
+
+	It("Deduplicate rules with different actions", func() {
+		tcp := 6
+		port := 22
+		_, ipNet1, _ := net.ParseCIDR("1.1.1.0/24")
+		_, ipNet2, _ := net.ParseCIDR("2.2.2.0/24")
+		inRules := []*securitygroup.IngressRule{
+			{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionAllow},
+			{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionDeny},
+			{FromPort: &port, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet2}, Action: securitygroup.RuleActionDeny},
+		}
+		eRules := []*securitygroup.EgressRule{
+			{ToPort: &port, Protocol: &tcp, ToDstIP: []*net.IPNet{ipNet1}, Action: securitygroup.RuleActionAllow},
+			{ToPort: &port, Protocol: &tcp, ToDstIP: []*net.IPNet{ipNet2}, Action: securitygroup.RuleActionDeny},
+		}
+		dedupInRules := deduplicateIngressRules(inRules)
+		Expect(len(dedupInRules)).To(Equal(2))
+		for _, rule := range dedupInRules {
+			if rule.Action.IsDeny() {
+				Expect(sortRuleIPs(rule.FromSrcIP)).To(Equal([]*net.IPNet{ipNet1, ipNet2}))
+			} else {
+				Expect(rule.FromSrcIP).To(Equal([]*net.IPNet{ipNet1}))
+			}
+		}
+		Expect(len(deduplicateEgressRules(eRules))).To(Equal(2))
+		Expect(hasDenyRules(nil, eRules)).To(BeTrue())
+		Expect(hasDenyRules(inRules[:1], eRules[:1])).To(BeFalse())
+	})
+
+	It("Service port ranges and named ports", func() {
+		endPort := int32(8080)
+		port, end, ok := getServicePortRange(antreanetworking.Service{Port: &intstr.IntOrString{IntVal: 8000}, EndPort: &endPort})
+		Expect(ok).To(BeTrue())
+		Expect(*port).To(Equal(8000))
+		Expect(*end).To(Equal(8080))
+		port, end, ok = getServicePortRange(antreanetworking.Service{Port: &intstr.IntOrString{IntVal: 8080}, EndPort: &endPort})
+		Expect(ok).To(BeTrue())
+		Expect(*port).To(Equal(8080))
+		Expect(end).To(BeNil())
+		_, _, ok = getServicePortRange(antreanetworking.Service{Port: &intstr.IntOrString{Type: intstr.String, StrVal: "http"}})
+		Expect(ok).To(BeFalse())
+		namedPortRules := []antreanetworking.NetworkPolicyRule{{Services: []antreanetworking.Service{
+			{Port: &intstr.IntOrString{IntVal: 8000}},
+			{Port: &intstr.IntOrString{Type: intstr.String, StrVal: "http"}},
+		}}}
+		Expect(getNamedPortError(namedPortRules)).To(MatchError(ContainSubstring("http")))
+		Expect(getNamedPortError(namedPortRules[:0])).ToNot(HaveOccurred())
+
+		tcp, startPort, endPort1, endPort2 := 6, 8000, 8080, 8090
+		_, ipNet1, _ := net.ParseCIDR("1.1.1.0/24")
+		inRules := []*securitygroup.IngressRule{
+			{FromPort: &startPort, FromEndPort: &endPort1, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}},
+			{FromPort: &startPort, FromEndPort: &endPort2, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}},
+			{FromPort: &startPort, FromEndPort: &endPort2, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}},
+		}
+		Expect(len(deduplicateIngressRules(inRules))).To(Equal(2))
+		Expect(rulePortSyncString(&startPort, &endPort1)).To(Equal("8000-8080"))
+		Expect(rulePortSyncString(&startPort, nil)).To(Equal("8000"))
+		Expect(rulePortSyncString(nil, nil)).To(Equal("0"))
+	})
+
+	It("Compact rules", func() {
+		tcp, port1, port2 := 6, 80, 81
+		_, ipNet1, _ := net.ParseCIDR("1.1.0.0/24")
+		_, ipNet2, _ := net.ParseCIDR("1.1.1.0/24")
+		_, ipNet3, _ := net.ParseCIDR("1.1.0.0/23")
+		inRules := []*securitygroup.IngressRule{
+			{FromPort: &port1, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet1}},
+			{FromPort: &port1, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet2}},
+			{FromPort: &port2, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet3}},
+		}
+		compactInRules, compactERules := compactRules(inRules, nil)
+		Expect(compactInRules).To(Equal([]*securitygroup.IngressRule{
+			{FromPort: &port1, FromEndPort: &port2, Protocol: &tcp, FromSrcIP: []*net.IPNet{ipNet3},
+				FromSecurityGroups: []*securitygroup.CloudResourceID{}},
+		}))
+		Expect(compactERules).To(BeEmpty())
+	})
+
+	It("Merge cloud service peers", func() {
+		Expect(mergeCloudServices([]string{"Storage.WestUS2", "Sql.WestUS2"}, []string{"AzureMonitor"},
+			[]string{"Sql.WestUS2"})).To(Equal([]string{"AzureMonitor", "Storage.WestUS2"}))
+
+		tcp, port := 6, 443
+		eRules := []*securitygroup.EgressRule{
+			{ToPort: &port, Protocol: &tcp, ToCloudServices: []string{"com.amazonaws.us-west-2.s3"}},
+			{ToPort: &port, Protocol: &tcp, ToCloudServices: []string{"com.amazonaws.us-west-2.s3", "pl-9"}},
+		}
+		_, compactERules := compactRules(nil, eRules)
+		Expect(compactERules).To(HaveLen(1))
+		Expect(compactERules[0].ToCloudServices).To(Equal([]string{"com.amazonaws.us-west-2.s3", "pl-9"}))
+	})
+
+	It("Track cloud service peers by ExternalEntity", func() {
+		sg := newAddrSecurityGroup(&securitygroup.CloudResourceID{Name: "ag"}, []*net.IPNet{}, nil).(*addrSecurityGroup)
+		Expect(sg.updateCloudServices(map[string]string{"ns/ee1": "Storage.WestUS2", "ns/ee2": "Storage.WestUS2",
+			"ns/ee3": "Sql.WestUS2"}, nil, reconciler)).To(BeTrue())
+		Expect(sg.getCloudServices()).To(Equal([]string{"Sql.WestUS2", "Storage.WestUS2"}))
+		// a cloud service remains while another ExternalEntity stands for it.
+		Expect(sg.updateCloudServices(nil, []string{"ns/ee1"}, reconciler)).To(BeFalse())
+		Expect(sg.getCloudServices()).To(Equal([]string{"Sql.WestUS2", "Storage.WestUS2"}))
+		// ExternalEntities deleted or relabeled are removed by namespaced name.
+		Expect(sg.updateCloudServices(nil, []string{"ns/ee2", "ns/ee3"}, reconciler)).To(BeTrue())
+		Expect(sg.getCloudServices()).To(BeEmpty())
+	})
+
+	It("IPv4 and IPv6 ip blocks", func() {
+		_, ipv4Net, _ := net.ParseCIDR("10.0.0.0/16")
+		_, ipv4SubNet, _ := net.ParseCIDR("10.0.1.0/24")
+		_, ipv6Net, _ := net.ParseCIDR("2600:1f14::/56")
+		_, ipv6SubNet, _ := net.ParseCIDR("2600:1f14::/64")
+		_, ipv6AnyNet, _ := net.ParseCIDR("::/0")
+
+		ipBlock := antreanetworking.IPBlock{}
+		ipBlock.CIDR.IP = antreanetworking.IPAddress(ipv6Net.IP)
+		ipBlock.CIDR.PrefixLength = 56
+		Expect(getIPBlockIPNet(ipBlock).String()).To(Equal(ipv6Net.String()))
+		ipBlock.CIDR.IP = antreanetworking.IPAddress(net.ParseIP("10.0.0.0"))
+		ipBlock.CIDR.PrefixLength = 16
+		Expect(getIPBlockIPNet(ipBlock).String()).To(Equal(ipv4Net.String()))
+
+		Expect(getHostIPNet("10.0.1.5").String()).To(Equal("10.0.1.5/32"))
+		Expect(getHostIPNet("2600:1f14::5").String()).To(Equal("2600:1f14::5/128"))
+		Expect(getHostIPNet("invalid")).To(BeNil())
+
+		Expect(overlap(ipv6Net, ipv6SubNet)).To(Equal(ipv6SubNet))
+		Expect(overlap(ipv4Net, ipv6AnyNet)).To(BeNil())
+		Expect(sortRuleIPs(deduplicateIP([]*net.IPNet{ipv4Net, ipv4SubNet, ipv6Net, ipv6SubNet}))).
+			To(Equal(sortRuleIPs([]*net.IPNet{ipv4Net, ipv6Net})))
+	})
+
 	It("Create NetworkPolicy groups after security group garbage collection with error", func() {
 		createAndVerifyNP(false)
 		sgConfig.sgDeletePending = true
@@ -1158,6 +1441,7 @@ var _ = Describe("NetworkPolicy", func() {
 		cloudReturnExtraSG
 		cloudReturnDiffMemberSG
 		cloudReturnDiffRuleSG
+		cloudReturnIncompleteSG
 	)
 
 	var (
@@ -1192,6 +1476,12 @@ var _ = Describe("NetworkPolicy", func() {
 				appSgRuleTimes:    0,
 				sgCreateTimes:     0,
 			},
+			cloudReturnIncompleteSG: {
+				addrSgMemberTimes: 0,
+				appSgMemberTimes:  0,
+				appSgRuleTimes:    0,
+				sgCreateTimes:     0,
+			},
 		}
 	)
 
@@ -1216,14 +1506,19 @@ var _ = Describe("NetworkPolicy", func() {
 				syncContents = append(syncContents, extraSG)
 			}
 			ch := make(chan securitygroup.SynchronizationContent)
-			mockCloudSecurityAPI.EXPECT().GetSecurityGroupSyncChan().Return(ch)
+			errCh := make(chan error, 1)
+			mockCloudSecurityAPI.EXPECT().GetSecurityGroupSyncChan().Return(ch, errCh)
 			go func() {
-				if cloudRet != cloudReturnNoSG {
+				if cloudRet != cloudReturnNoSG && cloudRet != cloudReturnIncompleteSG {
 					for _, c := range syncContents {
 						ch <- c
 					}
 				}
 				close(ch)
+				if cloudRet == cloudReturnIncompleteSG {
+					errCh <- fmt.Errorf("cloud view error")
+				}
+				close(errCh)
 			}()
 			reconciler.syncedWithCloud = false
 			sgConfig = cloudSgConfig[cloudRet]
@@ -1244,5 +1539,6 @@ var _ = Describe("NetworkPolicy", func() {
 		table.Entry("Cloud has mismatch security group member", cloudReturnDiffMemberSG),
 		table.Entry("Cloud has mismatch security group rule", cloudReturnDiffRuleSG),
 		table.Entry("Cloud has extra security group", cloudReturnExtraSG),
+		table.Entry("Cloud has incomplete security groups", cloudReturnIncompleteSG),
 	)
 })
@@ -22,9 +22,13 @@ import (
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // VirtualMachineReconciler reconciles a VirtualMachine object.
@@ -69,5 +73,47 @@ func (r *VirtualMachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	go r.converter.Start()
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&cloudv1alpha1.VirtualMachine{}).
+		Watches(&source.Kind{Type: &cloudv1alpha1.CloudEntitySelector{}},
+			handler.EnqueueRequestsFromMapFunc(r.getVirtualMachinesForSelector)).
+		Watches(&source.Kind{Type: &cloudv1alpha1.Vpc{}},
+			handler.EnqueueRequestsFromMapFunc(r.getVirtualMachinesForVpc)).
 		Complete(r)
 }
+
+// getVirtualMachinesForSelector returns reconcile requests for VirtualMachines owned by the CloudEntitySelector, so that
+// ExternalEntities follow changes of the selector endpoint address types.
+func (r *VirtualMachineReconciler) getVirtualMachinesForSelector(obj client.Object) []reconcile.Request {
+	vmList := &cloudv1alpha1.VirtualMachineList{}
+	if err := r.List(context.TODO(), vmList, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "failed to list VirtualMachines", "selector", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range vmList.Items {
+		vm := &vmList.Items[i]
+		if owner := metav1.GetControllerOf(vm); owner != nil && owner.UID == obj.GetUID() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(vm)})
+		}
+	}
+	return requests
+}
+
+// getVirtualMachinesForVpc returns reconcile requests for VirtualMachines in the Vpc, so that ExternalEntities follow
+// changes of the Vpc labels.
+func (r *VirtualMachineReconciler) getVirtualMachinesForVpc(obj client.Object) []reconcile.Request {
+	vmList := &cloudv1alpha1.VirtualMachineList{}
+	if err := r.List(context.TODO(), vmList, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "failed to list VirtualMachines", "vpc", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range vmList.Items {
+		vm := &vmList.Items[i]
+		if vm.Status.VirtualPrivateCloud == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(vm)})
+		}
+	}
+	return requests
+}
@@ -26,6 +26,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	antreatypes "antrea.io/antrea/pkg/apis/crd/v1alpha2"
@@ -103,4 +104,25 @@ var _ = Describe("VirtualmachineController", func() {
 		table.Entry("VM source not found on delete, forward empty VM", errors.NewNotFound(ctrl.GroupResource{}, "")),
 		table.Entry("VM source get error, error and no forwarding", errors.NewBadRequest("")),
 	)
+
+	It("Should enqueue VirtualMachines of an updated Vpc", func() {
+		reconciler := &VirtualMachineReconciler{
+			Log:    logf.Log,
+			Client: mockClient,
+		}
+		vpc := &cloud.Vpc{}
+		vpc.Namespace = testNamespace
+		vpc.Name = "vpc-01"
+		vms := []cloud.VirtualMachine{{}, {}}
+		vms[0].Namespace, vms[0].Name, vms[0].Status.VirtualPrivateCloud = testNamespace, "vm-01", "vpc-01"
+		vms[1].Namespace, vms[1].Name, vms[1].Status.VirtualPrivateCloud = testNamespace, "vm-02", "vpc-02"
+		mockClient.EXPECT().List(mock.Any(), mock.Any(), client.InNamespace(testNamespace)).Return(nil).
+			Do(func(_ context.Context, list *cloud.VirtualMachineList, _ ...client.ListOption) {
+				list.Items = vms
+			})
+
+		requests := reconciler.getVirtualMachinesForVpc(vpc)
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].NamespacedName).To(Equal(types.NamespacedName{Namespace: testNamespace, Name: "vm-01"}))
+	})
 })
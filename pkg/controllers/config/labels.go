@@ -22,4 +22,14 @@ const (
 	ExternalEntityLabelKeyName       = "name." + ExternalEntityLabelKeyPostfix
 	ExternalEntityLabelKeyTagPostfix = ".tag." + ExternalEntityLabelKeyPostfix
 	ExternalEntityLabelCloudVPCKey   = "vpc." + ExternalEntityLabelKeyPostfix
+	// Labels derived from cloud properties of a VirtualMachine.
+	ExternalEntityLabelKeyRegion       = "region." + ExternalEntityLabelKeyPostfix
+	ExternalEntityLabelKeyZone         = "zone." + ExternalEntityLabelKeyPostfix
+	ExternalEntityLabelKeyOSType       = "os." + ExternalEntityLabelKeyPostfix
+	ExternalEntityLabelKeyInstanceType = "instance-type." + ExternalEntityLabelKeyPostfix
+	// Labels derived from the Vpc of a VirtualMachine.
+	ExternalEntityLabelCloudVPCNameKey  = "vpc-name." + ExternalEntityLabelKeyPostfix
+	ExternalEntityLabelKeyVpcTagPostfix = ".vpc-tag." + ExternalEntityLabelKeyPostfix
+	// Label of user created ExternalEntities standing for a cloud service, Azure service tag or AWS prefix list.
+	ExternalEntityLabelKeyCloudService = "cloud-service." + ExternalEntityLabelKeyPostfix
 )
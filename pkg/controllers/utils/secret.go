@@ -0,0 +1,76 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"antrea.io/nephe/apis/crd/v1alpha1"
+)
+
+// ResolveSecretRef fills in the account credential secret from the Secret referenced by the account, if any.
+func ResolveSecretRef(ctx context.Context, k8sClient client.Client, account *v1alpha1.CloudProviderAccount) error {
+	secretRef := GetAccountSecretRef(account)
+	if secretRef == nil {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	secretNamespacedName := types.NamespacedName{Namespace: GetSecretRefNamespace(account, secretRef), Name: secretRef.Name}
+	if !v1alpha1.IsSecretNamespaceAllowed(secretNamespacedName.Namespace, account.Namespace) {
+		return fmt.Errorf("secretRef %v is not in namespace of the account or of nephe controller", secretNamespacedName)
+	}
+	if err := k8sClient.Get(ctx, secretNamespacedName, secret); err != nil {
+		return fmt.Errorf("failed to get Secret %v: %w", secretNamespacedName, err)
+	}
+	value, ok := secret.Data[secretRef.Key]
+	if !ok {
+		return fmt.Errorf("key %s not found in Secret %v", secretRef.Key, secretNamespacedName)
+	}
+
+	if account.Spec.AWSConfig != nil {
+		account.Spec.AWSConfig.AccessKeySecret = string(value)
+	} else if account.Spec.AzureConfig != nil {
+		account.Spec.AzureConfig.ClientKey = string(value)
+	} else if account.Spec.GCPConfig != nil {
+		account.Spec.GCPConfig.ServiceAccountKey = string(value)
+	}
+	return nil
+}
+
+// GetAccountSecretRef returns the Secret reference of account credential, nil if credential is specified inline.
+func GetAccountSecretRef(account *v1alpha1.CloudProviderAccount) *v1alpha1.SecretReference {
+	if account.Spec.AWSConfig != nil {
+		return account.Spec.AWSConfig.SecretRef
+	} else if account.Spec.AzureConfig != nil {
+		return account.Spec.AzureConfig.SecretRef
+	} else if account.Spec.GCPConfig != nil {
+		return account.Spec.GCPConfig.SecretRef
+	}
+	return nil
+}
+
+// GetSecretRefNamespace returns the namespace of referenced Secret, which defaults to namespace of account.
+func GetSecretRefNamespace(account *v1alpha1.CloudProviderAccount, secretRef *v1alpha1.SecretReference) string {
+	if len(secretRef.Namespace) != 0 {
+		return secretRef.Namespace
+	}
+	return account.Namespace
+}
@@ -15,6 +15,10 @@
 package source
 
 import (
+	"context"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	antreatypes "antrea.io/antrea/pkg/apis/crd/v1alpha2"
@@ -29,16 +33,48 @@ type VirtualMachineSource struct {
 	v1alpha1.VirtualMachine
 }
 
-// GetEndPointAddresses returns VirtualMachine's IP addresses.
-func (v *VirtualMachineSource) GetEndPointAddresses() ([]string, error) {
+// GetEndPointAddresses returns VirtualMachine's IP addresses, of the endpoint address types of its CloudEntitySelector.
+func (v *VirtualMachineSource) GetEndPointAddresses(cl client.Client) ([]string, error) {
+	addressTypes, err := v.getEndPointAddressTypes(cl)
+	if err != nil {
+		return nil, err
+	}
 	ipAddrs := utils.GetVMIPAddresses(&v.VirtualMachine)
 	ip := make([]string, 0, len(ipAddrs))
 	for _, ipAddr := range ipAddrs {
+		if _, ok := addressTypes[ipAddr.AddressType]; len(addressTypes) > 0 && !ok {
+			continue
+		}
 		ip = append(ip, ipAddr.Address)
 	}
 	return ip, nil
 }
 
+// getEndPointAddressTypes returns endpoint address types of the CloudEntitySelector owning VirtualMachine, nil if all
+// address types are used.
+func (v *VirtualMachineSource) getEndPointAddressTypes(cl client.Client) (map[v1alpha1.AddressType]struct{}, error) {
+	if cl == nil {
+		return nil, nil
+	}
+	owner := metav1.GetControllerOf(&v.VirtualMachine)
+	if owner == nil || owner.Kind != reflect.TypeOf(v1alpha1.CloudEntitySelector{}).Name() {
+		return nil, nil
+	}
+	selector := &v1alpha1.CloudEntitySelector{}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Namespace: v.Namespace, Name: owner.Name}, selector); err != nil {
+		// selector is deleted with its VirtualMachines.
+		return nil, client.IgnoreNotFound(err)
+	}
+	if len(selector.Spec.EndpointAddressTypes) == 0 {
+		return nil, nil
+	}
+	addressTypes := make(map[v1alpha1.AddressType]struct{})
+	for _, addressType := range selector.Spec.EndpointAddressTypes {
+		addressTypes[addressType] = struct{}{}
+	}
+	return addressTypes, nil
+}
+
 // GetEndPointPort returns nil as VirtualMachine has no associated port.
 func (v *VirtualMachineSource) GetEndPointPort(_ client.Client) []antreatypes.NamedPort {
 	return nil
@@ -49,9 +85,35 @@ func (v *VirtualMachineSource) GetTags() map[string]string {
 	return v.Status.Tags
 }
 
-// GetLabelsFromClient returns VirtualMachine specific labels.
-func (v *VirtualMachineSource) GetLabelsFromClient(_ client.Client) map[string]string {
-	return map[string]string{config.ExternalEntityLabelCloudVPCKey: v.Status.VirtualPrivateCloud}
+// GetLabelsFromClient returns VirtualMachine specific labels, including labels of its Vpc if known.
+func (v *VirtualMachineSource) GetLabelsFromClient(cl client.Client) map[string]string {
+	labels := map[string]string{config.ExternalEntityLabelCloudVPCKey: v.Status.VirtualPrivateCloud}
+	properties := map[string]string{
+		config.ExternalEntityLabelKeyRegion:       v.Status.Region,
+		config.ExternalEntityLabelKeyZone:         v.Status.AvailabilityZone,
+		config.ExternalEntityLabelKeyOSType:       v.Status.OSType,
+		config.ExternalEntityLabelKeyInstanceType: v.Status.InstanceType,
+	}
+	for key, val := range properties {
+		if len(val) > 0 {
+			labels[key] = target.GetLabelValue(val)
+		}
+	}
+	if cl == nil || v.Status.VirtualPrivateCloud == "" {
+		return labels
+	}
+	vpc := &v1alpha1.Vpc{}
+	key := client.ObjectKey{Namespace: v.Namespace, Name: v.Status.VirtualPrivateCloud}
+	if err := cl.Get(context.TODO(), key, vpc); err != nil {
+		return labels
+	}
+	if vpc.Status.Name != "" {
+		labels[config.ExternalEntityLabelCloudVPCNameKey] = target.GetLabelValue(vpc.Status.Name)
+	}
+	for k, val := range target.GetTagLabels(vpc.Status.Tags, config.ExternalEntityLabelKeyVpcTagPostfix) {
+		labels[k] = val
+	}
+	return labels
 }
 
 // GetExternalNode returns external node/controller associated with VirtualMachine.
@@ -100,7 +100,7 @@ func (v VMConverter) processEvent(vm *VirtualMachineSource, failedUpdates map[st
 	}()
 
 	ctx := context.Background()
-	ips, err := vm.GetEndPointAddresses()
+	ips, err := vm.GetEndPointAddresses(v.Client)
 	if err != nil {
 		log.Info("Failed to get IP address for", "Name", fetchKey, "err", err)
 		return
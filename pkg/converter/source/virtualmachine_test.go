@@ -58,6 +58,9 @@ var _ = Describe("VirtualmachineConverter", func() {
 
 	BeforeEach(func() {
 		commonInitTest()
+		// Vpc of VirtualMachines is not imported.
+		mockClient.EXPECT().Get(mock.Any(), client.ObjectKey{Namespace: testNamespace, Name: "test-vm-vpc"}, mock.Any()).
+			Return(errors.NewNotFound(schema.GroupResource{}, "test-vm-vpc")).AnyTimes()
 		converter = source.VMConverter{
 			Client: mockClient,
 			Log:    logf.Log,
@@ -34,11 +34,13 @@ const (
 	LabelExpression = "[^a-zA-Z0-9_-]+"
 )
 
+var labelRegexp = regexp.MustCompile(LabelExpression)
+
 type ExternalEntitySource interface {
 	client.Object
 	// GetEndPointAddresses returns IP addresses of ExternalEntitySource.
 	// Passing client in case there are references needs to be retrieved from local cache.
-	GetEndPointAddresses() ([]string, error)
+	GetEndPointAddresses(client client.Client) ([]string, error)
 	// GetEndPointPort returns port and port name, if applicable, of ExternalEntitySource.
 	GetEndPointPort(client client.Client) []antreatypes.NamedPort
 	// GetTags returns tags of ExternalEntitySource.
@@ -95,6 +97,27 @@ func PatchExternalEntityFrom(
 	return patch
 }
 
+// GetTagLabels converts cloud tags to labels, with postfix appended to each label key.
+func GetTagLabels(tags map[string]string, postfix string) map[string]string {
+	labels := make(map[string]string)
+	for key, val := range tags {
+		labels[truncateLabel(labelRegexp.ReplaceAllString(key, "")+postfix)] = GetLabelValue(val)
+	}
+	return labels
+}
+
+// GetLabelValue strips characters not allowed in labels from str, and truncates it to label size limit.
+func GetLabelValue(str string) string {
+	return truncateLabel(labelRegexp.ReplaceAllString(str, ""))
+}
+
+func truncateLabel(str string) string {
+	if len(str) > LabelSizeLimit {
+		str = str[:LabelSizeLimit]
+	}
+	return strings.ToLower(str)
+}
+
 func PopulateExternalEntityFrom(source ExternalEntitySource, externEntity *antreatypes.ExternalEntity, cl client.Client) {
 	labels := make(map[string]string)
 	accessor, _ := meta.Accessor(source)
@@ -104,21 +127,12 @@ func PopulateExternalEntityFrom(source ExternalEntitySource, externEntity *antre
 	for key, val := range source.GetLabelsFromClient(cl) {
 		labels[key] = val
 	}
-	for key, val := range source.GetTags() {
-		reg, _ := regexp.Compile(LabelExpression)
-		fkey := reg.ReplaceAllString(key, "") + config.ExternalEntityLabelKeyTagPostfix
-		if len(fkey) > LabelSizeLimit {
-			fkey = fkey[:LabelSizeLimit]
-		}
-		fval := reg.ReplaceAllString(val, "")
-		if len(fval) > LabelSizeLimit {
-			fval = fval[:LabelSizeLimit]
-		}
-		labels[strings.ToLower(fkey)] = strings.ToLower(fval)
+	for key, val := range GetTagLabels(source.GetTags(), config.ExternalEntityLabelKeyTagPostfix) {
+		labels[key] = val
 	}
 	externEntity.SetLabels(labels)
 
-	ipAddrs, _ := source.GetEndPointAddresses()
+	ipAddrs, _ := source.GetEndPointAddresses(cl)
 	endpoints := make([]antreatypes.Endpoint, 0, len(ipAddrs))
 	for _, ip := range ipAddrs {
 		endpoints = append(endpoints, antreatypes.Endpoint{IP: ip})
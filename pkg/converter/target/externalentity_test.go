@@ -22,12 +22,14 @@ import (
 	"github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	antreatypes "antrea.io/antrea/pkg/apis/crd/v1alpha2"
 
 	cloud "antrea.io/nephe/apis/crd/v1alpha1"
+	"antrea.io/nephe/pkg/controllers/config"
 	"antrea.io/nephe/pkg/testing"
 	"antrea.io/nephe/pkg/testing/controllerruntimeclient"
 )
@@ -64,7 +66,7 @@ var _ = Describe("Externalentity", func() {
 
 	getEndPointAddressesTester := func(name string) {
 		externalEntitySource := externalEntitySources[name]
-		ips, err := externalEntitySource.GetEndPointAddresses()
+		ips, err := externalEntitySource.GetEndPointAddresses(mockclient)
 		Expect(err).ToNot(HaveOccurred())
 		// As Equal and []string{} == nil
 		Expect(ips).To(ConsistOf(networkInterfaceIPAddresses))
@@ -106,11 +108,16 @@ var _ = Describe("Externalentity", func() {
 	getLabelsTester := func(name string, hasLabels bool) {
 		mockclient.EXPECT().Get(mock.Any(), mock.Any(), mock.Any()).
 			Return(nil).
-			Do(func(_ context.Context, key client.ObjectKey, out *cloud.VirtualMachine) {
+			Do(func(_ context.Context, key client.ObjectKey, out client.Object) {
 				vm := externalEntitySources["VirtualMachine"].EmbedType().(*cloud.VirtualMachine)
-				Expect(key.Name).To(Equal(vm.Name))
 				Expect(key.Namespace).To(Equal(vm.Namespace))
-				vm.DeepCopyInto(out)
+				switch obj := out.(type) {
+				case *cloud.VirtualMachine:
+					Expect(key.Name).To(Equal(vm.Name))
+					vm.DeepCopyInto(obj)
+				case *cloud.Vpc:
+					Expect(key.Name).To(Equal(vm.Status.VirtualPrivateCloud))
+				}
 			}).AnyTimes()
 
 		externalEntitySource := externalEntitySources[name]
@@ -160,6 +167,53 @@ var _ = Describe("Externalentity", func() {
 			table.Entry("VirtualMachine", "VirtualMachine", &cloud.VirtualMachine{}))
 	})
 
+	Context("Source has a Vpc", func() {
+		It("Should add Vpc labels", func() {
+			vm := externalEntitySources["VirtualMachine"].EmbedType().(*cloud.VirtualMachine)
+			vm.Status.VirtualPrivateCloud = "test-vpc-id"
+			mockclient.EXPECT().Get(mock.Any(), client.ObjectKey{Namespace: vm.Namespace, Name: "test-vpc-id"}, mock.Any()).
+				Return(nil).
+				Do(func(_ context.Context, _ client.ObjectKey, out *cloud.Vpc) {
+					out.Status.Name = "Test VPC"
+					out.Status.Tags = map[string]string{"Env": "Prod"}
+				})
+
+			labels := externalEntitySources["VirtualMachine"].GetLabelsFromClient(mockclient)
+			Expect(labels).To(HaveKeyWithValue(config.ExternalEntityLabelCloudVPCKey, "test-vpc-id"))
+			Expect(labels).To(HaveKeyWithValue(config.ExternalEntityLabelCloudVPCNameKey, "testvpc"))
+			Expect(labels).To(HaveKeyWithValue("env"+config.ExternalEntityLabelKeyVpcTagPostfix, "prod"))
+		})
+	})
+
+	Context("Source is owned by CloudEntitySelector", func() {
+		var vm *cloud.VirtualMachine
+
+		BeforeEach(func() {
+			vm = externalEntitySources["VirtualMachine"].EmbedType().(*cloud.VirtualMachine)
+			vm.Status.NetworkInterfaces[0].IPs[0].AddressType = cloud.AddressTypeInternalIP
+			vm.Status.NetworkInterfaces[1].IPs[0].AddressType = cloud.AddressTypeExternalIP
+			isController := true
+			vm.OwnerReferences = []metav1.OwnerReference{
+				{Kind: "CloudEntitySelector", Name: "test-selector", Controller: &isController},
+			}
+		})
+
+		table.DescribeTable("GetEndPointAddresses",
+			func(addressTypes []cloud.AddressType, expectedIPs []string) {
+				mockclient.EXPECT().Get(mock.Any(), client.ObjectKey{Namespace: vm.Namespace, Name: "test-selector"}, mock.Any()).
+					Return(nil).
+					Do(func(_ context.Context, _ client.ObjectKey, out *cloud.CloudEntitySelector) {
+						out.Spec.EndpointAddressTypes = addressTypes
+					})
+				ips, err := externalEntitySources["VirtualMachine"].GetEndPointAddresses(mockclient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ips).To(Equal(expectedIPs))
+			},
+			table.Entry("All address types", nil, []string{"1.1.1.1", "2.2.2.2"}),
+			table.Entry("Internal IP", []cloud.AddressType{cloud.AddressTypeInternalIP}, []string{"1.1.1.1"}),
+			table.Entry("External IP", []cloud.AddressType{cloud.AddressTypeExternalIP}, []string{"2.2.2.2"}))
+	})
+
 	Context("Source does not have required information", func() {
 		JustBeforeEach(func() {
 			networkInterfaceIPAddresses = nil
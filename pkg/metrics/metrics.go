@@ -0,0 +1,191 @@
+// Copyright 2022 Antrea Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics defines nephe Prometheus collectors. Collectors are registered with the
+// controller-runtime registry and are served on the manager metrics address.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricNamespace = "nephe"
+
+	labelAccountNamespace = "account_namespace"
+	labelAccountName      = "account_name"
+	labelService          = "service"
+	labelProvider         = "provider"
+	labelOperation        = "operation"
+	labelResult           = "result"
+	labelQueue            = "queue"
+	labelState            = "state"
+	labelType             = "type"
+	labelSecurityGroup    = "security_group"
+
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	SecurityGroupOperationCreate        = "create"
+	SecurityGroupOperationUpdateMembers = "update_members"
+	SecurityGroupOperationUpdateRules   = "update_rules"
+	SecurityGroupOperationDelete        = "delete"
+
+	CloudSyncCorrectionMembers              = "members"
+	CloudSyncCorrectionRules                = "rules"
+	CloudSyncCorrectionStaleSecurityGroup   = "stale_security_group"
+	CloudSyncCorrectionUnmanagedAttachments = "unmanaged_attachments"
+)
+
+var (
+	// InventoryVirtualMachines is number of virtual machines discovered per cloud account.
+	InventoryVirtualMachines = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: "inventory",
+			Name:      "virtual_machines",
+			Help:      "Number of virtual machines discovered in a cloud account.",
+		},
+		[]string{labelAccountNamespace, labelAccountName},
+	)
+
+	// InventoryPolls is number of inventory polls per cloud account service and poll result.
+	InventoryPolls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: "inventory",
+			Name:      "polls_total",
+			Help:      "Number of inventory polls of a cloud account service, partitioned by result.",
+		},
+		[]string{labelAccountNamespace, labelAccountName, labelService, labelResult},
+	)
+
+	// InventoryPollDuration is latency of inventory polls per cloud account service.
+	InventoryPollDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: "inventory",
+			Name:      "poll_duration_seconds",
+			Help:      "Latency of inventory polls of a cloud account service.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+		},
+		[]string{labelAccountNamespace, labelAccountName, labelService},
+	)
+
+	// SecurityGroupOperations is number of cloud security group operations per provider, operation and result.
+	SecurityGroupOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: "security_group",
+			Name:      "operations_total",
+			Help:      "Number of cloud security group operations, partitioned by provider, operation and result.",
+		},
+		[]string{labelProvider, labelOperation, labelResult},
+	)
+
+	// SecurityGroupOperationDuration is latency of cloud security group operations per provider and operation.
+	SecurityGroupOperationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: "security_group",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of cloud security group operations, partitioned by provider and operation.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+		},
+		[]string{labelProvider, labelOperation},
+	)
+
+	// SecurityGroupRuleHeadroom is number of rules that can be added to a cloud security group before its quota is
+	// reached, negative if rules to be realized exceed the quota.
+	SecurityGroupRuleHeadroom = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: "security_group",
+			Name:      "rule_headroom",
+			Help:      "Number of rules that can be added to a cloud security group before its rule quota is reached.",
+		},
+		[]string{labelProvider, labelSecurityGroup},
+	)
+
+	// PendingItemQueueDepth is number of items in network policy pending and retry queues.
+	PendingItemQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: "networkpolicy",
+			Name:      "queue_depth",
+			Help:      "Number of items waiting in a network policy pending or retry queue.",
+		},
+		[]string{labelQueue},
+	)
+
+	// NetworkPolicyRealization is number of network policies per realization state.
+	NetworkPolicyRealization = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: "networkpolicy",
+			Name:      "realization",
+			Help:      "Number of network policies, partitioned by realization state.",
+		},
+		[]string{labelState},
+	)
+
+	// CloudSyncCorrections is number of drifts from cloud corrected by periodic cloud synchronization.
+	CloudSyncCorrections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: "networkpolicy",
+			Name:      "cloud_sync_corrections_total",
+			Help:      "Number of drifts from cloud corrected by cloud synchronization, partitioned by drift type.",
+		},
+		[]string{labelType},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		InventoryVirtualMachines,
+		InventoryPolls,
+		InventoryPollDuration,
+		SecurityGroupOperations,
+		SecurityGroupOperationDuration,
+		SecurityGroupRuleHeadroom,
+		PendingItemQueueDepth,
+		NetworkPolicyRealization,
+		CloudSyncCorrections,
+	)
+}
+
+// Result returns result label value of an operation based on its error.
+func Result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
+
+// DeleteAccountMetrics removes metrics of a cloud account and its services.
+func DeleteAccountMetrics(namespace, name string, services []string) {
+	InventoryVirtualMachines.DeleteLabelValues(namespace, name)
+	for _, service := range services {
+		DeleteAccountServiceMetrics(namespace, name, service)
+	}
+}
+
+// DeleteAccountServiceMetrics removes metrics of a cloud account service.
+func DeleteAccountServiceMetrics(namespace, name, service string) {
+	InventoryPolls.DeleteLabelValues(namespace, name, service, ResultSuccess)
+	InventoryPolls.DeleteLabelValues(namespace, name, service, ResultFailure)
+	InventoryPollDuration.DeleteLabelValues(namespace, name, service)
+}
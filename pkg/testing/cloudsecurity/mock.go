@@ -78,11 +78,12 @@ func (mr *MockCloudSecurityGroupAPIMockRecorder) DeleteSecurityGroup(arg0, arg1
 }
 
 // GetSecurityGroupSyncChan mocks base method.
-func (m *MockCloudSecurityGroupAPI) GetSecurityGroupSyncChan() <-chan securitygroup.SynchronizationContent {
+func (m *MockCloudSecurityGroupAPI) GetSecurityGroupSyncChan() (<-chan securitygroup.SynchronizationContent, <-chan error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetSecurityGroupSyncChan")
 	ret0, _ := ret[0].(<-chan securitygroup.SynchronizationContent)
-	return ret0
+	ret1, _ := ret[1].(<-chan error)
+	return ret0, ret1
 }
 
 // GetSecurityGroupSyncChan indicates an expected call of GetSecurityGroupSyncChan.